@@ -0,0 +1,483 @@
+// Copyright 2026 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package cassette records the gcloud and terraform commands of an
+// integration test and replays them without a project. In record mode every
+// command is run for real and its arguments, stdout, stderr and exit code are
+// saved to testdata/cassettes/<test name>.json when the test ends. In replay
+// mode nothing is run: each command is answered with the first unused
+// recorded one whose normalized arguments match, so the validation logic of
+// a test can run in CI from its cassette.
+package cassette
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	gotesting "testing"
+
+	"github.com/GoogleCloudPlatform/cloudnetworking-config-solutions/common_utils/gcloud"
+	"github.com/GoogleCloudPlatform/cloudnetworking-config-solutions/common_utils/report"
+	"github.com/gruntwork-io/terratest/modules/shell"
+	"github.com/gruntwork-io/terratest/modules/terraform"
+	"github.com/gruntwork-io/terratest/modules/testing"
+)
+
+// ModeEnv is the environment variable that selects the mode: "record" or
+// "replay". Commands run live, without a cassette, when it is unset.
+const ModeEnv = "TEST_CASSETTE_MODE"
+
+// Mode says whether commands are run live, recorded or replayed.
+type Mode string
+
+const (
+	Live   Mode = ""
+	Record Mode = "record"
+	Replay Mode = "replay"
+)
+
+// Dir is where cassettes are stored, relative to the package of the test.
+var Dir = filepath.Join("testdata", "cassettes")
+
+// RunSuffix matches the run ID and hash that naming.Name appends to every
+// name. It is normalized in every cassette.
+const RunSuffix = `-r[a-z0-9]{1,10}-[0-9a-f]{6}\b`
+
+// Interaction is one recorded command.
+type Interaction struct {
+	Command  string   `json:"command"`
+	Args     []string `json:"args"`
+	Stdout   string   `json:"stdout,omitempty"`
+	Stderr   string   `json:"stderr,omitempty"`
+	ExitCode int      `json:"exitCode"`
+
+	used bool
+}
+
+// ExitError is returned for a replayed command that exited with a non-zero
+// code.
+type ExitError struct {
+	Code int
+}
+
+func (e *ExitError) Error() string {
+	return fmt.Sprintf("exit status %d", e.Code)
+}
+
+// Cassette records or replays the commands of one test. It implements
+// gcloud.Runner, and Start makes it the runner of the gcloud clients of the
+// test.
+type Cassette struct {
+	// Mode is read from $TEST_CASSETTE_MODE.
+	Mode Mode
+	// Path is the file the interactions are read from and saved to.
+	Path string
+	// Runner runs the commands in live and record mode. It defaults to
+	// gcloud.ShellRunner.
+	Runner gcloud.Runner
+
+	mu           sync.Mutex
+	interactions []*Interaction
+	normalizers  []*regexp.Regexp
+	vars         map[string]string
+	recorded     map[string]string
+	replacements map[string]string
+}
+
+// file is the layout of a cassette on disk.
+type file struct {
+	Vars         map[string]string `json:"vars,omitempty"`
+	Interactions []*Interaction    `json:"interactions"`
+}
+
+// Start returns the cassette of t. Call it first in the test, so that the
+// teardowns of the fixtures created afterwards are recorded too. In replay
+// mode the test fails when no cassette was recorded for it, so that a replay
+// run without cassettes cannot pass.
+func Start(t gotesting.TB) *Cassette {
+	t.Helper()
+	c := newCassette(Mode(os.Getenv(ModeEnv)), filepath.Join(Dir, strings.ReplaceAll(t.Name(), "/", "_")+".json"))
+	switch c.Mode {
+	case Live:
+		return c
+	case Record:
+		// The report ends after the cassette is saved and linked to it.
+		report.For(t)
+		t.Cleanup(func() {
+			if err := c.Save(); err != nil {
+				t.Errorf("Saving cassette %s: %v", c.Path, err)
+				return
+			}
+			report.AddArtifact(t, "cassette", c.Path)
+		})
+	case Replay:
+		if err := c.Load(); os.IsNotExist(err) {
+			t.Fatalf("No cassette recorded at %s: record it with %s=%s against a project", c.Path, ModeEnv, Record)
+		} else if err != nil {
+			t.Fatalf("Loading cassette %s: %v", c.Path, err)
+		}
+	default:
+		t.Fatalf("Unknown %s %q, want %q or %q", ModeEnv, c.Mode, Record, Replay)
+	}
+	gcloud.UseRunner(t, c)
+	return c
+}
+
+func newCassette(mode Mode, path string) *Cassette {
+	c := &Cassette{Mode: mode, Path: path, vars: map[string]string{}, replacements: map[string]string{}}
+	c.Normalize(RunSuffix)
+	return c
+}
+
+// For returns the cassette started for t or its closest parent test. Without
+// one, or in live mode, the returned cassette runs the commands as is.
+func For(t testing.TestingT) *Cassette {
+	if c, ok := gcloud.RunnerFor(t).(*Cassette); ok {
+		return c
+	}
+	return &Cassette{}
+}
+
+// Output runs cmd with the cassette of t like shell.RunCommandAndGetOutputE.
+func Output(t testing.TestingT, cmd shell.Command) (string, error) {
+	return For(t).Output(t, cmd)
+}
+
+// InitAndApply runs terraform init and apply with the cassette of t like
+// terraform.InitAndApply.
+func InitAndApply(t testing.TestingT, options *terraform.Options) string {
+	return For(t).InitAndApply(t, options)
+}
+
+// InitAndApplyE runs terraform init and apply with the cassette of t like
+// terraform.InitAndApplyE.
+func InitAndApplyE(t testing.TestingT, options *terraform.Options) (string, error) {
+	return For(t).InitAndApplyE(t, options)
+}
+
+// Destroy runs terraform destroy with the cassette of t like
+// terraform.Destroy.
+func Destroy(t testing.TestingT, options *terraform.Options) string {
+	return For(t).Destroy(t, options)
+}
+
+// DestroyE runs terraform destroy with the cassette of t like
+// terraform.DestroyE.
+func DestroyE(t testing.TestingT, options *terraform.Options) (string, error) {
+	return For(t).DestroyE(t, options)
+}
+
+// OutputJSON reads a terraform output with the cassette of t like
+// terraform.OutputJson.
+func OutputJSON(t testing.TestingT, options *terraform.Options, key string) string {
+	return For(t).OutputJSON(t, options, key)
+}
+
+// Normalize adds a regular expression matching the parts of the arguments
+// that change from one run to the next, such as the random suffix of the
+// resource names. Matches are ignored when looking up a recorded command, and
+// the recorded values are replaced by the current ones in its output.
+func (c *Cassette) Normalize(pattern string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.normalizers = append(c.normalizers, regexp.MustCompile(pattern))
+}
+
+// Vary names a value that changes from one run to the next but is known when
+// the test starts, such as the project ID, a random ID or an absolute path, and
+// returns it. The value is replaced by its name when looking up a recorded
+// command, and the recorded value by the current one in its output.
+func (c *Cassette) Vary(name, value string) string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.vars[name] = value
+	if was, ok := c.recorded[name]; ok && was != value {
+		c.replacements[was] = value
+	}
+	return value
+}
+
+// Load reads the interactions saved at Path.
+func (c *Cassette) Load() error {
+	b, err := os.ReadFile(c.Path)
+	if err != nil {
+		return err
+	}
+	var f file
+	if err := json.Unmarshal(b, &f); err != nil {
+		return fmt.Errorf("decoding %s: %w", c.Path, err)
+	}
+	c.mu.Lock()
+	c.interactions, c.recorded = f.Interactions, f.Vars
+	c.mu.Unlock()
+	return nil
+}
+
+// Save writes the recorded interactions to Path.
+func (c *Cassette) Save() error {
+	c.mu.Lock()
+	b, err := json.MarshalIndent(file{Vars: c.vars, Interactions: c.interactions}, "", "  ")
+	c.mu.Unlock()
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(c.Path), 0755); err != nil {
+		return err
+	}
+	return os.WriteFile(c.Path, append(b, '\n'), 0644)
+}
+
+// Run implements gcloud.Runner.
+func (c *Cassette) Run(t testing.TestingT, cmd shell.Command) (string, string, error) {
+	if c.Mode == Replay {
+		return c.replay(t, cmd.Command, cmd.Args)
+	}
+	runner := c.Runner
+	if runner == nil {
+		runner = gcloud.ShellRunner{}
+	}
+	stdout, stderr, err := runner.Run(t, cmd)
+	if c.Mode == Record {
+		c.record(cmd.Command, cmd.Args, stdout, stderr, err)
+	}
+	return stdout, stderr, err
+}
+
+// Output runs cmd like shell.RunCommandAndGetOutputE and returns its stdout
+// and stderr.
+func (c *Cassette) Output(t testing.TestingT, cmd shell.Command) (string, error) {
+	if c.Mode == Live && c.Runner == nil {
+		return shell.RunCommandAndGetOutputE(t, cmd)
+	}
+	stdout, stderr, err := c.Run(t, cmd)
+	return joinOutput(stdout, stderr), err
+}
+
+// InitAndApply runs terraform init and apply like terraform.InitAndApply and
+// fails the test on error.
+func (c *Cassette) InitAndApply(t testing.TestingT, options *terraform.Options) string {
+	out, err := c.InitAndApplyE(t, options)
+	if err != nil {
+		t.Fatalf("terraform apply of %s: %v", options.TerraformDir, err)
+	}
+	return out
+}
+
+// InitAndApplyE runs terraform init and apply like terraform.InitAndApplyE.
+// The variables of options are recorded as the arguments of apply, so that
+// the values they hold are normalized.
+func (c *Cassette) InitAndApplyE(t testing.TestingT, options *terraform.Options) (string, error) {
+	return c.terraform(t, append([]string{"apply"}, varArgs(options)...), func() (string, error) {
+		return terraform.InitAndApplyE(t, options)
+	})
+}
+
+// Destroy runs terraform destroy like terraform.Destroy and fails the test on
+// error.
+func (c *Cassette) Destroy(t testing.TestingT, options *terraform.Options) string {
+	out, err := c.DestroyE(t, options)
+	if err != nil {
+		t.Fatalf("terraform destroy of %s: %v", options.TerraformDir, err)
+	}
+	return out
+}
+
+// DestroyE runs terraform destroy like terraform.DestroyE.
+func (c *Cassette) DestroyE(t testing.TestingT, options *terraform.Options) (string, error) {
+	return c.terraform(t, append([]string{"destroy"}, varArgs(options)...), func() (string, error) {
+		return terraform.DestroyE(t, options)
+	})
+}
+
+// OutputJSON returns the output key as JSON like terraform.OutputJson and
+// fails the test on error.
+func (c *Cassette) OutputJSON(t testing.TestingT, options *terraform.Options, key string) string {
+	out, err := c.terraform(t, []string{"output", "-json", key}, func() (string, error) {
+		return terraform.OutputJsonE(t, options, key)
+	})
+	if err != nil {
+		t.Fatalf("terraform output %s of %s: %v", key, options.TerraformDir, err)
+	}
+	return out
+}
+
+// terraform runs fn, which invokes terraform, unless the cassette replays
+// args. The phase of the command is started in the report of the test either
+// way.
+func (c *Cassette) terraform(t testing.TestingT, args []string, fn func() (string, error)) (string, error) {
+	if tb, ok := t.(gotesting.TB); ok {
+		if phase, ok := report.TerraformPhase(args[0]); ok {
+			report.Enter(tb, phase)
+		}
+	}
+	if c.Mode == Replay {
+		stdout, stderr, err := c.replay(t, "terraform", args)
+		return joinOutput(stdout, stderr), err
+	}
+	out, err := fn()
+	if c.Mode == Record {
+		c.record("terraform", args, out, "", err)
+	}
+	return out, err
+}
+
+func (c *Cassette) record(command string, args []string, stdout, stderr string, err error) {
+	code := 0
+	if err != nil {
+		code = 1
+		var replayed *ExitError
+		if errors.As(err, &replayed) {
+			code = replayed.Code
+		} else if exit, exitErr := shell.GetExitCodeForRunCommandError(err); exitErr == nil && exit != 0 {
+			code = exit
+		}
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.interactions = append(c.interactions, &Interaction{
+		Command:  command,
+		Args:     append([]string(nil), args...),
+		Stdout:   stdout,
+		Stderr:   stderr,
+		ExitCode: code,
+	})
+}
+
+// replay answers a command with the first unused interaction that matches
+// it, learning the current values of the normalized parts of its arguments.
+func (c *Cassette) replay(t testing.TestingT, command string, args []string) (string, string, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, in := range c.interactions {
+		if in.used || !c.matches(in, command, args) {
+			continue
+		}
+		in.used = true
+		for i := range args {
+			c.learn(in.Args[i], args[i])
+		}
+		stdout, stderr := c.rewrite(in.Stdout), c.rewrite(in.Stderr)
+		if in.ExitCode != 0 {
+			return stdout, stderr, &ExitError{Code: in.ExitCode}
+		}
+		return stdout, stderr, nil
+	}
+	err := fmt.Errorf("cassette %s: no recorded interaction for %s %s", c.Path, command, strings.Join(args, " "))
+	t.Errorf("%v", err)
+	return "", "", err
+}
+
+func (c *Cassette) matches(in *Interaction, command string, args []string) bool {
+	if in.Command != command || len(in.Args) != len(args) {
+		return false
+	}
+	for i := range args {
+		if c.normalize(in.Args[i], c.recorded) != c.normalize(args[i], c.vars) {
+			return false
+		}
+	}
+	return true
+}
+
+// normalize replaces the values of vars by their names and the matches of
+// the normalizers by a placeholder.
+func (c *Cassette) normalize(s string, vars map[string]string) string {
+	for _, name := range byLength(vars) {
+		if value := vars[name]; value != "" {
+			s = strings.ReplaceAll(s, value, "<"+name+">")
+		}
+	}
+	for _, re := range c.normalizers {
+		s = re.ReplaceAllString(s, "<*>")
+	}
+	return s
+}
+
+// learn pairs the normalized parts of a recorded argument with those of the
+// current one.
+func (c *Cassette) learn(recorded, current string) {
+	for _, re := range c.normalizers {
+		was, is := re.FindAllString(recorded, -1), re.FindAllString(current, -1)
+		for i := 0; i < len(was) && i < len(is); i++ {
+			if was[i] != is[i] {
+				c.replacements[was[i]] = is[i]
+			}
+		}
+	}
+}
+
+// rewrite replaces the recorded values learned so far with the current ones,
+// longest first.
+func (c *Cassette) rewrite(s string) string {
+	if len(c.replacements) == 0 || s == "" {
+		return s
+	}
+	pairs := make([]string, 0, 2*len(c.replacements))
+	for _, old := range byKeyLength(c.replacements) {
+		pairs = append(pairs, old, c.replacements[old])
+	}
+	return strings.NewReplacer(pairs...).Replace(s)
+}
+
+// byLength returns the names of vars, the longest value first, so that a
+// value is replaced before the values it contains.
+func byLength(vars map[string]string) []string {
+	names := make([]string, 0, len(vars))
+	for name := range vars {
+		names = append(names, name)
+	}
+	sort.Slice(names, func(i, j int) bool { return len(vars[names[i]]) > len(vars[names[j]]) })
+	return names
+}
+
+// byKeyLength returns the keys of m, the longest first.
+func byKeyLength(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Slice(keys, func(i, j int) bool { return len(keys[i]) > len(keys[j]) })
+	return keys
+}
+
+// varArgs returns the variables of options as sorted name=value arguments,
+// the values encoded as JSON so that maps keep the same order.
+func varArgs(options *terraform.Options) []string {
+	args := make([]string, 0, len(options.Vars))
+	for name, value := range options.Vars {
+		b, err := json.Marshal(value)
+		if err != nil {
+			b = []byte(fmt.Sprint(value))
+		}
+		args = append(args, name+"="+string(b))
+	}
+	sort.Strings(args)
+	return args
+}
+
+func joinOutput(stdout, stderr string) string {
+	switch {
+	case stderr == "":
+		return stdout
+	case stdout == "":
+		return stderr
+	}
+	return stdout + "\n" + stderr
+}
@@ -0,0 +1,174 @@
+// Copyright 2026 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cassette
+
+import (
+	"fmt"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/GoogleCloudPlatform/cloudnetworking-config-solutions/common_utils/gcloud"
+	"github.com/GoogleCloudPlatform/cloudnetworking-config-solutions/common_utils/report"
+	"github.com/gruntwork-io/terratest/modules/shell"
+	"github.com/gruntwork-io/terratest/modules/terraform"
+	terratesting "github.com/gruntwork-io/terratest/modules/testing"
+)
+
+// projectRunner plays a project holding every network but "missing": a
+// describe echoes the name and project of its arguments back.
+type projectRunner struct {
+	calls int
+}
+
+func (p *projectRunner) Run(_ terratesting.TestingT, cmd shell.Command) (string, string, error) {
+	p.calls++
+	name := cmd.Args[3]
+	if name == "missing" {
+		return "", "ERROR: (gcloud.compute.networks.describe) The resource 'missing' was not found", &ExitError{Code: 1}
+	}
+	project := strings.TrimPrefix(cmd.Args[4], "--project=")
+	return fmt.Sprintf(`{"name":%q,"selfLink":"projects/%s/global/networks/%s"}`, name, project, name), "", nil
+}
+
+// fakeT captures errors so that a test can check them without failing.
+type fakeT struct {
+	*testing.T
+	errors []string
+}
+
+func (f *fakeT) Errorf(format string, args ...any) {
+	f.errors = append(f.errors, fmt.Sprintf(format, args...))
+}
+
+func TestRecordAndReplay(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "TestNetwork.json")
+	project := &projectRunner{}
+	recorder := newCassette(Record, path)
+	recorder.Runner = project
+	client := gcloud.New(recorder.Vary("project", "proj-a"))
+	client.Runner = recorder
+	if _, err := client.Networks.Describe(t, "vpc-ncc-rk2mzq1-3fa9c1"); err != nil {
+		t.Fatalf("Recording Networks.Describe() returned error: %v", err)
+	}
+	if _, err := client.Networks.Describe(t, "missing"); !gcloud.IsNotFound(err) {
+		t.Fatalf("Recording Networks.Describe(missing) error = %v, want = not found", err)
+	}
+	if err := recorder.Save(); err != nil {
+		t.Fatalf("Save() returned error: %v", err)
+	}
+
+	player := newCassette(Replay, path)
+	player.Runner = project
+	if err := player.Load(); err != nil {
+		t.Fatalf("Load() returned error: %v", err)
+	}
+	client = gcloud.New(player.Vary("project", "proj-b"))
+	client.Runner = player
+	network, err := client.Networks.Describe(t, "vpc-ncc-rp4x7tb-09be21")
+	if err != nil {
+		t.Fatalf("Replaying Networks.Describe() returned error: %v", err)
+	}
+	if got, want := network.Name, "vpc-ncc-rp4x7tb-09be21"; got != want {
+		t.Errorf("Replayed network name = %v, want = %v", got, want)
+	}
+	if got, want := network.SelfLink, "projects/proj-b/global/networks/vpc-ncc-rp4x7tb-09be21"; got != want {
+		t.Errorf("Replayed network selfLink = %v, want = %v", got, want)
+	}
+	if _, err := client.Networks.Describe(t, "missing"); !gcloud.IsNotFound(err) {
+		t.Errorf("Replaying Networks.Describe(missing) error = %v, want = not found", err)
+	}
+	if got, want := project.calls, 2; got != want {
+		t.Errorf("Commands run = %v, want = %v", got, want)
+	}
+}
+
+func TestReplayWithoutMatch(t *testing.T) {
+	ft := &fakeT{T: t}
+	player := newCassette(Replay, "TestNetwork.json")
+	player.interactions = []*Interaction{
+		{Command: "gcloud", Args: []string{"compute", "networks", "describe", "vpc"}},
+	}
+	if _, err := player.Output(ft, shell.Command{Command: "gcloud", Args: []string{"compute", "networks", "delete", "vpc"}}); err == nil {
+		t.Errorf("Output() returned no error for a command that was not recorded")
+	}
+	if got, want := len(ft.errors), 1; got != want {
+		t.Errorf("Errors = %v, want = %v", ft.errors, want)
+	}
+}
+
+func TestStartReplaysClientsOfSubtests(t *testing.T) {
+	Dir = t.TempDir()
+	t.Setenv(ModeEnv, string(Replay))
+	recorded := &Cassette{Path: filepath.Join(Dir, "TestStartReplaysClientsOfSubtests.json"), interactions: []*Interaction{
+		{Command: "gcloud", Args: []string{"compute", "networks", "describe", "vpc", "--project=p", "--format=json", "--quiet"}, Stdout: `{"name":"vpc"}`},
+	}}
+	if err := recorded.Save(); err != nil {
+		t.Fatalf("Save() returned error: %v", err)
+	}
+
+	Start(t)
+	t.Run("subtest", func(t *testing.T) {
+		network, err := gcloud.New("p").Networks.Describe(t, "vpc")
+		if err != nil {
+			t.Fatalf("Networks.Describe() returned error: %v", err)
+		}
+		if got, want := network.Name, "vpc"; got != want {
+			t.Errorf("Network name = %v, want = %v", got, want)
+		}
+	})
+}
+
+func TestReplayStartsTerraformPhases(t *testing.T) {
+	player := newCassette(Replay, "TestNetwork.json")
+	player.interactions = []*Interaction{
+		{Command: "terraform", Args: []string{"output", "-json", "id"}, Stdout: `"vpc"`},
+	}
+
+	if got, want := player.OutputJSON(t, &terraform.Options{}, "id"), `"vpc"`; got != want {
+		t.Errorf("OutputJSON() = %v, want = %v", got, want)
+	}
+	if phases := report.For(t).Phases; len(phases) != 1 || phases[0].Name != report.Verify {
+		t.Errorf("Phases = %v, want = a verify phase", phases)
+	}
+}
+
+// fatalT records the message of Fatalf and ends the goroutine of the test,
+// as testing.T does.
+type fatalT struct {
+	*testing.T
+	fatal string
+}
+
+func (f *fatalT) Fatalf(format string, args ...any) {
+	f.fatal = fmt.Sprintf(format, args...)
+	runtime.Goexit()
+}
+
+func TestStartFailsReplayWithoutCassette(t *testing.T) {
+	Dir = t.TempDir()
+	t.Setenv(ModeEnv, string(Replay))
+	ft := &fatalT{T: t}
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		Start(ft)
+	}()
+	<-done
+	if want := "No cassette recorded at " + filepath.Join(Dir, "TestStartFailsReplayWithoutCassette.json"); !strings.HasPrefix(ft.fatal, want) {
+		t.Errorf("Start() fatal = %q, want it to start with %q", ft.fatal, want)
+	}
+}
@@ -0,0 +1,82 @@
+// Copyright 2026 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Command janitor deletes the resources that interrupted integration test runs
+// left behind in a project.
+//
+//	go run ./cmd/janitor -project=PROJECT_ID -ttl=6h -dry-run
+//
+// Resources are selected by the naming patterns of the suites, or -pattern if
+// given, and must be older than -ttl. Without -dry-run they are deleted in
+// dependency order: forwarding rules, backend services, managed instance
+// groups, instances, firewall rules, private services access peerings and
+// ranges, subnetworks and finally networks. With -organization, the
+// hierarchical firewall policies of the tests in that organization are deleted
+// first, after their associations.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/GoogleCloudPlatform/cloudnetworking-config-solutions/common_utils/gcloud"
+	"github.com/GoogleCloudPlatform/cloudnetworking-config-solutions/common_utils/janitor"
+	"github.com/GoogleCloudPlatform/cloudnetworking-config-solutions/common_utils/standalone"
+	"github.com/gruntwork-io/terratest/modules/logger"
+)
+
+// patterns collects repeated -pattern flags.
+type patterns []string
+
+func (p *patterns) String() string { return strings.Join(*p, ",") }
+
+func (p *patterns) Set(v string) error {
+	*p = append(*p, v)
+	return nil
+}
+
+func main() {
+	project := flag.String("project", os.Getenv("TF_VAR_project_id"), "Project to clean up. Defaults to $TF_VAR_project_id.")
+	organization := flag.String("organization", os.Getenv("TF_VAR_organization_id"), "Organization whose test firewall policies are cleaned up too. Defaults to $TF_VAR_organization_id.")
+	ttl := flag.Duration("ttl", 6*time.Hour, "Only delete resources older than this.")
+	dryRun := flag.Bool("dry-run", false, "List the resources that would be deleted without deleting them.")
+	verbose := flag.Bool("v", false, "Log every gcloud command and its output.")
+	var names patterns
+	flag.Var(&names, "pattern", "Regular expression matched against resource names. Repeat to give several. Defaults to the patterns used by the suites.")
+	flag.Parse()
+
+	if *project == "" {
+		fmt.Fprintln(os.Stderr, "janitor: -project is required")
+		os.Exit(2)
+	}
+
+	c := gcloud.New(*project)
+	if !*verbose {
+		c.Logger = logger.Discard
+	}
+	orphans, err := janitor.Run(standalone.T("janitor"), c, janitor.Options{
+		TTL:          *ttl,
+		Patterns:     names,
+		Organization: *organization,
+		DryRun:       *dryRun,
+	})
+	fmt.Printf("%d orphaned resource(s) older than %s in project %s\n", len(orphans), *ttl, *project)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "janitor: %v\n", err)
+		os.Exit(1)
+	}
+}
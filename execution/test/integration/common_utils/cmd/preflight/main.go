@@ -0,0 +1,87 @@
+// Copyright 2026 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Command preflight checks that a project has the APIs, IAM roles and compute
+// quota an integration run needs, and prints the commands that fix what is
+// missing.
+//
+//	go run ./cmd/preflight -project=PROJECT_ID \
+//	  -api=compute.googleapis.com,vpcaccess.googleapis.com \
+//	  -role=roles/compute.networkAdmin \
+//	  -quota=FORWARDING_RULES@us-central1=5 -quota=NETWORKS=3
+//
+// Flags can be repeated or take comma separated lists. A quota is
+// METRIC[@REGION][=NEED]; without a region it is a project wide quota. The
+// command exits with status 1 when a requirement is missing.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/GoogleCloudPlatform/cloudnetworking-config-solutions/common_utils/gcloud"
+	"github.com/GoogleCloudPlatform/cloudnetworking-config-solutions/common_utils/preflight"
+	"github.com/GoogleCloudPlatform/cloudnetworking-config-solutions/common_utils/standalone"
+	"github.com/gruntwork-io/terratest/modules/logger"
+)
+
+// list collects repeated, comma separated flags.
+type list []string
+
+func (l *list) String() string { return strings.Join(*l, ",") }
+
+func (l *list) Set(v string) error {
+	for _, item := range strings.Split(v, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			*l = append(*l, item)
+		}
+	}
+	return nil
+}
+
+func main() {
+	project := flag.String("project", os.Getenv("TF_VAR_project_id"), "Project to check. Defaults to $TF_VAR_project_id.")
+	verbose := flag.Bool("v", false, "Log every gcloud command and its output.")
+	var apis, roles, quotas list
+	flag.Var(&apis, "api", "API that must be enabled, such as compute.googleapis.com.")
+	flag.Var(&roles, "role", "Role the caller must hold on the project or an ancestor, such as roles/compute.networkAdmin.")
+	flag.Var(&quotas, "quota", "Compute quota that must be free, as METRIC[@REGION][=NEED], such as FORWARDING_RULES@us-central1=5.")
+	flag.Parse()
+
+	if *project == "" {
+		fmt.Fprintln(os.Stderr, "preflight: -project is required")
+		os.Exit(2)
+	}
+	req := preflight.Requirements{Project: *project, APIs: apis, Roles: roles}
+	for _, s := range quotas {
+		q, err := preflight.ParseQuota(s)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "preflight: %v\n", err)
+			os.Exit(2)
+		}
+		req.Quotas = append(req.Quotas, q)
+	}
+
+	c := gcloud.New(*project)
+	if !*verbose {
+		c.Logger = logger.Discard
+	}
+	report := preflight.Check(standalone.T("preflight"), c, req)
+	fmt.Print(report)
+	if !report.OK() {
+		os.Exit(1)
+	}
+}
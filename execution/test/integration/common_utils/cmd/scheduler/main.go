@@ -0,0 +1,219 @@
+// Copyright 2026 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Command scheduler runs the integration suites concurrently while keeping
+// the quota they hold together under ceilings, and never runs two suites that
+// change the same organization level object at the same time. Each suite
+// declares its footprint in footprint.yaml (see the schedule package).
+//
+//	go run ./cmd/scheduler -project=PROJECT_ID -region=us-central1 \
+//	  -quota=FORWARDING_RULES=10 -parallel=6 -logs=/tmp/suites
+//
+// The ceiling of a metric that no -quota flag sets is the quota of the
+// project that is free when the command starts, read from -project; without
+// -project only the -quota ceilings apply. Each suite runs "go test" in its
+// directory with its output in <logs>/<suite>.log. The command exits with
+// status 1 when a suite fails.
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"os/exec"
+	"os/signal"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/GoogleCloudPlatform/cloudnetworking-config-solutions/common_utils/gcloud"
+	"github.com/GoogleCloudPlatform/cloudnetworking-config-solutions/common_utils/preflight"
+	"github.com/GoogleCloudPlatform/cloudnetworking-config-solutions/common_utils/profile"
+	"github.com/GoogleCloudPlatform/cloudnetworking-config-solutions/common_utils/schedule"
+	"github.com/GoogleCloudPlatform/cloudnetworking-config-solutions/common_utils/standalone"
+	"github.com/gruntwork-io/terratest/modules/logger"
+)
+
+// list collects repeated, comma separated flags.
+type list []string
+
+func (l *list) String() string { return strings.Join(*l, ",") }
+
+func (l *list) Set(v string) error {
+	for _, item := range strings.Split(v, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			*l = append(*l, item)
+		}
+	}
+	return nil
+}
+
+func main() {
+	env, err := profile.Get()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "scheduler: %v\n", err)
+		os.Exit(2)
+	}
+	root := flag.String("root", "..", "Directory the suites are found in, the integration directory.")
+	project := flag.String("project", env.Projects.Endpoint, "Project whose free quota sets the ceilings not given by -quota. Defaults to the endpoint project of the test profile.")
+	region := flag.String("region", env.Regions.Default, "Region of the regional quotas of footprints that name no region. Defaults to the default region of the test profile.")
+	parallel := flag.Int("parallel", 4, "Maximum number of suites running at once.")
+	run := flag.String("run", "", "Only run the suites whose directory matches this regular expression.")
+	timeout := flag.String("timeout", "3h", "Timeout of each suite, passed to go test.")
+	logs := flag.String("logs", "suite-logs", "Directory the output of each suite is written to.")
+	var quotas list
+	flag.Var(&quotas, "quota", "Ceiling of a quota as METRIC[@REGION]=AMOUNT, such as FORWARDING_RULES=10.")
+	flag.Parse()
+
+	suites, err := schedule.Discover(*root)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "scheduler: %v\n", err)
+		os.Exit(2)
+	}
+	if *run != "" {
+		re, err := regexp.Compile(*run)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "scheduler: -run: %v\n", err)
+			os.Exit(2)
+		}
+		var matched []schedule.Suite
+		for _, s := range suites {
+			if re.MatchString(s.Dir) {
+				matched = append(matched, s)
+			}
+		}
+		suites = matched
+	}
+	ceilings := map[string]float64{}
+	for _, s := range quotas {
+		q, err := preflight.ParseQuota(s)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "scheduler: %v\n", err)
+			os.Exit(2)
+		}
+		ceilings[schedule.Key(q)] = q.Need
+	}
+	if *project != "" {
+		if err := freeQuota(*project, *region, schedule.Keys(suites), ceilings); err != nil {
+			fmt.Fprintf(os.Stderr, "scheduler: reading the quota of %s: %v\n", *project, err)
+			os.Exit(1)
+		}
+	}
+	for _, key := range schedule.Keys(suites) {
+		if ceiling, ok := ceilings[key]; ok {
+			fmt.Printf("scheduler: ceiling of %s is %g\n", key, ceiling)
+		}
+	}
+	if err := os.MkdirAll(*logs, 0o755); err != nil {
+		fmt.Fprintf(os.Stderr, "scheduler: %v\n", err)
+		os.Exit(1)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	s := &schedule.Scheduler{
+		Ceilings: ceilings,
+		Parallel: *parallel,
+		Run: func(ctx context.Context, suite schedule.Suite) error {
+			return goTest(ctx, filepath.Join(*root, filepath.FromSlash(suite.Dir)), *timeout,
+				filepath.Join(*logs, strings.ReplaceAll(suite.Dir, "/", "_")+".log"))
+		},
+		Logf: func(format string, args ...any) {
+			fmt.Printf("%s scheduler: %s\n", time.Now().Format(time.TimeOnly), fmt.Sprintf(format, args...))
+		},
+	}
+	results := s.RunAll(ctx, suites)
+	fmt.Print(schedule.Summary(results))
+	for _, r := range results {
+		if r.Err != nil {
+			os.Exit(1)
+		}
+	}
+}
+
+// freeQuota sets the ceiling of each key without one to the quota of project
+// that is free now. Metrics that are not project wide are read in region,
+// unless the key names a region.
+func freeQuota(project, region string, keys []string, ceilings map[string]float64) error {
+	c := gcloud.New(project)
+	c.Logger = logger.Discard
+	byRegion := map[string][]gcloud.Quota{}
+	list := func(region string) ([]gcloud.Quota, error) {
+		if quotas, ok := byRegion[region]; ok {
+			return quotas, nil
+		}
+		quotas, err := c.Quotas.List(standalone.T("scheduler"), region)
+		byRegion[region] = quotas
+		return quotas, err
+	}
+	find := func(quotas []gcloud.Quota, metric string) (gcloud.Quota, bool) {
+		for _, q := range quotas {
+			if q.Metric == metric {
+				return q, true
+			}
+		}
+		return gcloud.Quota{}, false
+	}
+	for _, key := range keys {
+		if _, ok := ceilings[key]; ok {
+			continue
+		}
+		metric, keyRegion, _ := strings.Cut(key, "@")
+		if keyRegion == "" {
+			global, err := list("")
+			if err != nil {
+				return err
+			}
+			if q, ok := find(global, metric); ok {
+				ceilings[key] = q.Limit - q.Usage
+				continue
+			}
+			keyRegion = region
+		}
+		if keyRegion == "" {
+			continue
+		}
+		regional, err := list(keyRegion)
+		if err != nil {
+			return err
+		}
+		if q, ok := find(regional, metric); ok {
+			ceilings[key] = q.Limit - q.Usage
+		}
+	}
+	return nil
+}
+
+// goTest runs the tests of the package in dir and writes their output to
+// logPath.
+func goTest(ctx context.Context, dir, timeout, logPath string) error {
+	out, err := os.Create(logPath)
+	if err != nil {
+		return err
+	}
+	defer out.Close()
+	cmd := exec.CommandContext(ctx, "go", "test", "-v", "-count=1", "-timeout="+timeout, ".")
+	cmd.Dir = dir
+	cmd.Stdout, cmd.Stderr = out, out
+	// An interrupt reaches the test binary through the process group, so
+	// that its cleanups run; only kill it if it does not exit.
+	cmd.Cancel = func() error { return nil }
+	cmd.WaitDelay = 10 * time.Minute
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("%v, see %s", err, logPath)
+	}
+	return nil
+}
@@ -0,0 +1,509 @@
+// Copyright 2026 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package fakeapi
+
+import (
+	"fmt"
+	"net/http"
+	"net/netip"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// SelfLinkBase is the prefix of the selfLinks the fake returns. It is the one
+// of the real API, whichever address the fake listens on, so that outputs
+// holding links look the same as in a project.
+const SelfLinkBase = "https://www.googleapis.com/compute/v1/"
+
+// kinds are the singular names of the collections the fake serves, used in
+// the kind of resources and lists. Other collections are refused.
+var kinds = map[string]string{
+	"networks":        "network",
+	"subnetworks":     "subnetwork",
+	"firewalls":       "firewall",
+	"addresses":       "address",
+	"forwardingRules": "forwardingRule",
+	"routers":         "router",
+	"routes":          "route",
+}
+
+// linkFields hold references to other resources. They are stored as selfLinks
+// whether the request used a selfLink or a relative name, and a resource
+// cannot be deleted while another one refers to it through them.
+var linkFields = map[string]bool{"network": true, "subnetwork": true}
+
+// linkPattern matches links to compute resources served at any address.
+var linkPattern = regexp.MustCompile(`^https?://[^/]+/compute/(?:v1|beta|alpha)/(projects/.*)$`)
+
+// canonical returns the selfLink form of a link.
+func canonical(field, v string) string {
+	if m := linkPattern.FindStringSubmatch(v); m != nil {
+		return SelfLinkBase + m[1]
+	}
+	if linkFields[field] && strings.HasPrefix(v, "projects/") {
+		return SelfLinkBase + v
+	}
+	return v
+}
+
+func canonicalize(body map[string]any) {
+	for k, v := range body {
+		if s, ok := v.(string); ok {
+			body[k] = canonical(k, s)
+		}
+	}
+}
+
+// computeDefaults fill the output only fields and the defaults the API sets
+// on new resources of a collection.
+var computeDefaults = map[string]func(s *Server, res map[string]any) *apiError{
+	"networks": func(s *Server, res map[string]any) *apiError {
+		setDefault(res, "autoCreateSubnetworks", false)
+		setDefault(res, "routingConfig", map[string]any{"routingMode": "REGIONAL"})
+		setDefault(res, "mtu", 1460)
+		setDefault(res, "networkFirewallPolicyEnforcementOrder", "AFTER_CLASSIC_FIREWALL")
+		return nil
+	},
+	"subnetworks": func(s *Server, res map[string]any) *apiError {
+		prefix, err := netip.ParsePrefix(str(res["ipCidrRange"]))
+		if err != nil {
+			return badRequest("invalid", "Invalid value for field 'resource.ipCidrRange': '%s'.", str(res["ipCidrRange"]))
+		}
+		res["gatewayAddress"] = prefix.Masked().Addr().Next().String()
+		setDefault(res, "privateIpGoogleAccess", false)
+		setDefault(res, "purpose", "PRIVATE")
+		setDefault(res, "stackType", "IPV4_ONLY")
+		network, ok := s.resources[relative(str(res["network"]))]
+		if !ok {
+			return badRequest("required", "Required field 'resource.network' not specified")
+		}
+		network["subnetworks"] = append(list(network["subnetworks"]), res["selfLink"])
+		return nil
+	},
+	"firewalls": func(s *Server, res map[string]any) *apiError {
+		setDefault(res, "direction", "INGRESS")
+		setDefault(res, "priority", 1000)
+		setDefault(res, "disabled", false)
+		return nil
+	},
+	"addresses": func(s *Server, res map[string]any) *apiError {
+		n := s.lastID
+		if _, ok := res["prefixLength"]; ok {
+			setDefault(res, "address", fmt.Sprintf("10.%d.0.0", 100+n%100))
+		} else {
+			setDefault(res, "address", fmt.Sprintf("10.100.%d.%d", n/256%256, n%256))
+		}
+		setDefault(res, "addressType", "EXTERNAL")
+		res["status"] = "RESERVED"
+		return nil
+	},
+	"forwardingRules": func(s *Server, res map[string]any) *apiError {
+		n := s.lastID
+		setDefault(res, "IPAddress", fmt.Sprintf("10.101.%d.%d", n/256%256, n%256))
+		setDefault(res, "IPProtocol", "TCP")
+		setDefault(res, "loadBalancingScheme", "EXTERNAL")
+		return nil
+	},
+	"routes": func(s *Server, res map[string]any) *apiError {
+		setDefault(res, "priority", 1000)
+		return nil
+	},
+}
+
+// compute serves /compute/v1/projects/{project}/{scope}/{collection}/...,
+// where scope is "global", "regions/{region}" or "zones/{zone}".
+func (s *Server) compute(w http.ResponseWriter, r *http.Request) {
+	segs := strings.Split(strings.Trim(strings.TrimPrefix(r.URL.Path, "/compute/v1/"), "/"), "/")
+	if len(segs) < 2 || segs[0] != "projects" {
+		unsupported(w, r)
+		return
+	}
+	if len(segs) == 2 && r.Method == http.MethodGet {
+		reply(w, map[string]any{
+			"kind":     "compute#project",
+			"name":     segs[1],
+			"id":       ProjectNumber(segs[1]),
+			"selfLink": SelfLinkBase + "projects/" + segs[1],
+		})
+		return
+	}
+	var scope string
+	var rest []string
+	switch {
+	case len(segs) > 3 && segs[2] == "global":
+		scope, rest = strings.Join(segs[:3], "/"), segs[3:]
+	case len(segs) > 4 && (segs[2] == "regions" || segs[2] == "zones"):
+		scope, rest = strings.Join(segs[:4], "/"), segs[4:]
+	default:
+		unsupported(w, r)
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	col := rest[0]
+	if col == "operations" {
+		s.operation(w, r, scope, rest)
+		return
+	}
+	if _, ok := kinds[col]; !ok {
+		unsupported(w, r)
+		return
+	}
+	var body map[string]any
+	if r.Method == http.MethodPost || r.Method == http.MethodPatch || r.Method == http.MethodPut {
+		var err *apiError
+		if body, err = decode(r); err != nil {
+			fail(w, err)
+			return
+		}
+	}
+	var out any
+	var err *apiError
+	switch {
+	case len(rest) == 1 && r.Method == http.MethodGet:
+		out = s.list(scope, col, r.URL.Query().Get("filter"))
+	case len(rest) == 1 && r.Method == http.MethodPost:
+		out, err = s.insert(scope, col, body)
+	case len(rest) == 2 && r.Method == http.MethodGet:
+		out, err = s.get(scope + "/" + col + "/" + rest[1])
+	case len(rest) == 2 && r.Method == http.MethodDelete:
+		out, err = s.delete(scope, col, rest[1])
+	case len(rest) == 2 && (r.Method == http.MethodPatch || r.Method == http.MethodPut):
+		out, err = s.update(scope, col, rest[1], body, r.Method == http.MethodPut)
+	case len(rest) == 3 && r.Method == http.MethodPost:
+		out, err = s.custom(scope, col, rest[1], rest[2], body)
+	default:
+		unsupported(w, r)
+		return
+	}
+	if err != nil {
+		fail(w, err)
+		return
+	}
+	reply(w, out)
+}
+
+func (s *Server) get(name string) (map[string]any, *apiError) {
+	res, ok := s.resources[name]
+	if !ok {
+		return nil, notFound("The resource '%s' was not found", name)
+	}
+	return res, nil
+}
+
+func (s *Server) list(scope, col, filter string) map[string]any {
+	prefix := scope + "/" + col + "/"
+	match := filterFunc(filter)
+	var names []string
+	for name, res := range s.resources {
+		if strings.HasPrefix(name, prefix) && match(res) {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	items := make([]any, 0, len(names))
+	for _, name := range names {
+		items = append(items, s.resources[name])
+	}
+	return map[string]any{
+		"kind":     "compute#" + kinds[col] + "List",
+		"id":       prefix,
+		"items":    items,
+		"selfLink": SelfLinkBase + scope + "/" + col,
+	}
+}
+
+// filterPattern matches the single comparisons the fake understands, such as
+// `network="..."` or `name eq ".*foo.*"`. Other filters match everything.
+var filterPattern = regexp.MustCompile(`^\s*\(?\s*(\w+)\s*(=|eq)\s*"?([^"]*?)"?\s*\)?\s*$`)
+
+func filterFunc(filter string) func(map[string]any) bool {
+	m := filterPattern.FindStringSubmatch(filter)
+	if m == nil {
+		return func(map[string]any) bool { return true }
+	}
+	field, op, value := m[1], m[2], canonical(m[1], m[3])
+	if op == "eq" {
+		re, err := regexp.Compile("^(?:" + value + ")$")
+		if err != nil {
+			return func(map[string]any) bool { return false }
+		}
+		return func(res map[string]any) bool { return re.MatchString(str(res[field])) }
+	}
+	return func(res map[string]any) bool { return str(res[field]) == value }
+}
+
+func (s *Server) insert(scope, col string, body map[string]any) (map[string]any, *apiError) {
+	name := str(body["name"])
+	if name == "" {
+		return nil, badRequest("required", "Required field 'resource.name' not specified")
+	}
+	key := scope + "/" + col + "/" + name
+	if _, ok := s.resources[key]; ok {
+		return nil, alreadyExists("The resource '%s' already exists", key)
+	}
+	canonicalize(body)
+	for field := range linkFields {
+		if link := str(body[field]); link != "" {
+			if _, ok := s.resources[relative(link)]; !ok {
+				return nil, notFound("The resource '%s' was not found", relative(link))
+			}
+		}
+	}
+	id := s.nextID()
+	body["kind"] = "compute#" + kinds[col]
+	body["id"] = strconv.Itoa(id)
+	body["creationTimestamp"] = now()
+	body["selfLink"] = SelfLinkBase + key
+	setLocation(body, scope)
+	if col == "networks" || col == "subnetworks" {
+		body["fingerprint"] = fingerprint(id)
+	}
+	if fn := computeDefaults[col]; fn != nil {
+		if err := fn(s, body); err != nil {
+			return nil, err
+		}
+	}
+	s.resources[key] = body
+	return s.computeOperation(scope, "insert", key), nil
+}
+
+func (s *Server) delete(scope, col, name string) (map[string]any, *apiError) {
+	key := scope + "/" + col + "/" + name
+	res, err := s.get(key)
+	if err != nil {
+		return nil, err
+	}
+	link := str(res["selfLink"])
+	for other, o := range s.resources {
+		for field := range linkFields {
+			if str(o[field]) == link {
+				return nil, badRequest("resourceInUseByAnotherResource", "The %s resource '%s' is already being used by '%s'", kinds[col], key, other)
+			}
+		}
+	}
+	if network, ok := s.resources[relative(str(res["network"]))]; ok && col == "subnetworks" {
+		network["subnetworks"] = without(list(network["subnetworks"]), link)
+	}
+	delete(s.resources, key)
+	return s.computeOperation(scope, "delete", key), nil
+}
+
+// readOnly are the fields a PUT keeps from the stored resource.
+var readOnly = []string{"kind", "id", "creationTimestamp", "selfLink", "region", "zone", "subnetworks", "peerings", "gatewayAddress"}
+
+func (s *Server) update(scope, col, name string, body map[string]any, replace bool) (map[string]any, *apiError) {
+	key := scope + "/" + col + "/" + name
+	res, err := s.get(key)
+	if err != nil {
+		return nil, err
+	}
+	canonicalize(body)
+	if replace {
+		for _, field := range readOnly {
+			if v, ok := res[field]; ok {
+				body[field] = v
+			}
+		}
+		res = body
+		s.resources[key] = res
+	} else {
+		for k, v := range body {
+			res[k] = v
+		}
+	}
+	if _, ok := res["fingerprint"]; ok {
+		res["fingerprint"] = fingerprint(s.nextID())
+	}
+	return s.computeOperation(scope, "patch", key), nil
+}
+
+// custom runs the custom methods of the collections, such as
+// networks.addPeering.
+func (s *Server) custom(scope, col, name, method string, body map[string]any) (map[string]any, *apiError) {
+	key := scope + "/" + col + "/" + name
+	res, err := s.get(key)
+	if err != nil {
+		return nil, err
+	}
+	switch col + "." + method {
+	case "networks.addPeering":
+		peering, _ := body["networkPeering"].(map[string]any)
+		if peering == nil {
+			peering = map[string]any{"name": body["name"], "network": body["peerNetwork"], "exchangeSubnetRoutes": body["autoCreateRoutes"]}
+		}
+		if err := addPeering(res, peering); err != nil {
+			return nil, err
+		}
+	case "networks.removePeering":
+		if !removePeering(res, str(body["name"])) {
+			return nil, notFound("There is no peering with name '%s' in network '%s'", str(body["name"]), key)
+		}
+	case "networks.updatePeering":
+		update, _ := body["networkPeering"].(map[string]any)
+		peering := findPeering(res, str(update["name"]))
+		if peering == nil {
+			return nil, notFound("There is no peering with name '%s' in network '%s'", str(update["name"]), key)
+		}
+		for k, v := range update {
+			peering[k] = v
+		}
+	case "subnetworks.setPrivateIpGoogleAccess":
+		res["privateIpGoogleAccess"] = body["privateIpGoogleAccess"]
+	case "subnetworks.expandIpCidrRange":
+		res["ipCidrRange"] = body["ipCidrRange"]
+	default:
+		if method != "setLabels" {
+			return nil, &apiError{http.StatusNotImplemented, "UNIMPLEMENTED", "notImplemented",
+				fmt.Sprintf("the fake API does not support %s.%s", col, method)}
+		}
+		res["labels"] = body["labels"]
+		res["labelFingerprint"] = fingerprint(s.nextID())
+	}
+	if _, ok := res["fingerprint"]; ok {
+		res["fingerprint"] = fingerprint(s.nextID())
+	}
+	return s.computeOperation(scope, method, key), nil
+}
+
+func addPeering(network, peering map[string]any) *apiError {
+	name := str(peering["name"])
+	if name == "" {
+		return badRequest("required", "Required field 'networkPeering.name' not specified")
+	}
+	if findPeering(network, name) != nil {
+		return badRequest("invalid", "There is a peering with name '%s' in network '%s'", name, relative(str(network["selfLink"])))
+	}
+	peering["network"] = canonical("network", str(peering["network"]))
+	setDefault(peering, "exchangeSubnetRoutes", true)
+	setDefault(peering, "exportCustomRoutes", false)
+	setDefault(peering, "importCustomRoutes", false)
+	peering["state"] = "ACTIVE"
+	peering["stateDetails"] = "[" + now() + "]: Connected."
+	network["peerings"] = append(list(network["peerings"]), peering)
+	return nil
+}
+
+func findPeering(network map[string]any, name string) map[string]any {
+	for _, p := range list(network["peerings"]) {
+		if p, ok := p.(map[string]any); ok && str(p["name"]) == name {
+			return p
+		}
+	}
+	return nil
+}
+
+func removePeering(network map[string]any, name string) bool {
+	peering := findPeering(network, name)
+	if peering == nil {
+		return false
+	}
+	var kept []any
+	for _, p := range list(network["peerings"]) {
+		if p, ok := p.(map[string]any); !ok || str(p["name"]) != name {
+			kept = append(kept, p)
+		}
+	}
+	network["peerings"] = kept
+	return true
+}
+
+// operation serves the operations of a scope, which are all done.
+func (s *Server) operation(w http.ResponseWriter, r *http.Request, scope string, rest []string) {
+	if len(rest) < 2 || len(rest) > 3 || (len(rest) == 3 && rest[2] != "wait") {
+		unsupported(w, r)
+		return
+	}
+	op, err := s.get(scope + "/operations/" + rest[1])
+	if err != nil {
+		fail(w, err)
+		return
+	}
+	reply(w, op)
+}
+
+// computeOperation records a done operation of the given type on the
+// resource with the relative name target.
+func (s *Server) computeOperation(scope, kind, target string) map[string]any {
+	id := s.nextID()
+	name := fmt.Sprintf("operation-%d", id)
+	op := map[string]any{
+		"kind":          "compute#operation",
+		"id":            strconv.Itoa(id),
+		"name":          name,
+		"operationType": kind,
+		"targetLink":    SelfLinkBase + target,
+		"status":        "DONE",
+		"progress":      100,
+		"insertTime":    now(),
+		"startTime":     now(),
+		"endTime":       now(),
+		"selfLink":      SelfLinkBase + scope + "/operations/" + name,
+	}
+	if res, ok := s.resources[target]; ok {
+		op["targetId"] = res["id"]
+	}
+	setLocation(op, scope)
+	s.resources[scope+"/operations/"+name] = op
+	return op
+}
+
+// setLocation sets the region or zone link of a regional or zonal resource.
+func setLocation(res map[string]any, scope string) {
+	segs := strings.Split(scope, "/")
+	switch {
+	case len(segs) == 4 && segs[2] == "regions":
+		res["region"] = SelfLinkBase + scope
+	case len(segs) == 4 && segs[2] == "zones":
+		res["zone"] = SelfLinkBase + scope
+	}
+}
+
+// relative returns the relative name of a selfLink.
+func relative(link string) string {
+	return strings.TrimPrefix(link, SelfLinkBase)
+}
+
+func fingerprint(id int) string {
+	return fmt.Sprintf("fp-%08x", id)
+}
+
+func setDefault(res map[string]any, key string, value any) {
+	if _, ok := res[key]; !ok {
+		res[key] = value
+	}
+}
+
+func str(v any) string {
+	s, _ := v.(string)
+	return s
+}
+
+func list(v any) []any {
+	l, _ := v.([]any)
+	return l
+}
+
+func without(l []any, v any) []any {
+	var out []any
+	for _, e := range l {
+		if e != v {
+			out = append(out, e)
+		}
+	}
+	return out
+}
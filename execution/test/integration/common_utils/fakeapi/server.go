@@ -0,0 +1,264 @@
+// Copyright 2026 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package fakeapi serves a local stand-in for the Compute, Service
+// Networking, Network Connectivity and Resource Manager APIs, so that the
+// networking and security stages can be applied and destroyed without a
+// project. The google provider is pointed at it through its custom endpoint
+// variables and gcloud through its API endpoint overrides. Resources live in
+// memory for the duration of a test and every operation completes at once.
+package fakeapi
+
+import (
+	"encoding/json"
+	"fmt"
+	"hash/fnv"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// EnabledEnv is the environment variable that makes Use start the fake. The
+// suites that support it then run end to end without a project.
+const EnabledEnv = "TEST_FAKE_API"
+
+// AccessToken is the token handed to the provider and gcloud. The fake
+// accepts any token.
+const AccessToken = "fake-access-token"
+
+// Server is a running fake. URL is its base address.
+type Server struct {
+	URL string
+
+	mu        sync.Mutex
+	resources map[string]map[string]any
+	lastID    int
+	http      *httptest.Server
+}
+
+// Enabled reports whether $TEST_FAKE_API asks the suites to run against the
+// fake.
+func Enabled() bool {
+	enabled, _ := strconv.ParseBool(os.Getenv(EnabledEnv))
+	return enabled
+}
+
+// Use starts the fake for t when $TEST_FAKE_API is set and returns nil
+// otherwise, so that a test runs against a project unless asked not to.
+func Use(t testing.TB) *Server {
+	t.Helper()
+	if !Enabled() {
+		return nil
+	}
+	return Start(t)
+}
+
+// Start serves the fake for the duration of t and points terraform and gcloud
+// at it through the environment of the test.
+func Start(t testing.TB) *Server {
+	t.Helper()
+	s := New()
+	t.Cleanup(s.Close)
+	token := filepath.Join(t.TempDir(), "access-token")
+	if err := os.WriteFile(token, []byte(AccessToken), 0600); err != nil {
+		t.Fatalf("Writing the fake access token: %v", err)
+	}
+	for k, v := range s.Env() {
+		t.Setenv(k, v)
+	}
+	t.Setenv("CLOUDSDK_AUTH_ACCESS_TOKEN_FILE", token)
+	return s
+}
+
+// New starts a fake. Close stops it.
+func New() *Server {
+	s := &Server{resources: map[string]map[string]any{}}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/compute/v1/", s.compute)
+	mux.HandleFunc("/servicenetworking/v1/", s.serviceNetworking)
+	mux.HandleFunc("/networkconnectivity/v1/", s.networkConnectivity)
+	mux.HandleFunc("/cloudresourcemanager/v1/", s.resourceManager)
+	s.http = httptest.NewServer(mux)
+	s.URL = s.http.URL
+	return s
+}
+
+// Close stops the fake.
+func (s *Server) Close() {
+	s.http.Close()
+}
+
+// Env returns the environment that points the google provider and gcloud at
+// the fake. Suites that do not use Start can set it as the EnvVars of their
+// terraform.Options.
+func (s *Server) Env() map[string]string {
+	return map[string]string{
+		"GOOGLE_OAUTH_ACCESS_TOKEN":                            AccessToken,
+		"GOOGLE_COMPUTE_CUSTOM_ENDPOINT":                       s.URL + "/compute/v1/",
+		"GOOGLE_SERVICE_NETWORKING_CUSTOM_ENDPOINT":            s.URL + "/servicenetworking/v1/",
+		"GOOGLE_NETWORK_CONNECTIVITY_CUSTOM_ENDPOINT":          s.URL + "/networkconnectivity/v1/",
+		"GOOGLE_RESOURCE_MANAGER_CUSTOM_ENDPOINT":              s.URL + "/cloudresourcemanager/v1/",
+		"CLOUDSDK_API_ENDPOINT_OVERRIDES_COMPUTE":              s.URL + "/compute/v1/",
+		"CLOUDSDK_API_ENDPOINT_OVERRIDES_SERVICENETWORKING":    s.URL + "/servicenetworking/",
+		"CLOUDSDK_API_ENDPOINT_OVERRIDES_NETWORKCONNECTIVITY":  s.URL + "/networkconnectivity/",
+		"CLOUDSDK_API_ENDPOINT_OVERRIDES_CLOUDRESOURCEMANAGER": s.URL + "/cloudresourcemanager/",
+	}
+}
+
+// Get returns a copy of the resource with the relative name, such as
+// "projects/p/global/networks/n", or nil when it does not exist.
+func (s *Server) Get(name string) map[string]any {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	r, ok := s.resources[name]
+	if !ok {
+		return nil
+	}
+	return clone(r)
+}
+
+// Names returns the relative names of the resources the fake holds, sorted.
+func (s *Server) Names() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var names []string
+	for name := range s.resources {
+		if !strings.HasPrefix(name, "operations/") && !strings.Contains(name, "/operations/") {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// ProjectNumber returns the number the fake gives to a project. Service
+// Networking names networks by project number.
+func ProjectNumber(project string) string {
+	h := fnv.New32a()
+	h.Write([]byte(project))
+	return strconv.FormatUint(uint64(h.Sum32()%900000000)+100000000, 10)
+}
+
+// project returns the project with the number, or the argument itself when it
+// is not a known number.
+func (s *Server) project(number string) string {
+	for name := range s.resources {
+		if p, ok := strings.CutPrefix(name, "projects/"); ok {
+			p, _, _ = strings.Cut(p, "/")
+			if ProjectNumber(p) == number {
+				return p
+			}
+		}
+	}
+	return number
+}
+
+func (s *Server) resourceManager(w http.ResponseWriter, r *http.Request) {
+	project, ok := strings.CutPrefix(strings.TrimPrefix(r.URL.Path, "/cloudresourcemanager/v1/"), "projects/")
+	if !ok || strings.Contains(project, "/") || r.Method != http.MethodGet {
+		unsupported(w, r)
+		return
+	}
+	reply(w, map[string]any{
+		"projectId":      project,
+		"projectNumber":  ProjectNumber(project),
+		"name":           project,
+		"lifecycleState": "ACTIVE",
+	})
+}
+
+// nextID returns a new unique resource ID. Callers hold s.mu.
+func (s *Server) nextID() int {
+	s.lastID++
+	return s.lastID
+}
+
+func now() string {
+	return time.Now().UTC().Format(time.RFC3339Nano)
+}
+
+// apiError is an error in the format of the Google APIs.
+type apiError struct {
+	code    int
+	status  string
+	reason  string
+	message string
+}
+
+func notFound(format string, args ...any) *apiError {
+	return &apiError{http.StatusNotFound, "NOT_FOUND", "notFound", fmt.Sprintf(format, args...)}
+}
+
+func alreadyExists(format string, args ...any) *apiError {
+	return &apiError{http.StatusConflict, "ALREADY_EXISTS", "alreadyExists", fmt.Sprintf(format, args...)}
+}
+
+func badRequest(reason, format string, args ...any) *apiError {
+	return &apiError{http.StatusBadRequest, "FAILED_PRECONDITION", reason, fmt.Sprintf(format, args...)}
+}
+
+func fail(w http.ResponseWriter, err *apiError) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(err.code)
+	json.NewEncoder(w).Encode(map[string]any{"error": map[string]any{
+		"code":    err.code,
+		"message": err.message,
+		"status":  err.status,
+		"errors":  []any{map[string]any{"message": err.message, "domain": "global", "reason": err.reason}},
+	}})
+}
+
+// unsupported answers requests the fake does not implement, so that a missing
+// method shows up in the provider error rather than as a silent no-op.
+func unsupported(w http.ResponseWriter, r *http.Request) {
+	fail(w, &apiError{http.StatusNotImplemented, "UNIMPLEMENTED", "notImplemented",
+		fmt.Sprintf("the fake API does not support %s %s", r.Method, r.URL.Path)})
+}
+
+func reply(w http.ResponseWriter, body any) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(body)
+}
+
+// decode reads the JSON object in the body of r.
+func decode(r *http.Request) (map[string]any, *apiError) {
+	body := map[string]any{}
+	b, err := io.ReadAll(r.Body)
+	if err != nil {
+		return nil, badRequest("invalid", "reading the request: %v", err)
+	}
+	if len(b) == 0 {
+		return body, nil
+	}
+	if err := json.Unmarshal(b, &body); err != nil {
+		return nil, badRequest("invalid", "invalid JSON payload: %v", err)
+	}
+	return body, nil
+}
+
+// clone returns a deep copy of a resource.
+func clone(r map[string]any) map[string]any {
+	b, _ := json.Marshal(r)
+	var out map[string]any
+	json.Unmarshal(b, &out)
+	return out
+}
@@ -0,0 +1,241 @@
+// Copyright 2026 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package fakeapi
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"os"
+	"reflect"
+	"testing"
+)
+
+// do sends a request to the fake and returns the status and decoded body.
+func do(t *testing.T, s *Server, method, path string, body any) (int, map[string]any) {
+	t.Helper()
+	var b []byte
+	if body != nil {
+		var err error
+		if b, err = json.Marshal(body); err != nil {
+			t.Fatal(err)
+		}
+	}
+	req, err := http.NewRequest(method, s.URL+path, bytes.NewReader(b))
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	out := map[string]any{}
+	if err := json.NewDecoder(resp.Body).Decode(&out); err != nil {
+		t.Fatalf("%s %s: decoding the response: %v", method, path, err)
+	}
+	return resp.StatusCode, out
+}
+
+// mustDo is do for requests expected to succeed.
+func mustDo(t *testing.T, s *Server, method, path string, body any) map[string]any {
+	t.Helper()
+	code, out := do(t, s, method, path, body)
+	if code != http.StatusOK {
+		t.Fatalf("%s %s = %d %v, want = 200", method, path, code, out)
+	}
+	return out
+}
+
+func TestNetworkLifecycle(t *testing.T) {
+	s := New()
+	defer s.Close()
+	const base = "/compute/v1/projects/p/"
+
+	op := mustDo(t, s, "POST", base+"global/networks", map[string]any{"name": "net", "autoCreateSubnetworks": false})
+	if op["status"] != "DONE" {
+		t.Errorf("insert operation status = %v, want = DONE", op["status"])
+	}
+	if got := mustDo(t, s, "GET", base+"global/operations/"+op["name"].(string), nil); got["targetLink"] != SelfLinkBase+"projects/p/global/networks/net" {
+		t.Errorf("operation targetLink = %v, want = the network", got["targetLink"])
+	}
+	if code, _ := do(t, s, "POST", base+"global/networks", map[string]any{"name": "net"}); code != http.StatusConflict {
+		t.Errorf("second insert = %d, want = 409", code)
+	}
+	// The provider sends links built from its custom endpoint.
+	mustDo(t, s, "POST", base+"regions/us-west2/subnetworks", map[string]any{
+		"name":        "subnet",
+		"ipCidrRange": "10.0.0.0/24",
+		"network":     s.URL + "/compute/v1/projects/p/global/networks/net",
+	})
+	subnet := mustDo(t, s, "GET", base+"regions/us-west2/subnetworks/subnet", nil)
+	want := map[string]any{
+		"network":        SelfLinkBase + "projects/p/global/networks/net",
+		"region":         SelfLinkBase + "projects/p/regions/us-west2",
+		"selfLink":       SelfLinkBase + "projects/p/regions/us-west2/subnetworks/subnet",
+		"gatewayAddress": "10.0.0.1",
+	}
+	for k, v := range want {
+		if subnet[k] != v {
+			t.Errorf("subnet %s = %v, want = %v", k, subnet[k], v)
+		}
+	}
+	network := mustDo(t, s, "GET", base+"global/networks/net", nil)
+	if got, want := network["subnetworks"], []any{subnet["selfLink"]}; !reflect.DeepEqual(got, want) {
+		t.Errorf("network subnetworks = %v, want = %v", got, want)
+	}
+
+	code, out := do(t, s, "DELETE", base+"global/networks/net", nil)
+	if code != http.StatusBadRequest {
+		t.Errorf("deleting a network in use = %d %v, want = 400", code, out)
+	}
+	mustDo(t, s, "DELETE", base+"regions/us-west2/subnetworks/subnet", nil)
+	mustDo(t, s, "DELETE", base+"global/networks/net", nil)
+	if code, _ := do(t, s, "GET", base+"global/networks/net", nil); code != http.StatusNotFound {
+		t.Errorf("GET of a deleted network = %d, want = 404", code)
+	}
+	if got := s.Names(); len(got) != 0 {
+		t.Errorf("Names() = %v, want none", got)
+	}
+}
+
+func TestListFilter(t *testing.T) {
+	s := New()
+	defer s.Close()
+	const base = "/compute/v1/projects/p/global/"
+	mustDo(t, s, "POST", base+"networks", map[string]any{"name": "a"})
+	mustDo(t, s, "POST", base+"networks", map[string]any{"name": "b"})
+	for _, name := range []string{"a", "b"} {
+		mustDo(t, s, "POST", base+"firewalls", map[string]any{"name": "allow-" + name, "network": "projects/p/global/networks/" + name})
+	}
+
+	out := mustDo(t, s, "GET", base+"firewalls?filter="+`network%3D%22projects%2Fp%2Fglobal%2Fnetworks%2Fb%22`, nil)
+	items := out["items"].([]any)
+	if len(items) != 1 || items[0].(map[string]any)["name"] != "allow-b" {
+		t.Errorf("filtered firewalls = %v, want = [allow-b]", items)
+	}
+	if got := items[0].(map[string]any)["priority"]; got != float64(1000) {
+		t.Errorf("firewall priority = %v, want = 1000", got)
+	}
+}
+
+func TestPatchAndPeering(t *testing.T) {
+	s := New()
+	defer s.Close()
+	const base = "/compute/v1/projects/p/"
+	mustDo(t, s, "POST", base+"global/networks", map[string]any{"name": "net"})
+	mustDo(t, s, "POST", base+"regions/r/routers", map[string]any{"name": "router", "network": "projects/p/global/networks/net"})
+	mustDo(t, s, "PATCH", base+"regions/r/routers/router", map[string]any{"nats": []any{map[string]any{"name": "nat"}}})
+	if got := s.Get("projects/p/regions/r/routers/router")["nats"]; len(got.([]any)) != 1 {
+		t.Errorf("router nats = %v, want one", got)
+	}
+
+	mustDo(t, s, "POST", base+"global/networks/net/addPeering", map[string]any{"networkPeering": map[string]any{"name": "peer", "network": "projects/q/global/networks/other"}})
+	mustDo(t, s, "POST", base+"global/networks/net/updatePeering", map[string]any{"networkPeering": map[string]any{"name": "peer", "exportCustomRoutes": true}})
+	peering := s.Get("projects/p/global/networks/net")["peerings"].([]any)[0].(map[string]any)
+	if peering["state"] != "ACTIVE" || peering["exportCustomRoutes"] != true {
+		t.Errorf("peering = %v, want active and exporting custom routes", peering)
+	}
+	mustDo(t, s, "POST", base+"global/networks/net/removePeering", map[string]any{"name": "peer"})
+	if code, _ := do(t, s, "POST", base+"global/networks/net/removePeering", map[string]any{"name": "peer"}); code != http.StatusNotFound {
+		t.Errorf("removing a missing peering = %d, want = 404", code)
+	}
+	if code, _ := do(t, s, "POST", base+"global/networks/net/switchToCustomMode", nil); code != http.StatusNotImplemented {
+		t.Errorf("unsupported method = %d, want = 501", code)
+	}
+}
+
+func TestServiceNetworkingConnection(t *testing.T) {
+	s := New()
+	defer s.Close()
+	project := mustDo(t, s, "GET", "/cloudresourcemanager/v1/projects/p", nil)
+	number := project["projectNumber"].(string)
+	if number != ProjectNumber("p") {
+		t.Errorf("projectNumber = %v, want = %v", number, ProjectNumber("p"))
+	}
+	mustDo(t, s, "POST", "/compute/v1/projects/p/global/networks", map[string]any{"name": "net"})
+	network := "projects/" + number + "/global/networks/net"
+	const connections = "/servicenetworking/v1/services/servicenetworking.googleapis.com/connections"
+
+	if code, _ := do(t, s, "POST", connections, map[string]any{"network": network, "reservedPeeringRanges": []string{"psa"}}); code != http.StatusBadRequest {
+		t.Errorf("connecting with a missing range = %d, want = 400", code)
+	}
+	mustDo(t, s, "POST", "/compute/v1/projects/p/global/addresses", map[string]any{"name": "psa", "purpose": "VPC_PEERING", "addressType": "INTERNAL", "prefixLength": 20, "network": "projects/p/global/networks/net"})
+	op := mustDo(t, s, "POST", connections, map[string]any{"network": network, "reservedPeeringRanges": []string{"psa"}})
+	if op["done"] != true {
+		t.Errorf("connect operation done = %v, want = true", op["done"])
+	}
+	mustDo(t, s, "GET", "/servicenetworking/v1/"+op["name"].(string), nil)
+
+	list := mustDo(t, s, "GET", connections+"?network="+network, nil)
+	want := []any{map[string]any{
+		"network":               network,
+		"service":               "services/servicenetworking.googleapis.com",
+		"peering":               PeeringName,
+		"reservedPeeringRanges": []any{"psa"},
+	}}
+	if got := list["connections"]; !reflect.DeepEqual(got, want) {
+		t.Errorf("connections = %v, want = %v", got, want)
+	}
+	if findPeering(s.Get("projects/p/global/networks/net"), PeeringName) == nil {
+		t.Errorf("network has no %s peering after connecting", PeeringName)
+	}
+
+	mustDo(t, s, "POST", connections+"/"+PeeringName+":deleteConnection", map[string]any{"consumerNetwork": network})
+	if got := mustDo(t, s, "GET", connections+"?network="+network, nil)["connections"]; len(got.([]any)) != 0 {
+		t.Errorf("connections after deleteConnection = %v, want none", got)
+	}
+	if findPeering(s.Get("projects/p/global/networks/net"), PeeringName) != nil {
+		t.Errorf("network still has the %s peering after deleteConnection", PeeringName)
+	}
+}
+
+func TestServiceConnectionPolicy(t *testing.T) {
+	s := New()
+	defer s.Close()
+	const policies = "/networkconnectivity/v1/projects/p/locations/us-west2/serviceConnectionPolicies"
+	op := mustDo(t, s, "POST", policies+"?serviceConnectionPolicyId=policy", map[string]any{"serviceClass": "gcp-memorystore-redis", "network": "projects/p/global/networks/net"})
+	if op["done"] != true {
+		t.Errorf("create operation done = %v, want = true", op["done"])
+	}
+	mustDo(t, s, "GET", "/networkconnectivity/v1/"+op["name"].(string), nil)
+	policy := mustDo(t, s, "GET", policies+"/policy", nil)
+	if policy["name"] != "projects/p/locations/us-west2/serviceConnectionPolicies/policy" || policy["infrastructure"] != "PSC" {
+		t.Errorf("policy = %v, want it named and with PSC infrastructure", policy)
+	}
+	if got := mustDo(t, s, "GET", policies, nil)["serviceConnectionPolicies"]; len(got.([]any)) != 1 {
+		t.Errorf("listed policies = %v, want one", got)
+	}
+	mustDo(t, s, "DELETE", policies+"/policy", nil)
+	if code, _ := do(t, s, "GET", policies+"/policy", nil); code != http.StatusNotFound {
+		t.Errorf("GET of a deleted policy = %d, want = 404", code)
+	}
+}
+
+func TestUse(t *testing.T) {
+	t.Setenv(EnabledEnv, "")
+	if s := Use(t); s != nil {
+		t.Errorf("Use() with %s unset = %v, want = nil", EnabledEnv, s)
+	}
+	t.Setenv(EnabledEnv, "true")
+	s := Use(t)
+	if s == nil {
+		t.Fatalf("Use() with %s set = nil, want a server", EnabledEnv)
+	}
+	if got, want := os.Getenv("GOOGLE_COMPUTE_CUSTOM_ENDPOINT"), s.URL+"/compute/v1/"; got != want {
+		t.Errorf("GOOGLE_COMPUTE_CUSTOM_ENDPOINT = %v, want = %v", got, want)
+	}
+	mustDo(t, s, "GET", "/compute/v1/projects/p", nil)
+}
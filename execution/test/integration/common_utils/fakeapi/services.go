@@ -0,0 +1,303 @@
+// Copyright 2026 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package fakeapi
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+)
+
+// PeeringName is the name of the peering Service Networking adds to a
+// network it connects.
+const PeeringName = "servicenetworking-googleapis-com"
+
+// tenantNetwork is the producer network the Service Networking peerings
+// point at.
+const tenantNetwork = SelfLinkBase + "projects/servicenetworking-tenant/global/networks/servicenetworking"
+
+// serviceNetworking serves the connections of
+// /servicenetworking/v1/services/{service} and their operations.
+func (s *Server) serviceNetworking(w http.ResponseWriter, r *http.Request) {
+	path := strings.Trim(strings.TrimPrefix(r.URL.Path, "/servicenetworking/v1/"), "/")
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if strings.HasPrefix(path, "operations/") && r.Method == http.MethodGet {
+		op, err := s.get(path)
+		if err != nil {
+			fail(w, err)
+			return
+		}
+		reply(w, op)
+		return
+	}
+	segs := strings.Split(path, "/")
+	if len(segs) < 3 || segs[0] != "services" || segs[2] != "connections" {
+		unsupported(w, r)
+		return
+	}
+	service := segs[1]
+	var body map[string]any
+	if r.Method != http.MethodGet {
+		var err *apiError
+		if body, err = decode(r); err != nil {
+			fail(w, err)
+			return
+		}
+	}
+	var out any
+	var err *apiError
+	switch {
+	case len(segs) == 3 && r.Method == http.MethodGet:
+		out = s.connections(service, r.URL.Query().Get("network"))
+	case len(segs) == 3 && r.Method == http.MethodPost:
+		out, err = s.connect(service, body)
+	case len(segs) == 4 && r.Method == http.MethodPatch:
+		out, err = s.updateConnection(service, body)
+	case len(segs) == 4 && r.Method == http.MethodPost && strings.HasSuffix(segs[3], ":deleteConnection"):
+		out, err = s.disconnect(service, str(body["consumerNetwork"]))
+	default:
+		unsupported(w, r)
+		return
+	}
+	if err != nil {
+		fail(w, err)
+		return
+	}
+	reply(w, out)
+}
+
+// connectionKey is where the connection of service to network is stored.
+func connectionKey(service, network string) string {
+	return "services/" + service + "/connections/" + network
+}
+
+func (s *Server) connections(service, network string) map[string]any {
+	prefix := connectionKey(service, "")
+	var names []string
+	for name := range s.resources {
+		if strings.HasPrefix(name, prefix) && (network == "" || name == prefix+network) {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	connections := make([]any, 0, len(names))
+	for _, name := range names {
+		connections = append(connections, s.resources[name])
+	}
+	return map[string]any{"connections": connections}
+}
+
+// consumerNetwork returns the network named "projects/{number}/global/networks/{name}"
+// in a connection request.
+func (s *Server) consumerNetwork(network string) (map[string]any, *apiError) {
+	segs := strings.Split(network, "/")
+	if len(segs) != 5 || segs[0] != "projects" || segs[2] != "global" || segs[3] != "networks" {
+		return nil, badRequest("invalid", "Invalid network '%s'", network)
+	}
+	return s.get(fmt.Sprintf("projects/%s/global/networks/%s", s.project(segs[1]), segs[4]))
+}
+
+// checkRanges verifies that the reserved ranges of a connection are global
+// addresses of the project of the network.
+func (s *Server) checkRanges(network map[string]any, ranges []any) *apiError {
+	project := strings.SplitN(relative(str(network["selfLink"])), "/", 3)[1]
+	for _, name := range ranges {
+		if _, ok := s.resources[fmt.Sprintf("projects/%s/global/addresses/%s", project, str(name))]; !ok {
+			return badRequest("invalid", "Allocated IP range '%s' not found in consumer project", str(name))
+		}
+	}
+	return nil
+}
+
+func (s *Server) connect(service string, body map[string]any) (map[string]any, *apiError) {
+	network, err := s.consumerNetwork(str(body["network"]))
+	if err != nil {
+		return nil, err
+	}
+	key := connectionKey(service, str(body["network"]))
+	if _, ok := s.resources[key]; ok {
+		return nil, alreadyExists("Cannot modify allocated ranges in CreateConnection. Please use UpdateConnection.")
+	}
+	if err := s.checkRanges(network, list(body["reservedPeeringRanges"])); err != nil {
+		return nil, err
+	}
+	connection := map[string]any{
+		"network":               body["network"],
+		"service":               "services/" + service,
+		"peering":               PeeringName,
+		"reservedPeeringRanges": list(body["reservedPeeringRanges"]),
+	}
+	if err := addPeering(network, map[string]any{"name": PeeringName, "network": tenantNetwork}); err != nil {
+		return nil, err
+	}
+	s.resources[key] = connection
+	return s.serviceOperation(connection), nil
+}
+
+func (s *Server) updateConnection(service string, body map[string]any) (map[string]any, *apiError) {
+	network, err := s.consumerNetwork(str(body["network"]))
+	if err != nil {
+		return nil, err
+	}
+	connection, err := s.get(connectionKey(service, str(body["network"])))
+	if err != nil {
+		return nil, err
+	}
+	if err := s.checkRanges(network, list(body["reservedPeeringRanges"])); err != nil {
+		return nil, err
+	}
+	connection["reservedPeeringRanges"] = list(body["reservedPeeringRanges"])
+	return s.serviceOperation(connection), nil
+}
+
+func (s *Server) disconnect(service, consumerNetwork string) (map[string]any, *apiError) {
+	key := connectionKey(service, consumerNetwork)
+	if _, err := s.get(key); err != nil {
+		return nil, err
+	}
+	if network, err := s.consumerNetwork(consumerNetwork); err == nil {
+		removePeering(network, PeeringName)
+	}
+	delete(s.resources, key)
+	return s.serviceOperation(map[string]any{}), nil
+}
+
+// serviceOperation records a done Service Networking operation.
+func (s *Server) serviceOperation(response map[string]any) map[string]any {
+	name := fmt.Sprintf("operations/pssn.%d", s.nextID())
+	op := map[string]any{"name": name, "done": true, "response": response}
+	s.resources[name] = op
+	return op
+}
+
+// networkConnectivity serves the resources under
+// /networkconnectivity/v1/projects/{project}/locations/{location}, such as
+// serviceConnectionPolicies and hubs, and their operations.
+func (s *Server) networkConnectivity(w http.ResponseWriter, r *http.Request) {
+	segs := strings.Split(strings.Trim(strings.TrimPrefix(r.URL.Path, "/networkconnectivity/v1/"), "/"), "/")
+	if len(segs) < 5 || len(segs) > 6 || segs[0] != "projects" || segs[2] != "locations" {
+		unsupported(w, r)
+		return
+	}
+	parent := strings.Join(segs[:4], "/")
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var body map[string]any
+	if r.Method == http.MethodPost || r.Method == http.MethodPatch {
+		var err *apiError
+		if body, err = decode(r); err != nil {
+			fail(w, err)
+			return
+		}
+	}
+	name := strings.Join(segs, "/")
+	var out any
+	var err *apiError
+	switch {
+	case len(segs) == 6 && r.Method == http.MethodGet:
+		out, err = s.get(name)
+	case len(segs) == 5 && r.Method == http.MethodGet:
+		out = s.locationList(parent, segs[4])
+	case len(segs) == 5 && r.Method == http.MethodPost:
+		out, err = s.create(parent, segs[4], resourceID(r), body)
+	case len(segs) == 6 && r.Method == http.MethodPatch:
+		out, err = s.patch(parent, name, body)
+	case len(segs) == 6 && r.Method == http.MethodDelete:
+		if _, err = s.get(name); err == nil {
+			delete(s.resources, name)
+			out = s.locationOperation(parent, map[string]any{})
+		}
+	default:
+		unsupported(w, r)
+		return
+	}
+	if err != nil {
+		fail(w, err)
+		return
+	}
+	reply(w, out)
+}
+
+// resourceID returns the ID a create request gives to the new resource, in
+// its query parameter named after the collection, such as
+// serviceConnectionPolicyId.
+func resourceID(r *http.Request) string {
+	for k, v := range r.URL.Query() {
+		if strings.HasSuffix(k, "Id") && k != "requestId" && len(v) > 0 {
+			return v[0]
+		}
+	}
+	return ""
+}
+
+func (s *Server) locationList(parent, col string) map[string]any {
+	prefix := parent + "/" + col + "/"
+	var names []string
+	for name := range s.resources {
+		if strings.HasPrefix(name, prefix) {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	items := make([]any, 0, len(names))
+	for _, name := range names {
+		items = append(items, s.resources[name])
+	}
+	return map[string]any{col: items}
+}
+
+func (s *Server) create(parent, col, id string, body map[string]any) (map[string]any, *apiError) {
+	if id == "" {
+		return nil, badRequest("required", "the ID of the new resource must be set")
+	}
+	name := parent + "/" + col + "/" + id
+	if _, ok := s.resources[name]; ok {
+		return nil, alreadyExists("Resource '%s' already exists", name)
+	}
+	body["name"] = name
+	body["createTime"] = now()
+	body["updateTime"] = now()
+	body["etag"] = fingerprint(s.nextID())
+	if col == "serviceConnectionPolicies" {
+		setDefault(body, "infrastructure", "PSC")
+	}
+	s.resources[name] = body
+	return s.locationOperation(parent, body), nil
+}
+
+func (s *Server) patch(parent, name string, body map[string]any) (map[string]any, *apiError) {
+	res, err := s.get(name)
+	if err != nil {
+		return nil, err
+	}
+	for k, v := range body {
+		if k != "name" && k != "createTime" {
+			res[k] = v
+		}
+	}
+	res["updateTime"] = now()
+	res["etag"] = fingerprint(s.nextID())
+	return s.locationOperation(parent, res), nil
+}
+
+// locationOperation records a done long-running operation of parent.
+func (s *Server) locationOperation(parent string, response map[string]any) map[string]any {
+	name := fmt.Sprintf("%s/operations/operation-%d", parent, s.nextID())
+	op := map[string]any{"name": name, "done": true, "response": response}
+	s.resources[name] = op
+	return op
+}
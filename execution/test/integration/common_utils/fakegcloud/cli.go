@@ -0,0 +1,248 @@
+// Copyright 2026 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package fakegcloud
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// verbs end the command groups of a command line.
+var verbs = map[string]bool{
+	"create": true, "describe": true, "delete": true, "list": true,
+	"connect": true, "enable": true, "set": true, "print-access-token": true,
+	"print-identity-token": true,
+}
+
+// boolFlags never take a value, so the argument following them is not theirs.
+var boolFlags = map[string]bool{
+	"global": true, "quiet": true, "async": true, "enabled": true,
+	"enable-private-ip-google-access": true, "enable-flow-logs": true,
+}
+
+// command is a parsed command line.
+type command struct {
+	groups []string
+	verb   string
+	name   string
+	flags  map[string]string
+}
+
+func parse(args []string) *command {
+	c := &command{flags: map[string]string{}}
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		switch {
+		case strings.HasPrefix(arg, "--"):
+			key, value, ok := strings.Cut(strings.TrimPrefix(arg, "--"), "=")
+			if !ok && !boolFlags[key] && i+1 < len(args) && !strings.HasPrefix(args[i+1], "--") {
+				i++
+				value = args[i]
+			}
+			c.flags[key] = value
+		case c.verb == "" && verbs[arg]:
+			c.verb = arg
+		case c.verb == "":
+			c.groups = append(c.groups, arg)
+		case c.name == "":
+			c.name = arg
+		}
+	}
+	return c
+}
+
+// path returns the command group and verb, such as "compute networks create".
+func (c *command) path() string {
+	return strings.Join(append(append([]string{}, c.groups...), c.verb), " ")
+}
+
+// usage is the prefix of gcloud error messages, such as
+// "(gcloud.compute.networks.create)".
+func (c *command) usage() string {
+	return "(gcloud." + strings.Join(append(append([]string{}, c.groups...), c.verb), ".") + ")"
+}
+
+// cliError is a failure reported on stderr with exit code 1.
+type cliError struct {
+	message string
+}
+
+func (e *cliError) Error() string { return e.message }
+
+func errorf(c *command, format string, args ...any) error {
+	return &cliError{message: fmt.Sprintf("ERROR: %s %s", c.usage(), fmt.Sprintf(format, args...))}
+}
+
+// Main runs the fake gcloud with args against the state named by
+// $FAKE_GCLOUD_STATE and returns its exit code.
+func Main(args []string, stdout, stderr io.Writer) int {
+	path := os.Getenv(StateEnv)
+	if path == "" {
+		fmt.Fprintf(stderr, "ERROR: (gcloud) %s is not set\n", StateEnv)
+		return 2
+	}
+	code := 0
+	err := update(path, func(s *State) error {
+		code = s.Run(args, stdout, stderr)
+		return nil
+	})
+	if err != nil {
+		fmt.Fprintf(stderr, "ERROR: (gcloud) %v\n", err)
+		return 2
+	}
+	return code
+}
+
+// Run runs one command against the state, logs it and returns its exit code.
+func (s *State) Run(args []string, stdout, stderr io.Writer) int {
+	code := s.run(args, stdout, stderr)
+	s.Calls = append(s.Calls, Call{Args: args, ExitCode: code})
+	return code
+}
+
+func (s *State) run(args []string, stdout, stderr io.Writer) int {
+	if f := s.failure(strings.Join(args, " ")); f != nil {
+		fmt.Fprintln(stderr, f.Stderr)
+		if f.ExitCode == 0 {
+			return 1
+		}
+		return f.ExitCode
+	}
+	c := parse(args)
+	out, err := s.dispatch(c)
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		return 1
+	}
+	if text, ok := out.(string); ok {
+		fmt.Fprintln(stdout, text)
+	} else if out != nil {
+		b, err := json.MarshalIndent(out, "", "  ")
+		if err != nil {
+			fmt.Fprintln(stderr, err)
+			return 1
+		}
+		fmt.Fprintln(stdout, string(b))
+	}
+	return 0
+}
+
+func (s *State) dispatch(c *command) (any, error) {
+	switch c.path() {
+	case "config set":
+		return nil, nil
+	case "auth print-access-token":
+		return "fake-access-token", nil
+	case "auth print-identity-token":
+		return "fake-identity-token", nil
+	case "services enable":
+		return nil, nil
+	}
+	col, ok := collections[strings.Join(c.groups, " ")]
+	if !ok {
+		return nil, errorf(c, "the fake gcloud does not support %q", c.path())
+	}
+	project := c.flags["project"]
+	if project == "" {
+		return nil, errorf(c, "the project property must be set")
+	}
+	if c.verb == "list" {
+		return s.list(col, project, c), nil
+	}
+	name := col.name(c)
+	if name == "" {
+		return nil, errorf(c, "argument NAME: Must be specified.")
+	}
+	location, err := col.location(c)
+	if err != nil {
+		return nil, err
+	}
+	r := s.find(col.path, project, location, name)
+	link := col.link(project, location, name)
+	switch c.verb {
+	case "create", "connect":
+		if r != nil {
+			return nil, errorf(c, "Could not fetch resource:\n - The resource '%s' already exists", link)
+		}
+		r = &Resource{Collection: col.path, Project: project, Location: location, Name: name}
+		s.LastID++
+		r.Data = map[string]any{"name": name, "id": strconv.Itoa(s.LastID)}
+		if err := col.create(s, c, r); err != nil {
+			return nil, err
+		}
+		s.Resources = append(s.Resources, r)
+		if col.compute {
+			return []any{r.Data}, nil
+		}
+		return r.Data, nil
+	case "describe":
+		if r == nil {
+			return nil, errorf(c, "Could not fetch resource:\n - The resource '%s' was not found", link)
+		}
+		return r.Data, nil
+	case "delete":
+		if r == nil {
+			return nil, errorf(c, "Could not fetch resource:\n - The resource '%s' was not found", link)
+		}
+		if user := s.user(r); user != nil {
+			return nil, errorf(c, "Could not fetch resource:\n - The %s resource '%s' is already being used by '%s'", col.kind, link, collections[user.Collection].link(user.Project, user.Location, user.Name))
+		}
+		s.remove(r)
+		if col.deleted != nil {
+			col.deleted(s, r)
+		}
+		return nil, nil
+	}
+	return nil, errorf(c, "the fake gcloud does not support %q", c.path())
+}
+
+// list returns the resources of col in project, in the location of the
+// command when it names one, sorted by name. Filters are ignored.
+func (s *State) list(col *collection, project string, c *command) []any {
+	location, _ := col.location(c)
+	if c.flags["region"] == "" && c.flags["zone"] == "" {
+		location = ""
+	}
+	var rs []*Resource
+	for _, r := range s.Resources {
+		if r.Collection == col.path && r.Project == project && (location == "" || r.Location == location) {
+			rs = append(rs, r)
+		}
+	}
+	sort.Slice(rs, func(i, j int) bool { return rs[i].Name < rs[j].Name })
+	out := make([]any, 0, len(rs))
+	for _, r := range rs {
+		out = append(out, r.Data)
+	}
+	return out
+}
+
+// user returns a resource that refers to r and prevents its deletion, or nil.
+func (s *State) user(r *Resource) *Resource {
+	ref := collections[r.Collection].ref(r.Project, r.Location, r.Name)
+	for _, other := range s.Resources {
+		for _, used := range other.Refs {
+			if used == ref {
+				return other
+			}
+		}
+	}
+	return nil
+}
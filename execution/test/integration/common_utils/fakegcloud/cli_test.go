@@ -0,0 +1,128 @@
+// Copyright 2026 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package fakegcloud
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/GoogleCloudPlatform/cloudnetworking-config-solutions/common_utils/gcloud"
+)
+
+// run runs a command line against s and returns its stdout, stderr and exit
+// code.
+func run(s *State, line string) (string, string, int) {
+	var stdout, stderr strings.Builder
+	code := s.Run(strings.Fields(line), &stdout, &stderr)
+	return stdout.String(), stderr.String(), code
+}
+
+func TestParse(t *testing.T) {
+	c := parse(strings.Fields("compute backend-services describe bs-1 --region us-central1 --project p --format=json --global"))
+	if got, want := c.path(), "compute backend-services describe"; got != want {
+		t.Errorf("path() = %v, want = %v", got, want)
+	}
+	if got, want := c.name, "bs-1"; got != want {
+		t.Errorf("name = %v, want = %v", got, want)
+	}
+	want := map[string]string{"region": "us-central1", "project": "p", "format": "json", "global": ""}
+	for k, v := range want {
+		if got, ok := c.flags[k]; !ok || got != v {
+			t.Errorf("flags[%s] = %q, want = %q", k, got, v)
+		}
+	}
+}
+
+func TestNetworkLifecycle(t *testing.T) {
+	s := &State{}
+	stdout, stderr, code := run(s, "compute networks create vpc-1 --subnet-mode=custom --bgp-routing-mode=global --project=p --format=json --quiet")
+	if code != 0 {
+		t.Fatalf("networks create exit code = %v, want = 0: %s", code, stderr)
+	}
+	var created []gcloud.Network
+	if err := json.Unmarshal([]byte(stdout), &created); err != nil || len(created) != 1 {
+		t.Fatalf("networks create printed %q, want a list of one network: %v", stdout, err)
+	}
+	if got, want := created[0].RoutingConfig.RoutingMode, "GLOBAL"; got != want {
+		t.Errorf("Routing mode = %v, want = %v", got, want)
+	}
+
+	_, stderr, _ = run(s, "compute networks subnets create subnet-1 --network=vpc-2 --region=r --range=10.0.0.0/24 --project=p")
+	if !gcloud.IsNotFound(gcloud.Classify(stderr)) {
+		t.Errorf("Subnet create in a missing network stderr = %q, want = not found", stderr)
+	}
+	if _, stderr, code = run(s, "compute networks subnets create subnet-1 --network=vpc-1 --region=r --range=10.0.0.0/24 --project=p"); code != 0 {
+		t.Fatalf("subnets create exit code = %v, want = 0: %s", code, stderr)
+	}
+	_, stderr, _ = run(s, "compute networks create vpc-1 --project=p")
+	if !gcloud.IsAlreadyExists(gcloud.Classify(stderr)) {
+		t.Errorf("Second networks create stderr = %q, want = already exists", stderr)
+	}
+
+	stdout, _, _ = run(s, "compute networks describe vpc-1 --project=p --format=json")
+	var network gcloud.Network
+	if err := json.Unmarshal([]byte(stdout), &network); err != nil {
+		t.Fatalf("Decoding networks describe: %v", err)
+	}
+	if got, want := strings.Join(network.Subnetworks, " "), "https://www.googleapis.com/compute/v1/projects/p/regions/r/subnetworks/subnet-1"; got != want {
+		t.Errorf("Network subnetworks = %v, want = %v", got, want)
+	}
+
+	_, stderr, _ = run(s, "compute networks delete vpc-1 --project=p")
+	if !gcloud.IsTransient(gcloud.Classify(stderr)) {
+		t.Errorf("Deleting a network in use stderr = %q, want = transient", stderr)
+	}
+	run(s, "compute networks subnets delete subnet-1 --region=r --project=p")
+	if _, stderr, code = run(s, "compute networks delete vpc-1 --project=p"); code != 0 {
+		t.Errorf("networks delete exit code = %v, want = 0: %s", code, stderr)
+	}
+	_, stderr, _ = run(s, "compute networks describe vpc-1 --project=p")
+	if !gcloud.IsNotFound(gcloud.Classify(stderr)) {
+		t.Errorf("Describing a deleted network stderr = %q, want = not found", stderr)
+	}
+	if got, want := len(s.Calls), 9; got != want {
+		t.Errorf("Calls = %v, want = %v", got, want)
+	}
+}
+
+func TestFailureInjection(t *testing.T) {
+	s := &State{Failures: []*Failure{{Match: "sql instances describe", Stderr: "ERROR: PERMISSION_DENIED", Times: 1}}}
+	if _, stderr, code := run(s, "sql instances describe db-1 --project=p"); code != 1 || !gcloud.IsPermissionDenied(gcloud.Classify(stderr)) {
+		t.Errorf("First describe = %v %q, want = 1 and permission denied", code, stderr)
+	}
+	if _, stderr, _ := run(s, "sql instances describe db-1 --project=p"); !gcloud.IsNotFound(gcloud.Classify(stderr)) {
+		t.Errorf("Second describe stderr = %q, want = not found", stderr)
+	}
+	if got := len(s.Failures); got != 0 {
+		t.Errorf("Failures left = %v, want = 0", got)
+	}
+}
+
+func TestServiceAccountsByEmail(t *testing.T) {
+	s := &State{}
+	run(s, "iam service-accounts create sa-1 --display-name=test --project=p")
+	stdout, stderr, code := run(s, "iam service-accounts describe sa-1@p.iam.gserviceaccount.com --project=p")
+	if code != 0 {
+		t.Fatalf("service-accounts describe exit code = %v, want = 0: %s", code, stderr)
+	}
+	var sa gcloud.ServiceAccount
+	if err := json.Unmarshal([]byte(stdout), &sa); err != nil {
+		t.Fatalf("Decoding service-accounts describe: %v", err)
+	}
+	if got, want := sa.Email, "sa-1@p.iam.gserviceaccount.com"; got != want {
+		t.Errorf("Email = %v, want = %v", got, want)
+	}
+}
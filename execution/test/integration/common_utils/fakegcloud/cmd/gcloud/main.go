@@ -0,0 +1,28 @@
+// Copyright 2026 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Command gcloud is the fake gcloud that fakegcloud.Install builds and puts on
+// the PATH of a test. It serves the commands it understands from the state
+// file named by $FAKE_GCLOUD_STATE.
+package main
+
+import (
+	"os"
+
+	"github.com/GoogleCloudPlatform/cloudnetworking-config-solutions/common_utils/fakegcloud"
+)
+
+func main() {
+	os.Exit(fakegcloud.Main(os.Args[1:], os.Stdout, os.Stderr))
+}
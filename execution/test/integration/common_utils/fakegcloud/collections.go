@@ -0,0 +1,410 @@
+// Copyright 2026 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package fakegcloud
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+const computeURL = "https://www.googleapis.com/compute/v1/"
+
+// collection describes a command group of the fake gcloud and the resources
+// it manages.
+type collection struct {
+	path string
+	kind string
+	// scope is "global", "region", "zone" or "either", the latter meaning
+	// --global or --region.
+	scope string
+	// compute collections use full URLs as self links and print a list on
+	// create.
+	compute bool
+	// format is the relative resource name, given the project, the location
+	// and the name, which global resources leave out.
+	format string
+	// name returns the name of the resource a command is about. It defaults
+	// to the first positional argument.
+	nameOf func(c *command) string
+	// create fills the data of a new resource from the flags and returns an
+	// error when a resource it refers to is missing.
+	create func(s *State, c *command, r *Resource) error
+	// deleted updates the resources that list r when it is deleted.
+	deleted func(s *State, r *Resource)
+}
+
+// collections are the command groups the suites use.
+var collections = map[string]*collection{}
+
+func init() {
+	for _, col := range []*collection{
+		{path: "compute networks", kind: "network", scope: "global", compute: true, format: "projects/%[1]s/global/networks/%[3]s", create: createNetwork},
+		{path: "compute networks subnets", kind: "subnetwork", scope: "region", compute: true, format: "projects/%s/regions/%s/subnetworks/%s", create: createSubnet, deleted: deleteSubnet},
+		{path: "compute firewall-rules", kind: "firewall", scope: "global", compute: true, format: "projects/%[1]s/global/firewalls/%[3]s", create: createFirewall},
+		{path: "compute addresses", kind: "address", scope: "either", compute: true, format: "projects/%s/%s/addresses/%s", create: createAddress},
+		{path: "network-connectivity service-connection-policies", kind: "service connection policy", scope: "region", format: "projects/%s/locations/%s/serviceConnectionPolicies/%s", create: createServiceConnectionPolicy},
+		{path: "network-connectivity hubs", kind: "hub", scope: "global", format: "projects/%s/locations/%s/hubs/%s", create: createHub},
+		{path: "services vpc-peerings", kind: "connection", scope: "global", format: "projects/%[1]s/global/networks/%[3]s/servicenetworking", nameOf: peeringName, create: createPeering},
+		{path: "sql instances", kind: "instance", scope: "global", format: "projects/%[1]s/instances/%[3]s", create: createSQLInstance},
+		{path: "redis instances", kind: "instance", scope: "region", format: "projects/%s/locations/%s/instances/%s", create: createRedisInstance},
+		{path: "iam service-accounts", kind: "service account", scope: "global", format: "projects/%[1]s/serviceAccounts/%[3]s", nameOf: serviceAccountName, create: createServiceAccount},
+	} {
+		collections[col.path] = col
+	}
+}
+
+// location returns "global", the region or the zone of a command.
+func (col *collection) location(c *command) (string, error) {
+	switch col.scope {
+	case "region":
+		if c.flags["region"] == "" {
+			return "", errorf(c, "argument --region: Must be specified.")
+		}
+		return c.flags["region"], nil
+	case "zone":
+		if c.flags["zone"] == "" {
+			return "", errorf(c, "argument --zone: Must be specified.")
+		}
+		return c.flags["zone"], nil
+	case "either":
+		if c.flags["region"] != "" {
+			return c.flags["region"], nil
+		}
+	}
+	return "global", nil
+}
+
+// ref returns the relative name of a resource of the collection.
+func (col *collection) ref(project, location, name string) string {
+	if col.scope == "either" {
+		if location == "global" {
+			return fmt.Sprintf(col.format, project, "global", name)
+		}
+		return fmt.Sprintf(col.format, project, "regions/"+location, name)
+	}
+	return fmt.Sprintf(col.format, project, location, name)
+}
+
+// link returns the self link of a resource of the collection.
+func (col *collection) link(project, location, name string) string {
+	if col.compute {
+		return computeURL + col.ref(project, location, name)
+	}
+	return col.ref(project, location, name)
+}
+
+func (col *collection) name(c *command) string {
+	if col.nameOf != nil {
+		return col.nameOf(c)
+	}
+	return c.name
+}
+
+// useNetwork records that r uses the network named by a flag of c, which must
+// exist, and returns its self link.
+func (s *State) useNetwork(c *command, r *Resource, flag string) (string, error) {
+	value := c.flags[flag]
+	if value == "" {
+		return "", nil
+	}
+	project, name := r.Project, value
+	if i := strings.LastIndex(value, "/networks/"); i >= 0 {
+		name = value[i+len("/networks/"):]
+		if j := strings.Index(value, "projects/"); j >= 0 {
+			project = strings.SplitN(value[j+len("projects/"):], "/", 2)[0]
+		}
+	}
+	return s.use(c, r, collections["compute networks"], project, "global", name)
+}
+
+// use records that r uses a resource of col, which must exist, and returns its
+// self link.
+func (s *State) use(c *command, r *Resource, col *collection, project, location, name string) (string, error) {
+	if s.find(col.path, project, location, name) == nil {
+		return "", errorf(c, "Could not fetch resource:\n - The resource '%s' was not found", col.link(project, location, name))
+	}
+	r.Refs = append(r.Refs, col.ref(project, location, name))
+	return col.link(project, location, name), nil
+}
+
+func createNetwork(_ *State, c *command, r *Resource) error {
+	mode := c.flags["bgp-routing-mode"]
+	if mode == "" {
+		mode = "regional"
+	}
+	mtu := 1460
+	if v, err := strconv.Atoi(c.flags["mtu"]); err == nil {
+		mtu = v
+	}
+	r.Data["selfLink"] = collections[r.Collection].link(r.Project, r.Location, r.Name)
+	r.Data["autoCreateSubnetworks"] = c.flags["subnet-mode"] == "auto"
+	r.Data["mtu"] = mtu
+	r.Data["routingConfig"] = map[string]any{"routingMode": strings.ToUpper(mode)}
+	r.Data["subnetworks"] = []any{}
+	return nil
+}
+
+func createSubnet(s *State, c *command, r *Resource) error {
+	network, err := s.useNetwork(c, r, "network")
+	if err != nil {
+		return err
+	}
+	if network == "" {
+		return errorf(c, "argument --network: Must be specified.")
+	}
+	link := collections[r.Collection].link(r.Project, r.Location, r.Name)
+	r.Data["selfLink"] = link
+	r.Data["network"] = network
+	r.Data["region"] = computeURL + fmt.Sprintf("projects/%s/regions/%s", r.Project, r.Location)
+	r.Data["ipCidrRange"] = c.flags["range"]
+	r.Data["privateIpGoogleAccess"] = hasFlag(c, "enable-private-ip-google-access")
+	r.Data["logConfig"] = map[string]any{"enable": hasFlag(c, "enable-flow-logs")}
+	r.Data["purpose"] = "PRIVATE"
+	r.Data["state"] = "READY"
+	if n := s.find("compute networks", r.Project, "global", networkName(network)); n != nil {
+		n.Data["subnetworks"] = append(list(n.Data["subnetworks"]), link)
+	}
+	return nil
+}
+
+func deleteSubnet(s *State, r *Resource) {
+	network, _ := r.Data["network"].(string)
+	n := s.find("compute networks", r.Project, "global", networkName(network))
+	if n == nil {
+		return
+	}
+	kept := []any{}
+	for _, link := range list(n.Data["subnetworks"]) {
+		if link != r.Data["selfLink"] {
+			kept = append(kept, link)
+		}
+	}
+	n.Data["subnetworks"] = kept
+}
+
+func createFirewall(s *State, c *command, r *Resource) error {
+	network, err := s.useNetwork(c, r, "network")
+	if err != nil {
+		return err
+	}
+	var allowed []any
+	for _, rule := range split(c.flags["allow"]) {
+		protocol, ports, ok := strings.Cut(rule, ":")
+		entry := map[string]any{"IPProtocol": protocol}
+		if ok {
+			entry["ports"] = []any{ports}
+		}
+		allowed = append(allowed, entry)
+	}
+	r.Data["selfLink"] = collections[r.Collection].link(r.Project, r.Location, r.Name)
+	r.Data["network"] = network
+	r.Data["allowed"] = allowed
+	r.Data["sourceRanges"] = split(c.flags["source-ranges"])
+	r.Data["targetTags"] = split(c.flags["target-tags"])
+	r.Data["direction"] = "INGRESS"
+	return nil
+}
+
+func createAddress(s *State, c *command, r *Resource) error {
+	network, err := s.useNetwork(c, r, "network")
+	if err != nil {
+		return err
+	}
+	address := c.flags["addresses"]
+	if address == "" {
+		address = fmt.Sprintf("10.128.0.%d", 2+len(s.Resources)%250)
+	}
+	r.Data["selfLink"] = collections[r.Collection].link(r.Project, r.Location, r.Name)
+	r.Data["address"] = address
+	r.Data["status"] = "RESERVED"
+	if c.flags["purpose"] != "" {
+		r.Data["purpose"] = c.flags["purpose"]
+		r.Data["addressType"] = "INTERNAL"
+	} else {
+		r.Data["addressType"] = "EXTERNAL"
+	}
+	if v, err := strconv.Atoi(c.flags["prefix-length"]); err == nil {
+		r.Data["prefixLength"] = v
+	}
+	if network != "" {
+		r.Data["network"] = network
+	}
+	if r.Location != "global" {
+		r.Data["region"] = computeURL + fmt.Sprintf("projects/%s/regions/%s", r.Project, r.Location)
+	}
+	return nil
+}
+
+func createServiceConnectionPolicy(s *State, c *command, r *Resource) error {
+	network, err := s.useNetwork(c, r, "network")
+	if err != nil {
+		return err
+	}
+	subnets := collections["compute networks subnets"]
+	var subnetworks []any
+	for _, subnet := range split(c.flags["subnets"]) {
+		name := subnet[strings.LastIndex(subnet, "/")+1:]
+		link, err := s.use(c, r, subnets, r.Project, r.Location, name)
+		if err != nil {
+			return err
+		}
+		subnetworks = append(subnetworks, strings.TrimPrefix(link, computeURL))
+	}
+	psc := map[string]any{"subnetworks": subnetworks}
+	if c.flags["psc-connection-limit"] != "" {
+		psc["limit"] = c.flags["psc-connection-limit"]
+	}
+	r.Data["name"] = collections[r.Collection].ref(r.Project, r.Location, r.Name)
+	r.Data["network"] = strings.TrimPrefix(network, computeURL)
+	r.Data["serviceClass"] = c.flags["service-class"]
+	r.Data["infrastructure"] = "PSC"
+	r.Data["pscConfig"] = psc
+	r.Data["labels"] = labels(c)
+	return nil
+}
+
+func createHub(_ *State, c *command, r *Resource) error {
+	r.Data["name"] = collections[r.Collection].ref(r.Project, r.Location, r.Name)
+	r.Data["description"] = c.flags["description"]
+	r.Data["state"] = "ACTIVE"
+	r.Data["labels"] = labels(c)
+	return nil
+}
+
+func peeringName(c *command) string {
+	return networkName(c.flags["network"])
+}
+
+func createPeering(s *State, c *command, r *Resource) error {
+	network, err := s.useNetwork(c, r, "network")
+	if err != nil {
+		return err
+	}
+	var ranges []any
+	for _, name := range split(c.flags["ranges"]) {
+		if _, err := s.use(c, r, collections["compute addresses"], r.Project, "global", name); err != nil {
+			return err
+		}
+		ranges = append(ranges, name)
+	}
+	r.Data = map[string]any{
+		"network":               strings.TrimPrefix(network, computeURL),
+		"peering":               "servicenetworking-googleapis-com",
+		"service":               "services/servicenetworking.googleapis.com",
+		"reservedPeeringRanges": ranges,
+	}
+	return nil
+}
+
+func createSQLInstance(s *State, c *command, r *Resource) error {
+	network, err := s.useNetwork(c, r, "network")
+	if err != nil {
+		return err
+	}
+	region := c.flags["region"]
+	r.Data["connectionName"] = fmt.Sprintf("%s:%s:%s", r.Project, region, r.Name)
+	r.Data["databaseVersion"] = c.flags["database-version"]
+	r.Data["region"] = region
+	r.Data["state"] = "RUNNABLE"
+	r.Data["settings"] = map[string]any{
+		"tier":             c.flags["tier"],
+		"availabilityType": strings.ToUpper(valueOr(c.flags["availability-type"], "zonal")),
+		"ipConfiguration": map[string]any{
+			"ipv4Enabled":    !hasFlag(c, "no-assign-ip"),
+			"privateNetwork": strings.TrimPrefix(network, computeURL),
+		},
+	}
+	return nil
+}
+
+func createRedisInstance(s *State, c *command, r *Resource) error {
+	network, err := s.useNetwork(c, r, "network")
+	if err != nil {
+		return err
+	}
+	size, _ := strconv.Atoi(valueOr(c.flags["size"], "1"))
+	r.Data["name"] = collections[r.Collection].ref(r.Project, r.Location, r.Name)
+	r.Data["authorizedNetwork"] = strings.TrimPrefix(network, computeURL)
+	r.Data["connectMode"] = strings.ToUpper(valueOr(c.flags["connect-mode"], "direct-peering"))
+	r.Data["tier"] = strings.ToUpper(valueOr(c.flags["tier"], "basic"))
+	r.Data["memorySizeGb"] = size
+	r.Data["redisVersion"] = strings.ToUpper(valueOr(c.flags["redis-version"], "redis_7_0"))
+	r.Data["host"] = "10.0.0.3"
+	r.Data["port"] = 6379
+	r.Data["state"] = "READY"
+	r.Data["labels"] = labels(c)
+	return nil
+}
+
+// serviceAccountName accepts the account ID or the email of a service
+// account.
+func serviceAccountName(c *command) string {
+	name, _, _ := strings.Cut(c.name, "@")
+	return name
+}
+
+func createServiceAccount(_ *State, c *command, r *Resource) error {
+	email := fmt.Sprintf("%s@%s.iam.gserviceaccount.com", r.Name, r.Project)
+	r.Data["name"] = fmt.Sprintf("projects/%s/serviceAccounts/%s", r.Project, email)
+	r.Data["email"] = email
+	r.Data["displayName"] = c.flags["display-name"]
+	r.Data["uniqueId"] = "1" + strings.Repeat("0", 16) + r.Data["id"].(string)
+	r.Data["disabled"] = false
+	delete(r.Data, "id")
+	return nil
+}
+
+// networkName returns the name at the end of a network name or link.
+func networkName(network string) string {
+	return network[strings.LastIndex(network, "/")+1:]
+}
+
+func hasFlag(c *command, name string) bool {
+	_, ok := c.flags[name]
+	return ok
+}
+
+func valueOr(value, fallback string) string {
+	if value == "" {
+		return fallback
+	}
+	return value
+}
+
+// split returns the comma separated values of a flag.
+func split(value string) []string {
+	if value == "" {
+		return []string{}
+	}
+	return strings.Split(value, ",")
+}
+
+// labels parses --labels=k1=v1,k2=v2.
+func labels(c *command) map[string]any {
+	out := map[string]any{}
+	for _, pair := range split(c.flags["labels"]) {
+		k, v, _ := strings.Cut(pair, "=")
+		out[k] = v
+	}
+	return out
+}
+
+// list returns a list from the data of a resource, which holds []any once it
+// has been read back from the state file.
+func list(v any) []any {
+	l, _ := v.([]any)
+	return l
+}
@@ -0,0 +1,147 @@
+// Copyright 2026 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package fakegcloud is a fake gcloud CLI for unit tests of the helpers that
+// shell out to gcloud. Install builds it and puts it first on the PATH of the
+// test, so that gcloud.Client and plain shell.Command calls reach it instead
+// of a project. It understands the compute networks, subnets, firewall rules
+// and addresses, network-connectivity, services vpc-peerings, sql, redis and
+// iam service-accounts command families, keeps a model of the projects they
+// act on, logs every command and fails the ones matching an injected Failure.
+package fakegcloud
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+// program is the import path of the fake gcloud program.
+const program = "github.com/GoogleCloudPlatform/cloudnetworking-config-solutions/common_utils/fakegcloud/cmd/gcloud"
+
+// Fake is a fake gcloud installed for one test.
+type Fake struct {
+	// Dir holds the gcloud binary and the state file.
+	Dir string
+	// State is the path of the state file.
+	State string
+}
+
+// Install builds the fake gcloud into a temporary directory and puts it first
+// on the PATH of t, with an empty project model. It sets environment
+// variables, so t and its parents cannot be parallel.
+func Install(t testing.TB) *Fake {
+	t.Helper()
+	dir := t.TempDir()
+	binary := filepath.Join(dir, "gcloud")
+	if runtime.GOOS == "windows" {
+		binary += ".exe"
+	}
+	build := exec.Command(goTool(), "build", "-o", binary, program)
+	if out, err := build.CombinedOutput(); err != nil {
+		t.Fatalf("Building the fake gcloud: %v\n%s", err, out)
+	}
+	f := &Fake{Dir: dir, State: filepath.Join(dir, "state.json")}
+	if err := (&State{}).save(f.State); err != nil {
+		t.Fatalf("Writing the fake gcloud state: %v", err)
+	}
+	t.Setenv("PATH", dir+string(os.PathListSeparator)+os.Getenv("PATH"))
+	t.Setenv(StateEnv, f.State)
+	return f
+}
+
+// goTool returns the go command of the toolchain running the test.
+func goTool() string {
+	if path, err := exec.LookPath("go"); err == nil {
+		return path
+	}
+	return filepath.Join(runtime.GOROOT(), "bin", "go")
+}
+
+// Fail injects failure into the following commands.
+func (f *Fake) Fail(t testing.TB, failure Failure) {
+	t.Helper()
+	f.update(t, func(s *State) {
+		s.Failures = append(s.Failures, &failure)
+	})
+}
+
+// Calls returns the commands run so far, in order.
+func (f *Fake) Calls(t testing.TB) []Call {
+	t.Helper()
+	return f.load(t).Calls
+}
+
+// Commands returns the command lines run so far that contain match, in
+// order, without the flags the gcloud package adds to every command.
+func (f *Fake) Commands(t testing.TB, match string) []string {
+	t.Helper()
+	var out []string
+	for _, call := range f.Calls(t) {
+		var args []string
+		for _, arg := range call.Args {
+			if !strings.HasPrefix(arg, "--project=") && arg != "--format=json" && arg != "--quiet" {
+				args = append(args, arg)
+			}
+		}
+		if line := strings.Join(args, " "); strings.Contains(line, match) {
+			out = append(out, line)
+		}
+	}
+	return out
+}
+
+// Get returns the data of a resource, as describe would print it, or nil
+// when it does not exist. collection is a command group such as
+// "compute networks" and location is "global", a region or a zone.
+func (f *Fake) Get(t testing.TB, collection, project, location, name string) map[string]any {
+	t.Helper()
+	if r := f.load(t).find(collection, project, location, name); r != nil {
+		return r.Data
+	}
+	return nil
+}
+
+// Run runs a command against the model, as a test would to set up the
+// resources a helper expects to exist, and fails the test if it fails.
+func (f *Fake) Run(t testing.TB, args ...string) {
+	t.Helper()
+	var stdout, stderr strings.Builder
+	f.update(t, func(s *State) {
+		if code := s.Run(args, &stdout, &stderr); code != 0 {
+			t.Errorf("gcloud %s: exit code %d: %s", strings.Join(args, " "), code, stderr.String())
+		}
+	})
+}
+
+func (f *Fake) load(t testing.TB) *State {
+	t.Helper()
+	var state *State
+	f.update(t, func(s *State) { state = s })
+	return state
+}
+
+func (f *Fake) update(t testing.TB, fn func(*State)) {
+	t.Helper()
+	err := update(f.State, func(s *State) error {
+		fn(s)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Updating the fake gcloud state: %v", err)
+	}
+}
@@ -0,0 +1,169 @@
+// Copyright 2026 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package fakegcloud
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+)
+
+// StateEnv is the environment variable that points the fake gcloud at the
+// file holding its state. Every invocation reads the file, runs the command
+// against the project model and writes it back.
+const StateEnv = "FAKE_GCLOUD_STATE"
+
+// State is the model of the projects the fake gcloud serves, with the
+// failures still to inject and the log of the commands it ran.
+type State struct {
+	Resources []*Resource `json:"resources"`
+	Failures  []*Failure  `json:"failures,omitempty"`
+	Calls     []Call      `json:"calls,omitempty"`
+	LastID    int         `json:"lastId"`
+}
+
+// Resource is one resource of a project. Collection is the command group that
+// manages it, such as "compute networks subnets", and Location is "global", a
+// region or a zone. Data is what describe prints. Refs are the relative names
+// of the resources it uses, which cannot be deleted while it exists.
+type Resource struct {
+	Collection string         `json:"collection"`
+	Project    string         `json:"project"`
+	Location   string         `json:"location"`
+	Name       string         `json:"name"`
+	Data       map[string]any `json:"data"`
+	Refs       []string       `json:"refs,omitempty"`
+}
+
+// Failure makes the commands whose command line contains Match fail with
+// Stderr and ExitCode, without touching the model. Times limits how many
+// commands fail; zero fails every one.
+type Failure struct {
+	Match    string `json:"match"`
+	Stderr   string `json:"stderr"`
+	ExitCode int    `json:"exitCode,omitempty"`
+	Times    int    `json:"times,omitempty"`
+}
+
+// Call is one command run by the fake gcloud.
+type Call struct {
+	Args     []string `json:"args"`
+	ExitCode int      `json:"exitCode"`
+}
+
+// String returns the command line of the call without "gcloud".
+func (c Call) String() string {
+	return strings.Join(c.Args, " ")
+}
+
+// lockTimeout bounds the wait for another invocation to release the state.
+const lockTimeout = 30 * time.Second
+
+// update runs fn on the state stored at path under an exclusive lock and
+// saves the result.
+func update(path string, fn func(*State) error) error {
+	unlock, err := lock(path + ".lock")
+	if err != nil {
+		return err
+	}
+	defer unlock()
+	s, err := load(path)
+	if err != nil {
+		return err
+	}
+	if err := fn(s); err != nil {
+		return err
+	}
+	return s.save(path)
+}
+
+func lock(path string) (func(), error) {
+	deadline := time.Now().Add(lockTimeout)
+	for {
+		f, err := os.OpenFile(path, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0600)
+		if err == nil {
+			f.Close()
+			return func() { os.Remove(path) }, nil
+		}
+		if !errors.Is(err, os.ErrExist) {
+			return nil, err
+		}
+		if time.Now().After(deadline) {
+			return nil, fmt.Errorf("timed out waiting for %s", path)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func load(path string) (*State, error) {
+	s := &State{}
+	b, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return s, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(b, s); err != nil {
+		return nil, fmt.Errorf("decoding %s: %w", path, err)
+	}
+	return s, nil
+}
+
+func (s *State) save(path string) error {
+	b, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, b, 0600)
+}
+
+// find returns the resource of collection called name, or nil.
+func (s *State) find(collection, project, location, name string) *Resource {
+	for _, r := range s.Resources {
+		if r.Collection == collection && r.Project == project && r.Location == location && r.Name == name {
+			return r
+		}
+	}
+	return nil
+}
+
+func (s *State) remove(r *Resource) {
+	for i, other := range s.Resources {
+		if other == r {
+			s.Resources = append(s.Resources[:i], s.Resources[i+1:]...)
+			return
+		}
+	}
+}
+
+// failure returns the first failure matching the command line and uses it up.
+func (s *State) failure(line string) *Failure {
+	for i, f := range s.Failures {
+		if !strings.Contains(line, f.Match) {
+			continue
+		}
+		if f.Times > 0 {
+			if f.Times--; f.Times == 0 {
+				s.Failures = append(s.Failures[:i], s.Failures[i+1:]...)
+			}
+		}
+		return f
+	}
+	return nil
+}
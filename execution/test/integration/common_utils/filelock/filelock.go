@@ -0,0 +1,49 @@
+// Copyright 2026 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package filelock holds exclusive locks on files, so that the test binaries
+// running on a machine can serialize their changes to shared state such as
+// the ipam leases. The locks are advisory: they only exclude the processes
+// that lock the same file. The system releases the lock of a process when it
+// exits, even if it crashed, so a lock is never left behind. Files are locked
+// with flock on Unix and LockFileEx on Windows.
+package filelock
+
+import (
+	"errors"
+	"os"
+)
+
+// ErrUnsupported is returned on the platforms without file locks.
+var ErrUnsupported = errors.New("file locks are not supported on this platform")
+
+// Lock blocks until it holds the exclusive lock of f.
+func Lock(f *os.File) error {
+	return lock(f, true)
+}
+
+// TryLock takes the exclusive lock of f if no other file holds it, and
+// reports whether it did.
+func TryLock(f *os.File) (bool, error) {
+	err := lock(f, false)
+	if errors.Is(err, errLocked) {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+// Unlock releases the lock of f. Closing f releases it too.
+func Unlock(f *os.File) error {
+	return unlock(f)
+}
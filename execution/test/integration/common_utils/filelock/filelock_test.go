@@ -0,0 +1,47 @@
+// Copyright 2026 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package filelock
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestTryLockFailsWhileAnotherFileHoldsTheLock(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "test.lock")
+	open := func() *os.File {
+		f, err := os.OpenFile(path, os.O_CREATE|os.O_RDWR, 0o644)
+		if err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(func() { f.Close() })
+		return f
+	}
+	holder, other := open(), open()
+
+	if err := Lock(holder); err != nil {
+		t.Fatalf("Lock() = %v, want = nil", err)
+	}
+	if ok, err := TryLock(other); ok || err != nil {
+		t.Errorf("TryLock() while locked = %v, %v, want = false, nil", ok, err)
+	}
+	if err := Unlock(holder); err != nil {
+		t.Fatalf("Unlock() = %v, want = nil", err)
+	}
+	if ok, err := TryLock(other); !ok || err != nil {
+		t.Errorf("TryLock() after Unlock = %v, %v, want = true, nil", ok, err)
+	}
+}
@@ -0,0 +1,289 @@
+// Copyright 2026 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package fixture tracks the cloud resources a test creates and tears them
+// down when the test ends, whether it passed, failed, called t.Fatal from a
+// helper or panicked. Teardowns run from t.Cleanup in dependency order: a
+// resource is only torn down once everything that depends on it has been.
+// Teardowns that fail are collected into a leak report naming every resource
+// that may have been left behind. Resources, the outcome of their teardown and
+// the destroy phase are recorded in the report of the test. The registry of a
+// test also gives it its own gcloud configuration, see GcloudConfig.
+package fixture
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/GoogleCloudPlatform/cloudnetworking-config-solutions/common_utils/gcloud"
+	"github.com/GoogleCloudPlatform/cloudnetworking-config-solutions/common_utils/report"
+)
+
+// Resource identifies a cloud resource in logs and leak reports.
+type Resource struct {
+	// Kind is a short type name such as "network" or "subnetwork".
+	Kind string
+	// ID is the full resource name, for example
+	// projects/p/global/networks/vpc.
+	ID string
+}
+
+func (r Resource) String() string {
+	return r.Kind + " " + r.ID
+}
+
+// Leak is a resource whose teardown failed or was skipped.
+type Leak struct {
+	Resource
+	Err error
+}
+
+// Handle is a registered resource. Its teardown runs once, either when the
+// test ends or earlier through Teardown.
+type Handle struct {
+	Resource
+
+	registry *Registry
+	teardown func() error
+	deps     []*Handle
+	done     bool
+	err      error
+}
+
+// Registry holds the fixtures of a single test.
+type Registry struct {
+	t       testing.TB
+	gcloud  *gcloud.Config
+	mu      sync.Mutex
+	handles []*Handle
+	leaks   []Leak
+}
+
+var (
+	mu         sync.Mutex
+	registries = map[testing.TB]*Registry{}
+)
+
+// For returns the registry of t, creating it and hooking it into t.Cleanup on
+// first use. Helpers in different packages that register against the same t
+// share one registry, so ordering holds across them.
+func For(t testing.TB) *Registry {
+	mu.Lock()
+	defer mu.Unlock()
+	if r, ok := registries[t]; ok {
+		return r
+	}
+	r := &Registry{t: t, gcloud: gcloud.ConfigFor(t)}
+	registries[t] = r
+	// The configuration outlives the teardowns, which are registered after
+	// it, so that they run with the properties the test set. A subtest shares
+	// the configuration of its parent.
+	if r.gcloud == nil {
+		r.gcloud = gcloud.Isolate(t)
+	}
+	// The report ends after the teardowns, which are registered after it.
+	report.For(t)
+	t.Cleanup(func() {
+		report.Enter(t, report.Destroy)
+		r.teardownAll()
+		mu.Lock()
+		delete(registries, t)
+		mu.Unlock()
+	})
+	return r
+}
+
+// GcloudConfig returns the gcloud configuration isolated for t, or for its
+// closest parent test, by its registry. Properties set in it, such as
+// billing/quota_project, apply to the gcloud commands run with t through a
+// gcloud.Client and to the teardowns of its fixtures, and never reach the
+// configuration of the developer.
+func GcloudConfig(t testing.TB) *gcloud.Config {
+	return For(t).gcloud
+}
+
+// Register records res on the registry of t. teardown is called when the test
+// ends, after the teardown of every handle that depends on the returned one.
+// It should treat an already deleted resource as success.
+func Register(t testing.TB, res Resource, teardown func() error, dependsOn ...*Handle) *Handle {
+	return For(t).Register(res, teardown, dependsOn...)
+}
+
+// Register records res with teardown. See the package level Register.
+func (r *Registry) Register(res Resource, teardown func() error, dependsOn ...*Handle) *Handle {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	h := &Handle{Resource: res, registry: r, teardown: teardown}
+	h.deps = appendHandles(nil, dependsOn)
+	r.handles = append(r.handles, h)
+	r.t.Logf("Registered fixture %s", res)
+	report.Created(r.t, res.Kind, res.ID)
+	return h
+}
+
+// DependsOn declares that h must be torn down before each of others. It is
+// only needed for dependencies that were not known when h was registered;
+// handles registered later are torn down first by default.
+func (h *Handle) DependsOn(others ...*Handle) {
+	if h == nil {
+		return
+	}
+	h.registry.mu.Lock()
+	defer h.registry.mu.Unlock()
+	h.deps = appendHandles(h.deps, others)
+}
+
+// Teardown tears h down now instead of at the end of the test. A failure is
+// reported in the leak report as well as returned. Calling it again is a no-op
+// that returns the first result.
+func (h *Handle) Teardown() error {
+	if h == nil {
+		return nil
+	}
+	h.registry.mu.Lock()
+	defer h.registry.mu.Unlock()
+	h.registry.run(h)
+	return h.err
+}
+
+// Leaks returns the fixtures whose teardown has failed so far.
+func (r *Registry) Leaks() []Leak {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]Leak(nil), r.leaks...)
+}
+
+// run calls the teardown of h once. The registry lock must be held.
+func (r *Registry) run(h *Handle) {
+	if h.done {
+		return
+	}
+	h.done = true
+	r.t.Logf("Tearing down fixture %s", h.Resource)
+	start := time.Now()
+	if h.teardown != nil {
+		h.err = h.teardown()
+	}
+	r.done(h, time.Since(start))
+}
+
+// done records the outcome of the teardown of h. The registry lock must be
+// held.
+func (r *Registry) done(h *Handle, took time.Duration) {
+	if h.err != nil {
+		r.leaks = append(r.leaks, Leak{Resource: h.Resource, Err: h.err})
+	}
+	report.TornDown(r.t, h.Kind, h.ID, took, h.err)
+}
+
+// teardownAll tears down every remaining fixture, dependents first, and fails
+// the test with a leak report if any teardown failed. A fixture whose
+// dependent leaked is skipped, since deleting it would fail anyway, and is
+// reported as leaked too.
+func (r *Registry) teardownAll() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, h := range r.order() {
+		if h.done {
+			continue
+		}
+		if blocker := r.leakedDependent(h); blocker != nil {
+			h.done = true
+			h.err = fmt.Errorf("skipped because dependent %s was not torn down", blocker.Resource)
+			r.done(h, 0)
+			continue
+		}
+		r.run(h)
+	}
+	if len(r.leaks) > 0 {
+		r.t.Errorf("%s", Report(r.leaks))
+	}
+}
+
+// order returns the handles so that every handle comes before the handles it
+// depends on. Among handles that are free to go, the most recently
+// registered goes first, which matches the order of deferred calls.
+func (r *Registry) order() []*Handle {
+	pending := make(map[*Handle]int, len(r.handles))
+	for _, h := range r.handles {
+		for _, dep := range h.deps {
+			pending[dep]++
+		}
+	}
+	var ordered []*Handle
+	emitted := make(map[*Handle]bool, len(r.handles))
+	for len(ordered) < len(r.handles) {
+		var next *Handle
+		for i := len(r.handles) - 1; i >= 0; i-- {
+			h := r.handles[i]
+			if !emitted[h] && pending[h] == 0 {
+				next = h
+				break
+			}
+		}
+		if next == nil {
+			// A dependency cycle. Fall back to reverse registration order for
+			// whatever is left rather than leaking it.
+			for i := len(r.handles) - 1; i >= 0; i-- {
+				if h := r.handles[i]; !emitted[h] {
+					next = h
+					break
+				}
+			}
+		}
+		emitted[next] = true
+		ordered = append(ordered, next)
+		for _, dep := range next.deps {
+			pending[dep]--
+		}
+	}
+	return ordered
+}
+
+// leakedDependent returns a handle depending on h whose teardown failed.
+func (r *Registry) leakedDependent(h *Handle) *Handle {
+	for _, other := range r.handles {
+		if other.err == nil {
+			continue
+		}
+		for _, dep := range other.deps {
+			if dep == h {
+				return other
+			}
+		}
+	}
+	return nil
+}
+
+// Report formats leaks as a human readable list, one resource per line.
+func Report(leaks []Leak) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "Leak report: %d fixture(s) were not torn down and may need manual cleanup:", len(leaks))
+	for _, leak := range leaks {
+		fmt.Fprintf(&b, "\n  %s: %v", leak.Resource, leak.Err)
+	}
+	return b.String()
+}
+
+func appendHandles(dst, src []*Handle) []*Handle {
+	for _, h := range src {
+		if h != nil {
+			dst = append(dst, h)
+		}
+	}
+	return dst
+}
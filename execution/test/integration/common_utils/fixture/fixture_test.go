@@ -0,0 +1,203 @@
+// Copyright 2026 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package fixture
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/GoogleCloudPlatform/cloudnetworking-config-solutions/common_utils/cassette"
+	"github.com/GoogleCloudPlatform/cloudnetworking-config-solutions/common_utils/gcloud"
+	"github.com/GoogleCloudPlatform/cloudnetworking-config-solutions/common_utils/report"
+	"github.com/gruntwork-io/terratest/modules/terraform"
+)
+
+// fakeT captures cleanups and errors so that a test can run them and inspect
+// the leak report without failing itself.
+type fakeT struct {
+	*testing.T
+	cleanups []func()
+	errors   []string
+}
+
+func (f *fakeT) Cleanup(fn func()) { f.cleanups = append(f.cleanups, fn) }
+
+func (f *fakeT) Errorf(format string, args ...any) {
+	f.errors = append(f.errors, fmt.Sprintf(format, args...))
+}
+
+func (f *fakeT) finish() {
+	for i := len(f.cleanups) - 1; i >= 0; i-- {
+		f.cleanups[i]()
+	}
+}
+
+func record(order *[]string, name string, err error) func() error {
+	return func() error {
+		*order = append(*order, name)
+		return err
+	}
+}
+
+func TestTeardownOrder(t *testing.T) {
+	ft := &fakeT{T: t}
+	var order []string
+	network := Register(ft, Resource{Kind: "network", ID: "vpc"}, record(&order, "network", nil))
+	subnet := Register(ft, Resource{Kind: "subnetwork", ID: "subnet"}, record(&order, "subnetwork", nil), network)
+	tf := Register(ft, Resource{Kind: "terraform", ID: "dir"}, record(&order, "terraform", nil), subnet)
+	// Only the subnetwork must outlive the policy, so it goes first like a
+	// deferred call would.
+	Register(ft, Resource{Kind: "service connection policy", ID: "scp"}, record(&order, "policy", nil), subnet)
+	// The service account is registered after the policy but terraform uses
+	// it, so it must outlive the terraform configuration.
+	sa := Register(ft, Resource{Kind: "service account", ID: "sa"}, record(&order, "service account", nil))
+	tf.DependsOn(sa)
+
+	ft.finish()
+
+	want := "policy terraform service account subnetwork network"
+	if got := strings.Join(order, " "); got != want {
+		t.Errorf("Teardown order = %v, want = %v", got, want)
+	}
+	if len(ft.errors) != 0 {
+		t.Errorf("Errors = %v, want = none", ft.errors)
+	}
+}
+
+func TestLeakReport(t *testing.T) {
+	ft := &fakeT{T: t}
+	var order []string
+	network := Register(ft, Resource{Kind: "network", ID: "projects/p/global/networks/vpc"}, record(&order, "network", nil))
+	Register(ft, Resource{Kind: "subnetwork", ID: "projects/p/regions/r/subnetworks/subnet"}, record(&order, "subnetwork", errors.New("resource in use")), network)
+	Register(ft, Resource{Kind: "firewall", ID: "fw"}, record(&order, "firewall", nil))
+
+	ft.finish()
+
+	if got, want := strings.Join(order, " "), "firewall subnetwork"; got != want {
+		t.Errorf("Teardown order = %v, want = %v", got, want)
+	}
+	if got, want := len(ft.errors), 1; got != want {
+		t.Fatalf("Errors count = %v, want = %v", got, want)
+	}
+	report := ft.errors[0]
+	for _, want := range []string{
+		"2 fixture(s)",
+		"subnetwork projects/p/regions/r/subnetworks/subnet: resource in use",
+		"network projects/p/global/networks/vpc: skipped because dependent subnetwork",
+	} {
+		if !strings.Contains(report, want) {
+			t.Errorf("Leak report = %q, want it to contain %q", report, want)
+		}
+	}
+}
+
+func TestTeardownRunsOnce(t *testing.T) {
+	ft := &fakeT{T: t}
+	calls := 0
+	h := Register(ft, Resource{Kind: "network", ID: "vpc"}, func() error {
+		calls++
+		return nil
+	})
+	if err := h.Teardown(); err != nil {
+		t.Fatalf("Teardown() returned error: %v", err)
+	}
+	ft.finish()
+	if got, want := calls, 1; got != want {
+		t.Errorf("Teardown calls = %v, want = %v", got, want)
+	}
+}
+
+func TestTeardownRunsAfterFatal(t *testing.T) {
+	var order []string
+	t.Run("fatal", func(t *testing.T) {
+		Register(t, Resource{Kind: "network", ID: "vpc"}, record(&order, "network", nil))
+		t.SkipNow() // Exits through runtime.Goexit like t.Fatal, without failing.
+	})
+	if got, want := strings.Join(order, " "), "network"; got != want {
+		t.Errorf("Teardown order = %v, want = %v", got, want)
+	}
+}
+
+func TestGcloudConfigOutlivesTeardowns(t *testing.T) {
+	ft := &fakeT{T: t}
+	var env map[string]string
+	Register(ft, Resource{Kind: "network", ID: "vpc"}, func() error {
+		env = gcloud.Env(ft)
+		return nil
+	})
+	cfg := GcloudConfig(ft)
+	if cfg == nil {
+		t.Fatalf("GcloudConfig() = nil, want an isolated configuration")
+	}
+	t.Run("subtest", func(t *testing.T) {
+		if got := GcloudConfig(t); got != cfg {
+			t.Errorf("GcloudConfig() of a subtest = %+v, want = %+v", got, cfg)
+		}
+	})
+
+	ft.finish()
+
+	if got, want := env[gcloud.ConfigEnv], cfg.Dir; got != want {
+		t.Errorf("%s during teardown = %v, want = %v", gcloud.ConfigEnv, got, want)
+	}
+	if got := gcloud.ConfigFor(ft); got != nil {
+		t.Errorf("ConfigFor() after the test = %+v, want = nil", got)
+	}
+}
+
+func TestReportRecordsCleanup(t *testing.T) {
+	ft := &fakeT{T: t}
+	var order []string
+	network := Register(ft, Resource{Kind: "network", ID: "vpc"}, record(&order, "network", nil))
+	Register(ft, Resource{Kind: "subnetwork", ID: "subnet"}, record(&order, "subnetwork", errors.New("resource in use")), network)
+	rep := report.For(ft)
+
+	ft.finish()
+
+	var got []string
+	for _, res := range rep.Resources {
+		got = append(got, res.ID+" "+string(res.Cleanup))
+	}
+	if want := []string{"vpc leaked", "subnet leaked"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Resources = %v, want = %v", got, want)
+	}
+	if len(rep.Phases) != 1 || rep.Phases[0].Name != report.Destroy {
+		t.Errorf("Phases = %v, want = a destroy phase", rep.Phases)
+	}
+}
+
+func TestTerraformDestroysThroughCassette(t *testing.T) {
+	cassette.Dir = t.TempDir()
+	t.Setenv(cassette.ModeEnv, string(cassette.Replay))
+	recorded := `{"interactions": [{"command": "terraform", "args": ["destroy"], "exitCode": 0}]}`
+	if err := os.WriteFile(filepath.Join(cassette.Dir, "TestTerraformDestroysThroughCassette_replay.json"), []byte(recorded), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	// A destroy run by terraform instead of the cassette fails on the missing
+	// configuration.
+	passed := t.Run("replay", func(t *testing.T) {
+		cassette.Start(t)
+		Terraform(t, &terraform.Options{TerraformDir: filepath.Join(cassette.Dir, "missing")})
+	})
+	if !passed {
+		t.Errorf("teardown of Terraform failed, want the destroy replayed from the cassette")
+	}
+}
@@ -0,0 +1,214 @@
+// Copyright 2026 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package fixture
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/GoogleCloudPlatform/cloudnetworking-config-solutions/common_utils/gcloud"
+	"github.com/GoogleCloudPlatform/cloudnetworking-config-solutions/common_utils/retryable"
+	"github.com/GoogleCloudPlatform/cloudnetworking-config-solutions/common_utils/wait"
+	"github.com/gruntwork-io/terratest/modules/terraform"
+)
+
+const tokenCreator = "roles/iam.serviceAccountTokenCreator"
+
+// ServiceAccountOptions describe the service account created by
+// ImpersonatedServiceAccount and the roles it is granted.
+type ServiceAccountOptions struct {
+	// Name is the account ID, the part of the email before @.
+	Name        string
+	DisplayName string
+	// ProjectRoles are granted on the project of the client.
+	ProjectRoles []string
+	// OrgRoles are granted on the organization OrgID.
+	OrgID    string
+	OrgRoles []string
+}
+
+// ServiceAccount is a service account the caller of the test impersonates.
+// Its handle stands for the account and all of its bindings: resources that
+// depend on it are torn down while they still hold.
+type ServiceAccount struct {
+	*Handle
+	Email   string
+	Project string
+}
+
+// ImpersonatedServiceAccount creates a service account in the project of c,
+// grants it the project and organization roles of opts and grants the caller
+// of the test Token Creator on it. It then waits until the caller can mint
+// tokens for the account and until the account holds a permission of every
+// granted role, tested as the account itself. Every binding is registered as soon as it is made,
+// so a test failing halfway through still revokes the bindings made so far
+// before the account is deleted.
+func ImpersonatedServiceAccount(t testing.TB, c *gcloud.Client, opts ServiceAccountOptions, dependsOn ...*Handle) (*ServiceAccount, error) {
+	caller, err := c.Account(t)
+	if err != nil {
+		return nil, err
+	}
+	created, err := c.IAM.ServiceAccounts.Create(t, opts.Name, opts.DisplayName)
+	if err != nil {
+		return nil, err
+	}
+	email := created.Email
+	if email == "" {
+		email = fmt.Sprintf("%s@%s.iam.gserviceaccount.com", opts.Name, c.Project)
+	}
+	res := Resource{Kind: "service account", ID: fmt.Sprintf("projects/%s/serviceAccounts/%s", c.Project, email)}
+	account := Register(t, res, deletes(t, res, func() error {
+		return c.IAM.ServiceAccounts.Delete(t, email)
+	}), dependsOn...)
+	if _, err := wait.ForE(t, "service account "+email+" to exist", wait.DefaultOptions, wait.ServiceAccountExists(t, c, email)); err != nil {
+		return nil, err
+	}
+
+	member := gcloud.Member(email)
+	var bindings []*Handle
+	grant := func(scope, role, member string, add, remove func() error) error {
+		if err := retries(t, fmt.Sprintf("%s to be granted %s on %s", member, role, scope), add); err != nil {
+			return err
+		}
+		res := Resource{Kind: "iam binding", ID: fmt.Sprintf("%s %s %s", scope, role, member)}
+		bindings = append(bindings, Register(t, res, deletes(t, res, remove), account))
+		return nil
+	}
+	err = grant(res.ID, tokenCreator, gcloud.Member(caller), func() error {
+		return c.IAM.ServiceAccounts.AddIAMPolicyBinding(t, email, gcloud.Member(caller), tokenCreator)
+	}, func() error {
+		return c.IAM.ServiceAccounts.RemoveIAMPolicyBinding(t, email, gcloud.Member(caller), tokenCreator)
+	})
+	for _, role := range opts.ProjectRoles {
+		if err != nil {
+			break
+		}
+		err = grant("projects/"+c.Project, role, member, func() error {
+			return c.Projects.AddIAMPolicyBinding(t, member, role)
+		}, func() error {
+			return c.Projects.RemoveIAMPolicyBinding(t, member, role)
+		})
+	}
+	for _, role := range opts.OrgRoles {
+		if err != nil {
+			break
+		}
+		err = grant("organizations/"+opts.OrgID, role, member, func() error {
+			return c.Organizations.AddIAMPolicyBinding(t, opts.OrgID, member, role)
+		}, func() error {
+			return c.Organizations.RemoveIAMPolicyBinding(t, opts.OrgID, member, role)
+		})
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	// Dependents of the returned handle go before the bindings, which go
+	// before the account.
+	h := Register(t, Resource{Kind: "impersonation", ID: email}, nil, bindings...)
+	if _, err := wait.ForE(t, "impersonation of "+email+" to succeed", wait.DefaultOptions, wait.CanImpersonate(t, c, email)); err != nil {
+		return nil, err
+	}
+	scopes := []struct {
+		resource string
+		roles    []string
+	}{
+		{"projects/" + c.Project, opts.ProjectRoles},
+		{"organizations/" + opts.OrgID, opts.OrgRoles},
+	}
+	for _, scope := range scopes {
+		if len(scope.roles) == 0 {
+			continue
+		}
+		permissions, err := probes(t, c, email, scope.roles)
+		if err != nil {
+			return nil, err
+		}
+		if _, err := wait.ForE(t, fmt.Sprintf("roles of %s on %s to take effect", email, scope.resource), wait.DefaultOptions, wait.HasPermissions(t, c, email, scope.resource, permissions)); err != nil {
+			return nil, err
+		}
+	}
+	return &ServiceAccount{Handle: h, Email: email, Project: c.Project}, nil
+}
+
+// TerraformOptions returns a copy of options that runs terraform as the
+// service account, in its project and with the gcloud configuration isolated
+// for t. The copy retries the errors of the retryable catalog.
+func (sa *ServiceAccount) TerraformOptions(t testing.TB, options *terraform.Options) *terraform.Options {
+	options = retryable.With(t, options)
+	env := gcloud.Env(t)
+	if env == nil {
+		env = map[string]string{}
+	}
+	for k, v := range options.EnvVars {
+		env[k] = v
+	}
+	env["GOOGLE_PROJECT"] = sa.Project
+	env["GOOGLE_IMPERSONATE_SERVICE_ACCOUNT"] = sa.Email
+	options.EnvVars = env
+	return options
+}
+
+// probes returns one permission of each role to test for the account with
+// the given email: a get permission of the service the role belongs to when
+// there is one, as those apply to the project and to the organization alike.
+func probes(t testing.TB, c *gcloud.Client, email string, roles []string) ([]string, error) {
+	token, err := c.IAM.ServiceAccounts.PrintAccessToken(t, email)
+	if err != nil {
+		return nil, err
+	}
+	var permissions []string
+	for _, name := range roles {
+		role, err := c.IAM.Roles.Describe(t, token, name)
+		if err != nil {
+			return nil, err
+		}
+		included := slices.Sorted(slices.Values(role.IncludedPermissions))
+		if len(included) == 0 {
+			continue
+		}
+		probe := included[0]
+		service, _, _ := strings.Cut(strings.TrimPrefix(name, "roles/"), ".")
+		for _, p := range included {
+			if strings.HasPrefix(p, service+".") && strings.HasSuffix(p, ".get") {
+				probe = p
+				break
+			}
+		}
+		if !slices.Contains(permissions, probe) {
+			permissions = append(permissions, probe)
+		}
+	}
+	return permissions, nil
+}
+
+// retries calls fn until it succeeds, retrying transient failures such as
+// concurrent changes to the same IAM policy by parallel tests.
+func retries(t testing.TB, description string, fn func() error) error {
+	_, err := wait.ForE(t, description, wait.DefaultOptions, func() (bool, string, error) {
+		err := fn()
+		switch {
+		case err == nil:
+			return true, "done", nil
+		case gcloud.IsTransient(err):
+			return false, err.Error(), nil
+		default:
+			return false, "", err
+		}
+	})
+	return err
+}
@@ -0,0 +1,212 @@
+// Copyright 2026 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package fixture
+
+import (
+	"encoding/json"
+	"errors"
+	"reflect"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/GoogleCloudPlatform/cloudnetworking-config-solutions/common_utils/gcloud"
+	"github.com/GoogleCloudPlatform/cloudnetworking-config-solutions/common_utils/wait"
+	"github.com/gruntwork-io/terratest/modules/logger"
+	"github.com/gruntwork-io/terratest/modules/shell"
+	"github.com/gruntwork-io/terratest/modules/terraform"
+	terratesting "github.com/gruntwork-io/terratest/modules/testing"
+)
+
+// iamRunner records the gcloud commands, without their common flags, and
+// fails the first command starting with fail. It answers the API calls made
+// with curl as if the account held every permission tested, or only held
+// when it is set.
+type iamRunner struct {
+	commands []string
+	fail     string
+	held     []string
+}
+
+func (r *iamRunner) Run(_ terratesting.TestingT, cmd shell.Command) (string, string, error) {
+	if cmd.Command == "curl" {
+		return r.call(cmd.Args)
+	}
+	line := strings.Join(cmd.Args[:len(cmd.Args)-3], " ")
+	r.commands = append(r.commands, line)
+	if r.fail != "" && strings.HasPrefix(line, r.fail) {
+		r.fail = ""
+		return "", "ERROR: (gcloud.organizations.add-iam-policy-binding) PERMISSION_DENIED: Policy update access denied.", errors.New("exit status 1")
+	}
+	switch {
+	case strings.HasPrefix(line, "config get-value account"):
+		return `"dev@example.com"`, "", nil
+	case strings.HasPrefix(line, "iam service-accounts create"):
+		return `{"email": "sa-test@p.iam.gserviceaccount.com"}`, "", nil
+	case strings.HasPrefix(line, "auth print-access-token"):
+		return "token", "", nil
+	}
+	return "", "", nil
+}
+
+func (r *iamRunner) call(args []string) (string, string, error) {
+	if got, want := strings.Join(args[len(args)-2:], " "), "--oauth2-bearer token"; got != want {
+		return "", "unexpected credentials " + got, errors.New("exit status 2")
+	}
+	url := args[len(args)-3]
+	r.commands = append(r.commands, "curl "+url)
+	switch {
+	case url == "https://iam.googleapis.com/v1/roles/compute.networkAdmin":
+		return `{"includedPermissions": ["compute.addresses.create", "compute.networks.get", "resourcemanager.projects.get"]}`, "", nil
+	case url == "https://iam.googleapis.com/v1/roles/compute.orgFirewallPolicyAdmin":
+		return `{"includedPermissions": ["compute.firewallPolicies.create", "compute.firewallPolicies.get"]}`, "", nil
+	case strings.HasSuffix(url, ":testIamPermissions"):
+		var in struct{ Permissions []string }
+		if err := json.Unmarshal([]byte(args[slices.Index(args, "--data")+1]), &in); err != nil {
+			return "", err.Error(), errors.New("exit status 2")
+		}
+		r.commands[len(r.commands)-1] += " " + strings.Join(in.Permissions, ",")
+		held := in.Permissions
+		if r.held != nil {
+			held = r.held
+		}
+		out, _ := json.Marshal(map[string][]string{"permissions": held})
+		return string(out), "", nil
+	}
+	return "", `{"error": {"code": 404, "status": "NOT_FOUND"}}`, errors.New("exit status 22")
+}
+
+func newIAMClient(runner *iamRunner) *gcloud.Client {
+	c := gcloud.New("p")
+	c.Runner = runner
+	c.Logger = logger.Discard
+	return c
+}
+
+var saOptions = ServiceAccountOptions{
+	Name:         "sa-test",
+	DisplayName:  "Test SA",
+	ProjectRoles: []string{"roles/compute.networkAdmin"},
+	OrgID:        "42",
+	OrgRoles:     []string{"roles/compute.orgFirewallPolicyAdmin"},
+}
+
+func TestImpersonatedServiceAccount(t *testing.T) {
+	ft := &fakeT{T: t}
+	runner := &iamRunner{}
+	sa, err := ImpersonatedServiceAccount(ft, newIAMClient(runner), saOptions)
+	if err != nil {
+		t.Fatalf("ImpersonatedServiceAccount() error = %v", err)
+	}
+	if got, want := sa.Email, "sa-test@p.iam.gserviceaccount.com"; got != want {
+		t.Errorf("Email = %v, want = %v", got, want)
+	}
+	options := sa.TerraformOptions(ft, &terraform.Options{TerraformDir: "dir", EnvVars: map[string]string{"TF_LOG": "INFO"}})
+	wantEnv := map[string]string{
+		"TF_LOG":                             "INFO",
+		"GOOGLE_PROJECT":                     "p",
+		"GOOGLE_IMPERSONATE_SERVICE_ACCOUNT": "sa-test@p.iam.gserviceaccount.com",
+		gcloud.ConfigEnv:                     GcloudConfig(ft).Dir,
+	}
+	if !reflect.DeepEqual(options.EnvVars, wantEnv) {
+		t.Errorf("TerraformOptions().EnvVars = %v, want = %v", options.EnvVars, wantEnv)
+	}
+	if len(options.RetryableTerraformErrors) == 0 {
+		t.Errorf("TerraformOptions() has no retryable errors, want the defaults")
+	}
+	// Terraform registered on the account is destroyed while the bindings hold.
+	Register(ft, Resource{Kind: "terraform", ID: "dir"}, func() error {
+		runner.commands = append(runner.commands, "terraform destroy")
+		return nil
+	}, sa.Handle)
+
+	runner.commands = nil
+	ft.finish()
+	want := []string{
+		"terraform destroy",
+		"organizations remove-iam-policy-binding 42 --member=serviceAccount:sa-test@p.iam.gserviceaccount.com --role=roles/compute.orgFirewallPolicyAdmin",
+		"projects remove-iam-policy-binding p --member=serviceAccount:sa-test@p.iam.gserviceaccount.com --role=roles/compute.networkAdmin",
+		"iam service-accounts remove-iam-policy-binding sa-test@p.iam.gserviceaccount.com --member=user:dev@example.com --role=roles/iam.serviceAccountTokenCreator",
+		"iam service-accounts delete sa-test@p.iam.gserviceaccount.com",
+	}
+	if !reflect.DeepEqual(trimConditions(runner.commands), want) {
+		t.Errorf("teardown commands = %q, want = %q", runner.commands, want)
+	}
+	if len(ft.errors) > 0 {
+		t.Errorf("teardown errors = %v, want none", ft.errors)
+	}
+}
+
+func TestImpersonatedServiceAccountWaitsForAPermissionOfEachRole(t *testing.T) {
+	ft := &fakeT{T: t}
+	runner := &iamRunner{}
+	if _, err := ImpersonatedServiceAccount(ft, newIAMClient(runner), saOptions); err != nil {
+		t.Fatalf("ImpersonatedServiceAccount() error = %v", err)
+	}
+	var tested []string
+	for _, c := range runner.commands {
+		if strings.Contains(c, ":testIamPermissions") {
+			tested = append(tested, c)
+		}
+	}
+	want := []string{
+		"curl https://cloudresourcemanager.googleapis.com/v1/projects/p:testIamPermissions compute.networks.get",
+		"curl https://cloudresourcemanager.googleapis.com/v1/organizations/42:testIamPermissions compute.firewallPolicies.get",
+	}
+	if !reflect.DeepEqual(tested, want) {
+		t.Errorf("permission tests = %q, want = %q", tested, want)
+	}
+}
+
+func TestHasPermissionsWaitsForMissingPermissions(t *testing.T) {
+	runner := &iamRunner{held: []string{"compute.networks.get"}}
+	check := wait.HasPermissions(t, newIAMClient(runner), "sa-test@p.iam.gserviceaccount.com", "projects/p", []string{"compute.networks.get", "compute.subnetworks.get"})
+	done, status, err := check()
+	if err != nil {
+		t.Fatalf("HasPermissions() error = %v", err)
+	}
+	if done {
+		t.Errorf("HasPermissions() done = %v, want = %v", done, false)
+	}
+	if !strings.Contains(status, "compute.subnetworks.get") || strings.Contains(status, "compute.networks.get") {
+		t.Errorf("HasPermissions() status = %q, want only compute.subnetworks.get missing", status)
+	}
+}
+
+func TestImpersonatedServiceAccountRevertsPartialGrants(t *testing.T) {
+	ft := &fakeT{T: t}
+	runner := &iamRunner{fail: "organizations add-iam-policy-binding"}
+	if _, err := ImpersonatedServiceAccount(ft, newIAMClient(runner), saOptions); !gcloud.IsPermissionDenied(err) {
+		t.Fatalf("ImpersonatedServiceAccount() error = %v, want a permission error", err)
+	}
+	runner.commands = nil
+	ft.finish()
+	want := []string{
+		"projects remove-iam-policy-binding p --member=serviceAccount:sa-test@p.iam.gserviceaccount.com --role=roles/compute.networkAdmin",
+		"iam service-accounts remove-iam-policy-binding sa-test@p.iam.gserviceaccount.com --member=user:dev@example.com --role=roles/iam.serviceAccountTokenCreator",
+		"iam service-accounts delete sa-test@p.iam.gserviceaccount.com",
+	}
+	if !reflect.DeepEqual(trimConditions(runner.commands), want) {
+		t.Errorf("teardown commands = %q, want = %q", runner.commands, want)
+	}
+}
+
+func trimConditions(commands []string) []string {
+	out := make([]string, len(commands))
+	for i, c := range commands {
+		out[i] = strings.TrimSuffix(c, " --condition=None")
+	}
+	return out
+}
@@ -0,0 +1,212 @@
+// Copyright 2026 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package fixture
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/GoogleCloudPlatform/cloudnetworking-config-solutions/common_utils/filelock"
+	"github.com/GoogleCloudPlatform/cloudnetworking-config-solutions/common_utils/gcloud"
+	"github.com/GoogleCloudPlatform/cloudnetworking-config-solutions/common_utils/wait"
+)
+
+// LockDir holds the lock files that serialize changes to organization level
+// resources between the tests of a binary and between the test binaries
+// running on the machine.
+var LockDir = os.TempDir()
+
+// LockBucketEnv names the Cloud Storage bucket holding the lock objects that
+// serialize the same changes between runners, such as the CI jobs and the
+// developers sharing an organization. Without it only the runs of a machine
+// are serialized, and runs on other machines rely on their unique names.
+const LockBucketEnv = "TEST_LOCK_BUCKET"
+
+// LockTTL is how long a lock object outlives the last renewal of its holder.
+// A runner that crashed, or lost its network, keeps the others waiting that
+// long before they take its lock over. Holders renew their objects three
+// times per TTL.
+var LockTTL = 10 * time.Minute
+
+var unsafeLockChars = regexp.MustCompile(`[^a-zA-Z0-9_.-]+`)
+
+// lock waits until it holds the lock called name and returns its release.
+//
+// On the machine, the lock is a file lock on a file of LockDir that is never
+// removed, so the system releases it when the process holding it exits, even
+// if it crashed, and two processes can never both believe they hold it. The
+// file records the process ID of the holder for the messages of the processes
+// waiting on it.
+//
+// When $TEST_LOCK_BUCKET is set and c is not nil, the holder of the file lock
+// also creates the object cso-test-locks/<name> of the bucket, which only
+// succeeds when the object does not exist, and renews it until the release
+// deletes it. An object older than LockTTL was left by a runner that stopped
+// renewing it and is taken over. The object protects the runners that use
+// the bucket: changes made by hand, or by runs configured with another bucket
+// or none, are not serialized, and a holder cut off from Cloud Storage for
+// longer than LockTTL loses its lock while it still believes it holds it.
+func lock(t testing.TB, c *gcloud.Client, name string, opts wait.Options) (func(), error) {
+	name = unsafeLockChars.ReplaceAllString(name, "-")
+	release, err := lockFile(t, name, opts)
+	if err != nil {
+		return nil, err
+	}
+	bucket := os.Getenv(LockBucketEnv)
+	if bucket == "" || c == nil {
+		return release, nil
+	}
+	releaseObject, err := lockObject(t, c, "gs://"+bucket+"/cso-test-locks/"+name, opts)
+	if err != nil {
+		release()
+		return nil, err
+	}
+	return func() {
+		releaseObject()
+		release()
+	}, nil
+}
+
+// lockFile takes the lock of the machine.
+func lockFile(t testing.TB, name string, opts wait.Options) (func(), error) {
+	path := filepath.Join(LockDir, "cso-test-"+name+".lock")
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_RDWR, 0o644)
+	if err != nil {
+		return nil, err
+	}
+	_, err = wait.ForE(t, "lock "+name, opts, func() (bool, string, error) {
+		ok, err := filelock.TryLock(f)
+		if err != nil {
+			return false, "", fmt.Errorf("locking %s: %w", path, err)
+		}
+		if ok {
+			return true, "acquired", nil
+		}
+		b, _ := os.ReadFile(path)
+		return false, fmt.Sprintf("held by process %s", strings.TrimSpace(string(b))), nil
+	})
+	if err == nil {
+		// A holder that crashed left its process ID behind.
+		err = f.Truncate(0)
+	}
+	if err == nil {
+		_, err = f.WriteAt([]byte(fmt.Sprintf("%d\n", os.Getpid())), 0)
+	}
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	return func() {
+		f.Truncate(0)
+		filelock.Unlock(f)
+		f.Close()
+	}, nil
+}
+
+// lockObject takes the lock of the runners sharing the bucket of url.
+func lockObject(t testing.TB, c *gcloud.Client, url string, opts wait.Options) (func(), error) {
+	host, _ := os.Hostname()
+	holder := fmt.Sprintf("%s, process %d, %s\n", host, os.Getpid(), t.Name())
+	var generation string
+	_, err := wait.ForE(t, "lock "+url, opts, func() (bool, string, error) {
+		gen, err := createObject(t, c, url, holder, "0")
+		switch {
+		case err == nil:
+			generation = gen
+			return true, "acquired", nil
+		case gcloud.IsTransient(err):
+			return false, err.Error(), nil
+		case !gcloud.IsPreconditionFailed(err):
+			return false, "", err
+		}
+		o, err := c.Objects.Describe(t, url)
+		switch {
+		case gcloud.IsNotFound(err):
+			return false, "released", nil
+		case gcloud.IsTransient(err):
+			return false, err.Error(), nil
+		case err != nil:
+			return false, "", err
+		}
+		age := time.Since(o.TimeCreated)
+		if age < LockTTL {
+			return false, fmt.Sprintf("held by another runner, renewed %s ago", age.Round(time.Second)), nil
+		}
+		err = c.Objects.Delete(t, url, o.Generation.String())
+		if err != nil && !gcloud.IsNotFound(err) && !gcloud.IsPreconditionFailed(err) && !gcloud.IsTransient(err) {
+			return false, "", err
+		}
+		return false, fmt.Sprintf("took over the lock its holder stopped renewing %s ago", age.Round(time.Second)), nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// The renewal rewrites the object, which gives it a new creation time,
+	// until the release. It stops when another runner took the lock over.
+	stop, stopped := make(chan struct{}), make(chan struct{})
+	go func() {
+		defer close(stopped)
+		ticker := time.NewTicker(LockTTL / 3)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-stop:
+				return
+			case <-ticker.C:
+			}
+			gen, err := createObject(t, c, url, holder, generation)
+			switch {
+			case err == nil:
+				generation = gen
+			case gcloud.IsPreconditionFailed(err) || gcloud.IsNotFound(err):
+				return
+			}
+		}
+	}()
+	return func() {
+		close(stop)
+		<-stopped
+		// An object left behind expires after LockTTL.
+		c.Objects.Delete(t, url, generation)
+	}, nil
+}
+
+// createObject writes the lock object at url if its generation is
+// ifGenerationMatch and returns its new generation. The generation is read
+// until it can be, since the object is held once it is written.
+func createObject(t testing.TB, c *gcloud.Client, url, holder, ifGenerationMatch string) (string, error) {
+	if err := c.Objects.Create(t, url, holder, ifGenerationMatch); err != nil {
+		return "", err
+	}
+	var generation string
+	_, err := wait.ForE(t, "generation of "+url, wait.DefaultOptions, func() (bool, string, error) {
+		o, err := c.Objects.Describe(t, url)
+		switch {
+		case gcloud.IsTransient(err):
+			return false, err.Error(), nil
+		case err != nil:
+			return false, "", err
+		}
+		generation = o.Generation.String()
+		return true, generation, nil
+	})
+	return generation, err
+}
@@ -0,0 +1,246 @@
+// Copyright 2026 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package fixture
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/GoogleCloudPlatform/cloudnetworking-config-solutions/common_utils/gcloud"
+	"github.com/GoogleCloudPlatform/cloudnetworking-config-solutions/common_utils/wait"
+	"github.com/gruntwork-io/terratest/modules/logger"
+	"github.com/gruntwork-io/terratest/modules/shell"
+	terratesting "github.com/gruntwork-io/terratest/modules/testing"
+)
+
+var shortWait = wait.Options{Timeout: 200 * time.Millisecond, Interval: 10 * time.Millisecond, MaxInterval: 10 * time.Millisecond}
+
+func useLockDir(t *testing.T) string {
+	dir := t.TempDir()
+	old := LockDir
+	LockDir = dir
+	t.Cleanup(func() { LockDir = old })
+	return dir
+}
+
+func TestLockWaitsForHolder(t *testing.T) {
+	useLockDir(t)
+	release, err := lock(t, nil, "org-42", shortWait)
+	if err != nil {
+		t.Fatalf("lock() error = %v", err)
+	}
+	var timeout *wait.TimeoutError
+	if _, err := lock(t, nil, "org-42", shortWait); !errors.As(err, &timeout) {
+		t.Errorf("lock() of a held lock error = %v, want a timeout", err)
+	}
+	release()
+	release, err = lock(t, nil, "org-42", shortWait)
+	if err != nil {
+		t.Fatalf("lock() after release error = %v", err)
+	}
+	release()
+}
+
+func TestLockIgnoresLockFileOfExitedProcess(t *testing.T) {
+	dir := useLockDir(t)
+	path := filepath.Join(dir, "cso-test-org-42.lock")
+	if err := os.WriteFile(path, []byte("99999999\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	release, err := lock(t, nil, "org-42", shortWait)
+	if err != nil {
+		t.Fatalf("lock() over a left over lock file error = %v", err)
+	}
+	b, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := string(b), fmt.Sprintf("%d\n", os.Getpid()); got != want {
+		t.Errorf("lock file = %q, want = %q", got, want)
+	}
+	release()
+}
+
+func TestLockIsHeldByOneCallerAtATime(t *testing.T) {
+	useLockDir(t)
+	opts := wait.Options{Timeout: 10 * time.Second, Interval: time.Millisecond, MaxInterval: time.Millisecond}
+	var holders, overlaps atomic.Int32
+	var wg sync.WaitGroup
+	for range 8 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for range 20 {
+				release, err := lock(t, nil, "org-42", opts)
+				if err != nil {
+					t.Errorf("lock() error = %v", err)
+					return
+				}
+				if holders.Add(1) > 1 {
+					overlaps.Add(1)
+				}
+				holders.Add(-1)
+				release()
+			}
+		}()
+	}
+	wg.Wait()
+	if n := overlaps.Load(); n != 0 {
+		t.Errorf("lock() held by several callers %d times, want = 0", n)
+	}
+}
+
+// bucketRunner serves the gcloud storage commands of the lock objects from
+// memory, with the generation preconditions of Cloud Storage.
+type bucketRunner struct {
+	mu      sync.Mutex
+	next    int64
+	objects map[string]gcloud.Object
+}
+
+func (r *bucketRunner) put(url string, created time.Time) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.store(url, created)
+}
+
+func (r *bucketRunner) store(url string, created time.Time) {
+	r.next++
+	r.objects[url] = gcloud.Object{Generation: json.Number(strconv.FormatInt(r.next, 10)), TimeCreated: created}
+}
+
+func (r *bucketRunner) get(url string) (gcloud.Object, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	o, ok := r.objects[url]
+	return o, ok
+}
+
+func (r *bucketRunner) Run(_ terratesting.TestingT, cmd shell.Command) (string, string, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	failed := errors.New("exit status 1")
+	args := cmd.Args[:len(cmd.Args)-3]
+	line := strings.Join(args, " ")
+	match := func(url string) bool {
+		want := strings.TrimPrefix(args[len(args)-1], "--if-generation-match=")
+		o, ok := r.objects[url]
+		return want == "0" && !ok || ok && o.Generation.String() == want
+	}
+	switch {
+	case strings.HasPrefix(line, "storage cp "):
+		url := args[3]
+		if !match(url) {
+			return "", "HTTPError 412: At least one of the pre-conditions you specified did not hold.", failed
+		}
+		r.store(url, time.Now())
+		return "", "", nil
+	case strings.HasPrefix(line, "storage objects describe "):
+		o, ok := r.objects[args[3]]
+		if !ok {
+			return "", "HTTPError 404: No URLs matched", failed
+		}
+		return fmt.Sprintf(`{"generation": %q, "timeCreated": %q}`, o.Generation, o.TimeCreated.Format(time.RFC3339Nano)), "", nil
+	case strings.HasPrefix(line, "storage rm "):
+		url := args[2]
+		if _, ok := r.objects[url]; !ok {
+			return "", "HTTPError 404: No URLs matched", failed
+		}
+		if !match(url) {
+			return "", "HTTPError 412: At least one of the pre-conditions you specified did not hold.", failed
+		}
+		delete(r.objects, url)
+		return "", "", nil
+	}
+	return "", "unexpected command " + line, failed
+}
+
+func useLockBucket(t *testing.T) (*gcloud.Client, *bucketRunner) {
+	useLockDir(t)
+	t.Setenv(LockBucketEnv, "locks")
+	runner := &bucketRunner{objects: map[string]gcloud.Object{}}
+	c := gcloud.New("p")
+	c.Runner = runner
+	c.Logger = logger.Discard
+	return c, runner
+}
+
+const lockObjectURL = "gs://locks/cso-test-locks/org-42"
+
+func TestLockWaitsForObjectOfAnotherRunner(t *testing.T) {
+	c, runner := useLockBucket(t)
+	runner.put(lockObjectURL, time.Now())
+
+	var timeout *wait.TimeoutError
+	if _, err := lock(t, c, "org-42", shortWait); !errors.As(err, &timeout) {
+		t.Fatalf("lock() of an object held by another runner error = %v, want a timeout", err)
+	}
+	// The lock of the machine is released with the failed attempt.
+	release, err := lock(t, nil, "org-42", shortWait)
+	if err != nil {
+		t.Fatalf("lock() of the machine after the timeout error = %v", err)
+	}
+	release()
+}
+
+func TestLockTakesOverExpiredObject(t *testing.T) {
+	c, runner := useLockBucket(t)
+	runner.put(lockObjectURL, time.Now().Add(-LockTTL-time.Minute))
+	stale, _ := runner.get(lockObjectURL)
+
+	release, err := lock(t, c, "org-42", shortWait)
+	if err != nil {
+		t.Fatalf("lock() over an expired object error = %v", err)
+	}
+	held, ok := runner.get(lockObjectURL)
+	if !ok || held.Generation == stale.Generation {
+		t.Errorf("lock object = %+v, want a new generation replacing %v", held, stale.Generation)
+	}
+	release()
+	if o, ok := runner.get(lockObjectURL); ok {
+		t.Errorf("lock object after release = %+v, want it deleted", o)
+	}
+}
+
+func TestLockRenewsObjectUntilRelease(t *testing.T) {
+	c, runner := useLockBucket(t)
+	old := LockTTL
+	LockTTL = 30 * time.Millisecond
+	t.Cleanup(func() { LockTTL = old })
+
+	release, err := lock(t, c, "org-42", shortWait)
+	if err != nil {
+		t.Fatalf("lock() error = %v", err)
+	}
+	first, _ := runner.get(lockObjectURL)
+	time.Sleep(5 * LockTTL)
+	renewed, ok := runner.get(lockObjectURL)
+	if !ok || renewed.Generation == first.Generation {
+		t.Errorf("lock object after %v = %+v, want it renewed from %+v", 5*LockTTL, renewed, first)
+	}
+	release()
+	if o, ok := runner.get(lockObjectURL); ok {
+		t.Errorf("lock object after release = %+v, want it deleted", o)
+	}
+}
@@ -0,0 +1,150 @@
+// Copyright 2026 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package fixture
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/GoogleCloudPlatform/cloudnetworking-config-solutions/common_utils/gcloud"
+	"github.com/GoogleCloudPlatform/cloudnetworking-config-solutions/common_utils/naming"
+	"github.com/GoogleCloudPlatform/cloudnetworking-config-solutions/common_utils/wait"
+)
+
+// FirewallPolicy is a hierarchical firewall policy of an organization.
+type FirewallPolicy struct {
+	*Handle
+	OrgID     string
+	ShortName string
+}
+
+// OrgFirewallPolicy creates a hierarchical firewall policy in the organization
+// orgID and registers its deletion. The short name is derived from prefix
+// through the naming package and starts with naming.OrgPrefix, so it is
+// unique to the run and the janitor can find it if the run is interrupted.
+// The policy is only deleted once none of its associations is left.
+func OrgFirewallPolicy(t testing.TB, c *gcloud.Client, orgID, prefix string, dependsOn ...*Handle) (*FirewallPolicy, error) {
+	name := naming.Name(t, naming.FirewallPolicy, naming.OrgPrefix+prefix)
+	err := withOrgLock(t, c, orgID, func() error {
+		return c.FirewallPolicies.Create(t, orgID, name, "Integration test policy of "+t.Name())
+	})
+	if err != nil {
+		return nil, err
+	}
+	res := Resource{Kind: "firewall policy", ID: fmt.Sprintf("organizations/%s/firewallPolicies/%s", orgID, name)}
+	h := Register(t, res, func() error {
+		_, err := wait.ForE(t, res.String()+" to have no associations", wait.DefaultOptions, func() (bool, string, error) {
+			p, err := c.FirewallPolicies.Describe(t, orgID, name)
+			switch {
+			case gcloud.IsNotFound(err):
+				return true, "deleted", nil
+			case gcloud.IsTransient(err):
+				return false, err.Error(), nil
+			case err != nil:
+				return false, "", err
+			case len(p.Associations) > 0:
+				return false, fmt.Sprintf("%d association(s) left", len(p.Associations)), nil
+			}
+			return true, "no associations", nil
+		})
+		if err != nil {
+			return err
+		}
+		return withOrgLock(t, c, orgID, deletes(t, res, func() error {
+			return c.FirewallPolicies.Delete(t, orgID, name)
+		}))
+	}, dependsOn...)
+	return &FirewallPolicy{Handle: h, OrgID: orgID, ShortName: name}, nil
+}
+
+// OrgFirewallPolicyRule adds a rule to policy and registers its deletion
+// before the policy is deleted.
+func OrgFirewallPolicyRule(t testing.TB, c *gcloud.Client, policy *FirewallPolicy, opts gcloud.FirewallPolicyRuleOptions, dependsOn ...*Handle) (*Handle, error) {
+	if err := c.FirewallPolicies.CreateRule(t, policy.OrgID, policy.ShortName, opts); err != nil {
+		return nil, err
+	}
+	res := Resource{Kind: "firewall policy rule", ID: fmt.Sprintf("%s/rules/%d", policy.ID, opts.Priority)}
+	return Register(t, res, deletes(t, res, func() error {
+		return c.FirewallPolicies.DeleteRule(t, policy.OrgID, policy.ShortName, opts.Priority)
+	}), append([]*Handle{policy.Handle}, dependsOn...)...), nil
+}
+
+// OrgFirewallPolicyAssociation associates policy with its organization, or
+// with opts.Folder, and registers the removal of the association before the
+// policy is deleted. A target holds a single association, so the fixture
+// leases the target from the moment it associates the policy until the
+// association is gone, and waits for the lease if another test holds it.
+func OrgFirewallPolicyAssociation(t testing.TB, c *gcloud.Client, policy *FirewallPolicy, opts gcloud.FirewallPolicyAssociationOptions, dependsOn ...*Handle) (*Handle, error) {
+	target := "organizations/" + policy.OrgID
+	if opts.Folder != "" {
+		target = "folders/" + opts.Folder
+	}
+	release, err := lock(t, c, "association-"+target, wait.LongOptions)
+	if err != nil {
+		return nil, err
+	}
+	err = withOrgLock(t, c, policy.OrgID, func() error {
+		return c.FirewallPolicies.Associate(t, policy.OrgID, policy.ShortName, opts)
+	})
+	if err != nil {
+		release()
+		return nil, err
+	}
+	res := Resource{Kind: "firewall policy association", ID: fmt.Sprintf("%s/associations/%s", policy.ID, opts.Name)}
+	return Register(t, res, func() error {
+		err := withOrgLock(t, c, policy.OrgID, deletes(t, res, func() error {
+			return c.FirewallPolicies.DeleteAssociation(t, policy.OrgID, policy.ShortName, opts.Name)
+		}))
+		if err != nil {
+			return err
+		}
+		_, err = wait.ForE(t, res.String()+" to be detached", wait.DefaultOptions, func() (bool, string, error) {
+			p, err := c.FirewallPolicies.Describe(t, policy.OrgID, policy.ShortName)
+			switch {
+			case gcloud.IsNotFound(err):
+				return true, "policy deleted", nil
+			case gcloud.IsTransient(err):
+				return false, err.Error(), nil
+			case err != nil:
+				return false, "", err
+			}
+			for _, a := range p.Associations {
+				if a.Name == opts.Name || strings.HasSuffix(a.AttachmentTarget, target) {
+					return false, "still attached to " + a.AttachmentTarget, nil
+				}
+			}
+			return true, "detached", nil
+		})
+		if err != nil {
+			return err
+		}
+		// A failed removal keeps the lease until the process exits, so the
+		// tests that follow do not replace an association still in use.
+		release()
+		return nil
+	}, append([]*Handle{policy.Handle}, dependsOn...)...), nil
+}
+
+// withOrgLock runs fn while holding the lock that serializes the changes to
+// the firewall policies of the organization orgID.
+func withOrgLock(t testing.TB, c *gcloud.Client, orgID string, fn func() error) error {
+	release, err := lock(t, c, "org-"+orgID, wait.DefaultOptions)
+	if err != nil {
+		return err
+	}
+	defer release()
+	return fn()
+}
@@ -0,0 +1,101 @@
+// Copyright 2026 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package fixture
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/GoogleCloudPlatform/cloudnetworking-config-solutions/common_utils/gcloud"
+	"github.com/GoogleCloudPlatform/cloudnetworking-config-solutions/common_utils/naming"
+	"github.com/GoogleCloudPlatform/cloudnetworking-config-solutions/common_utils/wait"
+	"github.com/gruntwork-io/terratest/modules/logger"
+	"github.com/gruntwork-io/terratest/modules/shell"
+	terratesting "github.com/gruntwork-io/terratest/modules/testing"
+)
+
+// orgRunner records the firewall policy commands, without their common
+// flags, and reports the association as attached until it is deleted.
+type orgRunner struct {
+	commands []string
+	attached bool
+}
+
+func (r *orgRunner) Run(_ terratesting.TestingT, cmd shell.Command) (string, string, error) {
+	line := strings.Join(cmd.Args[:len(cmd.Args)-3], " ")
+	switch {
+	case strings.HasPrefix(line, "compute firewall-policies describe"):
+		if r.attached {
+			return `{"associations": [{"name": "assoc", "attachmentTarget": "organizations/42"}]}`, "", nil
+		}
+		return `{"associations": []}`, "", nil
+	case strings.HasPrefix(line, "compute firewall-policies associations create"):
+		r.attached = true
+	case strings.HasPrefix(line, "compute firewall-policies associations delete"):
+		r.attached = false
+	}
+	r.commands = append(r.commands, line)
+	return "", "", nil
+}
+
+func TestOrgFirewallPolicy(t *testing.T) {
+	useLockDir(t)
+	ft := &fakeT{T: t}
+	runner := &orgRunner{}
+	c := gcloud.New("p")
+	c.Runner = runner
+	c.Logger = logger.Discard
+
+	policy, err := OrgFirewallPolicy(ft, c, "42", "sp")
+	if err != nil {
+		t.Fatalf("OrgFirewallPolicy() error = %v", err)
+	}
+	name := naming.Name(ft, naming.FirewallPolicy, naming.OrgPrefix+"sp")
+	if policy.ShortName != name || !strings.HasPrefix(name, naming.OrgPrefix) {
+		t.Errorf("ShortName = %v, want = %v", policy.ShortName, name)
+	}
+	if _, err := OrgFirewallPolicyRule(ft, c, policy, gcloud.FirewallPolicyRuleOptions{Priority: 1000, Action: "allow"}); err != nil {
+		t.Fatalf("OrgFirewallPolicyRule() error = %v", err)
+	}
+	if _, err := OrgFirewallPolicyAssociation(ft, c, policy, gcloud.FirewallPolicyAssociationOptions{Name: "assoc", Replace: true}); err != nil {
+		t.Fatalf("OrgFirewallPolicyAssociation() error = %v", err)
+	}
+	var timeout *wait.TimeoutError
+	if _, err := lock(t, nil, "association-organizations-42", shortWait); !errors.As(err, &timeout) {
+		t.Errorf("lock() of the association lease error = %v, want it held until teardown", err)
+	}
+
+	runner.commands = nil
+	ft.finish()
+	want := []string{
+		"compute firewall-policies associations delete assoc --firewall-policy=" + name + " --organization=42",
+		"compute firewall-policies rules delete 1000 --firewall-policy=" + name + " --organization=42",
+		"compute firewall-policies delete " + name + " --organization=42",
+	}
+	if !reflect.DeepEqual(runner.commands, want) {
+		t.Errorf("teardown commands = %q, want = %q", runner.commands, want)
+	}
+	if len(ft.errors) > 0 {
+		t.Errorf("teardown errors = %v, want none", ft.errors)
+	}
+	release, err := lock(t, nil, "association-organizations-42", shortWait)
+	if err != nil {
+		t.Errorf("lock() of the association lease after teardown error = %v, want it released", err)
+	} else {
+		release()
+	}
+}
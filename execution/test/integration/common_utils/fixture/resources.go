@@ -0,0 +1,134 @@
+// Copyright 2026 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package fixture
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/GoogleCloudPlatform/cloudnetworking-config-solutions/common_utils/cassette"
+	"github.com/GoogleCloudPlatform/cloudnetworking-config-solutions/common_utils/gcloud"
+	"github.com/GoogleCloudPlatform/cloudnetworking-config-solutions/common_utils/wait"
+	"github.com/gruntwork-io/terratest/modules/terraform"
+)
+
+// deletes retries del until the resource is gone. Resources are often still in
+// use for a while by the dependents torn down just before them.
+func deletes(t testing.TB, res Resource, del func() error) func() error {
+	return func() error {
+		_, err := wait.ForE(t, res.String()+" to be deleted", wait.DefaultOptions, wait.Deletes(del))
+		return err
+	}
+}
+
+// Network creates a VPC network and registers its deletion.
+func Network(t testing.TB, c *gcloud.Client, name string, opts gcloud.NetworkOptions, dependsOn ...*Handle) (*Handle, error) {
+	if _, err := c.Networks.Create(t, name, opts); err != nil {
+		return nil, err
+	}
+	res := Resource{Kind: "network", ID: fmt.Sprintf("projects/%s/global/networks/%s", c.Project, name)}
+	return Register(t, res, deletes(t, res, func() error {
+		return c.Networks.Delete(t, name)
+	}), dependsOn...), nil
+}
+
+// Subnet creates a subnetwork and registers its deletion. Pass the handle of
+// its network so that the network outlives it.
+func Subnet(t testing.TB, c *gcloud.Client, name string, opts gcloud.SubnetOptions, dependsOn ...*Handle) (*Handle, error) {
+	if _, err := c.Subnets.Create(t, name, opts); err != nil {
+		return nil, err
+	}
+	res := Resource{Kind: "subnetwork", ID: fmt.Sprintf("projects/%s/regions/%s/subnetworks/%s", c.Project, opts.Region, name)}
+	return Register(t, res, deletes(t, res, func() error {
+		return c.Subnets.Delete(t, opts.Region, name)
+	}), dependsOn...), nil
+}
+
+// ServiceConnectionPolicy creates a service connection policy and registers
+// its deletion.
+func ServiceConnectionPolicy(t testing.TB, c *gcloud.Client, name string, opts gcloud.ServiceConnectionPolicyOptions, dependsOn ...*Handle) (*Handle, error) {
+	if _, err := c.ServiceConnectionPolicies.Create(t, name, opts); err != nil {
+		return nil, err
+	}
+	res := Resource{Kind: "service connection policy", ID: fmt.Sprintf("projects/%s/locations/%s/serviceConnectionPolicies/%s", c.Project, opts.Region, name)}
+	return Register(t, res, deletes(t, res, func() error {
+		return c.ServiceConnectionPolicies.Delete(t, opts.Region, name)
+	}), dependsOn...), nil
+}
+
+// Terraform registers "terraform destroy" for options. Call it before
+// "terraform apply" so that a partially applied configuration is destroyed
+// too. dependsOn lists the resources the configuration was applied on top of,
+// such as a network created outside of terraform. The destroy goes through
+// the cassette of the test, if any, like its gcloud commands.
+func Terraform(t testing.TB, options *terraform.Options, dependsOn ...*Handle) *Handle {
+	res := Resource{Kind: "terraform", ID: options.TerraformDir}
+	return Register(t, res, func() error {
+		_, err := cassette.DestroyE(t, options)
+		return err
+	}, dependsOn...)
+}
+
+// Address reserves an IP address, or with Purpose VPC_PEERING a private
+// services access range, and registers its release.
+func Address(t testing.TB, c *gcloud.Client, name string, opts gcloud.AddressOptions, dependsOn ...*Handle) (*Handle, error) {
+	if _, err := c.Addresses.Create(t, name, opts); err != nil {
+		return nil, err
+	}
+	location := "global"
+	if opts.Region != "" {
+		location = "regions/" + opts.Region
+	}
+	res := Resource{Kind: "address", ID: fmt.Sprintf("projects/%s/%s/addresses/%s", c.Project, location, name)}
+	return Register(t, res, deletes(t, res, func() error {
+		return c.Addresses.Delete(t, opts.Region, name)
+	}), dependsOn...), nil
+}
+
+// VPCPeering connects network to service networking over the allocated ranges
+// and registers the removal of the connection. Pass the handles of the ranges
+// so that they are released only after the connection is gone.
+func VPCPeering(t testing.TB, c *gcloud.Client, network string, ranges []string, dependsOn ...*Handle) (*Handle, error) {
+	if err := c.VPCPeerings.Connect(t, network, ranges...); err != nil {
+		return nil, err
+	}
+	res := Resource{Kind: "vpc peering", ID: fmt.Sprintf("projects/%s/global/networks/%s/servicenetworking", c.Project, network)}
+	return Register(t, res, deletes(t, res, func() error {
+		return c.VPCPeerings.Delete(t, network)
+	}), dependsOn...), nil
+}
+
+// Firewall creates a firewall rule and registers its deletion.
+func Firewall(t testing.TB, c *gcloud.Client, name string, opts gcloud.FirewallOptions, dependsOn ...*Handle) (*Handle, error) {
+	if _, err := c.Firewalls.Create(t, name, opts); err != nil {
+		return nil, err
+	}
+	res := Resource{Kind: "firewall rule", ID: fmt.Sprintf("projects/%s/global/firewalls/%s", c.Project, name)}
+	return Register(t, res, deletes(t, res, func() error {
+		return c.Firewalls.Delete(t, name)
+	}), dependsOn...), nil
+}
+
+// Instance creates a VM and registers its deletion. Pass the handle of its
+// subnetwork so that the subnetwork outlives it.
+func Instance(t testing.TB, c *gcloud.Client, name string, opts gcloud.InstanceOptions, dependsOn ...*Handle) (*Handle, error) {
+	if _, err := c.Instances.Create(t, name, opts); err != nil {
+		return nil, err
+	}
+	res := Resource{Kind: "instance", ID: fmt.Sprintf("projects/%s/zones/%s/instances/%s", c.Project, opts.Zone, name)}
+	return Register(t, res, deletes(t, res, func() error {
+		return c.Instances.Delete(t, opts.Zone, name)
+	}), dependsOn...), nil
+}
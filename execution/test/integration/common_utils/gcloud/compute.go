@@ -0,0 +1,689 @@
+// Copyright 2026 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gcloud
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/gruntwork-io/terratest/modules/testing"
+)
+
+// Network is the subset of a compute network returned by gcloud that the
+// tests inspect.
+type Network struct {
+	Name                  string           `json:"name"`
+	ID                    string           `json:"id"`
+	SelfLink              string           `json:"selfLink"`
+	CreationTimestamp     string           `json:"creationTimestamp"`
+	AutoCreateSubnetworks bool             `json:"autoCreateSubnetworks"`
+	MTU                   int              `json:"mtu"`
+	Subnetworks           []string         `json:"subnetworks"`
+	Peerings              []NetworkPeering `json:"peerings"`
+	RoutingConfig         struct {
+		RoutingMode string `json:"routingMode"`
+	} `json:"routingConfig"`
+}
+
+// NetworkPeering describes one peering of a Network.
+type NetworkPeering struct {
+	Name    string `json:"name"`
+	Network string `json:"network"`
+	State   string `json:"state"`
+}
+
+// NetworkOptions are the flags used when creating a network. Empty fields are
+// left to the gcloud defaults, except SubnetMode which defaults to "custom".
+type NetworkOptions struct {
+	SubnetMode     string
+	BGPRoutingMode string
+	MTU            int
+}
+
+// NetworksService wraps "gcloud compute networks".
+type NetworksService struct{ c *Client }
+
+// Create creates a VPC network.
+func (s *NetworksService) Create(t testing.TestingT, name string, opts NetworkOptions) (*Network, error) {
+	if opts.SubnetMode == "" {
+		opts.SubnetMode = "custom"
+	}
+	args := []string{"compute", "networks", "create", name, "--subnet-mode=" + opts.SubnetMode}
+	if opts.BGPRoutingMode != "" {
+		args = append(args, "--bgp-routing-mode="+opts.BGPRoutingMode)
+	}
+	if opts.MTU != 0 {
+		args = append(args, fmt.Sprintf("--mtu=%d", opts.MTU))
+	}
+	out := &Network{}
+	return out, s.c.RunJSON(t, out, args...)
+}
+
+// Describe returns the network called name.
+func (s *NetworksService) Describe(t testing.TestingT, name string) (*Network, error) {
+	out := &Network{}
+	return out, s.c.RunJSON(t, out, "compute", "networks", "describe", name)
+}
+
+// List returns the networks matching filter, or all networks if it is empty.
+func (s *NetworksService) List(t testing.TestingT, filter string) ([]Network, error) {
+	var out []Network
+	err := s.c.RunJSON(t, &out, withFilter([]string{"compute", "networks", "list"}, filter)...)
+	return out, err
+}
+
+// Delete deletes the network called name.
+func (s *NetworksService) Delete(t testing.TestingT, name string) error {
+	_, err := s.c.Run(t, "compute", "networks", "delete", name)
+	return err
+}
+
+// Subnet is the subset of a compute subnetwork returned by gcloud that the
+// tests inspect.
+type Subnet struct {
+	Name                  string `json:"name"`
+	ID                    string `json:"id"`
+	SelfLink              string `json:"selfLink"`
+	CreationTimestamp     string `json:"creationTimestamp"`
+	Network               string `json:"network"`
+	Region                string `json:"region"`
+	IPCIDRRange           string `json:"ipCidrRange"`
+	PrivateIPGoogleAccess bool   `json:"privateIpGoogleAccess"`
+	Purpose               string `json:"purpose"`
+	State                 string `json:"state"`
+}
+
+// SubnetOptions are the flags used when creating a subnetwork.
+type SubnetOptions struct {
+	Network                     string
+	Region                      string
+	Range                       string
+	EnablePrivateIPGoogleAccess bool
+	EnableFlowLogs              bool
+}
+
+// SubnetsService wraps "gcloud compute networks subnets".
+type SubnetsService struct{ c *Client }
+
+// Create creates a subnetwork.
+func (s *SubnetsService) Create(t testing.TestingT, name string, opts SubnetOptions) (*Subnet, error) {
+	args := []string{"compute", "networks", "subnets", "create", name,
+		"--network=" + opts.Network, "--region=" + opts.Region, "--range=" + opts.Range}
+	if opts.EnablePrivateIPGoogleAccess {
+		args = append(args, "--enable-private-ip-google-access")
+	}
+	if opts.EnableFlowLogs {
+		args = append(args, "--enable-flow-logs")
+	}
+	out := &Subnet{}
+	return out, s.c.RunJSON(t, out, args...)
+}
+
+// Describe returns the subnetwork called name in region.
+func (s *SubnetsService) Describe(t testing.TestingT, region, name string) (*Subnet, error) {
+	out := &Subnet{}
+	return out, s.c.RunJSON(t, out, "compute", "networks", "subnets", "describe", name, "--region="+region)
+}
+
+// List returns the subnetworks matching filter, or all subnetworks if it is
+// empty.
+func (s *SubnetsService) List(t testing.TestingT, filter string) ([]Subnet, error) {
+	var out []Subnet
+	err := s.c.RunJSON(t, &out, withFilter([]string{"compute", "networks", "subnets", "list"}, filter)...)
+	return out, err
+}
+
+// Delete deletes the subnetwork called name in region.
+func (s *SubnetsService) Delete(t testing.TestingT, region, name string) error {
+	_, err := s.c.Run(t, "compute", "networks", "subnets", "delete", name, "--region="+region)
+	return err
+}
+
+// Firewall is the subset of a VPC firewall rule returned by gcloud that the
+// tests inspect.
+type Firewall struct {
+	Name              string   `json:"name"`
+	SelfLink          string   `json:"selfLink"`
+	CreationTimestamp string   `json:"creationTimestamp"`
+	Network           string   `json:"network"`
+	Direction         string   `json:"direction"`
+	SourceRanges      []string `json:"sourceRanges"`
+	TargetTags        []string `json:"targetTags"`
+	Allowed           []struct {
+		IPProtocol string   `json:"IPProtocol"`
+		Ports      []string `json:"ports"`
+	} `json:"allowed"`
+}
+
+// FirewallOptions are the flags used when creating a firewall rule. Allow
+// entries use the gcloud syntax, for example "tcp:22" or "all".
+type FirewallOptions struct {
+	Network      string
+	Allow        []string
+	SourceRanges []string
+	TargetTags   []string
+}
+
+// FirewallsService wraps "gcloud compute firewall-rules".
+type FirewallsService struct{ c *Client }
+
+// Create creates a firewall rule.
+func (s *FirewallsService) Create(t testing.TestingT, name string, opts FirewallOptions) (*Firewall, error) {
+	args := []string{"compute", "firewall-rules", "create", name, "--network=" + opts.Network, "--allow=" + strings.Join(opts.Allow, ",")}
+	if len(opts.SourceRanges) > 0 {
+		args = append(args, "--source-ranges="+strings.Join(opts.SourceRanges, ","))
+	}
+	if len(opts.TargetTags) > 0 {
+		args = append(args, "--target-tags="+strings.Join(opts.TargetTags, ","))
+	}
+	out := &Firewall{}
+	return out, s.c.RunJSON(t, out, args...)
+}
+
+// Describe returns the firewall rule called name.
+func (s *FirewallsService) Describe(t testing.TestingT, name string) (*Firewall, error) {
+	out := &Firewall{}
+	return out, s.c.RunJSON(t, out, "compute", "firewall-rules", "describe", name)
+}
+
+// List returns the firewall rules matching filter, or all rules if it is empty.
+func (s *FirewallsService) List(t testing.TestingT, filter string) ([]Firewall, error) {
+	var out []Firewall
+	err := s.c.RunJSON(t, &out, withFilter([]string{"compute", "firewall-rules", "list"}, filter)...)
+	return out, err
+}
+
+// Delete deletes the firewall rule called name.
+func (s *FirewallsService) Delete(t testing.TestingT, name string) error {
+	_, err := s.c.Run(t, "compute", "firewall-rules", "delete", name)
+	return err
+}
+
+// FirewallPolicy is the subset of a hierarchical firewall policy returned by
+// gcloud that the tests inspect. Name is the numeric ID gcloud assigns; the
+// tests refer to policies by ShortName within their organization.
+type FirewallPolicy struct {
+	Name              string                      `json:"name"`
+	ShortName         string                      `json:"shortName"`
+	Description       string                      `json:"description"`
+	CreationTimestamp string                      `json:"creationTimestamp"`
+	Parent            string                      `json:"parent"`
+	Associations      []FirewallPolicyAssociation `json:"associations"`
+}
+
+// FirewallPolicyAssociation attaches a hierarchical firewall policy to an
+// organization or a folder.
+type FirewallPolicyAssociation struct {
+	Name             string `json:"name"`
+	AttachmentTarget string `json:"attachmentTarget"`
+}
+
+// FirewallPolicyRuleOptions are the flags used when creating a rule of a
+// hierarchical firewall policy. Action is allow, deny, goto_next or
+// apply_security_profile_group.
+type FirewallPolicyRuleOptions struct {
+	Priority             int
+	Action               string
+	SrcIPRanges          []string
+	Layer4Configs        []string
+	SecurityProfileGroup string
+	EnableLogging        bool
+	Description          string
+}
+
+// FirewallPolicyAssociationOptions are the flags used when associating a
+// hierarchical firewall policy. Without a folder the policy is associated
+// with its organization.
+type FirewallPolicyAssociationOptions struct {
+	Name   string
+	Folder string
+	// Replace replaces the policy already associated with the target.
+	Replace bool
+}
+
+// FirewallPoliciesService wraps "gcloud compute firewall-policies" for the
+// hierarchical firewall policies of an organization.
+type FirewallPoliciesService struct{ c *Client }
+
+// Create creates the policy shortName in the organization orgID.
+func (s *FirewallPoliciesService) Create(t testing.TestingT, orgID, shortName, description string) error {
+	_, err := s.c.Run(t, "compute", "firewall-policies", "create", "--short-name="+shortName, "--organization="+orgID, "--description="+description)
+	return err
+}
+
+// Describe returns the policy shortName of the organization orgID.
+func (s *FirewallPoliciesService) Describe(t testing.TestingT, orgID, shortName string) (*FirewallPolicy, error) {
+	out := &FirewallPolicy{}
+	return out, s.c.RunJSON(t, out, "compute", "firewall-policies", "describe", shortName, "--organization="+orgID)
+}
+
+// List returns the policies of the organization orgID.
+func (s *FirewallPoliciesService) List(t testing.TestingT, orgID string) ([]FirewallPolicy, error) {
+	var out []FirewallPolicy
+	err := s.c.RunJSON(t, &out, "compute", "firewall-policies", "list", "--organization="+orgID)
+	return out, err
+}
+
+// Delete deletes the policy shortName of the organization orgID, which must
+// not be associated with anything.
+func (s *FirewallPoliciesService) Delete(t testing.TestingT, orgID, shortName string) error {
+	_, err := s.c.Run(t, "compute", "firewall-policies", "delete", shortName, "--organization="+orgID)
+	return err
+}
+
+// CreateRule adds a rule to the policy shortName.
+func (s *FirewallPoliciesService) CreateRule(t testing.TestingT, orgID, shortName string, opts FirewallPolicyRuleOptions) error {
+	args := []string{"compute", "firewall-policies", "rules", "create", strconv.Itoa(opts.Priority),
+		"--firewall-policy=" + shortName, "--organization=" + orgID, "--action=" + opts.Action}
+	if len(opts.SrcIPRanges) > 0 {
+		args = append(args, "--src-ip-ranges="+strings.Join(opts.SrcIPRanges, ","))
+	}
+	if len(opts.Layer4Configs) > 0 {
+		args = append(args, "--layer4-configs="+strings.Join(opts.Layer4Configs, ","))
+	}
+	if opts.SecurityProfileGroup != "" {
+		args = append(args, "--security-profile-group="+opts.SecurityProfileGroup)
+	}
+	if opts.EnableLogging {
+		args = append(args, "--enable-logging")
+	}
+	if opts.Description != "" {
+		args = append(args, "--description="+opts.Description)
+	}
+	_, err := s.c.Run(t, args...)
+	return err
+}
+
+// DeleteRule deletes the rule with the given priority from the policy
+// shortName.
+func (s *FirewallPoliciesService) DeleteRule(t testing.TestingT, orgID, shortName string, priority int) error {
+	_, err := s.c.Run(t, "compute", "firewall-policies", "rules", "delete", strconv.Itoa(priority), "--firewall-policy="+shortName, "--organization="+orgID)
+	return err
+}
+
+// Associate associates the policy shortName with its organization or with
+// opts.Folder.
+func (s *FirewallPoliciesService) Associate(t testing.TestingT, orgID, shortName string, opts FirewallPolicyAssociationOptions) error {
+	args := []string{"compute", "firewall-policies", "associations", "create",
+		"--firewall-policy=" + shortName, "--organization=" + orgID, "--name=" + opts.Name}
+	if opts.Folder != "" {
+		args = append(args, "--folder="+opts.Folder)
+	}
+	if opts.Replace {
+		args = append(args, "--replace-association-on-target")
+	}
+	_, err := s.c.Run(t, args...)
+	return err
+}
+
+// DeleteAssociation deletes the association called name of the policy
+// shortName.
+func (s *FirewallPoliciesService) DeleteAssociation(t testing.TestingT, orgID, shortName, name string) error {
+	_, err := s.c.Run(t, "compute", "firewall-policies", "associations", "delete", name, "--firewall-policy="+shortName, "--organization="+orgID)
+	return err
+}
+
+// ForwardingRule is the subset of a forwarding rule returned by gcloud that
+// the tests inspect.
+type ForwardingRule struct {
+	Name                string   `json:"name"`
+	SelfLink            string   `json:"selfLink"`
+	CreationTimestamp   string   `json:"creationTimestamp"`
+	Region              string   `json:"region"`
+	IPAddress           string   `json:"IPAddress"`
+	IPProtocol          string   `json:"IPProtocol"`
+	Ports               []string `json:"ports"`
+	PortRange           string   `json:"portRange"`
+	LoadBalancingScheme string   `json:"loadBalancingScheme"`
+	BackendService      string   `json:"backendService"`
+	Target              string   `json:"target"`
+	Network             string   `json:"network"`
+	Subnetwork          string   `json:"subnetwork"`
+	PSCConnectionStatus string   `json:"pscConnectionStatus"`
+}
+
+// ForwardingRulesService wraps "gcloud compute forwarding-rules". An empty
+// region addresses global forwarding rules.
+type ForwardingRulesService struct{ c *Client }
+
+// Describe returns the forwarding rule called name in region.
+func (s *ForwardingRulesService) Describe(t testing.TestingT, region, name string) (*ForwardingRule, error) {
+	out := &ForwardingRule{}
+	return out, s.c.RunJSON(t, out, "compute", "forwarding-rules", "describe", name, regionFlag(region))
+}
+
+// List returns the forwarding rules matching filter, or all rules if it is
+// empty.
+func (s *ForwardingRulesService) List(t testing.TestingT, filter string) ([]ForwardingRule, error) {
+	var out []ForwardingRule
+	err := s.c.RunJSON(t, &out, withFilter([]string{"compute", "forwarding-rules", "list"}, filter)...)
+	return out, err
+}
+
+// Delete deletes the forwarding rule called name in region.
+func (s *ForwardingRulesService) Delete(t testing.TestingT, region, name string) error {
+	_, err := s.c.Run(t, "compute", "forwarding-rules", "delete", name, regionFlag(region))
+	return err
+}
+
+// BackendService is the subset of a backend service returned by gcloud that
+// the tests inspect.
+type BackendService struct {
+	Name                string `json:"name"`
+	SelfLink            string `json:"selfLink"`
+	CreationTimestamp   string `json:"creationTimestamp"`
+	Region              string `json:"region"`
+	Protocol            string `json:"protocol"`
+	LoadBalancingScheme string `json:"loadBalancingScheme"`
+	Backends            []struct {
+		Group string `json:"group"`
+	} `json:"backends"`
+}
+
+// BackendServicesService wraps "gcloud compute backend-services". An empty
+// region addresses global backend services.
+type BackendServicesService struct{ c *Client }
+
+// Describe returns the backend service called name in region.
+func (s *BackendServicesService) Describe(t testing.TestingT, region, name string) (*BackendService, error) {
+	out := &BackendService{}
+	return out, s.c.RunJSON(t, out, "compute", "backend-services", "describe", name, regionFlag(region))
+}
+
+// List returns the global and regional backend services matching filter, or
+// all backend services if it is empty.
+func (s *BackendServicesService) List(t testing.TestingT, filter string) ([]BackendService, error) {
+	var out []BackendService
+	err := s.c.RunJSON(t, &out, withFilter([]string{"compute", "backend-services", "list"}, filter)...)
+	return out, err
+}
+
+// Delete deletes the backend service called name in region.
+func (s *BackendServicesService) Delete(t testing.TestingT, region, name string) error {
+	_, err := s.c.Run(t, "compute", "backend-services", "delete", name, regionFlag(region))
+	return err
+}
+
+// Address is the subset of a compute address returned by gcloud that the
+// tests inspect.
+type Address struct {
+	Name              string `json:"name"`
+	SelfLink          string `json:"selfLink"`
+	CreationTimestamp string `json:"creationTimestamp"`
+	Region            string `json:"region"`
+	Address           string `json:"address"`
+	PrefixLength      int    `json:"prefixLength"`
+	Purpose           string `json:"purpose"`
+	Network           string `json:"network"`
+	Status            string `json:"status"`
+}
+
+// AddressOptions are the flags used when reserving an address. An empty
+// Region reserves a global address.
+type AddressOptions struct {
+	Region       string
+	Purpose      string
+	Address      string
+	PrefixLength int
+	Network      string
+}
+
+// AddressesService wraps "gcloud compute addresses".
+type AddressesService struct{ c *Client }
+
+// Create reserves an address or, with Purpose VPC_PEERING, a PSA range.
+func (s *AddressesService) Create(t testing.TestingT, name string, opts AddressOptions) (*Address, error) {
+	args := []string{"compute", "addresses", "create", name, regionFlag(opts.Region)}
+	if opts.Purpose != "" {
+		args = append(args, "--purpose="+opts.Purpose)
+	}
+	if opts.Address != "" {
+		args = append(args, "--addresses="+opts.Address)
+	}
+	if opts.PrefixLength != 0 {
+		args = append(args, fmt.Sprintf("--prefix-length=%d", opts.PrefixLength))
+	}
+	if opts.Network != "" {
+		args = append(args, "--network="+opts.Network)
+	}
+	out := &Address{}
+	return out, s.c.RunJSON(t, out, args...)
+}
+
+// Describe returns the address called name in region.
+func (s *AddressesService) Describe(t testing.TestingT, region, name string) (*Address, error) {
+	out := &Address{}
+	return out, s.c.RunJSON(t, out, "compute", "addresses", "describe", name, regionFlag(region))
+}
+
+// List returns the global and regional addresses matching filter, or all
+// addresses if it is empty.
+func (s *AddressesService) List(t testing.TestingT, filter string) ([]Address, error) {
+	var out []Address
+	err := s.c.RunJSON(t, &out, withFilter([]string{"compute", "addresses", "list"}, filter)...)
+	return out, err
+}
+
+// Delete releases the address called name in region.
+func (s *AddressesService) Delete(t testing.TestingT, region, name string) error {
+	_, err := s.c.Run(t, "compute", "addresses", "delete", name, regionFlag(region))
+	return err
+}
+
+func withFilter(args []string, filter string) []string {
+	if filter == "" {
+		return args
+	}
+	return append(args, "--filter="+filter)
+}
+
+// Operation is a compute long running operation.
+type Operation struct {
+	Name          string `json:"name"`
+	OperationType string `json:"operationType"`
+	Status        string `json:"status"`
+	TargetLink    string `json:"targetLink"`
+	Error         *struct {
+		Errors []struct {
+			Code    string `json:"code"`
+			Message string `json:"message"`
+		} `json:"errors"`
+	} `json:"error"`
+}
+
+// OperationsService wraps "gcloud compute operations". An empty region
+// addresses global operations.
+type OperationsService struct{ c *Client }
+
+// Describe returns the operation called name in region.
+func (s *OperationsService) Describe(t testing.TestingT, region, name string) (*Operation, error) {
+	out := &Operation{}
+	return out, s.c.RunJSON(t, out, "compute", "operations", "describe", name, regionFlag(region))
+}
+
+// Instance is the subset of a compute instance the tests read.
+type Instance struct {
+	Name              string `json:"name"`
+	SelfLink          string `json:"selfLink"`
+	CreationTimestamp string `json:"creationTimestamp"`
+	Zone              string `json:"zone"`
+	Status            string `json:"status"`
+	NetworkInterfaces []struct {
+		Network    string `json:"network"`
+		Subnetwork string `json:"subnetwork"`
+		NetworkIP  string `json:"networkIP"`
+	} `json:"networkInterfaces"`
+}
+
+// InstanceOptions are the flags used when creating a VM. Subnet is required
+// when the network has custom subnetworks. NoAddress leaves the VM without an
+// external IP address, in which case it is reached over IAP.
+type InstanceOptions struct {
+	Zone         string
+	MachineType  string
+	Network      string
+	Subnet       string
+	ImageFamily  string
+	ImageProject string
+	Tags         []string
+	NoAddress    bool
+	// StartupScript is passed inline as the startup-script metadata.
+	StartupScript string
+}
+
+// InstancesService wraps "gcloud compute instances".
+type InstancesService struct{ c *Client }
+
+// Create creates a VM. It defaults to an e2-micro running Debian 12.
+func (s *InstancesService) Create(t testing.TestingT, name string, opts InstanceOptions) (*Instance, error) {
+	if opts.MachineType == "" {
+		opts.MachineType = "e2-micro"
+	}
+	if opts.ImageFamily == "" {
+		opts.ImageFamily, opts.ImageProject = "debian-12", "debian-cloud"
+	}
+	args := []string{"compute", "instances", "create", name, "--zone=" + opts.Zone, "--machine-type=" + opts.MachineType,
+		"--image-family=" + opts.ImageFamily, "--image-project=" + opts.ImageProject}
+	if opts.Network != "" {
+		args = append(args, "--network="+opts.Network)
+	}
+	if opts.Subnet != "" {
+		args = append(args, "--subnet="+opts.Subnet)
+	}
+	if len(opts.Tags) > 0 {
+		args = append(args, "--tags="+strings.Join(opts.Tags, ","))
+	}
+	if opts.NoAddress {
+		args = append(args, "--no-address")
+	}
+	if opts.StartupScript != "" {
+		// The ^~~^ prefix changes the list delimiter so that the commas of
+		// the script are kept.
+		args = append(args, "--metadata=^~~^startup-script="+opts.StartupScript)
+	}
+	args = append(args, s.c.labelsFlag()...)
+	out := &Instance{}
+	return out, s.c.RunJSON(t, out, args...)
+}
+
+// Describe returns the instance called name in zone.
+func (s *InstancesService) Describe(t testing.TestingT, zone, name string) (*Instance, error) {
+	out := &Instance{}
+	return out, s.c.RunJSON(t, out, "compute", "instances", "describe", name, "--zone="+zone)
+}
+
+// List returns the instances of every zone matching filter, or all instances
+// if it is empty.
+func (s *InstancesService) List(t testing.TestingT, filter string) ([]Instance, error) {
+	var out []Instance
+	err := s.c.RunJSON(t, &out, withFilter([]string{"compute", "instances", "list"}, filter)...)
+	return out, err
+}
+
+// SerialPortOutput returns the serial console output of the instance.
+func (s *InstancesService) SerialPortOutput(t testing.TestingT, zone, name string) (string, error) {
+	var out struct {
+		Contents string `json:"contents"`
+	}
+	err := s.c.RunJSON(t, &out, "compute", "instances", "get-serial-port-output", name, "--zone="+zone)
+	return out.Contents, err
+}
+
+// SSH runs command on the instance over an IAP tunnel and returns its
+// stdout. The firewall of the network must admit IAP on port 22.
+func (s *InstancesService) SSH(t testing.TestingT, zone, name, command string) (string, error) {
+	return s.c.Run(t, "compute", "ssh", name, "--zone="+zone, "--tunnel-through-iap", "--command="+command)
+}
+
+// Delete deletes the instance called name in zone.
+func (s *InstancesService) Delete(t testing.TestingT, zone, name string) error {
+	_, err := s.c.Run(t, "compute", "instances", "delete", name, "--zone="+zone)
+	return err
+}
+
+// InstanceGroupManager is the subset of a managed instance group the tests
+// read.
+type InstanceGroupManager struct {
+	Name              string `json:"name"`
+	SelfLink          string `json:"selfLink"`
+	CreationTimestamp string `json:"creationTimestamp"`
+	Region            string `json:"region"`
+	Zone              string `json:"zone"`
+	InstanceGroup     string `json:"instanceGroup"`
+	InstanceTemplate  string `json:"instanceTemplate"`
+	TargetSize        int    `json:"targetSize"`
+	Status            struct {
+		IsStable bool `json:"isStable"`
+	} `json:"status"`
+}
+
+// InstanceGroupManagersService wraps "gcloud compute instance-groups
+// managed". Zonal groups are addressed by zone, regional ones by region.
+type InstanceGroupManagersService struct{ c *Client }
+
+// Describe returns the managed instance group called name. zone takes
+// precedence over region when both are set.
+func (s *InstanceGroupManagersService) Describe(t testing.TestingT, region, zone, name string) (*InstanceGroupManager, error) {
+	out := &InstanceGroupManager{}
+	return out, s.c.RunJSON(t, out, "compute", "instance-groups", "managed", "describe", name, locationFlag(region, zone))
+}
+
+// List returns the zonal and regional managed instance groups matching
+// filter, or all groups if it is empty.
+func (s *InstanceGroupManagersService) List(t testing.TestingT, filter string) ([]InstanceGroupManager, error) {
+	var out []InstanceGroupManager
+	err := s.c.RunJSON(t, &out, withFilter([]string{"compute", "instance-groups", "managed", "list"}, filter)...)
+	return out, err
+}
+
+// Delete deletes the managed instance group called name.
+func (s *InstanceGroupManagersService) Delete(t testing.TestingT, region, zone, name string) error {
+	_, err := s.c.Run(t, "compute", "instance-groups", "managed", "delete", name, locationFlag(region, zone))
+	return err
+}
+
+func locationFlag(region, zone string) string {
+	if zone != "" {
+		return "--zone=" + zone
+	}
+	return "--region=" + region
+}
+
+// Quota is the limit and usage of a compute quota metric, such as
+// FORWARDING_RULES.
+type Quota struct {
+	Metric string  `json:"metric"`
+	Limit  float64 `json:"limit"`
+	Usage  float64 `json:"usage"`
+}
+
+// QuotasService reads the compute quotas of the project. An empty region
+// addresses the project wide quotas.
+type QuotasService struct{ c *Client }
+
+// List returns the quotas of region, from "gcloud compute regions describe",
+// or the project wide quotas, from "gcloud compute project-info describe".
+func (s *QuotasService) List(t testing.TestingT, region string) ([]Quota, error) {
+	var out struct {
+		Quotas []Quota `json:"quotas"`
+	}
+	args := []string{"compute", "project-info", "describe"}
+	if region != "" {
+		args = []string{"compute", "regions", "describe", region}
+	}
+	err := s.c.RunJSON(t, &out, args...)
+	return out.Quotas, err
+}
@@ -0,0 +1,189 @@
+// Copyright 2026 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gcloud
+
+import (
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"sync"
+	gotesting "testing"
+
+	"github.com/gruntwork-io/terratest/modules/shell"
+	"github.com/gruntwork-io/terratest/modules/testing"
+)
+
+// ConfigEnv is the environment variable that points gcloud, and the Google
+// client libraries looking for application default credentials, at a
+// configuration directory.
+const ConfigEnv = "CLOUDSDK_CONFIG"
+
+// seededDirs are the subfolders of a configuration directory copied into an
+// isolated one, next to its top level files. Logs and caches are left out.
+var seededDirs = []string{"configurations", "legacy_credentials"}
+
+// Config is a gcloud configuration directory private to one test. Properties
+// set in it, such as billing/quota_project, auth/impersonate_service_account
+// or compute/region, never reach the configuration of the developer.
+type Config struct {
+	Dir string
+}
+
+var (
+	configsMu sync.Mutex
+	configs   = map[string]*Config{}
+)
+
+// Isolate gives t its own configuration directory, seeded from the active
+// credentials and configuration, and removed when the test ends. Client.Run
+// uses it for every command run with t or one of its subtests, and Env returns
+// it for commands run without a Client. Tests do not call it themselves: the
+// fixture registry of a test isolates its configuration when it is created,
+// see fixture.GcloudConfig.
+func Isolate(t gotesting.TB) *Config {
+	t.Helper()
+	cfg, err := NewConfig(t.TempDir(), ActiveConfigDir())
+	if err != nil {
+		t.Fatalf("Isolating the gcloud configuration: %v", err)
+	}
+	name := t.Name()
+	configsMu.Lock()
+	configs[name] = cfg
+	configsMu.Unlock()
+	t.Cleanup(func() {
+		configsMu.Lock()
+		delete(configs, name)
+		configsMu.Unlock()
+	})
+	return cfg
+}
+
+// ConfigFor returns the configuration isolated for t or its closest parent
+// test, or nil when there is none.
+func ConfigFor(t testing.TestingT) *Config {
+	configsMu.Lock()
+	defer configsMu.Unlock()
+	cfg, _ := lookup(configs, t.Name())
+	return cfg
+}
+
+// Env returns the environment that points gcloud at the configuration
+// isolated for t, or nil when there is none. Suites that build their own
+// shell.Command for gcloud set it as the command Env.
+func Env(t testing.TestingT) map[string]string {
+	return ConfigFor(t).Env()
+}
+
+// ActiveConfigDir returns the configuration directory gcloud uses outside of
+// the tests: $CLOUDSDK_CONFIG when set, and the platform default otherwise.
+func ActiveConfigDir() string {
+	if dir := os.Getenv(ConfigEnv); dir != "" {
+		return dir
+	}
+	if runtime.GOOS == "windows" {
+		return filepath.Join(os.Getenv("APPDATA"), "gcloud")
+	}
+	home, _ := os.UserHomeDir()
+	return filepath.Join(home, ".config", "gcloud")
+}
+
+// NewConfig creates a configuration directory at dir holding a copy of the
+// credentials, the active configuration name and the named configurations of
+// the directory from. A missing from yields an empty configuration, which
+// still works with credentials passed through the environment.
+func NewConfig(dir, from string) (*Config, error) {
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, err
+	}
+	entries, err := os.ReadDir(from)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+	for _, e := range entries {
+		if e.Type().IsRegular() {
+			if err := copyFile(filepath.Join(from, e.Name()), filepath.Join(dir, e.Name())); err != nil {
+				return nil, err
+			}
+		}
+	}
+	for _, sub := range seededDirs {
+		if err := copyDir(filepath.Join(from, sub), filepath.Join(dir, sub)); err != nil {
+			return nil, err
+		}
+	}
+	return &Config{Dir: dir}, nil
+}
+
+// Env returns the environment that points gcloud at the configuration. It is
+// nil for a nil Config so that callers can pass ConfigFor(t).Env() as is.
+func (c *Config) Env() map[string]string {
+	if c == nil {
+		return nil
+	}
+	return map[string]string{ConfigEnv: c.Dir}
+}
+
+// Set sets a property of the active configuration in the directory, for
+// example Set(t, "billing/quota_project", projectID).
+func (c *Config) Set(t testing.TestingT, property, value string) error {
+	cmd := shell.Command{Command: "gcloud", Args: []string{"config", "set", property, value, "--quiet"}, Env: c.Env()}
+	if _, stderr, err := (ShellRunner{}).Run(t, cmd); err != nil {
+		return fmt.Errorf("setting gcloud property %s: %w: %s", property, err, strings.TrimSpace(stderr))
+	}
+	return nil
+}
+
+func copyDir(from, to string) error {
+	return filepath.WalkDir(from, func(path string, d fs.DirEntry, err error) error {
+		if os.IsNotExist(err) && path == from {
+			return filepath.SkipDir
+		}
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(from, path)
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			return os.MkdirAll(filepath.Join(to, rel), 0700)
+		}
+		if !d.Type().IsRegular() {
+			return nil
+		}
+		return copyFile(path, filepath.Join(to, rel))
+	})
+}
+
+func copyFile(from, to string) error {
+	in, err := os.Open(from)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	out, err := os.OpenFile(to, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0600)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
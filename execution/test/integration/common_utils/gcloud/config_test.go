@@ -0,0 +1,102 @@
+// Copyright 2026 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gcloud
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func writeFile(t *testing.T, path, content string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0600); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestNewConfigSeedsCredentials(t *testing.T) {
+	from := t.TempDir()
+	writeFile(t, filepath.Join(from, "active_config"), "default")
+	writeFile(t, filepath.Join(from, "credentials.db"), "creds")
+	writeFile(t, filepath.Join(from, "application_default_credentials.json"), "{}")
+	writeFile(t, filepath.Join(from, "configurations", "config_default"), "[core]\naccount = dev@example.com\n")
+	writeFile(t, filepath.Join(from, "legacy_credentials", "dev@example.com", "adc.json"), "{}")
+	writeFile(t, filepath.Join(from, "logs", "2026.01.01", "gcloud.log"), "log")
+
+	dir := filepath.Join(t.TempDir(), "gcloud")
+	cfg, err := NewConfig(dir, from)
+	if err != nil {
+		t.Fatalf("NewConfig() returned error: %v", err)
+	}
+	for _, name := range []string{
+		"active_config",
+		"credentials.db",
+		"application_default_credentials.json",
+		filepath.Join("configurations", "config_default"),
+		filepath.Join("legacy_credentials", "dev@example.com", "adc.json"),
+	} {
+		if _, err := os.Stat(filepath.Join(cfg.Dir, name)); err != nil {
+			t.Errorf("Seeded file %s: %v", name, err)
+		}
+	}
+	if _, err := os.Stat(filepath.Join(cfg.Dir, "logs")); !os.IsNotExist(err) {
+		t.Errorf("Seeded logs folder: err = %v, want = not exist", err)
+	}
+
+	writeFile(t, filepath.Join(cfg.Dir, "configurations", "config_default"), "[billing]\nquota_project = other\n")
+	if b, _ := os.ReadFile(filepath.Join(from, "configurations", "config_default")); string(b) != "[core]\naccount = dev@example.com\n" {
+		t.Errorf("Source configuration = %q after changing the copy, want it unchanged", b)
+	}
+}
+
+func TestNewConfigWithoutSource(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "gcloud")
+	if _, err := NewConfig(dir, filepath.Join(t.TempDir(), "missing")); err != nil {
+		t.Fatalf("NewConfig() of a missing source returned error: %v", err)
+	}
+	if fi, err := os.Stat(dir); err != nil || !fi.IsDir() {
+		t.Errorf("Config folder %s: %v, want a folder", dir, err)
+	}
+}
+
+func TestIsolateAppliesToClientAndSubtests(t *testing.T) {
+	t.Setenv(ConfigEnv, t.TempDir())
+	if got := ConfigFor(t); got != nil {
+		t.Fatalf("ConfigFor() before Isolate() = %+v, want = nil", got)
+	}
+	cfg := Isolate(t)
+
+	runner := &fakeRunner{stdout: "[]"}
+	client := New("dummy-project")
+	client.Runner = runner
+	client.Env = map[string]string{"CLOUDSDK_CORE_VERBOSITY": "debug"}
+	t.Run("subtest", func(t *testing.T) {
+		if _, err := client.Networks.List(t, ""); err != nil {
+			t.Fatalf("Networks.List() returned error: %v", err)
+		}
+	})
+	want := map[string]string{ConfigEnv: cfg.Dir, "CLOUDSDK_CORE_VERBOSITY": "debug"}
+	if !reflect.DeepEqual(runner.env, want) {
+		t.Errorf("gcloud env = %v, want = %v", runner.env, want)
+	}
+	if got := Env(t); !reflect.DeepEqual(got, cfg.Env()) {
+		t.Errorf("Env() = %v, want = %v", got, cfg.Env())
+	}
+}
@@ -0,0 +1,130 @@
+// Copyright 2026 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gcloud
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/gruntwork-io/terratest/modules/shell"
+)
+
+// Sentinel error kinds. An *Error returned by a Client matches at most one of
+// them through errors.Is.
+var (
+	ErrNotFound           = errors.New("resource not found")
+	ErrAlreadyExists      = errors.New("resource already exists")
+	ErrPermissionDenied   = errors.New("permission denied")
+	ErrTransient          = errors.New("transient failure")
+	ErrPreconditionFailed = errors.New("precondition failed")
+)
+
+// classifiers are evaluated in order and the first match wins. Upper-case
+// status codes are matched case-sensitively because resource names quoted in
+// the message (for example "fw-allow-http-internal") would otherwise match.
+// Resources still in use by a dependent that is being torn down are
+// transient: the dependent disappears shortly after. So are concurrent
+// changes to the same IAM policy, which gcloud asks to retry. Status codes
+// only count in their status form, "INTERNAL: ..." or "code: INTERNAL", since
+// permanent errors quote values such as INTERNAL_MANAGED or INTERNAL.
+var classifiers = []struct {
+	kind    error
+	pattern *regexp.Regexp
+}{
+	{ErrPreconditionFailed, regexp.MustCompile(`conditionNotMet|HTTPError 412|(?i:pre-?conditions? you specified did not hold|precondition failed)`)},
+	{ErrAlreadyExists, regexp.MustCompile(`ALREADY_EXISTS|alreadyExists|(?i:already exists)`)},
+	{ErrNotFound, regexp.MustCompile(`NOT_FOUND|notFound|(?i:was not found|does not exist|policy binding with the specified .* not found)|HTTPError 404`)},
+	{ErrPermissionDenied, regexp.MustCompile(`PERMISSION_DENIED|insufficientPermissions|forbidden|(?i:does not have [a-z.]* ?permission|Required '[^']+' permission)|HTTPError 403`)},
+	{ErrTransient, regexp.MustCompile(`\b(UNAVAILABLE|DEADLINE_EXCEEDED|INTERNAL|ABORTED):|\bcode: (UNAVAILABLE|DEADLINE_EXCEEDED|INTERNAL|ABORTED)\b|rateLimitExceeded|resourceNotReady|resourceInUseByAnotherResource|(?i:rate limit exceeded|concurrent policy changes|is not ready|is already being used by|still using this connection|operation .* in progress|try again|connection reset|timed out)|Error 50[0234]`)},
+}
+
+// Error describes a failed gcloud invocation.
+type Error struct {
+	Args     []string
+	ExitCode int
+	Stderr   string
+	// Kind is one of the sentinel errors, or nil when the failure could not be
+	// classified.
+	Kind error
+	Err  error
+}
+
+func (e *Error) Error() string {
+	kind := "error"
+	if e.Kind != nil {
+		kind = e.Kind.Error()
+	}
+	return fmt.Sprintf("gcloud %s: %s (exit code %d): %s", strings.Join(e.Args, " "), kind, e.ExitCode, strings.TrimSpace(e.Stderr))
+}
+
+// Is reports whether target is the classified kind of e.
+func (e *Error) Is(target error) bool {
+	return e.Kind != nil && target == e.Kind
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// Classify returns the sentinel kind matching a gcloud error message, or nil.
+func Classify(message string) error {
+	for _, c := range classifiers {
+		if c.pattern.MatchString(message) {
+			return c.kind
+		}
+	}
+	return nil
+}
+
+func newError(args []string, stderr string, err error) *Error {
+	exitCode, _ := shell.GetExitCodeForRunCommandError(err)
+	if stderr == "" {
+		stderr = err.Error()
+	}
+	return &Error{
+		Args:     args,
+		ExitCode: exitCode,
+		Stderr:   stderr,
+		Kind:     Classify(stderr),
+		Err:      err,
+	}
+}
+
+// IsNotFound reports whether err was caused by a missing resource.
+func IsNotFound(err error) bool { return errors.Is(err, ErrNotFound) }
+
+// IsAlreadyExists reports whether err was caused by a resource that exists.
+func IsAlreadyExists(err error) bool { return errors.Is(err, ErrAlreadyExists) }
+
+// IsPermissionDenied reports whether err was caused by missing IAM permissions.
+func IsPermissionDenied(err error) bool { return errors.Is(err, ErrPermissionDenied) }
+
+// IsPreconditionFailed reports whether err was caused by a generation
+// precondition that did not hold, such as creating an object that exists.
+func IsPreconditionFailed(err error) bool { return errors.Is(err, ErrPreconditionFailed) }
+
+// IsTransient reports whether err is likely to succeed when retried.
+func IsTransient(err error) bool { return errors.Is(err, ErrTransient) }
+
+// IgnoreNotFound returns nil when err is a not-found error and err otherwise.
+// It is convenient for idempotent deletes in cleanup code.
+func IgnoreNotFound(err error) error {
+	if IsNotFound(err) {
+		return nil
+	}
+	return err
+}
@@ -0,0 +1,244 @@
+// Copyright 2026 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package gcloud is a typed wrapper around the gcloud CLI used by the
+// integration tests. Every call is made with --format=json, decoded into a Go
+// struct and, on failure, returned as an *Error whose kind can be checked with
+// errors.Is against ErrNotFound, ErrAlreadyExists, ErrPermissionDenied and
+// ErrTransient.
+package gcloud
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	gotesting "testing"
+
+	"github.com/gruntwork-io/terratest/modules/logger"
+	"github.com/gruntwork-io/terratest/modules/shell"
+	"github.com/gruntwork-io/terratest/modules/testing"
+)
+
+// Runner executes a single command on behalf of a Client. Stdout and stderr
+// are returned separately so that progress messages written to stderr never
+// corrupt the JSON document written to stdout.
+type Runner interface {
+	Run(t testing.TestingT, cmd shell.Command) (stdout string, stderr string, err error)
+}
+
+// ShellRunner runs commands through the terratest shell module.
+type ShellRunner struct{}
+
+// Run implements Runner.
+func (ShellRunner) Run(t testing.TestingT, cmd shell.Command) (string, string, error) {
+	return shell.RunCommandAndGetStdOutErrE(t, cmd)
+}
+
+var (
+	runnersMu sync.Mutex
+	runners   = map[string]Runner{}
+)
+
+// UseRunner makes r the runner of the clients without one of their own for
+// the commands run with t or one of its subtests, until the test ends. The
+// cassette package uses it to record and replay every gcloud call of a test.
+func UseRunner(t gotesting.TB, r Runner) {
+	name := t.Name()
+	runnersMu.Lock()
+	runners[name] = r
+	runnersMu.Unlock()
+	t.Cleanup(func() {
+		runnersMu.Lock()
+		delete(runners, name)
+		runnersMu.Unlock()
+	})
+}
+
+// RunnerFor returns the runner set with UseRunner for t or its closest parent
+// test, or ShellRunner when there is none.
+func RunnerFor(t testing.TestingT) Runner {
+	runnersMu.Lock()
+	defer runnersMu.Unlock()
+	if r, ok := lookup(runners, t.Name()); ok {
+		return r
+	}
+	return ShellRunner{}
+}
+
+// lookup returns the value registered for the test name or its closest
+// parent, subtests being named "parent/child".
+func lookup[V any](m map[string]V, name string) (V, bool) {
+	for {
+		if v, ok := m[name]; ok {
+			return v, true
+		}
+		i := strings.LastIndex(name, "/")
+		if i < 0 {
+			var zero V
+			return zero, false
+		}
+		name = name[:i]
+	}
+}
+
+// Client issues gcloud commands against a single project.
+type Client struct {
+	// Project is passed as --project to every project scoped command.
+	Project string
+	// Env holds additional environment variables set on every invocation.
+	// They take precedence over the configuration isolated for the test.
+	Env map[string]string
+	// Runner executes the commands. It defaults to the runner set for the
+	// test with UseRunner, and to ShellRunner otherwise.
+	Runner Runner
+	// Logger receives the commands and their output. It defaults to the
+	// terratest logger; logger.Discard silences listings in command line tools.
+	Logger *logger.Logger
+	// Labels are attached to every created resource that supports labels,
+	// typically naming.Labels of the test.
+	Labels map[string]string
+
+	Networks                  *NetworksService
+	Subnets                   *SubnetsService
+	Firewalls                 *FirewallsService
+	FirewallPolicies          *FirewallPoliciesService
+	ForwardingRules           *ForwardingRulesService
+	BackendServices           *BackendServicesService
+	Addresses                 *AddressesService
+	Instances                 *InstancesService
+	InstanceGroupManagers     *InstanceGroupManagersService
+	Operations                *OperationsService
+	VPCPeerings               *VPCPeeringsService
+	Objects                   *ObjectsService
+	ServiceConnectionPolicies *ServiceConnectionPoliciesService
+	SQL                       *SQLService
+	IAM                       *IAMService
+	APIs                      *APIsService
+	Projects                  *ProjectsService
+	Organizations             *OrganizationsService
+	Quotas                    *QuotasService
+}
+
+// New returns a Client for projectID that runs the real gcloud binary.
+func New(projectID string) *Client {
+	c := &Client{Project: projectID}
+	c.Networks = &NetworksService{c}
+	c.Subnets = &SubnetsService{c}
+	c.Firewalls = &FirewallsService{c}
+	c.FirewallPolicies = &FirewallPoliciesService{c}
+	c.ForwardingRules = &ForwardingRulesService{c}
+	c.BackendServices = &BackendServicesService{c}
+	c.Addresses = &AddressesService{c}
+	c.Instances = &InstancesService{c}
+	c.InstanceGroupManagers = &InstanceGroupManagersService{c}
+	c.Operations = &OperationsService{c}
+	c.VPCPeerings = &VPCPeeringsService{c}
+	c.Objects = &ObjectsService{c}
+	c.ServiceConnectionPolicies = &ServiceConnectionPoliciesService{c}
+	c.SQL = &SQLService{Instances: &SQLInstancesService{c}}
+	c.IAM = &IAMService{c: c, ServiceAccounts: &ServiceAccountsService{c}, Roles: &RolesService{c}}
+	c.APIs = &APIsService{c}
+	c.Projects = &ProjectsService{c}
+	c.Organizations = &OrganizationsService{c}
+	c.Quotas = &QuotasService{c}
+	return c
+}
+
+// Run executes gcloud with args plus --project and --format=json and returns
+// the raw stdout. It uses the configuration isolated for t, if any. Failures
+// are classified into an *Error.
+func (c *Client) Run(t testing.TestingT, args ...string) (string, error) {
+	args = append(args, "--project="+c.Project, "--format=json", "--quiet")
+	cmd := shell.Command{Command: "gcloud", Args: args, Env: c.env(t), Logger: c.Logger}
+	runner := c.Runner
+	if runner == nil {
+		runner = RunnerFor(t)
+	}
+	stdout, stderr, err := runner.Run(t, cmd)
+	if err != nil {
+		return stdout, newError(args, stderr, err)
+	}
+	return stdout, nil
+}
+
+// RunJSON executes gcloud like Run and decodes stdout into out. gcloud prints
+// a list for create commands, in which case the first element is decoded.
+func (c *Client) RunJSON(t testing.TestingT, out any, args ...string) error {
+	stdout, err := c.Run(t, args...)
+	if err != nil {
+		return err
+	}
+	return decode(stdout, out)
+}
+
+func decode(stdout string, out any) error {
+	stdout = strings.TrimSpace(stdout)
+	if stdout == "" || out == nil {
+		return nil
+	}
+	if strings.HasPrefix(stdout, "[") {
+		var list []json.RawMessage
+		if err := json.Unmarshal([]byte(stdout), &list); err != nil {
+			return fmt.Errorf("decoding gcloud output: %w", err)
+		}
+		if reflect.Indirect(reflect.ValueOf(out)).Kind() != reflect.Slice {
+			if len(list) == 0 {
+				return nil
+			}
+			stdout = string(list[0])
+		}
+	}
+	if err := json.Unmarshal([]byte(stdout), out); err != nil {
+		return fmt.Errorf("decoding gcloud output: %w", err)
+	}
+	return nil
+}
+
+// env returns the environment of a command run with t: the configuration
+// isolated for t overridden by the client Env.
+func (c *Client) env(t testing.TestingT) map[string]string {
+	env := Env(t)
+	if env == nil {
+		return c.Env
+	}
+	for k, v := range c.Env {
+		env[k] = v
+	}
+	return env
+}
+
+// labelsFlag returns --labels with the client labels in key order, or nothing
+// when there are none.
+func (c *Client) labelsFlag() []string {
+	if len(c.Labels) == 0 {
+		return nil
+	}
+	pairs := make([]string, 0, len(c.Labels))
+	for k, v := range c.Labels {
+		pairs = append(pairs, k+"="+v)
+	}
+	sort.Strings(pairs)
+	return []string{"--labels=" + strings.Join(pairs, ",")}
+}
+
+// regionFlag returns --region=region, or --global when region is empty.
+func regionFlag(region string) string {
+	if region == "" {
+		return "--global"
+	}
+	return "--region=" + region
+}
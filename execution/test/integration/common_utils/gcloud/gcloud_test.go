@@ -0,0 +1,190 @@
+// Copyright 2026 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gcloud
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gruntwork-io/terratest/modules/shell"
+	terratesting "github.com/gruntwork-io/terratest/modules/testing"
+)
+
+// fakeRunner records the last command and replies with canned output.
+type fakeRunner struct {
+	args   []string
+	env    map[string]string
+	stdout string
+	stderr string
+	err    error
+}
+
+func (f *fakeRunner) Run(_ terratesting.TestingT, cmd shell.Command) (string, string, error) {
+	f.args = cmd.Args
+	f.env = cmd.Env
+	return f.stdout, f.stderr, f.err
+}
+
+func TestSubnetsDescribeDecodesJSON(t *testing.T) {
+	runner := &fakeRunner{stdout: `{"name":"subnet-1","ipCidrRange":"10.0.1.0/24","state":"READY","region":"https://www.googleapis.com/compute/v1/projects/p/regions/us-central1"}`}
+	client := New("dummy-project")
+	client.Runner = runner
+
+	subnet, err := client.Subnets.Describe(t, "us-central1", "subnet-1")
+	if err != nil {
+		t.Fatalf("Subnets.Describe() returned error: %v", err)
+	}
+	if got, want := subnet.IPCIDRRange, "10.0.1.0/24"; got != want {
+		t.Errorf("Subnet IPCIDRRange = %v, want = %v", got, want)
+	}
+	if got, want := subnet.State, "READY"; got != want {
+		t.Errorf("Subnet State = %v, want = %v", got, want)
+	}
+	wantArgs := "compute networks subnets describe subnet-1 --region=us-central1 --project=dummy-project --format=json --quiet"
+	if got := strings.Join(runner.args, " "); got != wantArgs {
+		t.Errorf("gcloud args = %v, want = %v", got, wantArgs)
+	}
+}
+
+func TestNetworksCreateDecodesFirstListElement(t *testing.T) {
+	runner := &fakeRunner{stdout: `[{"name":"vpc-1","routingConfig":{"routingMode":"GLOBAL"}}]`}
+	client := New("dummy-project")
+	client.Runner = runner
+
+	network, err := client.Networks.Create(t, "vpc-1", NetworkOptions{BGPRoutingMode: "global"})
+	if err != nil {
+		t.Fatalf("Networks.Create() returned error: %v", err)
+	}
+	if got, want := network.RoutingConfig.RoutingMode, "GLOBAL"; got != want {
+		t.Errorf("Network RoutingMode = %v, want = %v", got, want)
+	}
+	if got, want := strings.Join(runner.args[:5], " "), "compute networks create vpc-1 --subnet-mode=custom"; got != want {
+		t.Errorf("gcloud args = %v, want = %v", got, want)
+	}
+}
+
+func TestForwardingRulesListDecodesList(t *testing.T) {
+	runner := &fakeRunner{stdout: `[{"name":"fr-1"},{"name":"fr-2"}]`}
+	client := New("dummy-project")
+	client.Runner = runner
+
+	rules, err := client.ForwardingRules.List(t, "name~^nlb-")
+	if err != nil {
+		t.Fatalf("ForwardingRules.List() returned error: %v", err)
+	}
+	if got, want := len(rules), 2; got != want {
+		t.Errorf("ForwardingRules.List() count = %v, want = %v", got, want)
+	}
+}
+
+func TestServiceConnectionPoliciesCreateAttachesLabels(t *testing.T) {
+	runner := &fakeRunner{stdout: `{"name":"scp-1"}`}
+	client := New("dummy-project")
+	client.Runner = runner
+	client.Labels = map[string]string{"test-run-id": "r1234", "test-name": "testscp"}
+
+	if _, err := client.ServiceConnectionPolicies.Create(t, "scp-1", ServiceConnectionPolicyOptions{Region: "us-central1", Network: "vpc-1", ServiceClass: "gcp-memorystore-redis", Subnets: []string{"subnet-1"}}); err != nil {
+		t.Fatalf("ServiceConnectionPolicies.Create() returned error: %v", err)
+	}
+	want := "--labels=test-name=testscp,test-run-id=r1234"
+	if got := runner.args[len(runner.args)-4]; got != want {
+		t.Errorf("gcloud args = %v, want %v before the common flags", runner.args, want)
+	}
+}
+
+func TestObjectsDescribeDecodesGenerationAndCreationTime(t *testing.T) {
+	runner := &fakeRunner{stdout: `{"name":"locks/org-42","generation":"1700000000000001","timeCreated":"2026-10-17T09:30:00.123Z"}`}
+	client := New("dummy-project")
+	client.Runner = runner
+
+	object, err := client.Objects.Describe(t, "gs://bucket/locks/org-42")
+	if err != nil {
+		t.Fatalf("Objects.Describe() returned error: %v", err)
+	}
+	if got, want := object.Generation.String(), "1700000000000001"; got != want {
+		t.Errorf("Object Generation = %v, want = %v", got, want)
+	}
+	if got, want := object.TimeCreated.Format(time.RFC3339), "2026-10-17T09:30:00Z"; got != want {
+		t.Errorf("Object TimeCreated = %v, want = %v", got, want)
+	}
+	wantArgs := "storage objects describe gs://bucket/locks/org-42 --raw --project=dummy-project --format=json --quiet"
+	if got := strings.Join(runner.args, " "); got != wantArgs {
+		t.Errorf("gcloud args = %v, want = %v", got, wantArgs)
+	}
+}
+
+func TestErrorClassification(t *testing.T) {
+	tests := []struct {
+		stderr string
+		want   error
+	}{
+		{"ERROR: (gcloud.compute.networks.describe) Could not fetch resource:\n - The resource 'projects/p/global/networks/vpc' was not found", ErrNotFound},
+		{"ERROR: (gcloud.sql.instances.describe) HTTPError 404: The Cloud SQL instance does not exist.", ErrNotFound},
+		{"ERROR: (gcloud.compute.networks.create) Could not fetch resource:\n - The resource 'projects/p/global/networks/vpc' already exists", ErrAlreadyExists},
+		{"ERROR: (gcloud.compute.networks.describe) Could not fetch resource:\n - Required 'compute.networks.get' permission for 'projects/p/global/networks/vpc'", ErrPermissionDenied},
+		{"ERROR: (gcloud.iam.service-accounts.create) PERMISSION_DENIED: Permission 'iam.serviceAccounts.create' denied", ErrPermissionDenied},
+		{"ERROR: (gcloud.compute.networks.delete) Could not fetch resource:\n - The resource 'projects/p/global/networks/vpc' is not ready", ErrTransient},
+		{"ERROR: (gcloud.services.vpc-peerings.connect) Operation 'operations/pssn.123' in progress", ErrTransient},
+		{"ERROR: (gcloud.compute.networks.delete) Could not fetch resource:\n - The network resource 'projects/p/global/networks/vpc' is already being used by 'projects/p/global/firewalls/fw'", ErrTransient},
+		{"ERROR: (gcloud.projects.remove-iam-policy-binding) Policy binding with the specified principal, role, and condition not found!", ErrNotFound},
+		{"ERROR: (gcloud.projects.add-iam-policy-binding) ABORTED: There were concurrent policy changes. Please retry the whole read-modify-write with exponential backoff.", ErrTransient},
+		{"ERROR: (gcloud.compute.firewall-rules.create) Invalid value for field 'resource.sourceRanges[0]': 'fw-allow-http-internal'", nil},
+		{"ERROR: (gcloud.compute.backend-services.create) INTERNAL: Internal error. Please try again or contact Google Support.", ErrTransient},
+		{"ERROR: (gcloud.compute.forwarding-rules.create) Could not fetch resource:\n - Error 500: Internal error, backendError", ErrTransient},
+		{"ERROR: (gcloud.storage.cp) HTTPError 412: At least one of the pre-conditions you specified did not hold.", ErrPreconditionFailed},
+		{"ERROR: (gcloud.compute.forwarding-rules.create) Could not fetch resource:\n - Invalid value for field 'resource.loadBalancingScheme': 'INTERNAL_MANAGED'. Load balancing scheme must be INTERNAL for a passthrough load balancer.", nil},
+	}
+	for _, tc := range tests {
+		runner := &fakeRunner{stderr: tc.stderr, err: errors.New("exit status 1")}
+		client := New("dummy-project")
+		client.Runner = runner
+		_, err := client.Networks.Describe(t, "vpc")
+		if err == nil {
+			t.Fatalf("Networks.Describe() returned nil error for %q", tc.stderr)
+		}
+		var gcloudErr *Error
+		if !errors.As(err, &gcloudErr) {
+			t.Fatalf("Networks.Describe() error type = %T, want = *Error", err)
+		}
+		if got := gcloudErr.Kind; got != tc.want {
+			t.Errorf("Classify(%q) = %v, want = %v", tc.stderr, got, tc.want)
+		}
+		if tc.want != nil && !errors.Is(err, tc.want) {
+			t.Errorf("errors.Is(%v, %v) = false, want = true", err, tc.want)
+		}
+	}
+}
+
+func TestIgnoreNotFound(t *testing.T) {
+	notFound := &Error{Kind: ErrNotFound, Err: errors.New("exit status 1")}
+	if err := IgnoreNotFound(notFound); err != nil {
+		t.Errorf("IgnoreNotFound(not found) = %v, want = nil", err)
+	}
+	denied := &Error{Kind: ErrPermissionDenied, Err: errors.New("exit status 1")}
+	if err := IgnoreNotFound(denied); err == nil {
+		t.Errorf("IgnoreNotFound(permission denied) = nil, want = %v", denied)
+	}
+}
+
+func TestMember(t *testing.T) {
+	if got, want := Member("dev@example.com"), "user:dev@example.com"; got != want {
+		t.Errorf("Member() = %v, want = %v", got, want)
+	}
+	if got, want := Member("ci@p.iam.gserviceaccount.com"), "serviceAccount:ci@p.iam.gserviceaccount.com"; got != want {
+		t.Errorf("Member() = %v, want = %v", got, want)
+	}
+}
@@ -0,0 +1,81 @@
+// Copyright 2026 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gcloud
+
+import (
+	"encoding/json"
+	"strings"
+
+	"github.com/gruntwork-io/terratest/modules/logger"
+	"github.com/gruntwork-io/terratest/modules/shell"
+	"github.com/gruntwork-io/terratest/modules/testing"
+)
+
+// Role is the subset of a predefined IAM role the tests read.
+type Role struct {
+	Name                string   `json:"name"`
+	IncludedPermissions []string `json:"includedPermissions"`
+}
+
+// RolesService reads predefined IAM roles. gcloud rejects --project for
+// predefined roles, so it calls the IAM API instead.
+type RolesService struct{ c *Client }
+
+// Describe returns the predefined role, such as "roles/compute.networkAdmin",
+// read with token. Reading a predefined role requires no permission.
+func (s *RolesService) Describe(t testing.TestingT, token, role string) (*Role, error) {
+	out := &Role{}
+	return out, s.c.call(t, token, "GET", "https://iam.googleapis.com/v1/"+role, nil, out)
+}
+
+// TestPermissions returns the permissions among permissions that the account
+// of token holds on resource, such as "projects/p" or "organizations/42". A
+// granted role takes effect some time after its binding is readable, and
+// testIamPermissions is how the account itself observes it.
+func (s *IAMService) TestPermissions(t testing.TestingT, token, resource string, permissions []string) ([]string, error) {
+	var out struct {
+		Permissions []string `json:"permissions"`
+	}
+	in := map[string][]string{"permissions": permissions}
+	err := s.c.call(t, token, "POST", "https://cloudresourcemanager.googleapis.com/v1/"+resource+":testIamPermissions", in, &out)
+	return out.Permissions, err
+}
+
+// call sends a request to a Google API with curl through the runner of the
+// client, so that cassettes record it like a gcloud command, and decodes the
+// JSON response into out. The token is only valid for an hour and left out of
+// the logs and of a returned *Error, which is classified from the API error
+// in the response body.
+func (c *Client) call(t testing.TestingT, token, method, url string, in, out any) error {
+	args := []string{"--silent", "--show-error", "--fail-with-body", "--request", method}
+	if in != nil {
+		body, err := json.Marshal(in)
+		if err != nil {
+			return err
+		}
+		args = append(args, "--header", "Content-Type: application/json", "--data", string(body))
+	}
+	args = append(args, url)
+	cmd := shell.Command{Command: "curl", Args: append(args, "--oauth2-bearer", token), Logger: logger.Discard}
+	runner := c.Runner
+	if runner == nil {
+		runner = RunnerFor(t)
+	}
+	stdout, stderr, err := runner.Run(t, cmd)
+	if err != nil {
+		return newError(args, strings.TrimSpace(stdout+"\n"+stderr), err)
+	}
+	return decode(stdout, out)
+}
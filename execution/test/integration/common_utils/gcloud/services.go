@@ -0,0 +1,343 @@
+// Copyright 2026 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gcloud
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/gruntwork-io/terratest/modules/testing"
+)
+
+const serviceNetworking = "servicenetworking.googleapis.com"
+
+// VPCPeering is a private services access connection of a network.
+type VPCPeering struct {
+	Network               string   `json:"network"`
+	Peering               string   `json:"peering"`
+	Service               string   `json:"service"`
+	ReservedPeeringRanges []string `json:"reservedPeeringRanges"`
+}
+
+// VPCPeeringsService wraps "gcloud services vpc-peerings" for the service
+// networking API.
+type VPCPeeringsService struct{ c *Client }
+
+// Connect creates the private services access connection of network using
+// the allocated ranges.
+func (s *VPCPeeringsService) Connect(t testing.TestingT, network string, ranges ...string) error {
+	_, err := s.c.Run(t, "services", "vpc-peerings", "connect", "--service="+serviceNetworking,
+		"--network="+network, "--ranges="+strings.Join(ranges, ","))
+	return err
+}
+
+// List returns the private services access connections of network.
+func (s *VPCPeeringsService) List(t testing.TestingT, network string) ([]VPCPeering, error) {
+	var out []VPCPeering
+	err := s.c.RunJSON(t, &out, "services", "vpc-peerings", "list", "--network="+network)
+	return out, err
+}
+
+// Delete removes the private services access connection of network.
+func (s *VPCPeeringsService) Delete(t testing.TestingT, network string) error {
+	_, err := s.c.Run(t, "services", "vpc-peerings", "delete", "--service="+serviceNetworking, "--network="+network)
+	return err
+}
+
+// ServiceConnectionPolicy is a network connectivity service connection policy.
+type ServiceConnectionPolicy struct {
+	Name         string `json:"name"`
+	Network      string `json:"network"`
+	ServiceClass string `json:"serviceClass"`
+	CreateTime   string `json:"createTime"`
+	PSCConfig    struct {
+		Subnetworks []string `json:"subnetworks"`
+		Limit       string   `json:"limit"`
+	} `json:"pscConfig"`
+}
+
+// ServiceConnectionPolicyOptions are the flags used when creating a service
+// connection policy.
+type ServiceConnectionPolicyOptions struct {
+	Region             string
+	Network            string
+	ServiceClass       string
+	Subnets            []string
+	PSCConnectionLimit int
+}
+
+// ServiceConnectionPoliciesService wraps "gcloud network-connectivity
+// service-connection-policies".
+type ServiceConnectionPoliciesService struct{ c *Client }
+
+// Create creates a service connection policy. Subnets given by name are
+// expanded into self links in the policy region.
+func (s *ServiceConnectionPoliciesService) Create(t testing.TestingT, name string, opts ServiceConnectionPolicyOptions) (*ServiceConnectionPolicy, error) {
+	subnets := make([]string, 0, len(opts.Subnets))
+	for _, subnet := range opts.Subnets {
+		if !strings.Contains(subnet, "/") {
+			subnet = fmt.Sprintf("https://www.googleapis.com/compute/v1/projects/%s/regions/%s/subnetworks/%s", s.c.Project, opts.Region, subnet)
+		}
+		subnets = append(subnets, subnet)
+	}
+	args := []string{"network-connectivity", "service-connection-policies", "create", name,
+		"--region=" + opts.Region, "--network=" + opts.Network, "--service-class=" + opts.ServiceClass,
+		"--subnets=" + strings.Join(subnets, ",")}
+	if opts.PSCConnectionLimit != 0 {
+		args = append(args, fmt.Sprintf("--psc-connection-limit=%d", opts.PSCConnectionLimit))
+	}
+	args = append(args, s.c.labelsFlag()...)
+	out := &ServiceConnectionPolicy{}
+	return out, s.c.RunJSON(t, out, args...)
+}
+
+// Describe returns the service connection policy called name in region.
+func (s *ServiceConnectionPoliciesService) Describe(t testing.TestingT, region, name string) (*ServiceConnectionPolicy, error) {
+	out := &ServiceConnectionPolicy{}
+	return out, s.c.RunJSON(t, out, "network-connectivity", "service-connection-policies", "describe", name, "--region="+region)
+}
+
+// Delete deletes the service connection policy called name in region.
+func (s *ServiceConnectionPoliciesService) Delete(t testing.TestingT, region, name string) error {
+	_, err := s.c.Run(t, "network-connectivity", "service-connection-policies", "delete", name, "--region="+region)
+	return err
+}
+
+// SQLInstance is the subset of a Cloud SQL instance returned by gcloud that
+// the tests inspect.
+type SQLInstance struct {
+	Name                     string `json:"name"`
+	ConnectionName           string `json:"connectionName"`
+	DatabaseVersion          string `json:"databaseVersion"`
+	Region                   string `json:"region"`
+	State                    string `json:"state"`
+	PSCServiceAttachmentLink string `json:"pscServiceAttachmentLink"`
+	IPAddresses              []struct {
+		IPAddress string `json:"ipAddress"`
+		Type      string `json:"type"`
+	} `json:"ipAddresses"`
+	Settings struct {
+		Tier             string `json:"tier"`
+		AvailabilityType string `json:"availabilityType"`
+		IPConfiguration  struct {
+			IPv4Enabled    bool   `json:"ipv4Enabled"`
+			PrivateNetwork string `json:"privateNetwork"`
+		} `json:"ipConfiguration"`
+	} `json:"settings"`
+}
+
+// SQLService groups the "gcloud sql" command families.
+type SQLService struct {
+	Instances *SQLInstancesService
+}
+
+// SQLInstancesService wraps "gcloud sql instances".
+type SQLInstancesService struct{ c *Client }
+
+// Describe returns the Cloud SQL instance called name.
+func (s *SQLInstancesService) Describe(t testing.TestingT, name string) (*SQLInstance, error) {
+	out := &SQLInstance{}
+	return out, s.c.RunJSON(t, out, "sql", "instances", "describe", name)
+}
+
+// Delete deletes the Cloud SQL instance called name.
+func (s *SQLInstancesService) Delete(t testing.TestingT, name string) error {
+	_, err := s.c.Run(t, "sql", "instances", "delete", name)
+	return err
+}
+
+// ServiceAccount is the subset of an IAM service account the tests read.
+type ServiceAccount struct {
+	Name        string `json:"name"`
+	Email       string `json:"email"`
+	DisplayName string `json:"displayName"`
+	UniqueID    string `json:"uniqueId"`
+	Disabled    bool   `json:"disabled"`
+}
+
+// IAMService groups the "gcloud iam" command families and the IAM checks
+// made with the API.
+type IAMService struct {
+	c *Client
+
+	ServiceAccounts *ServiceAccountsService
+	Roles           *RolesService
+}
+
+// ServiceAccountsService wraps "gcloud iam service-accounts".
+type ServiceAccountsService struct{ c *Client }
+
+// Create creates the service account name (the part of the email before @).
+func (s *ServiceAccountsService) Create(t testing.TestingT, name, displayName string) (*ServiceAccount, error) {
+	out := &ServiceAccount{}
+	return out, s.c.RunJSON(t, out, "iam", "service-accounts", "create", name, "--display-name="+displayName)
+}
+
+// Describe returns the service account with the given email.
+func (s *ServiceAccountsService) Describe(t testing.TestingT, email string) (*ServiceAccount, error) {
+	out := &ServiceAccount{}
+	return out, s.c.RunJSON(t, out, "iam", "service-accounts", "describe", email)
+}
+
+// Delete deletes the service account with the given email.
+func (s *ServiceAccountsService) Delete(t testing.TestingT, email string) error {
+	_, err := s.c.Run(t, "iam", "service-accounts", "delete", email)
+	return err
+}
+
+// AddIAMPolicyBinding grants role on the service account with the given email
+// to member, such as "user:dev@example.com".
+func (s *ServiceAccountsService) AddIAMPolicyBinding(t testing.TestingT, email, member, role string) error {
+	_, err := s.c.Run(t, "iam", "service-accounts", "add-iam-policy-binding", email, "--member="+member, "--role="+role, "--condition=None")
+	return err
+}
+
+// RemoveIAMPolicyBinding revokes role on the service account with the given
+// email from member.
+func (s *ServiceAccountsService) RemoveIAMPolicyBinding(t testing.TestingT, email, member, role string) error {
+	_, err := s.c.Run(t, "iam", "service-accounts", "remove-iam-policy-binding", email, "--member="+member, "--role="+role, "--condition=None")
+	return err
+}
+
+// PrintAccessToken mints an access token for the service account, which only
+// succeeds once the caller's Token Creator binding has propagated.
+func (s *ServiceAccountsService) PrintAccessToken(t testing.TestingT, email string) (string, error) {
+	out, err := s.c.Run(t, "auth", "print-access-token", "--impersonate-service-account="+email)
+	return strings.TrimSpace(out), err
+}
+
+// APIsService wraps "gcloud services" for enabling Google APIs.
+type APIsService struct{ c *Client }
+
+// Enable enables api. Enabling an API that is already enabled is a no-op.
+func (s *APIsService) Enable(t testing.TestingT, api string) error {
+	_, err := s.c.Run(t, "services", "enable", api)
+	return err
+}
+
+// ListEnabled returns the names of the APIs enabled on the project, such as
+// "compute.googleapis.com".
+func (s *APIsService) ListEnabled(t testing.TestingT) ([]string, error) {
+	var services []struct {
+		Config struct {
+			Name string `json:"name"`
+		} `json:"config"`
+	}
+	if err := s.c.RunJSON(t, &services, "services", "list", "--enabled"); err != nil {
+		return nil, err
+	}
+	names := make([]string, 0, len(services))
+	for _, service := range services {
+		names = append(names, service.Config.Name)
+	}
+	return names, nil
+}
+
+// Account returns the account gcloud runs as, such as "user@example.com" or
+// the email of a service account.
+func (c *Client) Account(t testing.TestingT) (string, error) {
+	var account string
+	if err := c.RunJSON(t, &account, "config", "get-value", "account"); err != nil {
+		return "", err
+	}
+	if account == "" {
+		return "", fmt.Errorf("gcloud has no active account: run gcloud auth login")
+	}
+	return account, nil
+}
+
+// AccessToken returns an access token of the account gcloud runs as, for the
+// tests that call a Google API over HTTP.
+func (c *Client) AccessToken(t testing.TestingT) (string, error) {
+	var token string
+	if err := c.RunJSON(t, &token, "auth", "print-access-token"); err != nil {
+		return "", err
+	}
+	return token, nil
+}
+
+// IdentityToken returns an identity token of the account gcloud runs as, for
+// the tests that call a service which requires authentication, such as a
+// Cloud Run service without public access.
+func (c *Client) IdentityToken(t testing.TestingT) (string, error) {
+	var token string
+	if err := c.RunJSON(t, &token, "auth", "print-identity-token"); err != nil {
+		return "", err
+	}
+	return token, nil
+}
+
+// Member returns the IAM member of a gcloud account: a serviceAccount: member
+// for a service account email and a user: member otherwise.
+func Member(account string) string {
+	if strings.HasSuffix(account, ".gserviceaccount.com") {
+		return "serviceAccount:" + account
+	}
+	return "user:" + account
+}
+
+// Binding grants a role to members in an IAM policy.
+type Binding struct {
+	Role    string   `json:"role"`
+	Members []string `json:"members"`
+}
+
+// AncestorPolicy is the IAM policy of the project or of one of its folders
+// or organization.
+type AncestorPolicy struct {
+	ID     string `json:"id"`
+	Type   string `json:"type"`
+	Policy struct {
+		Bindings []Binding `json:"bindings"`
+	} `json:"policy"`
+}
+
+// ProjectsService wraps "gcloud projects" for the client project.
+type ProjectsService struct{ c *Client }
+
+// AncestorsIAMPolicy returns the IAM policies of the project, its folders and
+// its organization, the bindings of which all apply to the project.
+func (s *ProjectsService) AncestorsIAMPolicy(t testing.TestingT) ([]AncestorPolicy, error) {
+	var out []AncestorPolicy
+	err := s.c.RunJSON(t, &out, "projects", "get-ancestors-iam-policy", s.c.Project)
+	return out, err
+}
+
+// AddIAMPolicyBinding grants role on the project to member.
+func (s *ProjectsService) AddIAMPolicyBinding(t testing.TestingT, member, role string) error {
+	_, err := s.c.Run(t, "projects", "add-iam-policy-binding", s.c.Project, "--member="+member, "--role="+role, "--condition=None")
+	return err
+}
+
+// RemoveIAMPolicyBinding revokes role on the project from member.
+func (s *ProjectsService) RemoveIAMPolicyBinding(t testing.TestingT, member, role string) error {
+	_, err := s.c.Run(t, "projects", "remove-iam-policy-binding", s.c.Project, "--member="+member, "--role="+role, "--condition=None")
+	return err
+}
+
+// OrganizationsService wraps "gcloud organizations".
+type OrganizationsService struct{ c *Client }
+
+// AddIAMPolicyBinding grants role on the organization orgID to member.
+func (s *OrganizationsService) AddIAMPolicyBinding(t testing.TestingT, orgID, member, role string) error {
+	_, err := s.c.Run(t, "organizations", "add-iam-policy-binding", orgID, "--member="+member, "--role="+role, "--condition=None")
+	return err
+}
+
+// RemoveIAMPolicyBinding revokes role on the organization orgID from member.
+func (s *OrganizationsService) RemoveIAMPolicyBinding(t testing.TestingT, orgID, member, role string) error {
+	_, err := s.c.Run(t, "organizations", "remove-iam-policy-binding", orgID, "--member="+member, "--role="+role, "--condition=None")
+	return err
+}
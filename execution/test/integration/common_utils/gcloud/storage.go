@@ -0,0 +1,68 @@
+// Copyright 2026 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gcloud
+
+import (
+	"encoding/json"
+	"os"
+	"time"
+
+	"github.com/gruntwork-io/terratest/modules/testing"
+)
+
+// Object is the subset of a Cloud Storage object the tests read.
+type Object struct {
+	Name        string      `json:"name"`
+	Generation  json.Number `json:"generation"`
+	TimeCreated time.Time   `json:"timeCreated"`
+}
+
+// ObjectsService wraps "gcloud storage" for single objects, addressed by URLs
+// such as "gs://bucket/name". The generation preconditions make it usable as a
+// lock: creating with generation "0" fails with ErrPreconditionFailed when
+// the object exists.
+type ObjectsService struct{ c *Client }
+
+// Create writes content to the object at url if its live generation is
+// ifGenerationMatch, "0" meaning that the object must not exist.
+func (s *ObjectsService) Create(t testing.TestingT, url, content, ifGenerationMatch string) error {
+	f, err := os.CreateTemp("", "cso-test-object-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(f.Name())
+	_, err = f.WriteString(content)
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return err
+	}
+	_, err = s.c.Run(t, "storage", "cp", f.Name(), url, "--if-generation-match="+ifGenerationMatch)
+	return err
+}
+
+// Describe returns the metadata of the object at url as returned by the API.
+func (s *ObjectsService) Describe(t testing.TestingT, url string) (*Object, error) {
+	out := &Object{}
+	return out, s.c.RunJSON(t, out, "storage", "objects", "describe", url, "--raw")
+}
+
+// Delete deletes the object at url if its live generation is
+// ifGenerationMatch.
+func (s *ObjectsService) Delete(t testing.TestingT, url, ifGenerationMatch string) error {
+	_, err := s.c.Run(t, "storage", "rm", url, "--if-generation-match="+ifGenerationMatch)
+	return err
+}
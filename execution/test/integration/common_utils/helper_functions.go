@@ -15,37 +15,72 @@
 package common_utils
 
 import (
+	"errors"
 	"fmt"
-	"github.com/gruntwork-io/terratest/modules/shell"
 	"testing"
-	"time"
+
+	"github.com/GoogleCloudPlatform/cloudnetworking-config-solutions/common_utils/fixture"
+	"github.com/GoogleCloudPlatform/cloudnetworking-config-solutions/common_utils/gcloud"
+	"github.com/GoogleCloudPlatform/cloudnetworking-config-solutions/common_utils/ipam"
+	"github.com/GoogleCloudPlatform/cloudnetworking-config-solutions/common_utils/naming"
+	"github.com/GoogleCloudPlatform/cloudnetworking-config-solutions/common_utils/wait"
 )
 
+/*
+VPCSubnets holds the fixture handles of the network and subnetwork created by
+CreateVPCSubnets. A handle is nil if its resource could not be created.
+*/
+type VPCSubnets struct {
+	Network    *fixture.Handle
+	Subnetwork *fixture.Handle
+}
+
 /*
 CreateVPCSubnets is a helper function which creates the VPC and subnets before
-execution of the test expecting to use existing VPC and subnets.
+execution of the test expecting to use existing VPC and subnets. The range of
+the subnetwork is a /24 leased from the ipam package. Both are deleted when the
+test ends, the subnetwork first.
 */
 
-func CreateVPCSubnets(t *testing.T, projectID string, networkName string, subnetworkName string, region string) {
-	subnetworkIPCIDR := "10.0.1.0/24"
-	text := "compute"
-	cmd := shell.Command{
-		Command: "gcloud",
-		Args:    []string{text, "networks", "create", networkName, "--project=" + projectID, "--format=json", "--bgp-routing-mode=global", "--subnet-mode=custom", "--verbosity=none"},
+func CreateVPCSubnets(t *testing.T, projectID string, networkName string, subnetworkName string, region string) *VPCSubnets {
+	vpc, err := CreateVPCSubnetsE(t, projectID, networkName, subnetworkName, region)
+	if err != nil {
+		t.Errorf("===Error %s Encountered while creating VPC subnets", err)
 	}
-	_, err := shell.RunCommandAndGetOutputE(t, cmd)
+	return vpc
+}
+
+/*
+CreateVPCSubnetsE creates the VPC and subnets like CreateVPCSubnets and returns
+the first error instead of failing the test. The resources created before the
+error are still deleted when the test ends.
+*/
+func CreateVPCSubnetsE(t testing.TB, projectID string, networkName string, subnetworkName string, region string) (*VPCSubnets, error) {
+	client := gcloud.New(projectID)
+	vpc := &VPCSubnets{}
+	subnetworkIPCIDR, err := ipam.LeaseE(t, ipam.Subnets, 24)
 	if err != nil {
-		t.Errorf("===Error %s Encountered while executing %s", err, text)
+		return vpc, err
 	}
-	time.Sleep(60 * time.Second)
-	cmd = shell.Command{
-		Command: "gcloud",
-		Args:    []string{text, "networks", "subnets", "create", subnetworkName, "--network=" + networkName, "--project=" + projectID, "--range=" + subnetworkIPCIDR, "--region=" + region, "--format=json", "--enable-private-ip-google-access", "--enable-flow-logs", "--verbosity=none"},
+	vpc.Network, err = fixture.Network(t, client, networkName, gcloud.NetworkOptions{BGPRoutingMode: "global"})
+	if err != nil {
+		return vpc, fmt.Errorf("creating network %s: %w", networkName, err)
+	}
+	if _, err := wait.ForE(t, "network "+networkName+" to exist", wait.DefaultOptions, wait.NetworkExists(t, client, networkName)); err != nil {
+		return vpc, err
 	}
-	_, err = shell.RunCommandAndGetOutputE(t, cmd)
+	vpc.Subnetwork, err = fixture.Subnet(t, client, subnetworkName, gcloud.SubnetOptions{
+		Network:                     networkName,
+		Region:                      region,
+		Range:                       subnetworkIPCIDR,
+		EnablePrivateIPGoogleAccess: true,
+		EnableFlowLogs:              true,
+	}, vpc.Network)
 	if err != nil {
-		t.Errorf("===Error %s Encountered while executing %s", err, text)
+		return vpc, fmt.Errorf("creating subnetwork %s: %w", subnetworkName, err)
 	}
+	_, err = wait.ForE(t, "subnetwork "+subnetworkName+" to be READY", wait.DefaultOptions, wait.SubnetReady(t, client, region, subnetworkName))
+	return vpc, err
 }
 
 /*
@@ -53,53 +88,60 @@ DeleteVPCSubnets is a helper function which deletes the VPC and subnets after
 completion of the test expecting to use existing VPC and subnets.
 */
 func DeleteVPCSubnets(t *testing.T, projectID string, networkName string, subnetworkName string, region string) {
-	text := "compute"
-	cmd := shell.Command{
-		Command: "gcloud",
-		Args:    []string{text, "networks", "subnets", "delete", subnetworkName, "--region=" + region, "--project=" + projectID, "--quiet"},
-	}
-	_, err := shell.RunCommandAndGetOutputE(t, cmd)
-	if err != nil {
-		t.Errorf("===Error %s Encountered while executing %s", err, text)
+	if err := DeleteVPCSubnetsE(t, projectID, networkName, subnetworkName, region); err != nil {
+		t.Errorf("===Error %s Encountered while deleting VPC subnets", err)
 	}
+}
 
-	// Sleep for 60 seconds to ensure the deleted subnets is reliably reflected.
-	time.Sleep(60 * time.Second)
+/*
+DeleteVPCSubnetsE deletes the VPC and subnets like DeleteVPCSubnets. It goes on
+after a failed step and returns the errors of all of them. Resources that do
+not exist are not an error.
+*/
+func DeleteVPCSubnetsE(t testing.TB, projectID string, networkName string, subnetworkName string, region string) error {
+	client := gcloud.New(projectID)
+	var errs []error
+	if err := client.Subnets.Delete(t, region, subnetworkName); gcloud.IgnoreNotFound(err) != nil {
+		errs = append(errs, fmt.Errorf("deleting subnetwork %s: %w", subnetworkName, err))
+	}
 
-	cmd = shell.Command{
-		Command: "gcloud",
-		Args:    []string{text, "networks", "delete", networkName, "--project=" + projectID, "--quiet"},
+	// Wait until the deleted subnet is reliably reflected before deleting the network.
+	if _, err := wait.ForE(t, "subnetwork "+subnetworkName+" to be deleted", wait.DefaultOptions, wait.SubnetDeleted(t, client, region, subnetworkName)); err != nil {
+		errs = append(errs, fmt.Errorf("waiting for subnetwork %s deletion: %w", subnetworkName, err))
 	}
-	_, err = shell.RunCommandAndGetOutputE(t, cmd)
-	if err != nil {
-		t.Errorf("===Error %s Encountered while executing %s", err, text)
+
+	if err := client.Networks.Delete(t, networkName); gcloud.IgnoreNotFound(err) != nil {
+		errs = append(errs, fmt.Errorf("deleting network %s: %w", networkName, err))
 	}
+	return errors.Join(errs...)
 }
 
 /*
 CreateServiceConnectionPolicy is a helped function that creates the service
-connection policy.
+connection policy, labelled with the run and the test. The policy is deleted
+when the test ends, before any of the fixtures in dependsOn such as its
+subnetwork.
 */
-func CreateServiceConnectionPolicy(t *testing.T, projectID string, region string, networkName string, policyName string, subnetworkName string, serviceClass string, connectionLimit int) {
-	// Get subnet self link from subnet ID using gcloud command
-	subnetSelfLink := fmt.Sprintf("https://www.googleapis.com/compute/v1/projects/%s/regions/%s/subnetworks/%s", projectID, region, subnetworkName)
-
-	cmd := shell.Command{
-		Command: "gcloud",
-		Args: []string{
-			"network-connectivity", "service-connection-policies", "create",
-			policyName, // Add the policyName here as the first argument after "create"
-			"--project", projectID,
-			"--region", region,
-			"--network", networkName,
-			"--service-class", serviceClass,
-			"--subnets", subnetSelfLink,
-			"--psc-connection-limit", fmt.Sprintf("%d", connectionLimit),
-			"--quiet",
-		},
-	}
-	_, err := shell.RunCommandAndGetOutputE(t, cmd)
+func CreateServiceConnectionPolicy(t *testing.T, projectID string, region string, networkName string, policyName string, subnetworkName string, serviceClass string, connectionLimit int, dependsOn ...*fixture.Handle) *fixture.Handle {
+	policy, err := CreateServiceConnectionPolicyE(t, projectID, region, networkName, policyName, subnetworkName, serviceClass, connectionLimit, dependsOn...)
 	if err != nil {
 		t.Errorf("Error creating Service Connection Policy: %s", err)
 	}
+	return policy
+}
+
+/*
+CreateServiceConnectionPolicyE creates the service connection policy like
+CreateServiceConnectionPolicy and returns the error instead of reporting it.
+*/
+func CreateServiceConnectionPolicyE(t testing.TB, projectID string, region string, networkName string, policyName string, subnetworkName string, serviceClass string, connectionLimit int, dependsOn ...*fixture.Handle) (*fixture.Handle, error) {
+	client := gcloud.New(projectID)
+	client.Labels = naming.Labels(t)
+	return fixture.ServiceConnectionPolicy(t, client, policyName, gcloud.ServiceConnectionPolicyOptions{
+		Region:             region,
+		Network:            networkName,
+		ServiceClass:       serviceClass,
+		Subnets:            []string{subnetworkName},
+		PSCConnectionLimit: connectionLimit,
+	}, dependsOn...)
 }
@@ -0,0 +1,144 @@
+// Copyright 2026 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package common_utils
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/GoogleCloudPlatform/cloudnetworking-config-solutions/common_utils/fakegcloud"
+	"github.com/GoogleCloudPlatform/cloudnetworking-config-solutions/common_utils/gcloud"
+	"github.com/GoogleCloudPlatform/cloudnetworking-config-solutions/common_utils/ipam"
+)
+
+const (
+	project = "fake-project"
+	region  = "us-central1"
+)
+
+func TestCreateVPCSubnets(t *testing.T) {
+	fake := fakegcloud.Install(t)
+	t.Setenv(ipam.LeaseFileEnv, filepath.Join(t.TempDir(), "leases.json"))
+	t.Run("create", func(t *testing.T) {
+		vpc, err := CreateVPCSubnetsE(t, project, "vpc-1", "subnet-1", region)
+		if err != nil {
+			t.Fatalf("CreateVPCSubnetsE() returned error: %v", err)
+		}
+		if vpc.Network == nil || vpc.Subnetwork == nil {
+			t.Errorf("CreateVPCSubnetsE() handles = %+v, want = both set", vpc)
+		}
+		subnet := fake.Get(t, "compute networks subnets", project, region, "subnet-1")
+		if got, want := subnet["ipCidrRange"], "10.0.0.0/24"; got != want {
+			t.Errorf("Subnet ipCidrRange = %v, want = %v", got, want)
+		}
+		if got, want := subnet["privateIpGoogleAccess"], true; got != want {
+			t.Errorf("Subnet privateIpGoogleAccess = %v, want = %v", got, want)
+		}
+	})
+
+	want := []string{
+		"compute networks create vpc-1 --subnet-mode=custom --bgp-routing-mode=global",
+		"compute networks subnets create subnet-1 --network=vpc-1 --region=us-central1 --range=10.0.0.0/24 --enable-private-ip-google-access --enable-flow-logs",
+		"compute networks subnets delete subnet-1 --region=us-central1",
+		"compute networks delete vpc-1",
+	}
+	got := append(fake.Commands(t, " create "), fake.Commands(t, " delete ")...)
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("Commands =\n%v\nwant =\n%v", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+	if fake.Get(t, "compute networks", project, "global", "vpc-1") != nil {
+		t.Errorf("Network vpc-1 still exists after the test")
+	}
+}
+
+func TestCreateVPCSubnetsFailure(t *testing.T) {
+	fake := fakegcloud.Install(t)
+	fake.Fail(t, fakegcloud.Failure{
+		Match:  "subnets create",
+		Stderr: "ERROR: (gcloud.compute.networks.subnets.create) PERMISSION_DENIED: Required 'compute.subnetworks.create' permission",
+	})
+	t.Run("create", func(t *testing.T) {
+		vpc, err := CreateVPCSubnetsE(t, project, "vpc-1", "subnet-1", region)
+		if !gcloud.IsPermissionDenied(err) {
+			t.Errorf("CreateVPCSubnetsE() error = %v, want = permission denied", err)
+		}
+		if vpc.Network == nil || vpc.Subnetwork != nil {
+			t.Errorf("CreateVPCSubnetsE() handles = %+v, want = only the network", vpc)
+		}
+	})
+
+	if fake.Get(t, "compute networks", project, "global", "vpc-1") != nil {
+		t.Errorf("Network vpc-1 still exists after the test")
+	}
+}
+
+func TestDeleteVPCSubnets(t *testing.T) {
+	fake := fakegcloud.Install(t)
+	fake.Run(t, "compute", "networks", "create", "vpc-1", "--project="+project)
+	fake.Run(t, "compute", "networks", "subnets", "create", "subnet-1", "--network=vpc-1", "--region="+region, "--project="+project)
+
+	if err := DeleteVPCSubnetsE(t, project, "vpc-1", "subnet-1", region); err != nil {
+		t.Fatalf("DeleteVPCSubnetsE() returned error: %v", err)
+	}
+	if fake.Get(t, "compute networks", project, "global", "vpc-1") != nil {
+		t.Errorf("Network vpc-1 still exists")
+	}
+	if err := DeleteVPCSubnetsE(t, project, "vpc-1", "subnet-1", region); err != nil {
+		t.Errorf("DeleteVPCSubnetsE() of deleted resources returned error: %v", err)
+	}
+
+	fake.Run(t, "compute", "networks", "create", "vpc-2", "--project="+project)
+	fake.Fail(t, fakegcloud.Failure{Match: "networks delete vpc-2", Stderr: "ERROR: (gcloud.compute.networks.delete) PERMISSION_DENIED"})
+	if err := DeleteVPCSubnetsE(t, project, "vpc-2", "subnet-2", region); !gcloud.IsPermissionDenied(err) {
+		t.Errorf("DeleteVPCSubnetsE() error = %v, want = permission denied", err)
+	}
+}
+
+func TestCreateServiceConnectionPolicy(t *testing.T) {
+	fake := fakegcloud.Install(t)
+	fake.Run(t, "compute", "networks", "create", "vpc-1", "--project="+project)
+	fake.Run(t, "compute", "networks", "subnets", "create", "subnet-1", "--network=vpc-1", "--region="+region, "--project="+project)
+
+	t.Run("create", func(t *testing.T) {
+		if _, err := CreateServiceConnectionPolicyE(t, project, region, "vpc-1", "scp-1", "subnet-1", "gcp-memorystore-redis", 5); err != nil {
+			t.Fatalf("CreateServiceConnectionPolicyE() returned error: %v", err)
+		}
+		policy := fake.Get(t, "network-connectivity service-connection-policies", project, region, "scp-1")
+		if got, want := policy["serviceClass"], "gcp-memorystore-redis"; got != want {
+			t.Errorf("Policy serviceClass = %v, want = %v", got, want)
+		}
+		labels, _ := policy["labels"].(map[string]any)
+		if got, want := labels["test-name"], "testcreateserviceconnectionpolicy_create"; got != want {
+			t.Errorf("Policy test-name label = %v, want = %v", got, want)
+		}
+		// The subnetwork cannot go while the policy uses it.
+		if err := gcloud.New(project).Subnets.Delete(t, region, "subnet-1"); !gcloud.IsTransient(err) {
+			t.Errorf("Subnets.Delete() of the policy subnetwork error = %v, want = transient", err)
+		}
+	})
+
+	want := []string{
+		"network-connectivity service-connection-policies create scp-1 --region=us-central1 --network=vpc-1 --service-class=gcp-memorystore-redis --subnets=https://www.googleapis.com/compute/v1/projects/fake-project/regions/us-central1/subnetworks/subnet-1 --psc-connection-limit=5",
+		"network-connectivity service-connection-policies delete scp-1 --region=us-central1",
+	}
+	got := fake.Commands(t, "service-connection-policies")
+	for i := range got {
+		got[i], _, _ = strings.Cut(got[i], " --labels=")
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("Commands =\n%v\nwant =\n%v", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+}
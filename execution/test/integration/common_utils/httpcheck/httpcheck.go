@@ -0,0 +1,257 @@
+// Copyright 2026 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package httpcheck verifies the HTTP endpoints a stage exposes, such as an
+// App Engine service, a Cloud Run service or a load balancer. A Check sends a
+// request until the response has an expected status and passes every
+// matcher, backing off between attempts, and reports why each failed attempt
+// did not pass.
+package httpcheck
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/GoogleCloudPlatform/cloudnetworking-config-solutions/common_utils/wait"
+	"github.com/gruntwork-io/terratest/modules/testing"
+)
+
+// DefaultTimeout bounds a single attempt when the check sets no Timeout.
+var DefaultTimeout = 30 * time.Second
+
+// maxBody is the number of bytes of a response body that are read.
+const maxBody = 1 << 20
+
+// Check describes a request and the responses that pass.
+type Check struct {
+	// Method defaults to GET.
+	Method string
+	URL    string
+	// Host overrides the Host header, for example to reach a load balancer by
+	// its IP address for a host name it routes. It is also the default
+	// ServerName.
+	Host   string
+	Header http.Header
+	Body   string
+
+	// Status lists the accepted status codes. It defaults to any 2xx.
+	Status []int
+	// Match lists further conditions on the response.
+	Match []Matcher
+
+	// CACert is a PEM bundle that server certificates are verified against
+	// instead of the system roots.
+	CACert []byte
+	// ServerName is the name the server certificate is verified for.
+	ServerName string
+	// Insecure skips the verification of the server certificate.
+	Insecure bool
+	// FollowRedirects follows redirects rather than checking the redirect.
+	FollowRedirects bool
+
+	// Timeout bounds a single attempt. It defaults to DefaultTimeout.
+	Timeout time.Duration
+	// Retry is the backoff between attempts and the overall deadline. It
+	// defaults to wait.DefaultOptions.
+	Retry wait.Options
+	// MaxAttempts stops the check after that many attempts if set.
+	MaxAttempts int
+}
+
+// Response is a response received by a check.
+type Response struct {
+	StatusCode int
+	Header     http.Header
+	Body       []byte
+	// Latency is the time until the body was read.
+	Latency time.Duration
+}
+
+// Attempt is a failed attempt of a check.
+type Attempt struct {
+	// Response is nil when no response was received.
+	Response *Response
+	Err      error
+}
+
+// Error reports a check that did not pass, with the reason of every attempt.
+type Error struct {
+	URL      string
+	Attempts []Attempt
+	// Err is the reason the check stopped, such as a *wait.TimeoutError.
+	Err error
+}
+
+func (e *Error) Error() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s did not pass after %d attempt(s): %v", e.URL, len(e.Attempts), e.Err)
+	for i, a := range e.Attempts {
+		fmt.Fprintf(&b, "\n  attempt %d: %v", i+1, a.Err)
+	}
+	return b.String()
+}
+
+func (e *Error) Unwrap() error { return e.Err }
+
+var errMaxAttempts = errors.New("maximum number of attempts reached")
+
+// VerifyE sends the request of c until a response passes and returns that
+// response. Otherwise it returns an *Error.
+func VerifyE(t testing.TestingT, c Check) (*Response, error) {
+	client, req, err := c.prepare()
+	if err != nil {
+		return nil, err
+	}
+	var passed *Response
+	var attempts []Attempt
+	_, err = wait.ForE(t, c.describe(), c.Retry, func() (bool, string, error) {
+		resp, err := c.do(client, req)
+		if err == nil {
+			err = c.check(resp)
+		}
+		if err == nil {
+			passed = resp
+			return true, fmt.Sprintf("HTTP %d", resp.StatusCode), nil
+		}
+		attempts = append(attempts, Attempt{Response: resp, Err: err})
+		if c.MaxAttempts > 0 && len(attempts) >= c.MaxAttempts {
+			return false, "", errMaxAttempts
+		}
+		return false, err.Error(), nil
+	})
+	if err != nil {
+		return nil, &Error{URL: c.URL, Attempts: attempts, Err: err}
+	}
+	return passed, nil
+}
+
+// Verify is like VerifyE but fails the test if no response passes.
+func Verify(t testing.TestingT, c Check) *Response {
+	resp, err := VerifyE(t, c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return resp
+}
+
+func (c Check) describe() string {
+	method := c.Method
+	if method == "" {
+		method = http.MethodGet
+	}
+	s := method + " " + c.URL
+	if c.Host != "" {
+		s += " (Host " + c.Host + ")"
+	}
+	return s + " to pass"
+}
+
+// prepare builds the client and the request of the check.
+func (c Check) prepare() (*http.Client, *http.Request, error) {
+	tlsConfig := &tls.Config{ServerName: c.ServerName, InsecureSkipVerify: c.Insecure}
+	if tlsConfig.ServerName == "" && c.Host != "" {
+		tlsConfig.ServerName = strings.Split(c.Host, ":")[0]
+	}
+	if len(c.CACert) > 0 {
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(c.CACert) {
+			return nil, nil, errors.New("httpcheck: CACert holds no PEM certificate")
+		}
+		tlsConfig.RootCAs = pool
+	}
+	timeout := c.Timeout
+	if timeout == 0 {
+		timeout = DefaultTimeout
+	}
+	client := &http.Client{
+		Timeout: timeout,
+		// A new connection per attempt, so that an attempt is not served by
+		// a backend that a previous attempt reached.
+		Transport: &http.Transport{TLSClientConfig: tlsConfig, DisableKeepAlives: true, Proxy: http.ProxyFromEnvironment},
+	}
+	if !c.FollowRedirects {
+		client.CheckRedirect = func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse }
+	}
+	method := c.Method
+	if method == "" {
+		method = http.MethodGet
+	}
+	req, err := http.NewRequest(method, c.URL, nil)
+	if err != nil {
+		return nil, nil, err
+	}
+	req.Header = c.Header.Clone()
+	if req.Header == nil {
+		req.Header = http.Header{}
+	}
+	if c.Host != "" {
+		req.Host = c.Host
+	}
+	return client, req, nil
+}
+
+func (c Check) do(client *http.Client, req *http.Request) (*Response, error) {
+	req = req.Clone(req.Context())
+	if c.Body != "" {
+		req.Body = io.NopCloser(strings.NewReader(c.Body))
+		req.ContentLength = int64(len(c.Body))
+	}
+	start := time.Now()
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxBody))
+	if err != nil {
+		return nil, fmt.Errorf("reading the body: %w", err)
+	}
+	return &Response{StatusCode: resp.StatusCode, Header: resp.Header, Body: body, Latency: time.Since(start)}, nil
+}
+
+// check returns why resp does not pass, or nil.
+func (c Check) check(resp *Response) error {
+	if len(c.Status) > 0 && !slices.Contains(c.Status, resp.StatusCode) ||
+		len(c.Status) == 0 && (resp.StatusCode < 200 || resp.StatusCode > 299) {
+		want := "2xx"
+		if len(c.Status) > 0 {
+			want = fmt.Sprint(c.Status)
+		}
+		return fmt.Errorf("status %d, want %s; body: %s", resp.StatusCode, want, excerpt(resp.Body))
+	}
+	var errs []error
+	for _, m := range c.Match {
+		if err := m(resp); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// excerpt returns the beginning of a body for error messages.
+func excerpt(body []byte) string {
+	const n = 200
+	s := strings.TrimSpace(string(body))
+	if len(s) > n {
+		s = s[:n] + "..."
+	}
+	return fmt.Sprintf("%q", s)
+}
@@ -0,0 +1,128 @@
+// Copyright 2026 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package httpcheck
+
+import (
+	"encoding/pem"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/GoogleCloudPlatform/cloudnetworking-config-solutions/common_utils/wait"
+)
+
+var fast = wait.Options{Timeout: 5 * time.Second, Interval: 10 * time.Millisecond, MaxInterval: 10 * time.Millisecond}
+
+func TestVerifyRetriesUntilPass(t *testing.T) {
+	var calls atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if calls.Add(1) < 3 {
+			http.Error(w, "warming up", http.StatusServiceUnavailable)
+			return
+		}
+		w.Header().Set("X-Backend", "mig-1")
+		w.Write([]byte(`{"greeting": "Hello World!", "status": {"conditions": [{"type": "Ready", "ok": true}]}}`))
+	}))
+	defer srv.Close()
+
+	resp, err := VerifyE(t, Check{
+		URL:   srv.URL,
+		Retry: fast,
+		Match: []Matcher{
+			BodyContains("Hello World"),
+			BodyMatches(`"greeting":\s*"Hello`),
+			JSONPath("status.conditions.0.type", "Ready"),
+			JSONPath("status.conditions.0.ok", "true"),
+			Header("X-Backend", "mig-1"),
+			HeaderMatches("Content-Type", "^text/plain"),
+		},
+	})
+	if err != nil {
+		t.Fatalf("VerifyE() error = %v", err)
+	}
+	if got, want := resp.StatusCode, http.StatusOK; got != want {
+		t.Errorf("StatusCode = %v, want = %v", got, want)
+	}
+	if got, want := calls.Load(), int32(3); got != want {
+		t.Errorf("requests = %v, want = %v", got, want)
+	}
+}
+
+func TestVerifyReportsEveryAttempt(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"mode": "production"}`))
+	}))
+	defer srv.Close()
+
+	_, err := VerifyE(t, Check{
+		URL:         srv.URL,
+		Retry:       fast,
+		MaxAttempts: 2,
+		Match:       []Matcher{JSONPath("mode", "staging"), BodyContains("Hello")},
+	})
+	var cerr *Error
+	if !errors.As(err, &cerr) {
+		t.Fatalf("VerifyE() error = %v, want an *Error", err)
+	}
+	if got, want := len(cerr.Attempts), 2; got != want {
+		t.Errorf("len(Attempts) = %v, want = %v", got, want)
+	}
+	for _, want := range []string{"attempt 2:", "mode = production, want = staging", `body does not contain "Hello"`} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("Error() = %q, want it to contain %q", err.Error(), want)
+		}
+	}
+}
+
+func TestVerifyStatusAndHost(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Host != "app.example.com" {
+			http.NotFound(w, r)
+			return
+		}
+		http.Redirect(w, r, "/login", http.StatusFound)
+	}))
+	defer srv.Close()
+
+	if _, err := VerifyE(t, Check{URL: srv.URL, Host: "app.example.com", Status: []int{http.StatusFound}, Retry: fast, MaxAttempts: 1,
+		Match: []Matcher{Header("Location", "/login")}}); err != nil {
+		t.Errorf("VerifyE() with Host error = %v", err)
+	}
+	_, err := VerifyE(t, Check{URL: srv.URL, Retry: fast, MaxAttempts: 1})
+	if err == nil || !strings.Contains(err.Error(), "status 404, want 2xx") {
+		t.Errorf("VerifyE() without Host error = %v, want a status error", err)
+	}
+}
+
+func TestVerifyCACert(t *testing.T) {
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer srv.Close()
+	ca := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: srv.Certificate().Raw})
+
+	if _, err := VerifyE(t, Check{URL: srv.URL, Retry: fast, MaxAttempts: 1}); err == nil {
+		t.Errorf("VerifyE() against the system roots error = nil, want a certificate error")
+	}
+	// The test certificate is issued for example.com.
+	if _, err := VerifyE(t, Check{URL: srv.URL, CACert: ca, Host: "example.com", Retry: fast, MaxAttempts: 1}); err != nil {
+		t.Errorf("VerifyE() with CACert error = %v", err)
+	}
+	if _, err := VerifyE(t, Check{URL: srv.URL, CACert: []byte("not PEM")}); err == nil {
+		t.Errorf("VerifyE() with an invalid CACert error = nil, want an error")
+	}
+}
@@ -0,0 +1,121 @@
+// Copyright 2026 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package httpcheck
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// Matcher returns why a response does not pass, or nil.
+type Matcher func(*Response) error
+
+// BodyContains passes responses whose body contains s.
+func BodyContains(s string) Matcher {
+	return func(r *Response) error {
+		if !bytes.Contains(r.Body, []byte(s)) {
+			return fmt.Errorf("body does not contain %q: %s", s, excerpt(r.Body))
+		}
+		return nil
+	}
+}
+
+// BodyMatches passes responses whose body matches the regular expression
+// pattern. It panics if pattern does not compile.
+func BodyMatches(pattern string) Matcher {
+	re := regexp.MustCompile(pattern)
+	return func(r *Response) error {
+		if !re.Match(r.Body) {
+			return fmt.Errorf("body does not match %q: %s", pattern, excerpt(r.Body))
+		}
+		return nil
+	}
+}
+
+// JSONPath passes responses whose body is a JSON document with want at path.
+// A path is a list of object keys and array indexes separated by dots, such
+// as "status.conditions.0.type". want is compared with the value printed as
+// by fmt: a string as is, a number or boolean in its JSON form, and null as
+// "<nil>".
+func JSONPath(path, want string) Matcher {
+	return func(r *Response) error {
+		got, err := lookup(r.Body, path)
+		if err != nil {
+			return err
+		}
+		if s := fmt.Sprint(got); s != want {
+			return fmt.Errorf("%s = %v, want = %v", path, s, want)
+		}
+		return nil
+	}
+}
+
+// Header passes responses whose header name has the value want.
+func Header(name, want string) Matcher {
+	return func(r *Response) error {
+		if got := r.Header.Values(name); !slices.Contains(got, want) {
+			return fmt.Errorf("header %s = %q, want = %q", name, got, want)
+		}
+		return nil
+	}
+}
+
+// HeaderMatches passes responses with a value of header name that matches the
+// regular expression pattern. It panics if pattern does not compile.
+func HeaderMatches(name, pattern string) Matcher {
+	re := regexp.MustCompile(pattern)
+	return func(r *Response) error {
+		for _, v := range r.Header.Values(name) {
+			if re.MatchString(v) {
+				return nil
+			}
+		}
+		return fmt.Errorf("header %s = %q, want a match of %q", name, r.Header.Values(name), pattern)
+	}
+}
+
+// lookup returns the value at path in the JSON document body.
+func lookup(body []byte, path string) (any, error) {
+	var v any
+	d := json.NewDecoder(bytes.NewReader(body))
+	d.UseNumber()
+	if err := d.Decode(&v); err != nil {
+		return nil, fmt.Errorf("body is not JSON: %v: %s", err, excerpt(body))
+	}
+	for _, key := range strings.Split(path, ".") {
+		switch node := v.(type) {
+		case map[string]any:
+			next, ok := node[key]
+			if !ok {
+				return nil, fmt.Errorf("%s: no key %q", path, key)
+			}
+			v = next
+		case []any:
+			i, err := strconv.Atoi(key)
+			if err != nil || i < 0 || i >= len(node) {
+				return nil, fmt.Errorf("%s: no index %q in an array of %d", path, key, len(node))
+			}
+			v = node[i]
+		default:
+			return nil, fmt.Errorf("%s: %q is not in an object or array", path, key)
+		}
+	}
+	return v, nil
+}
@@ -0,0 +1,273 @@
+// Copyright 2026 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package ipam hands out address ranges to tests so that tests running at the
+// same time, in one test binary or in several, never use overlapping ranges.
+// Ranges are carved from three pools that do not overlap each other: Subnets
+// for subnetworks, PSA for private services access allocations and BGP for
+// the link-local /29 ranges of Cloud Router interfaces.
+//
+//	subnet := ipam.Subnet(t, 24)      // such as 10.0.3.0/24
+//	psaRange := ipam.PSARange(t, 20)  // such as 172.16.16.0/20
+//	bgpRange := ipam.BGPRange(t)      // such as 169.254.64.8/29
+//	endpoint := ipam.Host(subnet, 30) // such as 10.0.3.30
+//
+// A range is leased to the test until it ends. The leases are kept in the
+// file named by $TEST_IPAM_LEASES, shared by the processes running on the
+// machine under an exclusive lock; the leases of a process that exited are
+// reclaimed. Runs on other machines are not coordinated.
+package ipam
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/netip"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sync"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/GoogleCloudPlatform/cloudnetworking-config-solutions/common_utils/filelock"
+)
+
+// LeaseFileEnv is the environment variable naming the lease file. It
+// defaults to cso-test-ipam.json in the temporary directory.
+const LeaseFileEnv = "TEST_IPAM_LEASES"
+
+// MaxLeaseAge is the age after which a lease is reclaimed even though its
+// process appears to be running, in case its process ID was reused.
+const MaxLeaseAge = 24 * time.Hour
+
+// Pool is a block of addresses that ranges are leased from.
+type Pool struct {
+	Name   string
+	Prefix netip.Prefix
+}
+
+// Pools of ranges.
+var (
+	Subnets = Pool{Name: "subnet", Prefix: netip.MustParsePrefix("10.0.0.0/9")}
+	PSA     = Pool{Name: "psa", Prefix: netip.MustParsePrefix("172.16.0.0/12")}
+	BGP     = Pool{Name: "bgp", Prefix: netip.MustParsePrefix("169.254.64.0/18")}
+)
+
+// Lease is a range leased to a test.
+type Lease struct {
+	Pool  string    `json:"pool"`
+	CIDR  string    `json:"cidr"`
+	Test  string    `json:"test"`
+	PID   int       `json:"pid"`
+	Since time.Time `json:"since"`
+}
+
+// leases is the content of the lease file.
+type leases struct {
+	Leases []Lease `json:"leases"`
+}
+
+// Subnet leases a subnetwork range of the given prefix length to t.
+func Subnet(t testing.TB, prefixLen int) string {
+	return mustLease(t, Subnets, prefixLen)
+}
+
+// PSARange leases a private services access range of the given prefix length
+// to t.
+func PSARange(t testing.TB, prefixLen int) string {
+	return mustLease(t, PSA, prefixLen)
+}
+
+// BGPRange leases a link-local /29 range for the BGP session of a Cloud
+// Router interface to t.
+func BGPRange(t testing.TB) string {
+	return mustLease(t, BGP, 29)
+}
+
+func mustLease(t testing.TB, pool Pool, prefixLen int) string {
+	t.Helper()
+	cidr, err := LeaseE(t, pool, prefixLen)
+	if err != nil {
+		t.Fatalf("Leasing a /%d %s range: %v", prefixLen, pool.Name, err)
+	}
+	return cidr
+}
+
+// LeaseE leases the first range of the given prefix length in pool that
+// overlaps no live lease, and releases it when t ends.
+func LeaseE(t testing.TB, pool Pool, prefixLen int) (string, error) {
+	if prefixLen < pool.Prefix.Bits() || prefixLen > 32 {
+		return "", fmt.Errorf("ipam: a /%d range does not fit in the %s pool %s", prefixLen, pool.Name, pool.Prefix)
+	}
+	var cidr string
+	err := update(func(l *leases) error {
+		live := l.Leases[:0]
+		for _, lease := range l.Leases {
+			if lease.live() {
+				live = append(live, lease)
+			}
+		}
+		l.Leases = live
+		prefix, ok := free(pool.Prefix, prefixLen, live)
+		if !ok {
+			return fmt.Errorf("ipam: no free /%d range left in the %s pool %s", prefixLen, pool.Name, pool.Prefix)
+		}
+		cidr = prefix.String()
+		l.Leases = append(l.Leases, Lease{Pool: pool.Name, CIDR: cidr, Test: t.Name(), PID: os.Getpid(), Since: time.Now()})
+		return nil
+	})
+	if err != nil {
+		return "", err
+	}
+	t.Logf("Leased %s range %s", pool.Name, cidr)
+	t.Cleanup(func() {
+		err := update(func(l *leases) error {
+			for i, lease := range l.Leases {
+				if lease.CIDR == cidr && lease.PID == os.Getpid() {
+					l.Leases = append(l.Leases[:i], l.Leases[i+1:]...)
+					break
+				}
+			}
+			return nil
+		})
+		if err != nil {
+			t.Logf("Releasing %s range %s: %v", pool.Name, cidr, err)
+		}
+	})
+	return cidr, nil
+}
+
+// Host returns the address at offset n in cidr, such as 10.0.3.30 for
+// 10.0.3.0/24 and 30. It gives the literal addresses of Private Service
+// Connect endpoints in a leased subnetwork.
+func Host(cidr string, n int) string {
+	prefix := netip.MustParsePrefix(cidr).Masked()
+	if n < 0 || n >= 1<<(32-prefix.Bits()) {
+		panic(fmt.Sprintf("ipam: offset %d is outside of %s", n, cidr))
+	}
+	return fromUint32(toUint32(prefix.Addr()) + uint32(n)).String()
+}
+
+// free returns the first range of length bits in pool that overlaps none of
+// leases.
+func free(pool netip.Prefix, bits int, leases []Lease) (netip.Prefix, bool) {
+	taken := make([]netip.Prefix, 0, len(leases))
+	for _, lease := range leases {
+		if p, err := netip.ParsePrefix(lease.CIDR); err == nil {
+			taken = append(taken, p)
+		}
+	}
+	base := toUint32(pool.Masked().Addr())
+	size := uint64(1) << (32 - bits)
+	for i := uint64(0); i < uint64(1)<<(bits-pool.Bits()); i++ {
+		candidate := netip.PrefixFrom(fromUint32(base+uint32(i*size)), bits)
+		overlaps := false
+		for _, p := range taken {
+			if p.Overlaps(candidate) {
+				overlaps = true
+				break
+			}
+		}
+		if !overlaps {
+			return candidate, true
+		}
+	}
+	return netip.Prefix{}, false
+}
+
+func toUint32(a netip.Addr) uint32 {
+	b := a.As4()
+	return binary.BigEndian.Uint32(b[:])
+}
+
+func fromUint32(v uint32) netip.Addr {
+	var b [4]byte
+	binary.BigEndian.PutUint32(b[:], v)
+	return netip.AddrFrom4(b)
+}
+
+// live reports whether the process holding the lease is still running.
+func (l Lease) live() bool {
+	if time.Since(l.Since) > MaxLeaseAge {
+		return false
+	}
+	p, err := os.FindProcess(l.PID)
+	if err != nil {
+		return false
+	}
+	defer p.Release()
+	// FindProcess opens the process on Windows, which fails once it exited,
+	// and Windows has no signal to probe it with.
+	if runtime.GOOS == "windows" {
+		return true
+	}
+	return p.Signal(syscall.Signal(0)) == nil
+}
+
+// mu serializes the updates of the goroutines of the process, which the file
+// lock alone would not do on every platform.
+var mu sync.Mutex
+
+// update runs fn on the leases stored in the lease file under an exclusive
+// lock and saves the result.
+func update(fn func(*leases) error) error {
+	mu.Lock()
+	defer mu.Unlock()
+	path := os.Getenv(LeaseFileEnv)
+	if path == "" {
+		path = filepath.Join(os.TempDir(), "cso-test-ipam.json")
+	}
+	// The lock is held on a separate file, as the lease file is replaced
+	// when it is saved. It is released when the process exits.
+	lock, err := os.OpenFile(path+".lock", os.O_CREATE|os.O_RDWR, 0o644)
+	if err != nil {
+		return err
+	}
+	defer lock.Close()
+	if err := filelock.Lock(lock); err != nil {
+		return fmt.Errorf("locking %s: %w", lock.Name(), err)
+	}
+	defer filelock.Unlock(lock)
+
+	l := &leases{}
+	b, err := os.ReadFile(path)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	if len(b) > 0 {
+		if err := json.Unmarshal(b, l); err != nil {
+			return fmt.Errorf("decoding %s: %w", path, err)
+		}
+	}
+	if err := fn(l); err != nil {
+		return err
+	}
+	b, err = json.MarshalIndent(l, "", "  ")
+	if err != nil {
+		return err
+	}
+	f, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*")
+	if err != nil {
+		return err
+	}
+	_, err = f.Write(b)
+	if err = errors.Join(err, f.Close()); err != nil {
+		os.Remove(f.Name())
+		return err
+	}
+	return os.Rename(f.Name(), path)
+}
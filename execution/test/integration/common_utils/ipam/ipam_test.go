@@ -0,0 +1,192 @@
+// Copyright 2026 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ipam
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/netip"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeT captures cleanups so that a test can end the leases of another.
+type fakeT struct {
+	*testing.T
+	name     string
+	cleanups []func()
+}
+
+func (f *fakeT) Name() string { return f.name }
+
+func (f *fakeT) Cleanup(fn func()) { f.cleanups = append(f.cleanups, fn) }
+
+func (f *fakeT) finish() {
+	for i := len(f.cleanups) - 1; i >= 0; i-- {
+		f.cleanups[i]()
+	}
+}
+
+func useLeaseFile(t *testing.T) string {
+	path := filepath.Join(t.TempDir(), "leases.json")
+	t.Setenv(LeaseFileEnv, path)
+	return path
+}
+
+func TestLeasesDoNotOverlap(t *testing.T) {
+	useLeaseFile(t)
+	ft := &fakeT{T: t, name: "TestNetworking"}
+	defer ft.finish()
+
+	got := []string{Subnet(ft, 24), Subnet(ft, 20), Subnet(ft, 24), PSARange(ft, 20), PSARange(ft, 16), BGPRange(ft), BGPRange(ft)}
+
+	want := []string{"10.0.0.0/24", "10.0.16.0/20", "10.0.1.0/24", "172.16.0.0/20", "172.17.0.0/16", "169.254.64.0/29", "169.254.64.8/29"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Leased ranges = %v, want = %v", got, want)
+	}
+}
+
+func TestLeasesAreReleasedWhenTheTestEnds(t *testing.T) {
+	path := useLeaseFile(t)
+	first := &fakeT{T: t, name: "TestFirst"}
+	second := &fakeT{T: t, name: "TestSecond"}
+	defer second.finish()
+
+	a := Subnet(first, 24)
+	first.finish()
+	b := Subnet(second, 24)
+
+	if a != b {
+		t.Errorf("Range after release = %v, want = %v", b, a)
+	}
+	if got := readLeases(t, path); len(got) != 1 || got[0].Test != "TestSecond" {
+		t.Errorf("Leases = %+v, want = the lease of TestSecond", got)
+	}
+}
+
+func TestLeasesOfExitedProcessesAreReclaimed(t *testing.T) {
+	path := useLeaseFile(t)
+	cmd := exec.Command("true")
+	if err := cmd.Run(); err != nil {
+		t.Fatalf("Running true: %v", err)
+	}
+	exited := cmd.Process.Pid
+	writeLeases(t, path, []Lease{
+		{Pool: "subnet", CIDR: "10.0.0.0/24", Test: "TestCrashed", PID: exited, Since: time.Now()},
+		{Pool: "subnet", CIDR: "10.0.1.0/24", Test: "TestOld", PID: os.Getpid(), Since: time.Now().Add(-MaxLeaseAge - time.Hour)},
+		{Pool: "subnet", CIDR: "10.0.2.0/24", Test: "TestRunning", PID: os.Getppid(), Since: time.Now()},
+	})
+	ft := &fakeT{T: t, name: "TestNew"}
+	defer ft.finish()
+
+	got := []string{Subnet(ft, 24), Subnet(ft, 24), Subnet(ft, 24)}
+
+	if want := []string{"10.0.0.0/24", "10.0.1.0/24", "10.0.3.0/24"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Leased ranges = %v, want = %v", got, want)
+	}
+}
+
+func TestConcurrentLeases(t *testing.T) {
+	useLeaseFile(t)
+	var (
+		wg   sync.WaitGroup
+		mu   sync.Mutex
+		seen = map[string]bool{}
+	)
+	var tests []*fakeT
+	for i := 0; i < 20; i++ {
+		ft := &fakeT{T: t, name: fmt.Sprintf("Test%d", i)}
+		tests = append(tests, ft)
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			cidr, err := LeaseE(ft, Subnets, 24)
+			mu.Lock()
+			defer mu.Unlock()
+			if err != nil || seen[cidr] {
+				t.Errorf("LeaseE() = %v, %v, want = a range not leased before", cidr, err)
+			}
+			seen[cidr] = true
+		}()
+	}
+	wg.Wait()
+	for _, ft := range tests {
+		ft.finish()
+	}
+}
+
+func TestLeaseEFailsWhenThePoolIsFull(t *testing.T) {
+	useLeaseFile(t)
+	ft := &fakeT{T: t, name: "TestFull"}
+	defer ft.finish()
+	small := Pool{Name: "small", Prefix: netip.MustParsePrefix("192.168.0.0/30")}
+
+	for _, bits := range []int{31, 31} {
+		if _, err := LeaseE(ft, small, bits); err != nil {
+			t.Fatalf("LeaseE(/%d) returned error: %v", bits, err)
+		}
+	}
+	if cidr, err := LeaseE(ft, small, 32); err == nil {
+		t.Errorf("LeaseE(/32) = %v, want = an error", cidr)
+	}
+	if cidr, err := LeaseE(ft, small, 29); err == nil {
+		t.Errorf("LeaseE(/29) = %v, want = an error", cidr)
+	}
+}
+
+func TestHost(t *testing.T) {
+	for _, tc := range []struct {
+		cidr string
+		n    int
+		want string
+	}{
+		{"10.0.3.0/24", 30, "10.0.3.30"},
+		{"10.0.16.0/20", 300, "10.0.17.44"},
+		{"169.254.64.8/29", 1, "169.254.64.9"},
+	} {
+		if got := Host(tc.cidr, tc.n); got != tc.want {
+			t.Errorf("Host(%s, %d) = %v, want = %v", tc.cidr, tc.n, got, tc.want)
+		}
+	}
+}
+
+func readLeases(t *testing.T, path string) []Lease {
+	t.Helper()
+	b, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("Reading the lease file: %v", err)
+	}
+	var l leases
+	if err := json.Unmarshal(b, &l); err != nil {
+		t.Fatalf("Parsing the lease file: %v", err)
+	}
+	return l.Leases
+}
+
+func writeLeases(t *testing.T, path string, l []Lease) {
+	t.Helper()
+	b, err := json.Marshal(leases{Leases: l})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, b, 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
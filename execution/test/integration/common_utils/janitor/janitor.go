@@ -0,0 +1,406 @@
+// Copyright 2026 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package janitor finds the resources that integration test runs left behind
+// in a project and deletes them. A resource is an orphan when its name matches
+// one of the patterns the suites use, or it is attached to a network that
+// does, and it is older than a TTL. With an organization, the hierarchical
+// firewall policies of the tests, found by their short name prefix, are
+// deleted too, after their associations. Orphans are deleted in dependency order so
+// that nothing is still in use when it is deleted.
+package janitor
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/GoogleCloudPlatform/cloudnetworking-config-solutions/common_utils/gcloud"
+	"github.com/GoogleCloudPlatform/cloudnetworking-config-solutions/common_utils/naming"
+	"github.com/gruntwork-io/terratest/modules/testing"
+)
+
+// DefaultPatterns match the names the integration suites give the resources
+// they create outside of, or around, their terraform configurations, including
+// every name derived through the naming package.
+var DefaultPatterns = []string{
+	naming.Pattern,
+	`^vpc-.+-test(-.+)?$`,
+	`^test-(second-)?(vpc|subnet)(-[a-z]+)?-\d+(-.+)?$`,
+	`^gke-cluster-(vpc|subnetwork)-\d+$`,
+	`^testing-(net|subnet)-workbench-\d+$`,
+	`^(.+-)?(nlb|ilb-test)-\d+(-.+)?$`,
+	`^mig-fw-allow-health-check-\d+$`,
+	`^testpsarange-ncc\d-\d+$`,
+	`^(vpc(-protected|-inspection)?|fwp)-(sp|fe)-test-[a-z0-9]+(-.+)?$`,
+	`^(lb|gce|mig|mrc|gke|cloudsql)-\d{6,}(-.+)?$`,
+}
+
+// DefaultPolicyPrefixes start the short names of the hierarchical firewall
+// policies the suites create, current and former.
+var DefaultPolicyPrefixes = []string{naming.OrgPrefix, "fwp-sp-test-"}
+
+// Kinds of resources the janitor deletes, in the order it deletes them.
+const (
+	KindFirewallPolicyAssociation = "firewall policy association"
+	KindFirewallPolicy            = "firewall policy"
+	KindForwardingRule            = "forwarding rule"
+	KindBackendService            = "backend service"
+	KindInstanceGroupManager      = "managed instance group"
+	KindInstance                  = "instance"
+	KindFirewall                  = "firewall rule"
+	KindVPCPeering                = "psa peering"
+	KindAddress                   = "address"
+	KindSubnetwork                = "subnetwork"
+	KindNetwork                   = "network"
+)
+
+var deletionOrder = []string{
+	KindFirewallPolicyAssociation,
+	KindFirewallPolicy,
+	KindForwardingRule,
+	KindBackendService,
+	KindInstanceGroupManager,
+	KindInstance,
+	KindFirewall,
+	KindVPCPeering,
+	KindAddress,
+	KindSubnetwork,
+	KindNetwork,
+}
+
+// Options control which resources are considered orphaned and what is done
+// with them.
+type Options struct {
+	// TTL is the minimum age of a resource before it is deleted. Runs that are
+	// still in progress own younger resources.
+	TTL time.Duration
+	// Patterns are regular expressions matched against resource names. They
+	// default to DefaultPatterns.
+	Patterns []string
+	// Organization is the ID of the organization whose hierarchical firewall
+	// policies are cleaned up as well. Without it only the project is.
+	Organization string
+	// PolicyPrefixes select the firewall policies of Organization by short
+	// name. They default to DefaultPolicyPrefixes.
+	PolicyPrefixes []string
+	// DryRun lists the orphans without deleting anything.
+	DryRun bool
+	// Now returns the current time. It defaults to time.Now.
+	Now func() time.Time
+	// Out receives one line per orphan found or deleted. It defaults to
+	// os.Stdout.
+	Out io.Writer
+}
+
+// Resource is an orphaned resource.
+type Resource struct {
+	Kind string
+	Name string
+	// Region is the region of regional resources and empty for global ones.
+	Region string
+	// Zone is the zone of zonal resources.
+	Zone string
+	// Organization is the organization of firewall policies and their
+	// associations, and Policy the policy of an association.
+	Organization string
+	Policy       string
+	Created      time.Time
+}
+
+func (r Resource) String() string {
+	location := "global"
+	switch {
+	case r.Zone != "":
+		location = r.Zone
+	case r.Region != "":
+		location = r.Region
+	}
+	switch r.Kind {
+	case KindVPCPeering:
+		return fmt.Sprintf("%s of network %s", r.Kind, r.Name)
+	case KindFirewallPolicyAssociation:
+		return fmt.Sprintf("%s %s of policy %s (organizations/%s)", r.Kind, r.Name, r.Policy, r.Organization)
+	case KindFirewallPolicy:
+		location = "organizations/" + r.Organization
+	}
+	return fmt.Sprintf("%s %s (%s, created %s)", r.Kind, r.Name, location, r.Created.Format(time.RFC3339))
+}
+
+// Run finds the orphans in the project of c and, unless opts.DryRun is set,
+// deletes them in dependency order. A failed delete does not stop the run;
+// every failure is returned joined into a single error. The returned
+// resources are the orphans found, in deletion order.
+func Run(t testing.TestingT, c *gcloud.Client, opts Options) ([]Resource, error) {
+	out := opts.Out
+	if out == nil {
+		out = os.Stdout
+	}
+	orphans, err := Find(t, c, opts)
+	if err != nil {
+		return nil, err
+	}
+	var errs []error
+	for _, r := range orphans {
+		if opts.DryRun {
+			fmt.Fprintf(out, "Would delete %s\n", r)
+			continue
+		}
+		if err := remove(t, c, r); err != nil && !gcloud.IsNotFound(err) {
+			fmt.Fprintf(out, "Failed to delete %s: %v\n", r, err)
+			errs = append(errs, fmt.Errorf("deleting %s %s: %w", r.Kind, r.Name, err))
+			continue
+		}
+		fmt.Fprintf(out, "Deleted %s\n", r)
+	}
+	return orphans, errors.Join(errs...)
+}
+
+// Find lists the orphans in the project of c in deletion order.
+func Find(t testing.TestingT, c *gcloud.Client, opts Options) ([]Resource, error) {
+	f, err := newFinder(opts)
+	if err != nil {
+		return nil, err
+	}
+
+	networks, err := c.Networks.List(t, "")
+	if err != nil {
+		return nil, fmt.Errorf("listing networks: %w", err)
+	}
+	for _, n := range networks {
+		if f.matches(n.Name) && f.add(KindNetwork, n.Name, "", "", n.CreationTimestamp) {
+			f.networks[n.Name] = true
+			for _, p := range n.Peerings {
+				if path.Base(p.Network) == "servicenetworking" {
+					f.orphans = append(f.orphans, Resource{Kind: KindVPCPeering, Name: n.Name})
+					break
+				}
+			}
+		}
+	}
+
+	subnets, err := c.Subnets.List(t, "")
+	if err != nil {
+		return nil, fmt.Errorf("listing subnetworks: %w", err)
+	}
+	for _, s := range subnets {
+		if f.selected(s.Name, s.Network) {
+			f.add(KindSubnetwork, s.Name, base(s.Region), "", s.CreationTimestamp)
+		}
+	}
+
+	addresses, err := c.Addresses.List(t, "")
+	if err != nil {
+		return nil, fmt.Errorf("listing addresses: %w", err)
+	}
+	for _, a := range addresses {
+		if f.selected(a.Name, a.Network) {
+			f.add(KindAddress, a.Name, base(a.Region), "", a.CreationTimestamp)
+		}
+	}
+
+	firewalls, err := c.Firewalls.List(t, "")
+	if err != nil {
+		return nil, fmt.Errorf("listing firewall rules: %w", err)
+	}
+	for _, fw := range firewalls {
+		if f.selected(fw.Name, fw.Network) {
+			f.add(KindFirewall, fw.Name, "", "", fw.CreationTimestamp)
+		}
+	}
+
+	instances, err := c.Instances.List(t, "")
+	if err != nil {
+		return nil, fmt.Errorf("listing instances: %w", err)
+	}
+	for _, i := range instances {
+		network := ""
+		if len(i.NetworkInterfaces) > 0 {
+			network = i.NetworkInterfaces[0].Network
+		}
+		if f.selected(i.Name, network) {
+			f.add(KindInstance, i.Name, "", base(i.Zone), i.CreationTimestamp)
+		}
+	}
+
+	migs, err := c.InstanceGroupManagers.List(t, "")
+	if err != nil {
+		return nil, fmt.Errorf("listing managed instance groups: %w", err)
+	}
+	for _, m := range migs {
+		if f.matches(m.Name) {
+			f.add(KindInstanceGroupManager, m.Name, base(m.Region), base(m.Zone), m.CreationTimestamp)
+		}
+	}
+
+	rules, err := c.ForwardingRules.List(t, "")
+	if err != nil {
+		return nil, fmt.Errorf("listing forwarding rules: %w", err)
+	}
+	backends := map[string]bool{}
+	for _, r := range rules {
+		if f.selected(r.Name, r.Network) && f.add(KindForwardingRule, r.Name, base(r.Region), "", r.CreationTimestamp) {
+			if r.BackendService != "" {
+				backends[base(r.BackendService)] = true
+			}
+		}
+	}
+
+	services, err := c.BackendServices.List(t, "")
+	if err != nil {
+		return nil, fmt.Errorf("listing backend services: %w", err)
+	}
+	for _, s := range services {
+		if f.matches(s.Name) || backends[s.Name] {
+			f.add(KindBackendService, s.Name, base(s.Region), "", s.CreationTimestamp)
+		}
+	}
+
+	if opts.Organization != "" {
+		prefixes := opts.PolicyPrefixes
+		if len(prefixes) == 0 {
+			prefixes = DefaultPolicyPrefixes
+		}
+		policies, err := c.FirewallPolicies.List(t, opts.Organization)
+		if err != nil {
+			return nil, fmt.Errorf("listing firewall policies of organization %s: %w", opts.Organization, err)
+		}
+		for _, p := range policies {
+			if !hasPrefix(p.ShortName, prefixes) || !f.add(KindFirewallPolicy, p.ShortName, "", "", p.CreationTimestamp) {
+				continue
+			}
+			policy := &f.orphans[len(f.orphans)-1]
+			policy.Organization = opts.Organization
+			for _, a := range p.Associations {
+				f.orphans = append(f.orphans, Resource{Kind: KindFirewallPolicyAssociation, Name: a.Name, Organization: opts.Organization, Policy: p.ShortName, Created: policy.Created})
+			}
+		}
+	}
+
+	rank := make(map[string]int, len(deletionOrder))
+	for i, kind := range deletionOrder {
+		rank[kind] = i
+	}
+	sort.SliceStable(f.orphans, func(i, j int) bool {
+		return rank[f.orphans[i].Kind] < rank[f.orphans[j].Kind]
+	})
+	return f.orphans, nil
+}
+
+// finder accumulates the orphans while the resource lists are walked.
+type finder struct {
+	patterns []*regexp.Regexp
+	cutoff   time.Time
+	networks map[string]bool
+	orphans  []Resource
+}
+
+func newFinder(opts Options) (*finder, error) {
+	patterns := opts.Patterns
+	if len(patterns) == 0 {
+		patterns = DefaultPatterns
+	}
+	now := time.Now
+	if opts.Now != nil {
+		now = opts.Now
+	}
+	f := &finder{cutoff: now().Add(-opts.TTL), networks: map[string]bool{}}
+	for _, p := range patterns {
+		re, err := regexp.Compile(p)
+		if err != nil {
+			return nil, fmt.Errorf("invalid pattern %q: %w", p, err)
+		}
+		f.patterns = append(f.patterns, re)
+	}
+	return f, nil
+}
+
+func (f *finder) matches(name string) bool {
+	for _, re := range f.patterns {
+		if re.MatchString(name) {
+			return true
+		}
+	}
+	return false
+}
+
+// selected reports whether a resource is a candidate by name or because it is
+// attached to a network that is being deleted.
+func (f *finder) selected(name, network string) bool {
+	return f.matches(name) || (network != "" && f.networks[base(network)])
+}
+
+// add records the resource if it is older than the TTL. A resource whose
+// creation timestamp cannot be parsed is left alone, since its age is unknown.
+func (f *finder) add(kind, name, region, zone, created string) bool {
+	ts, err := time.Parse(time.RFC3339, created)
+	if err != nil || !ts.Before(f.cutoff) {
+		return false
+	}
+	f.orphans = append(f.orphans, Resource{Kind: kind, Name: name, Region: region, Zone: zone, Created: ts})
+	return true
+}
+
+func hasPrefix(name string, prefixes []string) bool {
+	for _, p := range prefixes {
+		if strings.HasPrefix(name, p) {
+			return true
+		}
+	}
+	return false
+}
+
+// remove deletes a single orphan.
+func remove(t testing.TestingT, c *gcloud.Client, r Resource) error {
+	switch r.Kind {
+	case KindFirewallPolicyAssociation:
+		return c.FirewallPolicies.DeleteAssociation(t, r.Organization, r.Policy, r.Name)
+	case KindFirewallPolicy:
+		return c.FirewallPolicies.Delete(t, r.Organization, r.Name)
+	case KindForwardingRule:
+		return c.ForwardingRules.Delete(t, r.Region, r.Name)
+	case KindBackendService:
+		return c.BackendServices.Delete(t, r.Region, r.Name)
+	case KindInstanceGroupManager:
+		return c.InstanceGroupManagers.Delete(t, r.Region, r.Zone, r.Name)
+	case KindInstance:
+		return c.Instances.Delete(t, r.Zone, r.Name)
+	case KindFirewall:
+		return c.Firewalls.Delete(t, r.Name)
+	case KindVPCPeering:
+		return c.VPCPeerings.Delete(t, r.Name)
+	case KindAddress:
+		return c.Addresses.Delete(t, r.Region, r.Name)
+	case KindSubnetwork:
+		return c.Subnets.Delete(t, r.Region, r.Name)
+	case KindNetwork:
+		return c.Networks.Delete(t, r.Name)
+	}
+	return fmt.Errorf("unknown resource kind %q", r.Kind)
+}
+
+// base returns the last segment of a resource URL such as the region of a
+// regional resource.
+func base(url string) string {
+	if url == "" {
+		return ""
+	}
+	return path.Base(strings.TrimSuffix(url, "/"))
+}
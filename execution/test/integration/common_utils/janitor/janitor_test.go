@@ -0,0 +1,226 @@
+// Copyright 2026 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package janitor
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/GoogleCloudPlatform/cloudnetworking-config-solutions/common_utils/gcloud"
+	"github.com/gruntwork-io/terratest/modules/shell"
+	terratesting "github.com/gruntwork-io/terratest/modules/testing"
+)
+
+const computeURL = "https://www.googleapis.com/compute/v1/projects/p/"
+
+// listings are recorded "gcloud ... list --format=json" outputs keyed by the
+// command group. The test project holds orphans of a load balancer and a PSA
+// run, a fresh network of a run still in progress and unrelated resources.
+var listings = map[string]string{
+	"compute networks": `[
+		{"name":"vpc-psa-test","creationTimestamp":"2026-10-15T08:00:00.000-07:00","peerings":[{"name":"servicenetworking-googleapis-com","network":"https://www.googleapis.com/compute/v1/projects/tp/global/networks/servicenetworking"}]},
+		{"name":"lb-1760000000-vpc","creationTimestamp":"2026-10-15T08:00:00.000-07:00"},
+		{"name":"vpc-new-test","creationTimestamp":"2026-10-16T11:30:00.000-07:00"},
+		{"name":"default","creationTimestamp":"2020-01-01T00:00:00.000-07:00"}]`,
+	"compute networks subnets": `[
+		{"name":"subnet-a","region":"` + computeURL + `regions/us-central1","network":"` + computeURL + `global/networks/lb-1760000000-vpc","creationTimestamp":"2026-10-15T08:00:00.000-07:00"},
+		{"name":"default","region":"` + computeURL + `regions/us-central1","network":"` + computeURL + `global/networks/default","creationTimestamp":"2020-01-01T00:00:00.000-07:00"}]`,
+	"compute addresses": `[
+		{"name":"testpsarange-ncc1-1760000000","network":"` + computeURL + `global/networks/vpc-psa-test","purpose":"VPC_PEERING","creationTimestamp":"2026-10-15T08:00:00.000-07:00"}]`,
+	"compute firewall-rules": `[
+		{"name":"allow-ssh","network":"` + computeURL + `global/networks/lb-1760000000-vpc","creationTimestamp":"2026-10-15T08:00:00.000-07:00"},
+		{"name":"default-allow-ssh","network":"` + computeURL + `global/networks/default","creationTimestamp":"2020-01-01T00:00:00.000-07:00"}]`,
+	"compute instances": `[
+		{"name":"mig-abcd","zone":"` + computeURL + `zones/us-central1-a","networkInterfaces":[{"network":"` + computeURL + `global/networks/lb-1760000000-vpc"}],"creationTimestamp":"2026-10-15T08:00:00.000-07:00"}]`,
+	"compute instance-groups managed": `[
+		{"name":"lb-1760000000-mig","zone":"` + computeURL + `zones/us-central1-a","creationTimestamp":"2026-10-15T08:00:00.000-07:00"}]`,
+	"compute forwarding-rules": `[
+		{"name":"fr-1","region":"` + computeURL + `regions/us-central1","network":"` + computeURL + `global/networks/lb-1760000000-vpc","backendService":"` + computeURL + `regions/us-central1/backendServices/bs-1","creationTimestamp":"2026-10-15T08:00:00.000-07:00"}]`,
+	"compute backend-services": `[
+		{"name":"bs-1","region":"` + computeURL + `regions/us-central1","creationTimestamp":"2026-10-15T08:00:00.000-07:00"},
+		{"name":"other-bs","creationTimestamp":"2026-10-15T08:00:00.000-07:00"}]`,
+	"compute firewall-policies": `[
+		{"name":"1001","shortName":"itest-sp-r1760000000-0a1b2c","creationTimestamp":"2026-10-15T08:00:00.000-07:00","associations":[{"name":"assoc","attachmentTarget":"organizations/42"}]},
+		{"name":"1002","shortName":"fwp-sp-test-abc123","creationTimestamp":"2026-10-15T08:00:00.000-07:00"},
+		{"name":"1003","shortName":"itest-fe-r1760090000-3d4e5f","creationTimestamp":"2026-10-16T19:00:00.000-07:00"},
+		{"name":"1004","shortName":"corp-baseline","creationTimestamp":"2020-01-01T00:00:00.000-07:00","associations":[{"name":"org","attachmentTarget":"organizations/42"}]}]`,
+}
+
+// fakeGcloud replies to list commands with the recorded listings and records
+// every other command.
+type fakeGcloud struct {
+	calls []string
+	fail  map[string]error
+}
+
+func (f *fakeGcloud) Run(_ terratesting.TestingT, cmd shell.Command) (string, string, error) {
+	var words []string
+	for _, arg := range cmd.Args {
+		if !strings.HasPrefix(arg, "--project") && !strings.HasPrefix(arg, "--format") && arg != "--quiet" {
+			words = append(words, arg)
+		}
+	}
+	for i, word := range words {
+		if word == "list" {
+			return listings[strings.Join(words[:i], " ")], "", nil
+		}
+	}
+	call := strings.Join(words, " ")
+	f.calls = append(f.calls, call)
+	return "", "", f.fail[call]
+}
+
+func newClient(runner gcloud.Runner) *gcloud.Client {
+	c := gcloud.New("p")
+	c.Runner = runner
+	return c
+}
+
+func now() time.Time {
+	return time.Date(2026, 10, 16, 20, 0, 0, 0, time.UTC)
+}
+
+func TestRunDeletesInDependencyOrder(t *testing.T) {
+	runner := &fakeGcloud{}
+	var out bytes.Buffer
+	orphans, err := Run(t, newClient(runner), Options{TTL: 6 * time.Hour, Now: now, Out: &out})
+	if err != nil {
+		t.Fatalf("Run() returned error: %v", err)
+	}
+	want := []string{
+		"compute forwarding-rules delete fr-1 --region=us-central1",
+		"compute backend-services delete bs-1 --region=us-central1",
+		"compute instance-groups managed delete lb-1760000000-mig --zone=us-central1-a",
+		"compute instances delete mig-abcd --zone=us-central1-a",
+		"compute firewall-rules delete allow-ssh",
+		"services vpc-peerings delete --service=servicenetworking.googleapis.com --network=vpc-psa-test",
+		"compute addresses delete testpsarange-ncc1-1760000000 --global",
+		"compute networks subnets delete subnet-a --region=us-central1",
+		"compute networks delete vpc-psa-test",
+		"compute networks delete lb-1760000000-vpc",
+	}
+	if got := strings.Join(runner.calls, "\n"); got != strings.Join(want, "\n") {
+		t.Errorf("gcloud calls =\n%v\nwant =\n%v", got, strings.Join(want, "\n"))
+	}
+	if got, want := len(orphans), len(runner.calls); got != want {
+		t.Errorf("Orphans count = %v, want = %v", got, want)
+	}
+	if got, want := strings.Count(out.String(), "Deleted "), len(want); got != want {
+		t.Errorf("Deleted lines = %v, want = %v\n%s", got, want, out.String())
+	}
+}
+
+func TestRunDryRunDeletesNothing(t *testing.T) {
+	runner := &fakeGcloud{}
+	var out bytes.Buffer
+	orphans, err := Run(t, newClient(runner), Options{TTL: 6 * time.Hour, DryRun: true, Now: now, Out: &out})
+	if err != nil {
+		t.Fatalf("Run() returned error: %v", err)
+	}
+	if len(runner.calls) != 0 {
+		t.Errorf("gcloud calls = %v, want = none", runner.calls)
+	}
+	if got, want := strings.Count(out.String(), "Would delete "), len(orphans); got != want || got == 0 {
+		t.Errorf("Would delete lines = %v, want = %v", got, want)
+	}
+}
+
+func TestFindRespectsTTL(t *testing.T) {
+	// vpc-new-test is an hour and a half old at now.
+	for _, tc := range []struct {
+		ttl  time.Duration
+		want bool
+	}{
+		{ttl: time.Hour, want: true},
+		{ttl: 2 * time.Hour, want: false},
+	} {
+		orphans, err := Find(t, newClient(&fakeGcloud{}), Options{TTL: tc.ttl, Now: now})
+		if err != nil {
+			t.Fatalf("Find() returned error: %v", err)
+		}
+		found := false
+		for _, r := range orphans {
+			found = found || r.Name == "vpc-new-test"
+		}
+		if found != tc.want {
+			t.Errorf("Find(TTL=%s) found vpc-new-test = %v, want = %v", tc.ttl, found, tc.want)
+		}
+	}
+}
+
+func TestFindSkipsUnmatchedResources(t *testing.T) {
+	orphans, err := Find(t, newClient(&fakeGcloud{}), Options{TTL: time.Hour, Now: now})
+	if err != nil {
+		t.Fatalf("Find() returned error: %v", err)
+	}
+	for _, r := range orphans {
+		if strings.HasPrefix(r.Name, "default") || r.Name == "other-bs" {
+			t.Errorf("Find() selected %s, want it left alone", r)
+		}
+	}
+}
+
+func TestFindCustomPatterns(t *testing.T) {
+	orphans, err := Find(t, newClient(&fakeGcloud{}), Options{TTL: time.Hour, Now: now, Patterns: []string{`^other-bs$`}})
+	if err != nil {
+		t.Fatalf("Find() returned error: %v", err)
+	}
+	if got, want := len(orphans), 1; got != want {
+		t.Fatalf("Orphans count = %v, want = %v: %v", got, want, orphans)
+	}
+	if got, want := orphans[0].String(), "backend service other-bs (global, created 2026-10-15T08:00:00-07:00)"; got != want {
+		t.Errorf("Orphan = %v, want = %v", got, want)
+	}
+}
+
+func TestRunContinuesAfterFailure(t *testing.T) {
+	runner := &fakeGcloud{fail: map[string]error{
+		"compute firewall-rules delete allow-ssh": errors.New("exit status 1"),
+	}}
+	_, err := Run(t, newClient(runner), Options{TTL: 6 * time.Hour, Now: now, Out: &bytes.Buffer{}})
+	if err == nil || !strings.Contains(err.Error(), "firewall rule allow-ssh") {
+		t.Errorf("Run() error = %v, want it to name firewall rule allow-ssh", err)
+	}
+	if got, want := runner.calls[len(runner.calls)-1], "compute networks delete lb-1760000000-vpc"; got != want {
+		t.Errorf("Last gcloud call = %v, want = %v", got, want)
+	}
+}
+
+func TestRunDeletesOrganizationPolicies(t *testing.T) {
+	runner := &fakeGcloud{}
+	orphans, err := Run(t, newClient(runner), Options{TTL: 6 * time.Hour, Now: now, Organization: "42", Out: &bytes.Buffer{}})
+	if err != nil {
+		t.Fatalf("Run() returned error: %v", err)
+	}
+	want := []string{
+		"compute firewall-policies associations delete assoc --firewall-policy=itest-sp-r1760000000-0a1b2c --organization=42",
+		"compute firewall-policies delete itest-sp-r1760000000-0a1b2c --organization=42",
+		"compute firewall-policies delete fwp-sp-test-abc123 --organization=42",
+	}
+	if got := strings.Join(runner.calls[:len(want)], "\n"); got != strings.Join(want, "\n") {
+		t.Errorf("First gcloud calls =\n%v\nwant =\n%v", got, strings.Join(want, "\n"))
+	}
+	if got, want := orphans[0].String(), "firewall policy association assoc of policy itest-sp-r1760000000-0a1b2c (organizations/42)"; got != want {
+		t.Errorf("Orphan = %v, want = %v", got, want)
+	}
+	for _, call := range runner.calls[len(want):] {
+		if strings.Contains(call, "firewall-policies") {
+			t.Errorf("gcloud call %q, want fresh and foreign policies left alone", call)
+		}
+	}
+}
@@ -0,0 +1,146 @@
+// Copyright 2026 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package location moves a scenario to another zone or region when the one
+// it targets is out of capacity. Suites that pin a zone fail on
+// ZONE_RESOURCE_POOL_EXHAUSTED and on machine types the zone does not offer;
+// Try runs the scenario in the default location of the test profile and, on
+// those errors only, again in each fallback location in turn. The scenario
+// regenerates its YAML configuration for the location it is given, and the
+// location that finally succeeded is logged and recorded in the report of the
+// test.
+package location
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/GoogleCloudPlatform/cloudnetworking-config-solutions/common_utils/profile"
+	"github.com/GoogleCloudPlatform/cloudnetworking-config-solutions/common_utils/report"
+)
+
+// Location is a region and, for zonal scenarios, a zone in it.
+type Location struct {
+	Region string
+	Zone   string
+}
+
+func (l Location) String() string {
+	if l.Zone != "" {
+		return l.Zone
+	}
+	return l.Region
+}
+
+// Zones returns the default zone of p followed by its fallback zones in the
+// same region.
+func Zones(p *profile.Profile) []Location {
+	region := RegionOf(p.Zones.Default)
+	candidates := []Location{{Region: region, Zone: p.Zones.Default}}
+	for _, zone := range p.Zones.Fallback {
+		if RegionOf(zone) == region && zone != p.Zones.Default {
+			candidates = append(candidates, Location{Region: region, Zone: zone})
+		}
+	}
+	return candidates
+}
+
+// Regions returns the default region of p followed by its fallback regions.
+// The zone of a candidate is the first zone of the profile in its region, or
+// empty.
+func Regions(p *profile.Profile) []Location {
+	zones := append([]string{p.Zones.Default}, p.Zones.Fallback...)
+	var candidates []Location
+	for _, region := range append([]string{p.Regions.Default}, p.Regions.Fallback...) {
+		if region == "" || contains(candidates, region) {
+			continue
+		}
+		loc := Location{Region: region}
+		for _, zone := range zones {
+			if RegionOf(zone) == region {
+				loc.Zone = zone
+				break
+			}
+		}
+		candidates = append(candidates, loc)
+	}
+	return candidates
+}
+
+func contains(candidates []Location, region string) bool {
+	for _, c := range candidates {
+		if c.Region == region {
+			return true
+		}
+	}
+	return false
+}
+
+// RegionOf returns the region of zone, such as us-central1 for us-central1-a.
+func RegionOf(zone string) string {
+	if i := strings.LastIndex(zone, "-"); i > 0 {
+		return zone[:i]
+	}
+	return zone
+}
+
+// capacityErrors match the errors of terraform, gcloud and the APIs behind
+// them when a location is out of capacity or does not offer what was asked.
+var capacityErrors = regexp.MustCompile(`(?i)` + strings.Join([]string{
+	`ZONE_RESOURCE_POOL_EXHAUSTED`,
+	`RESOURCE_POOL_EXHAUSTED`,
+	`GCE_STOCKOUT`,
+	`stockout`,
+	`does not have enough resources available to fulfill the request`,
+	`machine type .* (does not exist|is not (available|supported)) in (zone|region)`,
+	`invalid value for field 'resource\.machineType'`,
+	`(is not|isn't) (available|supported|offered) in (the )?(zone|region|location)`,
+	`no available (zones|capacity)`,
+}, "|"))
+
+// IsCapacityError reports whether err is a stockout or names something the
+// location does not offer, such as a machine type, so that the scenario may
+// succeed elsewhere. Quota errors are not: they follow the project.
+func IsCapacityError(err error) bool {
+	return err != nil && capacityErrors.MatchString(err.Error())
+}
+
+// Try runs scenario at each of the candidates in turn until it does not fail
+// with a capacity error, and returns the location it ran at and its error.
+// scenario regenerates the configuration for the location it is given; what
+// it left behind at a previous location is replaced by the next terraform
+// apply, or should be removed by scenario before it returns a capacity error.
+func Try(t testing.TB, candidates []Location, scenario func(Location) error) (Location, error) {
+	if len(candidates) == 0 {
+		return Location{}, fmt.Errorf("location: no candidate location")
+	}
+	var tried []string
+	var err error
+	for _, loc := range candidates {
+		t.Logf("Running the scenario in %s", loc)
+		err = scenario(loc)
+		if !IsCapacityError(err) {
+			report.Set(t, "location", loc.String())
+			if len(tried) > 0 {
+				report.Set(t, "location.skipped", strings.Join(tried, ","))
+			}
+			return loc, err
+		}
+		t.Logf("%s is out of capacity: %v", loc, err)
+		tried = append(tried, loc.String())
+	}
+	return candidates[len(candidates)-1], fmt.Errorf("every candidate location is out of capacity (%s): %w", strings.Join(tried, ", "), err)
+}
@@ -0,0 +1,111 @@
+// Copyright 2026 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package location
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/GoogleCloudPlatform/cloudnetworking-config-solutions/common_utils/profile"
+	"github.com/GoogleCloudPlatform/cloudnetworking-config-solutions/common_utils/report"
+)
+
+func TestCandidates(t *testing.T) {
+	p := profile.Default()
+	p.Zones.Fallback = []string{"us-central1-b", "us-east1-b", "us-central1-a", "us-central1-c"}
+	p.Regions.Fallback = []string{"us-east1", "us-central1", "europe-west1"}
+
+	want := []Location{
+		{Region: "us-central1", Zone: "us-central1-a"},
+		{Region: "us-central1", Zone: "us-central1-b"},
+		{Region: "us-central1", Zone: "us-central1-c"},
+	}
+	if got := Zones(p); !reflect.DeepEqual(got, want) {
+		t.Errorf("Zones() = %v, want = %v", got, want)
+	}
+	want = []Location{
+		{Region: "us-central1", Zone: "us-central1-a"},
+		{Region: "us-east1", Zone: "us-east1-b"},
+		{Region: "europe-west1"},
+	}
+	if got := Regions(p); !reflect.DeepEqual(got, want) {
+		t.Errorf("Regions() = %v, want = %v", got, want)
+	}
+}
+
+func TestIsCapacityError(t *testing.T) {
+	for _, tc := range []struct {
+		msg  string
+		want bool
+	}{
+		{"Error: Error waiting for instance to create: The zone 'projects/p/zones/us-central1-a' does not have enough resources available to fulfill the request. '(resource type:compute)'.", true},
+		{"googleapi: Error 503: ZONE_RESOURCE_POOL_EXHAUSTED_WITH_DETAILS", true},
+		{"Error 400: Invalid value for field 'resource.machineType': 'zones/us-west2-a/machineTypes/c3-standard-4'. Machine type with name 'c3-standard-4' does not exist in zone 'us-west2-a'., invalid", true},
+		{"node pool creation failed: GCE_STOCKOUT", true},
+		{"Error 403: Quota 'CPUS' exceeded. Limit: 24.0 in region us-central1.", false},
+		{"Error 409: The resource 'projects/p/global/networks/vpc' already exists", false},
+	} {
+		if got := IsCapacityError(errors.New(tc.msg)); got != tc.want {
+			t.Errorf("IsCapacityError(%q) = %v, want = %v", tc.msg, got, tc.want)
+		}
+	}
+	if IsCapacityError(nil) {
+		t.Errorf("IsCapacityError(nil) = true, want = false")
+	}
+}
+
+func TestTryFallsBackOnCapacityErrors(t *testing.T) {
+	candidates := Zones(profile.Default())
+	var ran []string
+	loc, err := Try(t, candidates, func(loc Location) error {
+		ran = append(ran, loc.Zone)
+		if loc.Zone == "us-central1-a" {
+			return errors.New("ZONE_RESOURCE_POOL_EXHAUSTED")
+		}
+		return nil
+	})
+	if err != nil || loc.Zone != "us-central1-b" {
+		t.Errorf("Try() = %v, %v, want = us-central1-b, nil", loc, err)
+	}
+	if want := []string{"us-central1-a", "us-central1-b"}; !reflect.DeepEqual(ran, want) {
+		t.Errorf("Locations run = %v, want = %v", ran, want)
+	}
+	if got := report.For(t).Properties["location"]; got != "us-central1-b" {
+		t.Errorf("Reported location = %v, want = %v", got, "us-central1-b")
+	}
+}
+
+func TestTryStopsOnOtherErrors(t *testing.T) {
+	calls := 0
+	_, err := Try(t, Zones(profile.Default()), func(Location) error {
+		calls++
+		return errors.New("Error 409: already exists")
+	})
+	if err == nil || calls != 1 {
+		t.Errorf("Try() = %v after %d call(s), want = the error after 1", err, calls)
+	}
+}
+
+func TestTryReportsExhaustedCandidates(t *testing.T) {
+	candidates := []Location{{Region: "us-central1", Zone: "us-central1-a"}, {Region: "us-central1", Zone: "us-central1-b"}}
+	_, err := Try(t, candidates, func(Location) error {
+		return errors.New("GCE_STOCKOUT")
+	})
+	if err == nil || !strings.Contains(err.Error(), "us-central1-a, us-central1-b") || !IsCapacityError(err) {
+		t.Errorf("Try() error = %v, want = a capacity error naming both zones", err)
+	}
+}
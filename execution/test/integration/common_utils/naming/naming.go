@@ -0,0 +1,175 @@
+// Copyright 2026 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package naming derives the names and labels of the resources a test
+// creates. Names are built from a prefix, the run ID and a short hash of the
+// run ID, the test name and the prefix, so they are reproducible within a run,
+// distinct across tests and runs, and always within the length and character
+// rules of the resource type. Labels link a resource back to the run and the
+// test that created it and record when it may be cleaned up.
+package naming
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gruntwork-io/terratest/modules/testing"
+)
+
+// RunIDEnv is the environment variable that sets the run ID, for example to
+// the build number of a CI job. Without it a run ID is derived from the time
+// the run started.
+const RunIDEnv = "TEST_RUN_ID"
+
+// Label keys set by Labels.
+const (
+	LabelRunID   = "test-run-id"
+	LabelTest    = "test-name"
+	LabelExpires = "test-expires"
+)
+
+// Pattern matches every name returned by Name. The janitor uses it to find
+// resources left behind by interrupted runs.
+const Pattern = `^[a-z][-a-z0-9]*-r[a-z0-9]{1,10}-[0-9a-f]{6}$`
+
+// OrgPrefix starts the short names of the organization level resources of
+// the tests, such as hierarchical firewall policies, which are shared by every
+// project of the organization. The janitor finds stale ones by this prefix.
+const OrgPrefix = "itest-"
+
+// TTL is how long the resources of a run are expected to live. It sets the
+// expiry label and matches the default TTL of the janitor.
+var TTL = 6 * time.Hour
+
+// Kind holds the naming rules of a resource type. Every kind accepts
+// lowercase letters, digits and hyphens, starting with a letter and ending
+// with a letter or digit.
+type Kind struct {
+	// Name describes the resource type in errors.
+	Name string
+	// MinLength and MaxLength bound the length of a name.
+	MinLength int
+	MaxLength int
+}
+
+var (
+	// Compute covers networks, subnetworks, firewall rules, routers,
+	// addresses, instances, instance templates, instance groups and load
+	// balancer components, which all follow RFC 1035.
+	Compute = Kind{Name: "compute resource", MinLength: 1, MaxLength: 63}
+	// ServiceAccount is the account ID, the part of the email before the @.
+	ServiceAccount = Kind{Name: "service account", MinLength: 6, MaxLength: 30}
+	// SQLInstance leaves room for the longest project ID in the 98 characters
+	// Cloud SQL allows for "project:instance".
+	SQLInstance = Kind{Name: "Cloud SQL instance", MinLength: 1, MaxLength: 67}
+	// AlloyDBCluster is the ID of an AlloyDB cluster or instance.
+	AlloyDBCluster = Kind{Name: "AlloyDB cluster", MinLength: 1, MaxLength: 63}
+	// GKECluster is the name of a GKE cluster.
+	GKECluster = Kind{Name: "GKE cluster", MinLength: 1, MaxLength: 40}
+	// FirewallPolicy is the short name of a hierarchical firewall policy.
+	FirewallPolicy = Kind{Name: "firewall policy", MinLength: 1, MaxLength: 63}
+	// Bucket is a Cloud Storage bucket name without dots.
+	Bucket = Kind{Name: "bucket", MinLength: 3, MaxLength: 63}
+)
+
+var (
+	runOnce    sync.Once
+	runID      string
+	runStarted time.Time
+
+	invalid      = regexp.MustCompile(`[^a-z0-9]+`)
+	invalidLabel = regexp.MustCompile(`[^a-z0-9_-]+`)
+	valid        = regexp.MustCompile(`^[a-z]([-a-z0-9]*[a-z0-9])?$`)
+)
+
+// RunID returns the ID of the current run. It is "r" followed by up to ten
+// lowercase letters or digits taken from $TEST_RUN_ID, or by the start time of
+// the run in base 36.
+func RunID() string {
+	runOnce.Do(func() {
+		runStarted = time.Now()
+		id := invalid.ReplaceAllString(strings.ToLower(os.Getenv(RunIDEnv)), "")
+		if id == "" {
+			id = strconv.FormatInt(runStarted.Unix(), 36)
+		}
+		if len(id) > 10 {
+			id = id[len(id)-10:]
+		}
+		runID = "r" + id
+	})
+	return runID
+}
+
+// Expiry returns the time after which the resources of the run may be deleted.
+func Expiry() time.Time {
+	RunID()
+	return runStarted.Add(TTL)
+}
+
+// Name returns the name of the resource that the test t refers to by prefix,
+// such as "vpc-cloudsql". The same test and prefix give the same name for the
+// whole run. The prefix is lowercased, stripped of invalid characters and
+// shortened as needed to keep the name within the limits of kind.
+func Name(t testing.TestingT, kind Kind, prefix string) string {
+	sum := sha256.Sum256([]byte(RunID() + "\x00" + t.Name() + "\x00" + prefix))
+	suffix := "-" + RunID() + "-" + hex.EncodeToString(sum[:])[:6]
+
+	prefix = strings.Trim(invalid.ReplaceAllString(strings.ToLower(prefix), "-"), "-")
+	if prefix == "" || prefix[0] < 'a' || prefix[0] > 'z' {
+		prefix = "t" + prefix
+	}
+	if room := kind.MaxLength - len(suffix); len(prefix) > room {
+		prefix = strings.TrimRight(prefix[:room], "-")
+	}
+	name := prefix + suffix
+	if err := kind.Validate(name); err != nil {
+		t.Fatalf("naming: %v", err)
+	}
+	return name
+}
+
+// Validate reports whether name follows the rules of k.
+func (k Kind) Validate(name string) error {
+	if len(name) < k.MinLength || len(name) > k.MaxLength {
+		return fmt.Errorf("%s name %q is %d characters long, want %d to %d", k.Name, name, len(name), k.MinLength, k.MaxLength)
+	}
+	if !valid.MatchString(name) {
+		return fmt.Errorf("%s name %q must consist of lowercase letters, digits and hyphens, start with a letter and end with a letter or digit", k.Name, name)
+	}
+	return nil
+}
+
+// Labels returns the labels to attach to every resource of the test t that
+// supports them: the run ID, the test name and the expiry as a Unix time.
+// Label values are limited to 63 lowercase letters, digits, hyphens and
+// underscores, so subtest separators become underscores.
+func Labels(t testing.TestingT) map[string]string {
+	test := strings.ToLower(strings.ReplaceAll(t.Name(), "/", "_"))
+	test = invalidLabel.ReplaceAllString(test, "-")
+	if len(test) > 63 {
+		test = test[:63]
+	}
+	return map[string]string{
+		LabelRunID:   RunID(),
+		LabelTest:    test,
+		LabelExpires: strconv.FormatInt(Expiry().Unix(), 10),
+	}
+}
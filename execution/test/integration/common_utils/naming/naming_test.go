@@ -0,0 +1,138 @@
+// Copyright 2026 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package naming
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// resetRun makes the next RunID call read id from the environment.
+func resetRun(t *testing.T, id string) {
+	t.Setenv(RunIDEnv, id)
+	runOnce = sync.Once{}
+	t.Cleanup(func() { runOnce = sync.Once{} })
+}
+
+func TestRunIDFromEnvironment(t *testing.T) {
+	resetRun(t, "Build #1234")
+	if got, want := RunID(), "rbuild1234"; got != want {
+		t.Errorf("RunID() = %v, want = %v", got, want)
+	}
+	resetRun(t, "projects-p-builds-0123456789abcdef")
+	if got, want := RunID(), "r6789abcdef"; got != want {
+		t.Errorf("RunID() = %v, want = %v", got, want)
+	}
+}
+
+func TestRunIDGenerated(t *testing.T) {
+	resetRun(t, "")
+	id := RunID()
+	if !regexp.MustCompile(`^r[a-z0-9]{1,10}$`).MatchString(id) {
+		t.Errorf("RunID() = %v, want r followed by up to ten letters or digits", id)
+	}
+	if got := RunID(); got != id {
+		t.Errorf("Second RunID() = %v, want = %v", got, id)
+	}
+}
+
+func TestNameIsDeterministic(t *testing.T) {
+	resetRun(t, "1234")
+	first := Name(t, Compute, "vpc-cloudsql")
+	if got := Name(t, Compute, "vpc-cloudsql"); got != first {
+		t.Errorf("Name() = %v, want = %v on the second call", got, first)
+	}
+	if !strings.HasPrefix(first, "vpc-cloudsql-r1234-") {
+		t.Errorf("Name() = %v, want prefix vpc-cloudsql-r1234-", first)
+	}
+	if got := Name(t, Compute, "vpc-alloydb"); strings.TrimPrefix(got, "vpc-alloydb") == strings.TrimPrefix(first, "vpc-cloudsql") {
+		t.Errorf("Name() = %v for another prefix, want a different hash than %v", got, first)
+	}
+	t.Run("subtest", func(t *testing.T) {
+		if got := Name(t, Compute, "vpc-cloudsql"); got == first {
+			t.Errorf("Name() = %v in a subtest, want it to differ from the parent test", got)
+		}
+	})
+	resetRun(t, "5678")
+	if got := Name(t, Compute, "vpc-cloudsql"); got == first {
+		t.Errorf("Name() = %v in another run, want it to differ", got)
+	}
+}
+
+func TestNameLengthAndCharacters(t *testing.T) {
+	resetRun(t, "1234567890")
+	pattern := regexp.MustCompile(Pattern)
+	for _, tc := range []struct {
+		kind   Kind
+		prefix string
+		want   string
+	}{
+		{kind: Compute, prefix: "VPC_CloudSQL Test", want: "vpc-cloudsql-test-"},
+		{kind: Compute, prefix: "42-subnet", want: "t42-subnet-"},
+		{kind: Compute, prefix: "", want: "t-"},
+		{kind: Compute, prefix: strings.Repeat("network-", 10), want: "network-network-network-network-network-netw-r1234567890-"},
+		{kind: ServiceAccount, prefix: "sa-firewall-endpoint", want: "sa-firewall-r1234567890-"},
+		{kind: GKECluster, prefix: "gke-cluster-producer-test", want: "gke-cluster-producer-r1234567890-"},
+	} {
+		name := Name(t, tc.kind, tc.prefix)
+		if err := tc.kind.Validate(name); err != nil {
+			t.Errorf("Name(%q) = %v, invalid: %v", tc.prefix, name, err)
+		}
+		if !strings.HasPrefix(name, tc.want) {
+			t.Errorf("Name(%q) = %v, want prefix %v", tc.prefix, name, tc.want)
+		}
+		if !pattern.MatchString(name) {
+			t.Errorf("Name(%q) = %v, want it to match %v", tc.prefix, name, Pattern)
+		}
+	}
+}
+
+func TestValidate(t *testing.T) {
+	for _, tc := range []struct {
+		kind Kind
+		name string
+		ok   bool
+	}{
+		{kind: Compute, name: "vpc-1", ok: true},
+		{kind: Compute, name: "1-vpc", ok: false},
+		{kind: Compute, name: "vpc-", ok: false},
+		{kind: Compute, name: "Vpc", ok: false},
+		{kind: Compute, name: strings.Repeat("a", 64), ok: false},
+		{kind: ServiceAccount, name: "sa-1", ok: false},
+	} {
+		if err := tc.kind.Validate(tc.name); (err == nil) != tc.ok {
+			t.Errorf("%s.Validate(%q) = %v, want ok = %v", tc.kind.Name, tc.name, err, tc.ok)
+		}
+	}
+}
+
+func TestLabels(t *testing.T) {
+	resetRun(t, "1234")
+	t.Run("Sub Test/Case", func(t *testing.T) {
+		labels := Labels(t)
+		if got, want := labels[LabelRunID], "r1234"; got != want {
+			t.Errorf("Labels()[%s] = %v, want = %v", LabelRunID, got, want)
+		}
+		if got, want := labels[LabelTest], "testlabels_sub_test_case"; got != want {
+			t.Errorf("Labels()[%s] = %v, want = %v", LabelTest, got, want)
+		}
+		if got, want := labels[LabelExpires], strconv.FormatInt(Expiry().Unix(), 10); got != want {
+			t.Errorf("Labels()[%s] = %v, want = %v", LabelExpires, got, want)
+		}
+	})
+}
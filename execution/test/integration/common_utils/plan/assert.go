@@ -0,0 +1,350 @@
+// Copyright 2026 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package plan
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/gruntwork-io/terratest/modules/terraform"
+	"github.com/gruntwork-io/terratest/modules/testing"
+)
+
+// Plan asserts on the resource changes of a plan, or on the resources of a
+// state returned by State. Failed assertions are reported with t.Errorf, so a
+// test reports every mismatch in one run.
+type Plan struct {
+	t         testing.TestingT
+	h         interface{ Helper() }
+	resources []*resource
+	// state is set when the resources are those of a state.
+	state bool
+}
+
+// resource is a resource change of a plan or a resource of a state.
+type resource struct {
+	address string
+	// actions are the planned actions, nil in a state.
+	actions []string
+	// values are the planned values, or the values in the state.
+	values interface{}
+	// afterUnknown marks the planned values only known after apply, nil in
+	// a state.
+	afterUnknown interface{}
+}
+
+// For returns the assertions on a plan parsed by terraform.ParsePlanJSON or
+// terraform.InitAndPlanAndShowWithStruct.
+func For(t testing.TestingT, p *terraform.PlanStruct) *Plan {
+	resources := make([]*resource, 0, len(p.ResourceChangesMap))
+	for _, rc := range p.ResourceChangesMap {
+		if rc.Change == nil {
+			continue
+		}
+		actions := make([]string, len(rc.Change.Actions))
+		for i, a := range rc.Change.Actions {
+			actions[i] = string(a)
+		}
+		resources = append(resources, &resource{address: rc.Address, actions: actions, values: rc.Change.After, afterUnknown: rc.Change.AfterUnknown})
+	}
+	return newPlan(t, resources, false)
+}
+
+func newPlan(t testing.TestingT, resources []*resource, state bool) *Plan {
+	sort.Slice(resources, func(i, j int) bool { return resources[i].address < resources[j].address })
+	h, ok := t.(interface{ Helper() })
+	if !ok {
+		h = noHelper{}
+	}
+	return &Plan{t: t, h: h, resources: resources, state: state}
+}
+
+// Resource selects the resources whose address matches glob. A * in
+// glob matches any run of characters, including dots and brackets; every
+// other character, such as [ or ", matches itself. It reports an error when
+// no address matches.
+func (p *Plan) Resource(glob string) *Resources {
+	p.h.Helper()
+	r := &Resources{p: p, glob: glob}
+	for _, res := range p.resources {
+		if matchGlob(glob, res.address) {
+			r.resources = append(r.resources, res)
+		}
+	}
+	if len(r.resources) == 0 {
+		addrs := make([]string, len(p.resources))
+		for i, res := range p.resources {
+			addrs[i] = res.address
+		}
+		if p.state {
+			p.t.Errorf("No resource in the state matches %s, resources in the state:\n  %s", glob, strings.Join(addrs, "\n  "))
+		} else {
+			p.t.Errorf("No planned resource matches %s, planned resources:\n  %s", glob, strings.Join(addrs, "\n  "))
+		}
+	}
+	return r
+}
+
+// Resources are the resources selected by Plan.Resource.
+type Resources struct {
+	p         *Plan
+	glob      string
+	resources []*resource
+}
+
+// Count checks that n resources are selected.
+func (r *Resources) Count(n int) *Resources {
+	r.p.h.Helper()
+	if got := len(r.resources); got != n {
+		r.p.t.Errorf("Resources matching %s = %d, want = %d", r.glob, got, n)
+	}
+	return r
+}
+
+// Actions checks the actions planned for every selected resource, such as
+// Actions("create") or Actions("delete", "create"). A state has no actions.
+func (r *Resources) Actions(want ...string) *Resources {
+	r.p.h.Helper()
+	if r.p.state {
+		r.p.t.Errorf("Actions(%v) of %s: a state has no planned actions", want, r.glob)
+		return r
+	}
+	for _, res := range r.resources {
+		if !reflect.DeepEqual(res.actions, want) {
+			r.p.t.Errorf("%s: actions = %v, want = %v", res.address, res.actions, want)
+		}
+	}
+	return r
+}
+
+// Attr selects the attribute at a dot separated path of the planned values, or
+// of the values in the state, where numeric parts index into lists and nested
+// blocks, for example "settings.0.ip_configuration.0.ipv4_enabled".
+func (r *Resources) Attr(path string) *Attr {
+	return &Attr{r: r, path: path}
+}
+
+// Attr is an attribute of the selected resources. Its checks return the
+// resources so that several attributes can be checked in one chain.
+type Attr struct {
+	r    *Resources
+	path string
+}
+
+// Equals checks that the attribute is known and equal to want on every
+// selected resource. want is compared by its JSON encoding, so numbers of
+// any Go type, slices and maps compare with the decoded plan values.
+func (a *Attr) Equals(want interface{}) *Resources {
+	a.r.p.h.Helper()
+	w, err := normalizeJSON(want)
+	if err != nil {
+		a.r.p.t.Errorf("Attr(%s).Equals(%v): %v", a.path, want, err)
+		return a.r
+	}
+	for _, v := range a.known() {
+		if !reflect.DeepEqual(v.value, w) {
+			a.r.p.t.Errorf("%s: %s = %s, want = %s", v.address, a.path, format(v.value), format(w))
+		}
+	}
+	return a.r
+}
+
+// Contains checks that the attribute is a known list holding want, or a known
+// string containing want, on every selected resource.
+func (a *Attr) Contains(want interface{}) *Resources {
+	a.r.p.h.Helper()
+	w, err := normalizeJSON(want)
+	if err != nil {
+		a.r.p.t.Errorf("Attr(%s).Contains(%v): %v", a.path, want, err)
+		return a.r
+	}
+	for _, v := range a.known() {
+		found := false
+		switch g := v.value.(type) {
+		case []interface{}:
+			for _, e := range g {
+				found = found || reflect.DeepEqual(e, w)
+			}
+		case string:
+			s, ok := w.(string)
+			found = ok && strings.Contains(g, s)
+		}
+		if !found {
+			a.r.p.t.Errorf("%s: %s = %s, want it to contain %s", v.address, a.path, format(v.value), format(w))
+		}
+	}
+	return a.r
+}
+
+// Matches checks that the attribute is a known string matching the regular
+// expression expr on every selected resource.
+func (a *Attr) Matches(expr string) *Resources {
+	a.r.p.h.Helper()
+	re, err := regexp.Compile(expr)
+	if err != nil {
+		a.r.p.t.Errorf("Attr(%s).Matches(%q): %v", a.path, expr, err)
+		return a.r
+	}
+	for _, v := range a.known() {
+		if s, ok := v.value.(string); !ok || !re.MatchString(s) {
+			a.r.p.t.Errorf("%s: %s = %s, want a string matching %q", v.address, a.path, format(v.value), expr)
+		}
+	}
+	return a.r
+}
+
+// Unknown checks that the attribute is only known after apply on every
+// selected resource. Every value of a state is known.
+func (a *Attr) Unknown() *Resources {
+	a.r.p.h.Helper()
+	for _, res := range a.r.resources {
+		if !unknown(res.afterUnknown, a.path) {
+			v, _ := Lookup(res.values, a.path)
+			a.r.p.t.Errorf("%s: %s = %s, want = %s", res.address, a.path, format(v), Unknown)
+		}
+	}
+	return a.r
+}
+
+// Null checks that the attribute is known to be unset on every selected
+// resource.
+func (a *Attr) Null() *Resources {
+	a.r.p.h.Helper()
+	for _, res := range a.r.resources {
+		if unknown(res.afterUnknown, a.path) {
+			a.r.p.t.Errorf("%s: %s = %s, want = null", res.address, a.path, Unknown)
+		} else if v, _ := Lookup(res.values, a.path); v != nil {
+			a.r.p.t.Errorf("%s: %s = %s, want = null", res.address, a.path, format(v))
+		}
+	}
+	return a.r
+}
+
+// Value returns the attribute of the one selected resource, decoded from JSON
+// as by encoding/json, so that a test can compare it with an output or pass it
+// on. It reports an error and returns nil unless exactly one resource is
+// selected and the attribute is known.
+func (a *Attr) Value() interface{} {
+	a.r.p.h.Helper()
+	if n := len(a.r.resources); n != 1 {
+		if n > 1 {
+			a.r.p.t.Errorf("Attr(%s).Value(): %d resources match %s, want = 1", a.path, n, a.r.glob)
+		}
+		return nil
+	}
+	values := a.known()
+	if len(values) == 0 {
+		return nil
+	}
+	return values[0].value
+}
+
+// value is the known value of an attribute on one resource.
+type value struct {
+	address string
+	value   interface{}
+}
+
+// known returns the value of the attribute on every selected resource where
+// it is known, and reports the others.
+func (a *Attr) known() []value {
+	a.r.p.h.Helper()
+	var values []value
+	for _, res := range a.r.resources {
+		if unknown(res.afterUnknown, a.path) {
+			a.r.p.t.Errorf("%s: %s is %s, want a known value", res.address, a.path, Unknown)
+			continue
+		}
+		got, ok := Lookup(res.values, a.path)
+		if !ok {
+			a.r.p.t.Errorf("%s: %s is not set", res.address, a.path)
+			continue
+		}
+		values = append(values, value{address: res.address, value: got})
+	}
+	return values
+}
+
+// unknown reports whether the value at path, or a value that contains it, is
+// marked as only known after apply in the after_unknown tree of a change.
+func unknown(afterUnknown interface{}, path string) bool {
+	if afterUnknown == true {
+		return true
+	}
+	v := afterUnknown
+	for _, key := range strings.Split(path, ".") {
+		next, ok := Lookup(v, key)
+		if !ok {
+			return false
+		}
+		if next == true {
+			return true
+		}
+		v = next
+	}
+	return false
+}
+
+// matchGlob reports whether s matches pattern, where * matches any run of
+// characters and every other character matches itself.
+func matchGlob(pattern, s string) bool {
+	parts := strings.Split(pattern, "*")
+	if len(parts) == 1 {
+		return pattern == s
+	}
+	if !strings.HasPrefix(s, parts[0]) {
+		return false
+	}
+	s = s[len(parts[0]):]
+	for _, part := range parts[1 : len(parts)-1] {
+		i := strings.Index(s, part)
+		if i < 0 {
+			return false
+		}
+		s = s[i+len(part):]
+	}
+	return strings.HasSuffix(s, parts[len(parts)-1])
+}
+
+// normalizeJSON converts v to the types encoding/json decodes into.
+func normalizeJSON(v interface{}) (interface{}, error) {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	var out interface{}
+	if err := json.Unmarshal(b, &out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// format returns the JSON encoding of a decoded value for failure messages.
+func format(v interface{}) string {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Sprint(v)
+	}
+	return string(b)
+}
+
+// noHelper stands in for testing.T.Helper when t does not have it.
+type noHelper struct{}
+
+func (noHelper) Helper() {}
@@ -0,0 +1,76 @@
+// Copyright 2026 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package golden compares plan snapshots against golden files in the testdata
+// folder of the test package. It registers the -update flag, so that only the
+// unit tests that import it accept the flag.
+package golden
+
+import (
+	"errors"
+	"flag"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/GoogleCloudPlatform/cloudnetworking-config-solutions/common_utils/plan"
+	"github.com/gruntwork-io/terratest/modules/testing"
+)
+
+// update is set by go test -update. Packages that import golden must not
+// declare a flag of the same name.
+var update = flag.Bool("update", false, "Rewrite the golden files of plan snapshots instead of comparing against them.")
+
+// header starts every golden file.
+const header = "# Plan snapshot. Regenerate with: go test -update\n"
+
+// Path returns the golden file of a snapshot, in the testdata folder of the
+// test package.
+func Path(name string) string {
+	return filepath.Join("testdata", name+".golden")
+}
+
+// Match compares a snapshot against the golden file of name and fails the test
+// with a diff of the added, removed and changed addresses when they differ.
+// With go test -update it writes the snapshot to the file instead.
+func Match(t testing.TestingT, name string, got plan.Snapshot) {
+	match(t, Path(name), got, *update)
+}
+
+func match(t testing.TestingT, path string, got plan.Snapshot, update bool) {
+	if update {
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatalf("Creating the folder of %s: %v", path, err)
+		}
+		if err := os.WriteFile(path, []byte(header+got.String()), 0644); err != nil {
+			t.Fatalf("Writing %s: %v", path, err)
+		}
+		return
+	}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		t.Fatalf("Golden file %s does not exist, run go test -update to create it", path)
+	}
+	if err != nil {
+		t.Fatalf("Reading %s: %v", path, err)
+	}
+	want, err := plan.Parse(string(data))
+	if err != nil {
+		t.Fatalf("Parsing %s: %v", path, err)
+	}
+	if diff := plan.Diff(want, got); len(diff) > 0 {
+		t.Errorf("Plan does not match %s (run go test -update to accept it):\n%s", path, strings.Join(diff, "\n"))
+	}
+}
@@ -0,0 +1,64 @@
+// Copyright 2026 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package golden
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/GoogleCloudPlatform/cloudnetworking-config-solutions/common_utils/plan"
+)
+
+type fakeT struct {
+	*testing.T
+	errors []string
+}
+
+func (f *fakeT) Errorf(format string, args ...any) {
+	f.errors = append(f.errors, fmt.Sprintf(format, args...))
+}
+
+func (f *fakeT) Fatalf(format string, args ...any) {
+	f.Errorf(format, args...)
+}
+
+func TestMatch(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "testdata", "plan.golden")
+	s := plan.Snapshot{
+		{Address: "google_compute_route.default[0]", Actions: "delete,create", Attributes: map[string]string{}},
+		{Address: "google_compute_subnetwork.subnetwork", Actions: "create", Attributes: map[string]string{"name": `"subnet-1"`}},
+	}
+
+	match(t, path, s, true)
+	ft := &fakeT{T: t}
+	match(ft, path, s, false)
+	if len(ft.errors) != 0 {
+		t.Errorf("match() of the written snapshot reported %q, want no errors", ft.errors)
+	}
+
+	changed := append(plan.Snapshot{{Address: "google_compute_network.network", Actions: "create", Attributes: map[string]string{}}}, s[1:]...)
+	ft = &fakeT{T: t}
+	match(ft, path, changed, false)
+	if len(ft.errors) != 1 {
+		t.Fatalf("match() of a changed snapshot reported %d errors, want 1", len(ft.errors))
+	}
+	for _, line := range []string{"- delete,create google_compute_route.default[0]", "+ create google_compute_network.network"} {
+		if !strings.Contains(ft.errors[0], line) {
+			t.Errorf("match() error = %q, want it to contain %q", ft.errors[0], line)
+		}
+	}
+}
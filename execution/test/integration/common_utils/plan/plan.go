@@ -0,0 +1,257 @@
+// Copyright 2026 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package plan checks Terraform plans in unit tests. Assertions select
+// resource changes by an address glob and check their planned attributes,
+// for example:
+//
+//	p := plan.For(t, planStruct)
+//	p.Resource(`module.cloudsql["dummy1"].google_sql_database_instance.*`).
+//		Attr("settings.0.ip_configuration.0.ipv4_enabled").Equals(false)
+//
+// State runs the same assertions on the resources of the state after apply,
+// so that integration tests can check attributes the module does not output.
+//
+// A snapshot keeps the address and actions of every resource change and the
+// attributes a test selects, in a stable text form that the golden package
+// compares against a golden file.
+package plan
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/gruntwork-io/terratest/modules/terraform"
+)
+
+// Unknown is recorded for a selected attribute whose value is only known
+// after apply.
+const Unknown = "(known after apply)"
+
+// Attributes selects the attributes recorded for each resource type. Paths
+// are dot separated and numeric parts index into lists, for example
+// "log_config.0.enable".
+type Attributes map[string][]string
+
+// Resource is the normalized change of one resource.
+type Resource struct {
+	Address string
+	// Actions are the Terraform actions joined by commas, such as "create"
+	// or "delete,create".
+	Actions string
+	// Attributes maps the selected paths that are set after the change to
+	// their JSON encoded value, or to Unknown.
+	Attributes map[string]string
+}
+
+// Snapshot is a normalized plan, sorted by address.
+type Snapshot []Resource
+
+// New returns the snapshot of a plan, recording attrs for the resources of
+// the selected types.
+func New(p *terraform.PlanStruct, attrs Attributes) Snapshot {
+	s := Snapshot{}
+	for _, rc := range p.RawPlan.ResourceChanges {
+		if rc.Change == nil {
+			continue
+		}
+		actions := make([]string, len(rc.Change.Actions))
+		for i, a := range rc.Change.Actions {
+			actions[i] = string(a)
+		}
+		r := Resource{Address: rc.Address, Actions: strings.Join(actions, ","), Attributes: map[string]string{}}
+		for _, path := range attrs[rc.Type] {
+			if v, ok := Lookup(rc.Change.After, path); ok && v != nil {
+				b, err := json.Marshal(v)
+				if err != nil {
+					b = []byte(strconv.Quote(fmt.Sprint(v)))
+				}
+				r.Attributes[path] = string(b)
+			} else if unknown(rc.Change.AfterUnknown, path) {
+				r.Attributes[path] = Unknown
+			}
+		}
+		s = append(s, r)
+	}
+	sort.Slice(s, func(i, j int) bool { return s[i].Address < s[j].Address })
+	return s
+}
+
+// Lookup returns the value at a dot separated path of a decoded JSON value.
+func Lookup(v interface{}, path string) (interface{}, bool) {
+	if path == "" {
+		return v, true
+	}
+	for _, key := range strings.Split(path, ".") {
+		switch c := v.(type) {
+		case map[string]interface{}:
+			e, ok := c[key]
+			if !ok {
+				return nil, false
+			}
+			v = e
+		case []interface{}:
+			i, err := strconv.Atoi(key)
+			if err != nil || i < 0 || i >= len(c) {
+				return nil, false
+			}
+			v = c[i]
+		default:
+			return nil, false
+		}
+	}
+	return v, true
+}
+
+// ResourceCount counts the resources a plan adds, changes and destroys, as
+// terraform.GetResourceCount reads them from the "Plan:" line of terraform
+// plan. A replaced resource counts as both added and destroyed.
+func ResourceCount(p *terraform.PlanStruct) *terraform.ResourceCount {
+	c := &terraform.ResourceCount{}
+	for _, rc := range p.RawPlan.ResourceChanges {
+		if rc.Change == nil {
+			continue
+		}
+		a := rc.Change.Actions
+		switch {
+		case a.Replace():
+			c.Add++
+			c.Destroy++
+		case a.Create():
+			c.Add++
+		case a.Update():
+			c.Change++
+		case a.Delete():
+			c.Destroy++
+		}
+	}
+	return c
+}
+
+// Addresses returns the addresses of the snapshot.
+func (s Snapshot) Addresses() []string {
+	addrs := make([]string, len(s))
+	for i, r := range s {
+		addrs[i] = r.Address
+	}
+	return addrs
+}
+
+// String formats the snapshot as written to golden files: one line with the
+// actions and the address of each resource, followed by one indented line per
+// selected attribute.
+func (s Snapshot) String() string {
+	var b strings.Builder
+	for _, r := range s {
+		fmt.Fprintf(&b, "%s %s\n", r.Actions, r.Address)
+		for _, path := range sortedKeys(r.Attributes) {
+			fmt.Fprintf(&b, "    %s = %s\n", path, r.Attributes[path])
+		}
+	}
+	return b.String()
+}
+
+// Parse reads a snapshot formatted by String. Blank lines and lines starting
+// with # are ignored.
+func Parse(text string) (Snapshot, error) {
+	s := Snapshot{}
+	sc := bufio.NewScanner(strings.NewReader(text))
+	sc.Buffer(nil, 1<<20)
+	for n := 1; sc.Scan(); n++ {
+		line := sc.Text()
+		switch {
+		case strings.TrimSpace(line) == "" || strings.HasPrefix(line, "#"):
+		case strings.HasPrefix(line, " "):
+			path, value, ok := strings.Cut(strings.TrimSpace(line), " = ")
+			if !ok || len(s) == 0 {
+				return nil, fmt.Errorf("line %d: unexpected attribute %q", n, line)
+			}
+			s[len(s)-1].Attributes[path] = value
+		default:
+			actions, address, ok := strings.Cut(line, " ")
+			if !ok || address == "" {
+				return nil, fmt.Errorf("line %d: want actions and address, got %q", n, line)
+			}
+			s = append(s, Resource{Address: address, Actions: actions, Attributes: map[string]string{}})
+		}
+	}
+	if err := sc.Err(); err != nil {
+		return nil, err
+	}
+	sort.Slice(s, func(i, j int) bool { return s[i].Address < s[j].Address })
+	return s, nil
+}
+
+// Diff returns one line per difference between two snapshots: "+" for an
+// address only in got, "-" for an address only in want and "~" for a
+// changed action or attribute. It returns nil when they are equal.
+func Diff(want, got Snapshot) []string {
+	wantByAddr := map[string]Resource{}
+	for _, r := range want {
+		wantByAddr[r.Address] = r
+	}
+	gotByAddr := map[string]Resource{}
+	for _, r := range got {
+		gotByAddr[r.Address] = r
+	}
+	var lines []string
+	for _, r := range want {
+		if _, ok := gotByAddr[r.Address]; !ok {
+			lines = append(lines, fmt.Sprintf("- %s %s", r.Actions, r.Address))
+		}
+	}
+	for _, g := range got {
+		w, ok := wantByAddr[g.Address]
+		if !ok {
+			lines = append(lines, fmt.Sprintf("+ %s %s", g.Actions, g.Address))
+			continue
+		}
+		if w.Actions != g.Actions {
+			lines = append(lines, fmt.Sprintf("~ %s: actions %s -> %s", g.Address, w.Actions, g.Actions))
+		}
+		paths := map[string]bool{}
+		for p := range w.Attributes {
+			paths[p] = true
+		}
+		for p := range g.Attributes {
+			paths[p] = true
+		}
+		for _, p := range sortedKeys(paths) {
+			wv, wok := w.Attributes[p]
+			gv, gok := g.Attributes[p]
+			switch {
+			case !wok:
+				lines = append(lines, fmt.Sprintf("~ %s: + %s = %s", g.Address, p, gv))
+			case !gok:
+				lines = append(lines, fmt.Sprintf("~ %s: - %s = %s", g.Address, p, wv))
+			case wv != gv:
+				lines = append(lines, fmt.Sprintf("~ %s: %s = %s -> %s", g.Address, p, wv, gv))
+			}
+		}
+	}
+	return lines
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,392 @@
+// Copyright 2026 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package plan
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/gruntwork-io/terratest/modules/terraform"
+	tfjson "github.com/hashicorp/terraform-json"
+)
+
+const planJSON = `{
+  "format_version": "1.2",
+  "resource_changes": [
+    {
+      "address": "module.vpc.google_compute_subnetwork.subnetwork[\"us-central1/subnet-1\"]",
+      "type": "google_compute_subnetwork",
+      "change": {
+        "actions": ["create"],
+        "after": {"name": "subnet-1", "ip_cidr_range": "10.0.0.0/24", "log_config": [{"enable": true}]},
+        "after_unknown": {"id": true, "gateway_address": true}
+      }
+    },
+    {
+      "address": "google_compute_route.default[0]",
+      "type": "google_compute_route",
+      "change": {
+        "actions": ["delete", "create"],
+        "after": {"name": "route", "priority": 1000}
+      }
+    }
+  ]
+}`
+
+type fakeT struct {
+	*testing.T
+	errors []string
+}
+
+func (f *fakeT) Errorf(format string, args ...any) {
+	f.errors = append(f.errors, fmt.Sprintf(format, args...))
+}
+
+func (f *fakeT) Fatalf(format string, args ...any) {
+	f.Errorf(format, args...)
+}
+
+func newSnapshot(t *testing.T) Snapshot {
+	p, err := terraform.ParsePlanJSON(planJSON)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return New(p, Attributes{
+		"google_compute_subnetwork": {"name", "ip_cidr_range", "log_config.0.enable", "gateway_address", "description"},
+	})
+}
+
+func TestNew(t *testing.T) {
+	got := newSnapshot(t)
+	want := Snapshot{
+		{Address: "google_compute_route.default[0]", Actions: "delete,create", Attributes: map[string]string{}},
+		{Address: `module.vpc.google_compute_subnetwork.subnetwork["us-central1/subnet-1"]`, Actions: "create", Attributes: map[string]string{
+			"name":                `"subnet-1"`,
+			"ip_cidr_range":       `"10.0.0.0/24"`,
+			"log_config.0.enable": "true",
+			"gateway_address":     Unknown,
+		}},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("New() = %+v, want = %+v", got, want)
+	}
+}
+
+func TestResourceCount(t *testing.T) {
+	p, err := terraform.ParsePlanJSON(planJSON)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := ResourceCount(p)
+	want := &terraform.ResourceCount{Add: 2, Change: 0, Destroy: 1}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("ResourceCount() = %+v, want = %+v", got, want)
+	}
+}
+
+func TestStringParse(t *testing.T) {
+	want := newSnapshot(t)
+	got, err := Parse("# Plan snapshot.\n" + want.String())
+	if err != nil {
+		t.Fatalf("Parse() returned error: %v", err)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Parse(String()) = %+v, want = %+v", got, want)
+	}
+	if _, err := Parse("    name = \"x\"\n"); err == nil {
+		t.Errorf("Parse() of an attribute without a resource returned no error, want one")
+	}
+}
+
+func TestDiff(t *testing.T) {
+	want := Snapshot{
+		{Address: "a", Actions: "create", Attributes: map[string]string{"name": `"a"`, "region": `"r"`}},
+		{Address: "b", Actions: "create", Attributes: map[string]string{}},
+	}
+	got := Snapshot{
+		{Address: "a", Actions: "update", Attributes: map[string]string{"name": `"a2"`, "zone": `"z"`}},
+		{Address: "c", Actions: "create", Attributes: map[string]string{}},
+	}
+	wantDiff := []string{
+		"- create b",
+		"~ a: actions create -> update",
+		`~ a: name = "a" -> "a2"`,
+		`~ a: - region = "r"`,
+		`~ a: + zone = "z"`,
+		"+ create c",
+	}
+	if diff := Diff(want, got); !reflect.DeepEqual(diff, wantDiff) {
+		t.Errorf("Diff() = %q, want = %q", diff, wantDiff)
+	}
+	if diff := Diff(want, want); diff != nil {
+		t.Errorf("Diff() of equal snapshots = %q, want = nil", diff)
+	}
+}
+
+func TestAssertions(t *testing.T) {
+	p, err := terraform.ParsePlanJSON(planJSON)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ft := &fakeT{T: t}
+	a := For(ft, p)
+	a.Resource(`module.vpc.google_compute_subnetwork.subnetwork["us-central1/*"]`).
+		Count(1).
+		Actions("create").
+		Attr("name").Equals("subnet-1").
+		Attr("log_config.0.enable").Equals(true).
+		Attr("log_config").Equals([]map[string]bool{{"enable": true}}).
+		Attr("ip_cidr_range").Matches(`^10\.0\.`).
+		Attr("ip_cidr_range").Contains("0/24").
+		Attr("gateway_address").Unknown().
+		Attr("description").Null()
+	a.Resource("google_compute_route.*").Actions("delete", "create").Attr("priority").Equals(1000)
+	a.Resource("*").Count(2)
+	if len(ft.errors) != 0 {
+		t.Errorf("Passing assertions reported %q, want no errors", ft.errors)
+	}
+
+	for _, tc := range []struct {
+		assert func(a *Plan)
+		want   string
+	}{
+		{
+			assert: func(a *Plan) { a.Resource("google_compute_network.*") },
+			want:   "No planned resource matches google_compute_network.*",
+		},
+		{
+			assert: func(a *Plan) { a.Resource("*subnetwork*").Attr("name").Equals("subnet-2") },
+			want:   `module.vpc.google_compute_subnetwork.subnetwork["us-central1/subnet-1"]: name = "subnet-1", want = "subnet-2"`,
+		},
+		{
+			assert: func(a *Plan) { a.Resource("*subnetwork*").Attr("gateway_address").Equals("10.0.0.1") },
+			want:   "gateway_address is (known after apply), want a known value",
+		},
+		{
+			assert: func(a *Plan) { a.Resource("*subnetwork*").Attr("gateway_address.0").Equals("10.0.0.1") },
+			want:   "gateway_address.0 is (known after apply), want a known value",
+		},
+		{
+			assert: func(a *Plan) { a.Resource("*subnetwork*").Attr("region").Equals("us-central1") },
+			want:   "region is not set",
+		},
+		{
+			assert: func(a *Plan) { a.Resource("*route*").Actions("create") },
+			want:   "actions = [delete create], want = [create]",
+		},
+		{
+			assert: func(a *Plan) { a.Resource("*").Count(3) },
+			want:   "Resources matching * = 2, want = 3",
+		},
+	} {
+		ft := &fakeT{T: t}
+		tc.assert(For(ft, p))
+		if len(ft.errors) != 1 || !strings.Contains(ft.errors[0], tc.want) {
+			t.Errorf("Failing assertion reported %q, want one error containing %q", ft.errors, tc.want)
+		}
+	}
+}
+
+func TestMatchGlob(t *testing.T) {
+	for _, tc := range []struct {
+		pattern, s string
+		want       bool
+	}{
+		{`module.cloudsql["dummy1"].*`, `module.cloudsql["dummy1"].google_sql_database_instance.primary`, true},
+		{`module.cloudsql["dummy1"].*`, `module.cloudsql["dummy2"].google_sql_database_instance.primary`, false},
+		{`*.google_compute_firewall.*`, `module.firewall.google_compute_firewall.rules["allow-ssh"]`, true},
+		{`google_compute_route.default[0]`, `google_compute_route.default[0]`, true},
+		{`a*a`, `a`, false},
+	} {
+		if got := matchGlob(tc.pattern, tc.s); got != tc.want {
+			t.Errorf("matchGlob(%q, %q) = %v, want = %v", tc.pattern, tc.s, got, tc.want)
+		}
+	}
+}
+
+// fakeTerraform writes a terraform stand-in that exits with planCode on plan
+// and prints planJSON on show, and returns options that run it.
+func fakeTerraform(t *testing.T, planCode int) *terraform.Options {
+	dir := t.TempDir()
+	script := fmt.Sprintf(`#!/bin/sh
+case "$1" in
+plan) exit %d ;;
+show) cat %q ;;
+esac
+`, planCode, filepath.Join(dir, "plan.json"))
+	if err := os.WriteFile(filepath.Join(dir, "plan.json"), []byte(planJSON), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "terraform"), []byte(script), 0755); err != nil {
+		t.Fatal(err)
+	}
+	return &terraform.Options{TerraformDir: dir, TerraformBinary: filepath.Join(dir, "terraform"), PlanFilePath: "plan", NoColor: true}
+}
+
+func TestStage(t *testing.T) {
+	s := NewStage(fakeTerraform(t, 2))
+	s.planOnce(t)
+	if got := s.ExitCode(t); got != 2 {
+		t.Errorf("ExitCode() = %d, want = 2", got)
+	}
+	if got := s.JSON(t); got != planJSON {
+		t.Errorf("JSON() = %q, want = %q", got, planJSON)
+	}
+	if got := len(s.Plan(t).ResourceChangesMap); got != 2 {
+		t.Errorf("len(Plan().ResourceChangesMap) = %d, want = 2", got)
+	}
+
+	s = NewStage(fakeTerraform(t, 1))
+	s.planOnce(t)
+	if got := s.ExitCode(t); got != 1 {
+		t.Errorf("ExitCode() of a failed plan = %d, want = 1", got)
+	}
+	ft := &fakeT{T: t}
+	s.Plan(ft)
+	if len(ft.errors) != 1 || !strings.Contains(ft.errors[0], "exited with code 1") {
+		t.Errorf("Plan() of a failed plan reported %q, want one error about the exit code", ft.errors)
+	}
+}
+
+const stateJSON = `{
+  "format_version": "1.0",
+  "values": {
+    "root_module": {
+      "resources": [
+        {
+          "address": "google_compute_network.vpc",
+          "mode": "managed",
+          "type": "google_compute_network",
+          "name": "vpc",
+          "values": {"name": "vpc", "id": "projects/p/global/networks/vpc"}
+        },
+        {
+          "address": "data.google_project.project",
+          "mode": "data",
+          "type": "google_project",
+          "name": "project",
+          "values": {"number": "123"}
+        }
+      ],
+      "child_modules": [
+        {
+          "address": "module.cloudsql[\"sql-1\"]",
+          "resources": [
+            {
+              "address": "module.cloudsql[\"sql-1\"].google_sql_database_instance.default",
+              "mode": "managed",
+              "type": "google_sql_database_instance",
+              "name": "default",
+              "values": {
+                "name": "sql-1",
+                "region": "us-central1",
+                "settings": [{"tier": "db-f1-micro", "disk_size": 10, "ip_configuration": [{"ipv4_enabled": false, "private_network": "projects/p/global/networks/vpc"}]}],
+                "encryption_key_name": null
+              }
+            }
+          ]
+        }
+      ]
+    }
+  }
+}`
+
+func TestStateAssertions(t *testing.T) {
+	state := &tfjson.State{}
+	if err := json.Unmarshal([]byte(stateJSON), state); err != nil {
+		t.Fatal(err)
+	}
+	ft := &fakeT{T: t}
+	s := ForState(ft, state)
+	s.Resource(`module.cloudsql["sql-1"].google_sql_database_instance.*`).
+		Count(1).
+		Attr("region").Equals("us-central1").
+		Attr("settings.0.disk_size").Equals(10).
+		Attr("settings.0.ip_configuration.0.ipv4_enabled").Equals(false).
+		Attr("settings.0.ip_configuration.0.private_network").Matches(`/networks/vpc$`).
+		Attr("encryption_key_name").Null()
+	s.Resource("*").Count(2)
+	if got := s.Resource("google_compute_network.vpc").Attr("id").Value(); got != "projects/p/global/networks/vpc" {
+		t.Errorf("Attr(id).Value() = %v, want = projects/p/global/networks/vpc", got)
+	}
+	if len(ft.errors) != 0 {
+		t.Errorf("Passing assertions reported %q, want no errors", ft.errors)
+	}
+
+	for _, tc := range []struct {
+		assert func(s *Plan)
+		want   string
+	}{
+		{
+			assert: func(s *Plan) { s.Resource("data.*") },
+			want:   "No resource in the state matches data.*",
+		},
+		{
+			assert: func(s *Plan) { s.Resource("*sql*").Attr("region").Equals("europe-west1") },
+			want:   `module.cloudsql["sql-1"].google_sql_database_instance.default: region = "us-central1", want = "europe-west1"`,
+		},
+		{
+			assert: func(s *Plan) { s.Resource("*sql*").Attr("name").Unknown() },
+			want:   `name = "sql-1", want = (known after apply)`,
+		},
+		{
+			assert: func(s *Plan) { s.Resource("*sql*").Actions("create") },
+			want:   "a state has no planned actions",
+		},
+		{
+			assert: func(s *Plan) { s.Resource("*").Attr("name").Value() },
+			want:   "2 resources match *, want = 1",
+		},
+		{
+			assert: func(s *Plan) { s.Resource("*sql*").Attr("settings.0.edition").Value() },
+			want:   "settings.0.edition is not set",
+		},
+	} {
+		ft := &fakeT{T: t}
+		tc.assert(ForState(ft, state))
+		if len(ft.errors) != 1 || !strings.Contains(ft.errors[0], tc.want) {
+			t.Errorf("Failing assertion reported %q, want one error containing %q", ft.errors, tc.want)
+		}
+	}
+}
+
+func TestState(t *testing.T) {
+	dir := t.TempDir()
+	// The stand-in prints the state only when show is not given a plan file.
+	script := fmt.Sprintf(`#!/bin/sh
+[ "$1" = show ] && [ $# -eq 3 ] && cat %q
+`, filepath.Join(dir, "state.json"))
+	if err := os.WriteFile(filepath.Join(dir, "state.json"), []byte(stateJSON), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "terraform"), []byte(script), 0755); err != nil {
+		t.Fatal(err)
+	}
+	options := &terraform.Options{TerraformDir: dir, TerraformBinary: filepath.Join(dir, "terraform"), PlanFilePath: "plan", NoColor: true}
+
+	ft := &fakeT{T: t}
+	State(ft, options).Resource(`module.cloudsql["sql-1"].*`).Attr("region").Equals("us-central1")
+	if len(ft.errors) != 0 {
+		t.Errorf("State() reported %q, want no errors", ft.errors)
+	}
+	if options.PlanFilePath != "plan" {
+		t.Errorf("PlanFilePath after State() = %q, want = plan", options.PlanFilePath)
+	}
+}
@@ -0,0 +1,118 @@
+// Copyright 2026 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package plan
+
+import (
+	"fmt"
+	gotesting "testing"
+
+	"github.com/GoogleCloudPlatform/cloudnetworking-config-solutions/common_utils/standalone"
+	"github.com/gruntwork-io/terratest/modules/terraform"
+	"github.com/gruntwork-io/terratest/modules/testing"
+)
+
+// Stage is a Terraform directory that is initialized and planned once for all
+// the tests of a package. TestMain plans it and the tests read the shared
+// result:
+//
+//	var stage = plan.NewStage(&terraform.Options{TerraformDir: dir, Vars: tfVars, PlanFilePath: "./plan"})
+//
+//	func TestMain(m *testing.M) { os.Exit(stage.Run(m)) }
+//
+//	func TestResourcesCount(t *testing.T) {
+//		planStruct := stage.Plan(t)
+//		...
+//	}
+//
+// Tests that plan with other options, such as invalid variables, still run
+// their own plan.
+type Stage struct {
+	options  *terraform.Options
+	exitCode int
+	planErr  error
+	json     string
+	plan     *terraform.PlanStruct
+	showErr  error
+}
+
+// NewStage returns a stage planned with options and the default retryable
+// errors of terratest.
+func NewStage(options *terraform.Options) *Stage {
+	return &Stage{options: terraform.WithDefaultRetryableErrors(standalone.T("TestMain"), options)}
+}
+
+// Run initializes and plans the stage, shows the plan as JSON when it
+// succeeds, and then runs the tests of m and returns their exit code. A failed
+// plan does not stop the tests: the tests that read it fail with its error.
+func (s *Stage) Run(m *gotesting.M) int {
+	s.planOnce(standalone.T("TestMain"))
+	return m.Run()
+}
+
+func (s *Stage) planOnce(t testing.TestingT) {
+	s.exitCode = terraform.DefaultErrorExitCode
+	if _, err := terraform.InitE(t, s.options); err != nil {
+		s.planErr, s.showErr = err, err
+		return
+	}
+	s.exitCode, s.planErr = terraform.PlanExitCodeE(t, s.options)
+	if s.planErr != nil || s.exitCode == terraform.DefaultErrorExitCode {
+		s.showErr = fmt.Errorf("terraform plan of %s exited with code %d", s.options.TerraformDir, s.exitCode)
+		return
+	}
+	s.json, s.showErr = terraform.ShowE(t, s.options)
+	if s.showErr != nil {
+		return
+	}
+	s.plan, s.showErr = terraform.ParsePlanJSON(s.json)
+}
+
+// ExitCode returns the detailed exit code of the plan: 0 for an empty diff, 1
+// for an error and 2 for a non-empty diff. Like
+// terraform.InitAndPlanWithExitCode it fails the test when terraform could not
+// be run.
+func (s *Stage) ExitCode(t testing.TestingT) int {
+	if h, ok := t.(interface{ Helper() }); ok {
+		h.Helper()
+	}
+	if s.planErr != nil {
+		t.Fatalf("Planning %s: %v", s.options.TerraformDir, s.planErr)
+	}
+	return s.exitCode
+}
+
+// JSON returns the output of terraform show -json for the plan, or fails the
+// test when the plan failed.
+func (s *Stage) JSON(t testing.TestingT) string {
+	if h, ok := t.(interface{ Helper() }); ok {
+		h.Helper()
+	}
+	if s.showErr != nil {
+		t.Fatalf("Reading the plan of %s: %v", s.options.TerraformDir, s.showErr)
+	}
+	return s.json
+}
+
+// Plan returns the parsed plan, as terraform.InitAndPlanAndShowWithStruct and
+// terraform.ParsePlanJSON do, or fails the test when the plan failed.
+func (s *Stage) Plan(t testing.TestingT) *terraform.PlanStruct {
+	if h, ok := t.(interface{ Helper() }); ok {
+		h.Helper()
+	}
+	if s.showErr != nil {
+		t.Fatalf("Reading the plan of %s: %v", s.options.TerraformDir, s.showErr)
+	}
+	return s.plan
+}
@@ -0,0 +1,89 @@
+// Copyright 2026 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package plan
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/gruntwork-io/terratest/modules/terraform"
+	"github.com/gruntwork-io/terratest/modules/testing"
+	tfjson "github.com/hashicorp/terraform-json"
+)
+
+// State returns the assertions on the state of the Terraform directory of
+// options after apply, read with terraform show -json, or fails the test when
+// the state cannot be read. Integration tests use it to check the attributes
+// of the applied resources that the module does not output:
+//
+//	terraform.InitAndApply(t, terraformOptions)
+//	plan.State(t, terraformOptions).
+//		Resource(`module.cloudsql["` + instanceName + `"].google_sql_database_instance.*`).
+//		Attr("region").Equals(region)
+func State(t testing.TestingT, options *terraform.Options) *Plan {
+	if h, ok := t.(interface{ Helper() }); ok {
+		h.Helper()
+	}
+	p, err := StateE(t, options)
+	if err != nil {
+		t.Fatalf("Reading the state of %s: %v", options.TerraformDir, err)
+	}
+	return p
+}
+
+// StateE is State returning the error instead of failing the test.
+func StateE(t testing.TestingT, options *terraform.Options) (*Plan, error) {
+	// terraform show reads the plan file instead of the state when it is
+	// given one.
+	o := *options
+	o.PlanFilePath = ""
+	out, err := terraform.ShowE(t, &o)
+	if err != nil {
+		return nil, err
+	}
+	state := &tfjson.State{}
+	if err := json.Unmarshal([]byte(out), state); err != nil {
+		return nil, fmt.Errorf("decoding the output of terraform show: %w", err)
+	}
+	return ForState(t, state), nil
+}
+
+// ForState returns the assertions on the managed resources of a state parsed
+// from the output of terraform show -json.
+func ForState(t testing.TestingT, state *tfjson.State) *Plan {
+	var resources []*resource
+	if state.Values != nil {
+		resources = stateResources(state.Values.RootModule, resources)
+	}
+	return newPlan(t, resources, true)
+}
+
+// stateResources appends the managed resources of m and of its child modules
+// to resources.
+func stateResources(m *tfjson.StateModule, resources []*resource) []*resource {
+	if m == nil {
+		return resources
+	}
+	for _, r := range m.Resources {
+		if r.Mode != tfjson.ManagedResourceMode {
+			continue
+		}
+		resources = append(resources, &resource{address: r.Address, values: r.AttributeValues})
+	}
+	for _, child := range m.ChildModules {
+		resources = stateResources(child, resources)
+	}
+	return resources
+}
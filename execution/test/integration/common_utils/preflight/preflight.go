@@ -0,0 +1,315 @@
+// Copyright 2026 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package preflight verifies, before a suite creates anything, that the test
+// profile could be loaded, that the project has the APIs the suite needs
+// enabled, that the caller holds its IAM roles and that enough compute quota
+// is free. Suites declare their Requirements and call Run from TestMain:
+//
+//	func TestMain(m *testing.M) {
+//		os.Exit(preflight.Run(m, preflight.Requirements{
+//			Profile: envErr,
+//			Project: projectID,
+//			APIs:    []string{"compute.googleapis.com"},
+//			Roles:   []string{"roles/compute.networkAdmin"},
+//			Quotas:  []preflight.Quota{{Metric: "FORWARDING_RULES", Region: region, Need: 2}},
+//		}))
+//	}
+//
+// Everything missing is reported at once, with the commands that fix it, and
+// the tests do not run. The preflight command performs the same checks from
+// the command line.
+package preflight
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	gotesting "testing"
+
+	"github.com/GoogleCloudPlatform/cloudnetworking-config-solutions/common_utils/cassette"
+	"github.com/GoogleCloudPlatform/cloudnetworking-config-solutions/common_utils/fakeapi"
+	"github.com/GoogleCloudPlatform/cloudnetworking-config-solutions/common_utils/gcloud"
+	"github.com/GoogleCloudPlatform/cloudnetworking-config-solutions/common_utils/standalone"
+	"github.com/gruntwork-io/terratest/modules/logger"
+	"github.com/gruntwork-io/terratest/modules/testing"
+)
+
+// SkipEnv is the environment variable that turns the check off when set to
+// "skip", for example to run a suite with roles granted through a group.
+const SkipEnv = "TEST_PREFLIGHT"
+
+// Requirements are what a suite needs from its project.
+type Requirements struct {
+	// Profile is the error the test profile was loaded with, as returned by
+	// profile.Current. When it is set the tests do not run, whatever the
+	// other checks.
+	Profile error
+	Project string
+	// APIs are service names, such as "vpcaccess.googleapis.com".
+	APIs []string
+	// Roles are granted to the caller on the project or one of its
+	// ancestors, such as "roles/compute.networkAdmin". roles/owner satisfies
+	// any of them.
+	Roles  []string
+	Quotas []Quota
+}
+
+// Quota is an amount of compute quota the suite uses.
+type Quota struct {
+	// Metric is the compute quota metric, such as FORWARDING_RULES.
+	Metric string
+	// Region is the region of a regional quota, and empty for a project wide
+	// one.
+	Region string
+	// Need is how much of the quota must be free. It defaults to 1.
+	Need float64
+}
+
+func (q Quota) String() string {
+	s := q.Metric
+	if q.Region != "" {
+		s += "@" + q.Region
+	}
+	if q.Need > 1 {
+		s += "=" + strconv.FormatFloat(q.Need, 'f', -1, 64)
+	}
+	return s
+}
+
+// ParseQuota parses METRIC[@REGION][=NEED], the form String returns.
+func ParseQuota(s string) (Quota, error) {
+	q := Quota{Need: 1}
+	if i := strings.Index(s, "="); i >= 0 {
+		need, err := strconv.ParseFloat(s[i+1:], 64)
+		if err != nil || need <= 0 {
+			return q, fmt.Errorf("quota %q: the amount after = must be a positive number", s)
+		}
+		q.Need, s = need, s[:i]
+	}
+	q.Metric, q.Region, _ = strings.Cut(s, "@")
+	if q.Metric == "" {
+		return q, fmt.Errorf("quota %q: the metric is missing, want METRIC[@REGION][=NEED]", s)
+	}
+	return q, nil
+}
+
+// Problem is a requirement the project does not meet.
+type Problem struct {
+	// Kind is "api", "role" or "quota".
+	Kind string
+	// Item is the API, role or quota concerned.
+	Item string
+	// Detail says what is wrong.
+	Detail string
+	// Fix is the command or step that meets the requirement.
+	Fix string
+}
+
+// Report lists the problems found by Check.
+type Report struct {
+	Project  string
+	Problems []Problem
+	// Unchecked lists the requirements that could not be verified, such as
+	// roles when the caller cannot read the IAM policy. They do not fail the
+	// check.
+	Unchecked []string
+}
+
+// OK reports whether every requirement is met.
+func (r *Report) OK() bool { return len(r.Problems) == 0 }
+
+// String formats the problems followed by the commands that fix them, one
+// command for all the missing APIs.
+func (r *Report) String() string {
+	var b strings.Builder
+	if len(r.Problems) == 0 {
+		fmt.Fprintf(&b, "preflight: project %s meets the requirements of the suite\n", r.Project)
+	} else {
+		fmt.Fprintf(&b, "preflight: project %s is missing %d requirement(s) of the suite:\n", r.Project, len(r.Problems))
+	}
+	var apis, fixes []string
+	for _, p := range r.Problems {
+		fmt.Fprintf(&b, "  %s %s: %s\n", p.Kind, p.Item, p.Detail)
+		if p.Kind == "api" {
+			apis = append(apis, p.Item)
+		} else {
+			fixes = append(fixes, p.Fix)
+		}
+	}
+	if len(apis) > 0 {
+		fixes = append([]string{fmt.Sprintf("gcloud services enable %s --project=%s", strings.Join(apis, " "), r.Project)}, fixes...)
+	}
+	if len(fixes) > 0 {
+		b.WriteString("To fix them run:\n")
+		for _, fix := range fixes {
+			fmt.Fprintf(&b, "  %s\n", fix)
+		}
+	}
+	if len(r.Unchecked) > 0 {
+		b.WriteString("Could not check:\n")
+		for _, u := range r.Unchecked {
+			fmt.Fprintf(&b, "  %s\n", u)
+		}
+	}
+	return b.String()
+}
+
+// Check verifies the requirements against the project of c.
+func Check(t testing.TestingT, c *gcloud.Client, req Requirements) *Report {
+	r := &Report{Project: c.Project}
+	if len(req.APIs) > 0 {
+		checkAPIs(t, c, req.APIs, r)
+	}
+	if len(req.Roles) > 0 {
+		checkRoles(t, c, req.Roles, r)
+	}
+	if len(req.Quotas) > 0 {
+		checkQuotas(t, c, req.Quotas, r)
+	}
+	return r
+}
+
+func checkAPIs(t testing.TestingT, c *gcloud.Client, apis []string, r *Report) {
+	enabled, err := c.APIs.ListEnabled(t)
+	if err != nil {
+		r.Unchecked = append(r.Unchecked, fmt.Sprintf("APIs %s: %v", strings.Join(apis, ", "), err))
+		return
+	}
+	on := map[string]bool{}
+	for _, api := range enabled {
+		on[api] = true
+	}
+	for _, api := range apis {
+		if !on[api] {
+			r.Problems = append(r.Problems, Problem{Kind: "api", Item: api, Detail: "not enabled",
+				Fix: fmt.Sprintf("gcloud services enable %s --project=%s", api, c.Project)})
+		}
+	}
+}
+
+func checkRoles(t testing.TestingT, c *gcloud.Client, roles []string, r *Report) {
+	account, err := c.Account(t)
+	if err == nil {
+		var policies []gcloud.AncestorPolicy
+		if policies, err = c.Projects.AncestorsIAMPolicy(t); err == nil {
+			checkBindings(c.Project, gcloud.Member(account), roles, policies, r)
+			return
+		}
+	}
+	r.Unchecked = append(r.Unchecked, fmt.Sprintf("roles %s: %v", strings.Join(roles, ", "), err))
+}
+
+// checkBindings reports the roles member holds on none of the policies.
+// Roles granted to a group may include the member, which gcloud cannot tell,
+// so they are left unchecked.
+func checkBindings(project, member string, roles []string, policies []gcloud.AncestorPolicy, r *Report) {
+	granted, groups := map[string]bool{}, map[string][]string{}
+	for _, p := range policies {
+		for _, b := range p.Policy.Bindings {
+			for _, m := range b.Members {
+				if m == member {
+					granted[b.Role] = true
+				} else if strings.HasPrefix(m, "group:") {
+					groups[b.Role] = append(groups[b.Role], m)
+				}
+			}
+		}
+	}
+	if granted["roles/owner"] {
+		return
+	}
+	for _, role := range roles {
+		switch {
+		case granted[role]:
+		case len(groups[role]) > 0:
+			r.Unchecked = append(r.Unchecked, fmt.Sprintf("role %s: granted to %s, which may include %s", role, strings.Join(groups[role], ", "), member))
+		default:
+			r.Problems = append(r.Problems, Problem{Kind: "role", Item: role,
+				Detail: fmt.Sprintf("not granted to %s on the project, its folders or its organization", member),
+				Fix:    fmt.Sprintf("gcloud projects add-iam-policy-binding %s --member=%s --role=%s", project, member, role)})
+		}
+	}
+}
+
+func checkQuotas(t testing.TestingT, c *gcloud.Client, quotas []Quota, r *Report) {
+	byRegion := map[string][]gcloud.Quota{}
+	for _, q := range quotas {
+		got, ok := byRegion[q.Region]
+		if !ok {
+			var err error
+			if got, err = c.Quotas.List(t, q.Region); err != nil {
+				r.Unchecked = append(r.Unchecked, fmt.Sprintf("quota %s: %v", q, err))
+				continue
+			}
+			byRegion[q.Region] = got
+		}
+		checkQuota(c.Project, q, got, r)
+	}
+}
+
+// checkQuota reports q when less of it is free than the suite needs.
+func checkQuota(project string, q Quota, quotas []gcloud.Quota, r *Report) {
+	need := q.Need
+	if need == 0 {
+		need = 1
+	}
+	where := "the project"
+	if q.Region != "" {
+		where = q.Region
+	}
+	var got *gcloud.Quota
+	for i := range quotas {
+		if quotas[i].Metric == q.Metric {
+			got = &quotas[i]
+		}
+	}
+	if got == nil {
+		r.Unchecked = append(r.Unchecked, fmt.Sprintf("quota %s: %s reports no such metric", q.Metric, where))
+		return
+	}
+	if free := got.Limit - got.Usage; free < need {
+		r.Problems = append(r.Problems, Problem{Kind: "quota", Item: q.Metric + " in " + where,
+			Detail: fmt.Sprintf("%g free (limit %g, usage %g), need %g", free, got.Limit, got.Usage, need),
+			Fix: fmt.Sprintf("go run ./cmd/janitor -project=%s (from common_utils) to delete orphaned resources, or request more %s at https://console.cloud.google.com/iam-admin/quotas?project=%s",
+				project, q.Metric, project)})
+	}
+}
+
+// Run checks req before running the tests of m and returns their exit code,
+// or 1 without running them when the test profile could not be loaded or a
+// requirement is missing. The check of the project is skipped when $TEST_PREFLIGHT is "skip", when req has no project, which the
+// test profile reports, and when the suite runs against the fake API or
+// replays cassettes.
+func Run(m *gotesting.M, req Requirements) int {
+	if req.Profile != nil {
+		fmt.Fprintln(os.Stderr, req.Profile)
+		return 1
+	}
+	if os.Getenv(SkipEnv) == "skip" || req.Project == "" || fakeapi.Enabled() || cassette.Mode(os.Getenv(cassette.ModeEnv)) == cassette.Replay {
+		return m.Run()
+	}
+	c := gcloud.New(req.Project)
+	c.Logger = logger.Discard
+	report := Check(standalone.T("TestMain"), c, req)
+	if !report.OK() || len(report.Unchecked) > 0 {
+		fmt.Fprint(os.Stderr, report)
+	}
+	if !report.OK() {
+		fmt.Fprintf(os.Stderr, "Set %s=skip to run the tests anyway.\n", SkipEnv)
+		return 1
+	}
+	return m.Run()
+}
@@ -0,0 +1,168 @@
+// Copyright 2026 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package preflight
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/GoogleCloudPlatform/cloudnetworking-config-solutions/common_utils/gcloud"
+	"github.com/gruntwork-io/terratest/modules/logger"
+	"github.com/gruntwork-io/terratest/modules/shell"
+	terratesting "github.com/gruntwork-io/terratest/modules/testing"
+)
+
+// fakeRunner replies to the commands starting with a key of outputs, and
+// fails the others with a permission error.
+type fakeRunner struct {
+	outputs map[string]string
+}
+
+func (f *fakeRunner) Run(_ terratesting.TestingT, cmd shell.Command) (string, string, error) {
+	line := strings.Join(cmd.Args, " ")
+	for prefix, out := range f.outputs {
+		if strings.HasPrefix(line, prefix) {
+			return out, "", nil
+		}
+	}
+	return "", "ERROR: (gcloud) PERMISSION_DENIED: caller lacks permission", errors.New("exit status 1")
+}
+
+func newClient(outputs map[string]string) *gcloud.Client {
+	c := gcloud.New("p")
+	c.Runner = &fakeRunner{outputs: outputs}
+	c.Logger = logger.Discard
+	return c
+}
+
+const policies = `[
+  {"id": "p", "type": "project", "policy": {"bindings": [
+    {"role": "roles/compute.networkAdmin", "members": ["user:dev@example.com"]},
+    {"role": "roles/vpcaccess.admin", "members": ["group:netops@example.com"]}
+  ]}},
+  {"id": "42", "type": "organization", "policy": {"bindings": [
+    {"role": "roles/compute.orgFirewallPolicyAdmin", "members": ["user:dev@example.com"]}
+  ]}}
+]`
+
+func TestCheck(t *testing.T) {
+	c := newClient(map[string]string{
+		"services list --enabled":                `[{"config": {"name": "compute.googleapis.com"}}]`,
+		"config get-value account":               `"dev@example.com"`,
+		"projects get-ancestors-iam-policy p":    policies,
+		"compute project-info describe":          `{"quotas": [{"metric": "NETWORKS", "limit": 15, "usage": 3}]}`,
+		"compute regions describe us-central1 ":  `{"quotas": [{"metric": "FORWARDING_RULES", "limit": 15, "usage": 14}]}`,
+		"compute regions describe europe-west1 ": `{"quotas": []}`,
+	})
+	report := Check(t, c, Requirements{
+		APIs:  []string{"compute.googleapis.com", "vpcaccess.googleapis.com", "networksecurity.googleapis.com"},
+		Roles: []string{"roles/compute.networkAdmin", "roles/compute.orgFirewallPolicyAdmin", "roles/vpcaccess.admin", "roles/iam.serviceAccountAdmin"},
+		Quotas: []Quota{
+			{Metric: "NETWORKS", Need: 2},
+			{Metric: "FORWARDING_RULES", Region: "us-central1", Need: 2},
+			{Metric: "CPUS", Region: "europe-west1"},
+		},
+	})
+
+	var got []string
+	for _, p := range report.Problems {
+		got = append(got, p.Kind+" "+p.Item)
+	}
+	want := []string{
+		"api vpcaccess.googleapis.com",
+		"api networksecurity.googleapis.com",
+		"role roles/iam.serviceAccountAdmin",
+		"quota FORWARDING_RULES in us-central1",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Problems = %v, want = %v", got, want)
+	}
+	if report.OK() {
+		t.Errorf("OK() = true, want = false")
+	}
+	if got, want := len(report.Unchecked), 2; got != want {
+		t.Errorf("len(Unchecked) = %v, want = %v: %v", got, want, report.Unchecked)
+	}
+
+	s := report.String()
+	for _, want := range []string{
+		"gcloud services enable vpcaccess.googleapis.com networksecurity.googleapis.com --project=p",
+		"gcloud projects add-iam-policy-binding p --member=user:dev@example.com --role=roles/iam.serviceAccountAdmin",
+		"1 free (limit 15, usage 14), need 2",
+		"granted to group:netops@example.com",
+		"CPUS: europe-west1 reports no such metric",
+	} {
+		if !strings.Contains(s, want) {
+			t.Errorf("String() = %q, want it to contain %q", s, want)
+		}
+	}
+}
+
+func TestCheckOwnerAndServiceAccount(t *testing.T) {
+	c := newClient(map[string]string{
+		"config get-value account": `"ci@p.iam.gserviceaccount.com"`,
+		"projects get-ancestors-iam-policy p": `[{"id": "p", "type": "project", "policy": {"bindings": [
+			{"role": "roles/owner", "members": ["serviceAccount:ci@p.iam.gserviceaccount.com"]}]}}]`,
+	})
+	report := Check(t, c, Requirements{Roles: []string{"roles/compute.networkAdmin"}})
+	if !report.OK() || len(report.Unchecked) > 0 {
+		t.Errorf("Check() = %+v, want no problems", report)
+	}
+}
+
+func TestCheckUnreadable(t *testing.T) {
+	c := newClient(nil)
+	report := Check(t, c, Requirements{
+		APIs:   []string{"compute.googleapis.com"},
+		Roles:  []string{"roles/compute.networkAdmin"},
+		Quotas: []Quota{{Metric: "NETWORKS"}},
+	})
+	if !report.OK() {
+		t.Errorf("Problems = %v, want none when nothing can be read", report.Problems)
+	}
+	if got, want := len(report.Unchecked), 3; got != want {
+		t.Errorf("len(Unchecked) = %v, want = %v: %v", got, want, report.Unchecked)
+	}
+}
+
+func TestParseQuota(t *testing.T) {
+	for _, tc := range []struct {
+		in   string
+		want Quota
+	}{
+		{"NETWORKS", Quota{Metric: "NETWORKS", Need: 1}},
+		{"FORWARDING_RULES@us-central1=5", Quota{Metric: "FORWARDING_RULES", Region: "us-central1", Need: 5}},
+		{"CPUS@us-west2", Quota{Metric: "CPUS", Region: "us-west2", Need: 1}},
+	} {
+		got, err := ParseQuota(tc.in)
+		if err != nil {
+			t.Errorf("ParseQuota(%q) error = %v", tc.in, err)
+			continue
+		}
+		if got != tc.want {
+			t.Errorf("ParseQuota(%q) = %+v, want = %+v", tc.in, got, tc.want)
+		}
+		if got.String() != tc.in {
+			t.Errorf("Quota.String() = %v, want = %v", got.String(), tc.in)
+		}
+	}
+	for _, in := range []string{"", "@us-central1", "NETWORKS=0", "NETWORKS=x"} {
+		if _, err := ParseQuota(in); err == nil {
+			t.Errorf("ParseQuota(%q) error = nil, want an error", in)
+		}
+	}
+}
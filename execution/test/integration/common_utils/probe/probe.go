@@ -0,0 +1,317 @@
+// Copyright 2026 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package probe checks data plane connectivity from a client VM to the
+// endpoints a stage exposes. Probes run on the VM over IAP SSH, all of a call
+// in a single session, and report a typed result with the latency measured on
+// the VM and the reason of a failure. They only use bash, getent, curl and
+// openssl, which the Debian images ship, so the VM needs neither an external
+// address nor packages installed by a startup script.
+package probe
+
+import (
+	"encoding/base64"
+	"fmt"
+	"math"
+	"net"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Kind is the protocol a probe speaks.
+type Kind string
+
+// Kinds of probes.
+const (
+	KindTCP      Kind = "tcp"
+	KindHTTP     Kind = "http"
+	KindDNS      Kind = "dns"
+	KindTLS      Kind = "tls"
+	KindPostgres Kind = "postgres"
+	KindRedis    Kind = "redis"
+)
+
+// DefaultTimeout bounds a probe on the VM when its Timeout is not set.
+var DefaultTimeout = 10 * time.Second
+
+// Probe is a single connectivity check.
+type Probe struct {
+	Kind Kind
+	// Target is a URL for HTTP probes, a host name for DNS probes and
+	// host:port for the others.
+	Target string
+	// ServerName is the SNI of TLS probes. It defaults to the host of Target.
+	ServerName string
+	// Timeout bounds the probe on the VM. It defaults to DefaultTimeout.
+	Timeout time.Duration
+}
+
+// TCP checks that a TCP connection to host:port is accepted.
+func TCP(host string, port int) Probe {
+	return Probe{Kind: KindTCP, Target: net.JoinHostPort(host, strconv.Itoa(port))}
+}
+
+// HTTP sends a GET request to url and expects a 2xx or 3xx response.
+func HTTP(url string) Probe {
+	return Probe{Kind: KindHTTP, Target: url}
+}
+
+// DNS resolves name with the resolver of the VM.
+func DNS(name string) Probe {
+	return Probe{Kind: KindDNS, Target: name}
+}
+
+// TLS completes a TLS handshake with host:port. The certificate is not
+// verified; the output of the handshake is kept in the result detail.
+func TLS(host string, port int) Probe {
+	return Probe{Kind: KindTLS, Target: net.JoinHostPort(host, strconv.Itoa(port))}
+}
+
+// Postgres checks that a PostgreSQL server answers on host:port. It sends the
+// SSL negotiation request, which every server answers before authentication.
+func Postgres(host string, port int) Probe {
+	return Probe{Kind: KindPostgres, Target: net.JoinHostPort(host, strconv.Itoa(port))}
+}
+
+// Redis sends PING to a Redis server on host:port. A server that requires
+// authentication counts as reachable.
+func Redis(host string, port int) Probe {
+	return Probe{Kind: KindRedis, Target: net.JoinHostPort(host, strconv.Itoa(port))}
+}
+
+func (p Probe) String() string {
+	return string(p.Kind) + " " + p.Target
+}
+
+// Failure is the reason a probe failed.
+type Failure string
+
+// Failures of probes.
+const (
+	FailureRefused     Failure = "connection refused"
+	FailureTimeout     Failure = "timed out"
+	FailureUnresolved  Failure = "name not resolved"
+	FailureHandshake   Failure = "TLS handshake failed"
+	FailureResponse    Failure = "unexpected response"
+	FailureMissingTool Failure = "command not found on the VM"
+	FailureOther       Failure = "failed"
+)
+
+// Result is the outcome of a probe.
+type Result struct {
+	Probe Probe
+	OK    bool
+	// Failure is empty when OK is set.
+	Failure Failure
+	// Latency is the time the probe took on the VM, including the start of
+	// the commands it runs.
+	Latency time.Duration
+	// Detail is the end of the output of the probe, such as the error of
+	// curl or the protocol negotiated by a TLS handshake.
+	Detail string
+	// StatusCode is the response status of HTTP probes.
+	StatusCode int
+	// Addresses are the addresses a DNS probe resolved.
+	Addresses []string
+}
+
+func (r Result) String() string {
+	status := "ok"
+	if !r.OK {
+		status = string(r.Failure)
+	}
+	s := fmt.Sprintf("%s: %s in %s", r.Probe, status, r.Latency.Round(time.Millisecond))
+	switch {
+	case r.StatusCode != 0:
+		s += fmt.Sprintf(" (HTTP %d)", r.StatusCode)
+	case len(r.Addresses) > 0:
+		s += " (" + strings.Join(r.Addresses, ", ") + ")"
+	}
+	if !r.OK && r.Detail != "" {
+		s += ": " + r.Detail
+	}
+	return s
+}
+
+// Err returns nil for a probe that succeeded and an *Error otherwise.
+func (r Result) Err() error {
+	if r.OK {
+		return nil
+	}
+	return &Error{Result: r}
+}
+
+// Error is a failed probe.
+type Error struct {
+	Result Result
+}
+
+func (e *Error) Error() string {
+	return "probe " + e.Result.String()
+}
+
+// marker starts the line a probe prints with its exit code, its duration in
+// nanoseconds and its base64 encoded output.
+const marker = "@@probe"
+
+// maxDetail is the number of bytes of output kept per probe.
+const maxDetail = 2048
+
+// script returns the bash script that runs the probes in order.
+func script(probes []Probe) string {
+	var b strings.Builder
+	for _, p := range probes {
+		fmt.Fprintf(&b, "s=$(date +%%s%%N); o=$(%s 2>&1); c=$?; e=$(date +%%s%%N); "+
+			"echo \"%s $c $((e-s)) $(printf %%s \"$o\" | tail -c %d | base64 -w0)\"\n", p.command(), marker, maxDetail)
+	}
+	return b.String()
+}
+
+// command returns the shell command of the probe. Connections are opened
+// with the /dev/tcp files of bash, host and port passed as arguments.
+func (p Probe) command() string {
+	timeout := p.Timeout
+	if timeout == 0 {
+		timeout = DefaultTimeout
+	}
+	secs := strconv.Itoa(int(math.Ceil(timeout.Seconds())))
+	host, port, _ := net.SplitHostPort(p.Target)
+	connect := func(exchange string) string {
+		return "timeout " + secs + " bash -c " + quote(`exec 3<>"/dev/tcp/$0/$1"`+exchange) + " " + quote(host) + " " + quote(port)
+	}
+	switch p.Kind {
+	case KindTCP:
+		return connect("")
+	case KindHTTP:
+		return "curl -sS -o /dev/null -m " + secs + " -w " + quote(`\nHTTP %{http_code}\n`) + " " + quote(p.Target)
+	case KindDNS:
+		return "timeout " + secs + " getent ahosts " + quote(p.Target)
+	case KindTLS:
+		serverName := p.ServerName
+		if serverName == "" {
+			serverName = host
+		}
+		return "timeout " + secs + " openssl s_client -brief -connect " + quote(p.Target) + " -servername " + quote(serverName) + " </dev/null"
+	case KindPostgres:
+		// The SSLRequest message: its length, 8, and the code 80877103.
+		return connect(`; printf '\000\000\000\010\004\322\026\057' >&3; head -c1 <&3`)
+	case KindRedis:
+		return connect(`; printf 'PING\r\n' >&3; head -n1 <&3`)
+	}
+	return "echo " + quote("unknown probe kind "+string(p.Kind)) + "; false"
+}
+
+// quote quotes s for the shell.
+func quote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+// parse turns the output of script into one result per probe.
+func parse(probes []Probe, stdout string) ([]Result, error) {
+	var results []Result
+	for _, line := range strings.Split(stdout, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) < 3 || fields[0] != marker {
+			continue
+		}
+		if len(results) == len(probes) {
+			return nil, fmt.Errorf("probe output has more than %d results", len(probes))
+		}
+		code, err := strconv.Atoi(fields[1])
+		if err != nil {
+			return nil, fmt.Errorf("invalid probe exit code in %q", line)
+		}
+		ns, err := strconv.ParseInt(fields[2], 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid probe duration in %q", line)
+		}
+		var out []byte
+		if len(fields) > 3 {
+			if out, err = base64.StdEncoding.DecodeString(fields[3]); err != nil {
+				return nil, fmt.Errorf("invalid probe output in %q: %w", line, err)
+			}
+		}
+		results = append(results, classify(probes[len(results)], code, time.Duration(ns), string(out)))
+	}
+	if len(results) != len(probes) {
+		return nil, fmt.Errorf("probe output has %d results, want %d:\n%s", len(results), len(probes), stdout)
+	}
+	return results, nil
+}
+
+var httpStatus = regexp.MustCompile(`(?m)^HTTP (\d{3})$`)
+
+// classify builds the result of a probe from its exit code and output.
+func classify(p Probe, code int, latency time.Duration, out string) Result {
+	r := Result{Probe: p, Latency: latency, Detail: strings.TrimSpace(out)}
+	switch p.Kind {
+	case KindHTTP:
+		if m := httpStatus.FindStringSubmatch(out); m != nil {
+			r.StatusCode, _ = strconv.Atoi(m[1])
+			r.Detail = strings.TrimSpace(httpStatus.ReplaceAllString(out, ""))
+		}
+	case KindDNS:
+		seen := map[string]bool{}
+		for _, line := range strings.Split(out, "\n") {
+			if fields := strings.Fields(line); len(fields) > 0 && net.ParseIP(fields[0]) != nil && !seen[fields[0]] {
+				seen[fields[0]] = true
+				r.Addresses = append(r.Addresses, fields[0])
+			}
+		}
+	}
+	r.Failure = failure(p, code, r)
+	r.OK = r.Failure == ""
+	return r
+}
+
+func failure(p Probe, code int, r Result) Failure {
+	out := strings.ToLower(r.Detail)
+	if code != 0 {
+		switch {
+		case code == 127:
+			return FailureMissingTool
+		case code == 124 || strings.Contains(out, "timed out") || (p.Kind == KindHTTP && code == 28):
+			return FailureTimeout
+		case strings.Contains(out, "refused") || (p.Kind == KindHTTP && code == 7):
+			return FailureRefused
+		case (p.Kind == KindDNS && code == 2) || strings.Contains(out, "could not resolve") ||
+			strings.Contains(out, "name or service not known") || strings.Contains(out, "no address associated"):
+			return FailureUnresolved
+		case p.Kind == KindTLS || (p.Kind == KindHTTP && code == 35):
+			return FailureHandshake
+		}
+		return FailureOther
+	}
+	switch p.Kind {
+	case KindHTTP:
+		if r.StatusCode < 200 || r.StatusCode >= 400 {
+			return FailureResponse
+		}
+	case KindDNS:
+		if len(r.Addresses) == 0 {
+			return FailureUnresolved
+		}
+	case KindPostgres:
+		if r.Detail != "S" && r.Detail != "N" {
+			return FailureResponse
+		}
+	case KindRedis:
+		if !strings.HasPrefix(r.Detail, "+PONG") && !strings.HasPrefix(r.Detail, "-NOAUTH") {
+			return FailureResponse
+		}
+	}
+	return ""
+}
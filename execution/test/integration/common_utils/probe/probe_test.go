@@ -0,0 +1,176 @@
+// Copyright 2026 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package probe
+
+import (
+	"bufio"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os/exec"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/GoogleCloudPlatform/cloudnetworking-config-solutions/common_utils/gcloud"
+	"github.com/gruntwork-io/terratest/modules/logger"
+	"github.com/gruntwork-io/terratest/modules/shell"
+	terratesting "github.com/gruntwork-io/terratest/modules/testing"
+)
+
+// localRunner runs the command given to "gcloud compute ssh" on this machine,
+// standing in for the shell of the VM.
+type localRunner struct{}
+
+func (localRunner) Run(_ terratesting.TestingT, cmd shell.Command) (string, string, error) {
+	for _, arg := range cmd.Args {
+		if command, ok := strings.CutPrefix(arg, "--command="); ok {
+			out, err := exec.Command("bash", "-c", command).Output()
+			return string(out), "", err
+		}
+	}
+	return "", "", errors.New("not an ssh command")
+}
+
+// serve accepts connections on a local port and answers every line or
+// message with reply.
+func serve(t *testing.T, reply string) int {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { l.Close() })
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer conn.Close()
+				buf := make([]byte, 64)
+				if _, err := bufio.NewReader(conn).Read(buf); err == nil {
+					conn.Write([]byte(reply))
+				}
+			}()
+		}
+	}()
+	return l.Addr().(*net.TCPAddr).Port
+}
+
+func closedPort(t *testing.T) int {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	port := l.Addr().(*net.TCPAddr).Port
+	l.Close()
+	return port
+}
+
+func TestRun(t *testing.T) {
+	for _, tool := range []string{"bash", "curl", "getent", "openssl", "base64", "timeout"} {
+		if _, err := exec.LookPath(tool); err != nil {
+			t.Skipf("%s is not installed", tool)
+		}
+	}
+	web := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/" {
+			http.NotFound(w, r)
+		}
+	}))
+	defer web.Close()
+	secure := httptest.NewTLSServer(http.NotFoundHandler())
+	defer secure.Close()
+	securePort, _ := strconv.Atoi(secure.URL[strings.LastIndex(secure.URL, ":")+1:])
+
+	c := gcloud.New("p")
+	c.Runner = localRunner{}
+	c.Logger = logger.Discard
+	vm := Reuse(c, "us-central1-a", "client")
+	closed := closedPort(t)
+	for _, tc := range []struct {
+		probe   Probe
+		failure Failure
+	}{
+		{TCP("127.0.0.1", serve(t, "")), ""},
+		{TCP("127.0.0.1", closed), FailureRefused},
+		{HTTP(web.URL), ""},
+		{HTTP(web.URL + "/missing"), FailureResponse},
+		{HTTP("http://127.0.0.1:" + strconv.Itoa(closed)), FailureRefused},
+		{DNS("localhost"), ""},
+		{DNS("no-such-host.invalid"), FailureUnresolved},
+		{TLS("127.0.0.1", securePort), ""},
+		{TLS("127.0.0.1", closed), FailureRefused},
+		{Postgres("127.0.0.1", serve(t, "S")), ""},
+		{Postgres("127.0.0.1", serve(t, "")), FailureResponse},
+		{Redis("127.0.0.1", serve(t, "+PONG\r\n")), ""},
+		{Redis("127.0.0.1", serve(t, "-NOAUTH Authentication required.\r\n")), ""},
+		{Redis("127.0.0.1", serve(t, "HTTP/1.1 400 Bad Request\r\n")), FailureResponse},
+	} {
+		tc.probe.Timeout = 2 * time.Second
+		results, err := vm.Run(t, tc.probe)
+		if err != nil {
+			t.Fatalf("Run(%s) error = %v", tc.probe, err)
+		}
+		r := results[0]
+		if r.Failure != tc.failure || r.OK != (tc.failure == "") {
+			t.Errorf("Run(%s) = %s, want failure %q", tc.probe, r, tc.failure)
+		}
+		if r.Latency <= 0 {
+			t.Errorf("Run(%s) latency = %v, want > 0", tc.probe, r.Latency)
+		}
+	}
+}
+
+func TestClassify(t *testing.T) {
+	for _, tc := range []struct {
+		probe   Probe
+		code    int
+		out     string
+		failure Failure
+	}{
+		{HTTP("http://10.0.0.2"), 0, "\nHTTP 200\n", ""},
+		{HTTP("http://10.0.0.2"), 0, "\nHTTP 503\n", FailureResponse},
+		{HTTP("http://10.0.0.2"), 28, "curl: (28) Connection timed out after 5001 milliseconds\nHTTP 000\n", FailureTimeout},
+		{HTTP("http://db.internal"), 6, "curl: (6) Could not resolve host: db.internal\nHTTP 000\n", FailureUnresolved},
+		{HTTP("https://10.0.0.2"), 35, "curl: (35) OpenSSL SSL_connect: Connection reset by peer\nHTTP 000\n", FailureHandshake},
+		{TCP("10.0.0.2", 5432), 124, "", FailureTimeout},
+		{TLS("10.0.0.2", 443), 1, "40C7:error:0A000410:SSL routines::sslv3 alert handshake failure", FailureHandshake},
+		{Postgres("10.0.0.2", 5432), 127, "bash: line 1: timeout: command not found", FailureMissingTool},
+		{DNS("db.internal"), 0, "", FailureUnresolved},
+	} {
+		r := classify(tc.probe, tc.code, time.Millisecond, tc.out)
+		if r.Failure != tc.failure {
+			t.Errorf("classify(%s, %d, %q) failure = %q, want = %q", tc.probe, tc.code, tc.out, r.Failure, tc.failure)
+		}
+	}
+	r := classify(DNS("db.internal"), 0, time.Millisecond, "10.0.0.2  STREAM db.internal\n10.0.0.2  DGRAM\n10.0.0.3  STREAM\n")
+	if got, want := strings.Join(r.Addresses, ","), "10.0.0.2,10.0.0.3"; got != want {
+		t.Errorf("Addresses = %v, want = %v", got, want)
+	}
+	var perr *Error
+	if !errors.As(r.Err(), &perr) && r.Err() != nil {
+		t.Errorf("Err() = %v, want nil or an *Error", r.Err())
+	}
+}
+
+func TestParseRejectsMissingResults(t *testing.T) {
+	if _, err := parse([]Probe{TCP("10.0.0.2", 80), TCP("10.0.0.3", 80)}, marker+" 0 1000 \n"); err == nil {
+		t.Errorf("parse() error = nil, want an error for a missing result")
+	}
+}
@@ -0,0 +1,143 @@
+// Copyright 2026 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package probe
+
+import (
+	"fmt"
+	gotesting "testing"
+
+	"github.com/GoogleCloudPlatform/cloudnetworking-config-solutions/common_utils/fixture"
+	"github.com/GoogleCloudPlatform/cloudnetworking-config-solutions/common_utils/gcloud"
+	"github.com/GoogleCloudPlatform/cloudnetworking-config-solutions/common_utils/naming"
+	"github.com/GoogleCloudPlatform/cloudnetworking-config-solutions/common_utils/wait"
+	"github.com/gruntwork-io/terratest/modules/testing"
+)
+
+// IAPRange is the source range of the connections IAP forwards to VMs.
+const IAPRange = "35.235.240.0/20"
+
+// VMOptions describe the client VM created by NewVM.
+type VMOptions struct {
+	// Name defaults to a name derived from "probe" through the naming
+	// package.
+	Name        string
+	Zone        string
+	Network     string
+	Subnet      string
+	MachineType string
+	// ExternalAddress gives the VM an external address, for probes of
+	// external endpoints such as an external passthrough load balancer,
+	// which a VM without one only reaches through Cloud NAT.
+	ExternalAddress bool
+}
+
+// VM is the client VM the probes run on.
+type VM struct {
+	// Handle is the fixture of a VM created by NewVM and nil for a reused
+	// one.
+	*fixture.Handle
+	Client *gcloud.Client
+	Zone   string
+	Name   string
+}
+
+// NewVM creates a client VM in opts.Subnet, by default without an external
+// address, with a firewall rule that admits IAP SSH to it, and waits until it
+// can be reached. Both are registered as fixtures; pass the handles of the
+// network and subnetwork so that they outlive the VM.
+func NewVM(t gotesting.TB, c *gcloud.Client, opts VMOptions, dependsOn ...*fixture.Handle) (*VM, error) {
+	name := opts.Name
+	if name == "" {
+		name = naming.Name(t, naming.Compute, "probe")
+	}
+	firewall, err := fixture.Firewall(t, c, naming.Name(t, naming.Compute, name+"-iap"), gcloud.FirewallOptions{
+		Network:      opts.Network,
+		Allow:        []string{"tcp:22"},
+		SourceRanges: []string{IAPRange},
+		TargetTags:   []string{name},
+	}, dependsOn...)
+	if err != nil {
+		return nil, err
+	}
+	h, err := fixture.Instance(t, c, name, gcloud.InstanceOptions{
+		Zone:        opts.Zone,
+		MachineType: opts.MachineType,
+		Network:     opts.Network,
+		Subnet:      opts.Subnet,
+		Tags:        []string{name},
+		NoAddress:   !opts.ExternalAddress,
+	}, append([]*fixture.Handle{firewall}, dependsOn...)...)
+	if err != nil {
+		return nil, err
+	}
+	vm := &VM{Handle: h, Client: c, Zone: opts.Zone, Name: name}
+	if _, err := wait.ForE(t, "instance "+name+" to run", wait.DefaultOptions, wait.InstanceRunning(t, c, opts.Zone, name)); err != nil {
+		return nil, err
+	}
+	// The SSH keys of the caller take a while to reach a new VM.
+	if _, err := wait.ForE(t, "SSH to "+name, wait.DefaultOptions, wait.Check(func() error {
+		_, err := c.Instances.SSH(t, opts.Zone, name, "true")
+		return err
+	})); err != nil {
+		return nil, err
+	}
+	return vm, nil
+}
+
+// Reuse returns the VM called name, created by the test, to run probes on.
+// It must be reachable over IAP SSH.
+func Reuse(c *gcloud.Client, zone, name string) *VM {
+	return &VM{Client: c, Zone: zone, Name: name}
+}
+
+// Run runs the probes on the VM in a single SSH session and returns their
+// results in order. The error is only set when the session fails; failed
+// probes are reported in their result.
+func (vm *VM) Run(t testing.TestingT, probes ...Probe) ([]Result, error) {
+	stdout, err := vm.Client.Instances.SSH(t, vm.Zone, vm.Name, "bash -c "+quote(script(probes)))
+	if err != nil {
+		return nil, err
+	}
+	return parse(probes, stdout)
+}
+
+// Eventually runs the probes until every one of them succeeds, or with
+// reachable false until every one of them fails, such as after a firewall
+// change that takes a while to reach the data plane. It returns the last
+// results.
+func (vm *VM) Eventually(t testing.TestingT, opts wait.Options, reachable bool, probes ...Probe) ([]Result, error) {
+	want := "reachable"
+	if !reachable {
+		want = "unreachable"
+	}
+	var results []Result
+	_, err := wait.ForE(t, fmt.Sprintf("%d probe(s) from %s to be %s", len(probes), vm.Name, want), opts, func() (bool, string, error) {
+		rs, err := vm.Run(t, probes...)
+		if err != nil {
+			return false, err.Error(), nil
+		}
+		results = rs
+		for _, r := range rs {
+			if r.Failure == FailureMissingTool {
+				return false, "", r.Err()
+			}
+			if r.OK != reachable {
+				return false, r.String(), nil
+			}
+		}
+		return true, "all " + want, nil
+	})
+	return results, err
+}
@@ -0,0 +1,284 @@
+// Copyright 2026 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package profile describes the environment the integration tests run in:
+// the projects, organization, billing project, regions, zones and lab assets
+// the suites use. It is read from the YAML file named by $TEST_PROFILE (see
+// integration/profile.yaml.example), and the environment variables the
+// suites used before, such as TF_VAR_project_id, still set the matching
+// fields and take precedence over the file. Suites take their settings from
+// the profile instead of reading the environment or hard-coding regions, and
+// each test names the fields it needs so that it fails, before any resource
+// is created, when one is missing. Only the fields a lab may leave out, such
+// as the organization, skip the tests that need them instead.
+package profile
+
+import (
+	"fmt"
+	"os"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/GoogleCloudPlatform/cloudnetworking-config-solutions/common_utils/cassette"
+	"github.com/GoogleCloudPlatform/cloudnetworking-config-solutions/common_utils/fakeapi"
+	"github.com/GoogleCloudPlatform/cloudnetworking-config-solutions/common_utils/schema"
+)
+
+// Env is the environment variable naming the profile file. Use an absolute
+// path: each suite runs in its own package directory.
+const Env = "TEST_PROFILE"
+
+// OfflineProject is the project of the suites that run against the fake API
+// or replay cassettes when the profile names none. Nothing is created in it.
+const OfflineProject = "offline-project"
+
+// Profile is the environment of the integration tests. Fields are named in
+// Validate and Require by their YAML path, such as "projects.endpoint".
+type Profile struct {
+	Projects Projects `yaml:"projects"`
+	// OrgID is the organization of the organization-scoped stages.
+	OrgID string `yaml:"org_id"`
+	// BillingProject is the quota project of the calls to organization-scoped
+	// APIs.
+	BillingProject string  `yaml:"billing_project"`
+	Regions        Regions `yaml:"regions"`
+	Zones          Zones   `yaml:"zones"`
+	Lab            Lab     `yaml:"lab"`
+}
+
+// Projects are the projects the suites deploy to.
+type Projects struct {
+	// Endpoint is the project of the consumer side and of most stages.
+	Endpoint string `yaml:"endpoint"`
+	// Producer is the project of the producer side of producer-connectivity.
+	Producer string `yaml:"producer"`
+	// Interconnect holds the physical interconnects of the lab.
+	Interconnect string `yaml:"interconnect"`
+	// Attachment is the project of the PSC attachments of producer/AlloyDB.
+	Attachment string `yaml:"attachment"`
+}
+
+// Regions are the regions the suites deploy to.
+type Regions struct {
+	Default string `yaml:"default"`
+	// Networking is used by 02-networking, whose interconnect tests follow
+	// the location of the lab interconnects.
+	Networking string `yaml:"networking"`
+	// Fallback are the regions tried in order when the default one is out of
+	// capacity (see the location package).
+	Fallback []string `yaml:"fallback"`
+}
+
+// Zones are the zones the suites deploy to.
+type Zones struct {
+	Default string `yaml:"default"`
+	// Interconnect is the zone of the lab interconnects.
+	Interconnect string `yaml:"interconnect"`
+	// Fallback are the zones tried in order when the default one is out of
+	// capacity or does not offer a machine type. Zones outside the region of
+	// the suite are skipped.
+	Fallback []string `yaml:"fallback"`
+}
+
+// Lab lists assets that exist in the test lab ahead of the runs.
+type Lab struct {
+	// Interconnects are the dedicated interconnects the VLAN attachments of
+	// 02-networking use.
+	Interconnects []string `yaml:"interconnects"`
+	// DeployedInterconnect is the name of the interconnect deployed in the
+	// lab, such as dedicated-ix-vpn-client-0.
+	DeployedInterconnect string `yaml:"deployed_interconnect"`
+}
+
+// Interconnect returns the i-th lab interconnect, or "" when the profile
+// lists fewer.
+func (l Lab) Interconnect(i int) string {
+	if i < len(l.Interconnects) {
+		return l.Interconnects[i]
+	}
+	return ""
+}
+
+// envVars are the environment variables that set a field, in order of
+// precedence.
+var envVars = map[string][]string{
+	"projects.endpoint":         {"TF_VAR_endpoint_project_id", "TF_VAR_project_id"},
+	"projects.producer":         {"TF_VAR_producer_project_id"},
+	"projects.interconnect":     {"TF_VAR_interconnect_project_id"},
+	"projects.attachment":       {"TF_VAR_ATTACHMENT_PROJECT_ID"},
+	"org_id":                    {"TF_VAR_organization_id"},
+	"billing_project":           {"TF_VAR_billing_project_id"},
+	"lab.deployed_interconnect": {"deployed_interconnect_name"},
+}
+
+// Optional are the fields a lab may leave out of its profile, as noted in
+// profile.yaml.example. The tests that need one of them are skipped without
+// it rather than failed.
+var Optional = map[string]bool{
+	"org_id":                    true,
+	"billing_project":           true,
+	"lab.deployed_interconnect": true,
+}
+
+// Default returns the profile without a file or environment: the regions,
+// zones and lab interconnects the suites have always used, and no projects.
+func Default() *Profile {
+	return &Profile{
+		Regions: Regions{Default: "us-central1", Networking: "us-west2", Fallback: []string{"us-east1", "us-east4"}},
+		Zones:   Zones{Default: "us-central1-a", Interconnect: "us-west2-a", Fallback: []string{"us-central1-b", "us-central1-c", "us-central1-f"}},
+		Lab:     Lab{Interconnects: []string{"cso-lab-interconnect-1", "cso-lab-interconnect-2"}},
+	}
+}
+
+// Load reads the profile at path over the defaults, in strict mode so that a
+// misspelled key is an error, and applies the environment variables. An
+// empty path reads the environment only. When the suites run against the
+// fake API or replay cassettes, projects left empty are set to
+// OfflineProject.
+func Load(path string) (*Profile, error) {
+	p := Default()
+	if path != "" {
+		if err := schema.Load(path, p); err != nil {
+			return nil, fmt.Errorf("loading the test profile %s: %w", path, err)
+		}
+	}
+	for field, names := range envVars {
+		for _, name := range names {
+			if v := os.Getenv(name); v != "" {
+				p.field(field).SetString(v)
+				break
+			}
+		}
+	}
+	if fakeapi.Enabled() || cassette.Mode(os.Getenv(cassette.ModeEnv)) == cassette.Replay {
+		for _, project := range []*string{&p.Projects.Endpoint, &p.Projects.Producer, &p.Projects.Interconnect, &p.Projects.Attachment} {
+			if *project == "" {
+				*project = OfflineProject
+			}
+		}
+	}
+	return p, nil
+}
+
+var (
+	once    sync.Once
+	current *Profile
+	loadErr error
+)
+
+// Get returns the profile named by $TEST_PROFILE, loaded once per test
+// binary.
+func Get() (*Profile, error) {
+	once.Do(func() {
+		current, loadErr = Load(os.Getenv(Env))
+	})
+	return current, loadErr
+}
+
+// Current returns the profile without checking its fields. Suites declare
+// their package variables from it, for example
+//
+//	env, envErr = profile.Current()
+//	projectID   = env.Projects.Endpoint
+//
+// pass envErr to preflight from TestMain, so that a profile that cannot be
+// loaded stops the run, and call Require at the start of each test. When the
+// profile cannot be loaded Current returns the defaults with the error.
+func Current() (*Profile, error) {
+	p, err := Get()
+	if err != nil {
+		return Default(), err
+	}
+	return p, nil
+}
+
+// Require checks that the fields of the profile are set. It fails the test
+// when the profile cannot be loaded or misses one of the fields, with a
+// message naming the missing fields, the variables that set them and the
+// profile file. Only when all the missing fields are Optional is the test
+// skipped instead.
+func Require(t testing.TB, fields ...string) *Profile {
+	t.Helper()
+	p, err := Get()
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	var required, optional []string
+	for _, field := range fields {
+		if Optional[field] {
+			optional = append(optional, field)
+		} else {
+			required = append(required, field)
+		}
+	}
+	if err := p.Validate(required...); err != nil {
+		t.Fatalf("%v", err)
+	}
+	if err := p.Validate(optional...); err != nil {
+		t.Skipf("%v; the fields are optional, so the test is skipped", err)
+	}
+	return p
+}
+
+// Validate returns an error naming each of the fields that is empty, with
+// the environment variables that can set it.
+func (p *Profile) Validate(fields ...string) error {
+	var missing []string
+	for _, field := range fields {
+		v := p.field(field)
+		if v.IsValid() && !v.IsZero() {
+			continue
+		}
+		if !v.IsValid() {
+			missing = append(missing, field+" (unknown field)")
+		} else if names := envVars[field]; len(names) > 0 {
+			missing = append(missing, fmt.Sprintf("%s (or $%s)", field, strings.Join(names, ", $")))
+		} else {
+			missing = append(missing, field)
+		}
+	}
+	if len(missing) == 0 {
+		return nil
+	}
+	source := "$" + Env + " is not set"
+	if path := os.Getenv(Env); path != "" {
+		source = "read from " + path
+	}
+	return fmt.Errorf("test profile (%s) is missing required fields: %s", source, strings.Join(missing, ", "))
+}
+
+// field returns the field at the YAML path, or the zero Value when there is
+// none.
+func (p *Profile) field(path string) reflect.Value {
+	v := reflect.ValueOf(p).Elem()
+	for _, key := range strings.Split(path, ".") {
+		if v.Kind() != reflect.Struct {
+			return reflect.Value{}
+		}
+		next := reflect.Value{}
+		for i := 0; i < v.NumField(); i++ {
+			if strings.Split(v.Type().Field(i).Tag.Get("yaml"), ",")[0] == key {
+				next = v.Field(i)
+				break
+			}
+		}
+		if !next.IsValid() {
+			return next
+		}
+		v = next
+	}
+	return v
+}
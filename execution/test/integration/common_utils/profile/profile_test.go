@@ -0,0 +1,215 @@
+// Copyright 2026 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package profile
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/GoogleCloudPlatform/cloudnetworking-config-solutions/common_utils/cassette"
+	"github.com/GoogleCloudPlatform/cloudnetworking-config-solutions/common_utils/fakeapi"
+)
+
+// clearEnv unsets the variables that override the profile for the test.
+func clearEnv(t *testing.T) {
+	for _, names := range envVars {
+		for _, name := range names {
+			t.Setenv(name, "")
+		}
+	}
+	t.Setenv(Env, "")
+	t.Setenv(fakeapi.EnabledEnv, "")
+	t.Setenv(cassette.ModeEnv, "")
+}
+
+func TestLoadExample(t *testing.T) {
+	clearEnv(t)
+	p, err := Load(filepath.Join("..", "..", "profile.yaml.example"))
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	want := &Profile{
+		Projects: Projects{
+			Endpoint:     "my-endpoint-project",
+			Producer:     "my-producer-project",
+			Interconnect: "my-interconnect-project",
+			Attachment:   "my-attachment-project",
+		},
+		OrgID:          "123456789012",
+		BillingProject: "my-billing-project",
+		Regions:        Regions{Default: "us-central1", Networking: "us-west2", Fallback: []string{"us-east1", "us-east4"}},
+		Zones:          Zones{Default: "us-central1-a", Interconnect: "us-west2-a", Fallback: []string{"us-central1-b", "us-central1-c", "us-central1-f"}},
+		Lab: Lab{
+			Interconnects:        []string{"cso-lab-interconnect-1", "cso-lab-interconnect-2"},
+			DeployedInterconnect: "dedicated-ix-vpn-client-0",
+		},
+	}
+	if !reflect.DeepEqual(p, want) {
+		t.Errorf("Load() = %+v, want = %+v", p, want)
+	}
+}
+
+func TestLoadDefaultsAndEnv(t *testing.T) {
+	clearEnv(t)
+	path := filepath.Join(t.TempDir(), "profile.yaml")
+	if err := os.WriteFile(path, []byte("projects:\n  endpoint: from-file\nregions:\n  default: europe-west1\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("TF_VAR_project_id", "from-env")
+	t.Setenv("TF_VAR_organization_id", "42")
+	p, err := Load(path)
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if got, want := p.Projects.Endpoint, "from-env"; got != want {
+		t.Errorf("Projects.Endpoint = %v, want = %v", got, want)
+	}
+	if got, want := p.OrgID, "42"; got != want {
+		t.Errorf("OrgID = %v, want = %v", got, want)
+	}
+	if got, want := p.Regions.Default, "europe-west1"; got != want {
+		t.Errorf("Regions.Default = %v, want = %v", got, want)
+	}
+	if got, want := p.Regions.Networking, "us-west2"; got != want {
+		t.Errorf("Regions.Networking = %v, want = %v", got, want)
+	}
+}
+
+func TestLoadRejectsUnknownKeys(t *testing.T) {
+	clearEnv(t)
+	path := filepath.Join(t.TempDir(), "profile.yaml")
+	if err := os.WriteFile(path, []byte("projects:\n  endpoints: typo\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Load(path); err == nil || !strings.Contains(err.Error(), "endpoints") {
+		t.Errorf("Load() error = %v, want an error naming the unknown key", err)
+	}
+}
+
+func TestValidate(t *testing.T) {
+	clearEnv(t)
+	p := Default()
+	p.Projects.Endpoint = "p"
+	if err := p.Validate("projects.endpoint", "regions.default", "lab.interconnects"); err != nil {
+		t.Errorf("Validate() of set fields = %v, want = nil", err)
+	}
+	err := p.Validate("projects.endpoint", "org_id", "billing_project", "projects.producr")
+	if err == nil {
+		t.Fatalf("Validate() = nil, want an error")
+	}
+	for _, want := range []string{"org_id (or $TF_VAR_organization_id)", "billing_project (or $TF_VAR_billing_project_id)", "projects.producr (unknown field)", "$TEST_PROFILE is not set"} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("Validate() error = %q, want it to contain %q", err, want)
+		}
+	}
+	if strings.Contains(err.Error(), "projects.endpoint") {
+		t.Errorf("Validate() error = %q names a field that is set", err)
+	}
+}
+
+func TestLoadOffline(t *testing.T) {
+	for _, env := range [][2]string{{fakeapi.EnabledEnv, "true"}, {cassette.ModeEnv, string(cassette.Replay)}} {
+		clearEnv(t)
+		t.Setenv("TF_VAR_producer_project_id", "producer")
+		t.Setenv(env[0], env[1])
+		p, err := Load("")
+		if err != nil {
+			t.Fatalf("Load() error = %v", err)
+		}
+		if got, want := p.Projects.Endpoint, OfflineProject; got != want {
+			t.Errorf("%s=%s: Projects.Endpoint = %v, want = %v", env[0], env[1], got, want)
+		}
+		if got, want := p.Projects.Producer, "producer"; got != want {
+			t.Errorf("%s=%s: Projects.Producer = %v, want = %v", env[0], env[1], got, want)
+		}
+	}
+	clearEnv(t)
+	p, err := Load("")
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if got := p.Projects.Endpoint; got != "" {
+		t.Errorf("Projects.Endpoint without a fake API or replay = %v, want = \"\"", got)
+	}
+}
+
+func TestRequire(t *testing.T) {
+	clearEnv(t)
+	once.Do(func() {})
+	current, loadErr = Load("")
+	current.Projects.Endpoint = "p"
+	t.Cleanup(func() { current, loadErr = nil, nil })
+
+	t.Run("set", func(t *testing.T) {
+		if got := Require(t, "projects.endpoint"); got != current {
+			t.Errorf("Require() = %p, want the current profile %p", got, current)
+		}
+	})
+	var skipped bool
+	t.Run("missing optional", func(t *testing.T) {
+		defer func() { skipped = t.Skipped() }()
+		Require(t, "projects.endpoint", "org_id")
+		t.Errorf("Require() of a missing optional field returned, want the test skipped")
+	})
+	if !skipped {
+		t.Errorf("Require() of a missing optional field did not skip the test")
+	}
+	t.Run("missing required", func(t *testing.T) {
+		if fatal := requireFatal(t, "org_id", "projects.producer"); !strings.Contains(fatal, "projects.producer (or $TF_VAR_producer_project_id)") || !strings.Contains(fatal, "$TEST_PROFILE is not set") {
+			t.Errorf("Require() of a missing field failed with %q, want the field and the profile named", fatal)
+		}
+	})
+	t.Run("load error", func(t *testing.T) {
+		loadErr = errors.New("loading the test profile /lab.yaml: bad key")
+		defer func() { loadErr = nil }()
+		if fatal := requireFatal(t, "projects.endpoint"); fatal != loadErr.Error() {
+			t.Errorf("Require() with a load error failed with %q, want = %q", fatal, loadErr)
+		}
+		if p, err := Current(); err != loadErr || !reflect.DeepEqual(p, Default()) {
+			t.Errorf("Current() = %+v, %v, want = the defaults, %v", p, err, loadErr)
+		}
+	})
+}
+
+// fatalT records the message of Fatalf and ends the goroutine of the test,
+// as testing.T does.
+type fatalT struct {
+	*testing.T
+	fatal string
+}
+
+func (f *fatalT) Fatalf(format string, args ...any) {
+	f.fatal = fmt.Sprintf(format, args...)
+	runtime.Goexit()
+}
+
+// requireFatal calls Require with the fields and returns the message it
+// failed the test with, or "" when it returned.
+func requireFatal(t *testing.T, fields ...string) string {
+	ft := &fatalT{T: t}
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		Require(ft, fields...)
+	}()
+	<-done
+	return ft.fatal
+}
@@ -0,0 +1,153 @@
+// Copyright 2026 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package report
+
+import (
+	"encoding/xml"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// The JUnit layout read by Jenkins, GitLab and most CI dashboards. Phases and
+// resources are test case properties, and artifacts are attachments in the
+// [[ATTACHMENT|path]] form of the JUnit attachments plugin.
+type junitSuites struct {
+	XMLName xml.Name     `xml:"testsuites"`
+	Suites  []junitSuite `xml:"testsuite"`
+}
+
+type junitSuite struct {
+	Name       string          `xml:"name,attr"`
+	Tests      int             `xml:"tests,attr"`
+	Failures   int             `xml:"failures,attr"`
+	Skipped    int             `xml:"skipped,attr"`
+	Time       string          `xml:"time,attr"`
+	Timestamp  string          `xml:"timestamp,attr"`
+	Properties []junitProperty `xml:"properties>property"`
+	Cases      []junitCase     `xml:"testcase"`
+}
+
+type junitCase struct {
+	Name       string           `xml:"name,attr"`
+	Classname  string           `xml:"classname,attr"`
+	Time       string           `xml:"time,attr"`
+	Properties *junitProperties `xml:"properties"`
+	Failure    *junitMessage    `xml:"failure"`
+	Skipped    *junitMessage    `xml:"skipped"`
+	SystemOut  string           `xml:"system-out,omitempty"`
+}
+
+type junitProperties struct {
+	Properties []junitProperty `xml:"property"`
+}
+
+type junitProperty struct {
+	Name  string `xml:"name,attr"`
+	Value string `xml:"value,attr"`
+}
+
+type junitMessage struct {
+	Message string `xml:"message,attr"`
+	Text    string `xml:",chardata"`
+}
+
+// JUnit returns run as JUnit XML.
+func JUnit(run *Run) ([]byte, error) {
+	s := junitSuite{
+		Name:       run.Suite,
+		Tests:      len(run.Tests),
+		Time:       seconds(run.Duration),
+		Timestamp:  run.Started.UTC().Format(time.RFC3339),
+		Properties: []junitProperty{{Name: "run_id", Value: run.RunID}},
+	}
+	for _, t := range run.Tests {
+		c := junitCase{Name: t.Name, Classname: run.Suite, Time: seconds(t.Duration)}
+		var props []junitProperty
+		for _, p := range t.Phases {
+			props = append(props, junitProperty{Name: "phase." + string(p.Name), Value: seconds(p.Duration)})
+		}
+		for _, res := range t.Resources {
+			props = append(props, junitProperty{Name: "resource." + string(res.Cleanup), Value: res.Kind + " " + res.ID})
+		}
+		keys := make([]string, 0, len(t.Properties))
+		for key := range t.Properties {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			props = append(props, junitProperty{Name: key, Value: t.Properties[key]})
+		}
+		if len(props) > 0 {
+			c.Properties = &junitProperties{Properties: props}
+		}
+		var out strings.Builder
+		for _, a := range t.Artifacts {
+			fmt.Fprintf(&out, "%s: [[ATTACHMENT|%s]]\n", a.Name, a.Path)
+		}
+		c.SystemOut = out.String()
+		switch t.Status {
+		case Failed:
+			s.Failures++
+			c.Failure = failure(t)
+		case Skipped:
+			s.Skipped++
+			c.Skipped = &junitMessage{Message: "skipped"}
+		case "":
+			// The test has not ended, which only happens when the report is
+			// written while it runs.
+			s.Failures++
+			c.Failure = &junitMessage{Message: "did not end"}
+		}
+		s.Cases = append(s.Cases, c)
+	}
+	data, err := xml.MarshalIndent(junitSuites{Suites: []junitSuite{s}}, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append([]byte(xml.Header), append(data, '\n')...), nil
+}
+
+// failure describes a failed test by the phases it failed in and the
+// resources it leaked.
+func failure(t *Test) *junitMessage {
+	var phases []string
+	for _, p := range t.Phases {
+		if p.Failed {
+			phases = append(phases, string(p.Name))
+		}
+	}
+	msg := "failed"
+	if len(phases) > 0 {
+		msg = "failed during " + strings.Join(phases, ", ")
+	}
+	var text strings.Builder
+	for _, p := range t.Phases {
+		fmt.Fprintln(&text, p)
+	}
+	if leaked := t.Leaked(); len(leaked) > 0 {
+		msg += fmt.Sprintf("; %d resource(s) not cleaned up", len(leaked))
+		for _, res := range leaked {
+			fmt.Fprintf(&text, "%s %s %s %s\n", res.Cleanup, res.Kind, res.ID, res.Error)
+		}
+	}
+	return &junitMessage{Message: msg, Text: text.String()}
+}
+
+// seconds formats d as the seconds of a JUnit time attribute.
+func seconds(d time.Duration) string {
+	return fmt.Sprintf("%.3f", d.Seconds())
+}
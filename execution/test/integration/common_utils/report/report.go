@@ -0,0 +1,363 @@
+// Copyright 2026 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package report writes a JSON and a JUnit XML report of the tests of a suite
+// to the directory named by $TEST_REPORT_DIR. For every test it records the
+// phases the test went through (setup, apply, verify, destroy) with their
+// durations, the resources its fixtures created and whether their cleanup
+// succeeded, and the artifacts it captured, such as cassettes. CI dashboards
+// read the reports to show which stage is slow and which one leaks.
+//
+// A phase ends when the next one starts or when the test ends. The terraform
+// options returned by retryable.With, and the terraform helpers of the
+// cassette package, start the phase of each terraform command they run, see
+// TerraformPhase, so most tests mark no phase themselves. Tests mark the
+// phases terraform does not reveal, such as their setup or a verification
+// that runs no terraform command:
+//
+//	report.Phase(t, report.Setup)
+//	...
+//	terraform.InitAndApply(t, terraformOptions) // apply
+//	report.Phase(t, report.Verify)
+//	...
+//
+// The fixture package records the resources and their cleanup, and the
+// destroy phase in which the fixtures are torn down. The reports are rewritten
+// as each test ends, so a run that is interrupted keeps the tests that ended.
+package report
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/GoogleCloudPlatform/cloudnetworking-config-solutions/common_utils/naming"
+)
+
+// DirEnv is the environment variable naming the directory the reports are
+// written to. No report is written when it is unset. Use an absolute path:
+// each suite runs in its own package directory.
+const DirEnv = "TEST_REPORT_DIR"
+
+// PhaseName names a phase of a test.
+type PhaseName string
+
+// Phases of a test.
+const (
+	Setup   PhaseName = "setup"
+	Apply   PhaseName = "apply"
+	Verify  PhaseName = "verify"
+	Destroy PhaseName = "destroy"
+)
+
+// Status is the outcome of a test.
+type Status string
+
+// Statuses of a test.
+const (
+	Passed  Status = "passed"
+	Failed  Status = "failed"
+	Skipped Status = "skipped"
+)
+
+// Cleanup is the state of the cleanup of a resource.
+type Cleanup string
+
+// States of the cleanup of a resource.
+const (
+	// Pending resources were not torn down when the test ended.
+	Pending Cleanup = "pending"
+	Deleted Cleanup = "deleted"
+	Leaked  Cleanup = "leaked"
+)
+
+// Run is the report of the tests of a suite.
+type Run struct {
+	Suite    string        `json:"suite"`
+	RunID    string        `json:"runId"`
+	Started  time.Time     `json:"started"`
+	Duration time.Duration `json:"durationNs"`
+	Tests    []*Test       `json:"tests"`
+}
+
+// Test is the report of a single test or subtest.
+type Test struct {
+	Name      string        `json:"name"`
+	Status    Status        `json:"status"`
+	Started   time.Time     `json:"started"`
+	Duration  time.Duration `json:"durationNs"`
+	Phases    []*PhaseTime  `json:"phases,omitempty"`
+	Resources []*Resource   `json:"resources,omitempty"`
+	Artifacts []Artifact    `json:"artifacts,omitempty"`
+	// Properties are facts about the run of the test, such as the location
+	// it deployed to.
+	Properties map[string]string `json:"properties,omitempty"`
+
+	// running is the phase in progress, and failedBefore whether the test had
+	// failed when it started, so that a phase is only marked failed by its
+	// own failures.
+	running      *PhaseTime
+	failedBefore bool
+}
+
+// PhaseTime is a phase of a test and how long it took.
+type PhaseTime struct {
+	Name     PhaseName     `json:"name"`
+	Started  time.Time     `json:"started"`
+	Duration time.Duration `json:"durationNs"`
+	// Failed is set when the test failed during the phase.
+	Failed bool `json:"failed,omitempty"`
+}
+
+// Resource is a resource created by a fixture of the test.
+type Resource struct {
+	Kind    string  `json:"kind"`
+	ID      string  `json:"id"`
+	Cleanup Cleanup `json:"cleanup"`
+	// Error is the reason of a leak.
+	Error string `json:"error,omitempty"`
+	// CleanupDuration is the time the teardown took.
+	CleanupDuration time.Duration `json:"cleanupDurationNs,omitempty"`
+}
+
+// Artifact is a file captured by the test.
+type Artifact struct {
+	Name string `json:"name"`
+	// Path is absolute, so that it can be resolved from the report directory.
+	Path string `json:"path"`
+}
+
+var (
+	mu    sync.Mutex
+	run   *Run
+	tests = map[testing.TB]*Test{}
+)
+
+// For returns the report of t, creating it and hooking its completion into
+// t.Cleanup on first use. Cleanups run last in first out, so helpers that
+// register their own cleanups, such as the fixture package, call For first to
+// have their teardown included in the report.
+func For(t testing.TB) *Test {
+	mu.Lock()
+	defer mu.Unlock()
+	return forLocked(t)
+}
+
+func forLocked(t testing.TB) *Test {
+	if r, ok := tests[t]; ok {
+		return r
+	}
+	if run == nil {
+		run = &Run{Suite: suite(), RunID: naming.RunID(), Started: time.Now()}
+	}
+	r := &Test{Name: t.Name(), Started: time.Now()}
+	tests[t] = r
+	run.Tests = append(run.Tests, r)
+	t.Cleanup(func() {
+		mu.Lock()
+		defer mu.Unlock()
+		r.end(t)
+		delete(tests, t)
+		if err := writeLocked(os.Getenv(DirEnv)); err != nil {
+			t.Errorf("Writing the test report: %v", err)
+		}
+	})
+	return r
+}
+
+// Phase ends the current phase of t, if any, and starts phase.
+func Phase(t testing.TB, phase PhaseName) {
+	mu.Lock()
+	defer mu.Unlock()
+	r := forLocked(t)
+	now := time.Now()
+	r.endPhase(t, now)
+	r.running = &PhaseTime{Name: phase, Started: now}
+	r.failedBefore = t.Failed()
+	r.Phases = append(r.Phases, r.running)
+}
+
+// Enter starts phase unless it is the phase in progress of t, so that helpers
+// can mark the phase of every command they run without splitting it.
+func Enter(t testing.TB, phase PhaseName) {
+	mu.Lock()
+	running := forLocked(t).running
+	mu.Unlock()
+	if running == nil || running.Name != phase {
+		Phase(t, phase)
+	}
+}
+
+// TerraformPhase returns the phase a terraform subcommand, such as "apply" or
+// "output", belongs to. Subcommands that tell nothing about the phase, such as
+// "version", return false.
+func TerraformPhase(subcommand string) (PhaseName, bool) {
+	switch subcommand {
+	case "init", "plan", "apply":
+		return Apply, true
+	case "output", "show":
+		return Verify, true
+	case "destroy":
+		return Destroy, true
+	}
+	return "", false
+}
+
+// endPhase ends the phase in progress, if any. mu must be held.
+func (r *Test) endPhase(t testing.TB, now time.Time) {
+	if r.running == nil {
+		return
+	}
+	r.running.Duration = now.Sub(r.running.Started)
+	r.running.Failed = t.Failed() && !r.failedBefore
+	r.running = nil
+}
+
+// end completes r when t ends. mu must be held.
+func (r *Test) end(t testing.TB) {
+	now := time.Now()
+	r.endPhase(t, now)
+	r.Duration = now.Sub(r.Started)
+	switch {
+	case t.Skipped():
+		r.Status = Skipped
+	case t.Failed():
+		r.Status = Failed
+	default:
+		r.Status = Passed
+	}
+}
+
+// Created records a resource created for t. Fixtures call it when they
+// register the resource.
+func Created(t testing.TB, kind, id string) {
+	mu.Lock()
+	defer mu.Unlock()
+	r := forLocked(t)
+	r.Resources = append(r.Resources, &Resource{Kind: kind, ID: id, Cleanup: Pending})
+}
+
+// TornDown records the outcome of the teardown of a resource recorded by
+// Created. A nil err means the resource was deleted.
+func TornDown(t testing.TB, kind, id string, took time.Duration, err error) {
+	mu.Lock()
+	defer mu.Unlock()
+	r := forLocked(t)
+	for _, res := range r.Resources {
+		if res.Kind == kind && res.ID == id && res.Cleanup == Pending {
+			res.Cleanup, res.CleanupDuration = Deleted, took
+			if err != nil {
+				res.Cleanup, res.Error = Leaked, err.Error()
+			}
+			return
+		}
+	}
+}
+
+// AddArtifact links the file at path to the report of t.
+func AddArtifact(t testing.TB, name, path string) {
+	if abs, err := filepath.Abs(path); err == nil {
+		path = abs
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	r := forLocked(t)
+	r.Artifacts = append(r.Artifacts, Artifact{Name: name, Path: path})
+}
+
+// Set records a property of the test, replacing its previous value.
+func Set(t testing.TB, key, value string) {
+	mu.Lock()
+	defer mu.Unlock()
+	r := forLocked(t)
+	if r.Properties == nil {
+		r.Properties = map[string]string{}
+	}
+	r.Properties[key] = value
+}
+
+// Leaked returns the resources of the test whose cleanup failed or did not
+// run.
+func (r *Test) Leaked() []*Resource {
+	var leaked []*Resource
+	for _, res := range r.Resources {
+		if res.Cleanup != Deleted {
+			leaked = append(leaked, res)
+		}
+	}
+	return leaked
+}
+
+// writeLocked writes the reports of the run to dir, replacing the previous
+// ones. mu must be held.
+func writeLocked(dir string) error {
+	if dir == "" || run == nil {
+		return nil
+	}
+	run.Duration = time.Since(run.Started)
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return err
+	}
+	base := filepath.Join(dir, strings.ReplaceAll(run.Suite, "/", "_"))
+	data, err := json.MarshalIndent(run, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := writeFile(base+".json", data); err != nil {
+		return err
+	}
+	data, err = JUnit(run)
+	if err != nil {
+		return err
+	}
+	return writeFile(base+".xml", data)
+}
+
+// writeFile replaces path with data in a single rename, so that a reader never
+// sees half a report.
+func writeFile(path string, data []byte) error {
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+// suite returns the name of the suite of the running test binary: the path of
+// its package directory below the integration directory, such as
+// "security/SecurityProfile", or the name of the directory.
+func suite() string {
+	dir, err := os.Getwd()
+	if err != nil {
+		return "unknown"
+	}
+	dir = filepath.ToSlash(dir)
+	if i := strings.LastIndex(dir, "/integration/"); i >= 0 {
+		return dir[i+len("/integration/"):]
+	}
+	return filepath.Base(dir)
+}
+
+func (p *PhaseTime) String() string {
+	s := fmt.Sprintf("%s %s", p.Name, p.Duration.Round(time.Millisecond))
+	if p.Failed {
+		s += " (failed)"
+	}
+	return s
+}
@@ -0,0 +1,210 @@
+// Copyright 2026 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package report
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// fakeT captures cleanups and failures so that a test can fail it and inspect
+// the report without failing itself.
+type fakeT struct {
+	*testing.T
+	name     string
+	cleanups []func()
+	failed   bool
+	skipped  bool
+}
+
+func (f *fakeT) Name() string { return f.name }
+
+func (f *fakeT) Cleanup(fn func()) { f.cleanups = append(f.cleanups, fn) }
+
+func (f *fakeT) Errorf(format string, args ...any) {
+	f.T.Logf(format, args...)
+	f.failed = true
+}
+
+func (f *fakeT) Failed() bool { return f.failed }
+
+func (f *fakeT) Skipped() bool { return f.skipped }
+
+func (f *fakeT) finish() {
+	for i := len(f.cleanups) - 1; i >= 0; i-- {
+		f.cleanups[i]()
+	}
+}
+
+// useDir makes the tests of the run write their reports to a new directory
+// and returns the path of the JSON report.
+func useDir(t *testing.T) string {
+	dir := t.TempDir()
+	t.Setenv(DirEnv, dir)
+	mu.Lock()
+	run = nil
+	mu.Unlock()
+	t.Cleanup(func() {
+		mu.Lock()
+		run = nil
+		mu.Unlock()
+	})
+	return filepath.Join(dir, strings.ReplaceAll(suite(), "/", "_")+".json")
+}
+
+func readRun(t *testing.T, path string) *Run {
+	t.Helper()
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("Reading the report: %v", err)
+	}
+	var r Run
+	if err := json.Unmarshal(data, &r); err != nil {
+		t.Fatalf("Parsing the report: %v", err)
+	}
+	return &r
+}
+
+func TestReport(t *testing.T) {
+	path := useDir(t)
+	failing := &fakeT{T: t, name: "TestStage"}
+	Phase(failing, Setup)
+	Created(failing, "network", "vpc")
+	Created(failing, "subnetwork", "subnet")
+	Phase(failing, Apply)
+	failing.Errorf("terraform apply failed")
+	Set(failing, "location", "us-central1-b")
+	Phase(failing, Verify)
+	AddArtifact(failing, "cassette", "testdata/cassettes/TestStage.json")
+	Phase(failing, Destroy)
+	TornDown(failing, "subnetwork", "subnet", 0, nil)
+	TornDown(failing, "network", "vpc", 0, errors.New("resource in use"))
+	skipped := &fakeT{T: t, name: "TestSkipped", skipped: true}
+	For(skipped)
+
+	skipped.finish()
+	failing.finish()
+
+	r := readRun(t, path)
+	if r.Suite != "common_utils/report" {
+		t.Errorf("Suite = %v, want = %v", r.Suite, "common_utils/report")
+	}
+	var statuses []string
+	for _, test := range r.Tests {
+		statuses = append(statuses, test.Name+" "+string(test.Status))
+	}
+	if want := []string{"TestStage failed", "TestSkipped skipped"}; !reflect.DeepEqual(statuses, want) {
+		t.Errorf("Tests = %v, want = %v", statuses, want)
+	}
+	var phases []string
+	for _, p := range r.Tests[0].Phases {
+		phases = append(phases, fmt.Sprintf("%s %v", p.Name, p.Failed))
+	}
+	if want := []string{"setup false", "apply true", "verify false", "destroy false"}; !reflect.DeepEqual(phases, want) {
+		t.Errorf("Phases = %v, want = %v", phases, want)
+	}
+	var resources []string
+	for _, res := range r.Tests[0].Resources {
+		resources = append(resources, fmt.Sprintf("%s %s %s", res.ID, res.Cleanup, res.Error))
+	}
+	if want := []string{"vpc leaked resource in use", "subnet deleted "}; !reflect.DeepEqual(resources, want) {
+		t.Errorf("Resources = %q, want = %q", resources, want)
+	}
+	if got := r.Tests[0].Properties["location"]; got != "us-central1-b" {
+		t.Errorf("Property location = %v, want = %v", got, "us-central1-b")
+	}
+	if a := r.Tests[0].Artifacts; len(a) != 1 || !filepath.IsAbs(a[0].Path) {
+		t.Errorf("Artifacts = %v, want = one with an absolute path", a)
+	}
+
+	data, err := os.ReadFile(strings.TrimSuffix(path, ".json") + ".xml")
+	if err != nil {
+		t.Fatalf("Reading the JUnit report: %v", err)
+	}
+	var suites junitSuites
+	if err := xml.Unmarshal(data, &suites); err != nil {
+		t.Fatalf("Parsing the JUnit report: %v", err)
+	}
+	s := suites.Suites[0]
+	if s.Tests != 2 || s.Failures != 1 || s.Skipped != 1 {
+		t.Errorf("Suite counts = %d/%d/%d, want = 2/1/1", s.Tests, s.Failures, s.Skipped)
+	}
+	c := s.Cases[0]
+	if c.Failure == nil || c.Failure.Message != "failed during apply; 1 resource(s) not cleaned up" {
+		t.Errorf("Failure = %+v, want = failed during apply with one leak", c.Failure)
+	}
+	if !strings.Contains(c.SystemOut, "cassette: [[ATTACHMENT|/") {
+		t.Errorf("System out = %q, want = the cassette attachment", c.SystemOut)
+	}
+}
+
+func TestReportIsRewrittenAsTestsEnd(t *testing.T) {
+	path := useDir(t)
+	first := &fakeT{T: t, name: "TestFirst"}
+	second := &fakeT{T: t, name: "TestSecond"}
+	Phase(first, Verify)
+	Phase(second, Verify)
+
+	first.finish()
+
+	if r := readRun(t, path); r.Tests[0].Status != Passed || r.Tests[1].Status != "" {
+		t.Errorf("Statuses = %v %v, want = passed and none", r.Tests[0].Status, r.Tests[1].Status)
+	}
+	second.finish()
+	if r := readRun(t, path); r.Tests[1].Status != Passed {
+		t.Errorf("Status = %v, want = %v", r.Tests[1].Status, Passed)
+	}
+}
+
+func TestNoReportWithoutDir(t *testing.T) {
+	path := useDir(t)
+	t.Setenv(DirEnv, "")
+	ft := &fakeT{T: t, name: "TestStage"}
+	Phase(ft, Apply)
+
+	ft.finish()
+
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Errorf("Stat(%s) = %v, want = not exist", path, err)
+	}
+}
+
+func TestEnterKeepsThePhaseInProgress(t *testing.T) {
+	useDir(t)
+	ft := &fakeT{T: t, name: "TestStage"}
+	for _, subcommand := range []string{"init", "apply", "output", "version", "show", "destroy"} {
+		if phase, ok := TerraformPhase(subcommand); ok {
+			Enter(ft, phase)
+		}
+	}
+	Enter(ft, Destroy)
+
+	var phases []PhaseName
+	for _, p := range For(ft).Phases {
+		phases = append(phases, p.Name)
+	}
+	ft.finish()
+
+	if want := []PhaseName{Apply, Verify, Destroy}; !reflect.DeepEqual(phases, want) {
+		t.Errorf("Phases = %v, want = %v", phases, want)
+	}
+}
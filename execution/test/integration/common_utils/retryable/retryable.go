@@ -0,0 +1,264 @@
+// Copyright 2026 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package retryable is a catalog of the transient errors of Google Cloud APIs
+// that warrant running a terraform command again, grouped by service. The
+// default retryable errors of terratest only cover network failures while
+// downloading providers; resources that are not ready yet, operations still
+// in progress on a service networking connection, concurrent peering updates
+// and IAM bindings that have not propagated fail the apply instead.
+//
+// With composes the catalog into terraform.Options in place of
+// terraform.WithDefaultRetryableErrors:
+//
+//	terraformOptions := retryable.With(t, &terraform.Options{
+//		TerraformDir: terraformDirectoryPath,
+//		Vars:         tfVars,
+//	})
+//
+// or with some of its groups only:
+//
+//	retryable.With(t, options, retryable.Compute, retryable.ServiceNetworking)
+//
+// Every retry is logged with the rule that fired, and the number of times
+// each rule fired is recorded as the retry.<rule> property of the test in its
+// report. The options also start the phase of each terraform command in the
+// report, see report.TerraformPhase.
+package retryable
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/GoogleCloudPlatform/cloudnetworking-config-solutions/common_utils/report"
+	"github.com/gruntwork-io/terratest/modules/logger"
+	"github.com/gruntwork-io/terratest/modules/terraform"
+	terratesting "github.com/gruntwork-io/terratest/modules/testing"
+)
+
+// Rule is a transient error that warrants a retry.
+type Rule struct {
+	// Name identifies the rule in logs and reports, as service/error.
+	Name string
+	// Pattern is a regular expression matched against the error and the
+	// output of the command.
+	Pattern string
+	// Message explains why the error is transient.
+	Message string
+}
+
+// Transient errors of the Compute Engine API.
+var Compute = []Rule{
+	{
+		Name:    "compute/resource-not-ready",
+		Pattern: `The resource '[^']+' is not ready|resourceNotReady`,
+		Message: "A resource the request depends on is still being created or updated.",
+	},
+	{
+		Name:    "compute/peering-operation-in-progress",
+		Pattern: `peering operation in progress`,
+		Message: "Another peering of the network is being added or removed.",
+	},
+	{
+		Name:    "compute/rate-limit",
+		Pattern: `rateLimitExceeded|RATE_LIMIT_EXCEEDED|Rate Limit Exceeded`,
+		Message: "Too many requests were sent to the API in a short time.",
+	},
+	{
+		Name:    "compute/backend-error",
+		Pattern: `Error 5(00|02|03): .*(backendError|Internal error|Service Unavailable|try again)`,
+		Message: "The API returned a transient server error.",
+	},
+}
+
+// Transient errors of Service Networking, which manages the private services
+// access connections.
+var ServiceNetworking = []Rule{
+	{
+		Name:    "servicenetworking/operation-in-progress",
+		Pattern: `Operation [^ ]+ (is )?(still )?in progress|[Pp]revious operation .* is still in progress`,
+		Message: "Another operation on the service networking connection has not finished.",
+	},
+	{
+		Name:    "servicenetworking/peering-conflict",
+		Pattern: `Error 409: .*[Pp]eering|Error waiting for Create Service Networking Connection: .*409`,
+		Message: "The peering of the service networking connection is being updated concurrently.",
+	},
+	{
+		Name:    "servicenetworking/allocated-ranges",
+		Pattern: `Cannot modify allocated ranges`,
+		Message: "The allocated ranges of the connection are being updated concurrently.",
+	},
+}
+
+// Transient errors of IAM, mostly while a new service account or binding
+// propagates.
+var IAM = []Rule{
+	{
+		Name:    "iam/service-account-propagation",
+		Pattern: `[Ss]ervice account [^ ]+ does not exist`,
+		Message: "A service account created moments ago has not propagated yet.",
+	},
+	{
+		Name:    "iam/permission-propagation",
+		Pattern: `Error 403: Permission '?iam\.serviceAccounts\.(actAs|getAccessToken)'? denied|Error 403: .*does not have .*iam\.serviceAccounts\.(actAs|getAccessToken)`,
+		Message: "A role granted moments ago has not propagated yet.",
+	},
+	{
+		Name:    "iam/concurrent-policy-change",
+		Pattern: `There were concurrent policy changes`,
+		Message: "The IAM policy was changed while it was being updated.",
+	},
+}
+
+// Transient errors of Cloud SQL.
+var SQL = []Rule{
+	{
+		Name:    "sql/operation-in-progress",
+		Pattern: `another operation was already in progress|operationInProgress`,
+		Message: "Another operation on the Cloud SQL instance has not finished.",
+	},
+}
+
+// Transient errors of Google Kubernetes Engine.
+var Container = []Rule{
+	{
+		Name:    "container/cluster-operation-in-progress",
+		Pattern: `CLUSTER_ALREADY_HAS_OPERATION|incompatible operation`,
+		Message: "Another operation on the cluster has not finished.",
+	},
+}
+
+// Retries of the terraform commands run with options returned by With.
+const (
+	MaxRetries         = 6
+	TimeBetweenRetries = 15 * time.Second
+)
+
+// All returns every rule of the catalog.
+func All() []Rule {
+	var rules []Rule
+	for _, group := range [][]Rule{Compute, ServiceNetworking, IAM, SQL, Container} {
+		rules = append(rules, group...)
+	}
+	return rules
+}
+
+// Match returns the first of rules whose pattern matches text.
+func Match(text string, rules []Rule) (Rule, bool) {
+	for _, r := range rules {
+		if regexp.MustCompile(r.Pattern).MatchString(text) {
+			return r, true
+		}
+	}
+	return Rule{}, false
+}
+
+// With returns a copy of options with the default retryable errors of
+// terratest and the rules of groups, or of the whole catalog when no group is
+// given. It retries at least MaxRetries times, TimeBetweenRetries apart, so
+// that IAM changes have the minute they may take to propagate. The first call
+// installs the logger that records the retries in the reports. When t is a
+// test, it gets a report, and the options log through the same logger, which
+// starts the phase of each terraform command they run, unless they already
+// have a logger.
+func With(t terratesting.TestingT, options *terraform.Options, groups ...[]Rule) *terraform.Options {
+	installRecorder()
+	if tb, ok := t.(testing.TB); ok {
+		report.For(tb)
+	}
+	maxRetries, timeBetweenRetries := options.MaxRetries, options.TimeBetweenRetries
+	options = terraform.WithDefaultRetryableErrors(t, options)
+	if len(groups) == 0 {
+		groups = [][]Rule{All()}
+	}
+	for _, rules := range groups {
+		for _, r := range rules {
+			options.RetryableTerraformErrors[r.Pattern] = fmt.Sprintf("[retry rule %s] %s", r.Name, r.Message)
+		}
+	}
+	if options.Logger == nil {
+		options.Logger = logger.New(recorder{})
+	}
+	options.MaxRetries = max(maxRetries, MaxRetries)
+	options.TimeBetweenRetries = max(timeBetweenRetries, TimeBetweenRetries)
+	return options
+}
+
+// fired matches the rule named in the message terratest logs before a retry.
+var fired = regexp.MustCompile(`\[retry rule ([^\]]+)\]`)
+
+// running matches the message terratest logs before it runs a terraform or
+// OpenTofu command, and captures the subcommand.
+var running = regexp.MustCompile(`^Running command \S*(?:terraform|tofu) with args \[(\S+)`)
+
+var (
+	mu      sync.Mutex
+	counts  = map[testing.TB]map[string]int{}
+	install sync.Once
+)
+
+// installRecorder replaces the default logger of terratest with a recorder.
+// terratest logs the retries of terraform commands to its default logger
+// whatever the logger of the options, so it is the only place to see them.
+func installRecorder() {
+	install.Do(func() {
+		logger.Default = logger.New(recorder{})
+	})
+}
+
+// recorder logs to stdout like the default logger of terratest, counts the
+// retries it logs for each test and rule, and starts the phase of the
+// terraform commands it logs.
+type recorder struct{}
+
+func (recorder) Logf(t terratesting.TestingT, format string, args ...interface{}) {
+	msg := fmt.Sprintf(format, args...)
+	logger.DoLog(t, 3, os.Stdout, msg)
+	if tb, ok := t.(testing.TB); ok {
+		if m := fired.FindStringSubmatch(msg); m != nil {
+			count(tb, m[1])
+		}
+		if m := running.FindStringSubmatch(msg); m != nil {
+			if phase, ok := report.TerraformPhase(m[1]); ok {
+				report.Enter(tb, phase)
+			}
+		}
+	}
+}
+
+func count(t testing.TB, rule string) {
+	mu.Lock()
+	byRule, ok := counts[t]
+	if !ok {
+		byRule = map[string]int{}
+		counts[t] = byRule
+	}
+	byRule[rule]++
+	n := byRule[rule]
+	mu.Unlock()
+	if !ok {
+		t.Cleanup(func() {
+			mu.Lock()
+			delete(counts, t)
+			mu.Unlock()
+		})
+	}
+	report.Set(t, "retry."+rule, strconv.Itoa(n))
+}
@@ -0,0 +1,144 @@
+// Copyright 2026 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package retryable
+
+import (
+	"errors"
+	"reflect"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/GoogleCloudPlatform/cloudnetworking-config-solutions/common_utils/report"
+	"github.com/gruntwork-io/terratest/modules/retry"
+	"github.com/gruntwork-io/terratest/modules/terraform"
+)
+
+func TestCatalogMatches(t *testing.T) {
+	for _, tc := range []struct {
+		msg  string
+		want string
+	}{
+		{"Error: Error waiting for Creating Subnetwork: googleapi: Error 400: The resource 'projects/p/global/networks/vpc' is not ready, resourceNotReady", "compute/resource-not-ready"},
+		{"Error 400: There is a peering operation in progress on the local or peer network. Try again later., badRequest", "compute/peering-operation-in-progress"},
+		{"Error waiting for Create Service Networking Connection: Error code 9, message: Operation operations/pssn.p1-123 in progress", "servicenetworking/operation-in-progress"},
+		{"Error waiting for Create Service Networking Connection: Error code 9, message: Cannot modify allocated ranges in CreateConnection. Please use UpdateConnection.", "servicenetworking/allocated-ranges"},
+		{"googleapi: Error 409: Peering 'servicenetworking-googleapis-com' already exists in network, alreadyExists", "servicenetworking/peering-conflict"},
+		{"Error 400: Service account sa-test@p.iam.gserviceaccount.com does not exist., badRequest", "iam/service-account-propagation"},
+		{"Error 403: Permission 'iam.serviceAccounts.actAs' denied on service account sa@p.iam.gserviceaccount.com", "iam/permission-propagation"},
+		{"Error 409: There were concurrent policy changes. Please retry the whole read-modify-write with exponential backoff.", "iam/concurrent-policy-change"},
+		{"Error 409: Operation failed because another operation was already in progress., operationInProgress", "sql/operation-in-progress"},
+		{"Error 400: Cluster is running incompatible operation operation-123.", "container/cluster-operation-in-progress"},
+	} {
+		if r, ok := Match(tc.msg, All()); !ok || r.Name != tc.want {
+			t.Errorf("Match(%q) = %v, want = %v", tc.msg, r.Name, tc.want)
+		}
+	}
+	for _, msg := range []string{
+		"Error 403: Quota 'NETWORKS' exceeded. Limit: 15.0 globally.",
+		// An API that is not enabled is a setup error for preflight to catch.
+		"Error 403: Compute Engine API has not been used in project 123 before or it is disabled.",
+		"The zone 'projects/p/zones/us-central1-a' does not have enough resources available to fulfill the request.",
+		"Error 409: The resource 'projects/p/global/networks/vpc' already exists, alreadyExists",
+	} {
+		if r, ok := Match(msg, All()); ok {
+			t.Errorf("Match(%q) = %v, want = no rule", msg, r.Name)
+		}
+	}
+}
+
+func TestPatternsCompile(t *testing.T) {
+	names := map[string]bool{}
+	for _, r := range All() {
+		if _, err := regexp.Compile(r.Pattern); err != nil {
+			t.Errorf("Pattern of %s: %v", r.Name, err)
+		}
+		if names[r.Name] {
+			t.Errorf("Rule %s is in the catalog twice", r.Name)
+		}
+		names[r.Name] = true
+	}
+}
+
+func TestWith(t *testing.T) {
+	options := With(t, &terraform.Options{
+		RetryableTerraformErrors: map[string]string{"custom": "Custom error."},
+		MaxRetries:               10,
+	}, IAM)
+
+	for pattern := range terraform.DefaultRetryableTerraformErrors {
+		if _, ok := options.RetryableTerraformErrors[pattern]; !ok {
+			t.Errorf("Default retryable error %q is missing", pattern)
+		}
+	}
+	if got := options.RetryableTerraformErrors["custom"]; got != "Custom error." {
+		t.Errorf("Retryable error custom = %q, want = %q", got, "Custom error.")
+	}
+	want := "[retry rule iam/concurrent-policy-change] The IAM policy was changed while it was being updated."
+	if got := options.RetryableTerraformErrors[IAM[2].Pattern]; got != want {
+		t.Errorf("Retryable error of %s = %q, want = %q", IAM[2].Name, got, want)
+	}
+	if _, ok := options.RetryableTerraformErrors[Compute[0].Pattern]; ok {
+		t.Errorf("Retryable error of %s is set, want = only the IAM group", Compute[0].Name)
+	}
+	if options.MaxRetries != 10 || options.TimeBetweenRetries != TimeBetweenRetries {
+		t.Errorf("Retries = %d every %s, want = 10 every %s", options.MaxRetries, options.TimeBetweenRetries, TimeBetweenRetries)
+	}
+}
+
+func TestRetriesAreReported(t *testing.T) {
+	options := With(t, &terraform.Options{})
+	errs := []error{
+		errors.New("Error 400: The resource 'projects/p/global/networks/vpc' is not ready, resourceNotReady"),
+		errors.New("Error 400: The resource 'projects/p/global/networks/vpc' is not ready, resourceNotReady"),
+		errors.New("Error code 9, message: Cannot modify allocated ranges in CreateConnection."),
+	}
+
+	_, err := retry.DoWithRetryableErrorsE(t, "terraform apply", options.RetryableTerraformErrors, 5, time.Millisecond, func() (string, error) {
+		if len(errs) == 0 {
+			return "", nil
+		}
+		err := errs[0]
+		errs = errs[1:]
+		return "", err
+	})
+
+	if err != nil {
+		t.Fatalf("DoWithRetryableErrorsE() returned error: %v", err)
+	}
+	props := report.For(t).Properties
+	for rule, want := range map[string]string{"compute/resource-not-ready": "2", "servicenetworking/allocated-ranges": "1"} {
+		if got := props["retry."+rule]; got != want {
+			t.Errorf("Property retry.%s = %v, want = %v", rule, got, want)
+		}
+	}
+}
+
+func TestTerraformCommandsStartPhases(t *testing.T) {
+	options := With(t, &terraform.Options{})
+
+	options.Logger.Logf(t, "Running command %s with args %s", "terraform", []string{"init", "-upgrade=false"})
+	options.Logger.Logf(t, "Running command %s with args %s", "/usr/local/bin/tofu", []string{"apply", "-input=false", "-auto-approve"})
+	options.Logger.Logf(t, "Apply complete! Resources: 1 added, 0 changed, 0 destroyed.")
+	options.Logger.Logf(t, "Running command %s with args %s", "terraform", []string{"output", "-no-color", "-json", "id"})
+
+	var phases []report.PhaseName
+	for _, p := range report.For(t).Phases {
+		phases = append(phases, p.Name)
+	}
+	if want := []report.PhaseName{report.Apply, report.Verify}; !reflect.DeepEqual(phases, want) {
+		t.Errorf("Phases = %v, want = %v", phases, want)
+	}
+}
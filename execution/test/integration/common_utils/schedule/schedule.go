@@ -0,0 +1,314 @@
+// Copyright 2026 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package schedule runs integration suites concurrently within the quota of
+// the shared test project. Each suite declares its footprint in a
+// footprint.yaml file next to its tests: the compute quota it holds at its
+// peak and the organization level objects it changes, such as the firewall
+// policy association of the organization:
+//
+//	quotas:
+//	  - NETWORKS=1
+//	  - FORWARDING_RULES=3
+//	  - CPUS=4
+//	singletons:
+//	  - org-firewall-policy-association
+//
+// Quotas take the METRIC[@REGION]=AMOUNT form of the preflight package. A
+// suite starts once the quota held by the running suites plus its own stays
+// under every ceiling and no running suite holds one of its singletons. A
+// suite without a footprint runs alone.
+package schedule
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/GoogleCloudPlatform/cloudnetworking-config-solutions/common_utils/preflight"
+	"github.com/GoogleCloudPlatform/cloudnetworking-config-solutions/common_utils/schema"
+)
+
+// FootprintFile is the name of the footprint file of a suite.
+const FootprintFile = "footprint.yaml"
+
+// Footprint is the layout of a footprint file.
+type Footprint struct {
+	// Quotas are METRIC[@REGION]=AMOUNT, such as FORWARDING_RULES=3.
+	Quotas []string `yaml:"quotas"`
+	// Singletons name the organization level objects the suite changes.
+	// Suites sharing one never run at the same time.
+	Singletons []string `yaml:"singletons"`
+}
+
+// Suite is a package of integration tests.
+type Suite struct {
+	// Dir is the directory of the package relative to the root it was found
+	// in, with forward slashes, such as "security/SecurityProfile".
+	Dir string
+	// Needs is the quota the suite holds at its peak, by METRIC[@REGION].
+	Needs      map[string]float64
+	Singletons []string
+	// Exclusive suites run alone. It is set for suites without a footprint.
+	Exclusive bool
+}
+
+// Discover returns the suites below root in lexical order: the directories
+// holding _test.go files, except those of common_utils.
+func Discover(root string) ([]Suite, error) {
+	dirs := map[string]bool{}
+	err := filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() && (d.Name() == "common_utils" || d.Name() == "testdata" || strings.HasPrefix(d.Name(), ".")) && path != root {
+			return filepath.SkipDir
+		}
+		if !d.IsDir() && strings.HasSuffix(d.Name(), "_test.go") {
+			dirs[filepath.Dir(path)] = true
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	var suites []Suite
+	for dir := range dirs {
+		rel, err := filepath.Rel(root, dir)
+		if err != nil {
+			return nil, err
+		}
+		s, err := Load(root, filepath.ToSlash(rel))
+		if err != nil {
+			return nil, err
+		}
+		suites = append(suites, s)
+	}
+	sort.Slice(suites, func(i, j int) bool { return suites[i].Dir < suites[j].Dir })
+	return suites, nil
+}
+
+// Load reads the footprint of the suite in dir, relative to root. A suite
+// without a footprint file is exclusive.
+func Load(root, dir string) (Suite, error) {
+	s := Suite{Dir: dir, Needs: map[string]float64{}}
+	var fp Footprint
+	err := schema.Load(filepath.Join(root, filepath.FromSlash(dir), FootprintFile), &fp)
+	if errors.Is(err, fs.ErrNotExist) {
+		s.Exclusive = true
+		return s, nil
+	}
+	if err != nil {
+		return s, fmt.Errorf("suite %s: %w", dir, err)
+	}
+	for _, q := range fp.Quotas {
+		quota, err := preflight.ParseQuota(q)
+		if err != nil {
+			return s, fmt.Errorf("suite %s: %s: %w", dir, FootprintFile, err)
+		}
+		s.Needs[Key(quota)] += quota.Need
+	}
+	s.Singletons = fp.Singletons
+	return s, nil
+}
+
+// Key returns the METRIC[@REGION] a quota is accounted under.
+func Key(q preflight.Quota) string {
+	if q.Region == "" {
+		return q.Metric
+	}
+	return q.Metric + "@" + q.Region
+}
+
+// Result is the outcome of a suite.
+type Result struct {
+	Suite Suite
+	// Err is the error of the run, or why the suite did not start.
+	Err error
+	// Waited is the time the suite waited for quota or a singleton.
+	Waited   time.Duration
+	Started  time.Time
+	Duration time.Duration
+}
+
+// Scheduler runs suites within quota ceilings.
+type Scheduler struct {
+	// Ceilings bound the quota held by the running suites, by
+	// METRIC[@REGION]. Metrics without a ceiling are not limited.
+	Ceilings map[string]float64
+	// Parallel bounds the number of running suites if set.
+	Parallel int
+	// Run runs a suite.
+	Run func(ctx context.Context, s Suite) error
+	// Logf reports the start and end of each suite if set.
+	Logf func(format string, args ...any)
+}
+
+// RunAll runs the suites and returns their results in the order of suites.
+// Suites start in that order as soon as they fit, so a small suite may
+// start before a larger one that is waiting for quota. A suite that needs
+// more than a ceiling on its own does not run. Once ctx is done no suite
+// starts.
+func (s *Scheduler) RunAll(ctx context.Context, suites []Suite) []Result {
+	results := make([]Result, len(suites))
+	var pending []int
+	for i, suite := range suites {
+		results[i].Suite = suite
+		if err := s.fitsAlone(suite); err != nil {
+			results[i].Err = err
+			continue
+		}
+		pending = append(pending, i)
+	}
+	type done struct {
+		i   int
+		err error
+	}
+	finished := make(chan done)
+	used := map[string]float64{}
+	held := map[string]bool{}
+	running, exclusive := 0, false
+	start := time.Now()
+	for len(pending) > 0 || running > 0 {
+		if ctx.Err() == nil {
+			for k := 0; k < len(pending); k++ {
+				i := pending[k]
+				suite := suites[i]
+				if !s.fits(suite, used, held, running, exclusive) {
+					continue
+				}
+				pending = append(pending[:k], pending[k+1:]...)
+				k--
+				for key, need := range suite.Needs {
+					used[key] += need
+				}
+				for _, name := range suite.Singletons {
+					held[name] = true
+				}
+				running++
+				exclusive = suite.Exclusive
+				results[i].Started = time.Now()
+				results[i].Waited = results[i].Started.Sub(start)
+				s.logf("Starting %s (%d running)", suite.Dir, running)
+				go func() {
+					finished <- done{i, s.Run(ctx, suite)}
+				}()
+			}
+		} else {
+			for _, i := range pending {
+				results[i].Err = ctx.Err()
+			}
+			pending = nil
+		}
+		if running == 0 {
+			continue
+		}
+		d := <-finished
+		r := &results[d.i]
+		r.Err, r.Duration = d.err, time.Since(r.Started)
+		for key, need := range r.Suite.Needs {
+			used[key] -= need
+		}
+		for _, name := range r.Suite.Singletons {
+			delete(held, name)
+		}
+		running--
+		exclusive = false
+		status := "passed"
+		if d.err != nil {
+			status = "failed: " + d.err.Error()
+		}
+		s.logf("Finished %s in %s, %s", r.Suite.Dir, r.Duration.Round(time.Second), status)
+	}
+	return results
+}
+
+// fitsAlone returns an error when suite needs more than a ceiling.
+func (s *Scheduler) fitsAlone(suite Suite) error {
+	for _, key := range sortedKeys(suite.Needs) {
+		if ceiling, ok := s.Ceilings[key]; ok && suite.Needs[key] > ceiling {
+			return fmt.Errorf("needs %g %s, more than the ceiling of %g", suite.Needs[key], key, ceiling)
+		}
+	}
+	return nil
+}
+
+// fits reports whether suite can start next to the running suites.
+func (s *Scheduler) fits(suite Suite, used map[string]float64, held map[string]bool, running int, exclusive bool) bool {
+	if exclusive || (suite.Exclusive && running > 0) || (s.Parallel > 0 && running >= s.Parallel) {
+		return false
+	}
+	for key, need := range suite.Needs {
+		if ceiling, ok := s.Ceilings[key]; ok && used[key]+need > ceiling {
+			return false
+		}
+	}
+	for _, name := range suite.Singletons {
+		if held[name] {
+			return false
+		}
+	}
+	return true
+}
+
+func (s *Scheduler) logf(format string, args ...any) {
+	if s.Logf != nil {
+		s.Logf(format, args...)
+	}
+}
+
+// Summary formats results as a table, one suite per line.
+func Summary(results []Result) string {
+	var b strings.Builder
+	failed := 0
+	for _, r := range results {
+		status := "ok  "
+		if r.Err != nil {
+			status = "FAIL"
+			failed++
+		}
+		fmt.Fprintf(&b, "%s %-55s waited %8s ran %8s", status, r.Suite.Dir, r.Waited.Round(time.Second), r.Duration.Round(time.Second))
+		if r.Err != nil {
+			fmt.Fprintf(&b, "  %v", r.Err)
+		}
+		b.WriteString("\n")
+	}
+	fmt.Fprintf(&b, "%d suite(s), %d failed\n", len(results), failed)
+	return b.String()
+}
+
+// Keys returns the quota keys the suites need, in lexical order.
+func Keys(suites []Suite) []string {
+	all := map[string]float64{}
+	for _, s := range suites {
+		for key := range s.Needs {
+			all[key] = 0
+		}
+	}
+	return sortedKeys(all)
+}
+
+func sortedKeys(m map[string]float64) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,187 @@
+// Copyright 2026 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package schedule
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func write(t *testing.T, path, content string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestDiscover(t *testing.T) {
+	root := t.TempDir()
+	write(t, filepath.Join(root, "networking", "networking_test.go"), "package networking\n")
+	write(t, filepath.Join(root, "networking", FootprintFile), "quotas: [NETWORKS=2, ROUTERS=2]\n")
+	write(t, filepath.Join(root, "security", "SecurityProfile", "sp_test.go"), "package sp\n")
+	write(t, filepath.Join(root, "security", "SecurityProfile", FootprintFile),
+		"quotas: [NETWORKS, CPUS@us-central1=2]\nsingletons: [org-firewall-policy-association]\n")
+	write(t, filepath.Join(root, "producer", "GKE", "gke_test.go"), "package gke\n")
+	write(t, filepath.Join(root, "common_utils", "wait", "wait_test.go"), "package wait\n")
+	write(t, filepath.Join(root, "security", "config", "instance.yaml"), "name: x\n")
+
+	suites, err := Discover(root)
+	if err != nil {
+		t.Fatalf("Discover() returned error: %v", err)
+	}
+	want := []Suite{
+		{Dir: "networking", Needs: map[string]float64{"NETWORKS": 2, "ROUTERS": 2}},
+		{Dir: "producer/GKE", Needs: map[string]float64{}, Exclusive: true},
+		{Dir: "security/SecurityProfile", Needs: map[string]float64{"NETWORKS": 1, "CPUS@us-central1": 2}, Singletons: []string{"org-firewall-policy-association"}},
+	}
+	if !reflect.DeepEqual(suites, want) {
+		t.Errorf("Discover() = %+v, want = %+v", suites, want)
+	}
+}
+
+func TestLoadRejectsInvalidFootprint(t *testing.T) {
+	root := t.TempDir()
+	write(t, filepath.Join(root, "a", FootprintFile), "quotas: [NETWORKS=-1]\n")
+	write(t, filepath.Join(root, "b", FootprintFile), "quota: [NETWORKS=1]\n")
+	for _, dir := range []string{"a", "b"} {
+		if _, err := Load(root, dir); err == nil {
+			t.Errorf("Load(%s) returned no error, want = one", dir)
+		}
+	}
+}
+
+// recorder runs suites that block until released and records the suites
+// running together.
+type recorder struct {
+	mu      sync.Mutex
+	running map[string]bool
+	overlap map[string]bool
+	started chan string
+	release map[string]chan struct{}
+}
+
+func newRecorder(suites []Suite) *recorder {
+	r := &recorder{running: map[string]bool{}, overlap: map[string]bool{}, started: make(chan string, len(suites)), release: map[string]chan struct{}{}}
+	for _, s := range suites {
+		r.release[s.Dir] = make(chan struct{})
+	}
+	return r
+}
+
+func (r *recorder) run(ctx context.Context, s Suite) error {
+	r.mu.Lock()
+	for other := range r.running {
+		pair := []string{other, s.Dir}
+		if pair[0] > pair[1] {
+			pair[0], pair[1] = pair[1], pair[0]
+		}
+		r.overlap[strings.Join(pair, "+")] = true
+	}
+	r.running[s.Dir] = true
+	r.mu.Unlock()
+	r.started <- s.Dir
+	<-r.release[s.Dir]
+	r.mu.Lock()
+	delete(r.running, s.Dir)
+	r.mu.Unlock()
+	return nil
+}
+
+func TestRunAllKeepsQuotaUnderCeilings(t *testing.T) {
+	suites := []Suite{
+		{Dir: "lb", Needs: map[string]float64{"FORWARDING_RULES": 3, "NETWORKS": 1}},
+		{Dir: "psc", Needs: map[string]float64{"FORWARDING_RULES": 2, "NETWORKS": 1}},
+		{Dir: "gce", Needs: map[string]float64{"NETWORKS": 1, "CPUS": 2}},
+		{Dir: "sp", Needs: map[string]float64{"NETWORKS": 1}, Singletons: []string{"org"}},
+		{Dir: "fwe", Needs: map[string]float64{"NETWORKS": 1}, Singletons: []string{"org"}},
+		{Dir: "unknown", Needs: map[string]float64{}, Exclusive: true},
+		{Dir: "huge", Needs: map[string]float64{"CPUS": 100}},
+	}
+	r := newRecorder(suites)
+	s := &Scheduler{Ceilings: map[string]float64{"FORWARDING_RULES": 4, "NETWORKS": 3, "CPUS": 8}, Run: r.run}
+	results := make(chan []Result)
+	go func() { results <- s.RunAll(context.Background(), suites) }()
+
+	// lb, gce and sp fit: psc would exceed the forwarding rules and fwe
+	// shares the singleton of sp.
+	got := []string{<-r.started, <-r.started, <-r.started}
+	if want := []string{"gce", "lb", "sp"}; !sameSet(got, want) {
+		t.Fatalf("First suites = %v, want = %v", got, want)
+	}
+	close(r.release["lb"])
+	if got := <-r.started; got != "psc" {
+		t.Fatalf("Suite after lb = %v, want = psc", got)
+	}
+	close(r.release["sp"])
+	if got := <-r.started; got != "fwe" {
+		t.Fatalf("Suite after sp = %v, want = fwe", got)
+	}
+	for _, dir := range []string{"gce", "psc", "fwe"} {
+		close(r.release[dir])
+	}
+	if got := <-r.started; got != "unknown" {
+		t.Fatalf("Last suite = %v, want = unknown", got)
+	}
+	close(r.release["unknown"])
+
+	var errs []string
+	for _, res := range <-results {
+		if res.Err != nil {
+			errs = append(errs, res.Suite.Dir+": "+res.Err.Error())
+		}
+	}
+	if want := []string{"huge: needs 100 CPUS, more than the ceiling of 8"}; !reflect.DeepEqual(errs, want) {
+		t.Errorf("Errors = %v, want = %v", errs, want)
+	}
+	for pair := range r.overlap {
+		if strings.Contains(pair, "unknown") || pair == "fwe+sp" {
+			t.Errorf("Suites %s ran together", pair)
+		}
+	}
+}
+
+func TestRunAllStopsStartingWhenCanceled(t *testing.T) {
+	suites := []Suite{
+		{Dir: "a", Needs: map[string]float64{"NETWORKS": 1}},
+		{Dir: "b", Needs: map[string]float64{"NETWORKS": 1}},
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	s := &Scheduler{Ceilings: map[string]float64{"NETWORKS": 1}, Run: func(context.Context, Suite) error {
+		cancel()
+		return nil
+	}}
+
+	results := s.RunAll(ctx, suites)
+
+	if results[0].Err != nil || results[1].Err != context.Canceled {
+		t.Errorf("Errors = %v, %v, want = nil, %v", results[0].Err, results[1].Err, context.Canceled)
+	}
+}
+
+func sameSet(a, b []string) bool {
+	a, b = append([]string(nil), a...), append([]string(nil), b...)
+	sort.Strings(a)
+	sort.Strings(b)
+	return reflect.DeepEqual(a, b)
+}
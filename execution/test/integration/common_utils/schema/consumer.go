@@ -0,0 +1,423 @@
+// Copyright 2026 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package schema
+
+// GCE is a file of 06-consumer/GCE.
+type GCE struct {
+	Name       string `yaml:"name"`
+	ProjectID  string `yaml:"project_id"`
+	Region     string `yaml:"region"`
+	Zone       string `yaml:"zone"`
+	Network    string `yaml:"network"`
+	Subnetwork string `yaml:"subnetwork"`
+
+	Image                     string                 `yaml:"image,omitempty"`
+	InstanceType              string                 `yaml:"instance_type,omitempty"`
+	Description               string                 `yaml:"description,omitempty"`
+	Hostname                  string                 `yaml:"hostname,omitempty"`
+	Labels                    map[string]string      `yaml:"labels,omitempty"`
+	Metadata                  map[string]string      `yaml:"metadata,omitempty"`
+	Tags                      []string               `yaml:"tags,omitempty"`
+	TagBindings               map[string]string      `yaml:"tag_bindings,omitempty"`
+	MinCPUPlatform            string                 `yaml:"min_cpu_platform,omitempty"`
+	CanIPForward              *bool                  `yaml:"can_ip_forward,omitempty"`
+	EnableDisplay             *bool                  `yaml:"enable_display,omitempty"`
+	NetworkAttachedInterfaces []string               `yaml:"network_attached_interfaces,omitempty"`
+	ServiceAccount            *GCEServiceAccount     `yaml:"service_account,omitempty"`
+	BootDisk                  *GCEBootDisk           `yaml:"boot_disk,omitempty"`
+	AttachedDisks             []interface{}          `yaml:"attached_disks,omitempty"`
+	ScratchDisks              map[string]interface{} `yaml:"scratch_disks,omitempty"`
+	SnapshotSchedules         map[string]interface{} `yaml:"snapshot_schedules,omitempty"`
+	ShieldedConfig            map[string]interface{} `yaml:"shielded_config,omitempty"`
+	Options                   map[string]interface{} `yaml:"options,omitempty"`
+}
+
+// GCEServiceAccount is the service account of a GCE instance.
+type GCEServiceAccount struct {
+	AutoCreate *bool    `yaml:"auto_create,omitempty"`
+	Email      string   `yaml:"email,omitempty"`
+	Scopes     []string `yaml:"scopes,omitempty"`
+}
+
+// GCEBootDisk is the boot disk of a GCE instance.
+type GCEBootDisk struct {
+	AutoDelete         *bool  `yaml:"auto_delete,omitempty"`
+	SnapshotSchedule   string `yaml:"snapshot_schedule,omitempty"`
+	Source             string `yaml:"source,omitempty"`
+	UseIndependentDisk *bool  `yaml:"use_independent_disk,omitempty"`
+	InitializeParams   *struct {
+		Size *int   `yaml:"size,omitempty"`
+		Type string `yaml:"type,omitempty"`
+	} `yaml:"initialize_params,omitempty"`
+}
+
+// MIG is a file of 06-consumer/MIG. The instances are created from a
+// template attached to the subnetwork SubnetworkName of the network VPCName.
+type MIG struct {
+	Name           string `yaml:"name"`
+	ProjectID      string `yaml:"project_id"`
+	Location       string `yaml:"location"`
+	Zone           string `yaml:"zone"`
+	VPCName        string `yaml:"vpc_name"`
+	SubnetworkName string `yaml:"subnetwork_name"`
+
+	Description         string                 `yaml:"description,omitempty"`
+	TargetSize          *int                   `yaml:"target_size,omitempty"`
+	NamedPorts          map[string]int         `yaml:"named_ports,omitempty"`
+	AutoscalerConfig    *MIGAutoscalerConfig   `yaml:"autoscaler_config,omitempty"`
+	HealthCheckConfig   map[string]interface{} `yaml:"health_check_config,omitempty"`
+	AutoHealingPolicies map[string]interface{} `yaml:"auto_healing_policies,omitempty"`
+	DistributionPolicy  map[string]interface{} `yaml:"distribution_policy,omitempty"`
+}
+
+// MIGAutoscalerConfig is the autoscaler of a managed instance group.
+type MIGAutoscalerConfig struct {
+	MaxReplicas    *int `yaml:"max_replicas,omitempty"`
+	MinReplicas    *int `yaml:"min_replicas,omitempty"`
+	CooldownPeriod *int `yaml:"cooldown_period,omitempty"`
+	ScalingSignals *struct {
+		CPUUtilization *struct {
+			Target               *float64 `yaml:"target,omitempty"`
+			OptimizeAvailability *bool    `yaml:"optimize_availability,omitempty"`
+		} `yaml:"cpu_utilization,omitempty"`
+	} `yaml:"scaling_signals,omitempty"`
+}
+
+// UMIG is a file of 06-consumer/UMIG, an unmanaged instance group.
+type UMIG struct {
+	Name        string      `yaml:"name"`
+	ProjectID   string      `yaml:"project_id"`
+	Zone        string      `yaml:"zone"`
+	Network     string      `yaml:"network"`
+	Description string      `yaml:"description,omitempty"`
+	Instances   []string    `yaml:"instances"`
+	NamedPorts  []NamedPort `yaml:"named_ports,omitempty"`
+}
+
+// NamedPort maps a port name to a port number on an instance group.
+type NamedPort struct {
+	Name string `yaml:"name"`
+	Port int    `yaml:"port"`
+}
+
+// Workbench is a file of 06-consumer/Workbench.
+type Workbench struct {
+	Name      string            `yaml:"name"`
+	ProjectID string            `yaml:"project_id"`
+	Location  string            `yaml:"location"`
+	GCESetup  WorkbenchGCESetup `yaml:"gce_setup"`
+}
+
+// WorkbenchGCESetup is the VM of a Workbench instance. Only the first data
+// disk and network interface are used.
+type WorkbenchGCESetup struct {
+	NetworkInterfaces []WorkbenchNetworkInterface `yaml:"network_interfaces"`
+
+	MachineType        string                    `yaml:"machine_type,omitempty"`
+	ServiceAccounts    []WorkbenchServiceAccount `yaml:"service_accounts,omitempty"`
+	Metadata           map[string]string         `yaml:"metadata,omitempty"`
+	InstanceOwners     []string                  `yaml:"instance_owners,omitempty"`
+	Labels             map[string]string         `yaml:"labels,omitempty"`
+	Tags               []string                  `yaml:"tags,omitempty"`
+	VMImage            *WorkbenchVMImage         `yaml:"vm_image,omitempty"`
+	BootDiskType       string                    `yaml:"boot_disk_type,omitempty"`
+	BootDiskSizeGB     *int                      `yaml:"boot_disk_size_gb,omitempty"`
+	DataDisks          []WorkbenchDataDisk       `yaml:"data_disks,omitempty"`
+	DisablePublicIP    *bool                     `yaml:"disable_public_ip,omitempty"`
+	DisableProxyAccess *bool                     `yaml:"disable_proxy_access,omitempty"`
+
+	EnableSecureBoot          *bool `yaml:"enable_secure_boot,omitempty"`
+	EnableVTPM                *bool `yaml:"enable_vtpm,omitempty"`
+	EnableIntegrityMonitoring *bool `yaml:"enable_integrity_monitoring,omitempty"`
+
+	// The keys below are set by the expanded example but not read by the
+	// stage, which expects service_accounts, tags and the flat enable_*
+	// keys above instead.
+	ServiceAccount         string                       `yaml:"service_account,omitempty"`
+	NetworkTags            []string                     `yaml:"network_tags,omitempty"`
+	ShieldedInstanceConfig map[string]bool              `yaml:"shielded_instance_config,omitempty"`
+	AcceleratorConfigs     []WorkbenchAcceleratorConfig `yaml:"accelerator_configs,omitempty"`
+	InstallGPUDriver       *bool                        `yaml:"install_gpu_driver,omitempty"`
+	CustomGPUDriverPath    string                       `yaml:"custom_gpu_driver_path,omitempty"`
+}
+
+// WorkbenchNetworkInterface attaches a Workbench instance to a subnetwork.
+type WorkbenchNetworkInterface struct {
+	Network        string `yaml:"network"`
+	Subnet         string `yaml:"subnet"`
+	NICType        string `yaml:"nic_type,omitempty"`
+	InternalIPOnly *bool  `yaml:"internal_ip_only,omitempty"`
+}
+
+// WorkbenchServiceAccount is a service account of a Workbench instance.
+type WorkbenchServiceAccount struct {
+	Email  string   `yaml:"email"`
+	Scopes []string `yaml:"scopes,omitempty"`
+}
+
+// WorkbenchVMImage selects the image of a Workbench instance.
+type WorkbenchVMImage struct {
+	Project string `yaml:"project,omitempty"`
+	Family  string `yaml:"family,omitempty"`
+	Name    string `yaml:"name,omitempty"`
+}
+
+// WorkbenchDataDisk is a data disk of a Workbench instance.
+type WorkbenchDataDisk struct {
+	DiskSizeGB     *int   `yaml:"disk_size_gb,omitempty"`
+	DiskType       string `yaml:"disk_type,omitempty"`
+	DiskEncryption string `yaml:"disk_encryption,omitempty"`
+}
+
+// WorkbenchAcceleratorConfig is a GPU of a Workbench instance.
+type WorkbenchAcceleratorConfig struct {
+	Type      string `yaml:"type"`
+	CoreCount *int   `yaml:"core_count,omitempty"`
+}
+
+// AppEngineStandard is a file of 06-consumer/Serverless/AppEngine/Standard.
+type AppEngineStandard struct {
+	ProjectID string `yaml:"project_id"`
+	Service   string `yaml:"service"`
+	VersionID string `yaml:"version_id,omitempty"`
+	Runtime   string `yaml:"runtime"`
+
+	Deployment        *AppEngineDeployment   `yaml:"deployment,omitempty"`
+	Entrypoint        *AppEngineEntrypoint   `yaml:"entrypoint,omitempty"`
+	InstanceClass     string                 `yaml:"instance_class,omitempty"`
+	AutomaticScaling  map[string]interface{} `yaml:"automatic_scaling,omitempty"`
+	BasicScaling      map[string]interface{} `yaml:"basic_scaling,omitempty"`
+	ManualScaling     *AppEngineManual       `yaml:"manual_scaling,omitempty"`
+	Handlers          []AppEngineHandler     `yaml:"handlers,omitempty"`
+	Libraries         []map[string]string    `yaml:"libraries,omitempty"`
+	EnvVariables      map[string]string      `yaml:"env_variables,omitempty"`
+	InboundServices   []string               `yaml:"inbound_services,omitempty"`
+	Labels            map[string]string      `yaml:"labels,omitempty"`
+	ServiceAccount    string                 `yaml:"service_account,omitempty"`
+	RuntimeAPIVersion string                 `yaml:"runtime_api_version,omitempty"`
+	Threadsafe        *bool                  `yaml:"threadsafe,omitempty"`
+	AppEngineAPIs     *bool                  `yaml:"app_engine_apis,omitempty"`
+
+	DeleteServiceOnDestroy *bool `yaml:"delete_service_on_destroy,omitempty"`
+	NoopOnDestroy          *bool `yaml:"noop_on_destroy,omitempty"`
+
+	CreateAppEngineApplication *bool                 `yaml:"create_app_engine_application,omitempty"`
+	AppEngineApplication       *AppEngineApplication `yaml:"app_engine_application,omitempty"`
+	CreateAppVersion           *bool                 `yaml:"create_app_version,omitempty"`
+
+	VPCAccessConnector  *AppEngineVPCAccessConnector `yaml:"vpc_access_connector,omitempty"`
+	CreateVPCConnector  *bool                        `yaml:"create_vpc_connector,omitempty"`
+	VPCConnectorDetails map[string]interface{}       `yaml:"vpc_connector_details,omitempty"`
+
+	CreateDispatchRules   *bool                    `yaml:"create_dispatch_rules,omitempty"`
+	DispatchRules         []map[string]string      `yaml:"dispatch_rules,omitempty"`
+	CreateDomainMappings  *bool                    `yaml:"create_domain_mappings,omitempty"`
+	DomainMappings        []map[string]interface{} `yaml:"domain_mappings,omitempty"`
+	CreateFirewallRules   *bool                    `yaml:"create_firewall_rules,omitempty"`
+	FirewallRules         []map[string]interface{} `yaml:"firewall_rules,omitempty"`
+	CreateNetworkSettings *bool                    `yaml:"create_network_settings,omitempty"`
+	NetworkSettings       map[string]interface{}   `yaml:"network_settings,omitempty"`
+	CreateSplitTraffic    *bool                    `yaml:"create_split_traffic,omitempty"`
+	SplitTraffic          map[string]interface{}   `yaml:"split_traffic,omitempty"`
+}
+
+// AppEngineFlexible is a file of 06-consumer/Serverless/AppEngine/Flexible.
+type AppEngineFlexible struct {
+	ProjectID string `yaml:"project_id"`
+	Service   string `yaml:"service"`
+	Runtime   string `yaml:"runtime"`
+	VersionID string `yaml:"version_id,omitempty"`
+
+	FlexibleRuntimeSettings *AppEngineRuntimeSettings `yaml:"flexible_runtime_settings,omitempty"`
+	InstanceClass           string                    `yaml:"instance_class,omitempty"`
+	Network                 *AppEngineNetwork         `yaml:"network,omitempty"`
+	Resources               map[string]interface{}    `yaml:"resources,omitempty"`
+	Entrypoint              *AppEngineEntrypoint      `yaml:"entrypoint,omitempty"`
+	AutomaticScaling        map[string]interface{}    `yaml:"automatic_scaling,omitempty"`
+	ManualScaling           *AppEngineManual          `yaml:"manual_scaling,omitempty"`
+	EnvVariables            map[string]string         `yaml:"env_variables,omitempty"`
+	Deployment              *AppEngineDeployment      `yaml:"deployment,omitempty"`
+	// LivenessCheck and ReadinessCheck are required by the stage.
+	LivenessCheck  map[string]interface{} `yaml:"liveness_check"`
+	ReadinessCheck map[string]interface{} `yaml:"readiness_check"`
+
+	ServiceAccount            string                 `yaml:"service_account,omitempty"`
+	EndpointsAPIService       map[string]interface{} `yaml:"endpoints_api_service,omitempty"`
+	NobuildFilesRegex         string                 `yaml:"nobuild_files_regex,omitempty"`
+	BetaSettings              map[string]string      `yaml:"beta_settings,omitempty"`
+	InboundServices           []string               `yaml:"inbound_services,omitempty"`
+	Labels                    map[string]string      `yaml:"labels,omitempty"`
+	ServingStatus             string                 `yaml:"serving_status,omitempty"`
+	RuntimeAPIVersion         string                 `yaml:"runtime_api_version,omitempty"`
+	RuntimeChannel            string                 `yaml:"runtime_channel,omitempty"`
+	RuntimeMainExecutablePath string                 `yaml:"runtime_main_executable_path,omitempty"`
+	DeleteServiceOnDestroy    *bool                  `yaml:"delete_service_on_destroy,omitempty"`
+	NoopOnDestroy             *bool                  `yaml:"noop_on_destroy,omitempty"`
+
+	CreateApplication     *bool                    `yaml:"create_application,omitempty"`
+	AppEngineApplication  *AppEngineApplication    `yaml:"app_engine_application,omitempty"`
+	CreateDispatchRules   *bool                    `yaml:"create_dispatch_rules,omitempty"`
+	DispatchRules         []map[string]string      `yaml:"dispatch_rules,omitempty"`
+	CreateDomainMappings  *bool                    `yaml:"create_domain_mappings,omitempty"`
+	DomainMappings        []map[string]interface{} `yaml:"domain_mappings,omitempty"`
+	CreateFirewallRules   *bool                    `yaml:"create_firewall_rules,omitempty"`
+	FirewallRules         []map[string]interface{} `yaml:"firewall_rules,omitempty"`
+	CreateNetworkSettings *bool                    `yaml:"create_network_settings,omitempty"`
+	NetworkSettings       map[string]interface{}   `yaml:"network_settings,omitempty"`
+	CreateSplitTraffic    *bool                    `yaml:"create_split_traffic,omitempty"`
+	SplitTraffic          map[string]interface{}   `yaml:"split_traffic,omitempty"`
+}
+
+// AppEngineRuntimeSettings selects the operating system and runtime version
+// of an App Engine Flexible version.
+type AppEngineRuntimeSettings struct {
+	OperatingSystem string `yaml:"operating_system,omitempty"`
+	RuntimeVersion  string `yaml:"runtime_version,omitempty"`
+}
+
+// AppEngineNetwork attaches the instances of an App Engine Flexible version
+// to a network.
+type AppEngineNetwork struct {
+	Name            string   `yaml:"name"`
+	Subnetwork      string   `yaml:"subnetwork,omitempty"`
+	ForwardedPorts  []string `yaml:"forwarded_ports,omitempty"`
+	InstanceTag     string   `yaml:"instance_tag,omitempty"`
+	SessionAffinity *bool    `yaml:"session_affinity,omitempty"`
+}
+
+// AppEngineApplication is the App Engine application of the project, which
+// can only be created once.
+type AppEngineApplication struct {
+	LocationID      string                 `yaml:"location_id"`
+	AuthDomain      string                 `yaml:"auth_domain,omitempty"`
+	DatabaseType    string                 `yaml:"database_type,omitempty"`
+	ServingStatus   string                 `yaml:"serving_status,omitempty"`
+	FeatureSettings map[string]interface{} `yaml:"feature_settings,omitempty"`
+	IAP             map[string]interface{} `yaml:"iap,omitempty"`
+}
+
+// AppEngineDeployment is where the code of an App Engine version comes from.
+type AppEngineDeployment struct {
+	Files     *AppEngineFiles     `yaml:"files,omitempty"`
+	Zip       *AppEngineZip       `yaml:"zip,omitempty"`
+	Container *AppEngineContainer `yaml:"container,omitempty"`
+}
+
+// AppEngineFiles deploys a file from Cloud Storage.
+type AppEngineFiles struct {
+	Name      string `yaml:"name"`
+	SourceURL string `yaml:"source_url"`
+	SHA1Sum   string `yaml:"sha1_sum,omitempty"`
+}
+
+// AppEngineZip deploys a zip archive from Cloud Storage.
+type AppEngineZip struct {
+	SourceURL  string `yaml:"source_url"`
+	FilesCount *int   `yaml:"files_count,omitempty"`
+}
+
+// AppEngineContainer deploys a container image.
+type AppEngineContainer struct {
+	Image string `yaml:"image"`
+}
+
+// AppEngineEntrypoint is the command that starts an App Engine version.
+type AppEngineEntrypoint struct {
+	Shell string `yaml:"shell"`
+}
+
+// AppEngineManual fixes the number of instances of an App Engine version.
+type AppEngineManual struct {
+	Instances int `yaml:"instances"`
+}
+
+// AppEngineHandler routes URLs of an App Engine Standard version.
+type AppEngineHandler struct {
+	URLRegex                 string                 `yaml:"url_regex,omitempty"`
+	SecurityLevel            string                 `yaml:"security_level,omitempty"`
+	Login                    string                 `yaml:"login,omitempty"`
+	AuthFailAction           string                 `yaml:"auth_fail_action,omitempty"`
+	RedirectHTTPResponseCode string                 `yaml:"redirect_http_response_code,omitempty"`
+	Script                   *AppEngineScript       `yaml:"script,omitempty"`
+	StaticFiles              map[string]interface{} `yaml:"static_files,omitempty"`
+}
+
+// AppEngineScript is the script that serves a handler.
+type AppEngineScript struct {
+	ScriptPath string `yaml:"script_path"`
+}
+
+// AppEngineVPCAccessConnector is an existing Serverless VPC Access
+// connector for an App Engine Standard version.
+type AppEngineVPCAccessConnector struct {
+	Name          string `yaml:"name"`
+	EgressSetting string `yaml:"egress_setting,omitempty"`
+}
+
+// CloudRun is a file of 06-consumer/Serverless/CloudRun/Job or
+// 06-consumer/Serverless/CloudRun/Service, which read the same keys.
+type CloudRun struct {
+	ProjectID  string                       `yaml:"project_id"`
+	Region     string                       `yaml:"region"`
+	Name       string                       `yaml:"name"`
+	Containers map[string]CloudRunContainer `yaml:"containers"`
+
+	Prefix               string                 `yaml:"prefix,omitempty"`
+	Labels               map[string]string      `yaml:"labels,omitempty"`
+	IAM                  map[string][]string    `yaml:"iam,omitempty"`
+	Ingress              string                 `yaml:"ingress,omitempty"`
+	LaunchStage          string                 `yaml:"launch_stage,omitempty"`
+	CustomAudiences      []string               `yaml:"custom_audiences,omitempty"`
+	EncryptionKey        string                 `yaml:"encryption_key,omitempty"`
+	ServiceAccount       string                 `yaml:"service_account,omitempty"`
+	ServiceAccountCreate *bool                  `yaml:"service_account_create,omitempty"`
+	TagBindings          map[string]string      `yaml:"tag_bindings,omitempty"`
+	CreateJob            *bool                  `yaml:"create_job,omitempty"`
+	Revision             map[string]interface{} `yaml:"revision,omitempty"`
+	Volumes              map[string]interface{} `yaml:"volumes,omitempty"`
+	EventarcTriggers     map[string]interface{} `yaml:"eventarc_triggers,omitempty"`
+	VPCConnectorCreate   map[string]interface{} `yaml:"vpc_connector_create,omitempty"`
+}
+
+// CloudRunContainer is a container of a Cloud Run job or service.
+type CloudRunContainer struct {
+	Image         string                 `yaml:"image"`
+	Command       []string               `yaml:"command,omitempty"`
+	Args          []string               `yaml:"args,omitempty"`
+	Env           map[string]string      `yaml:"env,omitempty"`
+	EnvFromKey    map[string]interface{} `yaml:"env_from_key,omitempty"`
+	Ports         map[string]interface{} `yaml:"ports,omitempty"`
+	Resources     map[string]interface{} `yaml:"resources,omitempty"`
+	LivenessProbe map[string]interface{} `yaml:"liveness_probe,omitempty"`
+	StartupProbe  map[string]interface{} `yaml:"startup_probe,omitempty"`
+	VolumeMounts  map[string]string      `yaml:"volume_mounts,omitempty"`
+}
+
+// VPCAccessConnector is a file of 06-consumer/Serverless/VPCAccessConnector.
+// A connector uses either SubnetName, or Network and IPCIDRRange.
+type VPCAccessConnector struct {
+	Name          string `yaml:"name"`
+	ProjectID     string `yaml:"project_id"`
+	Region        string `yaml:"region"`
+	SubnetName    string `yaml:"subnet_name,omitempty"`
+	HostProjectID string `yaml:"host_project_id,omitempty"`
+	Network       string `yaml:"network,omitempty"`
+	IPCIDRRange   string `yaml:"ip_cidr_range,omitempty"`
+	MachineType   string `yaml:"machine_type,omitempty"`
+	MinInstances  *int   `yaml:"min_instances,omitempty"`
+	MaxInstances  *int   `yaml:"max_instances,omitempty"`
+	MinThroughput *int   `yaml:"min_throughput,omitempty"`
+	MaxThroughput *int   `yaml:"max_throughput,omitempty"`
+}
@@ -0,0 +1,223 @@
+// Copyright 2026 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package schema
+
+// ApplicationLoadBalancer is a file of
+// 07-consumer-load-balancing/Application/External.
+type ApplicationLoadBalancer struct {
+	Name     string      `yaml:"name"`
+	Project  string      `yaml:"project"`
+	Network  string      `yaml:"network"`
+	Backends ALBBackends `yaml:"backends"`
+}
+
+// ALBBackends are the backend services of an application load balancer.
+type ALBBackends struct {
+	Default ALBBackend `yaml:"default"`
+}
+
+// ALBBackend is the default backend service of an application load balancer.
+// Only the first group is used.
+type ALBBackend struct {
+	Protocol    string                 `yaml:"protocol,omitempty"`
+	Port        *int                   `yaml:"port,omitempty"`
+	PortName    string                 `yaml:"port_name,omitempty"`
+	TimeoutSec  *int                   `yaml:"timeout_sec,omitempty"`
+	EnableCDN   *bool                  `yaml:"enable_cdn,omitempty"`
+	HealthCheck map[string]interface{} `yaml:"health_check,omitempty"`
+	LogConfig   *ALBLogConfig          `yaml:"log_config,omitempty"`
+	Groups      []ALBGroup             `yaml:"groups"`
+	IAPConfig   map[string]interface{} `yaml:"iap_config,omitempty"`
+}
+
+// ALBLogConfig is the request logging of a backend service.
+type ALBLogConfig struct {
+	Enable     *bool    `yaml:"enable,omitempty"`
+	SampleRate *float64 `yaml:"sample_rate,omitempty"`
+}
+
+// ALBGroup is a regional instance group behind an application load balancer.
+type ALBGroup struct {
+	Group  string `yaml:"group"`
+	Region string `yaml:"region"`
+}
+
+// NetworkLoadBalancer is a file of
+// 07-consumer-load-balancing/Network/Passthrough/External.
+type NetworkLoadBalancer struct {
+	Name      string       `yaml:"name"`
+	ProjectID string       `yaml:"project_id"`
+	Region    string       `yaml:"region"`
+	Backends  []NLBBackend `yaml:"backends"`
+
+	Description            string                       `yaml:"description,omitempty"`
+	Labels                 map[string]string            `yaml:"labels,omitempty"`
+	BackendService         *NLBBackendService           `yaml:"backend_service,omitempty"`
+	HealthCheck            *NLBHealthCheck              `yaml:"health_check,omitempty"`
+	ForwardingRules        map[string]NLBForwardingRule `yaml:"forwarding_rules,omitempty"`
+	ForwardingRuleProtocol string                       `yaml:"forwarding_rule_protocol,omitempty"`
+}
+
+// NLBBackend is an instance group behind a passthrough network load
+// balancer. The group is zonal when GroupZone is set and regional otherwise,
+// in GroupRegion or the region of the load balancer.
+type NLBBackend struct {
+	GroupName   string `yaml:"group_name"`
+	GroupRegion string `yaml:"group_region,omitempty"`
+	GroupZone   string `yaml:"group_zone,omitempty"`
+	Description string `yaml:"description,omitempty"`
+	// Failover is only read by the external load balancer.
+	Failover *bool `yaml:"failover,omitempty"`
+}
+
+// NLBBackendService is the backend service of a passthrough network load
+// balancer.
+type NLBBackendService struct {
+	Protocol                     string   `yaml:"protocol,omitempty"`
+	PortName                     string   `yaml:"port_name,omitempty"`
+	TimeoutSec                   *int     `yaml:"timeout_sec,omitempty"`
+	ConnectionDrainingTimeoutSec *int     `yaml:"connection_draining_timeout_sec,omitempty"`
+	LogSampleRate                *float64 `yaml:"log_sample_rate,omitempty"`
+	LocalityLBPolicy             string   `yaml:"locality_lb_policy,omitempty"`
+	SessionAffinity              string   `yaml:"session_affinity,omitempty"`
+	ConnectionTracking           *struct {
+		IdleTimeoutSec         *int   `yaml:"idle_timeout_sec,omitempty"`
+		PersistConnOnUnhealthy string `yaml:"persist_conn_on_unhealthy,omitempty"`
+		TrackPerSession        *bool  `yaml:"track_per_session,omitempty"`
+	} `yaml:"connection_tracking,omitempty"`
+	FailoverConfig *struct {
+		DisableConnDrain       *bool    `yaml:"disable_conn_drain,omitempty"`
+		DropTrafficIfUnhealthy *bool    `yaml:"drop_traffic_if_unhealthy,omitempty"`
+		Ratio                  *float64 `yaml:"ratio,omitempty"`
+	} `yaml:"failover_config,omitempty"`
+}
+
+// NLBHealthCheck is the health check of an external passthrough network load
+// balancer. Name reuses an existing health check; otherwise one is created
+// with at most one of the protocol blocks, TCP by default.
+type NLBHealthCheck struct {
+	Name               string           `yaml:"name,omitempty"`
+	Description        string           `yaml:"description,omitempty"`
+	CheckIntervalSec   *int             `yaml:"check_interval_sec,omitempty"`
+	TimeoutSec         *int             `yaml:"timeout_sec,omitempty"`
+	HealthyThreshold   *int             `yaml:"healthy_threshold,omitempty"`
+	UnhealthyThreshold *int             `yaml:"unhealthy_threshold,omitempty"`
+	EnableLogging      *bool            `yaml:"enable_logging,omitempty"`
+	TCP                *HealthCheckTCP  `yaml:"tcp,omitempty"`
+	SSL                *HealthCheckTCP  `yaml:"ssl,omitempty"`
+	HTTP               *HealthCheckHTTP `yaml:"http,omitempty"`
+	HTTPS              *HealthCheckHTTP `yaml:"https,omitempty"`
+	HTTP2              *HealthCheckHTTP `yaml:"http2,omitempty"`
+	GRPC               *HealthCheckGRPC `yaml:"grpc,omitempty"`
+}
+
+// HealthCheckTCP probes a TCP or SSL port.
+type HealthCheckTCP struct {
+	Port              *int   `yaml:"port,omitempty"`
+	PortName          string `yaml:"port_name,omitempty"`
+	PortSpecification string `yaml:"port_specification,omitempty"`
+	Request           string `yaml:"request,omitempty"`
+	Response          string `yaml:"response,omitempty"`
+	ProxyHeader       string `yaml:"proxy_header,omitempty"`
+}
+
+// HealthCheckHTTP probes an HTTP, HTTPS or HTTP/2 path.
+type HealthCheckHTTP struct {
+	Port              *int   `yaml:"port,omitempty"`
+	PortName          string `yaml:"port_name,omitempty"`
+	PortSpecification string `yaml:"port_specification,omitempty"`
+	Host              string `yaml:"host,omitempty"`
+	RequestPath       string `yaml:"request_path,omitempty"`
+	Response          string `yaml:"response,omitempty"`
+	ProxyHeader       string `yaml:"proxy_header,omitempty"`
+}
+
+// HealthCheckGRPC probes a gRPC service.
+type HealthCheckGRPC struct {
+	Port              *int   `yaml:"port,omitempty"`
+	PortName          string `yaml:"port_name,omitempty"`
+	PortSpecification string `yaml:"port_specification,omitempty"`
+	ServiceName       string `yaml:"service_name,omitempty"`
+}
+
+// NLBForwardingRule is a forwarding rule of an external passthrough network
+// load balancer, keyed by a suffix of its name.
+type NLBForwardingRule struct {
+	Name        string   `yaml:"name,omitempty"`
+	Description string   `yaml:"description,omitempty"`
+	Address     string   `yaml:"address,omitempty"`
+	IPv6        *bool    `yaml:"ipv6,omitempty"`
+	Ports       []string `yaml:"ports,omitempty"`
+	Protocol    string   `yaml:"protocol,omitempty"`
+	Subnetwork  string   `yaml:"subnetwork,omitempty"`
+}
+
+// InternalLoadBalancer is a file of
+// 07-consumer-load-balancing/Network/Passthrough/Internal.
+type InternalLoadBalancer struct {
+	Name       string       `yaml:"name"`
+	Project    string       `yaml:"project"`
+	Region     string       `yaml:"region"`
+	Network    string       `yaml:"network"`
+	Subnetwork string       `yaml:"subnetwork"`
+	Backends   []NLBBackend `yaml:"backends,omitempty"`
+
+	Labels                       map[string]string  `yaml:"labels,omitempty"`
+	SourceTags                   []string           `yaml:"source_tags,omitempty"`
+	TargetTags                   []string           `yaml:"target_tags,omitempty"`
+	IsMirroringCollector         *bool              `yaml:"is_mirroring_collector,omitempty"`
+	CreateBackendFirewall        *bool              `yaml:"create_backend_firewall,omitempty"`
+	CreateHealthCheckFirewall    *bool              `yaml:"create_health_check_firewall,omitempty"`
+	FirewallEnableLogging        *bool              `yaml:"firewall_enable_logging,omitempty"`
+	SessionAffinity              string             `yaml:"session_affinity,omitempty"`
+	ConnectionDrainingTimeoutSec *int               `yaml:"connection_draining_timeout_sec,omitempty"`
+	HealthCheck                  *ILBHealthCheck    `yaml:"health_check,omitempty"`
+	ForwardingRule               *ILBForwardingRule `yaml:"forwarding_rule,omitempty"`
+
+	// Description and BackendService are set by the expanded example but not
+	// read by the stage, which takes SessionAffinity and
+	// ConnectionDrainingTimeoutSec from the top level.
+	Description    string             `yaml:"description,omitempty"`
+	BackendService *NLBBackendService `yaml:"backend_service,omitempty"`
+}
+
+// ILBHealthCheck is the health check of an internal passthrough network load
+// balancer.
+type ILBHealthCheck struct {
+	Type               string `yaml:"type,omitempty"`
+	CheckIntervalSec   *int   `yaml:"check_interval_sec,omitempty"`
+	TimeoutSec         *int   `yaml:"timeout_sec,omitempty"`
+	HealthyThreshold   *int   `yaml:"healthy_threshold,omitempty"`
+	UnhealthyThreshold *int   `yaml:"unhealthy_threshold,omitempty"`
+	Port               *int   `yaml:"port,omitempty"`
+	RequestPath        string `yaml:"request_path,omitempty"`
+	EnableLog          *bool  `yaml:"enable_log,omitempty"`
+
+	// Description, EnableLogging and HTTP are set by the expanded example but
+	// not read by the stage, which takes Type, Port, RequestPath and
+	// EnableLog instead.
+	Description   string           `yaml:"description,omitempty"`
+	EnableLogging *bool            `yaml:"enable_logging,omitempty"`
+	HTTP          *HealthCheckHTTP `yaml:"http,omitempty"`
+}
+
+// ILBForwardingRule is the forwarding rule of an internal passthrough
+// network load balancer.
+type ILBForwardingRule struct {
+	Address      string   `yaml:"address,omitempty"`
+	Protocol     string   `yaml:"protocol,omitempty"`
+	Ports        []string `yaml:"ports,omitempty"`
+	GlobalAccess *bool    `yaml:"global_access,omitempty"`
+}
@@ -0,0 +1,96 @@
+// Copyright 2026 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package schema
+
+// NCC is a file of 02-networking/NCC. Spokes are attached to the first hub
+// of the file.
+type NCC struct {
+	Hubs   []NCCHub   `yaml:"hubs"`
+	Spokes []NCCSpoke `yaml:"spokes,omitempty"`
+}
+
+// NCCHub is a Network Connectivity Center hub, created or reused through
+// ExistingHubURI.
+type NCCHub struct {
+	Name               string            `yaml:"name"`
+	ProjectID          string            `yaml:"project_id"`
+	Description        string            `yaml:"description,omitempty"`
+	Labels             map[string]string `yaml:"labels,omitempty"`
+	ExportPSC          *bool             `yaml:"export_psc,omitempty"`
+	PolicyMode         string            `yaml:"policy_mode,omitempty"`
+	PresetTopology     string            `yaml:"preset_topology,omitempty"`
+	AutoAcceptProjects []string          `yaml:"auto_accept_projects,omitempty"`
+	CreateNewHub       *bool             `yaml:"create_new_hub,omitempty"`
+	ExistingHubURI     string            `yaml:"existing_hub_uri,omitempty"`
+	GroupName          string            `yaml:"group_name,omitempty"`
+	// GroupDescription keeps the spelling of the key the stage reads.
+	GroupDescription string            `yaml:"group_decription,omitempty"`
+	SpokeLabels      map[string]string `yaml:"spoke_labels,omitempty"`
+}
+
+// NCCSpoke is a spoke of a hub. Type is one of linked_vpc_network,
+// linked_producer_vpc_network, linked_vpn_tunnels,
+// linked_interconnect_attachments and router_appliance_spoke; spokes of any
+// other type, such as the router_spoke of the expanded example, are ignored.
+type NCCSpoke struct {
+	Type        string            `yaml:"type"`
+	Name        string            `yaml:"name"`
+	ProjectID   string            `yaml:"project_id"`
+	Location    string            `yaml:"location,omitempty"`
+	Description string            `yaml:"description,omitempty"`
+	Labels      map[string]string `yaml:"labels,omitempty"`
+	// URI is the network of a VPC or producer VPC spoke.
+	URI string `yaml:"uri,omitempty"`
+	// URIs are the tunnels or attachments of a hybrid spoke.
+	URIs []string `yaml:"uris,omitempty"`
+	// Peering is the peering of a producer VPC spoke.
+	Peering                  string        `yaml:"peering,omitempty"`
+	ExcludeExportRanges      []string      `yaml:"exclude_export_ranges,omitempty"`
+	IncludeExportRanges      []string      `yaml:"include_export_ranges,omitempty"`
+	SiteToSiteDataTransfer   *bool         `yaml:"site_to_site_data_transfer,omitempty"`
+	Router                   string        `yaml:"router,omitempty"`
+	RouterApplianceInstances []interface{} `yaml:"router_appliance_instances,omitempty"`
+}
+
+// FirewallEndpoint is a file of 02-networking/FirewallEndpoint. A file
+// creates an endpoint, an association, or both.
+type FirewallEndpoint struct {
+	Location                    string                       `yaml:"location"`
+	FirewallEndpoint            *FirewallEndpointSpec        `yaml:"firewall_endpoint,omitempty"`
+	FirewallEndpointAssociation *FirewallEndpointAssociation `yaml:"firewall_endpoint_association,omitempty"`
+}
+
+// FirewallEndpointSpec is an organization level firewall endpoint.
+type FirewallEndpointSpec struct {
+	Create           *bool             `yaml:"create,omitempty"`
+	Name             string            `yaml:"name"`
+	OrganizationID   string            `yaml:"organization_id"`
+	BillingProjectID string            `yaml:"billing_project_id"`
+	Labels           map[string]string `yaml:"labels,omitempty"`
+}
+
+// FirewallEndpointAssociation associates a firewall endpoint with a network.
+// ExistingFirewallEndpointID is required when the file does not also create
+// the endpoint.
+type FirewallEndpointAssociation struct {
+	Create                     *bool             `yaml:"create,omitempty"`
+	Name                       string            `yaml:"name"`
+	AssociationProjectID       string            `yaml:"association_project_id"`
+	VPCID                      string            `yaml:"vpc_id"`
+	ExistingFirewallEndpointID string            `yaml:"existing_firewall_endpoint_id,omitempty"`
+	TLSInspectionPolicyID      string            `yaml:"tls_inspection_policy_id,omitempty"`
+	Disabled                   *bool             `yaml:"disabled,omitempty"`
+	Labels                     map[string]string `yaml:"labels,omitempty"`
+}
@@ -0,0 +1,283 @@
+// Copyright 2026 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package schema
+
+// AlloyDB is a file of 04-producer/AlloyDB.
+type AlloyDB struct {
+	ClusterID          string `yaml:"cluster_id"`
+	ClusterDisplayName string `yaml:"cluster_display_name"`
+	ProjectID          string `yaml:"project_id"`
+	Region             string `yaml:"region"`
+	// ConnectivityOptions is "psa" or "psc", in either case.
+	ConnectivityOptions string `yaml:"connectivity_options,omitempty"`
+	// NetworkID is required for PSA connectivity.
+	NetworkID                  string            `yaml:"network_id,omitempty"`
+	AllocatedIPRange           string            `yaml:"allocated_ip_range,omitempty"`
+	PSCAllowedConsumerProjects []string          `yaml:"psc_allowed_consumer_projects,omitempty"`
+	DatabaseVersion            string            `yaml:"database_version,omitempty"`
+	ClusterLabels              map[string]string `yaml:"cluster_labels,omitempty"`
+	ClusterEncryptionKeyName   string            `yaml:"cluster_encryption_key_name,omitempty"`
+	ClusterInitialUser         interface{}       `yaml:"cluster_initial_user,omitempty"`
+	PrimaryInstance            AlloyDBInstance   `yaml:"primary_instance"`
+	ReadPoolInstance           interface{}       `yaml:"read_pool_instance,omitempty"`
+	AutomatedBackupPolicy      interface{}       `yaml:"automated_backup_policy,omitempty"`
+}
+
+// AlloyDBInstance is the primary instance of an AlloyDB cluster.
+type AlloyDBInstance struct {
+	InstanceID      string            `yaml:"instance_id"`
+	DisplayName     string            `yaml:"display_name,omitempty"`
+	InstanceType    string            `yaml:"instance_type,omitempty"`
+	MachineCPUCount *int              `yaml:"machine_cpu_count,omitempty"`
+	DatabaseFlags   map[string]string `yaml:"database_flags,omitempty"`
+}
+
+// CloudSQL is a file of 04-producer/CloudSQL.
+type CloudSQL struct {
+	Name            string                `yaml:"name"`
+	ProjectID       string                `yaml:"project_id"`
+	Region          string                `yaml:"region"`
+	DatabaseVersion string                `yaml:"database_version"`
+	NetworkConfig   CloudSQLNetworkConfig `yaml:"network_config"`
+
+	TerraformDeletionProtection *bool             `yaml:"terraform_deletion_protection,omitempty"`
+	GCPDeletionProtection       *bool             `yaml:"gcp_deletion_protection,omitempty"`
+	Labels                      map[string]string `yaml:"labels,omitempty"`
+	Prefix                      string            `yaml:"prefix,omitempty"`
+	Tier                        string            `yaml:"tier,omitempty"`
+	Edition                     string            `yaml:"edition,omitempty"`
+	AvailabilityType            string            `yaml:"availability_type,omitempty"`
+	ActivationPolicy            string            `yaml:"activation_policy,omitempty"`
+	DiskSize                    *int              `yaml:"disk_size,omitempty"`
+	DiskType                    string            `yaml:"disk_type,omitempty"`
+	DiskAutoresizeLimit         *int              `yaml:"disk_autoresize_limit,omitempty"`
+	DataCache                   *bool             `yaml:"data_cache,omitempty"`
+	Collation                   string            `yaml:"collation,omitempty"`
+	Timezone                    string            `yaml:"timezone,omitempty"`
+	Flags                       map[string]string `yaml:"flags,omitempty"`
+	Databases                   []string          `yaml:"databases,omitempty"`
+	RootPassword                string            `yaml:"root_password,omitempty"`
+	ConnectorEnforcement        string            `yaml:"connector_enforcement,omitempty"`
+	Encryption                  string            `yaml:"encryption,omitempty"`
+	BackupConfiguration         interface{}       `yaml:"backup_configuration,omitempty"`
+	InsightsConfig              interface{}       `yaml:"insights_config,omitempty"`
+	MaintenanceConfig           interface{}       `yaml:"maintenance_config,omitempty"`
+	Replicas                    interface{}       `yaml:"replicas,omitempty"`
+	SSL                         interface{}       `yaml:"ssl,omitempty"`
+	Users                       interface{}       `yaml:"users,omitempty"`
+}
+
+// CloudSQLNetworkConfig selects how a Cloud SQL instance is reached.
+type CloudSQLNetworkConfig struct {
+	AuthorizedNetworks map[string]string    `yaml:"authorized_networks,omitempty"`
+	Connectivity       CloudSQLConnectivity `yaml:"connectivity"`
+}
+
+// CloudSQLConnectivity enables public IP, PSA and PSC access.
+type CloudSQLConnectivity struct {
+	PublicIPv4                 bool       `yaml:"public_ipv4,omitempty"`
+	PSAConfig                  *PSAConfig `yaml:"psa_config,omitempty"`
+	PSCAllowedConsumerProjects []string   `yaml:"psc_allowed_consumer_projects,omitempty"`
+}
+
+// PSAConfig attaches a Cloud SQL instance to a network through private
+// services access.
+type PSAConfig struct {
+	PrivateNetwork    string             `yaml:"private_network"`
+	AllocatedIPRanges *AllocatedIPRanges `yaml:"allocated_ip_ranges,omitempty"`
+}
+
+// AllocatedIPRanges names the PSA ranges of the primary and the replicas.
+type AllocatedIPRanges struct {
+	Primary string `yaml:"primary,omitempty"`
+	Replica string `yaml:"replica,omitempty"`
+}
+
+// GKE is a file of 04-producer/GKE. Every key but the network settings is
+// optional and falls back to the variable of the same name.
+type GKE struct {
+	Name            string `yaml:"name"`
+	ProjectID       string `yaml:"project_id"`
+	Network         string `yaml:"network"`
+	Subnetwork      string `yaml:"subnetwork"`
+	IPRangePods     string `yaml:"ip_range_pods"`
+	IPRangeServices string `yaml:"ip_range_services"`
+
+	AddClusterFirewallRules                 *bool                        `yaml:"add_cluster_firewall_rules,omitempty"`
+	AddMasterWebhookFirewallRules           *bool                        `yaml:"add_master_webhook_firewall_rules,omitempty"`
+	AddShadowFirewallRules                  *bool                        `yaml:"add_shadow_firewall_rules,omitempty"`
+	AdditionalIPRangePods                   []string                     `yaml:"additional_ip_range_pods,omitempty"`
+	AuthenticatorSecurityGroup              string                       `yaml:"authenticator_security_group,omitempty"`
+	BootDiskKMSKey                          string                       `yaml:"boot_disk_kms_key,omitempty"`
+	ClusterAutoscaling                      interface{}                  `yaml:"cluster_autoscaling,omitempty"`
+	ClusterDNSDomain                        string                       `yaml:"cluster_dns_domain,omitempty"`
+	ClusterDNSProvider                      string                       `yaml:"cluster_dns_provider,omitempty"`
+	ClusterDNSScope                         string                       `yaml:"cluster_dns_scope,omitempty"`
+	ClusterIPv4CIDR                         string                       `yaml:"cluster_ipv4_cidr,omitempty"`
+	ClusterResourceLabels                   map[string]string            `yaml:"cluster_resource_labels,omitempty"`
+	ConfigConnector                         *bool                        `yaml:"config_connector,omitempty"`
+	ConfigureIPMasq                         *bool                        `yaml:"configure_ip_masq,omitempty"`
+	CreateServiceAccount                    *bool                        `yaml:"create_service_account,omitempty"`
+	DatabaseEncryption                      interface{}                  `yaml:"database_encryption,omitempty"`
+	DatapathProvider                        string                       `yaml:"datapath_provider,omitempty"`
+	DefaultMaxPodsPerNode                   *int                         `yaml:"default_max_pods_per_node,omitempty"`
+	DeletionProtection                      *bool                        `yaml:"deletion_protection,omitempty"`
+	Description                             string                       `yaml:"description,omitempty"`
+	DisableDefaultSNAT                      *bool                        `yaml:"disable_default_snat,omitempty"`
+	DisableLegacyMetadataEndpoints          *bool                        `yaml:"disable_legacy_metadata_endpoints,omitempty"`
+	DNSCache                                *bool                        `yaml:"dns_cache,omitempty"`
+	EnableBinaryAuthorization               *bool                        `yaml:"enable_binary_authorization,omitempty"`
+	EnableCiliumClusterwideNetworkPolicy    *bool                        `yaml:"enable_cilium_clusterwide_network_policy,omitempty"`
+	EnableConfidentialNodes                 *bool                        `yaml:"enable_confidential_nodes,omitempty"`
+	EnableCostAllocation                    *bool                        `yaml:"enable_cost_allocation,omitempty"`
+	EnableIntranodeVisibility               *bool                        `yaml:"enable_intranode_visibility,omitempty"`
+	EnableKubernetesAlpha                   *bool                        `yaml:"enable_kubernetes_alpha,omitempty"`
+	EnableL4ILBSubsetting                   *bool                        `yaml:"enable_l4_ilb_subsetting,omitempty"`
+	EnableMeshCertificates                  *bool                        `yaml:"enable_mesh_certificates,omitempty"`
+	EnableNetworkEgressExport               *bool                        `yaml:"enable_network_egress_export,omitempty"`
+	EnablePrivateEndpoint                   *bool                        `yaml:"enable_private_endpoint,omitempty"`
+	EnablePrivateNodes                      *bool                        `yaml:"enable_private_nodes,omitempty"`
+	EnableResourceConsumptionExport         *bool                        `yaml:"enable_resource_consumption_export,omitempty"`
+	EnableShieldedNodes                     *bool                        `yaml:"enable_shielded_nodes,omitempty"`
+	EnableTPU                               *bool                        `yaml:"enable_tpu,omitempty"`
+	EnableVerticalPodAutoscaling            *bool                        `yaml:"enable_vertical_pod_autoscaling,omitempty"`
+	FilestoreCSIDriver                      *bool                        `yaml:"filestore_csi_driver,omitempty"`
+	FirewallInboundPorts                    []string                     `yaml:"firewall_inbound_ports,omitempty"`
+	FirewallPriority                        *int                         `yaml:"firewall_priority,omitempty"`
+	FleetProject                            string                       `yaml:"fleet_project,omitempty"`
+	GatewayAPIChannel                       string                       `yaml:"gateway_api_channel,omitempty"`
+	GcePDCSIDriver                          *bool                        `yaml:"gce_pd_csi_driver,omitempty"`
+	GCSFuseCSIDriver                        *bool                        `yaml:"gcs_fuse_csi_driver,omitempty"`
+	GKEBackupAgentConfig                    *bool                        `yaml:"gke_backup_agent_config,omitempty"`
+	GrantRegistryAccess                     *bool                        `yaml:"grant_registry_access,omitempty"`
+	HorizontalPodAutoscaling                *bool                        `yaml:"horizontal_pod_autoscaling,omitempty"`
+	HTTPLoadBalancing                       *bool                        `yaml:"http_load_balancing,omitempty"`
+	IdentityNamespace                       string                       `yaml:"identity_namespace,omitempty"`
+	InitialNodeCount                        *int                         `yaml:"initial_node_count,omitempty"`
+	IPMasqLinkLocal                         *bool                        `yaml:"ip_masq_link_local,omitempty"`
+	IPMasqResyncInterval                    string                       `yaml:"ip_masq_resync_interval,omitempty"`
+	IssueClientCertificate                  *bool                        `yaml:"issue_client_certificate,omitempty"`
+	KubernetesVersion                       string                       `yaml:"kubernetes_version,omitempty"`
+	LoggingEnabledComponents                []string                     `yaml:"logging_enabled_components,omitempty"`
+	LoggingService                          string                       `yaml:"logging_service,omitempty"`
+	MaintenanceEndTime                      string                       `yaml:"maintenance_end_time,omitempty"`
+	MaintenanceExclusions                   interface{}                  `yaml:"maintenance_exclusions,omitempty"`
+	MaintenanceRecurrence                   string                       `yaml:"maintenance_recurrence,omitempty"`
+	MaintenanceStartTime                    string                       `yaml:"maintenance_start_time,omitempty"`
+	MasterAuthorizedNetworks                interface{}                  `yaml:"master_authorized_networks,omitempty"`
+	MasterIPv4CIDRBlock                     string                       `yaml:"master_ipv4_cidr_block,omitempty"`
+	MonitoringEnableManagedPrometheus       *bool                        `yaml:"monitoring_enable_managed_prometheus,omitempty"`
+	MonitoringEnableObservabilityMetrics    *bool                        `yaml:"monitoring_enable_observability_metrics,omitempty"`
+	MonitoringEnabledComponents             []string                     `yaml:"monitoring_enabled_components,omitempty"`
+	MonitoringObservabilityMetricsRelayMode string                       `yaml:"monitoring_observability_metrics_relay_mode,omitempty"`
+	MonitoringService                       string                       `yaml:"monitoring_service,omitempty"`
+	NetworkPolicy                           *bool                        `yaml:"network_policy,omitempty"`
+	NetworkPolicyProvider                   string                       `yaml:"network_policy_provider,omitempty"`
+	NetworkProjectID                        string                       `yaml:"network_project_id,omitempty"`
+	NetworkTags                             []string                     `yaml:"network_tags,omitempty"`
+	NodeMetadata                            string                       `yaml:"node_metadata,omitempty"`
+	NodePools                               interface{}                  `yaml:"node_pools,omitempty"`
+	NodePoolsLabels                         map[string]map[string]string `yaml:"node_pools_labels,omitempty"`
+	NodePoolsLinuxNodeConfigsSysctls        map[string]map[string]string `yaml:"node_pools_linux_node_configs_sysctls,omitempty"`
+	NodePoolsMetadata                       map[string]map[string]string `yaml:"node_pools_metadata,omitempty"`
+	NodePoolsOAuthScopes                    map[string][]string          `yaml:"node_pools_oauth_scopes,omitempty"`
+	NodePoolsResourceLabels                 map[string]map[string]string `yaml:"node_pools_resource_labels,omitempty"`
+	NodePoolsTags                           map[string][]string          `yaml:"node_pools_tags,omitempty"`
+	NodePoolsTaints                         interface{}                  `yaml:"node_pools_taints,omitempty"`
+	NonMasqueradeCidrs                      []string                     `yaml:"non_masquerade_cidrs,omitempty"`
+	NotificationConfigTopic                 string                       `yaml:"notification_config_topic,omitempty"`
+	NotificationFilterEventType             []string                     `yaml:"notification_filter_event_type,omitempty"`
+	Region                                  string                       `yaml:"region,omitempty"`
+	Regional                                *bool                        `yaml:"regional,omitempty"`
+	RegistryProjectIds                      []string                     `yaml:"registry_project_ids,omitempty"`
+	ReleaseChannel                          string                       `yaml:"release_channel,omitempty"`
+	RemoveDefaultNodePool                   *bool                        `yaml:"remove_default_node_pool,omitempty"`
+	ResourceUsageExportDatasetID            string                       `yaml:"resource_usage_export_dataset_id,omitempty"`
+	SecurityPostureMode                     string                       `yaml:"security_posture_mode,omitempty"`
+	SecurityPostureVulnerabilityMode        string                       `yaml:"security_posture_vulnerability_mode,omitempty"`
+	ServiceAccount                          string                       `yaml:"service_account,omitempty"`
+	ServiceAccountName                      string                       `yaml:"service_account_name,omitempty"`
+	ServiceExternalIps                      *bool                        `yaml:"service_external_ips,omitempty"`
+	ShadowFirewallRulesLogConfig            interface{}                  `yaml:"shadow_firewall_rules_log_config,omitempty"`
+	ShadowFirewallRulesPriority             *int                         `yaml:"shadow_firewall_rules_priority,omitempty"`
+	StackType                               string                       `yaml:"stack_type,omitempty"`
+	StatefulHA                              *bool                        `yaml:"stateful_ha,omitempty"`
+	StubDomains                             map[string][]string          `yaml:"stub_domains,omitempty"`
+	Timeouts                                map[string]string            `yaml:"timeouts,omitempty"`
+	UpstreamNameservers                     []string                     `yaml:"upstream_nameservers,omitempty"`
+	WindowsNodePools                        interface{}                  `yaml:"windows_node_pools,omitempty"`
+	Zones                                   []string                     `yaml:"zones,omitempty"`
+}
+
+// MRC is a file of 04-producer/MRC, a Memorystore for Redis Cluster.
+type MRC struct {
+	RedisClusterName          string `yaml:"redis_cluster_name"`
+	ProjectID                 string `yaml:"project_id"`
+	NetworkID                 string `yaml:"network_id"`
+	Region                    string `yaml:"region,omitempty"`
+	ShardCount                *int   `yaml:"shard_count,omitempty"`
+	ReplicaCount              *int   `yaml:"replica_count,omitempty"`
+	DeletionProtectionEnabled *bool  `yaml:"deletion_protection_enabled,omitempty"`
+}
+
+// VectorSearch is a file of 04-producer/VectorSearch.
+type VectorSearch struct {
+	ProjectID                   string            `yaml:"project_id"`
+	Region                      string            `yaml:"region"`
+	IndexDisplayName            string            `yaml:"index_display_name"`
+	IndexDescription            string            `yaml:"index_description,omitempty"`
+	IndexLabels                 map[string]string `yaml:"index_labels,omitempty"`
+	IndexUpdateMethod           string            `yaml:"index_update_method,omitempty"`
+	ApproximateNeighborsCount   *int              `yaml:"approximate_neighbors_count,omitempty"`
+	ShardSize                   string            `yaml:"shard_size,omitempty"`
+	DistanceMeasureType         string            `yaml:"distance_measure_type,omitempty"`
+	TreeAHConfig                *TreeAHConfig     `yaml:"tree_ah_config,omitempty"`
+	BruteForceConfig            string            `yaml:"brute_force_config,omitempty"`
+	IndexEndpointDisplayName    string            `yaml:"index_endpoint_display_name"`
+	IndexEndpointDescription    string            `yaml:"index_endpoint_description,omitempty"`
+	IndexEndpointLabels         map[string]string `yaml:"index_endpoint_labels,omitempty"`
+	IndexEndpointNetwork        string            `yaml:"index_endpoint_network,omitempty"`
+	PublicEndpointEnabled       *bool             `yaml:"public_endpoint_enabled,omitempty"`
+	PrivateServiceConnectConfig interface{}       `yaml:"private_service_connect_config,omitempty"`
+	DeployedIndexID             string            `yaml:"deployed_index_id,omitempty"`
+	DeployedDisplayName         string            `yaml:"deployed_display_name,omitempty"`
+	DeploymentGroup             string            `yaml:"deployment_group,omitempty"`
+	EnableAccessLogging         *bool             `yaml:"enable_access_logging,omitempty"`
+	ReservedIPRanges            []string          `yaml:"reserved_ip_ranges,omitempty"`
+	AutomaticResources          interface{}       `yaml:"automatic_resources,omitempty"`
+	DedicatedResources          interface{}       `yaml:"dedicated_resources,omitempty"`
+	DeployedIndexAuthConfig     interface{}       `yaml:"deployed_index_auth_config,omitempty"`
+	// Dimension is set by the example and the suite but not read by the
+	// stage.
+	Dimension *int `yaml:"dimension,omitempty"`
+}
+
+// TreeAHConfig tunes the tree-AH algorithm of a Vector Search index.
+type TreeAHConfig struct {
+	LeafNodeEmbeddingCount   *int `yaml:"leaf_node_embedding_count,omitempty"`
+	LeafNodesToSearchPercent *int `yaml:"leaf_nodes_to_search_percent,omitempty"`
+}
+
+// VertexEndpoint is a file of 04-producer/Vertex-AI-Online-Endpoints.
+type VertexEndpoint struct {
+	Name        string            `yaml:"name"`
+	Project     string            `yaml:"project"`
+	DisplayName string            `yaml:"display_name"`
+	Description string            `yaml:"description,omitempty"`
+	Location    string            `yaml:"location"`
+	Region      string            `yaml:"region,omitempty"`
+	Network     string            `yaml:"network,omitempty"`
+	Labels      map[string]string `yaml:"labels,omitempty"`
+}
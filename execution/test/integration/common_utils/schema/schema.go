@@ -0,0 +1,75 @@
+// Copyright 2026 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package schema holds one Go type per stage for the YAML files the stage
+// reads from its config folder. The suites build the files they hand to a
+// stage from these types instead of declaring their own, and the package
+// tests load every configuration/**/*.yaml.example through them in strict
+// mode, so a key that is misspelled or renamed fails a test instead of being
+// silently ignored by the stage.
+//
+// Fields follow the keys the stage reads. Keys that the examples set but the
+// stage ignores are kept so the examples still load, and are documented as
+// such. Optional numbers and booleans are pointers so that an explicit zero
+// value is written out rather than dropped; Bool and Int build them inline.
+// Deeply nested settings that are passed through to a module unchanged are
+// left untyped.
+package schema
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"gopkg.in/yaml.v2"
+)
+
+// Decode parses data into out, failing on any key that out does not declare.
+func Decode(data []byte, out interface{}) error {
+	return yaml.UnmarshalStrict(data, out)
+}
+
+// Load reads the YAML file at path into out in strict mode.
+func Load(path string, out interface{}) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	if err := Decode(data, out); err != nil {
+		return fmt.Errorf("%s: %w", path, err)
+	}
+	return nil
+}
+
+// Write marshals in to YAML and writes it to path, creating the directory if
+// needed.
+func Write(path string, in interface{}) error {
+	data, err := yaml.Marshal(in)
+	if err != nil {
+		return fmt.Errorf("marshaling %s: %w", path, err)
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0644)
+}
+
+// Bool returns a pointer to v.
+func Bool(v bool) *bool { return &v }
+
+// Int returns a pointer to v.
+func Int(v int) *int { return &v }
+
+// Float returns a pointer to v.
+func Float(v float64) *float64 { return &v }
@@ -0,0 +1,184 @@
+// Copyright 2026 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package schema
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"gopkg.in/yaml.v2"
+)
+
+// configurationDir is the configuration folder at the root of the repository.
+var configurationDir = filepath.Join("..", "..", "..", "..", "..", "configuration")
+
+// stages maps the folder of every example, relative to configurationDir and
+// without the trailing config folder, to the type of its stage.
+var stages = map[string]func() interface{}{
+	"producer/AlloyDB":                                     func() interface{} { return &AlloyDB{} },
+	"producer/CloudSQL":                                    func() interface{} { return &CloudSQL{} },
+	"producer/GKE":                                         func() interface{} { return &GKE{} },
+	"producer/MRC":                                         func() interface{} { return &MRC{} },
+	"producer/VectorSearch":                                func() interface{} { return &VectorSearch{} },
+	"producer/Vertex-AI-Online-Endpoints":                  func() interface{} { return &VertexEndpoint{} },
+	"consumer/GCE":                                         func() interface{} { return &GCE{} },
+	"consumer/MIG":                                         func() interface{} { return &MIG{} },
+	"consumer/UMIG":                                        func() interface{} { return &UMIG{} },
+	"consumer/Workbench":                                   func() interface{} { return &Workbench{} },
+	"consumer/Serverless/AppEngine/Standard":               func() interface{} { return &AppEngineStandard{} },
+	"consumer/Serverless/AppEngine/Flexible":               func() interface{} { return &AppEngineFlexible{} },
+	"consumer/Serverless/CloudRun/Job":                     func() interface{} { return &CloudRun{} },
+	"consumer/Serverless/CloudRun/Service":                 func() interface{} { return &CloudRun{} },
+	"consumer/Serverless/VPCAccessConnector":               func() interface{} { return &VPCAccessConnector{} },
+	"consumer-load-balancing/Application/External":         func() interface{} { return &ApplicationLoadBalancer{} },
+	"consumer-load-balancing/Network/Passthrough/External": func() interface{} { return &NetworkLoadBalancer{} },
+	"consumer-load-balancing/Network/Passthrough/Internal": func() interface{} { return &InternalLoadBalancer{} },
+	"networking/ncc":                                       func() interface{} { return &NCC{} },
+	"networking/FirewallEndpoint":                          func() interface{} { return &FirewallEndpoint{} },
+	"security/SecurityProfile":                             func() interface{} { return &SecurityProfile{} },
+}
+
+// placeholders fills in the examples' placeholders for numeric fields, which
+// do not decode into numbers as written.
+var placeholders = strings.NewReplacer(
+	"<shard-count>", "3",
+	"<replica-count>", "1",
+	"your-boot-disk-size", "150",
+	"your-data-disk-size", "100",
+	"your-core-count", "1",
+)
+
+func TestExamplesRoundTrip(t *testing.T) {
+	seen := map[string]bool{}
+	err := filepath.WalkDir(configurationDir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() || !strings.HasSuffix(path, ".yaml.example") {
+			return err
+		}
+		rel, err := filepath.Rel(configurationDir, path)
+		if err != nil {
+			return err
+		}
+		stage := filepath.ToSlash(filepath.Dir(filepath.Dir(rel)))
+		seen[stage] = true
+		t.Run(filepath.ToSlash(rel), func(t *testing.T) {
+			newConfig, ok := stages[stage]
+			if !ok {
+				t.Fatalf("No schema type for the examples of %s", stage)
+			}
+			data, err := os.ReadFile(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			data = []byte(placeholders.Replace(string(data)))
+			config := newConfig()
+			if err := Decode(data, config); err != nil {
+				t.Fatalf("Decode() returned error: %v", err)
+			}
+			out, err := yaml.Marshal(config)
+			if err != nil {
+				t.Fatalf("Marshal() returned error: %v", err)
+			}
+			var want, got interface{}
+			if err := yaml.Unmarshal(data, &want); err != nil {
+				t.Fatal(err)
+			}
+			if err := yaml.Unmarshal(out, &got); err != nil {
+				t.Fatal(err)
+			}
+			if want, got := normalize(want), normalize(got); !reflect.DeepEqual(got, want) {
+				t.Errorf("Round trip = %v, want = %v", got, want)
+			}
+		})
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Walking %s: %v", configurationDir, err)
+	}
+	for stage := range stages {
+		if !seen[stage] {
+			t.Errorf("No examples found for %s", stage)
+		}
+	}
+}
+
+func TestDecodeRejectsUnknownKeys(t *testing.T) {
+	for _, tc := range []struct {
+		data   string
+		config interface{}
+	}{
+		{data: "name: sql\nprojectid: p\n", config: &CloudSQL{}},
+		{data: "hubs:\n  - name: hub\n    group_description: g\n", config: &NCC{}},
+		{data: "name: ilb\nforwarding_rules:\n  rule:\n    protocol: TCP\n", config: &InternalLoadBalancer{}},
+	} {
+		if err := Decode([]byte(tc.data), tc.config); err == nil {
+			t.Errorf("Decode(%q) into %T returned no error, want one", tc.data, tc.config)
+		}
+	}
+}
+
+func TestWriteLoad(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config", "instance.yaml")
+	want := MRC{RedisClusterName: "mrc", ProjectID: "p", NetworkID: "n", ReplicaCount: Int(0)}
+	if err := Write(path, want); err != nil {
+		t.Fatalf("Write() returned error: %v", err)
+	}
+	var got MRC
+	if err := Load(path, &got); err != nil {
+		t.Fatalf("Load() returned error: %v", err)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Load() = %+v, want = %+v", got, want)
+	}
+}
+
+// normalize drops the null and empty values of a decoded YAML document and
+// formats every scalar, so that documents which a stage reads the same way
+// compare equal.
+func normalize(v interface{}) interface{} {
+	switch v := v.(type) {
+	case nil:
+		return nil
+	case map[interface{}]interface{}:
+		m := map[string]interface{}{}
+		for k, e := range v {
+			if e := normalize(e); e != nil {
+				m[fmt.Sprint(k)] = e
+			}
+		}
+		if len(m) == 0 {
+			return nil
+		}
+		return m
+	case []interface{}:
+		var l []interface{}
+		for _, e := range v {
+			l = append(l, normalize(e))
+		}
+		if len(l) == 0 {
+			return nil
+		}
+		return l
+	case string:
+		if v == "" {
+			return nil
+		}
+	}
+	return fmt.Sprint(v)
+}
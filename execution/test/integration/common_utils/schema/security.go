@@ -0,0 +1,86 @@
+// Copyright 2026 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package schema
+
+// SecurityProfile is a file of 03-security/SecurityProfile. A file creates a
+// security profile, a security profile group, or both.
+type SecurityProfile struct {
+	OrganizationID       string                `yaml:"organization_id"`
+	Location             string                `yaml:"location,omitempty"`
+	SecurityProfile      *SecurityProfileSpec  `yaml:"security_profile,omitempty"`
+	SecurityProfileGroup *SecurityProfileGroup `yaml:"security_profile_group,omitempty"`
+	// LinkProfileToGroup adds the profile of the file to the group of the
+	// file.
+	LinkProfileToGroup *bool `yaml:"link_profile_to_group,omitempty"`
+}
+
+// SecurityProfileSpec is a security profile. Type is THREAT_PREVENTION,
+// CUSTOM_MIRRORING or CUSTOM_INTERCEPT and selects which of the profile
+// blocks applies.
+type SecurityProfileSpec struct {
+	Create      *bool             `yaml:"create,omitempty"`
+	Name        string            `yaml:"name"`
+	Type        string            `yaml:"type"`
+	Description string            `yaml:"description,omitempty"`
+	Labels      map[string]string `yaml:"labels,omitempty"`
+
+	ThreatPreventionProfile *ThreatPreventionProfile `yaml:"threat_prevention_profile,omitempty"`
+	CustomMirroringProfile  *struct {
+		MirroringEndpointGroup string `yaml:"mirroring_endpoint_group"`
+	} `yaml:"custom_mirroring_profile,omitempty"`
+	CustomInterceptProfile *struct {
+		InterceptEndpointGroup string `yaml:"intercept_endpoint_group"`
+	} `yaml:"custom_intercept_profile,omitempty"`
+}
+
+// ThreatPreventionProfile overrides the default actions of a threat
+// prevention profile.
+type ThreatPreventionProfile struct {
+	SeverityOverrides  []SeverityOverride  `yaml:"severity_overrides,omitempty"`
+	ThreatOverrides    []ThreatOverride    `yaml:"threat_overrides,omitempty"`
+	AntivirusOverrides []AntivirusOverride `yaml:"antivirus_overrides,omitempty"`
+}
+
+// SeverityOverride sets the action of the threats of a severity, such as
+// INFORMATIONAL.
+type SeverityOverride struct {
+	Severity string `yaml:"severity"`
+	Action   string `yaml:"action"`
+}
+
+// ThreatOverride sets the action of a threat.
+type ThreatOverride struct {
+	ThreatID string `yaml:"threat_id"`
+	Action   string `yaml:"action"`
+}
+
+// AntivirusOverride sets the action of the antivirus for a protocol.
+type AntivirusOverride struct {
+	Protocol string `yaml:"protocol"`
+	Action   string `yaml:"action"`
+}
+
+// SecurityProfileGroup is a security profile group that holds the profile of
+// the file or existing profiles.
+type SecurityProfileGroup struct {
+	Create      *bool             `yaml:"create,omitempty"`
+	Name        string            `yaml:"name"`
+	Description string            `yaml:"description,omitempty"`
+	Labels      map[string]string `yaml:"labels,omitempty"`
+
+	ExistingThreatPreventionProfileID string `yaml:"existing_threat_prevention_profile_id,omitempty"`
+	ExistingCustomMirroringProfileID  string `yaml:"existing_custom_mirroring_profile_id,omitempty"`
+	ExistingCustomInterceptProfileID  string `yaml:"existing_custom_intercept_profile_id,omitempty"`
+}
@@ -0,0 +1,54 @@
+// Copyright 2026 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package standalone runs the helpers that take a terratest testing.TestingT
+// outside of a test: from TestMain, before any test runs, and from the
+// commands under cmd.
+//
+//	c := gcloud.New(project)
+//	networks, err := c.Networks.List(standalone.T("janitor"), "")
+package standalone
+
+import (
+	"fmt"
+	"os"
+)
+
+// T satisfies testing.TestingT with the name it is given. Errors are printed
+// to stderr and a fatal error exits the process with status 1.
+type T string
+
+func (t T) Name() string { return string(t) }
+
+func (T) Fail() {}
+
+func (T) FailNow() { os.Exit(1) }
+
+func (T) Error(args ...interface{}) {
+	fmt.Fprintln(os.Stderr, args...)
+}
+
+func (T) Errorf(format string, args ...interface{}) {
+	fmt.Fprintf(os.Stderr, format+"\n", args...)
+}
+
+func (t T) Fatal(args ...interface{}) {
+	t.Error(args...)
+	t.FailNow()
+}
+
+func (t T) Fatalf(format string, args ...interface{}) {
+	t.Errorf(format, args...)
+	t.FailNow()
+}
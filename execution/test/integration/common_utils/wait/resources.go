@@ -0,0 +1,295 @@
+// Copyright 2026 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package wait
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/GoogleCloudPlatform/cloudnetworking-config-solutions/common_utils/gcloud"
+	"github.com/gruntwork-io/terratest/modules/testing"
+)
+
+// State polls get until it returns one of want. Not-found and transient
+// gcloud errors are treated as "not yet" so that a resource which is still
+// being created can be awaited.
+func State(get func() (string, error), want ...string) Condition {
+	return func() (bool, string, error) {
+		state, err := get()
+		if err != nil {
+			if gcloud.IsNotFound(err) || gcloud.IsTransient(err) {
+				return false, err.Error(), nil
+			}
+			return false, "", err
+		}
+		return slices.Contains(want, state), fmt.Sprintf("state %q", state), nil
+	}
+}
+
+// Exists is done as soon as describe succeeds.
+func Exists(describe func() error) Condition {
+	return State(func() (string, error) { return "EXISTS", describe() }, "EXISTS")
+}
+
+// Deleted is done as soon as describe fails with a not-found error.
+func Deleted(describe func() error) Condition {
+	return func() (bool, string, error) {
+		err := describe()
+		switch {
+		case gcloud.IsNotFound(err):
+			return true, "deleted", nil
+		case err == nil:
+			return false, "still exists", nil
+		case gcloud.IsTransient(err):
+			return false, err.Error(), nil
+		default:
+			return false, "", err
+		}
+	}
+}
+
+// Deletes retries a delete call until it succeeds or the resource is already
+// gone. Transient failures, including a resource that is still in use by a
+// dependent being torn down, are retried.
+func Deletes(del func() error) Condition {
+	return func() (bool, string, error) {
+		err := del()
+		switch {
+		case err == nil || gcloud.IsNotFound(err):
+			return true, "deleted", nil
+		case gcloud.IsTransient(err):
+			return false, err.Error(), nil
+		default:
+			return false, "", err
+		}
+	}
+}
+
+// Check wraps a predicate that has no natural state, such as a verification
+// that is expected to pass eventually. Every error is treated as "not yet".
+func Check(check func() error) Condition {
+	return func() (bool, string, error) {
+		if err := check(); err != nil {
+			return false, err.Error(), nil
+		}
+		return true, "ok", nil
+	}
+}
+
+// NetworkExists waits for a VPC network to be readable.
+func NetworkExists(t testing.TestingT, c *gcloud.Client, name string) Condition {
+	return Exists(func() error {
+		_, err := c.Networks.Describe(t, name)
+		return err
+	})
+}
+
+// NetworkDeleted waits for a VPC network to disappear.
+func NetworkDeleted(t testing.TestingT, c *gcloud.Client, name string) Condition {
+	return Deleted(func() error {
+		_, err := c.Networks.Describe(t, name)
+		return err
+	})
+}
+
+// FirewallExists waits for a VPC firewall rule to be readable.
+func FirewallExists(t testing.TestingT, c *gcloud.Client, name string) Condition {
+	return Exists(func() error {
+		_, err := c.Firewalls.Describe(t, name)
+		return err
+	})
+}
+
+// SubnetReady waits for a subnetwork to be READY. Only proxy-only subnets
+// report a state, so an existing subnet without one is considered ready.
+func SubnetReady(t testing.TestingT, c *gcloud.Client, region, name string) Condition {
+	return State(func() (string, error) {
+		subnet, err := c.Subnets.Describe(t, region, name)
+		if err != nil {
+			return "", err
+		}
+		if subnet.State == "" {
+			return "READY", nil
+		}
+		return subnet.State, nil
+	}, "READY")
+}
+
+// SubnetDeleted waits for a subnetwork to disappear, which must happen before
+// its network can be deleted.
+func SubnetDeleted(t testing.TestingT, c *gcloud.Client, region, name string) Condition {
+	return Deleted(func() error {
+		_, err := c.Subnets.Describe(t, region, name)
+		return err
+	})
+}
+
+// OperationDone waits for a compute operation to reach DONE. An operation that
+// finished with an error aborts the wait.
+func OperationDone(t testing.TestingT, c *gcloud.Client, region, name string) Condition {
+	return func() (bool, string, error) {
+		op, err := c.Operations.Describe(t, region, name)
+		if err != nil {
+			if gcloud.IsTransient(err) {
+				return false, err.Error(), nil
+			}
+			return false, "", err
+		}
+		if op.Status == "DONE" && op.Error != nil && len(op.Error.Errors) > 0 {
+			return false, op.Status, fmt.Errorf("operation %s failed: %s", name, op.Error.Errors[0].Message)
+		}
+		return op.Status == "DONE", fmt.Sprintf("status %q", op.Status), nil
+	}
+}
+
+// InstanceRunning waits for a VM to reach RUNNING.
+func InstanceRunning(t testing.TestingT, c *gcloud.Client, zone, name string) Condition {
+	return State(func() (string, error) {
+		instance, err := c.Instances.Describe(t, zone, name)
+		if err != nil {
+			return "", err
+		}
+		return instance.Status, nil
+	}, "RUNNING")
+}
+
+// StartupScriptFinished waits for the guest agent to log that a VM's startup
+// script exited, which is when packages it installs become usable.
+func StartupScriptFinished(t testing.TestingT, c *gcloud.Client, zone, name string) Condition {
+	return Check(func() error {
+		output, err := c.Instances.SerialPortOutput(t, zone, name)
+		if err != nil {
+			return err
+		}
+		if !strings.Contains(output, "startup-script exit status") {
+			return fmt.Errorf("startup script still running")
+		}
+		return nil
+	})
+}
+
+// MIGStable waits for a managed instance group to report that all its
+// instances are created and running. Pass zone for zonal groups and region
+// for regional ones.
+func MIGStable(t testing.TestingT, c *gcloud.Client, region, zone, name string) Condition {
+	return func() (bool, string, error) {
+		mig, err := c.InstanceGroupManagers.Describe(t, region, zone, name)
+		if err != nil {
+			if gcloud.IsNotFound(err) || gcloud.IsTransient(err) {
+				return false, err.Error(), nil
+			}
+			return false, "", err
+		}
+		return mig.Status.IsStable, fmt.Sprintf("isStable=%t", mig.Status.IsStable), nil
+	}
+}
+
+// SQLInstanceRunnable waits for a Cloud SQL instance to be RUNNABLE.
+func SQLInstanceRunnable(t testing.TestingT, c *gcloud.Client, name string) Condition {
+	return State(func() (string, error) {
+		instance, err := c.SQL.Instances.Describe(t, name)
+		if err != nil {
+			return "", err
+		}
+		return instance.State, nil
+	}, "RUNNABLE")
+}
+
+// ServiceConnectionPolicyExists waits for a service connection policy to be
+// readable.
+func ServiceConnectionPolicyExists(t testing.TestingT, c *gcloud.Client, region, name string) Condition {
+	return Exists(func() error {
+		_, err := c.ServiceConnectionPolicies.Describe(t, region, name)
+		return err
+	})
+}
+
+// VPCPeeringConnected waits for a private services access connection to show
+// up on a network.
+func VPCPeeringConnected(t testing.TestingT, c *gcloud.Client, network string) Condition {
+	return func() (bool, string, error) {
+		peerings, err := c.VPCPeerings.List(t, network)
+		if err != nil {
+			if gcloud.IsTransient(err) {
+				return false, err.Error(), nil
+			}
+			return false, "", err
+		}
+		return len(peerings) > 0, fmt.Sprintf("%d peerings", len(peerings)), nil
+	}
+}
+
+// ServiceAccountExists waits for a newly created service account to be
+// readable, which IAM only guarantees eventually.
+func ServiceAccountExists(t testing.TestingT, c *gcloud.Client, email string) Condition {
+	return Exists(func() error {
+		_, err := c.IAM.ServiceAccounts.Describe(t, email)
+		return err
+	})
+}
+
+// CanImpersonate waits until the caller can mint tokens for a service
+// account. Permission denied is expected while bindings propagate.
+func CanImpersonate(t testing.TestingT, c *gcloud.Client, email string) Condition {
+	return Check(func() error {
+		_, err := c.IAM.ServiceAccounts.PrintAccessToken(t, email)
+		return err
+	})
+}
+
+// HasPermissions waits until a service account holds every permission on
+// resource, such as "projects/p" or "organizations/42". Roles granted to the
+// account take effect some time after their bindings are readable, and after
+// the account can be impersonated.
+func HasPermissions(t testing.TestingT, c *gcloud.Client, email, resource string, permissions []string) Condition {
+	return Check(func() error {
+		token, err := c.IAM.ServiceAccounts.PrintAccessToken(t, email)
+		if err != nil {
+			return err
+		}
+		held, err := c.IAM.TestPermissions(t, token, resource, permissions)
+		if err != nil {
+			return err
+		}
+		var missing []string
+		for _, p := range permissions {
+			if !slices.Contains(held, p) {
+				missing = append(missing, p)
+			}
+		}
+		if len(missing) > 0 {
+			return fmt.Errorf("%s does not hold %s on %s yet", email, strings.Join(missing, ", "), resource)
+		}
+		return nil
+	})
+}
+
+// APIsEnabled waits for every api to be reported as enabled on the project.
+// Enabling an API returns before it is listed, and before it can be used.
+func APIsEnabled(t testing.TestingT, c *gcloud.Client, apis ...string) Condition {
+	return Check(func() error {
+		enabled, err := c.APIs.ListEnabled(t)
+		if err != nil {
+			return err
+		}
+		for _, api := range apis {
+			if !slices.Contains(enabled, api) {
+				return fmt.Errorf("%s is not enabled yet", api)
+			}
+		}
+		return nil
+	})
+}
@@ -0,0 +1,140 @@
+// Copyright 2026 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package wait polls resource state until it reaches the expected value,
+// replacing the fixed sleeps the integration tests used to rely on. Polls back
+// off exponentially up to a ceiling, give up at a deadline and record how long
+// each wait actually took.
+package wait
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/gruntwork-io/terratest/modules/logger"
+	"github.com/gruntwork-io/terratest/modules/testing"
+)
+
+// Options control how a condition is polled.
+type Options struct {
+	// Timeout is the deadline for the condition to become true.
+	Timeout time.Duration
+	// Interval is the delay before the second poll. It doubles after every
+	// attempt up to MaxInterval.
+	Interval    time.Duration
+	MaxInterval time.Duration
+}
+
+// DefaultOptions suit most control plane resources.
+var DefaultOptions = Options{Timeout: 10 * time.Minute, Interval: 5 * time.Second, MaxInterval: time.Minute}
+
+// LongOptions suit slow resources such as Cloud SQL or AlloyDB instances.
+var LongOptions = Options{Timeout: 45 * time.Minute, Interval: 30 * time.Second, MaxInterval: 2 * time.Minute}
+
+// Condition is polled until it reports done. status is a short description of
+// the observed state used in logs. A non-nil error aborts the wait; conditions
+// that can recover from an error should return false and a nil error instead.
+type Condition func() (done bool, status string, err error)
+
+// Record is the outcome of one wait.
+type Record struct {
+	Test        string
+	Description string
+	Attempts    int
+	Elapsed     time.Duration
+	Err         error
+}
+
+var (
+	mu      sync.Mutex
+	records []Record
+)
+
+// Records returns every wait completed by this process, in completion order.
+func Records() []Record {
+	mu.Lock()
+	defer mu.Unlock()
+	return append([]Record(nil), records...)
+}
+
+// TimeoutError is returned when a condition does not become true in time.
+type TimeoutError struct {
+	Description string
+	Elapsed     time.Duration
+	LastStatus  string
+}
+
+func (e *TimeoutError) Error() string {
+	return fmt.Sprintf("timed out after %s waiting for %s (last status: %s)", e.Elapsed.Round(time.Second), e.Description, e.LastStatus)
+}
+
+// ForE polls cond until it reports done, returns an error or opts.Timeout
+// elapses. It returns the time spent waiting.
+func ForE(t testing.TestingT, description string, opts Options, cond Condition) (time.Duration, error) {
+	opts = withDefaults(opts)
+	start := time.Now()
+	interval := opts.Interval
+	attempt := 0
+	var status string
+	var err error
+	for {
+		attempt++
+		var done bool
+		done, status, err = cond()
+		elapsed := time.Since(start)
+		if err != nil {
+			break
+		}
+		if done {
+			logger.Default.Logf(t, "Waited %s for %s (%d attempts, status: %s)", elapsed.Round(time.Second), description, attempt, status)
+			break
+		}
+		remaining := opts.Timeout - elapsed
+		if remaining <= 0 {
+			err = &TimeoutError{Description: description, Elapsed: elapsed, LastStatus: status}
+			break
+		}
+		logger.Default.Logf(t, "Waiting for %s: %s (attempt %d, %s elapsed)", description, status, attempt, elapsed.Round(time.Second))
+		time.Sleep(min(interval, remaining))
+		interval = min(interval*2, opts.MaxInterval)
+	}
+	elapsed := time.Since(start)
+	mu.Lock()
+	records = append(records, Record{Test: t.Name(), Description: description, Attempts: attempt, Elapsed: elapsed, Err: err})
+	mu.Unlock()
+	return elapsed, err
+}
+
+// For is like ForE but fails the test if the condition is not met.
+func For(t testing.TestingT, description string, opts Options, cond Condition) time.Duration {
+	elapsed, err := ForE(t, description, opts, cond)
+	if err != nil {
+		t.Fatalf("Error waiting for %s: %v", description, err)
+	}
+	return elapsed
+}
+
+func withDefaults(opts Options) Options {
+	if opts.Timeout == 0 {
+		opts.Timeout = DefaultOptions.Timeout
+	}
+	if opts.Interval == 0 {
+		opts.Interval = DefaultOptions.Interval
+	}
+	if opts.MaxInterval == 0 {
+		opts.MaxInterval = max(DefaultOptions.MaxInterval, opts.Interval)
+	}
+	return opts
+}
@@ -0,0 +1,85 @@
+// Copyright 2026 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package wait
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/GoogleCloudPlatform/cloudnetworking-config-solutions/common_utils/gcloud"
+)
+
+var fastOptions = Options{Timeout: time.Second, Interval: time.Millisecond, MaxInterval: 4 * time.Millisecond}
+
+func TestForEPollsUntilDone(t *testing.T) {
+	states := []string{"PENDING_CREATE", "PENDING_CREATE", "RUNNABLE"}
+	calls := 0
+	cond := State(func() (string, error) {
+		state := states[calls]
+		calls++
+		return state, nil
+	}, "RUNNABLE")
+
+	if _, err := ForE(t, "instance to be RUNNABLE", fastOptions, cond); err != nil {
+		t.Fatalf("ForE() returned error: %v", err)
+	}
+	if got, want := calls, 3; got != want {
+		t.Errorf("Condition calls = %v, want = %v", got, want)
+	}
+	last := Records()[len(Records())-1]
+	if got, want := last.Attempts, 3; got != want {
+		t.Errorf("Record Attempts = %v, want = %v", got, want)
+	}
+	if got, want := last.Description, "instance to be RUNNABLE"; got != want {
+		t.Errorf("Record Description = %v, want = %v", got, want)
+	}
+}
+
+func TestForETimesOut(t *testing.T) {
+	opts := Options{Timeout: 20 * time.Millisecond, Interval: time.Millisecond, MaxInterval: 2 * time.Millisecond}
+	_, err := ForE(t, "never", opts, func() (bool, string, error) { return false, "pending", nil })
+	var timeout *TimeoutError
+	if !errors.As(err, &timeout) {
+		t.Fatalf("ForE() error = %v, want = *TimeoutError", err)
+	}
+	if got, want := timeout.LastStatus, "pending"; got != want {
+		t.Errorf("TimeoutError LastStatus = %v, want = %v", got, want)
+	}
+}
+
+func TestStateTreatsNotFoundAsPending(t *testing.T) {
+	notFound := &gcloud.Error{Kind: gcloud.ErrNotFound, Err: errors.New("exit status 1")}
+	denied := &gcloud.Error{Kind: gcloud.ErrPermissionDenied, Err: errors.New("exit status 1")}
+
+	done, _, err := State(func() (string, error) { return "", notFound }, "READY")()
+	if done || err != nil {
+		t.Errorf("State(not found) = %v, %v, want = false, nil", done, err)
+	}
+	_, _, err = State(func() (string, error) { return "", denied }, "READY")()
+	if !errors.Is(err, gcloud.ErrPermissionDenied) {
+		t.Errorf("State(permission denied) error = %v, want = %v", err, gcloud.ErrPermissionDenied)
+	}
+}
+
+func TestDeleted(t *testing.T) {
+	notFound := &gcloud.Error{Kind: gcloud.ErrNotFound, Err: errors.New("exit status 1")}
+	if done, _, _ := Deleted(func() error { return nil })(); done {
+		t.Errorf("Deleted(exists) done = true, want = false")
+	}
+	if done, _, _ := Deleted(func() error { return notFound })(); !done {
+		t.Errorf("Deleted(not found) done = false, want = true")
+	}
+}
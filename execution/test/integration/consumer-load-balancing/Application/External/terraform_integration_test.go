@@ -16,13 +16,22 @@ package integrationtest
 
 import (
 	"fmt"
-	"math/rand"
 	"os"
 	"path/filepath"
 	"strings"
 	"testing"
 	"time"
 
+	"github.com/GoogleCloudPlatform/cloudnetworking-config-solutions/common_utils/fixture"
+	"github.com/GoogleCloudPlatform/cloudnetworking-config-solutions/common_utils/gcloud"
+	"github.com/GoogleCloudPlatform/cloudnetworking-config-solutions/common_utils/httpcheck"
+	"github.com/GoogleCloudPlatform/cloudnetworking-config-solutions/common_utils/ipam"
+	"github.com/GoogleCloudPlatform/cloudnetworking-config-solutions/common_utils/naming"
+	"github.com/GoogleCloudPlatform/cloudnetworking-config-solutions/common_utils/preflight"
+	"github.com/GoogleCloudPlatform/cloudnetworking-config-solutions/common_utils/profile"
+	"github.com/GoogleCloudPlatform/cloudnetworking-config-solutions/common_utils/retryable"
+	"github.com/GoogleCloudPlatform/cloudnetworking-config-solutions/common_utils/schema"
+	"github.com/GoogleCloudPlatform/cloudnetworking-config-solutions/common_utils/wait"
 	"github.com/gruntwork-io/terratest/modules/shell"
 	"github.com/gruntwork-io/terratest/modules/terraform"
 	"github.com/tidwall/gjson"
@@ -37,67 +46,53 @@ var (
 )
 
 var (
-	projectID        = os.Getenv("TF_VAR_project_id")
-	instanceName     = fmt.Sprintf("lb-%d", rand.Int())
-	region           = "us-central1"
-	networkName      = fmt.Sprintf("vpc-%s-test", instanceName)
-	subnetName       = fmt.Sprintf("%s-subnet", networkName)
-	migName          = fmt.Sprintf("mig-%s", instanceName)               // Name for the Managed Instance Group
-	templateName     = fmt.Sprintf("%s-instance-template", instanceName) // Name for the Instance Template
-	firewallRuleName = fmt.Sprintf("%s-firewall-rule", networkName)
+	env, envErr = profile.Current()
+	projectID   = env.Projects.Endpoint
+	region      = env.Regions.Default
+	// The names below are derived from the run and the test by setNames.
+	instanceName     string
+	networkName      string
+	subnetName       string
+	migName          string // Name for the Managed Instance Group
+	templateName     string // Name for the Instance Template
+	firewallRuleName string
 )
 
 const (
 	defaultHCLBName = "load-balancer-default-hc"
 	customHCLBName  = "load-balancer-custom-hc"
+	// backendGreeting is served by the backends on / and on the /healthz
+	// path of the custom health check.
+	backendGreeting = "Hello from the load balancer test backend"
 )
 
-// LoadBalancerConfig struct
-type LoadBalancerConfig struct {
-	Name      string         `yaml:"name"`
-	ProjectID string         `yaml:"project_id"`
-	Network   string         `yaml:"network"`
-	Backends  BackendsConfig `yaml:"backends"`
-}
-
-// MIGInstanceConfig struct
-type MIGInstanceConfig struct {
-	Name       string `yaml:"name"`
-	ProjectID  string `yaml:"project_id"`
-	Region     string `yaml:"region"`
-	Image      string `yaml:"image"`
-	Network    string `yaml:"network"`
-	Subnetwork string `yaml:"subnetwork"`
-}
-
-type BackendsConfig struct {
-	Default BackendConfig `yaml:"default"`
-}
-
-type BackendConfig struct {
-	Protocol    string        `yaml:"protocol"`
-	Port        int           `yaml:"port"`
-	PortName    string        `yaml:"port_name"`
-	TimeoutSec  int           `yaml:"timeout_sec"`
-	EnableCdn   bool          `yaml:"enable_cdn"`
-	HealthCheck HealthCheck   `yaml:"health_check"`
-	LogConfig   LogConfig     `yaml:"log_config"`
-	Groups      []GroupConfig `yaml:"groups"`
-}
-
-type HealthCheck struct {
-	RequestPath string `yaml:"request_path"`
-	Port        int    `yaml:"port"`
-}
-
-type LogConfig struct {
-	Enable     bool    `yaml:"enable"`
-	SampleRate float64 `yaml:"sample_rate"`
-}
-
-type GroupConfig struct {
-	Group  string `yaml:"group"`
-	Region string `yaml:"region"`
+// backendStartupScript serves backendGreeting on port 80 with the Python of
+// the Ubuntu image, so that the backends need no package installed.
+var backendStartupScript = `#!/bin/bash
+mkdir -p /var/www && cd /var/www
+echo "` + backendGreeting + `" > index.html
+cp index.html healthz
+nohup python3 -m http.server 80 >/dev/null 2>&1 &
+`
+
+// servingOptions bound the wait for a load balancer to serve, which takes a
+// few minutes after it is created.
+var servingOptions = wait.Options{Timeout: 15 * time.Minute, Interval: 30 * time.Second, MaxInterval: time.Minute}
+
+// TestMain checks the APIs, roles and quota the suite needs before any load
+// balancer is created.
+func TestMain(m *testing.M) {
+	os.Exit(preflight.Run(m, preflight.Requirements{
+		Profile: envErr,
+		Project: projectID,
+		APIs:    []string{"compute.googleapis.com"},
+		Roles:   []string{"roles/compute.loadBalancerAdmin", "roles/compute.instanceAdmin.v1", "roles/compute.networkAdmin", "roles/compute.securityAdmin"},
+		Quotas: []preflight.Quota{
+			{Metric: "FORWARDING_RULES", Need: 2},
+			{Metric: "BACKEND_SERVICES", Need: 2},
+			{Metric: "INSTANCES", Region: region, Need: 2},
+		},
+	}))
 }
 
 /*
@@ -109,13 +104,15 @@ Resources are cleaned up after the test to ensure no lingering infrastructure re
 */
 
 func TestCreateLoadBalancers(t *testing.T) {
+	profile.Require(t, "projects.endpoint")
+	setNames(t)
 	createLoadBalancerYAML(t) // Create YAML configurations
 
 	tfVars := map[string]interface{}{
 		"config_folder_path": configFolderPath,
 	}
 
-	terraformOptions := terraform.WithDefaultRetryableErrors(t, &terraform.Options{
+	terraformOptions := retryable.With(t, &terraform.Options{
 		Vars:                 tfVars,
 		TerraformDir:         terraformDirectoryPath,
 		Reconfigure:          true,
@@ -124,18 +121,16 @@ func TestCreateLoadBalancers(t *testing.T) {
 		SetVarsAfterVarFiles: true,
 	})
 
-	createVPC(t, projectID, networkName)
-	time.Sleep(60 * time.Second)
+	// The prerequisites are fixtures, torn down when the test ends after the
+	// load balancers that use them.
+	network, subnet := createVPC(t, projectID, networkName)
+	client := gcloud.New(projectID)
+	wait.For(t, "subnet "+networkName+"-subnet to be ready", wait.DefaultOptions, wait.SubnetReady(t, client, region, networkName+"-subnet"))
 
-	defer deleteVPC(t, projectID, networkName)
-	defer deleteInstanceTemplate(t)              // Delete Instance Template after the test
-	defer deleteManagedInstanceGroup(t)          // Delete MIG after the test
-	defer terraform.Destroy(t, terraformOptions) // Ensure resources are cleaned up after the test
-
-	createFirewallRule(t, projectID, networkName) // Create firewall rule
-	defer deleteFirewallRule(t, projectID)        // Delete firewall rule
-	createInstanceTemplate(t)                     // Create MIG Instance Template
-	createManagedInstanceGroup(t)                 // Create MIG using gcloud command
+	firewallRule := createFirewallRule(t, projectID, networkName, network) // Create firewall rule
+	template := createInstanceTemplate(t, subnet)                          // Create MIG Instance Template
+	mig := createManagedInstanceGroup(t, template)                         // Create MIG using gcloud command
+	fixture.Terraform(t, terraformOptions, mig, firewallRule)
 
 	// In your TestCreateLoadBalancers function
 	if _, err := terraform.InitAndApplyE(t, terraformOptions); err != nil {
@@ -145,9 +140,6 @@ func TestCreateLoadBalancers(t *testing.T) {
 	loadBalancersOutput := terraform.OutputJson(t, terraformOptions, "load_balancers") // Fetch load balancer output
 	loadBalancers := gjson.Parse(loadBalancersOutput).Map()
 
-	maxRetries := 5
-	retryInterval := 15 * time.Second
-
 	lbNameToYaml := map[string]string{
 		defaultHCLBName: "instance1.yaml",
 		customHCLBName:  "instance2.yaml",
@@ -156,32 +148,23 @@ func TestCreateLoadBalancers(t *testing.T) {
 	for lbName := range loadBalancers {
 		backendServiceName := fmt.Sprintf("%s-backend-default", lbName)
 
-		for i := 0; i < maxRetries; i++ {
-			gcloudOutput := shell.RunCommandAndGetOutput(t, shell.Command{
-				Command: "gcloud",
-				Args:    []string{"compute", "backend-services", "describe", backendServiceName, "--global", "--project", projectID},
-			})
-
-			if strings.Contains(gcloudOutput, "was not found") {
-				t.Logf("Backend service '%s' not found yet. Retrying...", backendServiceName)
-				time.Sleep(retryInterval)
-				continue
-			}
-
-			t.Logf("Backend service '%s' exists.", backendServiceName)
-			verifyLoadBalancerConfiguration(t, lbName, lbNameToYaml, terraformOptions)
-			break
-		}
+		wait.For(t, "backend service "+backendServiceName+" to exist", wait.DefaultOptions, wait.Exists(func() error {
+			_, err := client.Run(t, "compute", "backend-services", "describe", backendServiceName, "--global")
+			return err
+		}))
+		t.Logf("Backend service '%s' exists.", backendServiceName)
+		verifyLoadBalancerConfiguration(t, lbName, lbNameToYaml, terraformOptions)
 	}
 }
 
 /*
 createInstanceTemplate creates a Google Cloud instance template using the gcloud command.
 It configures the template with specified machine type, image, network, and other parameters.
-An error is logged if the creation fails.
+The test fails if the creation fails, and the template is deleted when the test
+ends, after the instance groups that use it.
 */
 
-func createInstanceTemplate(t *testing.T) {
+func createInstanceTemplate(t *testing.T, subnet *fixture.Handle) *fixture.Handle {
 	cmd := shell.Command{
 		Command: "gcloud",
 		Args: []string{
@@ -197,21 +180,34 @@ func createInstanceTemplate(t *testing.T) {
 			"--region", region,
 			"--tags", "http-server",
 			"--project", projectID,
+			// The ^~~^ prefix changes the list delimiter so that the script
+			// is passed as is.
+			"--metadata=^~~^startup-script=" + backendStartupScript,
 		},
 	}
 
 	if _, err := shell.RunCommandAndGetOutputE(t, cmd); err != nil {
-		t.Errorf("Failed to create Instance Template: %v", err)
-	}
+		t.Fatalf("Failed to create Instance Template: %v", err)
+	}
+	client := gcloud.New(projectID)
+	res := fixture.Resource{Kind: "instance template", ID: fmt.Sprintf("projects/%s/global/instanceTemplates/%s", projectID, templateName)}
+	return fixture.Register(t, res, func() error {
+		_, err := wait.ForE(t, res.String()+" to be deleted", wait.DefaultOptions, wait.Deletes(func() error {
+			_, err := client.Run(t, "compute", "instance-templates", "delete", templateName)
+			return err
+		}))
+		return err
+	}, subnet)
 }
 
 /*
 createManagedInstanceGroup creates a Managed Instance Group (MIG) in Google Cloud using
 the gcloud command. It sets the base instance name, size, and template for the group.
-An error is logged if the creation fails.
+The test fails if the creation fails, and the group is deleted when the test
+ends, after the load balancers that use it.
 */
 
-func createManagedInstanceGroup(t *testing.T) {
+func createManagedInstanceGroup(t *testing.T, template *fixture.Handle) *fixture.Handle {
 	cmd := shell.Command{
 		Command: "gcloud",
 		Args: []string{
@@ -229,8 +225,29 @@ func createManagedInstanceGroup(t *testing.T) {
 	}
 
 	if _, err := shell.RunCommandAndGetOutputE(t, cmd); err != nil {
-		t.Errorf("Failed to create Managed Instance Group: %v", err)
-	}
+		t.Fatalf("Failed to create Managed Instance Group: %v", err)
+	}
+	client := gcloud.New(projectID)
+	res := fixture.Resource{Kind: "managed instance group", ID: fmt.Sprintf("projects/%s/regions/%s/instanceGroupManagers/%s", projectID, region, migName)}
+	return fixture.Register(t, res, func() error {
+		_, err := wait.ForE(t, res.String()+" to be deleted", wait.DefaultOptions, wait.Deletes(func() error {
+			return client.InstanceGroupManagers.Delete(t, region, "", migName)
+		}))
+		return err
+	}, template)
+}
+
+/*
+setNames is a helper function which derives the names of the backends of the
+load balancers and of their network from the run and the test.
+*/
+func setNames(t *testing.T) {
+	instanceName = naming.Name(t, naming.Compute, "lb")
+	networkName = fmt.Sprintf("vpc-%s-test", instanceName)
+	subnetName = fmt.Sprintf("%s-subnet", networkName)
+	migName = fmt.Sprintf("mig-%s", instanceName)
+	templateName = fmt.Sprintf("%s-instance-template", instanceName)
+	firewallRuleName = fmt.Sprintf("%s-firewall-rule", networkName)
 }
 
 /*
@@ -242,27 +259,12 @@ and writes them to specified files. Errors during marshaling or file operations
 func createLoadBalancerYAML(t *testing.T) {
 	t.Log("========= YAML Files for Health Checks =========")
 
-	minimalHC := struct {
-		Name     string `yaml:"name"`
-		Project  string `yaml:"project"`
-		Network  string `yaml:"network"`
-		Backends struct {
-			Default struct {
-				Groups []struct {
-					Group  string `yaml:"group"`
-					Region string `yaml:"region"`
-				} `yaml:"groups"`
-			} `yaml:"default"`
-		} `yaml:"backends"`
-	}{
+	minimalHC := schema.ApplicationLoadBalancer{
 		Name:    "load-balancer-default-hc",
 		Project: projectID,
 		Network: networkName,
 	}
-	minimalHC.Backends.Default.Groups = append(minimalHC.Backends.Default.Groups, struct {
-		Group  string `yaml:"group"`
-		Region string `yaml:"region"`
-	}{Group: migName, Region: region})
+	minimalHC.Backends.Default.Groups = append(minimalHC.Backends.Default.Groups, schema.ALBGroup{Group: migName, Region: region})
 
 	yamlMinimalData, err := yaml.Marshal(&minimalHC)
 	if err != nil {
@@ -271,35 +273,26 @@ func createLoadBalancerYAML(t *testing.T) {
 	}
 
 	// Create maximal health check configuration
-	maximalHC := struct {
-		Name     string `yaml:"name"`
-		Project  string `yaml:"project"`
-		Network  string `yaml:"network"`
-		Backends struct {
-			Default BackendConfig `yaml:"default"` // Ensure this matches the expected type
-		} `yaml:"backends"`
-	}{
+	maximalHC := schema.ApplicationLoadBalancer{
 		Name:    "load-balancer-custom-hc",
 		Project: projectID,
 		Network: networkName,
-		Backends: struct {
-			Default BackendConfig `yaml:"default"` // Match the expected type with tag
-		}{
-			Default: BackendConfig{
+		Backends: schema.ALBBackends{
+			Default: schema.ALBBackend{
 				Protocol:   "HTTP",
-				Port:       80,
+				Port:       schema.Int(80),
 				PortName:   "http",
-				TimeoutSec: 30,
-				EnableCdn:  false,
-				HealthCheck: HealthCheck{
-					RequestPath: "/healthz",
-					Port:        80,
+				TimeoutSec: schema.Int(30),
+				EnableCDN:  schema.Bool(false),
+				HealthCheck: map[string]interface{}{
+					"request_path": "/healthz",
+					"port":         80,
 				},
-				LogConfig: LogConfig{
-					Enable:     true,
-					SampleRate: 0.5,
+				LogConfig: &schema.ALBLogConfig{
+					Enable:     schema.Bool(true),
+					SampleRate: schema.Float(0.5),
 				},
-				Groups: []GroupConfig{{Group: migName, Region: region}},
+				Groups: []schema.ALBGroup{{Group: migName, Region: region}},
 			},
 		},
 	}
@@ -352,19 +345,12 @@ func verifyLoadBalancerConfiguration(t *testing.T, lbName string, lbNameToYaml m
 	yamlFilePath := filepath.Join(configFolderPath, yamlFileName)
 	t.Logf("Reading the YAML for %s from file %s", lbName, yamlFilePath)
 
-	yamlFile, err := os.ReadFile(yamlFilePath)
-	if err != nil {
+	var expectedLB schema.ApplicationLoadBalancer
+	if err := schema.Load(yamlFilePath, &expectedLB); err != nil {
 		t.Errorf("Error reading YAML file %s: %s", yamlFilePath, err)
 		return
 	}
 
-	var expectedLB LoadBalancerConfig
-	err = yaml.Unmarshal(yamlFile, &expectedLB)
-	if err != nil {
-		t.Errorf("Error unmarshaling YAML for Load Balancer %s: %s", lbName, err)
-		return
-	}
-
 	t.Logf("Verifying Load Balancer configuration for %s...", lbName)
 
 	loadBalancersOutput := terraform.OutputJson(t, terraformOptions, "load_balancers")
@@ -438,162 +424,60 @@ func verifyLoadBalancerConfiguration(t *testing.T, lbName string, lbNameToYaml m
 	if !migFound {
 		t.Logf("No Managed Instance Groups (MIG) found in Load Balancer %s configuration.", lbName)
 	}
-}
-
-/*
-createVPC creates a Virtual Private Cloud (VPC) network and a subnet in Google Cloud
-using the gcloud command. It sets the VPC to custom subnet mode and specifies routing
-options. Errors encountered during the execution of the commands are logged.
-*/
 
-func createVPC(t *testing.T, projectID string, networkName string) {
-	cmd := shell.Command{
-		Command: "gcloud",
-		Args: []string{"compute", "networks", "create", networkName,
-			"--project=" + projectID,
-			"--format=json",
-			"--bgp-routing-mode=global",
-			"--subnet-mode=custom"},
-	}
-	if _, err := shell.RunCommandAndGetOutputE(t, cmd); err != nil {
-		t.Logf("===Error %s Encountered while executing gcloud command to create VPC.", err)
-	}
-
-	subnetName := fmt.Sprintf("%s-subnet", networkName)
-	cmd = shell.Command{
-		Command: "gcloud",
-		Args: []string{"compute", "networks", "subnets", "create", subnetName,
-			"--project=" + projectID,
-			"--network=" + networkName,
-			"--region=" + region,
-			"--range=10.0.0.0/24"},
-	}
-	if _, err := shell.RunCommandAndGetOutputE(t, cmd); err != nil {
-		t.Logf("===Error %s Encountered while executing gcloud command to create subnet.", err)
-	}
-}
-
-/*
-deleteVPC deletes a Virtual Private Cloud (VPC) network and its associated subnet
-in Google Cloud using the gcloud command. It includes delays to ensure resources
-are fully released before deletion. Errors encountered during the execution of the
-commands are logged.
-*/
-
-func deleteVPC(t *testing.T, projectID string, networkName string) {
-	time.Sleep(120 * time.Second)
-
-	subnetName := fmt.Sprintf("%s-subnet", networkName)
-	cmd := shell.Command{
-		Command: "gcloud",
-		Args: []string{"compute", "networks", "subnets", "delete", subnetName,
-			"--project=" + projectID,
-			"--region=" + region,
-			"--quiet"},
-	}
-	if _, err := shell.RunCommandAndGetOutputE(t, cmd); err != nil {
-		t.Errorf("===Error %s Encountered while executing gcloud command to delete subnet.", err)
-	}
-
-	time.Sleep(150 * time.Second)
-
-	cmd = shell.Command{
-		Command: "gcloud",
-		Args:    []string{"compute", "networks", "delete", networkName, "--project=" + projectID, "--quiet"},
-	}
-	if _, err := shell.RunCommandAndGetOutputE(t, cmd); err != nil {
-		t.Errorf("===Error %s Encountered while executing gcloud command to delete VPC.", err)
+	lbURL := "http://" + actualLBDetails.Get("external_ip").String() + "/"
+	t.Logf("Verifying that Load Balancer %s serves the backends at %s", lbName, lbURL)
+	if _, err := httpcheck.VerifyE(t, httpcheck.Check{
+		URL:   lbURL,
+		Match: []httpcheck.Matcher{httpcheck.BodyContains(backendGreeting)},
+		Retry: servingOptions,
+	}); err != nil {
+		t.Errorf("Load Balancer %s does not serve the backends: %v", lbName, err)
+	} else {
+		t.Logf("Load Balancer %s serves the backends.", lbName)
 	}
 }
 
 /*
-deleteManagedInstanceGroup deletes a Managed Instance Group (MIG) in Google Cloud
-using the gcloud command. It logs an error if the deletion fails.
+createVPC creates a Virtual Private Cloud (VPC) network and a subnet in Google Cloud
+as fixtures, which are deleted when the test ends after the resources using them.
+The test fails if either cannot be created.
 */
 
-func deleteManagedInstanceGroup(t *testing.T) {
-	cmd := shell.Command{
-		Command: "gcloud",
-		Args: []string{
-			"compute",
-			"instance-groups",
-			"managed",
-			"delete",
-			migName,
-			"--region=" + region,
-			"--project=" + projectID,
-			"--quiet",
-		},
+func createVPC(t *testing.T, projectID string, networkName string) (network, subnet *fixture.Handle) {
+	client := gcloud.New(projectID)
+	network, err := fixture.Network(t, client, networkName, gcloud.NetworkOptions{BGPRoutingMode: "global"})
+	if err != nil {
+		t.Fatalf("===Error %s Encountered while creating VPC %s.", err, networkName)
 	}
 
-	if _, err := shell.RunCommandAndGetOutputE(t, cmd); err != nil {
-		t.Errorf("Failed to delete Managed Instance Group %s: %v", migName, err)
+	subnetName := fmt.Sprintf("%s-subnet", networkName)
+	subnet, err = fixture.Subnet(t, client, subnetName, gcloud.SubnetOptions{
+		Network: networkName,
+		Region:  region,
+		Range:   ipam.Subnet(t, 24),
+	}, network)
+	if err != nil {
+		t.Fatalf("===Error %s Encountered while creating subnet %s.", err, subnetName)
 	}
+	return network, subnet
 }
 
 /*
-deleteInstanceTemplate deletes an instance template in Google Cloud using the gcloud
-command. An error is logged if the deletion fails.
+createFirewallRule allows the health checks to reach the backends as a fixture,
+which is deleted when the test ends.
 */
 
-func deleteInstanceTemplate(t *testing.T) {
-	cmd := shell.Command{
-		Command: "gcloud",
-		Args: []string{
-			"compute",
-			"instance-templates",
-			"delete",
-			"--project=" + projectID,
-			templateName,
-			"--quiet",
-		},
-	}
-
-	if _, err := shell.RunCommandAndGetOutputE(t, cmd); err != nil {
-		t.Errorf("Failed to delete Instance Template: %v", err)
-	}
-}
-
-func createFirewallRule(t *testing.T, projectID string, networkName string) {
-	healthCheckIPRanges := []string{"130.211.0.0/22", "35.191.0.0/16"}
-
-	cmd := shell.Command{
-		Command: "gcloud",
-		Args: []string{
-			"compute",
-			"firewall-rules",
-			"create",
-			firewallRuleName,
-			"--project=" + projectID,
-			"--network=" + networkName,
-			"--allow=tcp:80", // Allow TCP on port 80
-			"--source-ranges=" + strings.Join(healthCheckIPRanges, ","),
-			"--target-tags=http-server", // Apply to instances with the http-server tag
-		},
-	}
-
-	if _, err := shell.RunCommandAndGetOutputE(t, cmd); err != nil {
-		t.Errorf("Failed to create firewall rule: %v", err)
+func createFirewallRule(t *testing.T, projectID string, networkName string, network *fixture.Handle) *fixture.Handle {
+	rule, err := fixture.Firewall(t, gcloud.New(projectID), firewallRuleName, gcloud.FirewallOptions{
+		Network:      networkName,
+		Allow:        []string{"tcp:80"},                          // Allow TCP on port 80
+		SourceRanges: []string{"130.211.0.0/22", "35.191.0.0/16"}, // Health check ranges
+		TargetTags:   []string{"http-server"},                     // Apply to instances with the http-server tag
+	}, network)
+	if err != nil {
+		t.Fatalf("Failed to create firewall rule: %v", err)
 	}
 	t.Log("Successfully created firewall rule to allow health check traffic.")
-}
-
-func deleteFirewallRule(t *testing.T, projectID string) {
-
-	cmd := shell.Command{
-		Command: "gcloud",
-		Args: []string{
-			"compute",
-			"firewall-rules",
-			"delete",
-			firewallRuleName,
-			"--project=" + projectID,
-			"--quiet",
-		},
-	}
-
-	if _, err := shell.RunCommandAndGetOutputE(t, cmd); err != nil {
-		t.Errorf("Failed to delete firewall rule: %v", err)
-	}
-	t.Log("Successfully deleted firewall rule.")
+	return rule
 }
@@ -18,15 +18,24 @@ package integrationtest
 
 import (
 	"fmt"
-	"math/rand"
 	"os"
 	"path/filepath"
-	"regexp"
+	"strconv"
 	"strings"
 	"testing"
 	"time"
 
-	"github.com/gruntwork-io/terratest/modules/retry"
+	"github.com/GoogleCloudPlatform/cloudnetworking-config-solutions/common_utils/cassette"
+	"github.com/GoogleCloudPlatform/cloudnetworking-config-solutions/common_utils/fixture"
+	"github.com/GoogleCloudPlatform/cloudnetworking-config-solutions/common_utils/gcloud"
+	"github.com/GoogleCloudPlatform/cloudnetworking-config-solutions/common_utils/ipam"
+	"github.com/GoogleCloudPlatform/cloudnetworking-config-solutions/common_utils/naming"
+	"github.com/GoogleCloudPlatform/cloudnetworking-config-solutions/common_utils/preflight"
+	"github.com/GoogleCloudPlatform/cloudnetworking-config-solutions/common_utils/probe"
+	"github.com/GoogleCloudPlatform/cloudnetworking-config-solutions/common_utils/profile"
+	"github.com/GoogleCloudPlatform/cloudnetworking-config-solutions/common_utils/retryable"
+	"github.com/GoogleCloudPlatform/cloudnetworking-config-solutions/common_utils/schema"
+	"github.com/GoogleCloudPlatform/cloudnetworking-config-solutions/common_utils/wait"
 	"github.com/gruntwork-io/terratest/modules/shell"
 	"github.com/gruntwork-io/terratest/modules/terraform"
 	"github.com/tidwall/gjson"
@@ -42,19 +51,21 @@ var (
 
 var (
 	// nlbProjectID is set by TF_VAR_project_id environment variable
-	nlbProjectID         = os.Getenv("TF_VAR_project_id")
-	nlbInstanceName      = fmt.Sprintf("nlb-%d", rand.Int())
-	nlbRegion            = "us-central1" // Or make this configurable
-	nlbZone              = nlbRegion + "-a"
-	nlbNetworkName       = fmt.Sprintf("vpc-%s-test", nlbInstanceName)
-	nlbSubnetName        = fmt.Sprintf("%s-subnet", nlbNetworkName)
-	nlbMigName           = fmt.Sprintf("mig-%s-nlb-regional", nlbInstanceName)
-	nlbZonalMigName      = fmt.Sprintf("mig-%s-nlb-zonal", nlbInstanceName)
-	nlbTemplateName      = fmt.Sprintf("%s-it-nlb", nlbInstanceName)
-	nlbFwHcRuleName      = fmt.Sprintf("%s-fw-hc", nlbNetworkName)
-	nlbFwTrafficRuleName = fmt.Sprintf("%s-fw-traffic", nlbNetworkName)
-	nlbTestVmName        = fmt.Sprintf("test-vm-%s-nlb", nlbInstanceName)
-	nlbInstanceTag       = "nlb-backend-instance"
+	env, envErr    = profile.Current()
+	nlbProjectID   = env.Projects.Endpoint
+	nlbRegion      = env.Regions.Default
+	nlbZone        = nlbRegion + "-a"
+	nlbInstanceTag = "nlb-backend-instance"
+	// The names below are derived from the run and the test by setNames.
+	nlbInstanceName      string
+	nlbNetworkName       string
+	nlbSubnetName        string
+	nlbMigName           string
+	nlbZonalMigName      string
+	nlbTemplateName      string
+	nlbFwHcRuleName      string
+	nlbFwTrafficRuleName string
+	nlbTestVmName        string
 )
 
 const (
@@ -64,110 +75,38 @@ const (
 	apachePort         = "80" // Port Apache listens on in the MIG instances
 )
 
-// NetworkLoadBalancerConfig struct for YAML parsing
-type NetworkLoadBalancerConfig struct {
-	Name            string                          `yaml:"name"`
-	ProjectID       string                          `yaml:"project_id"`
-	Region          string                          `yaml:"region"`
-	Description     string                          `yaml:"description,omitempty"`
-	Backends        []BackendItemConfig             `yaml:"backends"` // Slice for backend groups
-	HealthCheck     *NetworkHealthCheckConfig       `yaml:"health_check,omitempty"`
-	ForwardingRules map[string]ForwardingRuleConfig `yaml:"forwarding_rules,omitempty"`
-}
-
-// For individual backend group items
-type BackendItemConfig struct {
-	GroupName                    string                    `yaml:"group_name"`
-	GroupRegion                  string                    `yaml:"group_region,omitempty"`
-	GroupZone                    string                    `yaml:"group_zone,omitempty"`
-	Failover                     *bool                     `yaml:"failover,omitempty"`
-	Description                  string                    `yaml:"description,omitempty"`
-	Protocol                     string                    `yaml:"protocol,omitempty"`
-	PortName                     string                    `yaml:"port_name,omitempty"`
-	TimeoutSec                   *int                      `yaml:"timeout_sec,omitempty"`
-	ConnectionDrainingTimeoutSec *int                      `yaml:"connection_draining_timeout_sec,omitempty"`
-	LogSampleRate                *float64                  `yaml:"log_sample_rate,omitempty"`
-	LocalityLbPolicy             string                    `yaml:"locality_lb_policy,omitempty"`
-	SessionAffinity              string                    `yaml:"session_affinity,omitempty"`
-	ConnectionTracking           *ConnectionTrackingConfig `yaml:"connection_tracking,omitempty"`
-	FailoverConfig               *BackendFailoverConfig    `yaml:"failover_config,omitempty"`
-}
-
-type ConnectionTrackingConfig struct {
-	IdleTimeoutSec         *int   `yaml:"idle_timeout_sec,omitempty"`
-	PersistConnOnUnhealthy string `yaml:"persist_conn_on_unhealthy,omitempty"`
-	TrackPerSession        *bool  `yaml:"track_per_session,omitempty"`
-}
-
-type BackendFailoverConfig struct {
-	DisableConnDrain       *bool    `yaml:"disable_conn_drain,omitempty"`
-	DropTrafficIfUnhealthy *bool    `yaml:"drop_traffic_if_unhealthy,omitempty"`
-	Ratio                  *float64 `yaml:"ratio,omitempty"`
-}
-
-type NetworkHealthCheckConfig struct {
-	Name               string           `yaml:"name,omitempty"`
-	CheckIntervalSec   *int             `yaml:"check_interval_sec,omitempty"`
-	TimeoutSec         *int             `yaml:"timeout_sec,omitempty"`
-	HealthyThreshold   *int             `yaml:"healthy_threshold,omitempty"`
-	UnhealthyThreshold *int             `yaml:"unhealthy_threshold,omitempty"`
-	EnableLogging      *bool            `yaml:"enable_logging,omitempty"`
-	Description        string           `yaml:"description,omitempty"`
-	TCP                *TCPHealthCheck  `yaml:"tcp,omitempty"`
-	HTTP               *HTTPHealthCheck `yaml:"http,omitempty"`
-	HTTPS              *HTTPHealthCheck `yaml:"https,omitempty"`
-	HTTP2              *HTTPHealthCheck `yaml:"http2,omitempty"`
-	GRPC               *GRPCHealthCheck `yaml:"grpc,omitempty"`
-	SSL                *SSLHealthCheck  `yaml:"ssl,omitempty"`
-}
-
-type TCPHealthCheck struct {
-	Port              *int   `yaml:"port,omitempty"`
-	PortSpecification string `yaml:"port_specification,omitempty"`
-	Request           string `yaml:"request,omitempty"`
-	Response          string `yaml:"response,omitempty"`
-	ProxyHeader       string `yaml:"proxy_header,omitempty"`
-}
-
-type HTTPHealthCheck struct {
-	Port              *int   `yaml:"port,omitempty"`
-	PortSpecification string `yaml:"port_specification,omitempty"`
-	RequestPath       string `yaml:"request_path,omitempty"`
-	ProxyHeader       string `yaml:"proxy_header,omitempty"`
-	Response          string `yaml:"response,omitempty"`
-}
-
-type SSLHealthCheck struct {
-	Port              *int   `yaml:"port,omitempty"`
-	PortSpecification string `yaml:"port_specification,omitempty"`
-	Request           string `yaml:"request,omitempty"`
-	Response          string `yaml:"response,omitempty"`
-	ProxyHeader       string `yaml:"proxy_header,omitempty"`
-}
-
-type GRPCHealthCheck struct {
-	Port              *int   `yaml:"port,omitempty"`
-	PortSpecification string `yaml:"port_specification,omitempty"`
-	ServiceName       string `yaml:"service_name,omitempty"`
-}
-
-type ForwardingRuleConfig struct {
-	Address     string   `yaml:"address,omitempty"`
-	Description string   `yaml:"description,omitempty"`
-	IPv6        *bool    `yaml:"ipv6,omitempty"`
-	Name        string   `yaml:"name,omitempty"`
-	Ports       []string `yaml:"ports,omitempty"`
-	Protocol    string   `yaml:"protocol,omitempty"`
-	Subnetwork  string   `yaml:"subnetwork,omitempty"`
+// TestMain checks the APIs, roles and quota the suite needs before any load
+// balancer is created.
+func TestMain(m *testing.M) {
+	os.Exit(preflight.Run(m, preflight.Requirements{
+		Profile: envErr,
+		Project: nlbProjectID,
+		APIs:    []string{"compute.googleapis.com"},
+		Roles:   []string{"roles/compute.loadBalancerAdmin", "roles/compute.instanceAdmin.v1", "roles/compute.networkAdmin", "roles/compute.securityAdmin"},
+		Quotas: []preflight.Quota{
+			{Metric: "FORWARDING_RULES", Need: 3},
+			{Metric: "INSTANCES", Region: nlbRegion, Need: 5},
+		},
+	}))
 }
 
 // TestCreateNetworkLoadBalancer tests the creation and verification of Network Load Balancers
 func TestCreateNetworkLoadBalancer(t *testing.T) {
+	profile.Require(t, "projects.endpoint")
 	t.Parallel()
 
 	if nlbProjectID == "" {
 		t.Fatal("TF_VAR_project_id must be set as an environment variable.")
 	}
+	setNames(t)
+
+	// Record or replay the commands of the test when TEST_CASSETTE_MODE is
+	// set. The values below differ between the recording and the replay.
+	cas := cassette.Start(t)
+	cas.Vary("project_id", nlbProjectID)
+	cas.Vary("run_id", naming.RunID())
+	cas.Vary("expires", strconv.FormatInt(naming.Expiry().Unix(), 10))
+	cas.Vary("project_root", nlbProjectRoot)
 
 	createNetworkLoadBalancerYAML(t)
 
@@ -175,7 +114,7 @@ func TestCreateNetworkLoadBalancer(t *testing.T) {
 		"config_folder_path": nlbConfigFolderPath,
 	}
 
-	terraformOptions := terraform.WithDefaultRetryableErrors(t, &terraform.Options{
+	terraformOptions := retryable.With(t, &terraform.Options{
 		TerraformDir:         nlbTerraformDirectoryPath,
 		Vars:                 tfVars,
 		Reconfigure:          true,
@@ -184,31 +123,29 @@ func TestCreateNetworkLoadBalancer(t *testing.T) {
 		SetVarsAfterVarFiles: true,
 	})
 
-	nlbFwIapRuleName := fmt.Sprintf("%s-fw-iap-ssh", nlbNetworkName)
-
 	createVPC(t, nlbProjectID, nlbNetworkName)
-	t.Logf("Waiting for VPC %s to be ready...", nlbNetworkName)
-	time.Sleep(60 * time.Second)
+	wait.For(t, "subnet "+nlbNetworkName+"-subnet to be ready", wait.DefaultOptions, wait.SubnetReady(t, gcloud.New(nlbProjectID), nlbRegion, nlbNetworkName+"-subnet"))
 
-	// Defer cleanup (LIFO - Last In First Out)
-	defer deleteVPC(t, nlbProjectID, nlbNetworkName) // Runs absolutely last
+	// The VPC is a fixture so that it is deleted after the probe VM and its
+	// firewall rule, which are torn down when the test ends.
+	network := fixture.Register(t, fixture.Resource{Kind: "network", ID: fmt.Sprintf("projects/%s/global/networks/%s", nlbProjectID, nlbNetworkName)}, func() error {
+		deleteVPC(t, nlbProjectID, nlbNetworkName)
+		return nil
+	})
 
-	defer deleteFirewallRule(t, nlbProjectID, nlbFwIapRuleName)
+	// Defer cleanup (LIFO - Last In First Out)
 	defer deleteFirewallRule(t, nlbProjectID, nlbFwTrafficRuleName)
 	defer deleteFirewallRule(t, nlbProjectID, nlbFwHcRuleName)
 
-	defer deleteTestVM(t, nlbProjectID, nlbZone, nlbTestVmName)
-
 	defer deleteInstanceTemplateNLB(t) // Runs after both MIGs
 
 	defer deleteZonalManagedInstanceGroupNLB(t) // Runs before template
 	defer deleteManagedInstanceGroupNLB(t)      // Runs before zonal MIG & template
 
-	defer terraform.Destroy(t, terraformOptions) // Runs first in cleanup
+	defer cassette.Destroy(t, terraformOptions) // Runs first in cleanup
 
 	createFirewallRuleForNLBHealthChecks(t, nlbProjectID, nlbNetworkName, nlbFwHcRuleName, []string{nlbInstanceTag})
 	createFirewallRuleForNLBTraffic(t, nlbProjectID, nlbNetworkName, nlbFwTrafficRuleName, []string{apachePort, "9000"}, []string{nlbInstanceTag})
-	createFirewallRuleForIAP(t, nlbProjectID, nlbNetworkName, nlbFwIapRuleName, []string{"allow-iap-ssh"})
 
 	createInstanceTemplate(t, nlbTemplateName, nlbProjectID, nlbNetworkName, nlbSubnetName, nlbRegion, []string{nlbInstanceTag})
 
@@ -218,18 +155,18 @@ func TestCreateNetworkLoadBalancer(t *testing.T) {
 	createZonalManagedInstanceGroupNLB(t) // Zonal 'nlbZonalMigName'
 	setNamedPortsOnZonalMIG(t, nlbProjectID, nlbZone, nlbZonalMigName, "http", apachePort)
 
-	if _, err := terraform.InitAndApplyE(t, terraformOptions); err != nil {
+	if _, err := cassette.InitAndApplyE(t, terraformOptions); err != nil {
 		planJSON := terraform.Show(t, terraformOptions)
 		t.Logf("Terraform apply failed. Plan output for debugging:\n%s", planJSON)
 		t.Fatalf("Failed to apply Terraform configuration for NLB: %v", err)
 	}
 
-	nlbForwardingRuleAddresses := terraform.OutputJson(t, terraformOptions, "nlb_forwarding_rule_addresses")
+	nlbForwardingRuleAddresses := cassette.OutputJSON(t, terraformOptions, "nlb_forwarding_rule_addresses")
 	if !gjson.Valid(nlbForwardingRuleAddresses) {
 		t.Fatalf("Output 'nlb_forwarding_rule_addresses' is not valid JSON: %s", nlbForwardingRuleAddresses)
 	}
-	nlbForwardingRulesOutput := terraform.OutputJson(t, terraformOptions, "nlb_forwarding_rules")
-	nlbBackendServicesOutput := terraform.OutputJson(t, terraformOptions, "nlb_backend_services")
+	nlbForwardingRulesOutput := cassette.OutputJSON(t, terraformOptions, "nlb_forwarding_rules")
+	nlbBackendServicesOutput := cassette.OutputJSON(t, terraformOptions, "nlb_backend_services")
 
 	nlbNameToYaml := map[string]string{
 		fmt.Sprintf("lite-%s", nlbInstanceName):     minimalNLBYamlFile,
@@ -242,7 +179,20 @@ func TestCreateNetworkLoadBalancer(t *testing.T) {
 		t.Logf("No load balancers found in the output 'nlb_forwarding_rule_addresses'. Raw output: %s", nlbForwardingRuleAddresses)
 	}
 
-	createTestVM(t, nlbProjectID, nlbZone, nlbTestVmName, nlbNetworkName, nlbSubnetName)
+	client := gcloud.New(nlbProjectID)
+	client.Labels = naming.Labels(t)
+	vm, err := probe.NewVM(t, client, probe.VMOptions{
+		Name:    nlbTestVmName,
+		Zone:    nlbZone,
+		Network: nlbNetworkName,
+		Subnet:  nlbSubnetName,
+		// The load balancers have external addresses, which the VM reaches
+		// without Cloud NAT through its own.
+		ExternalAddress: true,
+	}, network)
+	if err != nil {
+		t.Fatalf("Failed to create test VM %s: %v", nlbTestVmName, err)
+	}
 
 	for lbNameFromOutput := range loadBalancersToTest {
 		t.Logf("Processing Load Balancer from output: %s", lbNameFromOutput)
@@ -266,7 +216,7 @@ func TestCreateNetworkLoadBalancer(t *testing.T) {
 			if ipAddress == "" {
 				t.Errorf("IP address for lite NLB %s (rule key '') is empty. Raw IPs: %s", lbNameFromOutput, lbFwdRuleIPs.Raw)
 			} else {
-				verifyConnectivityToNLB(t, nlbProjectID, nlbZone, nlbTestVmName, ipAddress, apachePort, "Instance.*responding")
+				verifyConnectivityToNLB(t, vm, ipAddress, apachePort)
 			}
 		} else if strings.HasPrefix(lbNameFromOutput, "expanded-") {
 			t.Logf("--- Starting Debug Block for Expanded NLB (%s) ---", lbNameFromOutput)
@@ -287,7 +237,7 @@ func TestCreateNetworkLoadBalancer(t *testing.T) {
 						Command: "gcloud",
 						Args:    []string{"compute", "forwarding-rules", "describe", frName, "--region", frRegion, "--project", nlbProjectID, "--format=json"},
 					}
-					frDetailsJsonString, errFrDescribe := shell.RunCommandAndGetOutputE(t, cmdFrDescribe)
+					frDetailsJsonString, errFrDescribe := cassette.Output(t, cmdFrDescribe)
 					if errFrDescribe != nil {
 						t.Logf("DEBUG: ERROR describing Forwarding Rule '%s': %v. Output: %s", frName, errFrDescribe, frDetailsJsonString)
 					} else {
@@ -308,7 +258,7 @@ func TestCreateNetworkLoadBalancer(t *testing.T) {
 					Command: "gcloud",
 					Args:    []string{"compute", "backend-services", "describe", bsName, "--region", bsRegion, "--project", nlbProjectID, "--format=json"},
 				}
-				bsDetailsJson, errBsDescribe := shell.RunCommandAndGetOutputE(t, cmdBsDescribe)
+				bsDetailsJson, errBsDescribe := cassette.Output(t, cmdBsDescribe)
 				if errBsDescribe != nil {
 					t.Logf("DEBUG: ERROR describing Backend Service '%s': %v. Output: %s", bsName, errBsDescribe, bsDetailsJson)
 				} else {
@@ -320,7 +270,7 @@ func TestCreateNetworkLoadBalancer(t *testing.T) {
 					Command: "gcloud",
 					Args:    []string{"compute", "backend-services", "get-health", bsName, "--region", bsRegion, "--project", nlbProjectID, "--format=json"},
 				}
-				bsHealthJson, errBsHealth := shell.RunCommandAndGetOutputE(t, cmdBsGetHealth)
+				bsHealthJson, errBsHealth := cassette.Output(t, cmdBsGetHealth)
 				if errBsHealth != nil {
 					t.Logf("DEBUG: ERROR getting health for Backend Service '%s': %v. Output: %s", bsName, errBsHealth, bsHealthJson)
 				} else {
@@ -333,7 +283,7 @@ func TestCreateNetworkLoadBalancer(t *testing.T) {
 				Command: "gcloud",
 				Args:    []string{"compute", "instance-groups", "managed", "describe", nlbMigName, "--region", nlbRegion, "--project", nlbProjectID, "--format=json"},
 			}
-			regMigDetailsJson, errRegMigDescribe := shell.RunCommandAndGetOutputE(t, cmdRegMigDescribe)
+			regMigDetailsJson, errRegMigDescribe := cassette.Output(t, cmdRegMigDescribe)
 			if errRegMigDescribe != nil {
 				t.Logf("DEBUG: ERROR describing REGIONAL MIG '%s': %v. Output: %s", nlbMigName, errRegMigDescribe, regMigDetailsJson)
 			} else {
@@ -343,7 +293,7 @@ func TestCreateNetworkLoadBalancer(t *testing.T) {
 					Command: "gcloud",
 					Args:    []string{"compute", "instance-groups", "managed", "list-instances", nlbMigName, "--region", nlbRegion, "--project", nlbProjectID, "--format=json"},
 				}
-				regMigInstancesJson, errRegMigList := shell.RunCommandAndGetOutputE(t, cmdRegMigListInstances)
+				regMigInstancesJson, errRegMigList := cassette.Output(t, cmdRegMigListInstances)
 				if errRegMigList != nil {
 					t.Logf("DEBUG: ERROR listing instances in REGIONAL MIG '%s': %v. Output: %s", nlbMigName, errRegMigList, regMigInstancesJson)
 				} else {
@@ -357,26 +307,42 @@ func TestCreateNetworkLoadBalancer(t *testing.T) {
 			if ipAddressRuleHttp == "" {
 				t.Errorf("IP address for Expanded NLB %s (rule 'rule-http') is empty.", lbNameFromOutput)
 			} else {
-				verifyConnectivityToNLB(t, nlbProjectID, nlbZone, nlbTestVmName, ipAddressRuleHttp, apachePort, "Instance.*responding")
+				verifyConnectivityToNLB(t, vm, ipAddressRuleHttp, apachePort)
 			}
 
 			ipAddressRuleCustom := lbFwdRuleIPs.Get("rule-custom-port").String()
 			if ipAddressRuleCustom == "" {
 				t.Errorf("IP address for Expanded NLB %s (rule 'rule-custom-port') is empty.", lbNameFromOutput)
 			} else {
-				verifyConnectivityToNLB(t, nlbProjectID, nlbZone, nlbTestVmName, ipAddressRuleCustom, "9000", "Instance.*responding")
+				verifyConnectivityToNLB(t, vm, ipAddressRuleCustom, "9000")
 			}
 		} else if strings.HasPrefix(lbNameFromOutput, "hybrid-") {
 			ipAddressMainRule := lbFwdRuleIPs.Get("main-hybrid-rule").String()
 			if ipAddressMainRule == "" {
 				t.Errorf("IP address for Hybrid NLB %s (rule 'main-hybrid-rule') is empty.", lbNameFromOutput)
 			} else {
-				verifyConnectivityToNLB(t, nlbProjectID, nlbZone, nlbTestVmName, ipAddressMainRule, apachePort, "Instance.*responding")
+				verifyConnectivityToNLB(t, vm, ipAddressMainRule, apachePort)
 			}
 		}
 	}
 }
 
+/*
+setNames is a helper function which derives the names of the load balancers
+and of their network, backends and probe VM from the run and the test.
+*/
+func setNames(t *testing.T) {
+	nlbInstanceName = naming.Name(t, naming.Compute, "nlb")
+	nlbNetworkName = fmt.Sprintf("vpc-%s-test", nlbInstanceName)
+	nlbSubnetName = fmt.Sprintf("%s-subnet", nlbNetworkName)
+	nlbMigName = fmt.Sprintf("mig-%s-nlb-regional", nlbInstanceName)
+	nlbZonalMigName = fmt.Sprintf("mig-%s-nlb-zonal", nlbInstanceName)
+	nlbTemplateName = fmt.Sprintf("%s-it-nlb", nlbInstanceName)
+	nlbFwHcRuleName = fmt.Sprintf("%s-fw-hc", nlbNetworkName)
+	nlbFwTrafficRuleName = fmt.Sprintf("%s-fw-traffic", nlbNetworkName)
+	nlbTestVmName = fmt.Sprintf("test-vm-%s-nlb", nlbInstanceName)
+}
+
 func createNetworkLoadBalancerYAML(t *testing.T) {
 	t.Log("========= Generating YAML Files for Network Load Balancers =========")
 	if err := os.MkdirAll(nlbConfigFolderPath, 0755); err != nil {
@@ -385,11 +351,12 @@ func createNetworkLoadBalancerYAML(t *testing.T) {
 
 	// 1. Lite NLB Configuration (Regional MIG)
 	minNLBName := fmt.Sprintf("lite-%s", nlbInstanceName)
-	minimalNLBCfg := NetworkLoadBalancerConfig{
+	minimalNLBCfg := schema.NetworkLoadBalancer{
 		Name:      minNLBName,
 		ProjectID: nlbProjectID, // These are dynamic test variables
 		Region:    nlbRegion,
-		Backends: []BackendItemConfig{
+		Labels:    naming.Labels(t),
+		Backends: []schema.NLBBackend{
 			{
 				GroupName:   nlbMigName, // Your regional MIG variable
 				GroupRegion: nlbRegion,  // Explicitly state region
@@ -415,31 +382,32 @@ func createNetworkLoadBalancerYAML(t *testing.T) {
 	hcEnableLogging := true
 	hcTCPPort := 80
 
-	maximalNLBCfg := NetworkLoadBalancerConfig{
+	maximalNLBCfg := schema.NetworkLoadBalancer{
 		Name:        maxNLBName,
 		ProjectID:   nlbProjectID,
 		Region:      nlbRegion,
 		Description: "Expanded NLB pointing to a Zonal MIG",
-		Backends: []BackendItemConfig{
+		Labels:      naming.Labels(t),
+		Backends: []schema.NLBBackend{
 			{
 				GroupName:   nlbZonalMigName, // Your zonal MIG variable
 				GroupZone:   nlbZone,         // Your zonal MIG zone variable
 				Description: "Zonal MIG backend for expanded NLB",
 			},
 		},
-		HealthCheck: &NetworkHealthCheckConfig{
+		HealthCheck: &schema.NLBHealthCheck{
 			Description:        "Custom TCP Health Check for NLB",
 			CheckIntervalSec:   &hcCheckInterval,
 			TimeoutSec:         &hcTimeout,
 			HealthyThreshold:   &hcHealthyThreshold,
 			UnhealthyThreshold: &hcUnhealthyThreshold,
 			EnableLogging:      &hcEnableLogging,
-			TCP: &TCPHealthCheck{
+			TCP: &schema.HealthCheckTCP{
 				Port:              &hcTCPPort,
 				PortSpecification: "USE_FIXED_PORT",
 			},
 		},
-		ForwardingRules: map[string]ForwardingRuleConfig{
+		ForwardingRules: map[string]schema.NLBForwardingRule{
 			"rule-http": {
 				Protocol:    "TCP",
 				Ports:       []string{apachePort},
@@ -464,12 +432,13 @@ func createNetworkLoadBalancerYAML(t *testing.T) {
 
 	// 3. Hybrid NLB Configuration (Mix of Regional and Zonal MIGs) <-- NEW SECTION
 	hybridNLBName := fmt.Sprintf("hybrid-%s", nlbInstanceName)
-	hybridNLBCfg := NetworkLoadBalancerConfig{
+	hybridNLBCfg := schema.NetworkLoadBalancer{
 		Name:        hybridNLBName,
 		ProjectID:   nlbProjectID,
 		Region:      nlbRegion,
 		Description: "Hybrid NLB with Regional and Zonal MIGs",
-		Backends: []BackendItemConfig{
+		Labels:      naming.Labels(t),
+		Backends: []schema.NLBBackend{
 			{
 				GroupName:   nlbMigName,
 				GroupRegion: nlbRegion,
@@ -481,10 +450,10 @@ func createNetworkLoadBalancerYAML(t *testing.T) {
 				Description: "Zonal backend for hybrid NLB",
 			},
 		},
-		HealthCheck: &NetworkHealthCheckConfig{
-			TCP: &TCPHealthCheck{PortSpecification: "USE_SERVING_PORT"},
+		HealthCheck: &schema.NLBHealthCheck{
+			TCP: &schema.HealthCheckTCP{PortSpecification: "USE_SERVING_PORT"},
 		},
-		ForwardingRules: map[string]ForwardingRuleConfig{
+		ForwardingRules: map[string]schema.NLBForwardingRule{
 			"main-hybrid-rule": {
 				Protocol: "TCP",
 				Ports:    []string{apachePort},
@@ -506,21 +475,16 @@ func verifyNetworkLoadBalancerConfiguration(t *testing.T, lbNameFromOutput strin
 	t.Logf("Verifying NLB configuration for: %s using YAML: %s", lbNameFromOutput, yamlFileName)
 
 	yamlFilePath := filepath.Join(nlbConfigFolderPath, yamlFileName)
-	yamlFileContent, err := os.ReadFile(yamlFilePath)
-	if err != nil {
-		t.Errorf("Error reading YAML file %s for NLB %s: %v", yamlFilePath, lbNameFromOutput, err)
-		return // Cannot proceed without the expected config
-	}
-	var expectedConfig NetworkLoadBalancerConfig
-	if err := yaml.Unmarshal(yamlFileContent, &expectedConfig); err != nil {
-		t.Errorf("Error unmarshaling YAML for NLB %s from %s: %v", lbNameFromOutput, yamlFileName, err)
+	var expectedConfig schema.NetworkLoadBalancer
+	if err := schema.Load(yamlFilePath, &expectedConfig); err != nil {
+		t.Errorf("Error loading YAML for NLB %s: %v", lbNameFromOutput, err)
 		return // Cannot proceed without parsed expected config
 	}
 
 	// Fetch current state from Terraform outputs
-	nlbBackendServicesOutput := terraform.OutputJson(t, terraformOptions, "nlb_backend_services")
-	nlbHealthChecksOutput := terraform.OutputJson(t, terraformOptions, "nlb_health_checks")
-	nlbForwardingRulesOutput := terraform.OutputJson(t, terraformOptions, "nlb_forwarding_rules")
+	nlbBackendServicesOutput := cassette.OutputJSON(t, terraformOptions, "nlb_backend_services")
+	nlbHealthChecksOutput := cassette.OutputJSON(t, terraformOptions, "nlb_health_checks")
+	nlbForwardingRulesOutput := cassette.OutputJSON(t, terraformOptions, "nlb_forwarding_rules")
 
 	// --- 1. Verify Backend Service ---
 	actualBackendServiceJSON := gjson.Parse(nlbBackendServicesOutput).Get(lbNameFromOutput)
@@ -540,24 +504,21 @@ func verifyNetworkLoadBalancerConfiguration(t *testing.T, lbNameFromOutput strin
 				Command: "gcloud",
 				Args:    []string{"compute", "backend-services", "describe", bsName, "--region", bsRegion, "--project", nlbProjectID, "--format=json"},
 			}
-			bsDetailsJsonString, errBs := shell.RunCommandAndGetOutputE(t, cmdBsDescribe)
+			bsDetailsJsonString, errBs := cassette.Output(t, cmdBsDescribe)
 			if errBs != nil {
 				t.Errorf("Failed to describe backend service %s in region %s: %v. Output: %s", bsName, bsRegion, errBs, bsDetailsJsonString)
 			} else {
 				bsDetailsJson := gjson.Parse(bsDetailsJsonString)
-				if len(expectedConfig.Backends) > 0 {
-					tempProxyExpectedBSConfig := expectedConfig.Backends[0]
-
-					if tempProxyExpectedBSConfig.Protocol != "" && bsDetailsJson.Get("protocol").String() != tempProxyExpectedBSConfig.Protocol {
-						t.Errorf("NLB %s: Backend Service protocol mismatch. YAML Expected (from Backends[0]): %s, Actual: %s", lbNameFromOutput, tempProxyExpectedBSConfig.Protocol, bsDetailsJson.Get("protocol").String())
+				if expectedBS := expectedConfig.BackendService; expectedBS != nil {
+					if expectedBS.Protocol != "" && bsDetailsJson.Get("protocol").String() != expectedBS.Protocol {
+						t.Errorf("NLB %s: Backend Service protocol mismatch. YAML Expected: %s, Actual: %s", lbNameFromOutput, expectedBS.Protocol, bsDetailsJson.Get("protocol").String())
 					}
-					if tempProxyExpectedBSConfig.PortName != "" && bsDetailsJson.Get("portName").String() != tempProxyExpectedBSConfig.PortName {
-						t.Errorf("NLB %s: Backend Service portName mismatch. YAML Expected (from Backends[0]): %s, Actual: %s", lbNameFromOutput, tempProxyExpectedBSConfig.PortName, bsDetailsJson.Get("portName").String())
+					if expectedBS.PortName != "" && bsDetailsJson.Get("portName").String() != expectedBS.PortName {
+						t.Errorf("NLB %s: Backend Service portName mismatch. YAML Expected: %s, Actual: %s", lbNameFromOutput, expectedBS.PortName, bsDetailsJson.Get("portName").String())
 					}
-					if tempProxyExpectedBSConfig.TimeoutSec != nil && bsDetailsJson.Get("timeoutSec").Int() != int64(*tempProxyExpectedBSConfig.TimeoutSec) {
-						t.Errorf("NLB %s: Backend Service timeoutSec mismatch. YAML Expected (from Backends[0]): %d, Actual: %d", lbNameFromOutput, *tempProxyExpectedBSConfig.TimeoutSec, bsDetailsJson.Get("timeoutSec").Int())
+					if expectedBS.TimeoutSec != nil && bsDetailsJson.Get("timeoutSec").Int() != int64(*expectedBS.TimeoutSec) {
+						t.Errorf("NLB %s: Backend Service timeoutSec mismatch. YAML Expected: %d, Actual: %d", lbNameFromOutput, *expectedBS.TimeoutSec, bsDetailsJson.Get("timeoutSec").Int())
 					}
-
 				}
 				if expectedConfig.Description != "" && bsDetailsJson.Get("description").String() != expectedConfig.Description {
 					t.Errorf("NLB %s: Backend Service description mismatch (comparing overall LB desc). YAML Expected: '%s', Actual: '%s'", lbNameFromOutput, expectedConfig.Description, bsDetailsJson.Get("description").String())
@@ -628,7 +589,7 @@ func verifyNetworkLoadBalancerConfiguration(t *testing.T, lbNameFromOutput strin
 			t.Logf("NLB %s: Verifying association with pre-existing health check defined in YAML: %s.", lbNameFromOutput, hcNameInYaml)
 			hcAssociated := false
 			if actualBackendServiceJSON.Exists() {
-				bsDetailsJsonStringForHC, _ := shell.RunCommandAndGetOutputE(t, shell.Command{Command: "gcloud", Args: []string{"compute", "backend-services", "describe", gjson.Parse(actualBackendServiceJSON.Get("self_link").String()).Get("name").String(), "--region", gjson.Parse(actualBackendServiceJSON.Get("self_link").String()).Get("region").String(), "--project", nlbProjectID, "--format=json"}})
+				bsDetailsJsonStringForHC, _ := cassette.Output(t, shell.Command{Command: "gcloud", Args: []string{"compute", "backend-services", "describe", gjson.Parse(actualBackendServiceJSON.Get("self_link").String()).Get("name").String(), "--region", gjson.Parse(actualBackendServiceJSON.Get("self_link").String()).Get("region").String(), "--project", nlbProjectID, "--format=json"}})
 				bsHealthChecks := gjson.Parse(bsDetailsJsonStringForHC).Get("healthChecks").Array()
 				for _, bsHcLink := range bsHealthChecks {
 					if strings.HasSuffix(bsHcLink.String(), "/"+hcNameInYaml) {
@@ -648,7 +609,7 @@ func verifyNetworkLoadBalancerConfiguration(t *testing.T, lbNameFromOutput strin
 			}
 		} else {
 			if actualBackendServiceJSON.Exists() {
-				bsDetailsJsonStringForHC, _ := shell.RunCommandAndGetOutputE(t, shell.Command{Command: "gcloud", Args: []string{"compute", "backend-services", "describe", gjson.Parse(actualBackendServiceJSON.Get("self_link").String()).Get("name").String(), "--region", gjson.Parse(actualBackendServiceJSON.Get("self_link").String()).Get("region").String(), "--project", nlbProjectID, "--format=json"}})
+				bsDetailsJsonStringForHC, _ := cassette.Output(t, shell.Command{Command: "gcloud", Args: []string{"compute", "backend-services", "describe", gjson.Parse(actualBackendServiceJSON.Get("self_link").String()).Get("name").String(), "--region", gjson.Parse(actualBackendServiceJSON.Get("self_link").String()).Get("region").String(), "--project", nlbProjectID, "--format=json"}})
 				bsHealthChecks := gjson.Parse(bsDetailsJsonStringForHC).Get("healthChecks").Array()
 				if len(bsHealthChecks) > 0 {
 					hcSelfLink = bsHealthChecks[0].String()
@@ -692,7 +653,7 @@ func verifyNetworkLoadBalancerConfiguration(t *testing.T, lbNameFromOutput strin
 			if hcDescribeArgs != nil {
 				hcDescribeArgs = append(hcDescribeArgs, "--project", nlbProjectID, "--format=json")
 				cmdHcDescribe := shell.Command{Command: "gcloud", Args: hcDescribeArgs}
-				hcDetailsJsonString, errHc := shell.RunCommandAndGetOutputE(t, cmdHcDescribe)
+				hcDetailsJsonString, errHc := cassette.Output(t, cmdHcDescribe)
 				if errHc != nil {
 					t.Errorf("Failed to describe health check %s (Args: %v): %v. Output: %s", hcName, cmdHcDescribe.Args, errHc, hcDetailsJsonString)
 				} else {
@@ -764,7 +725,7 @@ func verifyNetworkLoadBalancerConfiguration(t *testing.T, lbNameFromOutput strin
 			actualFwdRulesMapJSON.ForEach(func(key, value gjson.Result) bool { outputRuleKeys = append(outputRuleKeys, key.String()); return true })
 			if len(outputRuleKeys) == 1 {
 				t.Logf("NLB %s: Lite config (no rules in YAML), verifying single output rule with key '%s'", lbNameFromOutput, outputRuleKeys[0])
-				expectedFwdRules = map[string]ForwardingRuleConfig{outputRuleKeys[0]: {}}
+				expectedFwdRules = map[string]schema.NLBForwardingRule{outputRuleKeys[0]: {}}
 			} else if len(outputRuleKeys) > 1 {
 				t.Errorf("NLB %s: Lite config expected (no rules in YAML), but multiple rules found in TF output: %v", lbNameFromOutput, outputRuleKeys)
 			} else {
@@ -788,7 +749,7 @@ func verifyNetworkLoadBalancerConfiguration(t *testing.T, lbNameFromOutput strin
 			frName := frPathParts[len(frPathParts)-1]
 			frRegion := frPathParts[len(frPathParts)-3]
 			cmdFrDescribe := shell.Command{Command: "gcloud", Args: []string{"compute", "forwarding-rules", "describe", frName, "--region", frRegion, "--project", nlbProjectID, "--format=json"}}
-			frDetailsJsonString, errFr := shell.RunCommandAndGetOutputE(t, cmdFrDescribe)
+			frDetailsJsonString, errFr := cassette.Output(t, cmdFrDescribe)
 			if errFr != nil {
 				t.Errorf("Failed to describe forwarding rule %s in region %s: %v. Output: %s", frName, frRegion, errFr, frDetailsJsonString)
 				continue
@@ -901,7 +862,7 @@ func createInstanceTemplate(t *testing.T, templateName, projectID, networkName,
 	cmd := shell.Command{Command: "gcloud", Args: args}
 	commandString := fmt.Sprintf("%s %s", cmd.Command, strings.Join(cmd.Args, " "))
 
-	_, err := shell.RunCommandAndGetOutputE(t, cmd)
+	_, err := cassette.Output(t, cmd)
 	if err != nil {
 		t.Fatalf("Failed to create Instance Template %s: %v. Command: [%s]", templateName, err, commandString)
 	} else {
@@ -920,7 +881,7 @@ func setNamedPortsOnMIG(t *testing.T, projectID, region, migName, portName, port
 			fmt.Sprintf("--named-ports=%s:%s", portName, portNumber),
 		},
 	}
-	_, err := shell.RunCommandAndGetOutputE(t, cmd)
+	_, err := cassette.Output(t, cmd)
 	if err != nil {
 		// Use t.Fatalf if setting named ports is critical
 		t.Fatalf("Failed to set named ports on MIG %s: %v", migName, err)
@@ -942,13 +903,12 @@ func createManagedInstanceGroupNLB(t *testing.T) {
 			// Add health check if needed (though module can create backend service based HC)
 		},
 	}
-	_, err := shell.RunCommandAndGetOutputE(t, cmd)
+	_, err := cassette.Output(t, cmd)
 	if err != nil {
 		t.Errorf("Failed to create Managed Instance Group %s: %v", nlbMigName, err)
 	} else {
 		t.Logf("Successfully created Managed Instance Group: %s", nlbMigName)
-		// Wait for MIG to stabilize
-		time.Sleep(120 * time.Second)
+		wait.For(t, "MIG "+nlbMigName+" to stabilize", wait.DefaultOptions, wait.MIGStable(t, gcloud.New(nlbProjectID), nlbRegion, "", nlbMigName))
 	}
 }
 
@@ -965,14 +925,12 @@ func createZonalManagedInstanceGroupNLB(t *testing.T) {
 			"--zone", nlbZone, // Specify zone for Zonal MIG
 		},
 	}
-	_, err := shell.RunCommandAndGetOutputE(t, cmd)
+	_, err := cassette.Output(t, cmd)
 	if err != nil {
 		t.Errorf("Failed to create Zonal Managed Instance Group %s: %v", nlbZonalMigName, err)
 	} else {
 		t.Logf("Successfully created Zonal Managed Instance Group: %s in zone %s", nlbZonalMigName, nlbZone)
-		// Wait for MIG to stabilize (adjust timing as needed)
-		t.Logf("Waiting for Zonal MIG %s to stabilize...", nlbZonalMigName)
-		time.Sleep(120 * time.Second)
+		wait.For(t, "zonal MIG "+nlbZonalMigName+" to stabilize", wait.DefaultOptions, wait.MIGStable(t, gcloud.New(nlbProjectID), "", nlbZone, nlbZonalMigName))
 	}
 }
 
@@ -987,7 +945,7 @@ func deleteZonalManagedInstanceGroupNLB(t *testing.T) {
 			"--quiet",
 		},
 	}
-	_, err := shell.RunCommandAndGetOutputE(t, cmd)
+	_, err := cassette.Output(t, cmd)
 	if err != nil {
 		t.Logf("Failed to delete Zonal Managed Instance Group %s: %v. This might be okay if it was already deleted or never created.", nlbZonalMigName, err)
 	} else {
@@ -1006,7 +964,7 @@ func deleteManagedInstanceGroupNLB(t *testing.T) {
 			"--quiet",
 		},
 	}
-	_, err := shell.RunCommandAndGetOutputE(t, cmd)
+	_, err := cassette.Output(t, cmd)
 	if err != nil {
 		// Don't fail the test, just log, as other cleanup might still be needed
 		t.Logf("Failed to delete Managed Instance Group %s: %v. This might be okay if it was already deleted or never created.", nlbMigName, err)
@@ -1025,7 +983,7 @@ func deleteInstanceTemplateNLB(t *testing.T) {
 			"--quiet",
 		},
 	}
-	_, err := shell.RunCommandAndGetOutputE(t, cmd)
+	_, err := cassette.Output(t, cmd)
 	if err != nil {
 		t.Logf("Failed to delete Instance Template %s: %v.", nlbTemplateName, err)
 	} else {
@@ -1050,7 +1008,7 @@ func createFirewallRuleForNLBHealthChecks(t *testing.T, projectID, networkName,
 			"--description=Allow traffic from GCP health checkers for NLB",
 		},
 	}
-	_, err := shell.RunCommandAndGetOutputE(t, cmd)
+	_, err := cassette.Output(t, cmd)
 	if err != nil {
 		t.Errorf("Failed to create firewall rule %s for NLB health checks: %v", ruleName, err)
 	} else {
@@ -1078,7 +1036,7 @@ func createFirewallRuleForNLBTraffic(t *testing.T, projectID, networkName, ruleN
 			"--description=Allow external traffic to NLB instances",
 		},
 	}
-	_, err := shell.RunCommandAndGetOutputE(t, cmd)
+	_, err := cassette.Output(t, cmd)
 	if err != nil {
 		t.Errorf("Failed to create firewall rule %s for NLB traffic: %v", ruleName, err)
 	} else {
@@ -1086,145 +1044,18 @@ func createFirewallRuleForNLBTraffic(t *testing.T, projectID, networkName, ruleN
 	}
 }
 
-// createTestVM: Creates a VM for connectivity testing
-func createTestVM(t *testing.T, projectID, zone, vmName, networkName, subnetName string) {
-	cmd := shell.Command{
-		Command: "gcloud",
-		Args: []string{
-			"compute", "instances", "create", vmName,
-			"--project=" + projectID,
-			"--zone=" + zone,
-			"--machine-type=e2-micro",
-			"--image-family=debian-11",
-			"--image-project=debian-cloud",
-			"--network=" + networkName,
-			"--subnet=" + subnetName,
-			"--scopes=cloud-platform", // For gcloud commands from within if needed
-			"--tags=allow-iap-ssh",
-			"--metadata=startup-script=apt-get update -y && apt-get install -y curl dnsutils netcat-openbsd",
-		},
-	}
-	_, err := retry.DoWithRetryE(t, "Create Test VM", 2, 10*time.Second, func() (string, error) {
-		return shell.RunCommandAndGetOutputE(t, cmd)
-	})
-	if err != nil {
-		t.Fatalf("Failed to create test VM %s after retries: %v", vmName, err)
-	}
-	time.Sleep(60 * time.Second) // Give VM time to boot and run startup
-}
-
-// deleteTestVM: Deletes the test VM
-func deleteTestVM(t *testing.T, projectID, zone, vmName string) {
-	cmd := shell.Command{
-		Command: "gcloud",
-		Args: []string{
-			"compute", "instances", "delete", vmName,
-			"--project=" + projectID,
-			"--zone=" + zone,
-			"--quiet",
-		},
-	}
-	_, err := shell.RunCommandAndGetOutputE(t, cmd)
+// verifyConnectivityToNLB checks from the probe VM that the NLB forwards HTTP
+// requests on port to the backends.
+func verifyConnectivityToNLB(t *testing.T, vm *probe.VM, lbIpAddress, port string) {
+	url := fmt.Sprintf("http://%s:%s", lbIpAddress, port)
+	t.Logf("Verifying connectivity from VM %s to NLB %s", vm.Name, url)
+	opts := wait.Options{Timeout: 5 * time.Minute, Interval: 20 * time.Second, MaxInterval: time.Minute}
+	results, err := vm.Eventually(t, opts, true, probe.HTTP(url))
 	if err != nil {
-		t.Logf("Failed to delete test VM %s: %v. This might be okay if it was already deleted.", vmName, err)
-	} else {
-		t.Logf("Successfully deleted test VM %s.", vmName)
-	}
-}
-
-// verifyConnectivityToNLB: Uses gcloud to SSH into test VM and curl the LB IP
-func verifyConnectivityToNLB(t *testing.T, projectID, zone, testVmName, lbIpAddress, port, expectedTextPattern string) {
-	t.Logf("Verifying connectivity from VM %s to NLB IP %s on port %s, expecting to match: '%s'", testVmName, lbIpAddress, port, expectedTextPattern)
-
-	maxRetries := 5
-	sleepBetweenRetries := 20 * time.Second
-	var sshCommand string
-
-	// Compile the regex pattern once before the retry loop
-	expectedRegex, regexCompileErr := regexp.Compile(expectedTextPattern)
-	if regexCompileErr != nil {
-		// If the pattern is invalid, fail fast, no point in retrying
-		t.Fatalf("Invalid regex pattern '%s' provided for matching: %v", expectedTextPattern, regexCompileErr)
+		t.Errorf("Failed to verify connectivity to NLB %s: %v", url, err)
 		return
 	}
-
-	// Determine the command based on whether a specific text pattern is expected (implies curl)
-	// or just a successful connection (can use netcat).
-	if expectedTextPattern == "" || expectedTextPattern == "Netcat success" {
-		// Use netcat for simple TCP connect check
-		sshCommand = fmt.Sprintf("nc -z -w 5 %s %s && echo 'Netcat success' || echo 'Netcat failed'", lbIpAddress, port)
-		if expectedTextPattern == "" {
-			expectedTextPattern = "Netcat success" // Explicitly expect this if none provided
-		}
-		t.Logf("Using netcat for connectivity check to %s:%s", lbIpAddress, port)
-		// Recompile regex if needed for the netcat case (should already be compiled above)
-		expectedRegex, _ = regexp.Compile(expectedTextPattern) // Assume pattern is valid
-	} else {
-		// Use curl with --fail for HTTP status check via exit code, plus -v for debug, -L follow redirects, -m timeout
-		sshCommand = fmt.Sprintf("curl -v -L --fail -m 15 http://%s:%s", lbIpAddress, port)
-		t.Logf("Using curl --fail for connectivity check to %s:%s", lbIpAddress, port)
-	}
-
-	_, err := retry.DoWithRetryE(t, fmt.Sprintf("SSH and check NLB %s:%s", lbIpAddress, port), maxRetries, sleepBetweenRetries, func() (string, error) {
-		gcloudArgs := []string{
-			"compute", "ssh", testVmName,
-			"--project=" + projectID,
-			"--zone=" + zone,
-			"--command=" + sshCommand,
-			"--tunnel-through-iap", // Assume IAP is needed based on common setup
-			"--quiet",              // Suppress gcloud informational messages
-		}
-		cmd := shell.Command{Command: "gcloud", Args: gcloudArgs}
-		// Execute the command. runErr will be non-nil if gcloud ssh fails OR if the command inside (curl --fail / nc) fails.
-		output, runErr := shell.RunCommandAndGetOutputE(t, cmd)
-
-		// Log the raw output regardless of error for better debugging context on retries
-		t.Logf("DEBUG: Attempting SSH command (%s) to %s:%s:\n%s", cmd.Command, lbIpAddress, port, output)
-
-		// --- Primary Failure Check ---
-		if runErr != nil {
-			// Check for specific connection errors *within* the output for better context, even though runErr is set.
-			// These might indicate network path issues before curl --fail could even evaluate HTTP status.
-			connectionRefused := strings.Contains(output, "Connection refused") || strings.Contains(output, "connect to .* port .* failed: Connection refused")
-			timeoutOccurred := strings.Contains(output, "Connection timed out") || strings.Contains(output, "Operation timed out")
-			netcatFailed := strings.Contains(output, "Netcat failed") // Check if using nc
-
-			failureReason := "gcloud ssh or internal command failed (check runErr)" // Default reason if runErr != nil
-			if connectionRefused {
-				failureReason = "connection refused"
-			} else if timeoutOccurred {
-				failureReason = "connection timed out"
-			} else if netcatFailed {
-				failureReason = "netcat failed"
-			}
-			// For curl --fail, runErr indicates either SSH issue or HTTP status >= 400
-
-			// Return the specific reason and the original error from RunCommandAndGetOutputE
-			return "", fmt.Errorf("%s checking NLB %s:%s via %s. Error: %v. Output logged above.", failureReason, lbIpAddress, port, testVmName, runErr)
-		}
-
-		// --- Success Check (if runErr is nil) ---
-		// If we are here, gcloud ssh succeeded AND the internal command (curl --fail or nc) also had exit code 0.
-		// For curl, this means HTTP status < 400. For nc, it means connection was successful.
-
-		// Now, check if the output *content* matches the expected pattern.
-		if !expectedRegex.MatchString(output) {
-			// Command execution succeeded, but the response content is wrong.
-			return "", fmt.Errorf("command succeeded but response from NLB %s:%s via %s did not match expected pattern '%s'. Output logged above.", lbIpAddress, port, testVmName, expectedTextPattern)
-		}
-
-		// Both command execution and content matching succeeded
-		t.Logf("Successfully connected to NLB %s:%s from %s. Output matches expected pattern.", lbIpAddress, port, testVmName)
-		return output, nil // Success
-	})
-
-	// Handle final error after retries
-	if err != nil {
-		// If all retries failed, log the final error and fail the test
-		t.Errorf("Failed to verify connectivity to NLB %s:%s after %d attempts: %v", lbIpAddress, port, maxRetries, err)
-		// Consider t.Fatalf if connectivity is absolutely critical for the test pass/fail
-		t.Fail() // Mark the test as failed but allow other parts of the test function to potentially run cleanup
-	}
+	t.Logf("Successfully connected to NLB: %s", results[0])
 }
 
 // createVPC creates a Virtual Private Cloud (VPC) network and a subnet in Google Cloud
@@ -1235,7 +1066,7 @@ func createVPC(t *testing.T, projectID string, networkName string) {
 		Command: "gcloud",
 		Args:    []string{"compute", "networks", "describe", networkName, "--project=" + projectID, "--format=value(name)"},
 	}
-	vpcExistsOutput, errVPC := shell.RunCommandAndGetOutputE(t, cmdCheckVPC)
+	vpcExistsOutput, errVPC := cassette.Output(t, cmdCheckVPC)
 	// It's good practice to log the error from describe, even if we proceed, to see why it might have failed if not "not found"
 	if errVPC != nil {
 		t.Logf("Describing VPC %s failed (this is often expected if it doesn't exist): %v. Output: %s", networkName, errVPC, vpcExistsOutput)
@@ -1253,13 +1084,13 @@ func createVPC(t *testing.T, projectID string, networkName string) {
 				"--bgp-routing-mode=global",
 				"--subnet-mode=custom"},
 		}
-		if _, err := shell.RunCommandAndGetOutputE(t, cmdCreateVPC); err != nil {
+		if _, err := cassette.Output(t, cmdCreateVPC); err != nil {
 			t.Fatalf("Error creating VPC %s: %v", networkName, err)
 		}
 		t.Logf("Successfully created VPC: %s", networkName)
 	}
 
-	time.Sleep(10 * time.Second) // allow network to be ready
+	wait.For(t, "network "+networkName+" to exist", wait.DefaultOptions, wait.NetworkExists(t, gcloud.New(projectID), networkName))
 
 	// Check if Subnet already exists
 	currentSubnetName := fmt.Sprintf("%s-subnet", networkName)
@@ -1268,7 +1099,7 @@ func createVPC(t *testing.T, projectID string, networkName string) {
 		Command: "gcloud",
 		Args:    []string{"compute", "networks", "subnets", "describe", currentSubnetName, "--project=" + projectID, "--region=" + nlbRegion, "--format=value(name)"},
 	}
-	subnetExistsOutput, errSubnet := shell.RunCommandAndGetOutputE(t, cmdCheckSubnet)
+	subnetExistsOutput, errSubnet := cassette.Output(t, cmdCheckSubnet)
 	if errSubnet != nil {
 		t.Logf("Describing Subnet %s failed (this is often expected if it doesn't exist): %v. Output: %s", currentSubnetName, errSubnet, subnetExistsOutput)
 	}
@@ -1283,9 +1114,9 @@ func createVPC(t *testing.T, projectID string, networkName string) {
 				"--project=" + projectID,
 				"--network=" + networkName,
 				"--region=" + nlbRegion,
-				"--range=10.10.0.0/24"},
+				"--range=" + ipam.Subnet(t, 24)},
 		}
-		if _, err := shell.RunCommandAndGetOutputE(t, cmdCreateSubnet); err != nil {
+		if _, err := cassette.Output(t, cmdCreateSubnet); err != nil {
 			t.Fatalf("Error creating subnet %s in VPC %s: %v", currentSubnetName, networkName, err)
 		}
 		t.Logf("Successfully created Subnet: %s in VPC: %s", currentSubnetName, networkName)
@@ -1295,31 +1126,25 @@ func createVPC(t *testing.T, projectID string, networkName string) {
 // deleteVPC deletes a Virtual Private Cloud (VPC) network and its associated subnet
 func deleteVPC(t *testing.T, projectID string, networkName string) {
 	// It's important to delete resources that depend on the VPC first, like MIGs, LBs, firewall rules.
-	// Terraform destroy should handle most of this. This is a fallback.
-	time.Sleep(30 * time.Second) // Wait for dependent resources to be potentially deleted by TF
+	// Terraform destroy should handle most of this. Deletes are retried while
+	// those dependents are still being torn down.
+	client := gcloud.New(projectID)
 
 	currentSubnetName := fmt.Sprintf("%s-subnet", networkName)
-	cmdDeleteSubnet := shell.Command{
-		Command: "gcloud",
-		Args: []string{"compute", "networks", "subnets", "delete", currentSubnetName,
-			"--project=" + projectID,
-			"--region=" + nlbRegion, // Use nlbRegion
-			"--quiet"},
-	}
 	// Log error but don't fail test, as it might have been cleaned up or not existed
-	if _, err := shell.RunCommandAndGetOutputE(t, cmdDeleteSubnet); err != nil {
+	_, err := wait.ForE(t, "subnet "+currentSubnetName+" to be deleted", wait.DefaultOptions, wait.Deletes(func() error {
+		return client.Subnets.Delete(t, nlbRegion, currentSubnetName)
+	}))
+	if err != nil {
 		t.Logf("Error deleting subnet %s: %v. This might be okay.", currentSubnetName, err)
 	} else {
 		t.Logf("Successfully deleted subnet %s.", currentSubnetName)
 	}
 
-	time.Sleep(60 * time.Second) // Increased delay before deleting VPC
-
-	cmdDeleteVPC := shell.Command{
-		Command: "gcloud",
-		Args:    []string{"compute", "networks", "delete", networkName, "--project=" + projectID, "--quiet"},
-	}
-	if _, err := shell.RunCommandAndGetOutputE(t, cmdDeleteVPC); err != nil {
+	_, err = wait.ForE(t, "network "+networkName+" to be deleted", wait.DefaultOptions, wait.Deletes(func() error {
+		return client.Networks.Delete(t, networkName)
+	}))
+	if err != nil {
 		t.Logf("Error deleting VPC %s: %v. This might be okay.", networkName, err)
 	} else {
 		t.Logf("Successfully deleted VPC %s.", networkName)
@@ -1336,7 +1161,7 @@ func deleteFirewallRule(t *testing.T, projectID string, ruleName string) {
 			"--quiet",
 		},
 	}
-	_, err := shell.RunCommandAndGetOutputE(t, cmd)
+	_, err := cassette.Output(t, cmd)
 	if err != nil {
 		t.Logf("Failed to delete firewall rule %s: %v. This might be okay.", ruleName, err)
 	} else {
@@ -1355,34 +1180,9 @@ func setNamedPortsOnZonalMIG(t *testing.T, projectID, zone, migName, portName, p
 			fmt.Sprintf("--named-ports=%s:%s", portName, portNumber),
 		},
 	}
-	_, err := shell.RunCommandAndGetOutputE(t, cmd)
+	_, err := cassette.Output(t, cmd)
 	if err != nil {
 		t.Fatalf("Failed to set named ports on Zonal MIG %s: %v", migName, err)
 	}
 	t.Logf("Successfully set named port %s:%s on Zonal MIG %s", portName, portNumber, migName)
 }
-
-func createFirewallRuleForIAP(t *testing.T, projectID, networkName, ruleName string, targetTags []string) {
-	cmd := shell.Command{
-		Command: "gcloud",
-		Args: []string{
-			"compute", "firewall-rules", "create", ruleName,
-			"--project=" + projectID,
-			"--network=" + networkName,
-			"--action=ALLOW",
-			"--direction=INGRESS",
-			"--rules=tcp:22",
-			"--source-ranges=35.235.240.0/20", // Google's IAP IP range
-			"--target-tags=" + strings.Join(targetTags, ","),
-			"--description=Allow SSH via IAP",
-		},
-	}
-	_, err := shell.RunCommandAndGetOutputE(t, cmd)
-	if err != nil {
-		// It might be acceptable for this to fail if running tests in parallel and another created it.
-		// However, for a single test run, this should ideally succeed.
-		t.Logf("Warning: Failed to create firewall rule %s for IAP: %v. This might be an issue.", ruleName, err)
-	} else {
-		t.Logf("Successfully created firewall rule %s for IAP.", ruleName)
-	}
-}
@@ -17,19 +17,23 @@
 package integrationtest
 
 import (
-	"context"
 	"fmt"
-	"io/ioutil"
-	"math/rand"
 	"os"
 	"path/filepath"
 	"strings"
 	"testing"
 	"time"
 
-	"cloud.google.com/go/storage"
-	"github.com/gruntwork-io/terratest/modules/gcp"
-	"github.com/gruntwork-io/terratest/modules/retry"
+	"github.com/GoogleCloudPlatform/cloudnetworking-config-solutions/common_utils/fixture"
+	"github.com/GoogleCloudPlatform/cloudnetworking-config-solutions/common_utils/gcloud"
+	"github.com/GoogleCloudPlatform/cloudnetworking-config-solutions/common_utils/ipam"
+	"github.com/GoogleCloudPlatform/cloudnetworking-config-solutions/common_utils/naming"
+	"github.com/GoogleCloudPlatform/cloudnetworking-config-solutions/common_utils/preflight"
+	"github.com/GoogleCloudPlatform/cloudnetworking-config-solutions/common_utils/probe"
+	"github.com/GoogleCloudPlatform/cloudnetworking-config-solutions/common_utils/profile"
+	"github.com/GoogleCloudPlatform/cloudnetworking-config-solutions/common_utils/retryable"
+	"github.com/GoogleCloudPlatform/cloudnetworking-config-solutions/common_utils/schema"
+	"github.com/GoogleCloudPlatform/cloudnetworking-config-solutions/common_utils/wait"
 	"github.com/gruntwork-io/terratest/modules/shell"
 	"github.com/gruntwork-io/terratest/modules/terraform"
 	"github.com/stretchr/testify/assert"
@@ -55,25 +59,23 @@ var tfVarsINLB = map[string]interface{}{
 
 var (
 	// ilbProjectID is set by the TF_VAR_project_id environment variable.
-	ilbProjectID = os.Getenv("TF_VAR_project_id")
-	// Dynamic names based on a random integer to ensure test isolation.
-	ilbInstanceName = fmt.Sprintf("ilb-test-%d", rand.New(rand.NewSource(time.Now().UnixNano())).Intn(100000))
-	ilbNamesToTest  = []string{
-		fmt.Sprintf("lite-%s", ilbInstanceName),
-		fmt.Sprintf("expanded-%s", ilbInstanceName),
-	}
-	ilbRegion            = "us-central1"
-	ilbZone              = ilbRegion + "-a"
-	ilbNetworkName       = fmt.Sprintf("vpc-%s", ilbInstanceName)
-	ilbSubnetName        = fmt.Sprintf("%s-subnet", ilbNetworkName)
-	ilbSubnetCidr        = "10.20.0.0/24" // Using a distinct CIDR for this test
-	ilbMigName           = fmt.Sprintf("mig-%s-regional", ilbInstanceName)
-	ilbTemplateName      = fmt.Sprintf("it-%s", ilbInstanceName)
-	ilbFwHcRuleName      = fmt.Sprintf("%s-fw-hc", ilbNetworkName)
-	ilbFwTrafficRuleName = fmt.Sprintf("%s-fw-traffic", ilbNetworkName)
-	ilbTestVmName        = fmt.Sprintf("test-vm-%s", ilbInstanceName)
-	ilbInstanceTag       = "ilb-backend-instance"
-	ilbTestBucketName    = fmt.Sprintf("ilb-test-results-%s", strings.ToLower(ilbInstanceName))
+	env, envErr    = profile.Current()
+	ilbProjectID   = env.Projects.Endpoint
+	ilbRegion      = env.Regions.Default
+	ilbZone        = ilbRegion + "-a"
+	ilbInstanceTag = "ilb-backend-instance"
+	// The names below are derived from the run and the test by setNames.
+	ilbInstanceName      string
+	ilbNamesToTest       []string
+	ilbNetworkName       string
+	ilbSubnetName        string
+	ilbMigName           string
+	ilbTemplateName      string
+	ilbFwHcRuleName      string
+	ilbFwTrafficRuleName string
+	ilbTestVmName        string
+	// ilbSubnetCidr is leased from the ipam package by each test.
+	ilbSubnetCidr string
 )
 
 const (
@@ -82,53 +84,19 @@ const (
 	apachePort         = "80"
 )
 
-// YAML Configuration Structs
-type NetworkLoadBalancerConfig struct {
-	Name            string                          `yaml:"name"`
-	Project         string                          `yaml:"project"`
-	Region          string                          `yaml:"region"`
-	Network         string                          `yaml:"network"`
-	Subnetwork      string                          `yaml:"subnetwork"`
-	Description     string                          `yaml:"description,omitempty"`
-	Backends        []BackendItemConfig             `yaml:"backends"`
-	HealthCheck     *NetworkHealthCheckConfig       `yaml:"health_check,omitempty"`
-	ForwardingRules map[string]ForwardingRuleConfig `yaml:"forwarding_rules"`
-}
-
-type BackendItemConfig struct {
-	GroupName   string `yaml:"group_name"`
-	GroupRegion string `yaml:"group_region,omitempty"`
-	GroupZone   string `yaml:"group_zone,omitempty"`
-	Description string `yaml:"description,omitempty"`
-}
-
-type NetworkHealthCheckConfig struct {
-	Name               string           `yaml:"name,omitempty"`
-	Description        string           `yaml:"description,omitempty"`
-	CheckIntervalSec   *int             `yaml:"check_interval_sec,omitempty"`
-	TimeoutSec         *int             `yaml:"timeout_sec,omitempty"`
-	HealthyThreshold   *int             `yaml:"healthy_threshold,omitempty"`
-	UnhealthyThreshold *int             `yaml:"unhealthy_threshold,omitempty"`
-	EnableLogging      *bool            `yaml:"enable_logging,omitempty"`
-	TCP                *TCPHealthCheck  `yaml:"tcp,omitempty"`
-	HTTP               *HTTPHealthCheck `yaml:"http,omitempty"`
-}
-
-type TCPHealthCheck struct {
-	Port              *int   `yaml:"port,omitempty"`
-	PortSpecification string `yaml:"port_specification,omitempty"`
-}
-
-type HTTPHealthCheck struct {
-	Port        *int   `yaml:"port,omitempty"`
-	RequestPath string `yaml:"request_path,omitempty"`
-}
-
-type ForwardingRuleConfig struct {
-	Address     string   `yaml:"address,omitempty"`
-	Description string   `yaml:"description,omitempty"`
-	Ports       []string `yaml:"ports,omitempty"`
-	Protocol    string   `yaml:"protocol,omitempty"`
+// TestMain checks the APIs, roles and quota the suite needs before any load
+// balancer is created.
+func TestMain(m *testing.M) {
+	os.Exit(preflight.Run(m, preflight.Requirements{
+		Profile: envErr,
+		Project: ilbProjectID,
+		APIs:    []string{"compute.googleapis.com"},
+		Roles:   []string{"roles/compute.loadBalancerAdmin", "roles/compute.instanceAdmin.v1", "roles/compute.networkAdmin", "roles/compute.securityAdmin"},
+		Quotas: []preflight.Quota{
+			{Metric: "FORWARDING_RULES", Need: 2},
+			{Metric: "INSTANCES", Region: ilbRegion, Need: 5},
+		},
+	}))
 }
 
 /*
@@ -137,11 +105,13 @@ for the Internal Network Load Balancer module with specified variables.
 It expects an exit code of 2, indicating that changes are planned.
 */
 func TestInitAndPlanRunWithTfVarsINLB(t *testing.T) {
+	profile.Require(t, "projects.endpoint")
+	setNames(t)
 	createInternalLoadBalancerYAML(t)
+	ilbSubnetCidr = ipam.Subnet(t, 24)
 	createVPC(t, ilbProjectID, ilbNetworkName, ilbRegion, ilbSubnetName, ilbSubnetCidr)
-	defer deleteVPC(t, ilbProjectID, ilbNetworkName, ilbRegion, ilbSubnetName)
 
-	terraformOptions := terraform.WithDefaultRetryableErrors(t, &terraform.Options{
+	terraformOptions := retryable.With(t, &terraform.Options{
 		TerraformDir: ilbTerraformDirectoryPath,
 		Vars:         tfVarsINLB,
 		Reconfigure:  true,
@@ -176,11 +146,13 @@ Typically, this includes:
 Total = 3 resources per NLB instance.
 */
 func TestResourcesCountINLB(t *testing.T) {
+	profile.Require(t, "projects.endpoint")
+	setNames(t)
 	createInternalLoadBalancerYAML(t)
+	ilbSubnetCidr = ipam.Subnet(t, 24)
 	createVPC(t, ilbProjectID, ilbNetworkName, ilbRegion, ilbSubnetName, ilbSubnetCidr)
-	defer deleteVPC(t, ilbProjectID, ilbNetworkName, ilbRegion, ilbSubnetName)
 
-	terraformOptions := terraform.WithDefaultRetryableErrors(t, &terraform.Options{
+	terraformOptions := retryable.With(t, &terraform.Options{
 		TerraformDir: ilbTerraformDirectoryPath,
 		Vars:         tfVarsINLB,
 		Reconfigure:  true,
@@ -216,9 +188,11 @@ Internal Load Balancer resources in the Terraform plan match the expected addres
 derived from YAML configuration files. It looks for module instances named 'module.inlb_passthrough'.
 */
 func TestTerraformModuleINLBResourceAddressListMatch(t *testing.T) {
+	profile.Require(t, "projects.endpoint")
+	setNames(t)
 	createInternalLoadBalancerYAML(t)
+	ilbSubnetCidr = ipam.Subnet(t, 24)
 	createVPC(t, ilbProjectID, ilbNetworkName, ilbRegion, ilbSubnetName, ilbSubnetCidr)
-	defer deleteVPC(t, ilbProjectID, ilbNetworkName, ilbRegion, ilbSubnetName)
 
 	expectedModuleAddresses := make(map[string]struct{})
 	for _, name := range ilbNamesToTest {
@@ -227,7 +201,7 @@ func TestTerraformModuleINLBResourceAddressListMatch(t *testing.T) {
 	}
 
 	// Initialize Terraform and generate a plan.
-	terraformOptions := terraform.WithDefaultRetryableErrors(t, &terraform.Options{
+	terraformOptions := retryable.With(t, &terraform.Options{
 		TerraformDir: ilbTerraformDirectoryPath,
 		Vars:         tfVarsINLB,
 		Reconfigure:  true,
@@ -257,42 +231,27 @@ func TestTerraformModuleINLBResourceAddressListMatch(t *testing.T) {
 
 // TestCreateInternalLoadBalancer tests the full lifecycle of an Internal Passthrough Network Load Balancer.
 func TestCreateInternalLoadBalancer(t *testing.T) {
+	profile.Require(t, "projects.endpoint")
 	t.Parallel()
-
-	if ilbProjectID == "" {
-		t.Fatal("TF_VAR_project_id must be set as an environment variable.")
-	}
-
-	bucketAttrs := &storage.BucketAttrs{
-		Location: "US",
-	}
+	setNames(t)
 
 	// 1. SETUP: Generate dynamic YAML configs for different test cases.
 	createInternalLoadBalancerYAML(t)
 
-	// SETUP: Create a GCS bucket for test results
-	gcp.CreateStorageBucketE(t, ilbProjectID, ilbTestBucketName, bucketAttrs)
-	defer gcp.DeleteStorageBucketE(t, ilbTestBucketName)
-
-	// 2. SETUP: Create all prerequisite cloud resources using gcloud commands.
-	createVPC(t, ilbProjectID, ilbNetworkName, ilbRegion, ilbSubnetName, ilbSubnetCidr)
-	defer deleteVPC(t, ilbProjectID, ilbNetworkName, ilbRegion, ilbSubnetName)
-
-	createFirewallRuleForILBTraffic(t, ilbProjectID, ilbNetworkName, ilbFwTrafficRuleName, []string{apachePort}, []string{ilbInstanceTag}, ilbSubnetCidr)
-	defer deleteFirewallRule(t, ilbProjectID, ilbFwTrafficRuleName)
-
-	createFirewallRuleForNLBHealthChecks(t, ilbProjectID, ilbNetworkName, ilbFwHcRuleName, []string{ilbInstanceTag})
-	defer deleteFirewallRule(t, ilbProjectID, ilbFwHcRuleName)
-
-	createInstanceTemplate(t, ilbProjectID, ilbTemplateName, ilbNetworkName, ilbSubnetName, ilbRegion, []string{ilbInstanceTag})
-	defer deleteInstanceTemplate(t, ilbProjectID, ilbTemplateName)
-
-	createManagedInstanceGroup(t, ilbProjectID, ilbRegion, "", ilbMigName, ilbTemplateName, 2)
-	defer deleteManagedInstanceGroup(t, ilbProjectID, ilbRegion, "", ilbMigName)
+	// 2. SETUP: Create all prerequisite cloud resources as fixtures, which
+	// are torn down in the reverse order of their dependencies when the test
+	// ends.
+	ilbSubnetCidr = ipam.Subnet(t, 24)
+	network, subnet := createVPC(t, ilbProjectID, ilbNetworkName, ilbRegion, ilbSubnetName, ilbSubnetCidr)
+	trafficRule := createFirewallRuleForILBTraffic(t, ilbProjectID, ilbNetworkName, ilbFwTrafficRuleName, []string{apachePort}, []string{ilbInstanceTag}, ilbSubnetCidr, network)
+	hcRule := createFirewallRuleForNLBHealthChecks(t, ilbProjectID, ilbNetworkName, ilbFwHcRuleName, []string{ilbInstanceTag}, network)
+	template := createInstanceTemplate(t, ilbProjectID, ilbTemplateName, ilbNetworkName, ilbSubnetName, ilbRegion, []string{ilbInstanceTag}, subnet)
+	mig := createManagedInstanceGroup(t, ilbProjectID, ilbRegion, "", ilbMigName, ilbTemplateName, 2, template)
 	setNamedPortsOnMIG(t, ilbProjectID, ilbRegion, "", ilbMigName, "http", apachePort)
 
-	// 3. EXECUTION: Run terraform init and apply.
-	terraformOptions := terraform.WithDefaultRetryableErrors(t, &terraform.Options{
+	// 3. EXECUTION: Run terraform init and apply. The load balancers are
+	// destroyed before their backends and the firewall rules.
+	terraformOptions := retryable.With(t, &terraform.Options{
 		TerraformDir:         ilbTerraformDirectoryPath,
 		Vars:                 map[string]interface{}{"config_folder_path": ilbConfigFolderPath},
 		Reconfigure:          true,
@@ -300,7 +259,7 @@ func TestCreateInternalLoadBalancer(t *testing.T) {
 		NoColor:              true,
 		SetVarsAfterVarFiles: true,
 	})
-	defer terraform.Destroy(t, terraformOptions)
+	fixture.Terraform(t, terraformOptions, mig, trafficRule, hcRule)
 
 	_, err := terraform.InitAndApplyE(t, terraformOptions)
 	if !assert.NoError(t, err, "Terraform apply failed for ILB") {
@@ -316,6 +275,20 @@ func TestCreateInternalLoadBalancer(t *testing.T) {
 		t.FailNow()
 	}
 
+	// The probe VM sends the requests to every load balancer from the subnet
+	// of the backends.
+	client := gcloud.New(ilbProjectID)
+	client.Labels = naming.Labels(t)
+	vm, err := probe.NewVM(t, client, probe.VMOptions{
+		Name:    ilbTestVmName,
+		Zone:    ilbZone,
+		Network: ilbNetworkName,
+		Subnet:  ilbSubnetName,
+	}, subnet)
+	if err != nil {
+		t.Fatalf("Failed to create test VM %s: %v", ilbTestVmName, err)
+	}
+
 	// Loop through each load balancer created by Terraform and verify it.
 	for lbNameFromOutput, fwdRules := range loadBalancersToTest {
 		t.Run(lbNameFromOutput, func(t *testing.T) {
@@ -330,11 +303,7 @@ func TestCreateInternalLoadBalancer(t *testing.T) {
 					return true // continue to next rule
 				}
 
-				// Create a unique test VM for each forwarding rule to be tested
-				testVmName := fmt.Sprintf("test-vm-%s-%s", ilbInstanceName, ruleKey.String())
-				createTestVM(t, ilbProjectID, ilbZone, testVmName, ilbNetworkName, ilbSubnetName, lbIP, ilbTestBucketName, apachePort)
-				defer deleteTestVM(t, ilbProjectID, ilbZone, testVmName)
-				verifyResponseFromGCS(t, ilbProjectID, ilbTestBucketName, testVmName)
+				verifyPassthroughResponse(t, vm, lbIP, apachePort)
 				return true // continue ForEach
 			})
 			t.Logf("--- Finished Verification for Internal Load Balancer: %s ---", lbNameFromOutput)
@@ -342,6 +311,25 @@ func TestCreateInternalLoadBalancer(t *testing.T) {
 	}
 }
 
+/*
+setNames is a helper function which derives the names of the load balancers
+and of their network, backends and probe VM from the run and the test.
+*/
+func setNames(t *testing.T) {
+	ilbInstanceName = naming.Name(t, naming.Compute, "ilb-test")
+	ilbNamesToTest = []string{
+		fmt.Sprintf("lite-%s", ilbInstanceName),
+		fmt.Sprintf("expanded-%s", ilbInstanceName),
+	}
+	ilbNetworkName = fmt.Sprintf("vpc-%s", ilbInstanceName)
+	ilbSubnetName = fmt.Sprintf("%s-subnet", ilbNetworkName)
+	ilbMigName = fmt.Sprintf("mig-%s-regional", ilbInstanceName)
+	ilbTemplateName = fmt.Sprintf("it-%s", ilbInstanceName)
+	ilbFwHcRuleName = fmt.Sprintf("%s-fw-hc", ilbNetworkName)
+	ilbFwTrafficRuleName = fmt.Sprintf("%s-fw-traffic", ilbNetworkName)
+	ilbTestVmName = fmt.Sprintf("test-vm-%s", ilbInstanceName)
+}
+
 // YAML Generation Function
 // createInternalLoadBalancerYAML generates the YAML config files for the ILB tests.
 func createInternalLoadBalancerYAML(t *testing.T) {
@@ -353,13 +341,14 @@ func createInternalLoadBalancerYAML(t *testing.T) {
 	err = os.MkdirAll(ilbConfigFolderPath, 0755)
 	assert.NoError(t, err, "Failed to create ILB config directory %s", ilbConfigFolderPath)
 
-	minimalILBCfg := NetworkLoadBalancerConfig{
+	minimalILBCfg := schema.InternalLoadBalancer{
 		Name:       ilbNamesToTest[0],
 		Project:    ilbProjectID,
 		Region:     ilbRegion,
 		Network:    ilbNetworkName,
 		Subnetwork: ilbSubnetName,
-		Backends: []BackendItemConfig{
+		Labels:     naming.Labels(t),
+		Backends: []schema.NLBBackend{
 			{GroupName: ilbMigName, GroupRegion: ilbRegion},
 		},
 	}
@@ -374,29 +363,27 @@ func createInternalLoadBalancerYAML(t *testing.T) {
 	hcTimeout, hcCheckInterval := 5, 10
 	hcEnableLogging := true
 
-	maximalILBCfg := NetworkLoadBalancerConfig{
+	maximalILBCfg := schema.InternalLoadBalancer{
 		Name:        ilbNamesToTest[1],
 		Project:     ilbProjectID,
 		Region:      ilbRegion,
 		Network:     ilbNetworkName,
 		Subnetwork:  ilbSubnetName,
 		Description: "Expanded ILB with custom HC",
-		Backends: []BackendItemConfig{
+		Labels:      naming.Labels(t),
+		Backends: []schema.NLBBackend{
 			{GroupName: ilbMigName, GroupRegion: ilbRegion},
 		},
-		HealthCheck: &NetworkHealthCheckConfig{
-			Description:      "Custom Health Check for ILB",
+		HealthCheck: &schema.ILBHealthCheck{
+			Type:             "tcp",
+			Port:             schema.Int(80),
 			CheckIntervalSec: &hcCheckInterval,
 			TimeoutSec:       &hcTimeout,
-			EnableLogging:    &hcEnableLogging,
-			TCP:              &TCPHealthCheck{PortSpecification: "USE_SERVING_PORT"},
+			EnableLog:        &hcEnableLogging,
 		},
-		ForwardingRules: map[string]ForwardingRuleConfig{
-			"expanded-rule": {
-				Protocol:    "TCP",
-				Ports:       []string{apachePort},
-				Description: "Forwarding rule for HTTP traffic",
-			},
+		ForwardingRule: &schema.ILBForwardingRule{
+			Protocol: "TCP",
+			Ports:    []string{apachePort},
 		},
 	}
 	yamlMaximalData, err := yaml.Marshal(&maximalILBCfg)
@@ -439,62 +426,58 @@ func verifyInternalLoadBalancerConfiguration(t *testing.T, lbNameFromOutput stri
 }
 
 // Prerequisite Infrastructure Helper Functions (gcloud Wrappers)
-func createVPC(t *testing.T, projectID, networkName, region, subnetName, subnetCidr string) {
-	t.Logf("Creating VPC '%s' and subnet '%s'", networkName, subnetName)
-	vpcCmd := shell.Command{Command: "gcloud", Args: []string{"compute", "networks", "create", networkName, "--project=" + projectID, "--subnet-mode=custom", "--bgp-routing-mode=global"}}
-	_, err := shell.RunCommandAndGetOutputE(t, vpcCmd)
-	assert.NoError(t, err, "Error creating VPC")
-
-	subnetCmd := shell.Command{Command: "gcloud", Args: []string{"compute", "networks", "subnets", "create", subnetName, "--project=" + projectID, "--network=" + networkName, "--region=" + region, "--range=" + subnetCidr}}
-	_, err = shell.RunCommandAndGetOutputE(t, subnetCmd)
-	assert.NoError(t, err, "Error creating subnet")
-	t.Logf("Successfully created VPC and Subnet.")
-}
-
-func deleteVPC(t *testing.T, projectID, networkName, region, subnetName string) {
-	t.Logf("Deleting VPC '%s' and subnet '%s'", networkName, subnetName)
 
-	// Delete subnet
-	subnetCmd := shell.Command{Command: "gcloud", Args: []string{"compute", "networks", "subnets", "delete", subnetName, "--project=" + projectID, "--region=" + region, "--quiet"}}
-	if err := shell.RunCommandE(t, subnetCmd); err != nil {
-		t.Logf("Warning: failed to delete subnet %s. Error: %v", subnetName, err)
+// createVPC creates the network and subnet of the test as fixtures and
+// returns their handles.
+func createVPC(t *testing.T, projectID, networkName, region, subnetName, subnetCidr string) (network, subnet *fixture.Handle) {
+	client := gcloud.New(projectID)
+	network, err := fixture.Network(t, client, networkName, gcloud.NetworkOptions{BGPRoutingMode: "global"})
+	if err != nil {
+		t.Fatalf("Error creating VPC %s: %v", networkName, err)
 	}
-
-	time.Sleep(5 * time.Second)
-
-	// Delete VPC network
-	vpcCmd := shell.Command{Command: "gcloud", Args: []string{"compute", "networks", "delete", networkName, "--project=" + projectID, "--quiet"}}
-	if err := shell.RunCommandE(t, vpcCmd); err != nil {
-		t.Logf("Warning: failed to delete network %s. Error: %v", networkName, err)
+	subnet, err = fixture.Subnet(t, client, subnetName, gcloud.SubnetOptions{Network: networkName, Region: region, Range: subnetCidr}, network)
+	if err != nil {
+		t.Fatalf("Error creating subnet %s: %v", subnetName, err)
 	}
+	return network, subnet
 }
 
-func createFirewallRuleForILBTraffic(t *testing.T, projectID, network, ruleName string, ports, tags []string, sourceCidr string) {
+func createFirewallRuleForILBTraffic(t *testing.T, projectID, network, ruleName string, ports, tags []string, sourceCidr string, dependsOn ...*fixture.Handle) *fixture.Handle {
 	t.Logf("Creating firewall rule '%s' to allow traffic from '%s'", ruleName, sourceCidr)
-	rules := "tcp:" + strings.Join(ports, ",tcp:")
-	cmd := shell.Command{Command: "gcloud", Args: []string{"compute", "firewall-rules", "create", ruleName, "--project=" + projectID, "--network=" + network, "--action=ALLOW", "--rules=" + rules, "--source-ranges=" + sourceCidr, "--target-tags=" + strings.Join(tags, ",")}}
-	_, err := shell.RunCommandAndGetOutputE(t, cmd)
-	assert.NoError(t, err, "Failed to create firewall rule for ILB traffic")
+	var allow []string
+	for _, port := range ports {
+		allow = append(allow, "tcp:"+port)
+	}
+	rule, err := fixture.Firewall(t, gcloud.New(projectID), ruleName, gcloud.FirewallOptions{
+		Network:      network,
+		Allow:        allow,
+		SourceRanges: []string{sourceCidr},
+		TargetTags:   tags,
+	}, dependsOn...)
+	if err != nil {
+		t.Fatalf("Failed to create firewall rule for ILB traffic: %v", err)
+	}
+	return rule
 }
 
-func createFirewallRuleForNLBHealthChecks(t *testing.T, projectID, network, ruleName string, tags []string) {
+func createFirewallRuleForNLBHealthChecks(t *testing.T, projectID, network, ruleName string, tags []string, dependsOn ...*fixture.Handle) *fixture.Handle {
 	t.Logf("Creating firewall rule '%s' for GCP health checkers", ruleName)
 	// These are Google's public IP ranges for health checkers.
-	hcRanges := "130.211.0.0/22,35.191.0.0/16,209.85.152.0/22,209.85.204.0/22"
-	cmd := shell.Command{Command: "gcloud", Args: []string{"compute", "firewall-rules", "create", ruleName, "--project=" + projectID, "--network=" + network, "--action=ALLOW", "--rules=tcp", "--source-ranges=" + hcRanges, "--target-tags=" + strings.Join(tags, ",")}}
-	_, err := shell.RunCommandAndGetOutputE(t, cmd)
-	assert.NoError(t, err, "Failed to create firewall rule for health checks")
-}
-
-func deleteFirewallRule(t *testing.T, projectID, ruleName string) {
-	t.Logf("Deleting firewall rule '%s'", ruleName)
-	cmd := shell.Command{Command: "gcloud", Args: []string{"compute", "firewall-rules", "delete", ruleName, "--project=" + projectID, "--quiet"}}
-	if err := shell.RunCommandE(t, cmd); err != nil {
-		t.Logf("Warning: failed to delete firewall rule %s. This may be expected if the test failed before creation. Error: %v", ruleName, err)
+	rule, err := fixture.Firewall(t, gcloud.New(projectID), ruleName, gcloud.FirewallOptions{
+		Network:      network,
+		Allow:        []string{"tcp"},
+		SourceRanges: []string{"130.211.0.0/22", "35.191.0.0/16", "209.85.152.0/22", "209.85.204.0/22"},
+		TargetTags:   tags,
+	}, dependsOn...)
+	if err != nil {
+		t.Fatalf("Failed to create firewall rule for health checks: %v", err)
 	}
+	return rule
 }
 
-func createInstanceTemplate(t *testing.T, projectID, templateName, network, subnet, region string, tags []string) {
+// createInstanceTemplate creates the template of the backends and registers
+// its deletion once the instance groups using it are gone.
+func createInstanceTemplate(t *testing.T, projectID, templateName, network, subnet, region string, tags []string, dependsOn ...*fixture.Handle) *fixture.Handle {
 	t.Logf("Creating instance template '%s' with an IP echo server", templateName)
 
 	// This startup script runs a simple Python web server that echoes the client's IP address.
@@ -528,23 +511,23 @@ nohup python3 /echo_server.py > /dev/null 2>&1 &`
 	metadataFlag := fmt.Sprintf("startup-script=%s", scriptPath)
 
 	cmd := shell.Command{Command: "gcloud", Args: []string{"compute", "instance-templates", "create", templateName, "--project=" + projectID, "--machine-type=e2-small", "--image-family=ubuntu-2204-lts", "--image-project=ubuntu-os-cloud", "--network=" + network, "--subnet=" + subnet, "--region=" + region, "--tags=" + strings.Join(tags, ","), "--metadata-from-file", metadataFlag}}
-	_, err = shell.RunCommandAndGetOutputE(t, cmd)
-	assert.NoError(t, err, "Failed to create Instance Template")
-}
-
-func deleteInstanceTemplate(t *testing.T, projectID, templateName string) {
-	t.Logf("Deleting instance template '%s'", templateName)
-	cmd := shell.Command{
-		Command: "gcloud",
-		Args:    []string{"compute", "instance-templates", "delete", templateName, "--project=" + projectID, "--quiet"},
-	}
-
-	if err := shell.RunCommandE(t, cmd); err != nil {
-		t.Logf("Warning: failed to delete instance template %s. Error: %v", templateName, err)
+	if _, err := shell.RunCommandAndGetOutputE(t, cmd); err != nil {
+		t.Fatalf("Failed to create Instance Template %s: %v", templateName, err)
 	}
+	client := gcloud.New(projectID)
+	res := fixture.Resource{Kind: "instance template", ID: fmt.Sprintf("projects/%s/global/instanceTemplates/%s", projectID, templateName)}
+	return fixture.Register(t, res, func() error {
+		_, err := wait.ForE(t, res.String()+" to be deleted", wait.DefaultOptions, wait.Deletes(func() error {
+			_, err := client.Run(t, "compute", "instance-templates", "delete", templateName)
+			return err
+		}))
+		return err
+	}, dependsOn...)
 }
 
-func createManagedInstanceGroup(t *testing.T, projectID, region, zone, migName, templateName string, size int) {
+// createManagedInstanceGroup creates the backends from the template and
+// registers their deletion before the template is deleted.
+func createManagedInstanceGroup(t *testing.T, projectID, region, zone, migName, templateName string, size int, template *fixture.Handle) *fixture.Handle {
 	t.Logf("Creating MIG '%s'", migName)
 	args := []string{"compute", "instance-groups", "managed", "create", migName, "--project=" + projectID, "--base-instance-name", migName, "--size", fmt.Sprintf("%d", size), "--template", templateName}
 	if zone != "" {
@@ -553,25 +536,22 @@ func createManagedInstanceGroup(t *testing.T, projectID, region, zone, migName,
 		args = append(args, "--region="+region)
 	}
 	cmd := shell.Command{Command: "gcloud", Args: args}
-	_, err := shell.RunCommandAndGetOutputE(t, cmd)
-	assert.NoError(t, err, "Failed to create MIG")
-	t.Logf("Waiting 120s for MIG '%s' to stabilize...", migName)
-	time.Sleep(120 * time.Second)
-}
-
-func deleteManagedInstanceGroup(t *testing.T, projectID, region, zone, migName string) {
-	t.Logf("Deleting MIG '%s'", migName)
-	args := []string{"compute", "instance-groups", "managed", "delete", migName, "--project=" + projectID, "--quiet"}
-	if zone != "" {
-		args = append(args, "--zone="+zone)
-	} else {
-		args = append(args, "--region="+region)
+	if _, err := shell.RunCommandAndGetOutputE(t, cmd); err != nil {
+		t.Fatalf("Failed to create MIG %s: %v", migName, err)
 	}
-	cmd := shell.Command{Command: "gcloud", Args: args}
-
-	if err := shell.RunCommandE(t, cmd); err != nil {
-		t.Logf("Warning: failed to delete MIG %s. Error: %v", migName, err)
+	client := gcloud.New(projectID)
+	res := fixture.Resource{Kind: "managed instance group", ID: fmt.Sprintf("projects/%s/regions/%s/instanceGroupManagers/%s", projectID, region, migName)}
+	if zone != "" {
+		res.ID = fmt.Sprintf("projects/%s/zones/%s/instanceGroupManagers/%s", projectID, zone, migName)
 	}
+	mig := fixture.Register(t, res, func() error {
+		_, err := wait.ForE(t, res.String()+" to be deleted", wait.DefaultOptions, wait.Deletes(func() error {
+			return client.InstanceGroupManagers.Delete(t, region, zone, migName)
+		}))
+		return err
+	}, template)
+	wait.For(t, "MIG "+migName+" to stabilize", wait.DefaultOptions, wait.MIGStable(t, client, region, zone, migName))
+	return mig
 }
 
 func setNamedPortsOnMIG(t *testing.T, projectID, region, zone, migName, portName, portNumber string) {
@@ -587,73 +567,6 @@ func setNamedPortsOnMIG(t *testing.T, projectID, region, zone, migName, portName
 	assert.NoError(t, err, "Failed to set named ports on MIG")
 }
 
-func createTestVM(t *testing.T, projectID, zone, vmName, network, subnet, lbIpToTest, bucketName, apachePort string) {
-	t.Logf("Creating test VM '%s' to test LB IP %s", vmName, lbIpToTest)
-
-	// This startup script curls the LB, checks the response, and writes the result to a GCS bucket.
-	startupScript := fmt.Sprintf(`#!/bin/bash
-apt-get update -y
-apt-get install -y curl google-cloud-sdk
-
-# Get the VM's own internal IP to check against the LB response
-MY_IP=$(curl -H "Metadata-Flavor: Google" http://metadata.google.internal/computeMetadata/v1/instance/network-interfaces/0/ip)
-LB_IP="%s"
-BUCKET_NAME="%s"
-RESULT_OBJECT_NAME="%s" # Use the VM name for a unique object name
-
-# Wait a bit for the LB to be ready, then curl it.
-sleep 20
-RESPONSE=$(curl -s --fail -m 10 http://${LB_IP}:%s)
-
-# Check if the response from the LB matches the VM's own IP
-if [ "$RESPONSE" == "$MY_IP" ]; then
-  echo "success" > /tmp/result.txt
-  echo "SUCCESS: Response from LB ($RESPONSE) matched client IP ($MY_IP)."
-else
-  echo "failure" > /tmp/result.txt
-  echo "FAILURE: Response from LB ($RESPONSE) did NOT match client IP ($MY_IP)."
-fi
-
-# Upload the result to GCS
-gsutil cp /tmp/result.txt gs://${BUCKET_NAME}/${RESULT_OBJECT_NAME}.txt
-`, lbIpToTest, bucketName, vmName, apachePort)
-
-	scriptFileName := fmt.Sprintf("startup-script-%s.sh", vmName)
-	scriptPath := filepath.Join(ilbConfigFolderPath, scriptFileName)
-	err := os.WriteFile(scriptPath, []byte(startupScript), 0755)
-	assert.NoError(t, err, "Failed to write startup script to file")
-
-	cmd := shell.Command{Command: "gcloud", Args: []string{
-		"compute", "instances", "create", vmName,
-		"--project=" + projectID,
-		"--zone=" + zone,
-		"--machine-type=e2-micro",
-		"--image-family=ubuntu-2204-lts",
-		"--image-project=ubuntu-os-cloud",
-		"--network=" + network,
-		"--subnet=" + subnet,
-		"--scopes=https://www.googleapis.com/auth/cloud-platform",            // Important: Scope for GCS access
-		"--metadata-from-file", fmt.Sprintf("startup-script=%s", scriptPath), // Use --metadata-from-file to safely pass the script.
-	}}
-
-	_, err = retry.DoWithRetryE(t, fmt.Sprintf("Create Test VM %s", vmName), 2, 10*time.Second, func() (string, error) {
-		return shell.RunCommandAndGetOutputE(t, cmd)
-	})
-	assert.NoError(t, err, "Failed to create test VM")
-}
-
-func deleteTestVM(t *testing.T, projectID, zone, vmName string) {
-	t.Logf("Deleting test VM '%s'", vmName)
-	cmd := shell.Command{
-		Command: "gcloud",
-		Args:    []string{"compute", "instances", "delete", vmName, "--project=" + projectID, "--zone=" + zone, "--quiet"},
-	}
-
-	if err := shell.RunCommandE(t, cmd); err != nil {
-		t.Logf("Warning: failed to delete VM %s. Error: %v", vmName, err)
-	}
-}
-
 // getYamlFileForTest determines the source YAML filename based on the LB's name prefix.
 func getYamlFileForTest(lbName string) string {
 	if strings.Contains(lbName, "lite") {
@@ -665,45 +578,25 @@ func getYamlFileForTest(lbName string) string {
 	return ""
 }
 
-// verifyResponseFromGCS polls a GCS bucket to check for a success/failure result file.
-func verifyResponseFromGCS(t *testing.T, projectID, bucketName, objectName string) {
-	t.Logf("Waiting for test result from VM '%s' in GCS bucket '%s'...", objectName, bucketName)
-
-	maxRetries := 10
-	sleepBetweenRetries := 60 * time.Second
-	objectPath := fmt.Sprintf("%s.txt", objectName)
-
-	message, err := retry.DoWithRetryE(t, "Poll GCS for test result", maxRetries, sleepBetweenRetries, func() (string, error) {
-		ctx := context.Background()
-		client, err := storage.NewClient(ctx)
-		if err != nil {
-			return "", fmt.Errorf("failed to create storage client: %w", err)
-		}
-		defer client.Close()
-
-		rc, err := client.Bucket(bucketName).Object(objectPath).NewReader(ctx)
-		if err != nil {
-			// This is an expected error while we wait for the file to be created.
-			return "", fmt.Errorf("result object '%s' not yet available in bucket '%s'", objectPath, bucketName)
-		}
-		defer rc.Close()
-
-		// Read the content of the result file.
-		content, err := ioutil.ReadAll(rc)
-		if err != nil {
-			return "", fmt.Errorf("failed to read result object: %w", err)
-		}
-
-		result := strings.TrimSpace(string(content))
-		if result == "success" {
-			t.Logf("Success! Received 'success' status from VM %s.", objectName)
-			return "Verification successful", nil // Return success to stop retrying.
-		}
-
-		// If the result is "failure" or anything else, we have a definitive failure.
-		return "", retry.FatalError{Underlying: fmt.Errorf("received '%s' status from VM %s", result, objectName)}
-	})
+// verifyPassthroughResponse checks from the probe VM that the load balancer
+// forwards HTTP requests on port to the backends, and that the backend sees
+// the address of the VM, which a passthrough load balancer preserves.
+func verifyPassthroughResponse(t *testing.T, vm *probe.VM, lbIP, port string) {
+	url := fmt.Sprintf("http://%s:%s", lbIP, port)
+	opts := wait.Options{Timeout: 10 * time.Minute, Interval: 20 * time.Second, MaxInterval: time.Minute}
+	if _, err := vm.Eventually(t, opts, true, probe.HTTP(url)); err != nil {
+		t.Errorf("Failed to reach ILB %s from VM %s: %v", url, vm.Name, err)
+		return
+	}
 
-	assert.NoError(t, err, "Failed to verify passthrough response after multiple retries.")
-	t.Log(message)
+	instance, err := vm.Client.Instances.Describe(t, vm.Zone, vm.Name)
+	if !assert.NoError(t, err, "Failed to describe test VM %s", vm.Name) || !assert.NotEmpty(t, instance.NetworkInterfaces, "Test VM %s has no network interface", vm.Name) {
+		return
+	}
+	// The backends echo the address of the client.
+	response, err := vm.Client.Instances.SSH(t, vm.Zone, vm.Name, "curl -s --fail -m 10 "+url)
+	if !assert.NoError(t, err, "Failed to send a request to ILB %s from VM %s", url, vm.Name) {
+		return
+	}
+	assert.Equal(t, instance.NetworkInterfaces[0].NetworkIP, strings.TrimSpace(response), "The backends behind ILB %s did not see the address of the client", url)
 }
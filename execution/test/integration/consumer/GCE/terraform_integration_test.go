@@ -18,14 +18,21 @@ package integrationtest
 
 import (
 	"fmt"
-	"math/rand"
 	"os"
 	"path/filepath"
 	"strings"
 	"testing"
-	"time"
 
-	"github.com/gruntwork-io/terratest/modules/shell"
+	"github.com/GoogleCloudPlatform/cloudnetworking-config-solutions/common_utils/fixture"
+	"github.com/GoogleCloudPlatform/cloudnetworking-config-solutions/common_utils/gcloud"
+	"github.com/GoogleCloudPlatform/cloudnetworking-config-solutions/common_utils/ipam"
+	"github.com/GoogleCloudPlatform/cloudnetworking-config-solutions/common_utils/location"
+	"github.com/GoogleCloudPlatform/cloudnetworking-config-solutions/common_utils/naming"
+	"github.com/GoogleCloudPlatform/cloudnetworking-config-solutions/common_utils/preflight"
+	"github.com/GoogleCloudPlatform/cloudnetworking-config-solutions/common_utils/profile"
+	"github.com/GoogleCloudPlatform/cloudnetworking-config-solutions/common_utils/retryable"
+	"github.com/GoogleCloudPlatform/cloudnetworking-config-solutions/common_utils/schema"
+	"github.com/GoogleCloudPlatform/cloudnetworking-config-solutions/common_utils/wait"
 	"github.com/gruntwork-io/terratest/modules/terraform"
 	"github.com/tidwall/gjson"
 	"gopkg.in/yaml.v2"
@@ -39,28 +46,31 @@ var (
 )
 
 var (
-	projectID    = os.Getenv("TF_VAR_project_id")
-	instanceName = fmt.Sprintf("gce-%d", rand.Int())
-	region       = "us-central1"
-	zone         = "us-central1-a"
-	networkName  = fmt.Sprintf("vpc-%s-test", instanceName)
-	networkID    = fmt.Sprintf("projects/%s/global/networks/%s", projectID, networkName)
-	subnetworkID = fmt.Sprintf("projects/%s/regions/%s/subnetworks/%s-subnet", projectID, region, networkName)
+	env, envErr = profile.Current()
+	projectID   = env.Projects.Endpoint
+	region      = env.Regions.Default
+	// The names below are derived from the run and the test by setNames.
+	instanceName string
+	networkName  string
+	networkID    string
+	subnetworkID string
 )
 
-// VMInstanceConfig struct
-type VMInstanceConfig struct {
-	Name       string `yaml:"name"`
-	ProjectID  string `yaml:"project_id"`
-	Region     string `yaml:"region"`
-	Zone       string `yaml:"zone"`
-	Image      string `yaml:"image"`
-	Network    string `yaml:"network"`
-	Subnetwork string `yaml:"subnetwork"`
+// TestMain checks the APIs, roles and quota the suite needs before any
+// instance is created.
+func TestMain(m *testing.M) {
+	os.Exit(preflight.Run(m, preflight.Requirements{
+		Profile: envErr,
+		Project: projectID,
+		APIs:    []string{"compute.googleapis.com"},
+		Roles:   []string{"roles/compute.instanceAdmin.v1", "roles/compute.networkAdmin", "roles/iam.serviceAccountUser"},
+		Quotas:  []preflight.Quota{{Metric: "NETWORKS"}, {Metric: "INSTANCES", Region: region}},
+	}))
 }
 
 func TestCreateVMInstances(t *testing.T) {
-	createConfigYAML(t) // Use the updated createConfigYAML for GCE
+	profile.Require(t, "projects.endpoint")
+	setNames(t)
 
 	// Terraform Variables (GCE-Specific)
 	tfVars := map[string]any{
@@ -68,7 +78,7 @@ func TestCreateVMInstances(t *testing.T) {
 	}
 
 	// Terraform Options
-	terraformOptions := terraform.WithDefaultRetryableErrors(t, &terraform.Options{
+	terraformOptions := retryable.With(t, &terraform.Options{
 		Vars:                 tfVars,
 		TerraformDir:         terraformDirectoryPath,
 		Reconfigure:          true,
@@ -77,183 +87,150 @@ func TestCreateVMInstances(t *testing.T) {
 		SetVarsAfterVarFiles: true,
 	})
 
-	// Create VPC and Subnet Before Applying Terraform
-	createVPC(t, projectID, networkName)
-	time.Sleep(60 * time.Second)
-
-	// Apply Terraform
-	terraform.InitAndApply(t, terraformOptions)
+	// Create VPC and Subnet Before Applying Terraform. Terraform is destroyed
+	// before them, however the test ends.
+	client := gcloud.New(projectID)
+	subnet := createVPC(t, client, networkName)
+	subnetName := fmt.Sprintf("%s-subnet", networkName)
+	wait.For(t, "subnet "+subnetName+" to be ready", wait.DefaultOptions, wait.SubnetReady(t, client, region, subnetName))
+	fixture.Terraform(t, terraformOptions, subnet)
+
+	// Apply Terraform, moving to a fallback zone of the region when the zone
+	// is out of capacity. What an attempt created is destroyed before the next
+	// zone is tried.
+	loc, err := location.Try(t, location.Zones(env), func(loc location.Location) error {
+		createConfigYAML(t, loc)
+		_, err := terraform.InitAndApplyE(t, terraformOptions)
+		if location.IsCapacityError(err) {
+			if _, destroyErr := terraform.DestroyE(t, terraformOptions); destroyErr != nil {
+				return fmt.Errorf("destroying the resources created in %s: %w", loc, destroyErr)
+			}
+		}
+		return err
+	})
+	if err != nil {
+		t.Fatalf("Applying the GCE configuration in %s failed: %v", loc, err)
+	}
 
 	// Get Instance Information from Terraform Output
 	vmInstancesOutput := terraform.OutputJson(t, terraformOptions, "vm_instances")
 	vmInstances := gjson.Parse(vmInstancesOutput).Map()
 
-	maxRetries := 5
-	retryInterval := 15 * time.Second
-
 	// Wait for Instances to be Running & Verify Configuration
 	for k, instanceDetails := range vmInstances { // Iterate over keys and values
 		instanceName := instanceDetails.Get("name").String() // Extract the name from the object
 		zone := strings.Split(k, "/")[2]                     // Extract the zone from the key
 
-		for i := 0; i < maxRetries; i++ {
-			gcloudOutput := shell.RunCommandAndGetOutput(t, shell.Command{
-				Command: "gcloud",
-				Args:    []string{"compute", "instances", "describe", instanceName, "--zone", zone, "--project", projectID, "--format", "json"},
-			})
-			status := gjson.Get(gcloudOutput, "status").String()
-
-			if status == "RUNNING" {
-				// Verify Instance Configuration (against YAML)
-				yamlFile, err := os.ReadFile(filepath.Join(configFolderPath, "instance1.yaml"))
-				if err != nil {
-					t.Errorf("Error reading YAML file: %s", err)
-					break
-				}
-
-				var expectedInstance VMInstanceConfig
-				err = yaml.Unmarshal(yamlFile, &expectedInstance)
-				if err != nil {
-					t.Errorf("Error unmarshaling YAML: %s", err)
-					break
-				}
+		wait.For(t, "instance "+instanceName+" to be running", wait.DefaultOptions, wait.InstanceRunning(t, client, zone, instanceName))
+		gcloudOutput, err := client.Run(t, "compute", "instances", "describe", instanceName, "--zone", zone)
+		if err != nil {
+			t.Errorf("Error describing instance %s: %s", instanceName, err)
+			continue
+		}
 
-				// Verify instance details
-				t.Log("========= Verify Instance name =========")
-				actualInstanceInfo := gjson.Parse(gcloudOutput)
-				if actualInstanceInfo.Get("name").String() != expectedInstance.Name {
-					t.Errorf("Instance name mismatch: actual=%s, expected=%s", actualInstanceInfo.Get("name").String(), expectedInstance.Name)
-				}
-				t.Log("========= Verify Instance zone =========")
-				zoneName := filepath.Base(actualInstanceInfo.Get("zone").String())
-				if zoneName != expectedInstance.Zone {
-					t.Errorf("Zone mismatch: actual=%s, expected=%s", zoneName, expectedInstance.Zone)
-				}
+		// Verify Instance Configuration (against YAML)
+		var expectedInstance schema.GCE
+		if err := schema.Load(filepath.Join(configFolderPath, "instance1.yaml"), &expectedInstance); err != nil {
+			t.Errorf("Error reading YAML file: %s", err)
+			continue
+		}
 
-				// Check for correct image
-				t.Log("========= Verify Instance image =========")
-				actualImage := gjson.Get(gcloudOutput, "disks.0.licenses.0").String()
-				// Get the image name from YAML config
-				expectedImage := expectedInstance.Image
-				// Split image name on '/'
-				expectedImageParts := strings.Split(expectedImage, "/")
-				// Extract only the image name
-				expectedImageName := expectedImageParts[len(expectedImageParts)-1]
+		// Verify instance details
+		t.Log("========= Verify Instance name =========")
+		actualInstanceInfo := gjson.Parse(gcloudOutput)
+		if actualInstanceInfo.Get("name").String() != expectedInstance.Name {
+			t.Errorf("Instance name mismatch: actual=%s, expected=%s", actualInstanceInfo.Get("name").String(), expectedInstance.Name)
+		}
+		t.Log("========= Verify Instance zone =========")
+		zoneName := filepath.Base(actualInstanceInfo.Get("zone").String())
+		if zoneName != expectedInstance.Zone {
+			t.Errorf("Zone mismatch: actual=%s, expected=%s", zoneName, expectedInstance.Zone)
+		}
 
-				if !strings.Contains(actualImage, expectedImageName) {
-					t.Errorf("Image mismatch: actual=%s, expected to contain %s", actualImage, expectedImageName)
-				}
-				t.Log("========= Verify Instance network =========")
-				// Fix for Network mismatch
-				actualNetwork := gjson.Get(gcloudOutput, "networkInterfaces.0.network").String()
-				if !strings.HasSuffix(actualNetwork, expectedInstance.Network) {
-					t.Errorf("Network mismatch: actual=%s, expected=%s", actualNetwork, expectedInstance.Network)
-				}
-				t.Log("========= Verify Instance subnetwork =========")
-				// Fix for Subnetwork mismatch
-				actualSubnetwork := gjson.Get(gcloudOutput, "networkInterfaces.0.subnetwork").String()
-				if !strings.HasSuffix(actualSubnetwork, expectedInstance.Subnetwork) {
-					t.Errorf("Subnetwork mismatch: actual=%s, expected=%s", actualSubnetwork, expectedInstance.Subnetwork)
-				}
-				break
+		// Check for correct image
+		t.Log("========= Verify Instance image =========")
+		actualImage := gjson.Get(gcloudOutput, "disks.0.licenses.0").String()
+		// Get the image name from YAML config
+		expectedImage := expectedInstance.Image
+		// Split image name on '/'
+		expectedImageParts := strings.Split(expectedImage, "/")
+		// Extract only the image name
+		expectedImageName := expectedImageParts[len(expectedImageParts)-1]
+
+		if !strings.Contains(actualImage, expectedImageName) {
+			t.Errorf("Image mismatch: actual=%s, expected to contain %s", actualImage, expectedImageName)
+		}
+		t.Log("========= Verify Instance network =========")
+		// Fix for Network mismatch
+		actualNetwork := gjson.Get(gcloudOutput, "networkInterfaces.0.network").String()
+		if !strings.HasSuffix(actualNetwork, expectedInstance.Network) {
+			t.Errorf("Network mismatch: actual=%s, expected=%s", actualNetwork, expectedInstance.Network)
+		}
+		t.Log("========= Verify Instance subnetwork =========")
+		// Fix for Subnetwork mismatch
+		actualSubnetwork := gjson.Get(gcloudOutput, "networkInterfaces.0.subnetwork").String()
+		if !strings.HasSuffix(actualSubnetwork, expectedInstance.Subnetwork) {
+			t.Errorf("Subnetwork mismatch: actual=%s, expected=%s", actualSubnetwork, expectedInstance.Subnetwork)
+		}
+		t.Log("========= Verify Instance labels =========")
+		for key, want := range expectedInstance.Labels {
+			if got := actualInstanceInfo.Get("labels." + key).String(); got != want {
+				t.Errorf("Label %s mismatch: actual=%s, expected=%s", key, got, want)
 			}
-
-			t.Logf("Instance '%s' not yet RUNNING (current state: %s). Retrying...", instanceName, status)
-			time.Sleep(retryInterval)
 		}
 	}
-	// Destroy Terraform Resources **First**
-	terraform.Destroy(t, terraformOptions)
-
-	// Delete VPC and Associated Resources (after Terraform destroy)
-	deleteVPC(t, projectID, networkName)
 }
 
 /*
-createVPC creates the VPC and subnet before the test execution.
+createVPC creates the VPC and subnet before the test execution as fixtures,
+which are deleted when the test ends, and returns the handle of the subnet.
 */
-func createVPC(t *testing.T, projectID string, networkName string) {
-	text := "compute"
-
-	// Create VPC
-	cmd := shell.Command{
-		Command: "gcloud",
-		Args:    []string{text, "networks", "create", networkName, "--project=" + projectID, "--format=json", "--bgp-routing-mode=global", "--subnet-mode=custom"},
-	}
-	_, err := shell.RunCommandAndGetOutputE(t, cmd)
+func createVPC(t *testing.T, client *gcloud.Client, networkName string) *fixture.Handle {
+	network, err := fixture.Network(t, client, networkName, gcloud.NetworkOptions{BGPRoutingMode: "global"})
 	if err != nil {
-		t.Logf("===Error %s Encountered while executing %s", err, text)
+		t.Fatalf("===Error %s Encountered while creating VPC %s.", err, networkName)
 	}
 
-	// Create Subnet
 	subnetName := fmt.Sprintf("%s-subnet", networkName)
-	cmd = shell.Command{ // Re-use cmd variable
-		Command: "gcloud",
-		Args: []string{
-			text, "networks", "subnets", "create", subnetName,
-			"--project=" + projectID,
-			"--network=" + networkName,
-			"--region=" + region,
-			"--range=10.0.0.0/24",
-		},
-	}
-	_, err = shell.RunCommandAndGetOutputE(t, cmd)
+	subnet, err := fixture.Subnet(t, client, subnetName, gcloud.SubnetOptions{
+		Network: networkName,
+		Region:  region,
+		Range:   ipam.Subnet(t, 24),
+	}, network)
 	if err != nil {
-		t.Logf("===Error %s Encountered while executing %s", err, text)
+		t.Fatalf("===Error %s Encountered while creating subnet %s.", err, subnetName)
 	}
+	return subnet
 }
 
 /*
-deleteVPC deletes the VPC and subnet after the test.
+setNames is a helper function which derives the names of the instance and of
+its network from the run and the test.
 */
-func deleteVPC(t *testing.T, projectID string, networkName string) {
-	text := "compute"
-	time.Sleep(120 * time.Second) // Wait for resources to be in a deletable state
-
-	// Delete Subnet
-	subnetName := fmt.Sprintf("%s-subnet", networkName)
-	cmd := shell.Command{
-		Command: "gcloud",
-		Args: []string{
-			text, "networks", "subnets", "delete", subnetName,
-			"--project=" + projectID,
-			"--region=" + region,
-			"--quiet",
-		},
-	}
-	_, err := shell.RunCommandAndGetOutputE(t, cmd)
-	if err != nil {
-		t.Errorf("===Error %s Encountered while executing %s", err, text)
-	}
-
-	// 3. Delete VPC
-	time.Sleep(150 * time.Second) // Wait for firewall deletion to complete
-	cmd = shell.Command{
-		Command: "gcloud",
-		Args:    []string{text, "networks", "delete", networkName, "--project=" + projectID, "--quiet"},
-	}
-	_, err = shell.RunCommandAndGetOutputE(t, cmd)
-	if err != nil {
-		t.Errorf("===Error %s Encountered while executing %s", err, text)
-	}
+func setNames(t *testing.T) {
+	instanceName = naming.Name(t, naming.Compute, "gce")
+	networkName = naming.Name(t, naming.Compute, "vpc-gce")
+	networkID = fmt.Sprintf("projects/%s/global/networks/%s", projectID, networkName)
+	subnetworkID = fmt.Sprintf("projects/%s/regions/%s/subnetworks/%s-subnet", projectID, region, networkName)
 }
 
 /*
 createConfigYAML is a helper function which creates the configigration YAML file
-for an MRC instance.
+for a GCE instance in the zone of loc.
 */
-func createConfigYAML(t *testing.T) {
+func createConfigYAML(t *testing.T, loc location.Location) {
 	t.Log("========= YAML File =========")
 
 	// Create a GCE-specific instance configuration
-	gceInstance := VMInstanceConfig{
+	gceInstance := schema.GCE{
 		Name:       instanceName,
 		ProjectID:  projectID,
 		Region:     region,
-		Zone:       zone,                              // Add zone for GCE
+		Zone:       loc.Zone,                          // Add zone for GCE
 		Image:      "ubuntu-os-cloud/ubuntu-2204-lts", // Replace with your desired image
 		Network:    networkID,                         // Use networkID for the network
 		Subnetwork: subnetworkID,                      // Use subnetworkID for the subnetwork
+		Labels:     naming.Labels(t),
 	}
 
 	yamlData, err := yaml.Marshal(&gceInstance)
@@ -18,14 +18,21 @@ package integrationtest
 
 import (
 	"fmt"
-	"math/rand"
 	"net/url"
 	"os"
 	"path/filepath"
 	"strings"
 	"testing"
-	"time"
 
+	"github.com/GoogleCloudPlatform/cloudnetworking-config-solutions/common_utils/gcloud"
+	"github.com/GoogleCloudPlatform/cloudnetworking-config-solutions/common_utils/ipam"
+	"github.com/GoogleCloudPlatform/cloudnetworking-config-solutions/common_utils/location"
+	"github.com/GoogleCloudPlatform/cloudnetworking-config-solutions/common_utils/naming"
+	"github.com/GoogleCloudPlatform/cloudnetworking-config-solutions/common_utils/preflight"
+	"github.com/GoogleCloudPlatform/cloudnetworking-config-solutions/common_utils/profile"
+	"github.com/GoogleCloudPlatform/cloudnetworking-config-solutions/common_utils/retryable"
+	"github.com/GoogleCloudPlatform/cloudnetworking-config-solutions/common_utils/schema"
+	"github.com/GoogleCloudPlatform/cloudnetworking-config-solutions/common_utils/wait"
 	"github.com/gruntwork-io/terratest/modules/shell"
 	"github.com/gruntwork-io/terratest/modules/terraform"
 	"github.com/stretchr/testify/assert"
@@ -41,51 +48,47 @@ var (
 )
 
 var (
-	projectID        = os.Getenv("TF_VAR_project_id")
-	migName          = fmt.Sprintf("mig-%d", rand.Int())
-	region           = "us-central1"
-	zone             = "us-central1-a"
-	vpcName          = "testing-net-mig"
-	subnetName       = "testing-subnet-mig"
-	firewallRuleName = "fw-allow-health-check"
+	env, envErr = profile.Current()
+	projectID   = env.Projects.Endpoint
+	region      = env.Regions.Default
+	zone        = env.Zones.Default
+	// The names below are derived from the run and the test by setNames.
+	migName          string
+	vpcName          string
+	subnetName       string
+	firewallRuleName string
 )
 
-// AutoscalerConfig struct to match the YAML structure
-type AutoscalerConfig struct {
-	MaxReplicas int `yaml:"max_replicas"`
-	MinReplicas int `yaml:"min_replicas"`
+// TestMain checks the APIs, roles and quota the suite needs before the
+// instance group is created.
+func TestMain(m *testing.M) {
+	os.Exit(preflight.Run(m, preflight.Requirements{
+		Profile: envErr,
+		Project: projectID,
+		APIs:    []string{"compute.googleapis.com"},
+		Roles:   []string{"roles/compute.instanceAdmin.v1", "roles/compute.networkAdmin", "roles/iam.serviceAccountUser"},
+		Quotas:  []preflight.Quota{{Metric: "NETWORKS"}, {Metric: "INSTANCES", Region: region, Need: 2}},
+	}))
 }
 
 var (
 	yaml_file_name = "instance.yaml"
 )
 
-// MIGConfig struct to match the new YAML structure
-type MIGConfig struct {
-	Name             string           `yaml:"name"`
-	ProjectID        string           `yaml:"project_id"`
-	Location         string           `yaml:"location"`
-	Zone             string           `yaml:"zone"`
-	VPCName          string           `yaml:"vpc_name"`
-	SubnetworkName   string           `yaml:"subnetwork_name"`
-	TargetSize       int              `yaml:"target_size"`
-	AutoscalerConfig AutoscalerConfig `yaml:"autoscaler_config"`
-}
-
 /*
 TestMIGs tests the creation and configuration of Managed Instance Groups (MIGs) in Google Cloud Platform
 using Terraform. It verifies that the resources are correctly provisioned, checks the status of VM instances,
 and ensures that configurations such as instance group names, zones, and autoscaler settings match expected values.
 */
 func TestMIGs(t *testing.T) {
-	createConfigYAML(t) // Use the updated createConfigYAML for MIG
-
+	profile.Require(t, "projects.endpoint")
+	setNames(t)
 	tfVars := map[string]interface{}{
 		"config_folder_path": configFolderPath,
 	}
 
 	// Terraform Options
-	terraformOptions := terraform.WithDefaultRetryableErrors(t, &terraform.Options{
+	terraformOptions := retryable.With(t, &terraform.Options{
 		Vars:         tfVars,
 		TerraformDir: terraformDirectoryPath,
 		Reconfigure:  true,
@@ -93,17 +96,27 @@ func TestMIGs(t *testing.T) {
 		NoColor:      true,
 	})
 
+	client := gcloud.New(projectID)
 	createVPC(t, projectID, vpcName) // Create VPC before applying Terraform
-	time.Sleep(30 * time.Second)
+	wait.For(t, "subnet "+subnetName+" to be ready", wait.DefaultOptions, wait.SubnetReady(t, client, region, subnetName))
 	createFirewallRule(t, projectID, vpcName) // Create Firewall rule before applying Terraform
-	time.Sleep(30 * time.Second)
+	wait.For(t, "firewall rule "+firewallRuleName+" to exist", wait.DefaultOptions, wait.FirewallExists(t, client, firewallRuleName))
 
 	defer deleteVPC(t, projectID, vpcName)                   // Delete VPC after test
 	defer deleteFirewallRule(t, projectID, firewallRuleName) // Delete Firewall rule after test
 	defer terraform.Destroy(t, terraformOptions)             // Destroy resources after test
 
-	// Apply Terraform
-	terraform.InitAndApply(t, terraformOptions)
+	// Apply Terraform, moving to a fallback zone of the region when the zone
+	// is out of capacity.
+	loc, err := location.Try(t, location.Zones(env), func(loc location.Location) error {
+		zone = loc.Zone
+		createConfigYAML(t) // Use the updated createConfigYAML for MIG
+		_, err := terraform.InitAndApplyE(t, terraformOptions)
+		return err
+	})
+	if err != nil {
+		t.Fatalf("Applying the MIG configuration in %s failed: %v", loc, err)
+	}
 
 	// Retrieve outputs
 	autoscalerOutput := terraform.OutputJson(t, terraformOptions, "autoscaler")
@@ -111,116 +124,103 @@ func TestMIGs(t *testing.T) {
 	vmInstances := gjson.Parse(groupManagerOutput).Map()
 	t.Logf(autoscalerOutput, groupManagerOutput, vmInstances)
 
-	maxRetries := 5
-	retryInterval := 20 * time.Second
-
 	for _, instanceDetails := range vmInstances {
 		instanceName := instanceDetails.Get("name").String()
-		t.Logf("Checking instance details for '%s' in 60 seconds", instanceName)
-		time.Sleep(6 * time.Second)
-
-		for i := 0; i < maxRetries; i++ {
-			statusOutput := shell.RunCommandAndGetOutput(t, shell.Command{
-				Command: "gcloud",
-				Args:    []string{"compute", "instance-groups", "managed", "list-instances", instanceName, "--region", region, "--project=" + projectID, "--format=json"},
-			})
-
-			t.Logf("Status Output: %s", statusOutput)
-			// Parse the JSON output
-			statusJSON := gjson.Parse(statusOutput)
-
-			// Access elements using array indexing where needed
-			status := statusJSON.Get("0.instanceStatus").String() // Accessing first element of array for instanceStatus
-			instanceURL := gjson.Get(statusOutput, "0.instance").String()
-
-			if status == "RUNNING" {
-				t.Logf("Instance group '%s' is stable (Status: '%s'). Proceeding with assertions...", instanceName, status)
-
-				gcloudOutput := shell.RunCommandAndGetOutput(t, shell.Command{
-					Command: "gcloud",
-					Args:    []string{"compute", "instance-groups", "managed", "describe", instanceName, "--region", region, "--project=" + projectID, "--format=json"},
-				})
-
-				yamlFilePath := filepath.Join(configFolderPath, yaml_file_name)
-				yamlFile, err := os.ReadFile(yamlFilePath)
-				if err != nil {
-					t.Fatalf("Error reading YAML file at %s: %s", yamlFilePath, err)
-				} else {
-					t.Logf("Read YAML file correctly.")
-				}
-
-				var expectedInstance MIGConfig
-				err = yaml.Unmarshal(yamlFile, &expectedInstance)
-				if err != nil {
-					t.Fatalf("Error unmarshaling YAML from %s: %s", yamlFilePath, err)
-				} else {
-					t.Logf("Read MIG Config correctly.")
-				}
-
-				t.Log("========= Verify Instance Group =========")
-				actualInstanceInfo := gjson.Parse(gcloudOutput)
-
-				actualName := actualInstanceInfo.Get("name").String()
-				t.Logf("Actual Instance Group Name: %s, Expected: %s", actualName, expectedInstance.Name)
-				if actualName != expectedInstance.Name {
-					t.Errorf("Instance group name mismatch: actual=%s, expected=%s", actualName, expectedInstance.Name)
-				} else {
-					t.Logf("Confirmed instance group name matches: %s", expectedInstance.Name)
-				}
-
-				actualZone := extractZoneFromInstanceURL(instanceURL, t)
-				distributionPolicyZones := gjson.Get(groupManagerOutput, fmt.Sprintf("%s.distribution_policy_zones", instanceName)).Array()
-				zoneStrings := make([]string, len(distributionPolicyZones))
-				for i, z := range distributionPolicyZones {
-					zoneStrings[i] = z.String()
-				}
-
-				if assert.Contains(t, zoneStrings, actualZone, "Actual zone not found in distribution policy zones") {
-					t.Logf("Zone assertion successful: Actual zone '%s' found in distribution policy zones.", actualZone)
-				}
-
-				// Verify Autoscaler Configuration
-				autoscalerConfig := gjson.Parse(autoscalerOutput).Map()
-				for migName := range vmInstances {
-					t.Logf("Verifying autoscaler for MIG key: %s", migName)
-
-					maxReplicas := autoscalerConfig[migName].Get("autoscaling_policy.0.max_replicas").Int()
-					minReplicas := autoscalerConfig[migName].Get("autoscaling_policy.0.min_replicas").Int()
-
-					if assert.Greater(t, maxReplicas, int64(0), "Max replicas should be greater than 0 for %s", migName) {
-						t.Logf("Max replicas assertion successful for %s: %d > 0", migName, maxReplicas)
-					}
-
-					if assert.GreaterOrEqual(t, minReplicas, int64(1), "Min replicas should be at least 1 for %s", migName) {
-						t.Logf("Min replicas assertion successful for %s: %d >= 1", migName, minReplicas)
-					}
-				}
-
-				break // Exit retry loop if successful
+		wait.For(t, "instance group "+instanceName+" to be stable", wait.DefaultOptions, wait.MIGStable(t, client, region, "", instanceName))
+
+		statusOutput := shell.RunCommandAndGetOutput(t, shell.Command{
+			Command: "gcloud",
+			Args:    []string{"compute", "instance-groups", "managed", "list-instances", instanceName, "--region", region, "--project=" + projectID, "--format=json"},
+		})
+
+		t.Logf("Status Output: %s", statusOutput)
+		// Parse the JSON output
+		statusJSON := gjson.Parse(statusOutput)
+
+		// Access elements using array indexing where needed
+		status := statusJSON.Get("0.instanceStatus").String() // Accessing first element of array for instanceStatus
+		instanceURL := gjson.Get(statusOutput, "0.instance").String()
+		t.Logf("Instance group '%s' is stable (Status: '%s'). Proceeding with assertions...", instanceName, status)
+
+		gcloudOutput := shell.RunCommandAndGetOutput(t, shell.Command{
+			Command: "gcloud",
+			Args:    []string{"compute", "instance-groups", "managed", "describe", instanceName, "--region", region, "--project=" + projectID, "--format=json"},
+		})
+
+		yamlFilePath := filepath.Join(configFolderPath, yaml_file_name)
+		var expectedInstance schema.MIG
+		if err := schema.Load(yamlFilePath, &expectedInstance); err != nil {
+			t.Fatalf("Error reading YAML file at %s: %s", yamlFilePath, err)
+		} else {
+			t.Logf("Read MIG Config correctly.")
+		}
+
+		t.Log("========= Verify Instance Group =========")
+		actualInstanceInfo := gjson.Parse(gcloudOutput)
+
+		actualName := actualInstanceInfo.Get("name").String()
+		t.Logf("Actual Instance Group Name: %s, Expected: %s", actualName, expectedInstance.Name)
+		if actualName != expectedInstance.Name {
+			t.Errorf("Instance group name mismatch: actual=%s, expected=%s", actualName, expectedInstance.Name)
+		} else {
+			t.Logf("Confirmed instance group name matches: %s", expectedInstance.Name)
+		}
+
+		actualZone := extractZoneFromInstanceURL(instanceURL, t)
+		distributionPolicyZones := gjson.Get(groupManagerOutput, fmt.Sprintf("%s.distribution_policy_zones", instanceName)).Array()
+		zoneStrings := make([]string, len(distributionPolicyZones))
+		for i, z := range distributionPolicyZones {
+			zoneStrings[i] = z.String()
+		}
+
+		if assert.Contains(t, zoneStrings, actualZone, "Actual zone not found in distribution policy zones") {
+			t.Logf("Zone assertion successful: Actual zone '%s' found in distribution policy zones.", actualZone)
+		}
+
+		// Verify Autoscaler Configuration
+		autoscalerConfig := gjson.Parse(autoscalerOutput).Map()
+		for migName := range vmInstances {
+			t.Logf("Verifying autoscaler for MIG key: %s", migName)
+
+			maxReplicas := autoscalerConfig[migName].Get("autoscaling_policy.0.max_replicas").Int()
+			minReplicas := autoscalerConfig[migName].Get("autoscaling_policy.0.min_replicas").Int()
+
+			if assert.Greater(t, maxReplicas, int64(0), "Max replicas should be greater than 0 for %s", migName) {
+				t.Logf("Max replicas assertion successful for %s: %d > 0", migName, maxReplicas)
 			}
 
-			t.Logf("Instance group '%s' not yet stable (Status: '%s'). Retrying...", instanceName, status)
-			time.Sleep(retryInterval)
+			if assert.GreaterOrEqual(t, minReplicas, int64(1), "Min replicas should be at least 1 for %s", migName) {
+				t.Logf("Min replicas assertion successful for %s: %d >= 1", migName, minReplicas)
+			}
 		}
 	}
 
 }
 
+// setNames derives the names of the MIG, of its network and of its firewall
+// rule from the run and the test.
+func setNames(t *testing.T) {
+	migName = naming.Name(t, naming.Compute, "mig")
+	vpcName = naming.Name(t, naming.Compute, "testing-net-mig")
+	subnetName = naming.Name(t, naming.Compute, "testing-subnet-mig")
+	firewallRuleName = naming.Name(t, naming.Compute, "fw-allow-health-check")
+}
+
 // createConfigYAML creates the configuration YAML file for a MIG instance.
 func createConfigYAML(t *testing.T) {
 	t.Log("========= YAML File =========")
 
-	migInstance := MIGConfig{
+	migInstance := schema.MIG{
 		Name:           migName,
 		ProjectID:      projectID,
 		Location:       region,
 		Zone:           zone,
 		VPCName:        vpcName,
 		SubnetworkName: subnetName,
-		TargetSize:     1, // setting it to minimal for testing
-		AutoscalerConfig: AutoscalerConfig{
-			MaxReplicas: 3,
-			MinReplicas: 1,
+		TargetSize:     schema.Int(1), // setting it to minimal for testing
+		AutoscalerConfig: &schema.MIGAutoscalerConfig{
+			MaxReplicas: schema.Int(3),
+			MinReplicas: schema.Int(1),
 		},
 	}
 
@@ -296,7 +296,7 @@ func createVPC(t *testing.T, projectID string, vpcName string) {
 			"--project=" + projectID,
 			"--network=" + vpcName,
 			"--region=" + region,
-			"--range=10.0.0.0/24",
+			"--range=" + ipam.Subnet(t, 24),
 		},
 	}
 	_, err = shell.RunCommandAndGetOutputE(t, cmd)
@@ -404,62 +404,43 @@ deleteVPC deletes the VPC, subnet, and Cloud NAT configuration after the test.
 */
 func deleteVPC(t *testing.T, projectID string, vpcName string) {
 	text := "compute"
-	time.Sleep(60 * time.Second) // Wait for resources to be in a deletable state
+	client := gcloud.New(projectID)
+	// Each delete is retried until the resources being torn down no longer use it.
+	deleteE := func(description string, del func() error) error {
+		_, err := wait.ForE(t, description+" to be deleted", wait.DefaultOptions, wait.Deletes(del))
+		return err
+	}
 
 	// Delete Cloud NAT mappings
 	natName := vpcName + "-nat" // Assuming NAT was created with this naming convention.
 	routerName := vpcName + "-router"
 
-	cmd := shell.Command{
-		Command: "gcloud",
-		Args: []string{
-			text, "routers", "nats", "delete", natName,
-			"--project=" + projectID,
-			"--region=" + region,
-			"--router=" + routerName,
-			"--quiet",
-		},
-	}
-	if _, err := shell.RunCommandAndGetOutputE(t, cmd); err != nil {
+	if err := deleteE("NAT "+natName, func() error {
+		_, err := client.Run(t, text, "routers", "nats", "delete", natName, "--region="+region, "--router="+routerName)
+		return err
+	}); err != nil {
 		t.Errorf("===Error %s Encountered while deleting NAT router: %s", err, natName)
 	}
 
 	// Delete the router itself after deleting the NAT
-	cmd = shell.Command{
-		Command: "gcloud",
-		Args: []string{
-			text, "routers", "delete", routerName,
-			"--project=" + projectID,
-			"--region=" + region,
-			"--quiet",
-		},
-	}
-	if _, err := shell.RunCommandAndGetOutputE(t, cmd); err != nil {
+	if err := deleteE("router "+routerName, func() error {
+		_, err := client.Run(t, text, "routers", "delete", routerName, "--region="+region)
+		return err
+	}); err != nil {
 		t.Errorf("===Error %s Encountered while deleting NAT router: %s", err, routerName)
 	}
 
 	// Delete Subnet
-	cmd = shell.Command{
-		Command: "gcloud",
-		Args: []string{
-			text, "networks", "subnets", "delete", subnetName,
-			"--project=" + projectID,
-			"--region=" + region,
-			"--quiet",
-		},
-	}
-	if _, err := shell.RunCommandAndGetOutputE(t, cmd); err != nil {
+	if err := deleteE("subnet "+subnetName, func() error {
+		return client.Subnets.Delete(t, region, subnetName)
+	}); err != nil {
 		t.Errorf("===Error %s Encountered while deleting subnet: %s", err, subnetName)
 	}
 
-	time.Sleep(150 * time.Second) // Wait for firewall deletion to complete
-
 	// Delete VPC
-	cmd = shell.Command{
-		Command: "gcloud",
-		Args:    []string{text, "networks", "delete", vpcName, "--project=" + projectID, "--quiet"},
-	}
-	if _, err := shell.RunCommandAndGetOutputE(t, cmd); err != nil {
+	if err := deleteE("VPC "+vpcName, func() error {
+		return client.Networks.Delete(t, vpcName)
+	}); err != nil {
 		t.Errorf("===Error %s Encountered while deleting VPC: %s", err, vpcName)
 	}
 }
@@ -20,7 +20,6 @@ import (
 	"archive/zip"
 	"fmt"
 	"io"
-	"math/rand"
 	"net/http"
 	"os"
 	"path/filepath"
@@ -28,6 +27,16 @@ import (
 	"testing"
 	"time"
 
+	"github.com/GoogleCloudPlatform/cloudnetworking-config-solutions/common_utils/fixture"
+	"github.com/GoogleCloudPlatform/cloudnetworking-config-solutions/common_utils/gcloud"
+	"github.com/GoogleCloudPlatform/cloudnetworking-config-solutions/common_utils/httpcheck"
+	"github.com/GoogleCloudPlatform/cloudnetworking-config-solutions/common_utils/ipam"
+	"github.com/GoogleCloudPlatform/cloudnetworking-config-solutions/common_utils/naming"
+	"github.com/GoogleCloudPlatform/cloudnetworking-config-solutions/common_utils/preflight"
+	"github.com/GoogleCloudPlatform/cloudnetworking-config-solutions/common_utils/profile"
+	"github.com/GoogleCloudPlatform/cloudnetworking-config-solutions/common_utils/retryable"
+	"github.com/GoogleCloudPlatform/cloudnetworking-config-solutions/common_utils/schema"
+	"github.com/GoogleCloudPlatform/cloudnetworking-config-solutions/common_utils/wait"
 	"github.com/gruntwork-io/terratest/modules/shell"
 	"github.com/gruntwork-io/terratest/modules/terraform"
 	"github.com/stretchr/testify/assert"
@@ -42,9 +51,10 @@ var (
 )
 
 var (
-	projectID           = os.Getenv("TF_VAR_project_id")
+	env, envErr         = profile.Current()
+	projectID           = env.Projects.Endpoint
 	instanceName        string
-	region              = "us-central1"
+	region              = env.Regions.Default
 	networkName         string
 	serviceAccountName  string
 	serviceAccountEmail string
@@ -52,99 +62,16 @@ var (
 	gcsSourceURL        string
 )
 
-type AppEngineConfig struct {
-	Project                 string `yaml:"project_id"`
-	Service                 string `yaml:"service"`
-	Runtime                 string `yaml:"runtime"`
-	FlexibleRuntimeSettings struct {
-		OperatingSystem string `yaml:"operating_system"`
-		RuntimeVersion  string `yaml:"runtime_version"`
-	} `yaml:"flexible_runtime_settings"`
-	InstanceClass string `yaml:"instance_class"`
-	Network       struct {
-		Name       string `yaml:"name"`
-		Subnetwork string `yaml:"subnetwork"`
-	} `yaml:"network"`
-	VersionID string `yaml:"version_id"`
-
-	AutomaticScaling struct {
-		CoolDownPeriod        string                 `yaml:"cool_down_period"`
-		MaxConcurrentRequests int                    `yaml:"max_concurrent_requests"`
-		MaxTotalInstances     int                    `yaml:"max_total_instances"`
-		MinTotalInstances     int                    `yaml:"min_total_instances"`
-		CPUUtilization        map[string]interface{} `yaml:"cpu_utilization"`
-	} `yaml:"automatic_scaling"`
-
-	Entrypoint struct {
-		Shell string `yaml:"shell"`
-	} `yaml:"entrypoint"`
-
-	Deployment *struct {
-		Zip *struct {
-			SourceURL string `yaml:"source_url"`
-		} `yaml:"zip,omitempty"`
-	} `yaml:"deployment,omitempty"`
-	LivenessCheck          map[string]interface{} `yaml:"liveness_check,omitempty"`
-	ReadinessCheck         map[string]interface{} `yaml:"readiness_check,omitempty"`
-	DeleteServiceOnDestroy bool                   `yaml:"delete_service_on_destroy,omitempty"`
-	EnvVariables           map[string]string      `yaml:"env_variables,omitempty"`
-	ServiceAccount         string                 `yaml:"service_account,omitempty"`
-	Labels                 map[string]string      `yaml:"labels,omitempty"`
-}
-
-func createServiceAccount(t *testing.T, projectID, saName, displayName string) (string, error) {
-	t.Logf("Attempting to create or verify service account: %s in project %s", saName, projectID)
-	expectedSaEmail := fmt.Sprintf("%s@%s.iam.gserviceaccount.com", saName, projectID)
-
-	cmd := shell.Command{
-		Command: "gcloud",
-		Args: []string{
-			"iam", "service-accounts", "create", saName,
-			"--project=" + projectID,
-			"--display-name=" + displayName,
-		},
-	}
-	rawOutput, err := shell.RunCommandAndGetOutputE(t, cmd)
-	if err != nil {
-		if strings.Contains(rawOutput, "already exists") || strings.Contains(err.Error(), "already exists") || strings.Contains(rawOutput, "Service account already exists") {
-			t.Logf("Service account %s (email: %s) already exists. Assuming it's usable.", saName, expectedSaEmail)
-			return expectedSaEmail, nil
-		}
-		return "", fmt.Errorf("failed to create service account %s: %w. Raw output: %s", saName, err, rawOutput)
-	}
-	t.Logf("Service account %s created/verified. Email: %s. Full gcloud output: %s", saName, expectedSaEmail, rawOutput)
-	return expectedSaEmail, nil
-}
-
-func addIAMPolicyBinding(t *testing.T, projectID, saEmail, role string) error {
-	t.Logf("Adding role %s to service account %s for project %s", role, saEmail, projectID)
-	cmd := shell.Command{
-		Command: "gcloud",
-		Args:    []string{"projects", "add-iam-policy-binding", projectID, "--member=serviceAccount:" + saEmail, "--role=" + role, "--format=json"},
-	}
-	_, err := shell.RunCommandAndGetOutputE(t, cmd)
-	if err != nil {
-		return fmt.Errorf("failed to add role %s to SA %s: %w", role, saEmail, err)
-	}
-	t.Logf("Role %s added to %s successfully.", role, saEmail)
-	return nil
-}
-
-func deleteServiceAccount(t *testing.T, projectID, saEmail string) {
-	if saEmail == "" {
-		return
-	}
-	t.Logf("--- Deleting service account: %s in project %s ---", saEmail, projectID)
-	cmd := shell.Command{
-		Command: "gcloud",
-		Args:    []string{"iam", "service-accounts", "delete", saEmail, "--project=" + projectID, "--quiet"},
-	}
-	_, err := shell.RunCommandAndGetOutputE(t, cmd)
-	if err != nil {
-		t.Errorf("Error deleting service account %s: %v. Manual cleanup might be required.", saEmail, err)
-	} else {
-		t.Logf("Service account %s deleted successfully or did not exist.", saEmail)
-	}
+// TestMain checks the APIs, roles and quota the suite needs before the
+// application is deployed.
+func TestMain(m *testing.M) {
+	os.Exit(preflight.Run(m, preflight.Requirements{
+		Profile: envErr,
+		Project: projectID,
+		APIs:    []string{"appengine.googleapis.com", "appengineflex.googleapis.com", "compute.googleapis.com", "iam.googleapis.com", "storage.googleapis.com", "cloudbuild.googleapis.com"},
+		Roles:   []string{"roles/appengine.appAdmin", "roles/compute.networkAdmin", "roles/iam.serviceAccountAdmin", "roles/resourcemanager.projectIamAdmin", "roles/storage.admin"},
+		Quotas:  []preflight.Quota{{Metric: "NETWORKS"}},
+	}))
 }
 
 func createGCSBucket(t *testing.T, projectID, bucketName string) error {
@@ -153,16 +80,22 @@ func createGCSBucket(t *testing.T, projectID, bucketName string) error {
 		Command: "gsutil",
 		Args:    []string{"mb", "-p", projectID, "-l", region, fmt.Sprintf("gs://%s", bucketName)},
 	}
-	_, err := shell.RunCommandAndGetOutputE(t, cmd)
-	if err != nil {
-		if strings.Contains(err.Error(), "SERVICE_ACCOUNT_NOT_SET_UP") || strings.Contains(err.Error(), "Service account has not been granted Legacy Bucket Writer role") {
-			t.Logf("Storage service account might not be set up yet for project %s. This can happen on new projects. Trying one more time after a delay or check project setup.", projectID)
-			time.Sleep(30 * time.Second)
-			_, err = shell.RunCommandAndGetOutputE(t, cmd)
-		}
-		if err != nil && !strings.Contains(err.Error(), "you already own it") {
-			return fmt.Errorf("failed to create GCS bucket gs://%s: %w", bucketName, err)
+	// The storage service account of a new project takes a while to be set up,
+	// retry until it is.
+	opts := wait.Options{Timeout: 2 * time.Minute, Interval: 10 * time.Second, MaxInterval: 30 * time.Second}
+	_, err := wait.ForE(t, "GCS bucket gs://"+bucketName+" to be created", opts, func() (bool, string, error) {
+		_, err := shell.RunCommandAndGetOutputE(t, cmd)
+		switch {
+		case err == nil || strings.Contains(err.Error(), "you already own it"):
+			return true, "created", nil
+		case strings.Contains(err.Error(), "SERVICE_ACCOUNT_NOT_SET_UP") || strings.Contains(err.Error(), "Service account has not been granted Legacy Bucket Writer role"):
+			return false, fmt.Sprintf("storage service account not set up yet for project %s", projectID), nil
+		default:
+			return false, "", err
 		}
+	})
+	if err != nil {
+		return fmt.Errorf("failed to create GCS bucket gs://%s: %w", bucketName, err)
 	}
 	t.Logf("GCS bucket gs://%s created/verified successfully.", bucketName)
 	return nil
@@ -172,24 +105,15 @@ func downloadFile(t *testing.T, url string, destDir string, fileName string) (st
 	filePath := filepath.Join(destDir, fileName)
 	t.Logf("Downloading %s to %s", url, filePath)
 
-	resp, err := http.Get(url)
-	if err != nil {
-		return "", err
-	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode != http.StatusOK {
-		return "", fmt.Errorf("failed to download %s: status %s", url, resp.Status)
-	}
-
-	out, err := os.Create(filePath)
+	resp, err := httpcheck.VerifyE(t, httpcheck.Check{
+		URL:         url,
+		Status:      []int{http.StatusOK},
+		MaxAttempts: 3,
+	})
 	if err != nil {
-		return "", err
+		return "", fmt.Errorf("failed to download %s: %w", url, err)
 	}
-	defer out.Close()
-
-	_, err = io.Copy(out, resp.Body)
-	if err != nil {
+	if err := os.WriteFile(filePath, resp.Body, 0644); err != nil {
 		return "", err
 	}
 	return filePath, nil
@@ -303,44 +227,30 @@ func deleteGCSBucket(t *testing.T, bucketName string) {
 		t.Logf("GCS bucket gs://%s and its contents deleted successfully or did not exist.", bucketName)
 	}
 }
-func getBaseAppEngineConfig(t *testing.T) AppEngineConfig {
-	return AppEngineConfig{
-		Project: projectID,
-		Runtime: "python",
-		FlexibleRuntimeSettings: struct {
-			OperatingSystem string `yaml:"operating_system"`
-			RuntimeVersion  string `yaml:"runtime_version"`
-		}{
+func getBaseAppEngineConfig(t *testing.T) schema.AppEngineFlexible {
+	return schema.AppEngineFlexible{
+		ProjectID: projectID,
+		Runtime:   "python",
+		FlexibleRuntimeSettings: &schema.AppEngineRuntimeSettings{
 			OperatingSystem: "ubuntu22",
 			RuntimeVersion:  "3.12",
 		},
-		Network: struct {
-			Name       string `yaml:"name"`
-			Subnetwork string `yaml:"subnetwork"`
-		}{
+		Network: &schema.AppEngineNetwork{
 			Name:       networkName,
 			Subnetwork: fmt.Sprintf("%s-subnet", networkName),
 		},
 		VersionID: "v1",
-		AutomaticScaling: struct {
-			CoolDownPeriod        string                 `yaml:"cool_down_period"`
-			MaxConcurrentRequests int                    `yaml:"max_concurrent_requests"`
-			MaxTotalInstances     int                    `yaml:"max_total_instances"`
-			MinTotalInstances     int                    `yaml:"min_total_instances"`
-			CPUUtilization        map[string]interface{} `yaml:"cpu_utilization"`
-		}{
-			CoolDownPeriod:        "120s",
-			MaxConcurrentRequests: 50,
-			MaxTotalInstances:     10,
-			MinTotalInstances:     2,
-			CPUUtilization: map[string]interface{}{
+		AutomaticScaling: map[string]interface{}{
+			"cool_down_period":        "120s",
+			"max_concurrent_requests": 50,
+			"max_total_instances":     10,
+			"min_total_instances":     2,
+			"cpu_utilization": map[string]interface{}{
 				"target_utilization":        0.6,
 				"aggregation_window_length": "60s",
 			},
 		},
-		Entrypoint: struct {
-			Shell string `yaml:"shell"`
-		}{
+		Entrypoint: &schema.AppEngineEntrypoint{
 			Shell: "pip3 install gunicorn flask && gunicorn -b :8080 main:app"},
 		LivenessCheck: map[string]interface{}{
 			"path":          "/",
@@ -350,23 +260,19 @@ func getBaseAppEngineConfig(t *testing.T) AppEngineConfig {
 			"path":              "/",
 			"app_start_timeout": "300s",
 		},
-		DeleteServiceOnDestroy: true,
+		DeleteServiceOnDestroy: schema.Bool(true),
+		Labels:                 naming.Labels(t),
 	}
 }
-func createConfigYAML(t *testing.T, currentSaEmail string, currentGcsSourceURL string) []AppEngineConfig {
+func createConfigYAML(t *testing.T, currentSaEmail string, currentGcsSourceURL string) []schema.AppEngineFlexible {
 	t.Log("Generating YAML configuration files aligned with the minimal working example, with dynamic SA and GCS URL...")
-	baseConfig := getBaseAppEngineConfig(t) // This now returns a minimal config
-	service1Config := baseConfig
+	// Each service starts from its own base config, as the maps of a config
+	// would otherwise be shared between the services.
+	service1Config := getBaseAppEngineConfig(t) // This now returns a minimal config
 	service1Config.Service = "test-service1"
 	service1Config.InstanceClass = "F4_1G"
-	service1Config.Deployment = &struct {
-		Zip *struct {
-			SourceURL string `yaml:"source_url"`
-		} `yaml:"zip,omitempty"`
-	}{
-		Zip: &struct {
-			SourceURL string `yaml:"source_url"`
-		}{
+	service1Config.Deployment = &schema.AppEngineDeployment{
+		Zip: &schema.AppEngineZip{
 			SourceURL: currentGcsSourceURL,
 		},
 	}
@@ -380,19 +286,13 @@ func createConfigYAML(t *testing.T, currentSaEmail string, currentGcsSourceURL s
 		"managed-by":  "terratest",
 		"specific-to": "service1",
 	}
-	service2Config := baseConfig
+	service2Config := getBaseAppEngineConfig(t)
 	service2Config.Service = "test-service2"
-	service2Config.AutomaticScaling.MinTotalInstances = 1
-	service2Config.AutomaticScaling.MaxTotalInstances = 3
-
-	service2Config.Deployment = &struct {
-		Zip *struct {
-			SourceURL string `yaml:"source_url"`
-		} `yaml:"zip,omitempty"`
-	}{
-		Zip: &struct {
-			SourceURL string `yaml:"source_url"`
-		}{
+	service2Config.AutomaticScaling["min_total_instances"] = 1
+	service2Config.AutomaticScaling["max_total_instances"] = 3
+
+	service2Config.Deployment = &schema.AppEngineDeployment{
+		Zip: &schema.AppEngineZip{
 			SourceURL: currentGcsSourceURL,
 		},
 	}
@@ -407,7 +307,7 @@ func createConfigYAML(t *testing.T, currentSaEmail string, currentGcsSourceURL s
 		"specific-to": "service2",
 		"feature-x":   "enabled", // Example of an additional label for service2
 	}
-	servicesToCreate := []AppEngineConfig{service1Config, service2Config}
+	servicesToCreate := []schema.AppEngineFlexible{service1Config, service2Config}
 	if err := os.RemoveAll(configFolderPath); err != nil && !os.IsNotExist(err) {
 		t.Fatalf("Failed to clean config directory %s: %v", configFolderPath, err)
 	}
@@ -468,7 +368,7 @@ func createVPC(t *testing.T, projectID string, networkName string) bool {
 			"--project=" + projectID,
 			"--network=" + networkName,
 			"--region=" + region,
-			"--range=10.128.0.0/20",
+			"--range=" + ipam.Subnet(t, 20),
 			"--enable-private-ip-google-access",
 			"--format=json",
 		},
@@ -509,7 +409,9 @@ func deleteVPC(t *testing.T, projectID string, networkName string) {
 		t.Logf("Subnet %s deleted successfully or did not exist.", subnetName)
 	}
 
-	time.Sleep(15 * time.Second)
+	if _, err := wait.ForE(t, "subnet "+subnetName+" to be deleted", wait.DefaultOptions, wait.SubnetDeleted(t, gcloud.New(projectID), region, subnetName)); err != nil {
+		t.Logf("Note: Subnet %s still present before deleting VPC Network: %v", subnetName, err)
+	}
 	t.Logf("Attempting to delete VPC Network: %s", networkName)
 	cmdVPC := shell.Command{
 		Command: "gcloud",
@@ -600,26 +502,14 @@ func deleteFirewallRules(t *testing.T, projectID string, instanceName string) {
 }
 
 func TestCreateAppEngine(t *testing.T) {
-	if projectID == "" {
-		t.Fatal("TF_VAR_project_id environment variable must be set")
-	}
-	instanceName = fmt.Sprintf("appeng-flex-test-%d", rand.Intn(10000))
+	profile.Require(t, "projects.endpoint")
+	instanceName = naming.Name(t, naming.Compute, "appeng-flex-test")
 	networkName = fmt.Sprintf("vpc-%s", instanceName)
-	serviceAccountName = fmt.Sprintf("sa-%s", instanceName)
-	gcsBucketName = fmt.Sprintf("bkt-%s-%s", strings.ToLower(projectID), instanceName)
-	gcsBucketName = strings.ReplaceAll(gcsBucketName, "_", "-")
-	if len(gcsBucketName) > 63 {
-		gcsBucketName = gcsBucketName[:63]
-	}
+	serviceAccountName = naming.Name(t, naming.ServiceAccount, "sa-appeng-flex")
+	gcsBucketName = naming.Name(t, naming.Bucket, fmt.Sprintf("bkt-%s", projectID))
 
 	t.Logf("Test Run Config: ProjectID=%s, InstanceSuffix=%s, Network=%s, SA=%s, Bucket=%s",
 		projectID, instanceName, networkName, serviceAccountName, gcsBucketName)
-	var err error
-	serviceAccountEmail, err = createServiceAccount(t, projectID, serviceAccountName, "AppEngine Test SA")
-	if err != nil {
-		t.Fatalf("Failed to create service account: %v", err)
-	}
-	defer deleteServiceAccount(t, projectID, serviceAccountEmail)
 	rolesToGrant := []string{
 		"roles/logging.logWriter",
 		"roles/artifactregistry.admin",
@@ -628,12 +518,18 @@ func TestCreateAppEngine(t *testing.T) {
 		"roles/storage.admin",
 		"roles/monitoring.metricWriter",
 	}
-	for _, role := range rolesToGrant {
-		err = addIAMPolicyBinding(t, projectID, serviceAccountEmail, role)
-		if err != nil {
-			t.Fatalf("Failed to add role %s to SA %s: %v", role, serviceAccountEmail, err)
-		}
+	// The application runs as the service account. The fixture revokes its
+	// roles and deletes it after the deferred terraform destroy.
+	client := gcloud.New(projectID)
+	sa, err := fixture.ImpersonatedServiceAccount(t, client, fixture.ServiceAccountOptions{
+		Name:         serviceAccountName,
+		DisplayName:  "AppEngine Test SA",
+		ProjectRoles: rolesToGrant,
+	})
+	if err != nil {
+		t.Fatalf("Failed to create service account: %v", err)
 	}
+	serviceAccountEmail = sa.Email
 	t.Logf("IAM roles granted to service account %s", serviceAccountEmail)
 
 	err = createGCSBucket(t, projectID, gcsBucketName)
@@ -674,7 +570,7 @@ func TestCreateAppEngine(t *testing.T) {
 	}
 
 	tfVars := map[string]interface{}{"config_folder_path": configFolderPath}
-	terraformOptions := terraform.WithDefaultRetryableErrors(t, &terraform.Options{
+	terraformOptions := retryable.With(t, &terraform.Options{
 		Vars: tfVars, TerraformDir: terraformDirectoryPath, Reconfigure: true, Lock: true, NoColor: true, SetVarsAfterVarFiles: true,
 	})
 	if !createVPC(t, projectID, networkName) {
@@ -682,7 +578,8 @@ func TestCreateAppEngine(t *testing.T) {
 	}
 	defer deleteVPC(t, projectID, networkName)
 	t.Log("VPC/Subnet created. Waiting for propagation...")
-	time.Sleep(60 * time.Second)
+	subnetName := fmt.Sprintf("%s-subnet", networkName)
+	wait.For(t, "subnet "+subnetName+" to be ready", wait.DefaultOptions, wait.SubnetReady(t, client, region, subnetName))
 
 	if !createFirewallRules(t, projectID, networkName, instanceName) {
 		t.Fatal("Firewall rule creation failed.")
@@ -702,8 +599,8 @@ func TestCreateAppEngine(t *testing.T) {
 	}
 	t.Logf("Found %d instance outputs for verification.", len(instanceServiceURLsMap))
 
-	maxRetries := 7 // Allow more retries as GAE flex can be slow
-	retryInterval := 1 * time.Minute
+	// GAE flex can be slow to start serving.
+	servingOptions := wait.Options{Timeout: 7 * time.Minute, Interval: time.Minute, MaxInterval: time.Minute}
 	verifiedServiceCount := 0
 
 	for instanceKey, serviceURLMapResult := range instanceServiceURLsMap {
@@ -725,9 +622,9 @@ func TestCreateAppEngine(t *testing.T) {
 		}
 		t.Logf("Verifying service: %s", serviceName)
 
-		var expectedConfig *AppEngineConfig
+		var expectedConfig *schema.AppEngineFlexible
 		for i := range generatedConfigs {
-			if generatedConfigs[i].Service == serviceName && generatedConfigs[i].Project == projectID {
+			if generatedConfigs[i].Service == serviceName && generatedConfigs[i].ProjectID == projectID {
 				expectedConfig = &generatedConfigs[i]
 				break
 			}
@@ -737,51 +634,40 @@ func TestCreateAppEngine(t *testing.T) {
 			continue
 		}
 
-		serviceIsReady := false
 		versionID := expectedConfig.VersionID
-		for i := 0; i < maxRetries; i++ {
-			t.Logf("Describing %s/%s (Attempt %d/%d)...", serviceName, versionID, i+1, maxRetries)
-			cmd := shell.Command{Command: "gcloud", Args: []string{"app", "versions", "describe", versionID, "--service", serviceName, "--verbosity=none","--project", projectID, "--format", "json"}}
-			gcloudOutput, errCmd := shell.RunCommandAndGetOutputE(t, cmd)
-			if errCmd != nil {
-				t.Logf("gcloud error for %s/%s: %v. Retrying...", serviceName, versionID, errCmd)
-				time.Sleep(retryInterval)
-				continue
+		var gcloudOutput string
+		wait.For(t, "App Engine version "+serviceName+"/"+versionID+" to be SERVING", servingOptions, wait.State(func() (string, error) {
+			output, err := client.Run(t, "app", "versions", "describe", versionID, "--service", serviceName, "--verbosity=none")
+			if err != nil {
+				return "", err
 			}
-			if gcloudOutput == "" {
-				t.Logf("Empty gcloud output for %s/%s. Retrying...", serviceName, versionID)
-				time.Sleep(retryInterval)
-				continue
-			}
-			t.Logf("gcloud Output : %s",gcloudOutput)
-			actualServiceInfo := gjson.Parse(gcloudOutput)
-			t.Logf("Actual Service Info : %s",actualServiceInfo)
-			status := gjson.Get(actualServiceInfo.String(),"servingStatus").String()
-			t.Logf("Status for %s/%s: %s", serviceName, versionID, status)
-			if status == "SERVING" {
-				t.Logf("Service %s/%s is SERVING. Performing assertions...", serviceName, versionID)
-				assert.Equal(t, expectedConfig.Runtime, actualServiceInfo.Get("runtime").String(), "Runtime mismatch")
-				assert.Equal(t, expectedConfig.InstanceClass, actualServiceInfo.Get("instanceClass").String(), "InstanceClass mismatch")
-				assert.Equal(t, expectedConfig.ServiceAccount, actualServiceInfo.Get("serviceAccount").String(), "ServiceAccount mismatch")
-				assert.Equal(t, expectedConfig.Network.Name, actualServiceInfo.Get("network.name").String(), "Network name mismatch")
-				actualSubnetworkPath := actualServiceInfo.Get("network.subnetworkName").String()
-				assert.Equal(t, expectedConfig.Network.Subnetwork, actualSubnetworkPath, "Subnetwork name mismatch")
-
-				if expectedConfig.Deployment != nil && expectedConfig.Deployment.Zip != nil {
-					t.Logf("Verifying deployment source URL for %s/%s against expected %s (actual field may vary).",
-						serviceName, versionID, expectedConfig.Deployment.Zip.SourceURL)
-				}
-
-				serviceIsReady = true
-				verifiedServiceCount++
-				break
-			}
-			t.Logf("Waiting %v for %s/%s...", retryInterval, serviceName, versionID)
-			time.Sleep(retryInterval)
-		}
-		if !serviceIsReady {
-			t.Fatalf("Service %s/%s did not reach SERVING after %d retries.", serviceName, versionID, maxRetries)
+			gcloudOutput = output
+			return gjson.Get(output, "servingStatus").String(), nil
+		}, "SERVING"))
+
+		t.Logf("gcloud Output : %s", gcloudOutput)
+		actualServiceInfo := gjson.Parse(gcloudOutput)
+		t.Logf("Service %s/%s is SERVING. Performing assertions...", serviceName, versionID)
+		assert.Equal(t, expectedConfig.Runtime, actualServiceInfo.Get("runtime").String(), "Runtime mismatch")
+		assert.Equal(t, expectedConfig.InstanceClass, actualServiceInfo.Get("instanceClass").String(), "InstanceClass mismatch")
+		assert.Equal(t, expectedConfig.ServiceAccount, actualServiceInfo.Get("serviceAccount").String(), "ServiceAccount mismatch")
+		assert.Equal(t, expectedConfig.Network.Name, actualServiceInfo.Get("network.name").String(), "Network name mismatch")
+		actualSubnetworkPath := actualServiceInfo.Get("network.subnetworkName").String()
+		assert.Equal(t, expectedConfig.Network.Subnetwork, actualSubnetworkPath, "Subnetwork name mismatch")
+
+		serviceURL := serviceURLMap[serviceName].String()
+		t.Logf("Verifying that %s serves the sample app.", serviceURL)
+		httpcheck.Verify(t, httpcheck.Check{
+			URL:   serviceURL,
+			Match: []httpcheck.Matcher{httpcheck.BodyContains("Hello World!")},
+			Retry: servingOptions,
+		})
+
+		if expectedConfig.Deployment != nil && expectedConfig.Deployment.Zip != nil {
+			t.Logf("Verifying deployment source URL for %s/%s against expected %s (actual field may vary).",
+				serviceName, versionID, expectedConfig.Deployment.Zip.SourceURL)
 		}
+		verifiedServiceCount++
 	}
 	assert.Equal(t, len(generatedConfigs), verifiedServiceCount, "Number of verified services did not match generated configs.")
 	t.Logf("Successfully verified %d service(s).", verifiedServiceCount)
@@ -18,8 +18,6 @@ package integrationtest
 
 import (
 	"fmt"
-	"io"
-	"net/http"
 	"os"
 	"path"
 	"path/filepath"
@@ -27,7 +25,14 @@ import (
 	"testing"
 	"time"
 
-	"github.com/gruntwork-io/terratest/modules/random"
+	"github.com/GoogleCloudPlatform/cloudnetworking-config-solutions/common_utils/fixture"
+	"github.com/GoogleCloudPlatform/cloudnetworking-config-solutions/common_utils/gcloud"
+	"github.com/GoogleCloudPlatform/cloudnetworking-config-solutions/common_utils/httpcheck"
+	"github.com/GoogleCloudPlatform/cloudnetworking-config-solutions/common_utils/naming"
+	"github.com/GoogleCloudPlatform/cloudnetworking-config-solutions/common_utils/preflight"
+	"github.com/GoogleCloudPlatform/cloudnetworking-config-solutions/common_utils/profile"
+	"github.com/GoogleCloudPlatform/cloudnetworking-config-solutions/common_utils/schema"
+	"github.com/GoogleCloudPlatform/cloudnetworking-config-solutions/common_utils/wait"
 	"github.com/gruntwork-io/terratest/modules/shell"
 	"github.com/gruntwork-io/terratest/modules/terraform"
 	"github.com/stretchr/testify/require"
@@ -38,7 +43,6 @@ import (
 // --- Test Configuration ---
 const (
 	// Default values - can be overridden by environment variables where applicable
-	defaultRegion = "us-central1"
 	// networkPrefix = "appeng-test-vpc"    // Prefix for created VPC Network
 	// subnetPrefix  = "appeng-test-subnet" // Prefix for created Subnet
 	// connectorPrefix              = "test-conn"          // Prefix for created VPC Connector
@@ -47,10 +51,6 @@ const (
 	httpCheckInterval = 30 * time.Second // Interval for HTTP check
 	// vpcCleanupWaitShort          = 30 * time.Second // Shorter wait in deleteVPC
 	// vpcCleanupWaitLong           = 60 * time.Second // Longer wait in deleteVPC
-	iamPropagationWait           = 30 * time.Second // Wait after adding IAM bindings
-	saCreationPropagationWait    = 15 * time.Second // Wait after SA creation before binding roles
-	appCreatePropagationWait     = 15 * time.Second // Wait after App Engine app creation
-	apiEnablementPropagationWait = 30 * time.Second // Wait after enabling APIs
 	// vpcAccessCreateWaitTime      = 120 * time.Second
 
 	// Constants for gcloud-created VPC Access Connector (used with --subnet)
@@ -67,16 +67,18 @@ const (
 )
 
 var (
-	uniqueID               = strings.ToLower(random.UniqueId())
 	projectRoot, _         = filepath.Abs("../../../../../../")
 	terraformDirectoryPath = filepath.Join(projectRoot, "06-consumer/Serverless/AppEngine/Standard")
 	configFolderPath       = filepath.Join(projectRoot, "test/integration/consumer/Serverless/AppEngine/Standard/config")
 	service1               = "service1"
 	service2               = "service2"
-	projectID              = os.Getenv("TF_VAR_project_id")
-	versionID1             = fmt.Sprintf("v1-%s", uniqueID)
-	versionID2             = fmt.Sprintf("v1-%s", uniqueID)
+	env, envErr            = profile.Current()
+	projectID              = env.Projects.Endpoint
+	defaultRegion          = env.Regions.Default
 	sampleAppGcsBucket     = getEnv("TF_VAR_test_gcs_bucket", fmt.Sprintf("%s-tf-test-bucket", projectID))
+	// The names below are derived from the run and the test by setNames.
+	versionID1 string
+	versionID2 string
 
 	testServiceAccountRoles = []string{
 		"roles/compute.networkUser",
@@ -97,91 +99,17 @@ var (
 		"roles/artifactregistry.writer",
 		"roles/artifactregistry.reader",
 	}
-
-	requiredGcpApis = []string{
-		"serviceusage.googleapis.com", // API to enable other APIs
-		"appengine.googleapis.com",
-		"compute.googleapis.com",
-		"iam.googleapis.com",
-		"cloudresourcemanager.googleapis.com",
-		"storage.googleapis.com",
-		// "vpcaccess.googleapis.com",
-		"cloudbuild.googleapis.com",
-		"artifactregistry.googleapis.com",
-	}
 )
 
-// --- Struct Definitions ---
-type AppEngineConfig struct {
-	ProjectID        string                  `yaml:"project_id"`
-	Service          string                  `yaml:"service"`
-	VersionID        string                  `yaml:"version_id"`
-	Runtime          string                  `yaml:"runtime"`
-	Deployment       *DeploymentConfig       `yaml:"deployment,omitempty"`
-	Entrypoint       *EntrypointConfig       `yaml:"entrypoint,omitempty"`
-	AutomaticScaling *AutomaticScalingConfig `yaml:"automatic_scaling,omitempty"`
-	Handlers         HandlerConfig           `yaml:"handlers,omitempty"`
-	// VPCAccessConnector         *VPCAccessConnectorConfig        `yaml:"vpc_access_connector,omitempty"`
-	// VPCAccessConnectorDetails *VPCAccessConnectorDetailsConfig `yaml:"vpc_connector_details,omitempty"`
-	AppEngineApplication *AppEngineAppConfig `yaml:"app_engine_application,omitempty"`
-	// CreateVPCConnector         bool                             `yaml:"create_vpc_connector,omitempty"`
-	CreateSplitTraffic         bool                  `yaml:"create_split_traffic,omitempty"`
-	CreateAppEngineApplication bool                  `yaml:"create_app_engine_application,omitempty"`
-	CreateDispatchRules        bool                  `yaml:"create_dispatch_rules,omitempty"`
-	CreateNetworkSettings      bool                  `yaml:"create_network_settings,omitempty"`
-	DeleteServiceOnDestroy     bool                  `yaml:"delete_service_on_destroy,omitempty"`
-	CreateDomainMappings       bool                  `yaml:"create_domain_mappings,omitempty"`
-	DomainMappings             []DomainMappingConfig `yaml:"domain_mappings,omitempty"`
-	CreateFirewallRules        bool                  `yaml:"create_firewall_rules,omitempty"`
-	FirewallRules              []FirewallRuleConfig  `yaml:"firewall_rules,omitempty"`
-	CreateAppVersion           bool                  `yaml:"create_app_version,omitempty"`
-	EnvVariables               map[string]string     `yaml:"env_variables,omitempty"`
-	Labels                     map[string]string     `yaml:"labels,omitempty"`
-}
-type DeploymentConfig struct {
-	Files *FilesConfig `yaml:"files,omitempty"`
-}
-type FilesConfig struct {
-	Name      string `yaml:"name"`
-	SourceURL string `yaml:"source_url"`
-}
-type EntrypointConfig struct {
-	Shell string `yaml:"shell"`
-}
-type AutomaticScalingConfig struct {
-	MaxConcurrentRequests int `yaml:"max_concurrent_requests,omitempty"`
-	MinIdleInstances      int `yaml:"min_idle_instances,omitempty"`
-	MaxIdleInstances      int `yaml:"max_idle_instances,omitempty"`
-}
-type HandlerConfig []struct {
-	URLRegex string        `yaml:"url_regex"`
-	Script   *ScriptConfig `yaml:"script,omitempty"`
-}
-type ScriptConfig struct {
-	ScriptPath string `yaml:"script_path"`
-}
-type VPCAccessConnectorConfig struct {
-	Name string `yaml:"name"`
-}
-
-//	type VPCAccessConnectorDetailsConfig struct {
-//		Name          string `yaml:"name"`
-//		SubnetName    string `yaml:"subnet_name"`
-//		MachineType   string `yaml:"machine_type"`
-//		MinInstances  int    `yaml:"min_instances"`
-//		MaxInstances  int    `yaml:"max_instances"`
-//		HostProjectID string `yaml:"host_project_id"`
-//		Region        string `yaml:"region"`
-//	}
-type AppEngineAppConfig struct {
-	LocationID string `yaml:"location_id"`
-}
-type DomainMappingConfig struct {
-	DomainName string `yaml:"domain_name"`
-}
-type FirewallRuleConfig struct {
-	SourceRange string `yaml:"source_range"`
-	Action      string `yaml:"action"`
+// TestMain checks the APIs and roles the suite needs before the application is
+// deployed.
+func TestMain(m *testing.M) {
+	os.Exit(preflight.Run(m, preflight.Requirements{
+		Profile: envErr,
+		Project: projectID,
+		APIs:    []string{"appengine.googleapis.com", "compute.googleapis.com", "iam.googleapis.com", "cloudresourcemanager.googleapis.com", "storage.googleapis.com", "cloudbuild.googleapis.com", "artifactregistry.googleapis.com"},
+		Roles:   []string{"roles/appengine.appAdmin", "roles/iam.serviceAccountAdmin", "roles/resourcemanager.projectIamAdmin", "roles/storage.admin"},
+	}))
 }
 
 // --- Helper Functions ---
@@ -193,62 +121,54 @@ func getEnv(key, fallback string) string {
 	return fallback
 }
 
-func min(a, b int) int {
-	if a < b {
-		return a
-	}
-	return b
+/*
+setNames is a helper function which derives the versions of the services from
+the run and the test.
+*/
+func setNames(t *testing.T) {
+	versionID1 = naming.Name(t, naming.Compute, "v1")
+	versionID2 = versionID1
 }
 
 func TestAppEngineStandardIntegration(t *testing.T) {
+	profile.Require(t, "projects.endpoint")
 	t.Parallel()
+	setNames(t)
 
-	if projectID == "" {
-		t.Fatal("TF_VAR_project_id environment variable must be set")
-	}
-
-	// Get current gcloud user who will be granted token creator role
-	currentPrincipal := getCurrentGcloudUser(t)
+	client := gcloud.New(projectID)
 	projectNumber := getProjectNumber(t, projectID)
 
-	serviceAccountID := fmt.Sprintf("ae-test-sa-%s", uniqueID[:min(len(uniqueID), 18)])
-	serviceAccountDisplayName := fmt.Sprintf("App Engine Test SA (%s)", uniqueID)
-	// testNetworkName := fmt.Sprintf("%s-%s", networkPrefix, uniqueID)
-	// testSubnetworkName := fmt.Sprintf("%s-%s", subnetPrefix, uniqueID)
-	// testConnectorName := fmt.Sprintf("%s-%s", connectorPrefix, uniqueID)
+	serviceAccountID := naming.Name(t, naming.ServiceAccount, "ae-test-sa")
+	serviceAccountDisplayName := fmt.Sprintf("App Engine Test SA (%s)", naming.RunID())
+	// testNetworkName := naming.Name(t, naming.Compute, networkPrefix)
+	// testSubnetworkName := naming.Name(t, naming.Compute, subnetPrefix)
+	// testConnectorName := naming.Name(t, naming.Compute, connectorPrefix)
 	testConfigFolderPath := configFolderPath
-	testGcsObjectPathPrefix := fmt.Sprintf("app-test-%s", uniqueID)
+	testGcsObjectPathPrefix := naming.Name(t, naming.Compute, "app-test")
 
+	// Terraform runs as the test Service Account. The fixture grants the current
+	// principal Token Creator on it and revokes every binding after terraform
+	// destroy, before the account is deleted.
 	t.Logf("Creating test Service Account: %s", serviceAccountID)
-	serviceAccountEmail := createServiceAccount(t, projectID, serviceAccountID, serviceAccountDisplayName)
-	defer deleteServiceAccount(t, projectID, serviceAccountEmail)
-
-	// Grant the current principal the Service Account Token Creator role on the new SA <<< ADD THIS BLOCK
-	t.Logf("Granting Service Account Token Creator role to principal '%s' on new SA '%s'", currentPrincipal, serviceAccountEmail)
-	addTokenCreatorRoleToPrincipalOnServiceAccount(t, projectID, serviceAccountEmail, currentPrincipal)
-	defer removeTokenCreatorRoleFromPrincipalOnServiceAccount(t, projectID, serviceAccountEmail, currentPrincipal) // Ensure cleanup
-
-	t.Logf("Waiting %v for SA '%s' to propagate before adding IAM bindings...", saCreationPropagationWait, serviceAccountEmail)
-	time.Sleep(saCreationPropagationWait)
+	sa, err := fixture.ImpersonatedServiceAccount(t, client, fixture.ServiceAccountOptions{
+		Name:         serviceAccountID,
+		DisplayName:  serviceAccountDisplayName,
+		ProjectRoles: testServiceAccountRoles,
+	})
+	require.NoError(t, err)
 
-	t.Logf("Adding IAM roles to test Service Account %s...", serviceAccountEmail)
-	addProjectIamBindings(t, projectID, serviceAccountEmail, testServiceAccountRoles)
+	// The default Compute Service Account is shared by the tests of the project,
+	// so the roles it needs to build the application are left in place.
 	defaultComputeServiceAccountEmail := fmt.Sprintf("%s-compute@developer.gserviceaccount.com", projectNumber)
-
 	t.Logf("Adding IAM roles to default Compute Service Account %s...", defaultComputeServiceAccountEmail)
-	addProjectIamBindings(t, projectID, defaultComputeServiceAccountEmail, defaultComputeServiceAccountEmailRoles)
-
-	defer removeProjectIamBindings(t, projectID, serviceAccountEmail, testServiceAccountRoles)
-
-	t.Logf("Waiting %v for IAM binding propagation before Terraform apply...", iamPropagationWait)
-	time.Sleep(iamPropagationWait)
+	for _, role := range defaultComputeServiceAccountEmailRoles {
+		if err := client.Projects.AddIAMPolicyBinding(t, gcloud.Member(defaultComputeServiceAccountEmail), role); err != nil {
+			t.Logf("Failed to add IAM binding '%s' for '%s' to project '%s': %s", role, defaultComputeServiceAccountEmail, projectID, err)
+		}
+	}
 
 	// Enable required GCP APIs
 	t.Logf("Enabling required GCP APIs for project '%s'...", projectID)
-	enableGcpApis(t, projectID, requiredGcpApis)
-	t.Logf("Waiting %v for API enablement to propagate...", apiEnablementPropagationWait)
-	time.Sleep(apiEnablementPropagationWait)
-
 	// Ensure App Engine application exists or create it
 	t.Logf("Ensuring App Engine application exists in project '%s' for region '%s'...", projectID, defaultRegion)
 	ensureAppEngineApplicationExists(t, projectID, defaultRegion)
@@ -279,15 +199,18 @@ func TestAppEngineStandardIntegration(t *testing.T) {
 	// defer deleteVPCConnectorGcloud(t, projectID, testConnectorName, defaultRegion)
 
 	appYamlDisplayUrl := fmt.Sprintf("https://storage.googleapis.com/%s/%s", sampleAppGcsBucket, appYamlGcsPath)
-	defer os.RemoveAll(testConfigFolderPath)
-	// Pass gcloudCreatedConnectorFullName; testSubnetName and uniqueID for createConfigYAML are for other potential uses or logging.
+	// terraform destroy reads the configuration, so it is removed afterwards.
+	config := fixture.Register(t, fixture.Resource{Kind: "config folder", ID: testConfigFolderPath}, func() error {
+		return os.RemoveAll(testConfigFolderPath)
+	})
+	// Pass gcloudCreatedConnectorFullName and testSubnetName to createConfigYAML for other potential uses or logging.
 	t.Logf("Creating YAML Config.")
-	createConfigYAML(t, testConfigFolderPath, uniqueID, appYamlDisplayUrl) //,testSubnetworkName, gcloudCreatedConnectorFullName)
+	createConfigYAML(t, testConfigFolderPath, appYamlDisplayUrl) //,testSubnetworkName, gcloudCreatedConnectorFullName)
 
 	tfVars := map[string]interface{}{
 		"config_folder_path": testConfigFolderPath,
 	}
-	terraformOptions := terraform.WithDefaultRetryableErrors(t, &terraform.Options{
+	terraformOptions := sa.TerraformOptions(t, &terraform.Options{
 		TerraformDir: terraformDirectoryPath,
 		Vars:         tfVars,
 		Reconfigure:  true,
@@ -295,9 +218,9 @@ func TestAppEngineStandardIntegration(t *testing.T) {
 		NoColor:      true,
 	})
 
-	defer terraform.Destroy(t, terraformOptions)
+	fixture.Terraform(t, terraformOptions, sa.Handle, config)
 	t.Logf("====== Running terraform init & apply... ======")
-	_, err := terraform.InitAndApplyE(t, terraformOptions)
+	_, err = terraform.InitAndApplyE(t, terraformOptions)
 	if err != nil {
 		t.Errorf("Terraform init/apply failed : %s", err)
 	} else {
@@ -372,83 +295,24 @@ func TestAppEngineStandardIntegration(t *testing.T) {
 		t.Log("Runtime ID for Instance 2 verified successfully.")
 	}
 
-	t.Log("====== Test AppEngine Standard Integration Completed. ====")
-}
-
-// --- Helper Function Implementations ---
-
-func createServiceAccount(t *testing.T, projectID string, accountID string, displayName string) string {
-	t.Helper()
-	t.Logf("Attempting to create service account '%s' in project '%s' with display name '%s'...", accountID, projectID, displayName)
-	var extractedEmail string
-	cmd := shell.Command{
-		Command: "gcloud",
-		Args: []string{
-			"iam", "service-accounts", "create", accountID,
-			"--project=" + projectID,
-			"--display-name=" + displayName,
-			"--format=value(email)",
-			"--quiet",
-		},
-	}
-
-	_, err := shell.RunCommandAndGetOutputE(t, cmd)
-	if err != nil {
-		t.Errorf("Error creating service account %s : Error %s", accountID, err)
-	} else {
-		extractedEmail = fmt.Sprintf("%s@%s.iam.gserviceaccount.com", accountID, projectID)
-	}
-	return extractedEmail
-}
+	t.Log(" ========= Verify Serving ========= ")
 
-func deleteServiceAccount(t *testing.T, projectID string, email string) {
-	t.Helper()
-	t.Logf("Deleting service account '%s'...", email)
-	cmd := shell.Command{
-		Command: "gcloud",
-		Args:    []string{"iam", "service-accounts", "delete", email, "--project=" + projectID, "--quiet"},
-	}
-	output, err := shell.RunCommandAndGetOutputE(t, cmd)
+	appURL := fmt.Sprintf("https://%s-dot-%s-dot-%s.%s.r.appspot.com", versionID1, service1, projectID, getRegionCode(t, defaultRegion))
+	_, err = httpcheck.VerifyE(t, httpcheck.Check{
+		URL:   appURL,
+		Match: []httpcheck.Matcher{httpcheck.BodyContains("Hello World!")},
+		Retry: wait.Options{Timeout: httpCheckRetries * httpCheckInterval, Interval: httpCheckInterval, MaxInterval: httpCheckInterval},
+	})
 	if err != nil {
-		t.Logf("Error deleting service account %s: %v. Output:\n%s", email, err, output)
+		t.Errorf("App Engine version %s is not serving: %v", versionID1, err)
 	} else {
-		t.Logf("Service Account %s deleted.", email)
+		t.Log("Version 1 serves the sample app.")
 	}
-}
 
-func addProjectIamBindings(t *testing.T, projectID string, serviceAccountEmail string, roles []string) {
-	t.Helper()
-	member := fmt.Sprintf("serviceAccount:%s", serviceAccountEmail)
-	for _, role := range roles {
-		t.Logf("Adding role %s to %s for project %s", role, member, projectID)
-		cmd := shell.Command{
-			Command: "gcloud",
-			Args:    []string{"projects", "add-iam-policy-binding", projectID, "--member=" + member, "--role=" + role, "--condition=None", "--format=none"},
-		}
-		output, err := shell.RunCommandAndGetOutputE(t, cmd)
-		if err != nil {
-			t.Logf("Failed to add IAM binding '%s' for member '%s' to project '%s'. Output:\n%s, Error: %s", role, member, projectID, output, err)
-		}
-	}
+	t.Log("====== Test AppEngine Standard Integration Completed. ====")
 }
 
-func removeProjectIamBindings(t *testing.T, projectID string, serviceAccountEmail string, roles []string) {
-	t.Helper()
-	member := fmt.Sprintf("serviceAccount:%s", serviceAccountEmail)
-	for _, role := range roles {
-		t.Logf("Removing role %s from %s for project %s", role, member, projectID)
-		cmd := shell.Command{
-			Command: "gcloud",
-			Args:    []string{"projects", "remove-iam-policy-binding", projectID, "--member=" + member, "--role=" + role, "--condition=None", "--quiet", "--format=none"},
-		}
-		output, err := shell.RunCommandAndGetOutputE(t, cmd)
-		if err != nil {
-			t.Logf("Error removing IAM binding '%s' for member '%s' from project '%s': %v. Output:\n%s", role, member, projectID, err, output)
-		} else {
-			t.Logf("Removed role %s from %s", role, member)
-		}
-	}
-}
+// --- Helper Function Implementations ---
 
 func createGcsBucket(t *testing.T, projectID string, bucketName string, location string) {
 	t.Helper()
@@ -653,42 +517,43 @@ func uploadGcsObject(t *testing.T, projectID string, bucketName string, objectPa
 // }
 
 // Updated createConfigYAML helper function
-func createConfigYAML(t *testing.T, outputDir string, uniqueID string, deploymentFileURL string) { //,testSubnetName string, gcloudCreatedConnectorFullName string) {
+func createConfigYAML(t *testing.T, outputDir string, deploymentFileURL string) { //,testSubnetName string, gcloudCreatedConnectorFullName string) {
 	t.Helper()
 	testProjectID := projectID
 
-	configs := map[string]AppEngineConfig{
+	deployment := &schema.AppEngineDeployment{Files: &schema.AppEngineFiles{Name: sampleAppGcsObjectName, SourceURL: deploymentFileURL}}
+	configs := map[string]schema.AppEngineStandard{
 		"instance1.yaml": {
 			ProjectID:        testProjectID,
 			Service:          service1,
 			VersionID:        versionID1,
 			Runtime:          sampleAppRuntime,
-			Deployment:       &DeploymentConfig{Files: &FilesConfig{Name: sampleAppGcsObjectName, SourceURL: deploymentFileURL}},
-			Entrypoint:       &EntrypointConfig{Shell: sampleAppEntrypoint},
-			AutomaticScaling: &AutomaticScalingConfig{MaxConcurrentRequests: 50, MinIdleInstances: 1, MaxIdleInstances: 3},
-			Handlers:         HandlerConfig{{URLRegex: "/.*", Script: &ScriptConfig{ScriptPath: "auto"}}},
-			// VPCAccessConnector:        &VPCAccessConnectorConfig{Name: gcloudCreatedConnectorFullName},
+			Deployment:       deployment,
+			Entrypoint:       &schema.AppEngineEntrypoint{Shell: sampleAppEntrypoint},
+			AutomaticScaling: map[string]interface{}{"max_concurrent_requests": 50, "min_idle_instances": 1, "max_idle_instances": 3},
+			Handlers:         []schema.AppEngineHandler{{URLRegex: "/.*", Script: &schema.AppEngineScript{ScriptPath: "auto"}}},
+			// VPCAccessConnector:        &schema.AppEngineVPCAccessConnector{Name: gcloudCreatedConnectorFullName},
 			// CreateVPCConnector:        false, // Terraform is not creating the connector
-			// VPCAccessConnectorDetails: nil,   // Ensure not set
-			CreateAppVersion:       false,
-			DeleteServiceOnDestroy: true,
-			AppEngineApplication:   &AppEngineAppConfig{LocationID: defaultRegion},
+			// VPCConnectorDetails:       nil,   // Ensure not set
+			DeleteServiceOnDestroy: schema.Bool(true),
+			AppEngineApplication:   &schema.AppEngineApplication{LocationID: defaultRegion},
+			Labels:                 naming.Labels(t),
 		},
 		"instance2.yaml": {
 			ProjectID:        testProjectID,
 			Service:          service2,
 			VersionID:        versionID2,
 			Runtime:          sampleAppRuntime,
-			Deployment:       &DeploymentConfig{Files: &FilesConfig{Name: sampleAppGcsObjectName, SourceURL: deploymentFileURL}},
-			Entrypoint:       &EntrypointConfig{Shell: sampleAppEntrypoint},
-			AutomaticScaling: &AutomaticScalingConfig{MaxConcurrentRequests: 60, MinIdleInstances: 0, MaxIdleInstances: 2},
-			Handlers:         HandlerConfig{{URLRegex: "/.*", Script: &ScriptConfig{ScriptPath: "auto"}}},
-			// VPCAccessConnector:        &VPCAccessConnectorConfig{Name: gcloudCreatedConnectorFullName},
+			Deployment:       deployment,
+			Entrypoint:       &schema.AppEngineEntrypoint{Shell: sampleAppEntrypoint},
+			AutomaticScaling: map[string]interface{}{"max_concurrent_requests": 60, "max_idle_instances": 2},
+			Handlers:         []schema.AppEngineHandler{{URLRegex: "/.*", Script: &schema.AppEngineScript{ScriptPath: "auto"}}},
+			// VPCAccessConnector:        &schema.AppEngineVPCAccessConnector{Name: gcloudCreatedConnectorFullName},
 			// CreateVPCConnector:        false, // Terraform is not creating the connector
-			// VPCAccessConnectorDetails: nil,   // Ensure not set
-			CreateAppVersion:       false,
-			DeleteServiceOnDestroy: true,
-			AppEngineApplication:   &AppEngineAppConfig{LocationID: defaultRegion},
+			// VPCConnectorDetails:       nil,   // Ensure not set
+			DeleteServiceOnDestroy: schema.Bool(true),
+			AppEngineApplication:   &schema.AppEngineApplication{LocationID: defaultRegion},
+			Labels:                 naming.Labels(t),
 		},
 	}
 	if err := os.MkdirAll(outputDir, 0755); err != nil {
@@ -727,44 +592,6 @@ if __name__ == '__main__':
 	return appYamlContent, mainPyContent
 }
 
-func httpGetWithRetry(t *testing.T, url string, maxRetries int, timeBetweenRetries time.Duration) (int, string) {
-	var lastErr string
-	for i := 0; i < maxRetries; i++ {
-		t.Logf("HTTP GET Attempt %d/%d for URL: %s", i+1, maxRetries, url)
-		resp, err := http.Get(url)
-		if err != nil {
-			lastErr = fmt.Sprintf("HTTP GET failed (attempt %d/%d): %s", i+1, maxRetries, err)
-			t.Logf("%s", lastErr)
-			if i < maxRetries-1 {
-				time.Sleep(timeBetweenRetries)
-			}
-			continue
-		}
-		bodyBytes, readErr := io.ReadAll(resp.Body)
-		resp.Body.Close()
-		if readErr != nil {
-			lastErr = fmt.Sprintf("Failed to read response body (attempt %d/%d) for URL %s: %s", i+1, maxRetries, url, readErr)
-			t.Logf("%s", lastErr)
-			if i < maxRetries-1 {
-				time.Sleep(timeBetweenRetries)
-			}
-			continue
-		}
-		bodyString := string(bodyBytes)
-		if resp.StatusCode >= 200 && resp.StatusCode < 400 {
-			t.Logf("HTTP GET successful on attempt %d/%d for URL %s. Status: %d", i+1, maxRetries, url, resp.StatusCode)
-			return resp.StatusCode, bodyString
-		}
-		lastErr = fmt.Sprintf("Attempt %d/%d for URL %s: Got status code %d. Body: %s", i+1, maxRetries, url, resp.StatusCode, bodyString)
-		t.Logf("%s", lastErr)
-		if i < maxRetries-1 {
-			time.Sleep(timeBetweenRetries)
-		}
-	}
-	t.Logf("HTTP GET failed for URL %s after %d attempts. Last error: %v", url, maxRetries, lastErr)
-	return 0, ""
-}
-
 func getRegionCode(t *testing.T, region string) string {
 	codes := map[string]string{"us-central": "uc", "us-central1": "uc", "us-east1": "ue", "us-east4": "us-e4", "us-west1": "uw", "us-west2": "uw2", "us-west3": "uw3", "us-west4": "uw4", "europe-central2": "eur-c2", "europe-west1": "ew", "europe-west2": "ew2", "europe-west3": "ew3", "europe-west6": "ew6", "asia-east1": "as-e1", "asia-east2": "as-e2", "asia-northeast1": "an-e1", "asia-northeast2": "an-e2", "asia-northeast3": "an-e3", "asia-south1": "as-s1", "asia-south2": "as-s2", "asia-southeast1": "as-se1", "asia-southeast2": "as-se2", "australia-southeast1": "au-se1", "australia-southeast2": "au-se2", "southamerica-east1": "sa-e1", "southamerica-west1": "sa-w1"}
 	code, ok := codes[region]
@@ -823,8 +650,14 @@ func ensureAppEngineApplicationExists(t *testing.T, projectID string, region str
 
 		t.Logf("Successfully created App Engine application in project '%s', region '%s'. Output:\n%s", projectID, region, createOutput)
 
-		t.Logf("Waiting %v for App Engine application creation to propagate...", appCreatePropagationWait)
-		time.Sleep(appCreatePropagationWait)
+		client := gcloud.New(projectID)
+		wait.For(t, "App Engine application to be serving", wait.DefaultOptions, wait.State(func() (string, error) {
+			output, err := client.Run(t, "app", "describe")
+			if err != nil {
+				return "", err
+			}
+			return gjson.Get(output, "servingStatus").String(), nil
+		}, "SERVING"))
 	} else {
 		// If it's a different error from `gcloud app describe`, it's unexpected.
 		if err != nil {
@@ -834,122 +667,6 @@ func ensureAppEngineApplicationExists(t *testing.T, projectID string, region str
 	}
 }
 
-// enableGcpApis enables a list of specified GCP APIs for the project.
-func enableGcpApis(t *testing.T, projectID string, apis []string) {
-	t.Helper()
-	t.Logf("Attempting to enable %d GCP APIs for project '%s': %v", len(apis), projectID, apis)
-
-	for _, api := range apis {
-		t.Logf("Enabling API '%s' for project '%s'...", api, projectID)
-		cmd := shell.Command{
-			Command: "gcloud",
-			Args: []string{
-				"services", "enable", api,
-				"--project=" + projectID,
-			},
-		}
-		// The `gcloud services enable` command is idempotent.
-		// If the service is already enabled, it will report success without making changes.
-		output, err := shell.RunCommandAndGetOutputE(t, cmd)
-		if err != nil {
-			t.Logf("Failed to enable API '%s' for project '%s'. Output:\n%s. Error: %s", api, projectID, output, err)
-		}
-		// This operation MUST succeed for the test to reliably proceed.
-
-		// Log output for visibility, it often confirms if it was already enabled or just enabled.
-		t.Logf("Processed API '%s'. gcloud output:\n%s", api, output)
-	}
-	t.Logf("All %d required GCP APIs have been processed for enablement in project '%s'.", len(apis), projectID)
-}
-
-// addTokenCreatorRoleToPrincipalOnServiceAccount grants the Token Creator role to a principal on a specific service account.
-func addTokenCreatorRoleToPrincipalOnServiceAccount(t *testing.T, projectID string, serviceAccountEmail string, principalEmail string) {
-	t.Helper()
-	var memberIdentifier string
-	// Differentiate between a user account and a service account for the member format.
-	if strings.Contains(principalEmail, ".iam.gserviceaccount.com") {
-		memberIdentifier = fmt.Sprintf("serviceAccount:%s", principalEmail)
-	} else {
-		memberIdentifier = fmt.Sprintf("user:%s", principalEmail)
-	}
-
-	role := "roles/iam.serviceAccountTokenCreator"
-	t.Logf("Adding role '%s' to principal '%s' on service account '%s' in project '%s'", role, memberIdentifier, serviceAccountEmail, projectID)
-
-	cmd := shell.Command{
-		Command: "gcloud",
-		Args: []string{
-			"iam", "service-accounts", "add-iam-policy-binding", serviceAccountEmail,
-			"--project=" + projectID,
-			"--member=" + memberIdentifier,
-			"--role=" + role,
-			"--quiet",
-			"--format=none",
-		},
-	}
-	output, err := shell.RunCommandAndGetOutputE(t, cmd)
-	if err != nil {
-		t.Logf("Failed to add role '%s' to principal '%s' on service account '%s'. Output:\n%s. Error: %s", role, memberIdentifier, serviceAccountEmail, output, err)
-	}
-
-	t.Logf("Successfully added role '%s' to principal '%s' on service account '%s'", role, memberIdentifier, serviceAccountEmail)
-}
-
-// removeTokenCreatorRoleFromPrincipalOnServiceAccount removes the Token Creator role from a principal on a specific service account.
-func removeTokenCreatorRoleFromPrincipalOnServiceAccount(t *testing.T, projectID string, serviceAccountEmail string, principalEmail string) {
-	t.Helper()
-	var memberIdentifier string
-	if strings.Contains(principalEmail, ".iam.gserviceaccount.com") {
-		memberIdentifier = fmt.Sprintf("serviceAccount:%s", principalEmail)
-	} else {
-		memberIdentifier = fmt.Sprintf("user:%s", principalEmail)
-	}
-
-	role := "roles/iam.serviceAccountTokenCreator"
-	t.Logf("Removing role '%s' from principal '%s' on service account '%s' in project '%s'", role, memberIdentifier, serviceAccountEmail, projectID)
-
-	cmd := shell.Command{
-		Command: "gcloud",
-		Args: []string{
-			"iam", "service-accounts", "remove-iam-policy-binding", serviceAccountEmail,
-			"--project=" + projectID,
-			"--member=" + memberIdentifier,
-			"--role=" + role,
-			"--quiet",
-			"--format=none",
-		},
-	}
-	output, err := shell.RunCommandAndGetOutputE(t, cmd)
-	if err != nil {
-		// Log non-critical errors for removal, as the binding might already be gone or test is tearing down.
-		if !strings.Contains(output, "NOT_FOUND") && !strings.Contains(output, "PolicyBindingNotFound") && !strings.Contains(err.Error(), "NOT_FOUND") {
-			t.Logf("Error removing IAM binding '%s' for member '%s' from SA '%s': %v. Output:\n%s", role, memberIdentifier, serviceAccountEmail, err, output)
-		} else {
-			t.Logf("IAM binding '%s' for member '%s' on SA '%s' already gone or not found.", role, memberIdentifier, serviceAccountEmail)
-		}
-	} else {
-		t.Logf("Successfully removed role '%s' from principal '%s' on service account '%s'", role, memberIdentifier, serviceAccountEmail)
-	}
-}
-
-// getCurrentGcloudUser retrieves the currently authenticated gcloud account email.
-func getCurrentGcloudUser(t *testing.T) string {
-	t.Helper()
-	cmd := shell.Command{
-		Command: "gcloud",
-		Args:    []string{"auth", "list", "--filter=status:ACTIVE", "--format=value(account)"}, //gcloud auth list --filter=status:ACTIVE --format="value(account)"
-	}
-	output, err := shell.RunCommandAndGetOutputE(t, cmd)
-	// If this fails, the test setup cannot proceed with impersonation correctly.
-	if err != nil {
-		t.Logf("Failed to get current gcloud user. Ensure gcloud is authenticated. Error: %v. Output: %s", err, output)
-	}
-	currentUser := strings.TrimSpace(output)
-	require.NotEmpty(t, currentUser, "gcloud config get-value account returned empty string. Ensure gcloud is authenticated.")
-	t.Logf("Current gcloud principal identified as: %s", currentUser)
-	return currentUser
-}
-
 // getProjectNumber gets the Project Number for the Endpoint configuration
 func getProjectNumber(t *testing.T, projectID string) string {
 	cmd := shell.Command{
@@ -18,47 +18,52 @@ package integrationtest
 
 import (
 	"fmt"
+	"github.com/GoogleCloudPlatform/cloudnetworking-config-solutions/common_utils/gcloud"
+	"github.com/GoogleCloudPlatform/cloudnetworking-config-solutions/common_utils/naming"
+	"github.com/GoogleCloudPlatform/cloudnetworking-config-solutions/common_utils/preflight"
+	"github.com/GoogleCloudPlatform/cloudnetworking-config-solutions/common_utils/profile"
+	"github.com/GoogleCloudPlatform/cloudnetworking-config-solutions/common_utils/retryable"
+	"github.com/GoogleCloudPlatform/cloudnetworking-config-solutions/common_utils/schema"
+	"github.com/GoogleCloudPlatform/cloudnetworking-config-solutions/common_utils/wait"
 	"github.com/gruntwork-io/terratest/modules/terraform"
 	"github.com/tidwall/gjson"
 	"gopkg.in/yaml.v2"
-	"math/rand"
 
 	"os"
 	"testing"
-	"time"
 )
 
 const (
 	terraformDirectoryPath = "../../../../../../06-consumer/Serverless/CloudRun/Job"
-	region                 = "us-central1"
 	configFolderPath       = "../../../../test/integration/consumer/Serverless/CloudRun/Job/config"
 	image                  = "us-docker.pkg.dev/cloudrun/container/job"
 )
 
 var (
-	projectID = os.Getenv("TF_VAR_project_id")
-	jobName   = fmt.Sprintf("test-%d", rand.Int())
-	tfVars    = map[string]any{
+	env, envErr = profile.Current()
+	projectID   = env.Projects.Endpoint
+	region      = env.Regions.Default
+	tfVars      = map[string]any{
 		"config_folder_path": configFolderPath,
 	}
+	// The name below is derived from the run and the test by setNames.
+	jobName string
 )
 
-type ContainerNameStruct struct {
-	Image string `yaml:"image"`
-}
-
-type ContainersStruct struct {
-	ContainerName ContainerNameStruct `yaml:"container-name"`
-}
-
-type CloudRunJobStruct struct {
-	ProjectID  string           `yaml:"project_id"`
-	Region     string           `yaml:"region"`
-	Name       string           `yaml:"name"`
-	Containers ContainersStruct `yaml:"containers"`
+// TestMain checks the APIs and roles the suite needs before the job is
+// deployed.
+func TestMain(m *testing.M) {
+	os.Exit(preflight.Run(m, preflight.Requirements{
+		Profile: envErr,
+		Project: projectID,
+		APIs:    []string{"run.googleapis.com"},
+		Roles:   []string{"roles/run.admin", "roles/iam.serviceAccountUser"},
+	}))
 }
 
 func TestCreateCloudRunJob(t *testing.T) {
+	profile.Require(t, "projects.endpoint")
+	setNames(t)
 	createConfigYAML(t)
 	var (
 		tfVars = map[string]any{
@@ -66,7 +71,7 @@ func TestCreateCloudRunJob(t *testing.T) {
 		}
 	)
 
-	terraformOptions := terraform.WithDefaultRetryableErrors(t, &terraform.Options{
+	terraformOptions := retryable.With(t, &terraform.Options{
 		// Set the path to the Terraform code that will be tested.
 		Vars:                 tfVars,
 		TerraformDir:         terraformDirectoryPath,
@@ -82,8 +87,15 @@ func TestCreateCloudRunJob(t *testing.T) {
 	// Run "terraform init" and "terraform apply". Fail the test if there are any errors.
 	terraform.InitAndApply(t, terraformOptions)
 
-	// Wait for 60 seconds to let resource acheive stable state.
-	time.Sleep(60 * time.Second)
+	// Wait for the job to report Ready before verifying its details.
+	client := gcloud.New(projectID)
+	wait.For(t, "Cloud Run job "+jobName+" to be ready", wait.DefaultOptions, wait.State(func() (string, error) {
+		output, err := client.Run(t, "run", "jobs", "describe", jobName, "--region="+region)
+		if err != nil {
+			return "", err
+		}
+		return gjson.Get(output, `status.conditions.#(type=="Ready").status`).String(), nil
+	}, "True"))
 
 	// Run `terraform output` to get the values of output variables and check they have the expected values.
 	cloudRunJobOutputValue := terraform.OutputJson(t, terraformOptions, "cloud_run_job_details")
@@ -115,25 +127,28 @@ func TestCreateCloudRunJob(t *testing.T) {
 	}
 }
 
+/*
+setNames is a helper function which derives the name of the job from the run
+and the test.
+*/
+func setNames(t *testing.T) {
+	jobName = naming.Name(t, naming.Compute, "test")
+}
+
 /*
 createConfigYAML is a helper function which creates the configuration YAML file.
 */
 func createConfigYAML(t *testing.T) {
 	t.Log("========= YAML File =========")
 
-	containerNameList := ContainerNameStruct{
-		Image: image,
-	}
-
-	containersStructList := ContainersStruct{
-		ContainerName: containerNameList,
-	}
-
-	instance1 := CloudRunJobStruct{
-		Name:       jobName,
-		ProjectID:  projectID,
-		Region:     region,
-		Containers: containersStructList,
+	instance1 := schema.CloudRun{
+		Name:      jobName,
+		ProjectID: projectID,
+		Region:    region,
+		Labels:    naming.Labels(t),
+		Containers: map[string]schema.CloudRunContainer{
+			"container-name": {Image: image},
+		},
 	}
 	yamlData, err := yaml.Marshal(&instance1)
 	if err != nil {
@@ -18,47 +18,56 @@ package integrationtest
 
 import (
 	"fmt"
+	"github.com/GoogleCloudPlatform/cloudnetworking-config-solutions/common_utils/gcloud"
+	"github.com/GoogleCloudPlatform/cloudnetworking-config-solutions/common_utils/httpcheck"
+	"github.com/GoogleCloudPlatform/cloudnetworking-config-solutions/common_utils/naming"
+	"github.com/GoogleCloudPlatform/cloudnetworking-config-solutions/common_utils/preflight"
+	"github.com/GoogleCloudPlatform/cloudnetworking-config-solutions/common_utils/profile"
+	"github.com/GoogleCloudPlatform/cloudnetworking-config-solutions/common_utils/retryable"
+	"github.com/GoogleCloudPlatform/cloudnetworking-config-solutions/common_utils/schema"
+	"github.com/GoogleCloudPlatform/cloudnetworking-config-solutions/common_utils/wait"
 	"github.com/gruntwork-io/terratest/modules/terraform"
 	"github.com/tidwall/gjson"
 	"gopkg.in/yaml.v2"
-	"math/rand"
+	"net/http"
 
 	"os"
 	"testing"
-	"time"
 )
 
 const (
 	terraformDirectoryPath = "../../../../../../06-consumer/Serverless/CloudRun/Service"
-	region                 = "us-central1"
 	configFolderPath       = "../../../../test/integration/consumer/Serverless/CloudRun/Service/config"
 	image                  = "us-docker.pkg.dev/cloudrun/container/hello"
+	// serviceGreeting is part of the page the hello image serves.
+	serviceGreeting = "It's running!"
 )
 
 var (
-	projectID   = os.Getenv("TF_VAR_project_id")
-	serviceName = fmt.Sprintf("test-%d", rand.Int())
+	env, envErr = profile.Current()
+	projectID   = env.Projects.Endpoint
+	region      = env.Regions.Default
 	tfVars      = map[string]any{
 		"config_folder_path": configFolderPath,
 	}
+	// The name below is derived from the run and the test by setNames.
+	serviceName string
 )
 
-type ContainerNameStruct struct {
-	Image string `yaml:"image"`
-}
-
-type ContainersStruct struct {
-	ContainerName ContainerNameStruct `yaml:"container-name"`
-}
-
-type CloudRunServiceStruct struct {
-	ProjectID  string           `yaml:"project_id"`
-	Region     string           `yaml:"region"`
-	Name       string           `yaml:"name"`
-	Containers ContainersStruct `yaml:"containers"`
+// TestMain checks the APIs and roles the suite needs before the service is
+// deployed.
+func TestMain(m *testing.M) {
+	os.Exit(preflight.Run(m, preflight.Requirements{
+		Profile: envErr,
+		Project: projectID,
+		APIs:    []string{"run.googleapis.com"},
+		Roles:   []string{"roles/run.admin", "roles/iam.serviceAccountUser"},
+	}))
 }
 
 func TestCreateCloudRunService(t *testing.T) {
+	profile.Require(t, "projects.endpoint")
+	setNames(t)
 	createConfigYAML(t)
 	var (
 		tfVars = map[string]any{
@@ -66,7 +75,7 @@ func TestCreateCloudRunService(t *testing.T) {
 		}
 	)
 
-	terraformOptions := terraform.WithDefaultRetryableErrors(t, &terraform.Options{
+	terraformOptions := retryable.With(t, &terraform.Options{
 		// Set the path to the Terraform code that will be tested.
 		Vars:                 tfVars,
 		TerraformDir:         terraformDirectoryPath,
@@ -82,8 +91,30 @@ func TestCreateCloudRunService(t *testing.T) {
 	// Run "terraform init" and "terraform apply". Fail the test if there are any errors.
 	terraform.InitAndApply(t, terraformOptions)
 
-	// Wait for 60 seconds to let resource acheive stable state.
-	time.Sleep(60 * time.Second)
+	// Wait for the service to report Ready before verifying its details.
+	client := gcloud.New(projectID)
+	var serviceURL string
+	wait.For(t, "Cloud Run service "+serviceName+" to be ready", wait.DefaultOptions, wait.State(func() (string, error) {
+		output, err := client.Run(t, "run", "services", "describe", serviceName, "--region="+region)
+		if err != nil {
+			return "", err
+		}
+		serviceURL = gjson.Get(output, "status.url").String()
+		return gjson.Get(output, `status.conditions.#(type=="Ready").status`).String(), nil
+	}, "True"))
+
+	t.Log(" ========= Verify Serving ========= ")
+	// The service admits no unauthenticated calls, so the request carries an
+	// identity token of the caller.
+	token, err := client.IdentityToken(t)
+	if err != nil {
+		t.Fatalf("Unable to get an identity token to call the service: %v", err)
+	}
+	httpcheck.Verify(t, httpcheck.Check{
+		URL:    serviceURL,
+		Header: http.Header{"Authorization": {"Bearer " + token}},
+		Match:  []httpcheck.Matcher{httpcheck.BodyContains(serviceGreeting)},
+	})
 
 	// Run `terraform output` to get the values of output variables and check they have the expected values.
 	cloudRunServiceOutputValue := terraform.OutputJson(t, terraformOptions, "cloud_run_service_details")
@@ -115,24 +146,28 @@ func TestCreateCloudRunService(t *testing.T) {
 	}
 }
 
+/*
+setNames is a helper function which derives the name of the service from the run
+and the test.
+*/
+func setNames(t *testing.T) {
+	serviceName = naming.Name(t, naming.Compute, "test")
+}
+
 /*
 createConfigYAML is a helper function which creates the configuration YAML file.
 */
 func createConfigYAML(t *testing.T) {
 	t.Log("========= YAML File =========")
 
-	containerNameList := ContainerNameStruct{
-		Image: image,
-	}
-
-	containersStructList := ContainersStruct{
-		ContainerName: containerNameList,
-	}
-	instance1 := CloudRunServiceStruct{
-		Name:       serviceName,
-		ProjectID:  projectID,
-		Region:     region,
-		Containers: containersStructList,
+	instance1 := schema.CloudRun{
+		Name:      serviceName,
+		ProjectID: projectID,
+		Region:    region,
+		Labels:    naming.Labels(t),
+		Containers: map[string]schema.CloudRunContainer{
+			"container-name": {Image: image},
+		},
 	}
 	yamlData, err := yaml.Marshal(&instance1)
 	if err != nil {
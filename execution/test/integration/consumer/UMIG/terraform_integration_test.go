@@ -18,13 +18,20 @@ package integrationtest
 
 import (
 	"fmt"
-	"math/rand"
 	"os"
 	"path/filepath"
 	"strings"
 	"testing"
-	"time"
 
+	"github.com/GoogleCloudPlatform/cloudnetworking-config-solutions/common_utils/gcloud"
+	"github.com/GoogleCloudPlatform/cloudnetworking-config-solutions/common_utils/ipam"
+	"github.com/GoogleCloudPlatform/cloudnetworking-config-solutions/common_utils/location"
+	"github.com/GoogleCloudPlatform/cloudnetworking-config-solutions/common_utils/naming"
+	"github.com/GoogleCloudPlatform/cloudnetworking-config-solutions/common_utils/preflight"
+	"github.com/GoogleCloudPlatform/cloudnetworking-config-solutions/common_utils/profile"
+	"github.com/GoogleCloudPlatform/cloudnetworking-config-solutions/common_utils/retryable"
+	"github.com/GoogleCloudPlatform/cloudnetworking-config-solutions/common_utils/schema"
+	"github.com/GoogleCloudPlatform/cloudnetworking-config-solutions/common_utils/wait"
 	"github.com/gruntwork-io/terratest/modules/shell"
 	"github.com/gruntwork-io/terratest/modules/terraform"
 	"github.com/stretchr/testify/assert"
@@ -40,55 +47,52 @@ var (
 )
 
 var (
-	projectID     = os.Getenv("TF_VAR_project_id")
-	umigName      = fmt.Sprintf("umig-%d", rand.Intn(10000)) // Renamed for UMIG
-	region        = "us-central1"
-	zone          = "us-central1-a"
-	vpcName       = "testing-net-umig"
-	subnetName    = "testing-subnet-umig"
-	instanceNames = []string{"umig-instance-1", "umig-instance-2"}
+	env, envErr = profile.Current()
+	projectID   = env.Projects.Endpoint
+	region      = env.Regions.Default
+	zone        = env.Zones.Default
+	// The names below are derived from the run and the test by setNames.
+	umigName      string
+	vpcName       string
+	subnetName    string
+	instanceNames []string
 )
 
-// NamedPortConfig struct to match the YAML structure
-type NamedPortConfig struct {
-	Name string `yaml:"name"`
-	Port int    `yaml:"port"`
+// TestMain checks the APIs, roles and quota the suite needs before the
+// instance groups are created.
+func TestMain(m *testing.M) {
+	os.Exit(preflight.Run(m, preflight.Requirements{
+		Profile: envErr,
+		Project: projectID,
+		APIs:    []string{"compute.googleapis.com"},
+		Roles:   []string{"roles/compute.instanceAdmin.v1", "roles/compute.networkAdmin", "roles/iam.serviceAccountUser"},
+		Quotas:  []preflight.Quota{{Metric: "NETWORKS"}, {Metric: "INSTANCES", Region: region, Need: 2}},
+	}))
 }
 
 var (
 	yaml_file_name = "instance.yaml"
 )
 
-// UMIGConfig struct to match the new YAML structure
-type UMIGConfig struct {
-	Name        string            `yaml:"name"`
-	ProjectID   string            `yaml:"project_id"`
-	Zone        string            `yaml:"zone"`
-	Network     string            `yaml:"network"`
-	Description string            `yaml:"description"`
-	Instances   []string          `yaml:"instances"`
-	NamedPorts  []NamedPortConfig `yaml:"named_ports"`
-}
-
 /*
 TestUMIGs tests the creation and configuration of Unmanaged Instance Groups (UMIGs) in Google Cloud Platform
 using Terraform. It verifies that the resources are correctly provisioned, checks the status of VM instances,
 and ensures that configurations such as instance group names, zones, and named ports match expected values.
 */
 func TestUMIGs(t *testing.T) {
+	profile.Require(t, "projects.endpoint")
+	setNames(t)
 	// Ensure config folder exists
 	if err := os.MkdirAll(configFolderPath, 0755); err != nil {
 		t.Fatalf("Failed to create config directory at %s: %v", configFolderPath, err)
 	}
 
-	createConfigYAML(t) // Create the UMIG configuration YAML
-
 	tfVars := map[string]interface{}{
 		"config_folder_path": configFolderPath,
 	}
 
 	// Terraform Options
-	terraformOptions := terraform.WithDefaultRetryableErrors(t, &terraform.Options{
+	terraformOptions := retryable.With(t, &terraform.Options{
 		Vars:         tfVars,
 		TerraformDir: terraformDirectoryPath,
 		Reconfigure:  true,
@@ -97,23 +101,39 @@ func TestUMIGs(t *testing.T) {
 	})
 
 	// --- Pre-Terraform resource creation ---
+	client := gcloud.New(projectID)
 	createVPC(t, projectID, vpcName)
-	time.Sleep(10 * time.Second) // Give VPC time to provision
-	createSubnet(t, projectID, vpcName, subnetName, region, "10.0.0.0/24")
-	time.Sleep(10 * time.Second) // Give subnet time to provision
-	time.Sleep(10 * time.Second) // Give firewall rule time to provision
-	createVMInstances(t, projectID, zone, vpcName, subnetName, instanceNames)
-	time.Sleep(30 * time.Second) // Give VM instances time to provision and become ready
+	wait.For(t, "VPC "+vpcName+" to exist", wait.DefaultOptions, wait.NetworkExists(t, client, vpcName))
+	createSubnet(t, projectID, vpcName, subnetName, region, ipam.Subnet(t, 24))
+	wait.For(t, "subnet "+subnetName+" to be ready", wait.DefaultOptions, wait.SubnetReady(t, client, region, subnetName))
 
 	// --- Deferred cleanup ---
 	defer deleteVPC(t, projectID, vpcName)
 	defer deleteSubnet(t, projectID, subnetName, region)
-	defer deleteVMInstances(t, projectID, zone, instanceNames)
+	defer func() { deleteVMInstances(t, projectID, zone, instanceNames) }()
 	defer terraform.Destroy(t, terraformOptions)
 	defer deleteUMIGConfigYAML(t)
 
-	// Apply Terraform
-	terraform.InitAndApply(t, terraformOptions)
+	// Create the instances and apply Terraform, moving to a fallback zone
+	// when the zone is out of capacity.
+	loc, err := location.Try(t, location.Zones(env), func(loc location.Location) error {
+		if loc.Zone != zone {
+			deleteVMInstances(t, projectID, zone, instanceNames)
+			zone = loc.Zone
+		}
+		createConfigYAML(t) // Create the UMIG configuration YAML
+		if err := createVMInstances(t, projectID, zone, vpcName, subnetName, instanceNames); err != nil {
+			return err
+		}
+		for _, instanceName := range instanceNames {
+			wait.For(t, "instance "+instanceName+" to be running", wait.DefaultOptions, wait.InstanceRunning(t, client, zone, instanceName))
+		}
+		_, err := terraform.InitAndApplyE(t, terraformOptions)
+		return err
+	})
+	if err != nil {
+		t.Fatalf("Applying the UMIG configuration in %s failed: %v", loc, err)
+	}
 
 	// Retrieve outputs
 	umigSelfLinksOutput := terraform.OutputJson(t, terraformOptions, "umig_self_links")
@@ -139,53 +159,27 @@ func TestUMIGs(t *testing.T) {
 
 	// Verify the created UMIG
 	yamlFilePath := filepath.Join(configFolderPath, yaml_file_name)
-	yamlFile, err := os.ReadFile(yamlFilePath)
-	if err != nil {
+	var expectedUMIG schema.UMIG
+	if err := schema.Load(yamlFilePath, &expectedUMIG); err != nil {
 		t.Fatalf("Error reading YAML file at %s: %s", yamlFilePath, err)
 	}
 
-	var expectedUMIG UMIGConfig
-	err = yaml.Unmarshal(yamlFile, &expectedUMIG)
-	if err != nil {
-		t.Fatalf("Error unmarshaling YAML from %s: %s", yamlFilePath, err)
-	}
-
 	t.Logf("Verifying UMIG: %s", expectedUMIG.Name)
 
-	maxRetries := 5
-	retryInterval := 15 * time.Second
 	var actualUMIGInfo gjson.Result
-	var lastGcloudOutput string
-	succeeded := false
-
-	for i := 0; i < maxRetries; i++ {
-		gcloudRawOutput := shell.RunCommandAndGetOutput(t, shell.Command{
-			Command: "gcloud",
-			Args:    []string{"compute", "instance-groups", "unmanaged", "describe", expectedUMIG.Name, "--zone", expectedUMIG.Zone, "--project=" + projectID, "--format=json"},
-		})
-		lastGcloudOutput = gcloudRawOutput // Store the last output for debugging
-
-		jsonStartIndex := strings.Index(gcloudRawOutput, "{")
-		gcloudJSONOutput := ""
-		if jsonStartIndex != -1 {
-			gcloudJSONOutput = gcloudRawOutput[jsonStartIndex:]
+	wait.For(t, "UMIG "+expectedUMIG.Name+" to be complete", wait.DefaultOptions, wait.Check(func() error {
+		gcloudOutput, err := client.Run(t, "compute", "instance-groups", "unmanaged", "describe", expectedUMIG.Name, "--zone", expectedUMIG.Zone)
+		if err != nil {
+			return err
 		}
-
-		actualUMIGInfo = gjson.Parse(gcloudJSONOutput)
+		actualUMIGInfo = gjson.Parse(gcloudOutput)
 
 		// Check if the required fields are present in the gcloud output
 		if actualUMIGInfo.Get("name").Exists() && actualUMIGInfo.Get("network").Exists() && actualUMIGInfo.Get("zone").Exists() && actualUMIGInfo.Get("namedPorts").Exists() {
-			t.Logf("gcloud describe returned complete data after %d retry/retries.", i+1)
-			succeeded = true
-			break
+			return nil
 		}
-		t.Logf("gcloud describe output for %s not yet complete. Retrying in %v...", expectedUMIG.Name, retryInterval)
-		time.Sleep(retryInterval)
-	}
-
-	if !succeeded {
-		t.Fatalf("Failed to get complete UMIG info for '%s' after %d retries. Last gcloud output:\n%s", expectedUMIG.Name, maxRetries, lastGcloudOutput)
-	}
+		return fmt.Errorf("gcloud describe output not yet complete:\n%s", gcloudOutput)
+	}))
 
 	t.Log("========= Verify Unmanaged Instance Group =========")
 
@@ -237,18 +231,30 @@ func TestUMIGs(t *testing.T) {
 	t.Log("Confirmed instances in UMIG output match.")
 }
 
+// setNames derives the names of the UMIG, of its instances and of their network
+// from the run and the test.
+func setNames(t *testing.T) {
+	umigName = naming.Name(t, naming.Compute, "umig")
+	vpcName = naming.Name(t, naming.Compute, "testing-net-umig")
+	subnetName = naming.Name(t, naming.Compute, "testing-subnet-umig")
+	instanceNames = []string{
+		naming.Name(t, naming.Compute, "umig-instance-1"),
+		naming.Name(t, naming.Compute, "umig-instance-2"),
+	}
+}
+
 // createConfigYAML creates the configuration YAML file for a UMIG instance.
 func createConfigYAML(t *testing.T) {
 	t.Log("========= Creating UMIG YAML File =========")
 
-	umigInstance := UMIGConfig{
+	umigInstance := schema.UMIG{
 		Name:        umigName,
 		ProjectID:   projectID,
 		Zone:        zone,
 		Network:     vpcName,
 		Description: "Integration test unmanaged instance group.",
 		Instances:   instanceNames, // Use the pre-defined instance names
-		NamedPorts: []NamedPortConfig{
+		NamedPorts: []schema.NamedPort{
 			{Name: "http", Port: 80},
 			{Name: "https", Port: 443},
 		},
@@ -320,8 +326,10 @@ func createSubnet(t *testing.T, projectID, vpcName, subnetName, region, ipRange
 
 /*
 createVMInstances creates a set of compute instances to be used with the UMIG.
+It returns the error of the first instance that could not be created, so that
+the caller may try another zone.
 */
-func createVMInstances(t *testing.T, projectID, zone, network, subnet string, instances []string) {
+func createVMInstances(t *testing.T, projectID, zone, network, subnet string, instances []string) error {
 	t.Logf("Creating VM instances in project '%s', zone '%s'...", projectID, zone)
 	for _, instName := range instances {
 		t.Logf("Creating instance: %s", instName)
@@ -343,11 +351,12 @@ func createVMInstances(t *testing.T, projectID, zone, network, subnet string, in
 		}
 		_, err := shell.RunCommandAndGetOutputE(t, cmd)
 		if err != nil {
-			t.Fatalf("Error creating VM instance '%s': %s", instName, err)
+			return fmt.Errorf("creating VM instance '%s': %w", instName, err)
 		}
 		t.Logf("VM instance '%s' created.", instName)
 	}
 	t.Logf("All VM instances created.")
+	return nil
 }
 
 /*
@@ -401,14 +410,13 @@ func deleteSubnet(t *testing.T, projectID, subnetName, region string) {
 deleteVPC deletes the VPC after the test.
 */
 func deleteVPC(t *testing.T, projectID string, vpcName string) {
-	// Give some time for resources to detach from VPC before deletion
-	time.Sleep(30 * time.Second)
+	// Resources may take some time to detach from the VPC, retry the deletion until they have.
 	t.Logf("Deleting VPC '%s'...", vpcName)
-	cmd := shell.Command{
-		Command: "gcloud",
-		Args:    []string{"compute", "networks", "delete", vpcName, "--project=" + projectID, "--quiet"},
-	}
-	if _, err := shell.RunCommandAndGetOutputE(t, cmd); err != nil {
+	client := gcloud.New(projectID)
+	_, err := wait.ForE(t, "VPC "+vpcName+" to be deleted", wait.DefaultOptions, wait.Deletes(func() error {
+		return client.Networks.Delete(t, vpcName)
+	}))
+	if err != nil {
 		t.Logf("Error deleting VPC '%s': %s (may already be deleted or still have dependencies)", vpcName, err)
 	} else {
 		t.Logf("Successfully deleted VPC '%s'.", vpcName)
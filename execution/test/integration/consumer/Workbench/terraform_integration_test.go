@@ -17,7 +17,6 @@ package integrationtest
 
 import (
 	"fmt"
-	"math/rand"
 	"net"
 	"os"
 	"path/filepath"
@@ -25,6 +24,17 @@ import (
 	"testing"
 	"time"
 
+	"github.com/GoogleCloudPlatform/cloudnetworking-config-solutions/common_utils/fixture"
+	"github.com/GoogleCloudPlatform/cloudnetworking-config-solutions/common_utils/gcloud"
+	"github.com/GoogleCloudPlatform/cloudnetworking-config-solutions/common_utils/ipam"
+	"github.com/GoogleCloudPlatform/cloudnetworking-config-solutions/common_utils/location"
+	"github.com/GoogleCloudPlatform/cloudnetworking-config-solutions/common_utils/naming"
+	"github.com/GoogleCloudPlatform/cloudnetworking-config-solutions/common_utils/preflight"
+	"github.com/GoogleCloudPlatform/cloudnetworking-config-solutions/common_utils/probe"
+	"github.com/GoogleCloudPlatform/cloudnetworking-config-solutions/common_utils/profile"
+	"github.com/GoogleCloudPlatform/cloudnetworking-config-solutions/common_utils/retryable"
+	"github.com/GoogleCloudPlatform/cloudnetworking-config-solutions/common_utils/schema"
+	"github.com/GoogleCloudPlatform/cloudnetworking-config-solutions/common_utils/wait"
 	"github.com/gruntwork-io/terratest/modules/shell"
 	"github.com/gruntwork-io/terratest/modules/terraform"
 	"github.com/stretchr/testify/assert"
@@ -40,27 +50,28 @@ var (
 )
 
 var (
-	projectID            = os.Getenv("TF_VAR_project_id") // Ensure TF_VAR_project_id is set in your environment
-	region               = "us-central1"
-	zone                 = "us-central1-a"
-	vpcName              = fmt.Sprintf("testing-net-workbench-%d", rand.Intn(100000000))
-	subnetName           = fmt.Sprintf("testing-subnet-workbench-%d", rand.Intn(100000000))
-	workbenchName        = fmt.Sprintf("workbench-%d", rand.Intn(100000000))
-	yaml_file_name       = "instance.yaml"
-	connectivityTestName = fmt.Sprintf("workbench-bq-test-%d", rand.Intn(100000000))
+	env, envErr    = profile.Current()
+	projectID      = env.Projects.Endpoint
+	region         = env.Regions.Default
+	zone           = env.Zones.Default
+	yaml_file_name = "instance.yaml"
+	// The names below are derived from the run and the test by setNames.
+	vpcName              string
+	subnetName           string
+	workbenchName        string
+	connectivityTestName string
 )
 
-// WorkbenchConfig struct to match the YAML structure
-type WorkbenchConfig struct {
-	Name      string `yaml:"name"`
-	ProjectID string `yaml:"project_id"`
-	Location  string `yaml:"location"`
-	GceSetup  struct {
-		NetworkInterfaces []struct {
-			Network string `yaml:"network"`
-			Subnet  string `yaml:"subnet"`
-		} `yaml:"network_interfaces"`
-	} `yaml:"gce_setup"`
+// TestMain checks the APIs, roles and quota the suite needs before the network
+// and the instance are created.
+func TestMain(m *testing.M) {
+	os.Exit(preflight.Run(m, preflight.Requirements{
+		Profile: envErr,
+		Project: projectID,
+		APIs:    []string{"notebooks.googleapis.com", "compute.googleapis.com"},
+		Roles:   []string{"roles/notebooks.admin", "roles/compute.networkAdmin", "roles/compute.securityAdmin", "roles/compute.instanceAdmin.v1", "roles/iap.tunnelResourceAccessor", "roles/iam.serviceAccountUser"},
+		Quotas:  []preflight.Quota{{Metric: "NETWORKS"}, {Metric: "INSTANCES", Region: region}},
+	}))
 }
 
 // TestWorkbenchWithBigQueryConnectivity verifies the end-to-end connectivity between a Google Cloud Workbench instance and a BigQuery API within a specified VPC.
@@ -68,17 +79,14 @@ type WorkbenchConfig struct {
 // checks that the instance does not have a public IP, retrieves its internal IP, and finally tests connectivity to BigQuery.
 // Resources are cleaned up after the test completes.
 func TestWorkbenchWithBigQueryConnectivity(t *testing.T) {
-	if projectID == "" {
-		t.Fatal("TF_VAR_project_id environment variable is not set.")
-	}
-	createConfigYAML(t, filepath.Join(configFolderPath, yaml_file_name))
-
+	profile.Require(t, "projects.endpoint")
+	setNames(t)
 	tfVars := map[string]interface{}{
 		"config_folder_path": configFolderPath,
 	}
 
 	// Terraform Options
-	terraformOptions := terraform.WithDefaultRetryableErrors(t, &terraform.Options{
+	terraformOptions := retryable.With(t, &terraform.Options{
 		Vars:         tfVars,
 		TerraformDir: terraformDirectoryPath,
 		Reconfigure:  true,
@@ -87,15 +95,27 @@ func TestWorkbenchWithBigQueryConnectivity(t *testing.T) {
 	})
 
 	createVPC(t, projectID, vpcName)
-	time.Sleep(30 * time.Second) // Allow time for VPC/subnet to be fully ready
-	time.Sleep(30 * time.Second) // Allow time for firewall rule to propagate
-
-	// Defer cleanup operations
-	defer deleteVPC(t, projectID, vpcName)       // Then delete VPC
-	defer terraform.Destroy(t, terraformOptions) // Finally, terraform destroy
+	wait.For(t, "subnet "+subnetName+" to be ready", wait.DefaultOptions, wait.SubnetReady(t, gcloud.New(projectID), region, subnetName))
 
-	// Apply Terraform
-	terraform.InitAndApply(t, terraformOptions)
+	// Delete the VPC when the test ends, after the firewall rule of the probe,
+	// which is a fixture that depends on it.
+	network := fixture.Register(t, fixture.Resource{Kind: "network", ID: fmt.Sprintf("projects/%s/global/networks/%s", projectID, vpcName)}, func() error {
+		deleteVPC(t, projectID, vpcName)
+		return nil
+	})
+	defer terraform.Destroy(t, terraformOptions)
+
+	// Apply Terraform, moving to a fallback zone when the zone is out of
+	// capacity.
+	loc, err := location.Try(t, location.Zones(env), func(loc location.Location) error {
+		zone = loc.Zone
+		createConfigYAML(t, filepath.Join(configFolderPath, yaml_file_name))
+		_, err := terraform.InitAndApplyE(t, terraformOptions)
+		return err
+	})
+	if err != nil {
+		t.Fatalf("Applying the Workbench configuration in %s failed: %v", loc, err)
+	}
 
 	allWorkbenchOutputs := terraform.OutputJson(t, terraformOptions, "")
 	workbenchInstanceIds := gjson.Get(allWorkbenchOutputs, "workbench_instance_ids.value").Map()
@@ -145,6 +165,39 @@ func TestWorkbenchWithBigQueryConnectivity(t *testing.T) {
 	t.Logf("Retrieved Workbench instance internal IP: %s", workbenchInternalIP)
 
 	testConnectivity(t, projectID, workbenchInternalIP, vpcName)
+	verifyReachable(t, network, workbenchSSHInstanceName)
+}
+
+// verifyReachable checks from the Workbench instance itself, over IAP SSH, that
+// it reaches the BigQuery API through Private Google Access.
+func verifyReachable(t *testing.T, network *fixture.Handle, instanceName string) {
+	t.Log("========= Reachability Probe from the Workbench instance =========")
+	client := gcloud.New(projectID)
+	firewallName := fmt.Sprintf("%s-iap", vpcName)
+	_, err := fixture.Firewall(t, client, firewallName, gcloud.FirewallOptions{
+		Network:      vpcName,
+		Allow:        []string{"tcp:22"},
+		SourceRanges: []string{probe.IAPRange},
+	}, network)
+	if err != nil {
+		t.Fatalf("Error creating firewall rule %s: %v", firewallName, err)
+	}
+	vm := probe.Reuse(client, zone, instanceName)
+	results, err := vm.Eventually(t, wait.DefaultOptions, true, probe.TLS("bigquery.googleapis.com", 443))
+	if err != nil {
+		t.Errorf("Workbench instance '%s' cannot reach bigquery.googleapis.com:443: %v", instanceName, err)
+		return
+	}
+	t.Logf("Workbench instance '%s' reaches BigQuery: %s", instanceName, results[0])
+}
+
+// setNames derives the names of the Workbench instance, of its network and of
+// the connectivity test from the run and the test.
+func setNames(t *testing.T) {
+	vpcName = naming.Name(t, naming.Compute, "testing-net-workbench")
+	subnetName = naming.Name(t, naming.Compute, "testing-subnet-workbench")
+	workbenchName = naming.Name(t, naming.Compute, "workbench")
+	connectivityTestName = naming.Name(t, naming.Compute, "workbench-bq-test")
 }
 
 // createConfigYAML creates the configuration YAML file for a Workbench instance.
@@ -152,20 +205,13 @@ func TestWorkbenchWithBigQueryConnectivity(t *testing.T) {
 func createConfigYAML(t *testing.T, filePath string) {
 	t.Log("========= YAML File Creation =========")
 
-	workbenchInstance := WorkbenchConfig{
+	workbenchInstance := schema.Workbench{
 		Name:      workbenchName,
 		ProjectID: projectID,
 		Location:  zone, // Use zone for Workbench instance location
-		GceSetup: struct {
-			NetworkInterfaces []struct {
-				Network string `yaml:"network"`
-				Subnet  string `yaml:"subnet"`
-			} `yaml:"network_interfaces"`
-		}{
-			NetworkInterfaces: []struct {
-				Network string `yaml:"network"`
-				Subnet  string `yaml:"subnet"`
-			}{
+		GCESetup: schema.WorkbenchGCESetup{
+			Labels: naming.Labels(t),
+			NetworkInterfaces: []schema.WorkbenchNetworkInterface{
 				{
 					Network: fmt.Sprintf("projects/%s/global/networks/%s", projectID, vpcName),
 					Subnet:  fmt.Sprintf("projects/%s/regions/%s/subnetworks/%s", projectID, region, subnetName),
@@ -176,7 +222,7 @@ func createConfigYAML(t *testing.T, filePath string) {
 
 	yamlData, err := yaml.Marshal(&workbenchInstance)
 	if err != nil {
-		t.Fatalf("Error while marshaling YAML for WorkbenchConfig: %v", err)
+		t.Fatalf("Error while marshaling YAML for the Workbench instance: %v", err)
 	}
 
 	// Ensure the directory for the filePath exists (in case it's nested)
@@ -233,7 +279,7 @@ func createVPC(t *testing.T, projectID string, vpcName string) {
 			"--project=" + projectID,
 			"--network=" + vpcName,
 			"--region=" + region,
-			"--range=10.0.0.0/24", // Make sure this range doesn't overlap if running multiple tests in parallel on same project
+			"--range=" + ipam.Subnet(t, 24),
 			"--enable-private-ip-google-access",
 		},
 	}
@@ -247,51 +293,27 @@ func createVPC(t *testing.T, projectID string, vpcName string) {
 func deleteVPC(t *testing.T, projectID string, vpcName string) {
 	t.Logf("Deleting Subnet: %s and VPC: %s", subnetName, vpcName)
 	// It can take time for dependent resources (like workbench instance) to be fully deleted
-	// before a subnet/network can be. The sleeps are attempts to mitigate this.
-	// Ideally, ensure all dependent resources are gone first. Terraform destroy should handle the instance.
+	// before a subnet/network can be, so each delete is retried while they are still in use.
+	client := gcloud.New(projectID)
 
-	cmdDeleteSubnet := shell.Command{
-		Command: "gcloud",
-		Args:    []string{"compute", "networks", "subnets", "delete", subnetName, "--project=" + projectID, "--region=" + region, "--quiet"},
-	}
-	// Retry subnet deletion as it often fails if resources are still attached
-	maxRetries := 3
-	for i := 0; i < maxRetries; i++ {
-		output, err := shell.RunCommandAndGetOutputE(t, cmdDeleteSubnet)
-		if err == nil {
-			t.Logf("Successfully deleted subnet %s.", subnetName)
-			break
-		}
-		t.Logf("Error deleting subnet %s (attempt %d/%d): %v. Output: %s", subnetName, i+1, maxRetries, err, output)
-		if i < maxRetries-1 {
-			time.Sleep(30 * time.Second)
-		} else {
-			t.Errorf("Failed to delete subnet %s after %d attempts. Last error: %v", subnetName, maxRetries, err)
-			// Continue to attempt VPC deletion anyway
-		}
+	_, err := wait.ForE(t, "subnet "+subnetName+" to be deleted", wait.DefaultOptions, wait.Deletes(func() error {
+		return client.Subnets.Delete(t, region, subnetName)
+	}))
+	if err != nil {
+		t.Errorf("Failed to delete subnet %s: %v", subnetName, err)
+		// Continue to attempt VPC deletion anyway
+	} else {
+		t.Logf("Successfully deleted subnet %s.", subnetName)
 	}
 
-	// Wait longer before attempting network deletion
-	t.Logf("Waiting before deleting network %s...", vpcName)
-	time.Sleep(60 * time.Second)
-
-	cmdDeleteNet := shell.Command{
-		Command: "gcloud",
-		Args:    []string{"compute", "networks", "delete", vpcName, "--project=" + projectID, "--quiet"},
-	}
-	for i := 0; i < maxRetries; i++ {
-		output, err := shell.RunCommandAndGetOutputE(t, cmdDeleteNet)
-		if err == nil {
-			t.Logf("Successfully deleted VPC network %s.", vpcName)
-			return
-		}
-		t.Logf("Error deleting VPC network %s (attempt %d/%d): %v. Output: %s", vpcName, i+1, maxRetries, err, output)
-		if i < maxRetries-1 {
-			time.Sleep(30 * time.Second)
-		} else {
-			t.Errorf("Failed to delete VPC network %s after %d attempts. Last error: %v", vpcName, maxRetries, err)
-		}
+	_, err = wait.ForE(t, "VPC network "+vpcName+" to be deleted", wait.DefaultOptions, wait.Deletes(func() error {
+		return client.Networks.Delete(t, vpcName)
+	}))
+	if err != nil {
+		t.Errorf("Failed to delete VPC network %s: %v", vpcName, err)
+		return
 	}
+	t.Logf("Successfully deleted VPC network %s.", vpcName)
 }
 
 // testConnectivity tests the connectivity from the Workbench instance to the BigQuery endpoint.
@@ -343,14 +365,11 @@ func testConnectivity(t *testing.T, projectID, sourceIP, networkName string) {
 		}
 	}()
 
-	maxPollRetries := 3
-	pollInterval := 20 * time.Second
 	testStatus := ""
 	finalDescribeOutput := ""
-	var reachabilityResult gjson.Result
 
-	for i := 0; i < maxPollRetries; i++ {
-		time.Sleep(pollInterval)
+	pollOptions := wait.Options{Timeout: 2 * time.Minute, Interval: 20 * time.Second, MaxInterval: 20 * time.Second}
+	wait.ForE(t, "connectivity test "+connectivityTestName+" analysis", pollOptions, func() (bool, string, error) {
 		describeCmd := shell.Command{
 			Command: "gcloud",
 			Args:    []string{"network-management", "connectivity-tests", "describe", connectivityTestName, "--project=" + projectID, "--format=json"},
@@ -358,8 +377,7 @@ func testConnectivity(t *testing.T, projectID, sourceIP, networkName string) {
 		describeOutput, describeErr := shell.RunCommandAndGetOutputE(t, describeCmd)
 		finalDescribeOutput = describeOutput
 		if describeErr != nil {
-			t.Logf("Error describing connectivity test (attempt %d/%d): %v. Output: %s", i+1, maxPollRetries, describeErr, describeOutput)
-			continue
+			return false, describeErr.Error(), nil
 		}
 
 		// Extract only the JSON part from the output (skip warnings/logs)
@@ -368,17 +386,14 @@ func testConnectivity(t *testing.T, projectID, sourceIP, networkName string) {
 			describeOutput = describeOutput[jsonStart:]
 		}
 		parsedOutput := gjson.Parse(describeOutput)
-		reachabilityResult = parsedOutput.Get("reachabilityDetails.result")
-		if reachabilityResult.Exists() {
-			testStatus = reachabilityResult.String()
-			t.Logf("Connectivity test status: %s (Attempt %d/%d)", testStatus, i+1, maxPollRetries)
-			if testStatus == "REACHABLE" || testStatus == "UNREACHABLE" || testStatus == "AMBIGUOUS" {
-				break
-			}
-		} else {
-			t.Logf("Connectivity test status not yet available (attempt %d/%d). State: %s. Output: %s", i+1, maxPollRetries, parsedOutput.Get("state").String(), describeOutput)
+		reachabilityResult := parsedOutput.Get("reachabilityDetails.result")
+		if !reachabilityResult.Exists() {
+			return false, "result not yet available, state " + parsedOutput.Get("state").String(), nil
 		}
-	}
+		testStatus = reachabilityResult.String()
+		done := testStatus == "REACHABLE" || testStatus == "UNREACHABLE" || testStatus == "AMBIGUOUS"
+		return done, testStatus, nil
+	})
 
 	// Assert the final testStatus after the loop
 	assert.Equal(t, "REACHABLE", testStatus, fmt.Sprintf("Connectivity test to BigQuery endpoint should be REACHABLE. Final describe output:\n%s", finalDescribeOutput))
@@ -16,48 +16,52 @@ package integrationtest
 
 import (
 	"fmt"
-	"math/rand"
+	"net/netip"
 	"os"
 	"path/filepath"
+	"strconv"
 	"strings"
 	"testing"
-	"time"
 
-	"github.com/gruntwork-io/terratest/modules/shell"
+	"github.com/GoogleCloudPlatform/cloudnetworking-config-solutions/common_utils/cassette"
+	"github.com/GoogleCloudPlatform/cloudnetworking-config-solutions/common_utils/fixture"
+	"github.com/GoogleCloudPlatform/cloudnetworking-config-solutions/common_utils/gcloud"
+	"github.com/GoogleCloudPlatform/cloudnetworking-config-solutions/common_utils/ipam"
+	"github.com/GoogleCloudPlatform/cloudnetworking-config-solutions/common_utils/naming"
+	"github.com/GoogleCloudPlatform/cloudnetworking-config-solutions/common_utils/preflight"
+	"github.com/GoogleCloudPlatform/cloudnetworking-config-solutions/common_utils/profile"
+	"github.com/GoogleCloudPlatform/cloudnetworking-config-solutions/common_utils/retryable"
+	"github.com/GoogleCloudPlatform/cloudnetworking-config-solutions/common_utils/schema"
+	"github.com/GoogleCloudPlatform/cloudnetworking-config-solutions/common_utils/wait"
 	"github.com/gruntwork-io/terratest/modules/terraform"
 	"github.com/tidwall/gjson"
 	"gopkg.in/yaml.v2"
 )
 
 const (
-	region       = "us-west2"
 	yamlFileName = "instance.yaml"
 )
 
 var (
-	projectID            = os.Getenv("TF_VAR_project_id")
-	uniqueID             = rand.Int()
-	networkName          = fmt.Sprintf("test-vpc-ncc-%d", uniqueID)
-	subnetworkName       = fmt.Sprintf("test-subnet-ncc-%d", uniqueID)
-	secondNetworkName    = fmt.Sprintf("test-second-vpc-ncc-%d", uniqueID)
-	secondSubnetworkName = fmt.Sprintf("test-second-subnet-ncc-%d", uniqueID)
-	firstTunnel          = fmt.Sprintf("test-first-tunnel-%d", uniqueID)
-	secondTunnel         = fmt.Sprintf("test-second-tunnel-%d", uniqueID)
-	firstGatewayName     = fmt.Sprintf("test-first-gateway-%d", uniqueID)
-	secondGatewayName    = fmt.Sprintf("test-second-gateway-%d", uniqueID)
-	subnetworkIPCIDR     = "10.0.2.0/24"
-	psaRangeName         = fmt.Sprintf("testpsarange-ncc1-%d", uniqueID)
-	secondPSARangeName   = fmt.Sprintf("testpsarange-ncc2-%d", uniqueID)
-	psaRange             = "10.0.64.0/20"
-	secondPSARange       = "10.10.10.0/24"
-	testHubName          = fmt.Sprintf("ncc-hub-test-%d", uniqueID)
-	testHubDescription   = "Test NCC Hub for integration"
-	testHubLabels        = map[string]string{
-		"environment": "testing",
-	}
-	testSpokeLabels = map[string]string{
-		"team": "testing",
-	}
+	env, envErr        = profile.Current()
+	projectID          = env.Projects.Endpoint
+	region             = env.Regions.Networking
+	testHubDescription = "Test NCC Hub for integration"
+	// The names and labels below are derived from the run and the test by
+	// setNames.
+	networkName          string
+	subnetworkName       string
+	secondNetworkName    string
+	secondSubnetworkName string
+	firstTunnel          string
+	secondTunnel         string
+	firstGatewayName     string
+	secondGatewayName    string
+	psaRangeName         string
+	secondPSARangeName   string
+	testHubName          string
+	testHubLabels        map[string]string
+	testSpokeLabels      map[string]string
 
 	testVPCSpokeName      = "spoke1-test"
 	testProducerSpokeName = "prodspoke1-test"
@@ -75,57 +79,53 @@ var (
 	configFolderPathNCC       = filepath.Join(projectRoot, "test/integration/networking/ncc/config")
 )
 
-// NCCConfig struct to match the new YAML structure
-type NCCConfig struct {
-	Hubs   []HubConfig   `yaml:"hubs"`
-	Spokes []SpokeConfig `yaml:"spokes,omitempty"`
+// TestMain checks the APIs, roles and quota the suite needs before the
+// networks and the hub are created.
+func TestMain(m *testing.M) {
+	os.Exit(preflight.Run(m, preflight.Requirements{
+		Profile: envErr,
+		Project: projectID,
+		APIs:    []string{"compute.googleapis.com", "servicenetworking.googleapis.com", "networkconnectivity.googleapis.com"},
+		Roles:   []string{"roles/compute.networkAdmin", "roles/servicenetworking.networksAdmin", "roles/networkconnectivity.hubAdmin"},
+		Quotas:  []preflight.Quota{{Metric: "NETWORKS", Need: 2}, {Metric: "ROUTERS", Need: 2}, {Metric: "VPN_GATEWAYS", Region: region, Need: 2}, {Metric: "VPN_TUNNELS", Region: region, Need: 2}},
+	}))
 }
 
-type HubConfig struct {
-	Name               string            `yaml:"name"`
-	ProjectID          string            `yaml:"project_id"`
-	Description        string            `yaml:"description"`
-	Labels             map[string]string `yaml:"labels"`
-	ExportPSC          bool              `yaml:"export_psc"`
-	PolicyMode         string            `yaml:"policy_mode"`
-	PresetTopology     string            `yaml:"preset_topology"`
-	AutoAcceptProjects []string          `yaml:"auto_accept_projects"`
-	CreateNewHub       bool              `yaml:"create_new_hub"`
-	ExistingHubURI     string            `yaml:"existing_hub_uri"`
-	GroupName          string            `yaml:"group_name"`
-	GroupDescription   string            `yaml:"group_decription"`
-	SpokeLabels        map[string]string `yaml:"spoke_labels"`
-}
-
-type SpokeConfig struct {
-	Type                string            `yaml:"type"`
-	Name                string            `yaml:"name"`
-	ProjectID           string            `yaml:"project_id"`
-	Location            string            `yaml:"location,omitempty"`
-	URI                 any               `yaml:"uri,omitempty"`
-	URIS                any               `yaml:"uris,omitempty"`
-	Description         string            `yaml:"description"`
-	Labels              map[string]string `yaml:"labels"`
-	Peering             string            `yaml:"peering,omitempty"`
-	ExcludeExportRanges []string          `yaml:"exclude_export_ranges,omitempty"`
-	IncludeExportRanges []string          `yaml:"include_export_ranges,omitempty"`
-}
+// Ranges of the networks, leased from the ipam package by TestNCC so that the
+// spokes overlap no other test.
+var (
+	subnetworkIPCIDR, secondSubnetworkIPCIDR string
+	psaRange, secondPSARange                 string
+)
 
 func TestNCC(t *testing.T) {
-	if projectID == "" {
-		t.Skipf("Skipping test because TF_VAR_project_id is not set %s.", projectID)
-	}
-
-	// Setup: create YAML config and VPC/subnet/PSA
+	profile.Require(t, "projects.endpoint")
+
+	// Record or replay the commands of the test when TEST_CASSETTE_MODE is
+	// set. The values below differ between the recording and the replay.
+	cas := cassette.Start(t)
+	cas.Vary("project_id", projectID)
+	setNames(t)
+	// The labels hold the run ID and its expiry, which are passed to apply.
+	cas.Vary("run_id", naming.RunID())
+	cas.Vary("expires", strconv.FormatInt(naming.Expiry().Unix(), 10))
+	cas.Vary("project_root", projectRoot)
+	subnetworkIPCIDR = cas.Vary("subnetwork_range", ipam.Subnet(t, 24))
+	secondSubnetworkIPCIDR = cas.Vary("second_subnetwork_range", ipam.Subnet(t, 24))
+	psaRange = cas.Vary("psa_range", ipam.PSARange(t, 20))
+	secondPSARange = cas.Vary("second_psa_range", ipam.PSARange(t, 24))
+
+	// Setup: create YAML config and VPC/subnet/PSA. The prerequisites are
+	// fixtures, torn down when the test ends after the hub and its spokes.
 	createConfigYAMLNCC(t, true, "", false, testHubName)
-	createVPCAndSubnetWithPSA(t, projectID, networkName, subnetworkName, region, psaRangeName, psaRange)
-	createVPCAndSubnetWithPSA(t, projectID, secondNetworkName, secondSubnetworkName, region, secondPSARangeName, secondPSARange)
-	firstIPGateway1, secondIPGateway1 := createHAVPNGateway(t, projectID, networkName, firstGatewayName, "65417")
-	firstIPGateway2, secondIPGateway2 := createHAVPNGateway(t, projectID, secondNetworkName, secondGatewayName, "65416")
+	network1, prereqs1 := createVPCAndSubnetWithPSA(t, projectID, networkName, subnetworkName, subnetworkIPCIDR, region, psaRangeName, psaRange)
+	network2, prereqs2 := createVPCAndSubnetWithPSA(t, projectID, secondNetworkName, secondSubnetworkName, secondSubnetworkIPCIDR, region, secondPSARangeName, secondPSARange)
+	firstIPGateway1, secondIPGateway1, gateway1 := createHAVPNGateway(t, projectID, networkName, firstGatewayName, "65417", network1)
+	firstIPGateway2, secondIPGateway2, gateway2 := createHAVPNGateway(t, projectID, secondNetworkName, secondGatewayName, "65416", network2)
 	t.Logf("IP address for Interface0 : %s, Interface1: %s for gateway1.", firstIPGateway1, secondIPGateway1)
 	t.Logf("IP address for Interface0 : %s, Interface1: %s for gateway2.", firstIPGateway2, secondIPGateway2)
-	createHAVPNTunnel(t, projectID, firstGatewayName, secondGatewayName, firstTunnel)
-	createHAVPNTunnel(t, projectID, secondGatewayName, firstGatewayName, secondTunnel)
+	tunnel1 := createHAVPNTunnel(t, projectID, firstGatewayName, secondGatewayName, firstTunnel, gateway1, gateway2)
+	tunnel2 := createHAVPNTunnel(t, projectID, secondGatewayName, firstGatewayName, secondTunnel, gateway2, gateway1)
 	tfVars := map[string]interface{}{
 		"config_folder_path":   configFolderPathNCC,
 		"create_new_hub":       true,
@@ -141,7 +141,7 @@ func TestNCC(t *testing.T) {
 		"group_decription":     groupDescription,
 	}
 
-	terraformOptions := terraform.WithDefaultRetryableErrors(t, &terraform.Options{
+	terraformOptions := retryable.With(t, &terraform.Options{
 		Vars:         tfVars,
 		TerraformDir: terraformNCCDirectoryPath,
 		Reconfigure:  true,
@@ -149,23 +149,43 @@ func TestNCC(t *testing.T) {
 		NoColor:      true,
 	})
 
-	defer deleteVPCAndSubnet(t, projectID, networkName, subnetworkName, region, psaRangeName)
-	defer deleteVPCAndSubnet(t, projectID, secondNetworkName, secondSubnetworkName, region, secondPSARangeName)
-	defer deleteHAVPNGatewayAndTunnel(t, projectID, networkName, firstGatewayName, firstTunnel)
-	defer deleteHAVPNGatewayAndTunnel(t, projectID, secondNetworkName, secondGatewayName, secondTunnel)
-	defer terraform.Destroy(t, terraformOptions)
+	fixture.Terraform(t, terraformOptions, append(append([]*fixture.Handle{tunnel1, tunnel2}, prereqs1...), prereqs2...)...)
 
-	terraform.InitAndApply(t, terraformOptions)
-	time.Sleep(30 * time.Second)
+	cassette.InitAndApply(t, terraformOptions)
+	client := gcloud.New(projectID)
+	wait.For(t, "NCC hub "+testHubName+" to exist", wait.DefaultOptions, wait.Exists(func() error {
+		_, err := client.Run(t, "network-connectivity", "hubs", "describe", testHubName)
+		return err
+	}))
 
 	verifyNCCResources(t, terraformOptions, testHubName)
 }
 
+// setNames derives the names of the resources of the test, and the labels of
+// the hub and the spokes, from the run and the test.
+func setNames(t *testing.T) {
+	networkName = naming.Name(t, naming.Compute, "test-vpc-ncc")
+	subnetworkName = naming.Name(t, naming.Compute, "test-subnet-ncc")
+	secondNetworkName = naming.Name(t, naming.Compute, "test-second-vpc-ncc")
+	secondSubnetworkName = naming.Name(t, naming.Compute, "test-second-subnet-ncc")
+	firstTunnel = naming.Name(t, naming.Compute, "test-first-tunnel")
+	secondTunnel = naming.Name(t, naming.Compute, "test-second-tunnel")
+	firstGatewayName = naming.Name(t, naming.Compute, "test-first-gateway")
+	secondGatewayName = naming.Name(t, naming.Compute, "test-second-gateway")
+	psaRangeName = naming.Name(t, naming.Compute, "testpsarange-ncc1")
+	secondPSARangeName = naming.Name(t, naming.Compute, "testpsarange-ncc2")
+	testHubName = naming.Name(t, naming.Compute, "ncc-hub-test")
+	testHubLabels = naming.Labels(t)
+	testHubLabels["environment"] = "testing"
+	testSpokeLabels = naming.Labels(t)
+	testSpokeLabels["team"] = "testing"
+}
+
 // createConfigYAMLNCC creates the configuration YAML file for NCC.
 func createConfigYAMLNCC(t *testing.T, createNewHub bool, existingHubURI string, existingSpoke bool, nccHubName string) {
 	t.Helper()
 
-	spokes := []SpokeConfig{
+	spokes := []schema.NCCSpoke{
 		{
 			Type:        "linked_vpc_network",
 			Name:        testVPCSpokeName,
@@ -191,27 +211,27 @@ func createConfigYAMLNCC(t *testing.T, createNewHub bool, existingHubURI string,
 			Name:      testVPNSpokeName,
 			ProjectID: projectID,
 			Location:  region,
-			URIS:      []string{fmt.Sprintf("projects/%s/regions/%s/vpnTunnels/%s", projectID, region, firstTunnel)},
+			URIs:      []string{fmt.Sprintf("projects/%s/regions/%s/vpnTunnels/%s", projectID, region, firstTunnel)},
 		},
 	}
-	hubs := []HubConfig{
+	hubs := []schema.NCCHub{
 		{
 			Name:               nccHubName,
 			ProjectID:          projectID,
 			Description:        testHubDescription,
 			Labels:             testHubLabels,
-			ExportPSC:          defaultExportPSC,
+			ExportPSC:          schema.Bool(defaultExportPSC),
 			PolicyMode:         defaultPolicyMode,
 			PresetTopology:     defaultPresetTopology,
 			AutoAcceptProjects: append(defaultAutoAcceptProjects, projectID),
-			CreateNewHub:       createNewHub,
+			CreateNewHub:       schema.Bool(createNewHub),
 			ExistingHubURI:     existingHubURI,
 			SpokeLabels:        testSpokeLabels,
 			GroupName:          groupName,
 			GroupDescription:   groupDescription,
 		},
 	}
-	nccInstance := NCCConfig{
+	nccInstance := schema.NCC{
 		Hubs:   hubs,
 		Spokes: spokes,
 	}
@@ -234,129 +254,53 @@ func createConfigYAMLNCC(t *testing.T, createNewHub bool, existingHubURI string,
 	}
 }
 
-// createVPCAndSubnetWithPSA creates a VPC, a subnet with PSA enabled.
-func createVPCAndSubnetWithPSA(t *testing.T, projectID, networkName, subnetworkName, region, psaRangeName, psaRange string) {
+// createVPCAndSubnetWithPSA creates a VPC, a subnet with PSA enabled as
+// fixtures. It returns the handle of the network and the handles of the
+// subnet and the PSA connection, which the spokes use.
+func createVPCAndSubnetWithPSA(t *testing.T, projectID, networkName, subnetworkName, subnetworkIPCIDR, region, psaRangeName, psaRange string) (*fixture.Handle, []*fixture.Handle) {
 	t.Helper()
-	text := "compute"
-	// Create VPC
-	cmd := shell.Command{
-		Command: "gcloud",
-		Args:    []string{text, "networks", "create", networkName, "--project=" + projectID, "--format=json", "--bgp-routing-mode=global", "--subnet-mode=custom", "--quiet"},
-	}
-	_, err := shell.RunCommandAndGetOutputE(t, cmd)
+	client := gcloud.New(projectID)
+	network, err := fixture.Network(t, client, networkName, gcloud.NetworkOptions{BGPRoutingMode: "global"})
 	if err != nil {
 		t.Fatalf("Error creating VPC: %v", err)
 	}
 
-	cmd = shell.Command{
-		Command: "gcloud",
-		Args: []string{
-			text, "networks", "subnets", "create", subnetworkName,
-			"--project=" + projectID,
-			"--network=" + networkName,
-			"--region=" + region,
-			"--range=" + subnetworkIPCIDR,
-			"--enable-private-ip-google-access",
-			"--quiet",
-		},
-	}
-	_, err = shell.RunCommandAndGetOutputE(t, cmd)
+	subnet, err := fixture.Subnet(t, client, subnetworkName, gcloud.SubnetOptions{
+		Network:                     networkName,
+		Region:                      region,
+		Range:                       subnetworkIPCIDR,
+		EnablePrivateIPGoogleAccess: true,
+	}, network)
 	if err != nil {
 		t.Fatalf("Error creating subnet: %v", err)
 	}
 
 	// Create Allocated PSA Range
-	cmd = shell.Command{
-		Command: "gcloud",
-		Args: []string{
-			"compute", "addresses", "create", psaRangeName,
-			"--global",
-			"--purpose=VPC_PEERING",
-			"--addresses=" + psaRange[:strings.Index(psaRange, "/")],       // Extract the IP from the CIDR
-			"--prefix-length=" + psaRange[strings.Index(psaRange, "/")+1:], // Extract the prefix length from the CIDR
-			"--network=" + networkName,
-			"--project=" + projectID,
-			"--quiet",
-		},
-	}
-	_, err = shell.RunCommandAndGetOutputE(t, cmd)
+	prefix := netip.MustParsePrefix(psaRange)
+	allocation, err := fixture.Address(t, client, psaRangeName, gcloud.AddressOptions{
+		Purpose:      "VPC_PEERING",
+		Address:      prefix.Addr().String(),
+		PrefixLength: prefix.Bits(),
+		Network:      networkName,
+	}, network)
 	if err != nil {
 		t.Fatalf("Error creating allocated PSA range: %v", err)
 	}
 
 	// Create PSA Range Peering
-	cmd = shell.Command{
-		Command: "gcloud",
-		Args: []string{
-			"services", "vpc-peerings", "connect",
-			"--project=" + projectID,
-			"--service=servicenetworking.googleapis.com",
-			"--ranges=" + psaRangeName,
-			"--network=" + networkName,
-			"--quiet",
-		},
-	}
-	_, err = shell.RunCommandAndGetOutputE(t, cmd)
+	peering, err := fixture.VPCPeering(t, client, networkName, []string{psaRangeName}, allocation)
 	if err != nil {
 		t.Fatalf("Error creating PSA range: %v", err)
 	}
 
 	t.Logf("Successfully created VPC '%s' with subnet '%s' and PSA range '%s'.", networkName, subnetworkName, psaRangeName)
-	time.Sleep(60 * time.Second)
-}
-
-func deleteVPCAndSubnet(t *testing.T, projectID, networkName, subnetworkName, region, psaRangeName string) {
-	t.Helper()
-	text := "compute"
-	time.Sleep(60 * time.Second) // Wait for resources to be in a deletable state
-
-	// Delete subnet
-	cmd := shell.Command{
-		Command: "gcloud",
-		Args: []string{
-			text, "networks", "subnets", "delete", subnetworkName,
-			"--project=" + projectID,
-			"--region=" + region,
-			"--quiet",
-		},
-	}
-	if _, err := shell.RunCommandAndGetOutputE(t, cmd); err != nil {
-		t.Errorf("Error deleting subnet: %v", err)
-	}
-
-	time.Sleep(60 * time.Second)
-
-	// Delete PSA range
-	cmd = shell.Command{
-		Command: "gcloud",
-		Args: []string{
-			"compute", "addresses", "delete", psaRangeName,
-			"--global",
-			"--project=" + projectID,
-			"--quiet",
-		},
-	}
-	if _, err := shell.RunCommandAndGetOutputE(t, cmd); err != nil {
-		t.Errorf("Error deleting PSA range: %v", err)
-	}
-
-	time.Sleep(60 * time.Second)
-
-	// Delete VPC
-	cmd = shell.Command{
-		Command: "gcloud",
-		Args:    []string{text, "networks", "delete", networkName, "--project=" + projectID, "--quiet"},
-	}
-	if _, err := shell.RunCommandAndGetOutputE(t, cmd); err != nil {
-		t.Errorf("Error deleting VPC: %v", err)
-	}
-
-	time.Sleep(60 * time.Second)
+	wait.For(t, "vpc peering of "+networkName+" to be connected", wait.DefaultOptions, wait.VPCPeeringConnected(t, client, networkName))
+	return network, []*fixture.Handle{subnet, peering}
 }
 
 func verifyNCCResources(t *testing.T, terraformOptions *terraform.Options, testHubName string) {
 	t.Helper()
-	nccOutputValue := terraform.OutputJson(t, terraformOptions, "ncc_module")
+	nccOutputValue := cassette.OutputJSON(t, terraformOptions, "ncc_module")
 	if !gjson.Valid(nccOutputValue) {
 		t.Fatalf("Error parsing network_connectivity_center output, invalid json: %s", nccOutputValue)
 	}
@@ -433,121 +377,77 @@ func verifyNCCResources(t *testing.T, terraformOptions *terraform.Options, testH
 	t.Log("NCC Resources verification completed.")
 }
 
-// createHAVPNTunnel creates a havpn gateway.
-func createHAVPNGateway(t *testing.T, projectID, networkName string, gatewayName string, asnRouter string) (string, string) {
-
-	text := "compute"
-	// Create vpn-gateways
-	cmd := shell.Command{
-		Command: "gcloud",
-		Args:    []string{text, "vpn-gateways", "create", gatewayName, "--network=" + networkName, "--region=" + region, "--project=" + projectID, "--stack-type=IPV4_ONLY", "--quiet"},
-	}
-	_, err := shell.RunCommandAndGetOutputE(t, cmd)
-	if err != nil {
-		t.Errorf("Error creating vpn-gateways: %v", err)
-	}
+// createHAVPNGateway creates a havpn gateway and its cloud router as
+// fixtures. It returns the addresses of the interfaces of the gateway and
+// the handle of the router, which is deleted after the gateway.
+func createHAVPNGateway(t *testing.T, projectID, networkName string, gatewayName string, asnRouter string, network *fixture.Handle) (string, string, *fixture.Handle) {
+	client := gcloud.New(projectID)
 
 	// Create cloud router
-	cmd = shell.Command{
-		Command: "gcloud",
-		Args:    []string{text, "routers", "create", gatewayName + "-router", "--network=" + networkName, "--region=" + region, "--project=" + projectID, "--asn=" + asnRouter, "--quiet"},
-	}
-	_, err = shell.RunCommandAndGetOutputE(t, cmd)
-	if err != nil {
-		t.Errorf("Error creating Cloud Router: %v", err)
+	if _, err := client.Run(t, "compute", "routers", "create", gatewayName+"-router", "--network="+networkName, "--region="+region, "--asn="+asnRouter); err != nil {
+		t.Fatalf("Error creating Cloud Router: %v", err)
 	}
+	router := registerRegional(t, client, "router", "routers", gatewayName+"-router", network)
 
-	time.Sleep(60 * time.Second)
-
-	// retrieve ip details of interfaces
-	cmd = shell.Command{
-		Command: "gcloud",
-		Args:    []string{text, "vpn-gateways", "describe", gatewayName, "--region=" + region, "--project=" + projectID, "--format=json"},
-	}
-
-	outputJSON, err := shell.RunCommandAndGetOutputE(t, cmd)
-	if err != nil {
-		t.Errorf("Error retreiving json output: %v", err)
-	}
-
-	if !gjson.Valid(outputJSON) {
-		t.Fatalf("Error parsing gateway details output, invalid json: %s", outputJSON)
+	// Create vpn-gateways
+	if _, err := client.Run(t, "compute", "vpn-gateways", "create", gatewayName, "--network="+networkName, "--region="+region, "--stack-type=IPV4_ONLY"); err != nil {
+		t.Fatalf("Error creating vpn-gateways: %v", err)
 	}
-	resultGateway := gjson.Parse(outputJSON)
-
-	firstIP := gjson.Get(resultGateway.String(), "vpnInterfaces.0.ipAddress").String()
-	secondIP := gjson.Get(resultGateway.String(), "vpnInterfaces.1.ipAddress").String()
+	gateway := registerRegional(t, client, "vpn gateway", "vpn-gateways", gatewayName, router)
 
-	if err == nil {
-		return firstIP, secondIP
+	// Wait for both interfaces of the gateway to be assigned an IP address.
+	var details struct {
+		VPNInterfaces []struct {
+			IPAddress string `json:"ipAddress"`
+		} `json:"vpnInterfaces"`
 	}
-
-	return "", ""
-
+	wait.For(t, "VPN gateway "+gatewayName+" interfaces", wait.DefaultOptions, wait.Check(func() error {
+		if err := client.RunJSON(t, &details, "compute", "vpn-gateways", "describe", gatewayName, "--region="+region); err != nil {
+			return err
+		}
+		if len(details.VPNInterfaces) < 2 || details.VPNInterfaces[1].IPAddress == "" {
+			return fmt.Errorf("%d interfaces assigned", len(details.VPNInterfaces))
+		}
+		return nil
+	}))
+	return details.VPNInterfaces[0].IPAddress, details.VPNInterfaces[1].IPAddress, gateway
 }
 
-// createHAVPNTunnel creates a havpn tunnel.
-func createHAVPNTunnel(t *testing.T, projectID, firstGatewayName, secondGatewayName string, tunnelName string) {
-
-	text := "compute"
-	// Create VPN tunnel
-	cmd := shell.Command{
-		Command: "gcloud",
-		Args: []string{text, "vpn-tunnels", "create", tunnelName,
-			"--peer-gcp-gateway=" + secondGatewayName,
-			"--interface=0",
-			"--ike-version=2",
-			"--shared-secret=testsecret",
-			"--vpn-gateway=" + firstGatewayName,
-			"--region=" + region,
-			"--project=" + projectID,
-			"--router=" + firstGatewayName + "-router",
-			"--format=json",
-			"--quiet"},
-	}
-	_, err := shell.RunCommandAndGetOutputE(t, cmd)
+// createHAVPNTunnel creates a havpn tunnel as a fixture, deleted before the
+// gateways it connects.
+func createHAVPNTunnel(t *testing.T, projectID, firstGatewayName, secondGatewayName string, tunnelName string, gateways ...*fixture.Handle) *fixture.Handle {
+	client := gcloud.New(projectID)
+	_, err := client.Run(t, "compute", "vpn-tunnels", "create", tunnelName,
+		"--peer-gcp-gateway="+secondGatewayName,
+		"--interface=0",
+		"--ike-version=2",
+		"--shared-secret=testsecret",
+		"--vpn-gateway="+firstGatewayName,
+		"--region="+region,
+		"--router="+firstGatewayName+"-router")
 	if err != nil {
-		t.Errorf("Error creating vpn-gateways: %v", err)
-	}
-
-	if err == nil {
-		t.Logf("Successfully created VPN tunnels with name '%s' in gateways %s.", tunnelName, firstGatewayName)
+		t.Fatalf("Error creating vpn-tunnel %s: %v", tunnelName, err)
 	}
+	tunnel := registerRegional(t, client, "vpn tunnel", "vpn-tunnels", tunnelName, gateways...)
+	t.Logf("Successfully created VPN tunnels with name '%s' in gateways %s.", tunnelName, firstGatewayName)
 
-	time.Sleep(60 * time.Second)
+	wait.For(t, "VPN tunnel "+tunnelName+" to exist", wait.DefaultOptions, wait.Exists(func() error {
+		_, err := client.Run(t, "compute", "vpn-tunnels", "describe", tunnelName, "--region="+region)
+		return err
+	}))
+	return tunnel
 }
 
-func deleteHAVPNGatewayAndTunnel(t *testing.T, projectID, networkName string, gatewayName string, tunnelName string) {
-	text := "compute"
-	// Delete vpn tunnel
-	cmd := shell.Command{
-		Command: "gcloud",
-		Args: []string{text, "vpn-tunnels", "delete", tunnelName,
-			"--region=" + region,
-			"--project=" + projectID, "--quiet"},
-	}
-	_, err := shell.RunCommandAndGetOutputE(t, cmd)
-	if err != nil {
-		t.Errorf("Error deleting vpn-tunnel: %v", err)
-	}
-	time.Sleep(60 * time.Second)
-	// Delete first vpn-gateways
-	cmd = shell.Command{
-		Command: "gcloud",
-		Args:    []string{text, "vpn-gateways", "delete", gatewayName, "--region=" + region, "--project=" + projectID, "--quiet"},
-	}
-	_, err = shell.RunCommandAndGetOutputE(t, cmd)
-	if err != nil {
-		t.Errorf("Error deleting vpn-gateways: %v", err)
-	}
-	time.Sleep(60 * time.Second)
-	//Delete cloud routers
-	cmd = shell.Command{
-		Command: "gcloud",
-		Args:    []string{text, "routers", "delete", gatewayName + "-router", "--region=" + region, "--project=" + projectID, "--quiet"},
-	}
-	_, err = shell.RunCommandAndGetOutputE(t, cmd)
-	if err != nil {
-		t.Errorf("Error deleting Cloud Router: %v", err)
-	}
+// registerRegional registers the deletion of the regional resource name of
+// the "gcloud compute" group, retried while the dependents being torn down
+// still reference it.
+func registerRegional(t *testing.T, client *gcloud.Client, kind, group, name string, dependsOn ...*fixture.Handle) *fixture.Handle {
+	res := fixture.Resource{Kind: kind, ID: fmt.Sprintf("projects/%s/regions/%s/%s/%s", client.Project, region, group, name)}
+	return fixture.Register(t, res, func() error {
+		_, err := wait.ForE(t, res.String()+" to be deleted", wait.DefaultOptions, wait.Deletes(func() error {
+			_, err := client.Run(t, "compute", group, "delete", name, "--region="+region)
+			return err
+		}))
+		return err
+	}, dependsOn...)
 }
@@ -17,13 +17,20 @@ package integrationtest
 import (
 	"fmt"
 	"log"
-	"math/rand"
 	"os"
 	"strconv"
 	"testing"
-	"time"
 
 	"github.com/GoogleCloudPlatform/cloudnetworking-config-solutions/common_utils"
+	"github.com/GoogleCloudPlatform/cloudnetworking-config-solutions/common_utils/fakeapi"
+	"github.com/GoogleCloudPlatform/cloudnetworking-config-solutions/common_utils/fixture"
+	"github.com/GoogleCloudPlatform/cloudnetworking-config-solutions/common_utils/gcloud"
+	"github.com/GoogleCloudPlatform/cloudnetworking-config-solutions/common_utils/ipam"
+	"github.com/GoogleCloudPlatform/cloudnetworking-config-solutions/common_utils/naming"
+	"github.com/GoogleCloudPlatform/cloudnetworking-config-solutions/common_utils/preflight"
+	"github.com/GoogleCloudPlatform/cloudnetworking-config-solutions/common_utils/profile"
+	"github.com/GoogleCloudPlatform/cloudnetworking-config-solutions/common_utils/retryable"
+	"github.com/GoogleCloudPlatform/cloudnetworking-config-solutions/common_utils/wait"
 	"github.com/google/go-cmp/cmp"
 	"github.com/gruntwork-io/terratest/modules/shell"
 	"github.com/gruntwork-io/terratest/modules/terraform"
@@ -32,10 +39,8 @@ import (
 
 const (
 	terraformDirectoryPath   = "../../../02-networking"
-	region                   = "us-west2"
 	peerASN                  = 64513
 	psaRangeName             = "testpsarange"
-	psaRange                 = "10.0.64.0/20"
 	tunnel1BGPPeerASNAddress = "169.254.1.1"
 	tunnel1SharedSecret      = "secret1"
 	tunnel2BGPPeerASNAddress = "169.254.2.1"
@@ -45,28 +50,29 @@ const (
 )
 
 var (
-	projectID          = os.Getenv("TF_VAR_project_id")
-	uniqueID           = rand.Int() //included as a suffix to the VPC and subnet names.
-	networkName        = fmt.Sprintf("test-vpc-existing-%d", uniqueID)
-	subnetworkName     = fmt.Sprintf("test-subnet-existing-%d", uniqueID)
-	subnetworkIPCIDR   = "10.0.0.0/24"
+	env, envErr        = profile.Current()
+	projectID          = env.Projects.Endpoint
+	region             = env.Regions.Networking
 	createInterconnect = true
+	// The names below are derived from the run and the test by setNames.
+	networkName    string
+	subnetworkName string
 )
 
 // Name of the deployed dedicated interconnect received after deploying the resource in the test lab
 // e.g. dedicated-ix-vpn-client-0
-var deployedInterconnectName = os.Getenv("deployed_interconnect_name")
+var deployedInterconnectName = env.Lab.DeployedInterconnect
 
 // Variables for Interconnect configuration.
-var interconnectProjectID = os.Getenv("TF_VAR_interconnect_project_id")
+var interconnectProjectID = env.Projects.Interconnect
 
-var zone = "us-west2-a"
+var zone = env.Zones.Interconnect
 
 var deletionProtection = false
 
 // Variables for Interconnect configuration.
-var firstInterconnectName = "cso-lab-interconnect-1"
-var secondInterconnectName = "cso-lab-interconnect-2"
+var firstInterconnectName = env.Lab.Interconnect(0)
+var secondInterconnectName = env.Lab.Interconnect(1)
 var userSpecifiedIPRange = []string{"0.0.0.0/0", "199.36.154.8/30"}
 
 // First vlan attachment configuration values.
@@ -79,6 +85,18 @@ var secondVaAsn = "65418"
 var secondVlanAttachmentName = "vlan-attachment-b"
 var secondVaBandwidth = "BPS_1G"
 
+// TestMain checks the APIs, roles and quota the suite needs before any
+// network is created.
+func TestMain(m *testing.M) {
+	os.Exit(preflight.Run(m, preflight.Requirements{
+		Profile: envErr,
+		Project: projectID,
+		APIs:    []string{"compute.googleapis.com", "servicenetworking.googleapis.com", "networkconnectivity.googleapis.com"},
+		Roles:   []string{"roles/compute.networkAdmin", "roles/servicenetworking.networksAdmin", "roles/networkconnectivity.hubAdmin"},
+		Quotas:  []preflight.Quota{{Metric: "NETWORKS", Need: 2}, {Metric: "ROUTERS", Need: 2}},
+	}))
+}
+
 /*
 This test creates all the resources including the vpc network, subnetwork along with a PSA range.
 
@@ -88,13 +106,14 @@ It then validates if
 3. PSA range is created
 */
 func TestCreateVPCNetworkModule(t *testing.T) {
-	//wait for 60 seconds to allow resources to be available
-	time.Sleep(60 * time.Second)
-
+	profile.Require(t, "projects.endpoint")
+	fakeapi.Use(t)
 	var (
-		networkName    = fmt.Sprintf("test-vpc-new-%d", uniqueID)
-		subnetworkName = fmt.Sprintf("test-subnet-new-%d", uniqueID)
-		tfVars         = map[string]any{
+		networkName      = naming.Name(t, naming.Compute, "test-vpc-new")
+		subnetworkName   = naming.Name(t, naming.Compute, "test-subnet-new")
+		subnetworkIPCIDR = ipam.Subnet(t, 24)
+		psaRange         = ipam.PSARange(t, 20)
+		tfVars           = map[string]any{
 			"project_id":             projectID,
 			"region":                 region,
 			"create_network":         true,
@@ -122,7 +141,7 @@ func TestCreateVPCNetworkModule(t *testing.T) {
 		}
 	)
 
-	terraformOptions := terraform.WithDefaultRetryableErrors(t, &terraform.Options{
+	terraformOptions := retryable.With(t, &terraform.Options{
 		// Set the path to the Terraform code that will be tested.
 		Vars:                 tfVars,
 		TerraformDir:         terraformDirectoryPath,
@@ -139,8 +158,8 @@ func TestCreateVPCNetworkModule(t *testing.T) {
 	// Run "terraform init" and "terraform apply". Fail the test if there are any errors.
 	terraform.InitAndApply(t, terraformOptions)
 
-	// Wait for 60 seconds to let resource acheive stable state
-	time.Sleep(60 * time.Second)
+	// Wait for the subnetwork to be ready before verifying.
+	wait.For(t, "subnetwork "+subnetworkName+" to be ready", wait.DefaultOptions, wait.SubnetReady(t, gcloud.New(projectID), region, subnetworkName))
 
 	// Run `terraform output` to get the values of output variables and check they have the expected values.
 	want := networkName
@@ -205,10 +224,13 @@ It then validates if
 3. PSA range is created.
 */
 func TestExistingVPCNetworkModule(t *testing.T) {
-	// wait for 60 seconds to allow resources to be available.
-	time.Sleep(60 * time.Second)
+	profile.Require(t, "projects.endpoint")
+	fakeapi.Use(t)
+	setNames(t)
 	var (
-		tfVars = map[string]any{
+		subnetworkIPCIDR = ipam.Subnet(t, 24)
+		psaRange         = ipam.PSARange(t, 20)
+		tfVars           = map[string]any{
 			"project_id":             projectID,
 			"region":                 region,
 			"create_network":         false,
@@ -236,7 +258,7 @@ func TestExistingVPCNetworkModule(t *testing.T) {
 		}
 	)
 
-	terraformOptions := terraform.WithDefaultRetryableErrors(t, &terraform.Options{
+	terraformOptions := retryable.With(t, &terraform.Options{
 		// Set the path to the Terraform code that will be tested.
 		Vars:                 tfVars,
 		TerraformDir:         terraformDirectoryPath,
@@ -246,20 +268,18 @@ func TestExistingVPCNetworkModule(t *testing.T) {
 		SetVarsAfterVarFiles: true,
 	})
 
-	// Create VPC and subnet outside of the terraform module.
-	common_utils.CreateVPCSubnets(t, projectID, networkName, subnetworkName, region)
-
-	// Delete VPC and subnet created outside of the terraform module.
-	defer common_utils.DeleteVPCSubnets(t, projectID, networkName, subnetworkName, region)
+	// Create VPC and subnet outside of the terraform module. They are deleted
+	// at the end of the test, after "terraform destroy".
+	vpc := common_utils.CreateVPCSubnets(t, projectID, networkName, subnetworkName, region)
 
 	// Clean up resources with "terraform destroy" at the end of the test.
-	defer terraform.Destroy(t, terraformOptions)
+	fixture.Terraform(t, terraformOptions, vpc.Subnetwork)
 
 	// Run "terraform init" and "terraform apply". Fail the test if there are any errors.
 	terraform.InitAndApply(t, terraformOptions)
 
-	// wait for 60 seconds to let resource acheive stable state.
-	time.Sleep(60 * time.Second)
+	// Wait for the network to be readable before verifying.
+	wait.For(t, "network "+networkName+" to exist", wait.DefaultOptions, wait.NetworkExists(t, gcloud.New(projectID), networkName))
 
 	// Run `terraform output` to get the values of output variables and check they have the expected values.
 	want := networkName
@@ -273,7 +293,7 @@ func TestExistingVPCNetworkModule(t *testing.T) {
 	// Create SCP outside of terraform
 	defaultServiceClass := "gcp-memorystore-redis"
 	policyName := fmt.Sprintf("SCP-%s-%s", networkName, defaultServiceClass)
-	common_utils.CreateServiceConnectionPolicy(t, projectID, region, networkName, policyName, subnetworkName, defaultServiceClass, 5) //Pass the correct parameters
+	common_utils.CreateServiceConnectionPolicy(t, projectID, region, networkName, policyName, subnetworkName, defaultServiceClass, 5, vpc.Subnetwork) //Pass the correct parameters
 
 	t.Logf("======= Verify Service Connection Policy (Terraform Output) =======")
 	output := gjson.Parse(terraform.OutputJson(t, terraformOptions, "service_connection_policy_details"))
@@ -284,37 +304,20 @@ func TestExistingVPCNetworkModule(t *testing.T) {
 	t.Logf("======= Verify Service Connection Policy using gcloud =======")
 
 	// Check if policy exists using gcloud describe
-	scpOutput, err := shell.RunCommandAndGetOutputE(t, shell.Command{
-		Command: "gcloud",
-		Args: []string{
-			"network-connectivity", "service-connection-policies", "describe", policyName,
-			"--project", projectID,
-			"--region", region,
-			"--verbosity=none",
-			"--format", "json", // Format output as JSON for easy parsing
-		},
-	})
-
+	policyDetails, err := gcloud.New(projectID).ServiceConnectionPolicies.Describe(t, region, policyName)
 	if err != nil {
 		t.Errorf("Error: Service Connection Policy '%s' not found or could not be described: %s", policyName, err)
 	}
 
-	// Validate if valid JSON received
-	if !gjson.Valid(scpOutput) {
-		t.Errorf("Error parsing output, invalid json: %s", scpOutput)
-	}
-	// Parse gcloud output using gjson
-	policyDetails := gjson.Parse(scpOutput)
-
 	expectedPolicyName := fmt.Sprintf("projects/%s/locations/%s/serviceConnectionPolicies/%s", projectID, region, policyName)
-	gotPolicyName := gjson.Get(policyDetails.String(), "name").String()
+	gotPolicyName := policyDetails.Name
 	// Check if policy details are as expected
 	if gotPolicyName != expectedPolicyName {
 		t.Errorf("Service Connection Policy name mismatch: got %s, want %s", gotPolicyName, expectedPolicyName)
 	} else {
 		t.Logf("=============== Service Connection Policy '%s' verified successfully.================", gotPolicyName)
 	}
-	gotNetworkName := gjson.Get(policyDetails.String(), "network").String()
+	gotNetworkName := policyDetails.Network
 	expectedNetworkName := fmt.Sprintf("projects/%s/global/networks/%s", projectID, networkName)
 	if gotNetworkName != expectedNetworkName {
 		t.Errorf("Service Connection Policy network mismatch: got %s, want %s", gotNetworkName, expectedNetworkName)
@@ -325,10 +328,10 @@ func TestExistingVPCNetworkModule(t *testing.T) {
 	//Get self link of the existing subnet you created manually
 	existingSubnetSelfLink := fmt.Sprintf("projects/%s/regions/%s/subnetworks/%s", projectID, region, subnetworkName)
 
-	subnets := policyDetails.Get("pscConfig.subnetworks")
-	if len(subnets.Array()) > 0 {
-		if subnets.Array()[0].String() != existingSubnetSelfLink {
-			t.Errorf("Service Connection Policy subnetwork mismatch: got %s, want %s", subnets.Array()[0].String(), existingSubnetSelfLink)
+	subnets := policyDetails.PSCConfig.Subnetworks
+	if len(subnets) > 0 {
+		if subnets[0] != existingSubnetSelfLink {
+			t.Errorf("Service Connection Policy subnetwork mismatch: got %s, want %s", subnets[0], existingSubnetSelfLink)
 		}
 	} else {
 		t.Log("No subnets specified in Service Connection Policy, which is acceptable in this test scenario.")
@@ -355,19 +358,21 @@ func TestExistingVPCNetworkModule(t *testing.T) {
 interconnect.tf example by creating a new vpc and a new subnet.
 */
 func TestInterconnectWithVPCCreation(t *testing.T) {
-	if deployedInterconnectName == "" {
-		t.Skip("Skipping Interconnect testing.")
-	}
+	// Labs without a deployed interconnect skip the test.
+	profile.Require(t, "projects.endpoint", "lab.deployed_interconnect")
+	setNames(t)
 	deploymentNumber, err := strconv.Atoi(deployedInterconnectName[len(deployedInterconnectName)-1:])
 	if err != nil {
 		t.Errorf("Deployment number is not an int, using default value for deployment number.")
 		deploymentNumber = 1
 	}
 	var icRouterBgpAsn = 65000 + deploymentNumber
-	var firstVaBgpRange = fmt.Sprintf("169.254.6%d.0/29", deploymentNumber)
+	var firstVaBgpRange = ipam.BGPRange(t)
 	var firstVlanTag = 600 + deploymentNumber
-	var secondVaBgpRange = fmt.Sprintf("169.254.6%d.8/29", deploymentNumber)
+	var secondVaBgpRange = ipam.BGPRange(t)
 	var secondVlanTag = 600 + deploymentNumber
+	var subnetworkIPCIDR = ipam.Subnet(t, 24)
+	var psaRange = ipam.PSARange(t, 20)
 	var tfVars = map[string]any{
 		"project_id":          projectID,
 		"region":              region,
@@ -410,7 +415,7 @@ func TestInterconnectWithVPCCreation(t *testing.T) {
 		"second_vlan_tag":              secondVlanTag,
 	}
 
-	terraformOptions := terraform.WithDefaultRetryableErrors(t, &terraform.Options{
+	terraformOptions := retryable.With(t, &terraform.Options{
 		// Set the path to the Terraform code that will be tested.
 		TerraformDir:         terraformDirectoryPath,
 		Vars:                 tfVars,
@@ -426,19 +431,21 @@ func TestInterconnectWithVPCCreation(t *testing.T) {
 TestInterconnectWithoutVPCCreation tests the creation of example by using the existing vpc and  subnet.
 */
 func TestInterconnectWithoutVPCCreation(t *testing.T) {
-	if deployedInterconnectName == "" {
-		t.Skip("Skipping Interconnect testing.")
-	}
+	// Labs without a deployed interconnect skip the test.
+	profile.Require(t, "projects.endpoint", "lab.deployed_interconnect")
+	setNames(t)
 	deploymentNumber, err := strconv.Atoi(deployedInterconnectName[len(deployedInterconnectName)-1:])
 	if err != nil {
 		t.Errorf("Deployment number is not an int, using default value for deployment number.")
 		deploymentNumber = 1
 	}
 	var icRouterBgpAsn = 65000 + deploymentNumber
-	var firstVaBgpRange = fmt.Sprintf("169.254.6%d.0/29", deploymentNumber)
+	var firstVaBgpRange = ipam.BGPRange(t)
 	var firstVlanTag = 600 + deploymentNumber
-	var secondVaBgpRange = fmt.Sprintf("169.254.6%d.8/29", deploymentNumber)
+	var secondVaBgpRange = ipam.BGPRange(t)
 	var secondVlanTag = 600 + deploymentNumber
+	var subnetworkIPCIDR = ipam.Subnet(t, 24)
+	var psaRange = ipam.PSARange(t, 20)
 	ProjectID := projectID
 	var tfVars = map[string]any{
 		"project_id":          projectID,
@@ -482,7 +489,7 @@ func TestInterconnectWithoutVPCCreation(t *testing.T) {
 		"second_vlan_tag":              secondVlanTag,
 	}
 
-	terraformOptions := terraform.WithDefaultRetryableErrors(t, &terraform.Options{
+	terraformOptions := retryable.With(t, &terraform.Options{
 		// Set the path to the Terraform code that will be tested.
 		TerraformDir:         terraformDirectoryPath,
 		Vars:                 tfVars,
@@ -491,28 +498,37 @@ func TestInterconnectWithoutVPCCreation(t *testing.T) {
 		NoColor:              true,
 		SetVarsAfterVarFiles: true,
 	})
-	// Create VPC and subnet outside of the terraform module
-
-	text := "compute"
-	cmd := shell.Command{
-		Command: "gcloud",
-		Args:    []string{text, "networks", "create", networkName, "--project=" + ProjectID, "--format=json", "--bgp-routing-mode=global", "--subnet-mode=custom", "--verbosity=none"},
-	}
-	_, err = shell.RunCommandAndGetOutputE(t, cmd)
+	// Create VPC and subnet outside of the terraform module. They are deleted
+	// at the end of the test, after "terraform destroy".
+	client := gcloud.New(ProjectID)
+	network, err := fixture.Network(t, client, networkName, gcloud.NetworkOptions{BGPRoutingMode: "global"})
 	if err != nil {
-		log.Printf("===Error %s Encountered while executing %s", err, text)
-	}
-	cmd = shell.Command{
-		Command: "gcloud",
-		Args:    []string{text, "networks", "subnets", "create", subnetworkName, "--network=" + networkName, "--project=" + ProjectID, "--range=10.0.0.0/24", "--region=" + region, "--format=json", "--enable-private-ip-google-access", "--enable-flow-logs", "--verbosity=none"},
+		t.Fatalf("===Error %s Encountered while creating network %s", err, networkName)
 	}
-	_, err = shell.RunCommandAndGetOutputE(t, cmd)
+	wait.For(t, "network "+networkName+" to exist", wait.DefaultOptions, wait.NetworkExists(t, client, networkName))
+	_, err = fixture.Subnet(t, client, subnetworkName, gcloud.SubnetOptions{
+		Network:                     networkName,
+		Region:                      region,
+		Range:                       subnetworkIPCIDR,
+		EnablePrivateIPGoogleAccess: true,
+		EnableFlowLogs:              true,
+	}, network)
 	if err != nil {
-		log.Printf("===Error %s Encountered while executing %s", err, text)
+		t.Fatalf("===Error %s Encountered while creating subnetwork %s", err, subnetworkName)
 	}
+	wait.For(t, "subnetwork "+subnetworkName+" to be READY", wait.DefaultOptions, wait.SubnetReady(t, client, region, subnetworkName))
 	initiateTestForNetworkResource(t, terraformOptions, firstVlanTag)
 }
 
+/*
+setNames is a helper function which derives the names of the VPC and subnet
+of the test from the run and the test.
+*/
+func setNames(t *testing.T) {
+	networkName = naming.Name(t, naming.Compute, "test-vpc-existing")
+	subnetworkName = naming.Name(t, naming.Compute, "test-subnet-existing")
+}
+
 /*
 	initiateTestForNetworkResource is a helper function that helps in verification
 
@@ -527,8 +543,17 @@ func initiateTestForNetworkResource(t *testing.T, terraformOptions *terraform.Op
 	// Run "terraform init" and "terraform apply". Fail the test if there are any errors.
 	terraform.InitAndApply(t, terraformOptions)
 
-	// Wait for 60 seconds to let resource achieve stable state.
-	time.Sleep(60 * time.Second)
+	// Wait for the VLAN attachments to become active before verifying.
+	client := gcloud.New(projectID)
+	for _, vlanAttachmentName := range []string{firstVlanAttachmentName, secondVlanAttachmentName} {
+		wait.For(t, "VLAN attachment "+vlanAttachmentName+" to be active", wait.DefaultOptions, wait.State(func() (string, error) {
+			var attachment struct {
+				State string `json:"state"`
+			}
+			err := client.RunJSON(t, &attachment, "compute", "interconnects", "attachments", "describe", vlanAttachmentName, "--region="+region)
+			return attachment.State, err
+		}, "ACTIVE"))
+	}
 
 	log.Println(" ========= Verify Subnet Name ========= ")
 	want := networkName
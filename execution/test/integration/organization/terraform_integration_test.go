@@ -19,8 +19,12 @@ import (
 	"fmt"
 	"os"
 	"testing"
-	"time"
 
+	"github.com/GoogleCloudPlatform/cloudnetworking-config-solutions/common_utils/gcloud"
+	"github.com/GoogleCloudPlatform/cloudnetworking-config-solutions/common_utils/preflight"
+	"github.com/GoogleCloudPlatform/cloudnetworking-config-solutions/common_utils/profile"
+	"github.com/GoogleCloudPlatform/cloudnetworking-config-solutions/common_utils/retryable"
+	"github.com/GoogleCloudPlatform/cloudnetworking-config-solutions/common_utils/wait"
 	"github.com/google/go-cmp/cmp"
 	"github.com/google/go-cmp/cmp/cmpopts"
 	"github.com/gruntwork-io/terratest/modules/terraform"
@@ -28,7 +32,8 @@ import (
 )
 
 var (
-	projectID              = os.Getenv("TF_VAR_project_id")
+	env, envErr            = profile.Current()
+	projectID              = env.Projects.Endpoint
 	terraformDirectoryPath = "../../../01-organization"
 	apisList               = []string{
 		"aiplatform.googleapis.com",
@@ -52,13 +57,25 @@ var (
 	}
 )
 
+// TestMain checks the APIs and roles the suite needs before the stage enables
+// the APIs of the project.
+func TestMain(m *testing.M) {
+	os.Exit(preflight.Run(m, preflight.Requirements{
+		Profile: envErr,
+		Project: projectID,
+		APIs:    []string{"serviceusage.googleapis.com", "cloudresourcemanager.googleapis.com"},
+		Roles:   []string{"roles/serviceusage.serviceUsageAdmin"},
+	}))
+}
+
 /*
 This test validates if
 1. Correct Project ID is used.
 2. List of Project API's has been enabled.
 */
 func TestEnableAPI(t *testing.T) {
-	terraformOptions := terraform.WithDefaultRetryableErrors(t, &terraform.Options{
+	profile.Require(t, "projects.endpoint")
+	terraformOptions := retryable.With(t, &terraform.Options{
 		// Set the path to the Terraform code that will be tested.
 		Vars:                 tfVars,
 		TerraformDir:         terraformDirectoryPath,
@@ -74,8 +91,9 @@ func TestEnableAPI(t *testing.T) {
 	// Run "terraform init" and "terraform apply". Fail the test if there are any errors.
 	terraform.InitAndApply(t, terraformOptions)
 
-	// Wait for 60 seconds to let resource acheive stable state.
-	time.Sleep(60 * time.Second)
+	// Wait for every API to be reported as enabled before verifying.
+	client := gcloud.New(projectID)
+	wait.For(t, "APIs to be enabled", wait.DefaultOptions, wait.APIsEnabled(t, client, apisList...))
 
 	// Run `terraform output` to get the values of output variables and check they have the expected values.
 	activateAPIOutputValue := terraform.OutputJson(t, terraformOptions, "activated_api_identities")
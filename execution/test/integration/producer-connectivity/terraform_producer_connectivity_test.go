@@ -17,13 +17,21 @@ package integrationtest
 import (
 	"fmt"
 	"log"
-	"math/rand"
 	"os"
 	"os/exec"
 	"strings"
 	"testing"
 	"time"
 
+	"github.com/GoogleCloudPlatform/cloudnetworking-config-solutions/common_utils/fixture"
+	"github.com/GoogleCloudPlatform/cloudnetworking-config-solutions/common_utils/gcloud"
+	"github.com/GoogleCloudPlatform/cloudnetworking-config-solutions/common_utils/ipam"
+	"github.com/GoogleCloudPlatform/cloudnetworking-config-solutions/common_utils/naming"
+	"github.com/GoogleCloudPlatform/cloudnetworking-config-solutions/common_utils/preflight"
+	"github.com/GoogleCloudPlatform/cloudnetworking-config-solutions/common_utils/probe"
+	"github.com/GoogleCloudPlatform/cloudnetworking-config-solutions/common_utils/profile"
+	"github.com/GoogleCloudPlatform/cloudnetworking-config-solutions/common_utils/retryable"
+	"github.com/GoogleCloudPlatform/cloudnetworking-config-solutions/common_utils/wait"
 	"github.com/gruntwork-io/terratest/modules/terraform"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -39,6 +47,8 @@ type Producer struct {
 	GetReadyState         func(t *testing.T, name, projectID, region string) (string, error)
 	ExpectedReadyState    string
 	TerraformProducerKey  string
+	// Port is the port the producer serves on behind its PSC endpoint.
+	Port int
 }
 
 // producersToTest is the list of all producers to be tested.
@@ -48,6 +58,7 @@ var producersToTest = map[string]Producer{
 		Name:                 "cloudsql",
 		TerraformProducerKey: "producer_cloudsql",
 		ExpectedReadyState:   "RUNNABLE",
+		Port:                 3306,
 		GetCreateArgs: func(name, projectID, allowedProjects, region, networkName string) []string {
 			return []string{"sql", "instances", "create", name,
 				"--project=" + projectID, "--database-version=MYSQL_8_0", "--region=" + region,
@@ -82,13 +93,24 @@ const (
 
 // Global variables for test configuration.
 var (
-	ipAddressLiteral           = "10.10.10.30"
-	ipAddressLiteralWithTarget = "10.10.10.31"
-	region                     = "us-central1"
+	env, envErr = profile.Current()
+	region      = env.Regions.Default
 )
 
+// TestMain checks the APIs, roles and quota the suite needs before the
+// producers and their endpoints are created.
+func TestMain(m *testing.M) {
+	os.Exit(preflight.Run(m, preflight.Requirements{
+		Profile: envErr,
+		Project: env.Projects.Endpoint,
+		APIs:    []string{"sqladmin.googleapis.com", "compute.googleapis.com"},
+		Roles:   []string{"roles/cloudsql.admin", "roles/compute.networkAdmin", "roles/compute.instanceAdmin.v1", "roles/iap.tunnelResourceAccessor"},
+		Quotas:  []preflight.Quota{{Metric: "NETWORKS"}, {Metric: "INSTANCES", Region: region}},
+	}))
+}
+
 // runGcloudCommand executes a gcloud command and streams its output for logging.
-func runGcloudCommand(_ *testing.T, args ...string) error {
+func runGcloudCommand(t *testing.T, args ...string) error {
 	command := "gcloud"
 	commandArgs := args
 	if len(args) > 0 && args[0] == "bash" {
@@ -99,19 +121,21 @@ func runGcloudCommand(_ *testing.T, args ...string) error {
 	log.Printf("Running command: %s %s", command, strings.Join(commandArgs, " "))
 
 	var output []byte
-	var err error
-	for i := 0; i < 5; i++ {
-		cmd := exec.Command(command, commandArgs...)
-		output, err = cmd.CombinedOutput()
-		if err == nil {
-			log.Printf("Output:\n%s", string(output))
-			return nil
+	opts := wait.Options{Timeout: 25 * time.Second, Interval: 5 * time.Second, MaxInterval: 5 * time.Second}
+	_, err := wait.ForE(t, command+" "+commandArgs[0]+" to succeed", opts, wait.Check(func() error {
+		var err error
+		output, err = exec.Command(command, commandArgs...).CombinedOutput()
+		if err != nil {
+			log.Printf("Command failed, retrying... Output:\n%s", string(output))
 		}
-		log.Printf("Command failed, retrying in 5 seconds... Output:\n%s", string(output))
-		time.Sleep(5 * time.Second)
+		return err
+	}))
+	if err != nil {
+		log.Printf("Final output after retries:\n%s", string(output))
+		return err
 	}
-	log.Printf("Final output after retries:\n%s", string(output))
-	return err
+	log.Printf("Output:\n%s", string(output))
+	return nil
 }
 
 // runGcloudCommandWithOutput executes a gcloud command and returns its output as a string.
@@ -126,10 +150,12 @@ func runGcloudCommandWithOutput(_ *testing.T, args ...string) (string, error) {
 	return strings.TrimSpace(string(output)), nil
 }
 
-// setupNetwork creates a custom VPC and Subnet in the endpoint project.
-func setupNetwork(t *testing.T, projectID string, uniqueID int) (string, string, func()) {
-	networkName := fmt.Sprintf("test-vpc-%d", uniqueID)
-	subnetworkName := fmt.Sprintf("test-subnet-%d", uniqueID)
+// setupNetwork creates a custom VPC and Subnet in the endpoint project, with
+// a range leased from the ipam package.
+func setupNetwork(t *testing.T, projectID string) (string, string, string, func()) {
+	networkName := naming.Name(t, naming.Compute, "test-vpc")
+	subnetworkName := naming.Name(t, naming.Compute, "test-subnet")
+	subnetworkIPCIDR := ipam.Subnet(t, 24)
 
 	log.Printf("Creating custom VPC network: %s", networkName)
 	err := runGcloudCommand(t, "compute", "networks", "create", networkName,
@@ -143,7 +169,7 @@ func setupNetwork(t *testing.T, projectID string, uniqueID int) (string, string,
 	log.Printf("Creating custom subnetwork: %s", subnetworkName)
 	err = runGcloudCommand(t, "compute", "networks", "subnets", "create", subnetworkName,
 		"--network="+networkName,
-		"--range=10.10.10.0/24",
+		"--range="+subnetworkIPCIDR,
 		"--region="+region,
 		"--project="+projectID,
 	)
@@ -153,46 +179,45 @@ func setupNetwork(t *testing.T, projectID string, uniqueID int) (string, string,
 		runGcloudCommand(t, "compute", "networks", "subnets", "delete", subnetworkName, "--region="+region, "--project="+projectID, "--quiet")
 		runGcloudCommand(t, "compute", "networks", "delete", networkName, "--project="+projectID, "--quiet")
 	}
-	return networkName, subnetworkName, cleanupFunc
+	return networkName, subnetworkName, subnetworkIPCIDR, cleanupFunc
 }
 
 // waitForProducer polls the status of a producer instance until it reaches the expected ready state.
 func waitForProducer(t *testing.T, producer Producer, projectID, instanceName, region string) {
-	log.Printf("Waiting for %s instance %s to be %s...", producer.Name, instanceName, producer.ExpectedReadyState)
-	for i := 0; i < 30; i++ { // Wait for up to 30 minutes.
+	description := fmt.Sprintf("%s instance %s to be %s", producer.Name, instanceName, producer.ExpectedReadyState)
+	wait.For(t, description, wait.LongOptions, wait.Check(func() error {
 		status, err := producer.GetReadyState(t, instanceName, projectID, region)
-		if err == nil && status == producer.ExpectedReadyState {
-			log.Printf("%s instance %s is %s.", producer.Name, instanceName, producer.ExpectedReadyState)
-			return
+		if err != nil {
+			return err
 		}
-		log.Printf("Instance %s not ready yet (current state: %s), waiting 60 seconds...", instanceName, status)
-		time.Sleep(60 * time.Second)
-	}
-	t.Fatalf("Timed out waiting for %s instance %s to become %s.", producer.Name, instanceName, producer.ExpectedReadyState)
+		if status != producer.ExpectedReadyState {
+			return fmt.Errorf("current state: %s", status)
+		}
+		return nil
+	}))
 }
 
-// getEndpointProjectID retrieves the mandatory endpoint project ID from an environment variable.
+// getEndpointProjectID retrieves the mandatory endpoint project ID from the test profile.
 func getEndpointProjectID(t *testing.T) string {
-	projectID := os.Getenv("TF_VAR_endpoint_project_id")
-	require.NotEmpty(t, projectID, "Environment variable 'TF_VAR_endpoint_project_id' must be set")
-	return projectID
+	return profile.Require(t, "projects.endpoint").Projects.Endpoint
 }
 
 // getProducerProjectID retrieves the optional producer project ID, defaulting to the endpoint project ID.
 func getProducerProjectID(_ *testing.T, endpointProjectID string) string {
-	producerProjectID := os.Getenv("TF_VAR_producer_project_id")
+	producerProjectID := env.Projects.Producer
 	if producerProjectID == "" {
-		log.Printf("TF_VAR_producer_project_id not set, defaulting to endpoint_project_id: %s", endpointProjectID)
+		log.Printf("projects.producer not set in the test profile, defaulting to endpoint_project_id: %s", endpointProjectID)
 		return endpointProjectID
 	}
-	log.Printf("Using producer project ID from environment variable: %s", producerProjectID)
+	log.Printf("Using producer project ID from the test profile: %s", producerProjectID)
 	return producerProjectID
 }
 
 // TestPlanFailsWithoutVars tests that the Terraform plan fails when required input variables are missing.
 func TestPlanFailsWithoutVars(t *testing.T) {
+	profile.Require(t, "projects.endpoint")
 	t.Parallel()
-	terraformOptions := terraform.WithDefaultRetryableErrors(t, &terraform.Options{
+	terraformOptions := retryable.With(t, &terraform.Options{
 		TerraformDir: terraformDirectoryPath, Reconfigure: true, Lock: true, NoColor: true,
 	})
 	_, err := terraform.InitAndPlanE(t, terraformOptions)
@@ -201,6 +226,7 @@ func TestPlanFailsWithoutVars(t *testing.T) {
 
 // TestProducerConnectivity is the main test function that orchestrates all test cases.
 func TestProducerConnectivity(t *testing.T) {
+	profile.Require(t, "projects.endpoint")
 	t.Parallel()
 	endpointProjectID := getEndpointProjectID(t)
 	producerProjectID := getProducerProjectID(t, endpointProjectID)
@@ -213,12 +239,18 @@ func TestProducerConnectivity(t *testing.T) {
 			t.Parallel()
 
 			// ONE-TIME SETUP: Create producer instance and network once per producer type.
-			rand.Seed(time.Now().UnixNano())
-			uniqueID := rand.Intn(10000)
-			dynamicInstanceName := fmt.Sprintf("test-%s-psc-%d", producer.Name, uniqueID)
-
-			networkName, subnetworkName, cleanupNetwork := setupNetwork(t, endpointProjectID, uniqueID)
-			defer cleanupNetwork()
+			dynamicInstanceName := naming.Name(t, naming.Compute, fmt.Sprintf("test-%s-psc", producer.Name))
+
+			networkName, subnetworkName, subnetworkIPCIDR, cleanupNetwork := setupNetwork(t, endpointProjectID)
+			// The network is removed when the test ends, after the client VM,
+			// which is a fixture that depends on it.
+			network := fixture.Register(t, fixture.Resource{Kind: "network", ID: fmt.Sprintf("projects/%s/global/networks/%s", endpointProjectID, networkName)}, func() error {
+				cleanupNetwork()
+				return nil
+			})
+			// The endpoints with a provided address take fixed hosts of the subnetwork.
+			ipAddressLiteral := ipam.Host(subnetworkIPCIDR, 30)
+			ipAddressLiteralWithTarget := ipam.Host(subnetworkIPCIDR, 31)
 
 			createArgs := producer.GetCreateArgs(dynamicInstanceName, producerProjectID, endpointProjectID, region, networkName)
 			// 'err' is declared for the first time here.
@@ -234,19 +266,29 @@ func TestProducerConnectivity(t *testing.T) {
 			waitForProducer(t, producer, producerProjectID, dynamicInstanceName, region)
 
 			var serviceAttachment string
-			// The existing 'err' variable from above will be reused.
-			for i := 0; i < 4; i++ {
+			opts := wait.Options{Timeout: time.Minute, Interval: 15 * time.Second, MaxInterval: 15 * time.Second}
+			_, err = wait.ForE(t, "service attachment link of "+dynamicInstanceName, opts, wait.Check(func() error {
 				describeArgs := producer.GetDescribeAttachArgs(dynamicInstanceName, producerProjectID, region)
+				var err error
 				serviceAttachment, err = runGcloudCommandWithOutput(t, describeArgs...)
-				if err == nil && serviceAttachment != "" {
-					break // Success
+				if err == nil && serviceAttachment == "" {
+					return fmt.Errorf("service attachment link not yet available")
 				}
-				log.Printf("Service attachment link not yet available for %s, retrying in 15 seconds...", dynamicInstanceName)
-				time.Sleep(15 * time.Second)
-			}
+				return err
+			}))
 			require.NoError(t, err, "Failed to get service attachment link after retries")
 			require.NotEmpty(t, serviceAttachment, "Service attachment link was empty after retries")
 
+			// The endpoints are probed from a client VM in the subnetwork.
+			client := gcloud.New(endpointProjectID)
+			client.Labels = naming.Labels(t)
+			vm, err := probe.NewVM(t, client, probe.VMOptions{
+				Zone:    env.Zones.Default,
+				Network: networkName,
+				Subnet:  subnetworkName,
+			}, network)
+			require.NoError(t, err, "Failed to create the client VM")
+
 			// === RUN TEST VARIATIONS AGAINST THE CREATED PRODUCER ===
 			// These sub-tests run SEQUENTIALLY to avoid race conditions.
 
@@ -263,10 +305,11 @@ func TestProducerConnectivity(t *testing.T) {
 						producer.TerraformProducerKey:  producer.GetTerraformBlock(dynamicInstanceName),
 					}},
 				}
-				tfOptions := terraform.WithDefaultRetryableErrors(t, &terraform.Options{TerraformDir: terraformDirectoryPath, Vars: tfVars})
+				tfOptions := retryable.With(t, &terraform.Options{TerraformDir: terraformDirectoryPath, Vars: tfVars})
 				defer terraform.Destroy(t, tfOptions)
 				terraform.InitAndApply(t, tfOptions)
 				assertOutputs(t, tfOptions, producer.TerraformProducerKey)
+				assertReachable(t, vm, tfOptions, producer.Port)
 			})
 
 			// Test Case 2: With an auto-allocated IP address
@@ -282,10 +325,11 @@ func TestProducerConnectivity(t *testing.T) {
 						producer.TerraformProducerKey:  producer.GetTerraformBlock(dynamicInstanceName),
 					}},
 				}
-				tfOptions := terraform.WithDefaultRetryableErrors(t, &terraform.Options{TerraformDir: terraformDirectoryPath, Vars: tfVars})
+				tfOptions := retryable.With(t, &terraform.Options{TerraformDir: terraformDirectoryPath, Vars: tfVars})
 				defer terraform.Destroy(t, tfOptions)
 				terraform.InitAndApply(t, tfOptions)
 				assertOutputsForAutoAllocatedIPAddress(t, tfOptions, producer.TerraformProducerKey)
+				assertReachable(t, vm, tfOptions, producer.Port)
 			})
 
 			// Test Case 3: With a direct service attachment target
@@ -301,10 +345,11 @@ func TestProducerConnectivity(t *testing.T) {
 						"target":                       serviceAttachment, // Use the pre-fetched target.
 					}},
 				}
-				tfOptions := terraform.WithDefaultRetryableErrors(t, &terraform.Options{TerraformDir: terraformDirectoryPath, Vars: tfVars})
+				tfOptions := retryable.With(t, &terraform.Options{TerraformDir: terraformDirectoryPath, Vars: tfVars})
 				defer terraform.Destroy(t, tfOptions)
 				terraform.InitAndApply(t, tfOptions)
 				assertOutputsWithTarget(t, tfOptions, serviceAttachment)
+				assertReachable(t, vm, tfOptions, producer.Port)
 			})
 		})
 	}
@@ -363,3 +408,13 @@ func assertOutputsWithTarget(t *testing.T, tfOptions *terraform.Options, expecte
 	assert.NotNil(t, actualIPAddress, "IP address is nil")
 	assert.Equal(t, expectedTarget, actualTarget, "Target mismatch")
 }
+
+// assertReachable checks that the producer answers on port behind the endpoint
+// from the client VM.
+func assertReachable(t *testing.T, vm *probe.VM, tfOptions *terraform.Options, port int) {
+	actualIPAddress := terraform.OutputMap(t, tfOptions, "ip_address_literal")["0"]
+	results, err := vm.Eventually(t, wait.DefaultOptions, true, probe.TCP(actualIPAddress, port))
+	if assert.NoError(t, err, "Producer is not reachable through the endpoint") {
+		log.Printf("Producer is reachable through the endpoint: %s", results[0])
+	}
+}
@@ -15,15 +15,23 @@ package integrationtest
 
 import (
 	"fmt"
-	"math/rand"
 	"os"
 	"path"
 	"reflect"
 	stdlib_strconv "strconv"
 	"strings"
 	"testing"
-	"time"
 
+	"github.com/GoogleCloudPlatform/cloudnetworking-config-solutions/common_utils/fixture"
+	"github.com/GoogleCloudPlatform/cloudnetworking-config-solutions/common_utils/gcloud"
+	"github.com/GoogleCloudPlatform/cloudnetworking-config-solutions/common_utils/ipam"
+	"github.com/GoogleCloudPlatform/cloudnetworking-config-solutions/common_utils/naming"
+	"github.com/GoogleCloudPlatform/cloudnetworking-config-solutions/common_utils/preflight"
+	"github.com/GoogleCloudPlatform/cloudnetworking-config-solutions/common_utils/probe"
+	"github.com/GoogleCloudPlatform/cloudnetworking-config-solutions/common_utils/profile"
+	"github.com/GoogleCloudPlatform/cloudnetworking-config-solutions/common_utils/retryable"
+	"github.com/GoogleCloudPlatform/cloudnetworking-config-solutions/common_utils/schema"
+	"github.com/GoogleCloudPlatform/cloudnetworking-config-solutions/common_utils/wait"
 	"github.com/gruntwork-io/terratest/modules/shell"
 	"github.com/gruntwork-io/terratest/modules/terraform"
 	"github.com/tidwall/gjson"
@@ -31,39 +39,30 @@ import (
 )
 
 var (
-	projectID              = os.Getenv("TF_VAR_project_id")
-	region                 = "us-central1"
+	env, envErr            = profile.Current()
+	projectID              = env.Projects.Endpoint
+	region                 = env.Regions.Default
 	terraformDirectoryPath = "../../../../04-producer/AlloyDB"
 	configFolderPath       = "../../test/integration/producer/AlloyDB/config"
-	rangeName              = fmt.Sprintf("psatestrangealloydb-%s", clusterDisplayName)
-	clusterDisplayName     = fmt.Sprint(rand.Int())
-	networkName            = fmt.Sprintf("vpc-%s-test", clusterDisplayName)
-	alloyDBClusterID       = fmt.Sprintf("cid-%s-test", clusterDisplayName)
-	instanceID             = fmt.Sprintf("id-%s-test", clusterDisplayName)
-	networkID              = fmt.Sprintf("projects/%s/global/networks/%s", projectID, networkName)
+	// The names below are derived from the run and the test by setNames.
+	rangeName          string
+	clusterDisplayName string
+	networkName        string
+	alloyDBClusterID   string
+	instanceID         string
+	networkID          string
 )
 
-type PrimaryInstanceStruct struct {
-	InstanceID    string      `yaml:"instance_id"`
-	DisplayName   string      `yaml:"display_name"`
-	InstanceType  string      `yaml:"instance_type"`
-	MachineCPUs   int         `yaml:"machine_cpu_count"`
-	DatabaseFlags interface{} `yaml:"database_flags"`
-}
-
-type AlloyDBStruct struct {
-	ClusterID                  string                `yaml:"cluster_id"`
-	ClusterDisplayName         string                `yaml:"cluster_display_name"`
-	ProjectID                  string                `yaml:"project_id"`
-	Region                     string                `yaml:"region"`
-	NetworkID                  string                `yaml:"network_id"`
-	PrimaryInstance            PrimaryInstanceStruct `yaml:"primary_instance"`
-	AllocatedIPRange           string                `yaml:"allocated_ip_range"`
-	PscAllowedConsumerProjects []string              `yaml:"psc_allowed_consumer_projects"`
-	ConnectivityOptions        string                `yaml:"connectivity_options"`
-	ReadPoolInstance           interface{}           `yaml:"read_pool_instance"`
-	AutomatedBackupPolicy      interface{}           `yaml:"automated_backup_policy"`
-	DeletionProtection         bool                  `yaml:"deletion_protection"`
+// TestMain checks the APIs, roles and quota the suite needs before the network
+// and the cluster are created.
+func TestMain(m *testing.M) {
+	os.Exit(preflight.Run(m, preflight.Requirements{
+		Profile: envErr,
+		Project: projectID,
+		APIs:    []string{"alloydb.googleapis.com", "compute.googleapis.com", "servicenetworking.googleapis.com"},
+		Roles:   []string{"roles/alloydb.admin", "roles/compute.networkAdmin", "roles/servicenetworking.networksAdmin", "roles/compute.instanceAdmin.v1", "roles/iap.tunnelResourceAccessor"},
+		Quotas:  []preflight.Quota{{Metric: "NETWORKS"}, {Metric: "INSTANCES", Region: region}},
+	}))
 }
 
 // getProjectNumber retrieves the project number for a given project ID.
@@ -97,14 +96,14 @@ func getProjectNumber(t *testing.T, projectID string) (string, error) {
 }
 
 // getAttachmentProjectNumber retrieves the project number for the attachment project.
-// If TF_VAR_ATTACHMENT_PROJECT_ID is not set, it defaults to the primary project ID.
+// If projects.attachment is not set in the test profile, it defaults to the primary project ID.
 func getAttachmentProjectNumber(t *testing.T) (string, error) {
-	attachmentProjectID := os.Getenv("TF_VAR_ATTACHMENT_PROJECT_ID")
+	attachmentProjectID := env.Projects.Attachment
 
 	// If attachmentProjectID is not set, use the primary projectID as fallback.
 	if attachmentProjectID == "" {
 		attachmentProjectID = projectID
-		t.Logf("TF_VAR_ATTACHMENT_PROJECT_ID not set. Defaulting to primary project ID: %s", projectID)
+		t.Logf("projects.attachment not set in the test profile. Defaulting to primary project ID: %s", projectID)
 		return getProjectNumber(t, projectID) // Use the global projectID as the fallback
 	}
 
@@ -119,6 +118,8 @@ It then validates if
 3. AlloyDB instance is in ACTIVE state.
 */
 func TestCreateAlloyDB(t *testing.T) {
+	profile.Require(t, "projects.endpoint")
+	setNames(t)
 	// Initialize AlloyDB config YAML files
 	createConfigYAMLs(t)
 
@@ -140,7 +141,7 @@ func TestCreateAlloyDB(t *testing.T) {
 		}
 	)
 
-	terraformOptions := terraform.WithDefaultRetryableErrors(t, &terraform.Options{
+	terraformOptions := retryable.With(t, &terraform.Options{
 		// Set the path to the Terraform code that will be tested.
 		Vars:                 tfVars,
 		TerraformDir:         terraformDirectoryPath,
@@ -150,27 +151,34 @@ func TestCreateAlloyDB(t *testing.T) {
 		SetVarsAfterVarFiles: true,
 	})
 	// Create VPC outside of the terraform module.
-	err = createVPC(t, projectID, networkName)
+	network, err := createVPC(t, projectID, networkName)
 	if err != nil {
 		t.Fatal(err)
 	}
-	// Create PSA in the VPC.
-	createPSA(t, projectID, networkName, rangeName)
-
-	// Delete VPC created outside of the terraform module.
-	defer deleteVPC(t, projectID, networkName)
-
-	// Remove PSA from the VPC.
-	defer deletePSA(t, projectID, networkName, rangeName)
-
+	// Create PSA in the VPC. The PSA and the VPC are removed at the end of the
+	// test, after "terraform destroy".
+	psa := createPSA(t, projectID, networkName, rangeName, network)
 	// Clean up resources with "terraform destroy" at the end of the test.
-	defer terraform.Destroy(t, terraformOptions)
+	fixture.Terraform(t, terraformOptions, psa)
 
 	// Run "terraform init" and "terraform apply". Fail the test if there are any errors.
 	terraform.InitAndApply(t, terraformOptions)
 
-	// Wait for 60 seconds to let resource acheive stable state.
-	time.Sleep(60 * time.Second)
+	// Wait for both primary instances to be READY before verifying, and keep
+	// their private IPs.
+	client := gcloud.New(projectID)
+	instanceIPs := map[string]string{}
+	for clusterID, instance := range map[string]string{alloyDBClusterID: instanceID, alloyDBClusterID + "-psc": instanceID + "-psc"} {
+		wait.For(t, "AlloyDB instance "+instance+" to be READY", wait.LongOptions, wait.State(func() (string, error) {
+			var alloyDBInstance struct {
+				State     string `json:"state"`
+				IPAddress string `json:"ipAddress"`
+			}
+			err := client.RunJSON(t, &alloyDBInstance, "alloydb", "instances", "describe", instance, "--cluster="+clusterID, "--region="+region)
+			instanceIPs[instance] = alloyDBInstance.IPAddress
+			return alloyDBInstance.State, err
+		}, "READY"))
+	}
 
 	// Run `terraform output` to get the values of output variables
 	alloyDBOutputValue := terraform.OutputJson(t, terraformOptions, "cluster_details")
@@ -268,99 +276,97 @@ func TestCreateAlloyDB(t *testing.T) {
 		}
 
 	}
-}
 
-/*
-deleteVPC is a helper function which deletes the VPC after
-completion of the test.
-*/
-func deleteVPC(t *testing.T, projectID string, networkName string) {
-	time.Sleep(60 * time.Second)
-	text := "compute"
-	time.Sleep(60 * time.Second)
-	cmd := shell.Command{
-		Command: "gcloud",
-		Args:    []string{text, "networks", "delete", networkName, "--project=" + projectID, "--quiet"},
-	}
-	_, err := shell.RunCommandAndGetOutputE(t, cmd)
-	if err != nil {
-		t.Errorf("===Error %s Encountered while executing %s", err, text)
+	// The PSC instance is only reachable through an endpoint, which the
+	// producer-connectivity suite covers.
+	t.Log(" ========= Verify the PSA instance is reachable from the VPC ========= ")
+	if ip := instanceIPs[instanceID]; ip == "" {
+		t.Errorf("AlloyDB instance %s has no private IP", instanceID)
+	} else {
+		verifyReachable(t, network, ip)
 	}
 }
 
 /*
-deletePSA is a helper function which deletes the PSA range after the
-execution of the test.
+verifyReachable is a helper function which creates a client VM in a new
+subnetwork of the VPC and checks that the instance answers the PostgreSQL
+protocol on its private IP. The VM and the subnetwork are removed before the
+instance.
 */
-func deletePSA(t *testing.T, projectID string, networkName string, rangeName string) {
-	// Delete PSA IP range
-	time.Sleep(60 * time.Second)
-	text := "compute"
-	cmd := shell.Command{
-		Command: "gcloud",
-		Args:    []string{text, "addresses", "delete", rangeName, "--project=" + projectID, "--global", "--verbosity=none", "--format=json", "--quiet"},
-	}
-	_, err := shell.RunCommandAndGetOutputE(t, cmd)
+func verifyReachable(t *testing.T, network *fixture.Handle, privateIP string) {
+	client := gcloud.New(projectID)
+	client.Labels = naming.Labels(t)
+	subnetName := naming.Name(t, naming.Compute, "subnet-alloydb")
+	subnet, err := fixture.Subnet(t, client, subnetName, gcloud.SubnetOptions{
+		Network: networkName,
+		Region:  region,
+		Range:   ipam.Subnet(t, 28),
+	}, network)
 	if err != nil {
-		t.Errorf("===Error %s Encountered while executing %s", err, text)
+		t.Fatalf("===Error %s Encountered while creating subnetwork %s", err, subnetName)
 	}
-	time.Sleep(60 * time.Second)
-	// Delete PSA range
-	text = "services"
-	cmd = shell.Command{
-		Command: "gcloud",
-		Args:    []string{text, "vpc-peerings", "delete", "--service=servicenetworking.googleapis.com", "--project=" + projectID, "--network=" + networkName, "--verbosity=none", "--format=json", "--quiet"},
+	vm, err := probe.NewVM(t, client, probe.VMOptions{Zone: env.Zones.Default, Network: networkName, Subnet: subnetName}, subnet)
+	if err != nil {
+		t.Fatalf("===Error %s Encountered while creating the client VM", err)
 	}
-	_, err = shell.RunCommandAndGetOutputE(t, cmd)
+	results, err := vm.Eventually(t, wait.DefaultOptions, true, probe.Postgres(privateIP, 5432))
 	if err != nil {
-		t.Errorf("===Error %s Encountered while executing %s", err, text)
+		t.Errorf("AlloyDB instance is not reachable on %s:5432 from the VPC: %v", privateIP, err)
+		return
 	}
+	t.Logf("AlloyDB instance is reachable: %s", results[0])
 }
 
 /*
 createVPC is a helper function which creates the VPC before the
-execution of the test.
+execution of the test. The VPC is deleted when the test ends.
 */
-func createVPC(t *testing.T, projectID string, networkName string) error {
-	text := "compute"
-	cmd := shell.Command{
-		Command: "gcloud",
-		Args:    []string{text, "networks", "create", networkName, "--project=" + projectID, "--format=json", "--bgp-routing-mode=global", "--subnet-mode=custom", "--verbosity=none"},
-	}
-	_, err := shell.RunCommandAndGetOutputE(t, cmd)
+func createVPC(t *testing.T, projectID string, networkName string) (*fixture.Handle, error) {
+	network, err := fixture.Network(t, gcloud.New(projectID), networkName, gcloud.NetworkOptions{BGPRoutingMode: "global"})
 	if err != nil {
-		t.Errorf("===Error %s Encountered while executing %s", err, text)
+		t.Errorf("===Error %s Encountered while creating network %s", err, networkName)
 	}
-	return err
+	return network, err
 }
 
 /*
 createPSA is a helper function which creates the PSA range before the
-execution of the test.
+execution of the test, with a /20 leased from the ipam package. The connection
+and the range are removed when the test ends, before the VPC.
 */
-func createPSA(t *testing.T, projectID string, networkName string, rangeName string) {
+func createPSA(t *testing.T, projectID string, networkName string, rangeName string, network *fixture.Handle) *fixture.Handle {
+	client := gcloud.New(projectID)
 	// Create an IP range
-
-	text := "compute"
-	cmd := shell.Command{
-		Command: "gcloud",
-		Args:    []string{text, "addresses", "create", rangeName, "--purpose=VPC_PEERING", "--addresses=10.0.64.0", "--prefix-length=20", "--project=" + projectID, "--network=" + networkName, "--global", "--verbosity=none", "--format=json"},
-	}
-	_, err := shell.RunCommandAndGetOutputE(t, cmd)
+	psaRange := ipam.PSARange(t, 20)
+	address, err := fixture.Address(t, client, rangeName, gcloud.AddressOptions{
+		Purpose:      "VPC_PEERING",
+		Address:      ipam.Host(psaRange, 0),
+		PrefixLength: 20,
+		Network:      networkName,
+	}, network)
 	if err != nil {
-		t.Errorf("===Error %s Encountered while executing %s", err, text)
+		t.Errorf("===Error %s Encountered while creating address %s", err, rangeName)
 	}
-
 	// Create PSA range
-	text = "services"
-	cmd = shell.Command{
-		Command: "gcloud",
-		Args:    []string{text, "vpc-peerings", "connect", "--service=servicenetworking.googleapis.com", "--ranges=" + rangeName, "--project=" + projectID, "--network=" + networkName, "--verbosity=none", "--format=json"},
-	}
-	_, err = shell.RunCommandAndGetOutputE(t, cmd)
+	peering, err := fixture.VPCPeering(t, client, networkName, []string{rangeName}, address)
 	if err != nil {
-		t.Errorf("===Error %s Encountered while executing %s", err, text)
+		t.Errorf("===Error %s Encountered while connecting vpc peering of %s", err, networkName)
 	}
+	wait.For(t, "vpc peering of "+networkName+" to be connected", wait.DefaultOptions, wait.VPCPeeringConnected(t, client, networkName))
+	return peering
+}
+
+/*
+setNames is a helper function which derives the names of the clusters, of their
+instances and of their network from the run and the test.
+*/
+func setNames(t *testing.T) {
+	clusterDisplayName = naming.Name(t, naming.AlloyDBCluster, "alloydb")
+	rangeName = fmt.Sprintf("psatestrangealloydb-%s", clusterDisplayName)
+	networkName = fmt.Sprintf("vpc-%s-test", clusterDisplayName)
+	alloyDBClusterID = fmt.Sprintf("cid-%s-test", clusterDisplayName)
+	instanceID = fmt.Sprintf("id-%s-test", clusterDisplayName)
+	networkID = fmt.Sprintf("projects/%s/global/networks/%s", projectID, networkName)
 }
 
 /*
@@ -380,46 +386,40 @@ func createConfigYAMLs(t *testing.T) {
 		t.Fatal(err)
 	}
 
-	instance1 := AlloyDBStruct{ // PSA config
+	instance1 := schema.AlloyDB{ // PSA config
 		ClusterID:          alloyDBClusterID,
 		ClusterDisplayName: clusterDisplayName,
 		ProjectID:          projectID,
 		Region:             region,
 		NetworkID:          networkID,
 		AllocatedIPRange:   rangeName,
-		PrimaryInstance: PrimaryInstanceStruct{
-			InstanceID:    instanceID,
-			DisplayName:   instanceID,
-			InstanceType:  "PRIMARY",
-			MachineCPUs:   2,
-			DatabaseFlags: nil,
+		PrimaryInstance: schema.AlloyDBInstance{
+			InstanceID:      instanceID,
+			DisplayName:     instanceID,
+			InstanceType:    "PRIMARY",
+			MachineCPUCount: schema.Int(2),
 		},
-		ReadPoolInstance:           nil,
-		AutomatedBackupPolicy:      nil,
-		DeletionProtection:         false,
 		ConnectivityOptions:        "psa",
-		PscAllowedConsumerProjects: []string{projectNumber, attachmentProjectNumber},
+		PSCAllowedConsumerProjects: []string{projectNumber, attachmentProjectNumber},
+		ClusterLabels:              naming.Labels(t),
 	}
 
-	instance2 := AlloyDBStruct{ // PSC config
+	instance2 := schema.AlloyDB{ // PSC config
 		ClusterID:          alloyDBClusterID + "-psc",
 		ClusterDisplayName: clusterDisplayName + "-psc",
 		ProjectID:          projectID,
 		Region:             region,
 		NetworkID:          networkID,
-		PrimaryInstance: PrimaryInstanceStruct{
-			InstanceID:    instanceID + "-psc",
-			DisplayName:   instanceID + "-psc",
-			InstanceType:  "PRIMARY",
-			MachineCPUs:   2,
-			DatabaseFlags: nil,
+		PrimaryInstance: schema.AlloyDBInstance{
+			InstanceID:      instanceID + "-psc",
+			DisplayName:     instanceID + "-psc",
+			InstanceType:    "PRIMARY",
+			MachineCPUCount: schema.Int(2),
 		},
-		ReadPoolInstance:           nil,
-		AutomatedBackupPolicy:      nil,
-		DeletionProtection:         false,
 		ConnectivityOptions:        "psc",
-		PscAllowedConsumerProjects: []string{projectNumber, attachmentProjectNumber},
+		PSCAllowedConsumerProjects: []string{projectNumber, attachmentProjectNumber},
 		AllocatedIPRange:           "", // No Allocated IP Range for PSC
+		ClusterLabels:              naming.Labels(t),
 	}
 
 	yamlData1, err := yaml.Marshal(&instance1)
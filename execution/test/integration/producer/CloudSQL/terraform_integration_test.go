@@ -15,65 +15,48 @@ package integrationtest
 
 import (
 	"fmt"
-	"github.com/gruntwork-io/terratest/modules/shell"
+	"github.com/GoogleCloudPlatform/cloudnetworking-config-solutions/common_utils/fixture"
+	"github.com/GoogleCloudPlatform/cloudnetworking-config-solutions/common_utils/gcloud"
+	"github.com/GoogleCloudPlatform/cloudnetworking-config-solutions/common_utils/ipam"
+	"github.com/GoogleCloudPlatform/cloudnetworking-config-solutions/common_utils/naming"
+	"github.com/GoogleCloudPlatform/cloudnetworking-config-solutions/common_utils/plan"
+	"github.com/GoogleCloudPlatform/cloudnetworking-config-solutions/common_utils/preflight"
+	"github.com/GoogleCloudPlatform/cloudnetworking-config-solutions/common_utils/probe"
+	"github.com/GoogleCloudPlatform/cloudnetworking-config-solutions/common_utils/profile"
+	"github.com/GoogleCloudPlatform/cloudnetworking-config-solutions/common_utils/retryable"
+	"github.com/GoogleCloudPlatform/cloudnetworking-config-solutions/common_utils/schema"
+	"github.com/GoogleCloudPlatform/cloudnetworking-config-solutions/common_utils/wait"
 	"github.com/gruntwork-io/terratest/modules/terraform"
 	"github.com/tidwall/gjson"
 	"gopkg.in/yaml.v2"
-	"math/rand"
 	"os"
 	"testing"
-	"time"
 )
 
 var (
-	projectID              = os.Getenv("TF_VAR_project_id")
-	region                 = "us-central1"
+	env, envErr            = profile.Current()
+	projectID              = env.Projects.Endpoint
+	region                 = env.Regions.Default
 	terraformDirectoryPath = "../../../../04-producer/CloudSQL"
 	configFolderPath       = "../../test/integration/producer/CloudSQL/config"
-	rangeName              = "psatestrangecloudsql"
 	databaseVersion        = "POSTGRES_15"
-	name                   = fmt.Sprintf("cloudsql-%d", rand.Int())
-	networkName            = fmt.Sprintf("vpc-%s-test", name)
-	networkID              = fmt.Sprintf("projects/%s/global/networks/%s", projectID, networkName)
+	// The names below are derived from the run and the test by setNames.
+	name        string
+	networkName string
+	networkID   string
+	rangeName   string
 )
 
-// AllocatedIPRangesStruct represents the allocated IP Ranges in the PSA Configuration(PSAConfigStruct).
-type AllocatedIPRangesStruct struct {
-	Primary string `yaml:"primary,omitempty"`
-	Replica string `yaml:"replica,omitempty"`
-}
-
-// PSAConfigStruct represents the PSA configurations in the Connectivity Struct.
-type PSAConfigStruct struct {
-	PrivateNetwork    string                  `yaml:"private_network"`
-	AllocatedIPRanges AllocatedIPRangesStruct `yaml:"allocated_ip_ranges,omitempty"`
-}
-
-// ConnectivityStruct represents the Connectivity in the network configuration.
-type ConnectivityStruct struct {
-	PublicIPV4                 bool            `yaml:"public_ipv4,omitempty"`
-	PSAConfig                  PSAConfigStruct `yaml:"psa_config,omitempty"`
-	PSCAllowedConsumerProjects []string        `yaml:"psc_allowed_consumer_projects,omitempty"`
-}
-
-// NetworkConfigStruct represent the Network Config in the CloudSQLStruct.
-type NetworkConfigStruct struct {
-	AuthorizedNetwork map[string]string  `yaml:"authorized_networks,omitempty"`
-	Connectivity      ConnectivityStruct `yaml:"connectivity"`
-}
-
-// CloudSQLStruct defines the structure to parse configuration data for a YAML file
-// for creating Cloud SQL instances. This structure maps directly to the expected format
-// of the YAML file and uses struct tags for unmarshalling.
-
-type CloudSQLStruct struct {
-	Name                        string              `yaml:"name"`
-	ProjectID                   string              `yaml:"project_id"`
-	Region                      string              `yaml:"region"`
-	DatabaseVersion             string              `yaml:"database_version"`
-	NetworkConfig               NetworkConfigStruct `yaml:"network_config"`
-	TerraformDeletionProtection bool                `yaml:"terraform_deletion_protection"`
-	GCPDeletionProtection       bool                `yaml:"gcp_deletion_protection"`
+// TestMain checks the APIs, roles and quota the suite needs before the network
+// and the instance are created.
+func TestMain(m *testing.M) {
+	os.Exit(preflight.Run(m, preflight.Requirements{
+		Profile: envErr,
+		Project: projectID,
+		APIs:    []string{"sqladmin.googleapis.com", "compute.googleapis.com", "servicenetworking.googleapis.com"},
+		Roles:   []string{"roles/cloudsql.admin", "roles/compute.networkAdmin", "roles/servicenetworking.networksAdmin", "roles/compute.instanceAdmin.v1", "roles/iap.tunnelResourceAccessor"},
+		Quotas:  []preflight.Quota{{Metric: "NETWORKS"}, {Metric: "INSTANCES", Region: region}},
+	}))
 }
 
 /*
@@ -82,8 +65,11 @@ It then validates if
 1. CloudSQL instance is created.
 2. CloudSQL instance is created in the correct network, project, region and of correct version.
 3. CloudSQL instance only have a private ip and does not have a public IP.
+4. The applied state records the region and the network of the instance.
 */
 func TestCreateCloudSQL(t *testing.T) {
+	profile.Require(t, "projects.endpoint")
+	setNames(t)
 	// Initialize a Cloud SQL config YAML file to be tested.
 	createConfigYAML(t)
 	var (
@@ -91,7 +77,7 @@ func TestCreateCloudSQL(t *testing.T) {
 			"config_folder_path": configFolderPath,
 		}
 	)
-	terraformOptions := terraform.WithDefaultRetryableErrors(t, &terraform.Options{
+	terraformOptions := retryable.With(t, &terraform.Options{
 		// Set the path to the Terraform code that will be tested.
 		Vars:                 tfVars,
 		TerraformDir:         terraformDirectoryPath,
@@ -101,22 +87,19 @@ func TestCreateCloudSQL(t *testing.T) {
 		SetVarsAfterVarFiles: true,
 	})
 	// Create VPC outside of the terraform module.
-	err := createVPC(t, projectID, networkName)
+	network, err := createVPC(t, projectID, networkName)
 	if err != nil {
 		t.Fatal(err)
 	}
-	// Create PSA in the VPC.
-	createPSA(t, projectID, networkName, rangeName)
-	// Delete VPC created outside of the terraform module.
-	defer deleteVPC(t, projectID, networkName)
-	// Remove PSA from the VPC.
-	defer deletePSA(t, projectID, networkName, rangeName)
+	// Create PSA in the VPC. The PSA and the VPC are removed at the end of the
+	// test, after "terraform destroy".
+	psa := createPSA(t, projectID, networkName, rangeName, network)
 	// Clean up resources with "terraform destroy" at the end of the test.
-	defer terraform.Destroy(t, terraformOptions)
+	fixture.Terraform(t, terraformOptions, psa)
 	// Run "terraform init" and "terraform apply". Fail the test if there are any errors.
 	terraform.InitAndApply(t, terraformOptions)
-	// Wait for 60 seconds to let resource acheive stable state.
-	time.Sleep(60 * time.Second)
+	// Wait for the instance to be RUNNABLE before verifying.
+	wait.For(t, "Cloud SQL instance "+name+" to be RUNNABLE", wait.LongOptions, wait.SQLInstanceRunnable(t, gcloud.New(projectID), name))
 	// Run `terraform output` to get the values of output variables and check they have the expected values.
 	cloudSQLOutputValue := terraform.OutputJson(t, terraformOptions, "cloudsql_instance_details")
 	t.Log(" ========= Terraform resource creation completed ========= ")
@@ -155,102 +138,103 @@ func TestCreateCloudSQL(t *testing.T) {
 
 	t.Log(" ========= Verify Cloud SQL Instance does  have a private ip ========= ")
 	cloudSQLPrivateIPPath := fmt.Sprintf("%s.private_ip_address", name)
-	got = gjson.Get(result.String(), cloudSQLPrivateIPPath).String()
-	if got == "" {
-		t.Errorf("Cloud SQL Instance does not contain private ip = %v", got)
+	privateIP := gjson.Get(result.String(), cloudSQLPrivateIPPath).String()
+	if privateIP == "" {
+		t.Errorf("Cloud SQL Instance does not contain private ip = %v", privateIP)
+	}
+
+	t.Log(" ========= Verify Cloud SQL Instance is reachable from the VPC ========= ")
+	if privateIP != "" {
+		verifyReachable(t, network, privateIP)
 	}
+
+	t.Log(" ========= Verify Cloud SQL Instance region and network in the state ========= ")
+	plan.State(t, terraformOptions).
+		Resource(fmt.Sprintf(`module.cloudsql[%q].google_sql_database_instance.*`, name)).
+		Count(1).
+		Attr("region").Equals(region).
+		Attr("database_version").Equals(databaseVersion).
+		Attr("settings.0.ip_configuration.0.ipv4_enabled").Equals(false).
+		Attr("settings.0.ip_configuration.0.private_network").Equals(networkID)
 }
 
 /*
-deleteVPC is a helper function which deletes the VPC after
-completion of the test.
+setNames is a helper function which derives the names of the Cloud SQL
+instance and of its network and PSA range from the run and the test.
 */
-func deleteVPC(t *testing.T, projectID string, networkName string) {
-	time.Sleep(60 * time.Second)
-	text := "compute"
-	time.Sleep(60 * time.Second)
-	cmd := shell.Command{
-		Command: "gcloud",
-		Args:    []string{text, "networks", "delete", networkName, "--project=" + projectID, "--quiet"},
-	}
-	_, err := shell.RunCommandAndGetOutputE(t, cmd)
-	if err != nil {
-		t.Errorf("===Error %s Encountered while executing %s", err, text)
-	}
+func setNames(t *testing.T) {
+	name = naming.Name(t, naming.SQLInstance, "cloudsql")
+	networkName = naming.Name(t, naming.Compute, "vpc-cloudsql")
+	networkID = fmt.Sprintf("projects/%s/global/networks/%s", projectID, networkName)
+	rangeName = naming.Name(t, naming.Compute, "psa-range-cloudsql")
 }
 
 /*
-deletePSA is a helper function which deletes the PSA range after the
-execution of the test.
+createVPC is a helper function which creates the VPC before the
+execution of the test. The VPC is deleted when the test ends.
 */
-func deletePSA(t *testing.T, projectID string, networkName string, rangeName string) {
-	// Delete PSA IP range
-	time.Sleep(60 * time.Second)
-	text := "compute"
-	cmd := shell.Command{
-		Command: "gcloud",
-		Args:    []string{text, "addresses", "delete", rangeName, "--project=" + projectID, "--global", "--verbosity=info", "--format=json", "--quiet"},
-	}
-	_, err := shell.RunCommandAndGetOutputE(t, cmd)
-	if err != nil {
-		t.Logf("===Error %s Encountered while executing %s", err, text)
-	}
-	time.Sleep(60 * time.Second)
-	// Delete PSA range
-	text = "services"
-	cmd = shell.Command{
-		Command: "gcloud",
-		Args:    []string{text, "vpc-peerings", "delete", "--service=servicenetworking.googleapis.com", "--project=" + projectID, "--network=" + networkName, "--verbosity=info", "--format=json", "--quiet"},
-	}
-	_, err = shell.RunCommandAndGetOutputE(t, cmd)
+func createVPC(t *testing.T, projectID string, networkName string) (*fixture.Handle, error) {
+	network, err := fixture.Network(t, gcloud.New(projectID), networkName, gcloud.NetworkOptions{BGPRoutingMode: "global"})
 	if err != nil {
-		t.Logf("===Error %s Encountered while executing %s", err, text)
+		t.Errorf("===Error %s Encountered while creating network %s", err, networkName)
 	}
+	return network, err
 }
 
 /*
-createVPC is a helper function which creates the VPC before the
-execution of the test.
+createPSA is a helper function which creates the PSA range before the
+execution of the test, with a /20 leased from the ipam package. The connection
+and the range are removed when the test ends, before the VPC.
 */
-func createVPC(t *testing.T, projectID string, networkName string) error {
-	text := "compute"
-	cmd := shell.Command{
-		Command: "gcloud",
-		Args:    []string{text, "networks", "create", networkName, "--project=" + projectID, "--format=json", "--bgp-routing-mode=global", "--subnet-mode=custom", "--verbosity=info"},
+func createPSA(t *testing.T, projectID string, networkName string, rangeName string, network *fixture.Handle) *fixture.Handle {
+	client := gcloud.New(projectID)
+	// Create an IP range
+	psaRange := ipam.PSARange(t, 20)
+	address, err := fixture.Address(t, client, rangeName, gcloud.AddressOptions{
+		Purpose:      "VPC_PEERING",
+		Address:      ipam.Host(psaRange, 0),
+		PrefixLength: 20,
+		Network:      networkName,
+	}, network)
+	if err != nil {
+		t.Errorf("===Error %s Encountered while creating address %s", err, rangeName)
 	}
-	_, err := shell.RunCommandAndGetOutputE(t, cmd)
+	// Create PSA range
+	peering, err := fixture.VPCPeering(t, client, networkName, []string{rangeName}, address)
 	if err != nil {
-		t.Errorf("===Error %s Encountered while executing %s", err, text)
+		t.Errorf("===Error %s Encountered while connecting vpc peering of %s", err, networkName)
 	}
-	return err
+	wait.For(t, "vpc peering of "+networkName+" to be connected", wait.DefaultOptions, wait.VPCPeeringConnected(t, client, networkName))
+	return peering
 }
 
 /*
-createPSA is a helper function which creates the PSA range before the
-execution of the test.
+verifyReachable is a helper function which creates a client VM in a new
+subnetwork of the VPC and checks that the instance answers the PostgreSQL
+protocol on its private IP. The VM and the subnetwork are removed before the
+instance.
 */
-func createPSA(t *testing.T, projectID string, networkName string, rangeName string) {
-	// Create an IP range
-	text := "compute"
-	cmd := shell.Command{
-		Command: "gcloud",
-		Args:    []string{text, "addresses", "create", rangeName, "--purpose=VPC_PEERING", "--addresses=10.0.64.0", "--prefix-length=20", "--project=" + projectID, "--network=" + networkName, "--global", "--verbosity=info", "--format=json"},
-	}
-	_, err := shell.RunCommandAndGetOutputE(t, cmd)
+func verifyReachable(t *testing.T, network *fixture.Handle, privateIP string) {
+	client := gcloud.New(projectID)
+	subnetName := naming.Name(t, naming.Compute, "subnet-cloudsql")
+	subnet, err := fixture.Subnet(t, client, subnetName, gcloud.SubnetOptions{
+		Network: networkName,
+		Region:  region,
+		Range:   ipam.Subnet(t, 28),
+	}, network)
 	if err != nil {
-		t.Errorf("===Error %s Encountered while executing %s", err, text)
+		t.Fatalf("===Error %s Encountered while creating subnetwork %s", err, subnetName)
 	}
-	// Create PSA range
-	text = "services"
-	cmd = shell.Command{
-		Command: "gcloud",
-		Args:    []string{text, "vpc-peerings", "connect", "--service=servicenetworking.googleapis.com", "--ranges=" + rangeName, "--project=" + projectID, "--network=" + networkName, "--verbosity=info", "--format=json"},
+	vm, err := probe.NewVM(t, client, probe.VMOptions{Zone: env.Zones.Default, Network: networkName, Subnet: subnetName}, subnet)
+	if err != nil {
+		t.Fatalf("===Error %s Encountered while creating the client VM", err)
 	}
-	_, err = shell.RunCommandAndGetOutputE(t, cmd)
+	results, err := vm.Eventually(t, wait.DefaultOptions, true, probe.Postgres(privateIP, 5432))
 	if err != nil {
-		t.Errorf("===Error %s Encountered while executing %s", err, text)
+		t.Errorf("Cloud SQL instance is not reachable on %s:5432 from the VPC: %v", privateIP, err)
+		return
 	}
-	time.Sleep(60 * time.Second)
+	t.Logf("Cloud SQL instance is reachable: %s", results[0])
 }
 
 /*
@@ -259,18 +243,19 @@ for a cloudsql instance.
 */
 func createConfigYAML(t *testing.T) {
 	t.Log("========= YAML File =========")
-	instance1 := CloudSQLStruct{
+	instance1 := schema.CloudSQL{
 		Name:                        name,
 		ProjectID:                   projectID,
 		Region:                      region,
 		DatabaseVersion:             databaseVersion,
-		TerraformDeletionProtection: false,
-		GCPDeletionProtection:       false,
-		NetworkConfig: NetworkConfigStruct{
-			Connectivity: ConnectivityStruct{
-				PSAConfig: PSAConfigStruct{
+		TerraformDeletionProtection: schema.Bool(false),
+		GCPDeletionProtection:       schema.Bool(false),
+		Labels:                      naming.Labels(t),
+		NetworkConfig: schema.CloudSQLNetworkConfig{
+			Connectivity: schema.CloudSQLConnectivity{
+				PSAConfig: &schema.PSAConfig{
 					PrivateNetwork: networkID,
-					AllocatedIPRanges: AllocatedIPRangesStruct{
+					AllocatedIPRanges: &schema.AllocatedIPRanges{
 						Primary: rangeName,
 					},
 				},
@@ -16,16 +16,22 @@ package integrationtest
 
 import (
 	"fmt"
-	"io/ioutil"
-	"math/rand"
 	"os"
 	"path/filepath"
 	"sort"
 	"testing"
-	"time"
 
 	// for sorting slices
 	// for comparison operations
+	"github.com/GoogleCloudPlatform/cloudnetworking-config-solutions/common_utils/gcloud"
+	"github.com/GoogleCloudPlatform/cloudnetworking-config-solutions/common_utils/ipam"
+	"github.com/GoogleCloudPlatform/cloudnetworking-config-solutions/common_utils/location"
+	"github.com/GoogleCloudPlatform/cloudnetworking-config-solutions/common_utils/naming"
+	"github.com/GoogleCloudPlatform/cloudnetworking-config-solutions/common_utils/preflight"
+	"github.com/GoogleCloudPlatform/cloudnetworking-config-solutions/common_utils/profile"
+	"github.com/GoogleCloudPlatform/cloudnetworking-config-solutions/common_utils/retryable"
+	"github.com/GoogleCloudPlatform/cloudnetworking-config-solutions/common_utils/schema"
+	"github.com/GoogleCloudPlatform/cloudnetworking-config-solutions/common_utils/wait"
 	"github.com/google/go-cmp/cmp"
 	"github.com/gruntwork-io/terratest/modules/shell"
 	"github.com/gruntwork-io/terratest/modules/terraform"
@@ -39,17 +45,12 @@ var (
 	terraformDirectoryPath = filepath.Join(projectRoot, "04-producer/GKE")
 	// Path to the folder containing YAML configuration files.
 	configFolderPath         = filepath.Join(projectRoot, "test/integration/producer/GKE/config")
-	projectID                = os.Getenv("TF_VAR_project_id")
-	region                   = "us-central1"
+	env, envErr              = profile.Current()
+	projectID                = env.Projects.Endpoint
+	region                   = env.Regions.Default
 	kubernetesVersion        = "latest"
-	instanceName             = fmt.Sprintf("gke-%d", rand.Int())
-	networkName              = fmt.Sprintf("gke-cluster-vpc-%d", rand.Int())
-	subnetName               = fmt.Sprintf("gke-cluster-subnetwork-%d", rand.Int())
-	subnetIPRange            = "10.0.0.0/16"
 	ipRangePods              = "pods"
 	ipRangeServices          = "services"
-	podIPRange               = "10.1.0.0/16"
-	servicesIPRange          = "10.2.0.0/16"
 	deletionProtection       = false
 	remove_default_node_pool = true
 	tfVars                   = map[string]any{
@@ -59,33 +60,40 @@ var (
 		"config_folder_path": configFolderPath,
 		"network":            "random/google/cloud/network/",
 	}
+	// The names below are derived from the run and the test by setNames.
+	instanceName string
+	networkName  string
+	subnetName   string
+	// The primary and secondary ranges of the subnet are leased from the
+	// ipam package by TestCreateGKECluster.
+	subnetIPRange   string
+	podIPRange      string
+	servicesIPRange string
 )
 
-type GKEConfig struct {
-	Name                  string `yaml:"name"`
-	ProjectID             string `yaml:"project_id"`
-	KubernetesVersion     string `yaml:"kubernetes_version"`
-	Network               string `yaml:"network"`
-	Subnetwork            string `yaml:"subnetwork"`
-	IPRangePods           string `yaml:"ip_range_pods"`
-	IPRangeServices       string `yaml:"ip_range_services"`
-	Region                string `yaml:"region"`
-	RemoveDefaultNodePool bool   `yaml:"remove_default_node_pool"`
-	DeletionProtection    bool   `yaml:"deletion_protection"`
+// TestMain checks the APIs, roles and quota the suite needs before the network
+// and the cluster are created.
+func TestMain(m *testing.M) {
+	os.Exit(preflight.Run(m, preflight.Requirements{
+		Profile: envErr,
+		Project: projectID,
+		APIs:    []string{"container.googleapis.com", "compute.googleapis.com"},
+		Roles:   []string{"roles/container.admin", "roles/compute.networkAdmin", "roles/iam.serviceAccountUser"},
+		Quotas:  []preflight.Quota{{Metric: "NETWORKS"}},
+	}))
 }
 
 // TestCreateGKECluster tests the creation of a GKE cluster.
 func TestCreateGKECluster(t *testing.T) {
-	// Initialize a GKE config YAML file to be tested.
-	createGKEConfigYAML(t)
-
+	profile.Require(t, "projects.endpoint")
+	setNames(t)
 	var (
 		tfVars = map[string]any{
 			"config_folder_path": configFolderPath,
 		}
 	)
 
-	terraformOptions := terraform.WithDefaultRetryableErrors(t, &terraform.Options{
+	terraformOptions := retryable.With(t, &terraform.Options{
 		Vars:                 tfVars,
 		TerraformDir:         terraformDirectoryPath,
 		Reconfigure:          true,
@@ -94,11 +102,12 @@ func TestCreateGKECluster(t *testing.T) {
 		SetVarsAfterVarFiles: true,
 	})
 
-	// Create network, subnet, and IP ranges
+	// Create the network; the subnet follows the cluster to its region.
 	createNetwork(t, projectID, networkName)
-	createSubnet(t, projectID, networkName, subnetName)
-	createIPRanges(t, projectID, region, subnetName)
-	time.Sleep(60 * time.Second)
+	subnetIPRange = ipam.Subnet(t, 16)
+	podIPRange = ipam.Subnet(t, 16)
+	servicesIPRange = ipam.Subnet(t, 16)
+	client := gcloud.New(projectID)
 
 	// Delete network, subnet, and IP ranges
 	defer deleteNetwork(t, projectID, networkName)
@@ -107,72 +116,78 @@ func TestCreateGKECluster(t *testing.T) {
 	// Clean up resources with "terraform destroy" at the end of the test.
 	defer terraform.Destroy(t, terraformOptions)
 
-	// Run "terraform init" and "terraform apply". Fail the test if there are any errors.
-	terraform.InitAndApply(t, terraformOptions)
+	// Create the subnet, its IP ranges and the cluster, moving to a fallback
+	// region when the region is out of capacity.
+	loc, err := location.Try(t, location.Regions(env), func(loc location.Location) error {
+		region = loc.Region
+		createSubnet(t, projectID, networkName, subnetName)
+		createIPRanges(t, projectID, region, subnetName)
+		wait.For(t, "subnetwork "+subnetName+" to be ready", wait.DefaultOptions, wait.SubnetReady(t, client, region, subnetName))
+		createGKEConfigYAML(t)
+		_, err := terraform.InitAndApplyE(t, terraformOptions)
+		if location.IsCapacityError(err) {
+			// Free the subnet name for the next region.
+			terraform.Destroy(t, terraformOptions)
+			deleteSubnet(t, projectID, subnetName)
+		}
+		return err
+	})
+	if err != nil {
+		t.Fatalf("Creating the GKE cluster in %s failed: %v", loc, err)
+	}
+
+	// Wait for the GKE cluster to be RUNNING before verifying.
+	wait.For(t, "GKE cluster "+instanceName+" to be RUNNING", wait.LongOptions, wait.State(func() (string, error) {
+		var cluster struct {
+			Status string `json:"status"`
+		}
+		err := client.RunJSON(t, &cluster, "container", "clusters", "describe", instanceName, "--region="+region)
+		return cluster.Status, err
+	}, "RUNNING"))
 
-	// Wait for the GKE cluster to become available with retries
-	maxRetries := 10
-	retryInterval := 10 * time.Second
 	var clusterID string
+	clusterOutput := terraform.OutputJson(t, terraformOptions, "gke_clusters")
+	if !gjson.Valid(clusterOutput) {
+		t.Fatalf("Error parsing output, invalid json: %s", clusterOutput)
+	}
+
+	result := gjson.Parse(clusterOutput)
 
-	for i := 0; i < maxRetries; i++ {
-		clusterOutput := terraform.OutputJson(t, terraformOptions, "gke_clusters")
+	result.ForEach(func(key, value gjson.Result) bool {
+		clusterID = key.String()
+		clusterData := value
 
-		if !gjson.Valid(clusterOutput) {
-			t.Errorf("Error parsing output, invalid json: %s", clusterOutput)
-			continue // Skip to next retry if output is invalid
+		// Verify GKE Cluster Properties
+		name := clusterData.Get("name").String()
+		if name != instanceName {
+			t.Errorf("GKE Cluster name is invalid: got %s, want %s", name, instanceName)
+		} else {
+			t.Logf("GKE Cluster name is valid: %s", name) // Success message
 		}
 
-		result := gjson.Parse(clusterOutput)
-
-		result.ForEach(func(key, value gjson.Result) bool {
-			clusterID = key.String()
-			clusterData := value
-
-			// Verify GKE Cluster Properties
-			name := clusterData.Get("name").String()
-			if name != instanceName {
-				t.Errorf("GKE Cluster name is invalid: got %s, want %s", name, instanceName)
-			} else {
-				t.Logf("GKE Cluster name is valid: %s", name) // Success message
-			}
-
-			// Verify Region
-			regionInLogs := clusterData.Get("region").String()
-			if regionInLogs != region {
-				t.Errorf("GKE Cluster region is invalid: got %s, want %s", regionInLogs, region)
-			} else {
-				t.Logf("GKE Cluster region is valid: %s", regionInLogs) // Success message
-			}
-
-			return false // Break out of the iteration
-		})
-
-		if clusterID != "" {
-			break
+		// Verify Region
+		regionInLogs := clusterData.Get("region").String()
+		if regionInLogs != region {
+			t.Errorf("GKE Cluster region is invalid: got %s, want %s", regionInLogs, region)
 		} else {
-			time.Sleep(retryInterval)
+			t.Logf("GKE Cluster region is valid: %s", regionInLogs) // Success message
 		}
-	}
+
+		return false // Break out of the iteration
+	})
 
 	if clusterID == "" {
-		t.Errorf("GKE cluster ID not found after %d retries", maxRetries)
+		t.Errorf("GKE cluster ID not found in output")
 	}
 }
 
 // TestTerraformModuleResourceAddressListMatch compares and verifies the list of resources,
 // modules created by the Terraform solution.
 func TestTerraformModuleResourceAddressListMatch(t *testing.T) {
+	profile.Require(t, "projects.endpoint")
 	// 1. Read and parse the YAML config file
-	yamlFile, err := ioutil.ReadFile(filepath.Join(configFolderPath, "gke-config.yaml"))
-	if err != nil {
-		t.Fatal(err)
-	}
-
-	// Unmarshal into a GKEConfig struct
-	var gkeConfig GKEConfig
-	err = yaml.Unmarshal(yamlFile, &gkeConfig)
-	if err != nil {
+	var gkeConfig schema.GKE
+	if err := schema.Load(filepath.Join(configFolderPath, "gke-config.yaml"), &gkeConfig); err != nil {
 		t.Fatal(err)
 	}
 
@@ -180,7 +195,7 @@ func TestTerraformModuleResourceAddressListMatch(t *testing.T) {
 	expectedModulesAddress := []string{fmt.Sprintf("module.gke[\"%s\"]", gkeConfig.Name)}
 
 	// Terraform options for planning.
-	terraformOptions := terraform.WithDefaultRetryableErrors(t, &terraform.Options{
+	terraformOptions := retryable.With(t, &terraform.Options{
 		TerraformDir: terraformDirectoryPath,
 		Vars:         tfVars,
 		Reconfigure:  true,
@@ -226,6 +241,7 @@ TestInitAndPlanRunWithInvalidTfVarsExpectFailureScenario performs test runs with
 to ensure the terraform init && terraform plan is executed unsuccessfully and returns an expected error run code.
 */
 func TestInitAndPlanRunWithInvalidTfVarsExpectFailureScenario(t *testing.T) {
+	profile.Require(t, "projects.endpoint")
 	/*
 	 0 = Succeeded with empty diff (no changes)
 	 1 = Error
@@ -234,7 +250,7 @@ func TestInitAndPlanRunWithInvalidTfVarsExpectFailureScenario(t *testing.T) {
 	// Construct the terraform options with default retryable errors to handle the most common
 	// retryable errors in terraform testing.
 
-	terraformOptions := terraform.WithDefaultRetryableErrors(t, &terraform.Options{
+	terraformOptions := retryable.With(t, &terraform.Options{
 		// Set the path to the Terraform code that will be tested.
 		TerraformDir: terraformDirectoryPath,
 		Vars:         invalidTFVars,
@@ -255,7 +271,8 @@ func TestInitAndPlanRunWithInvalidTfVarsExpectFailureScenario(t *testing.T) {
 // succeed with the provided variables. It expects changes (exit code 2) as it's not applying.
 
 func TestInitAndPlanRunWithTfVars(t *testing.T) {
-	terraformOptions := terraform.WithDefaultRetryableErrors(t, &terraform.Options{
+	profile.Require(t, "projects.endpoint")
+	terraformOptions := retryable.With(t, &terraform.Options{
 		TerraformDir: terraformDirectoryPath,
 		Vars:         tfVars,
 		Reconfigure:  true,
@@ -277,7 +294,8 @@ func TestInitAndPlanRunWithTfVars(t *testing.T) {
 
 // TestResourcesCount verifies the number of resources to be added by the Terraform plan.
 func TestResourcesCount(t *testing.T) {
-	terraformOptions := terraform.WithDefaultRetryableErrors(t, &terraform.Options{
+	profile.Require(t, "projects.endpoint")
+	terraformOptions := retryable.With(t, &terraform.Options{
 		TerraformDir: terraformDirectoryPath,
 		Vars:         tfVars,
 		Reconfigure:  true,
@@ -303,12 +321,22 @@ func TestResourcesCount(t *testing.T) {
 	}
 }
 
+/*
+setNames derives the names of the GKE cluster and of its network from the run
+and the test.
+*/
+func setNames(t *testing.T) {
+	instanceName = naming.Name(t, naming.GKECluster, "gke")
+	networkName = naming.Name(t, naming.Compute, "gke-cluster-vpc")
+	subnetName = naming.Name(t, naming.Compute, "gke-cluster-subnetwork")
+}
+
 /*
 createGKEConfigYAML creates the YAML configuration file for GKE.
 */
 func createGKEConfigYAML(t *testing.T) {
 	t.Log("========= YAML File =========")
-	gkeConfig := GKEConfig{
+	gkeConfig := schema.GKE{
 		Name:                  instanceName,
 		ProjectID:             projectID,
 		KubernetesVersion:     kubernetesVersion,
@@ -317,8 +345,9 @@ func createGKEConfigYAML(t *testing.T) {
 		IPRangePods:           ipRangePods,
 		IPRangeServices:       ipRangeServices,
 		Region:                region,
-		RemoveDefaultNodePool: remove_default_node_pool,
-		DeletionProtection:    deletionProtection,
+		RemoveDefaultNodePool: schema.Bool(remove_default_node_pool),
+		DeletionProtection:    schema.Bool(deletionProtection),
+		ClusterResourceLabels: naming.Labels(t),
 	}
 
 	yamlData, err := yaml.Marshal(&gkeConfig)
@@ -15,12 +15,20 @@ package integrationtest
 
 import (
 	"fmt"
-	"math/rand"
 	"os"
 	"path/filepath"
 	"testing"
-	"time"
 
+	"github.com/GoogleCloudPlatform/cloudnetworking-config-solutions/common_utils/fixture"
+	"github.com/GoogleCloudPlatform/cloudnetworking-config-solutions/common_utils/gcloud"
+	"github.com/GoogleCloudPlatform/cloudnetworking-config-solutions/common_utils/ipam"
+	"github.com/GoogleCloudPlatform/cloudnetworking-config-solutions/common_utils/naming"
+	"github.com/GoogleCloudPlatform/cloudnetworking-config-solutions/common_utils/preflight"
+	"github.com/GoogleCloudPlatform/cloudnetworking-config-solutions/common_utils/probe"
+	"github.com/GoogleCloudPlatform/cloudnetworking-config-solutions/common_utils/profile"
+	"github.com/GoogleCloudPlatform/cloudnetworking-config-solutions/common_utils/retryable"
+	"github.com/GoogleCloudPlatform/cloudnetworking-config-solutions/common_utils/schema"
+	"github.com/GoogleCloudPlatform/cloudnetworking-config-solutions/common_utils/wait"
 	"github.com/gruntwork-io/terratest/modules/shell"
 	"github.com/gruntwork-io/terratest/modules/terraform"
 	"github.com/tidwall/gjson"
@@ -37,25 +45,33 @@ var (
 )
 
 var (
-	projectID                 = os.Getenv("TF_VAR_project_id")
-	region                    = "us-central1"
-	instanceName              = fmt.Sprintf("mrc-%d", rand.Int())
-	networkName               = fmt.Sprintf("vpc-%s-test", instanceName)
-	networkID                 = fmt.Sprintf("projects/%s/global/networks/%s", projectID, networkName)
+	env, envErr               = profile.Current()
+	projectID                 = env.Projects.Endpoint
+	region                    = env.Regions.Default
 	deletionProtectionEnabled = false
+	// The names below are derived from the run and the test by setNames.
+	instanceName string
+	networkName  string
+	networkID    string
 )
 
-type MRCStruct struct {
-	InstanceName              string `yaml:"redis_cluster_name"`
-	ProjectID                 string `yaml:"project_id"`
-	NetworkID                 string `yaml:"network_id"`
-	Region                    string `yaml:"region"`
-	DeletionProtectionEnabled bool   `yaml:"deletion_protection_enabled"`
+// TestMain checks the APIs, roles and quota the suite needs before the network
+// and the cluster are created.
+func TestMain(m *testing.M) {
+	os.Exit(preflight.Run(m, preflight.Requirements{
+		Profile: envErr,
+		Project: projectID,
+		APIs:    []string{"redis.googleapis.com", "compute.googleapis.com", "networkconnectivity.googleapis.com", "serviceconsumermanagement.googleapis.com"},
+		Roles:   []string{"roles/redis.admin", "roles/compute.networkAdmin", "roles/networkconnectivity.admin", "roles/compute.instanceAdmin.v1", "roles/iap.tunnelResourceAccessor"},
+		Quotas:  []preflight.Quota{{Metric: "NETWORKS"}, {Metric: "INSTANCES", Region: region}},
+	}))
 }
 
 // GetFirstNonEmptyEnvVarOrUseDefault retrieves the first non-empty environment variable
 // from the provided list, or falls back to a default value if none are set.
 func TestCreateMRC(t *testing.T) {
+	profile.Require(t, "projects.endpoint")
+	setNames(t)
 	// Initialize a MRC config YAML file to be tested.
 	createConfigYAML(t)
 
@@ -67,7 +83,7 @@ func TestCreateMRC(t *testing.T) {
 		}
 	)
 
-	terraformOptions := terraform.WithDefaultRetryableErrors(t, &terraform.Options{
+	terraformOptions := retryable.With(t, &terraform.Options{
 		Vars:                 tfVars,
 		TerraformDir:         terraformDirectoryPath,
 		Reconfigure:          true,
@@ -78,10 +94,15 @@ func TestCreateMRC(t *testing.T) {
 
 	// Create VPC, subnet, and service connection policy
 	createVPC(t, projectID, networkName)
-	time.Sleep(60 * time.Second)
+	policyName := fmt.Sprintf("%s-policy", networkName)
+	wait.For(t, "service connection policy "+policyName+" to exist", wait.DefaultOptions, wait.ServiceConnectionPolicyExists(t, gcloud.New(projectID), region, policyName))
 
-	// Delete VPC, subnet, and service connection policy
-	defer deleteVPC(t, projectID, networkName)
+	// Delete VPC, subnet, and service connection policy when the test ends,
+	// after the client VM, which is a fixture that depends on them.
+	network := fixture.Register(t, fixture.Resource{Kind: "network", ID: networkID}, func() error {
+		deleteVPC(t, projectID, networkName)
+		return nil
+	})
 
 	// Clean up resources with "terraform destroy" at the end of the test.
 	defer terraform.Destroy(t, terraformOptions)
@@ -89,83 +110,102 @@ func TestCreateMRC(t *testing.T) {
 	// Run "terraform init" and "terraform apply". Fail the test if there are any errors.
 	terraform.InitAndApply(t, terraformOptions)
 
-	// Wait for the MRC cluster to become available with retries
-	maxRetries := 10
-	retryInterval := 10 * time.Second
-	instanceReady := false // Flag to track if instance is ready
+	// Wait for the MRC cluster to become ACTIVE before verifying.
+	client := gcloud.New(projectID)
+	var discoveryAddress string
+	wait.For(t, "MRC cluster "+instanceName+" to be ACTIVE", wait.LongOptions, wait.State(func() (string, error) {
+		var cluster struct {
+			State              string `json:"state"`
+			DiscoveryEndpoints []struct {
+				Address string `json:"address"`
+			} `json:"discoveryEndpoints"`
+		}
+		err := client.RunJSON(t, &cluster, "redis", "clusters", "describe", instanceName, "--region="+region)
+		if len(cluster.DiscoveryEndpoints) > 0 {
+			discoveryAddress = cluster.DiscoveryEndpoints[0].Address
+		}
+		return cluster.State, err
+	}, "ACTIVE"))
+
+	MRCOutputValue := terraform.OutputJson(t, terraformOptions, "redis_cluster_details")
+	if !gjson.Valid(MRCOutputValue) {
+		t.Fatalf("Error parsing output, invalid json: %s", MRCOutputValue)
+	}
+	result := gjson.Parse(MRCOutputValue)
+
+	// Iterate over each MRC instance details within the redis_cluster_details output
+	result.ForEach(func(key, value gjson.Result) bool {
+		// Extract the instance name from the key
+		instanceName := key.String()
+
+		// 1. Verify MRC Cluster Name
+		gotName := value.Get("name").String()
+		if gotName != instanceName {
+			t.Errorf("MRC Cluster '%s' has invalid name: got %s, want %s", instanceName, gotName, instanceName)
+		}
 
-	for i := 0; i < maxRetries; i++ {
+		// 2. Verify Network ID using gcloud command
+		expectedNetworkID := value.Get("network").String()
+		cmd := shell.Command{
+			Command: "gcloud",
+			Args:    []string{"redis", "clusters", "describe", instanceName, "--project=" + projectID, "--region=" + region, "--format=json", "--verbosity=none", "--quiet"},
+		}
+		output, err := shell.RunCommandAndGetOutputE(t, cmd)
+		if err != nil {
+			t.Errorf("Error running gcloud command: %s", err)
+			return false
+		}
 
-		MRCOutputValue := terraform.OutputJson(t, terraformOptions, "redis_cluster_details")
+		actualNetwork := gjson.Get(output, "pscConnections.0.network").String()
 
-		if !gjson.Valid(MRCOutputValue) {
-			t.Errorf("Error parsing output, invalid json: %s", MRCOutputValue)
-			continue // Skip to next retry if output is invalid
+		if actualNetwork != expectedNetworkID {
+			t.Errorf("MRC Cluster '%s' has invalid network ID: got %s, want %s", instanceName, actualNetwork, expectedNetworkID)
+			return false
 		}
 
-		result := gjson.Parse(MRCOutputValue)
-		instanceReady = true // Assume instance is ready before checking
-
-		// Iterate over each MRC instance details within the redis_cluster_details output
-		result.ForEach(func(key, value gjson.Result) bool {
-			// Extract the instance name from the key
-			instanceName := key.String()
-
-			// 1. Verify MRC Cluster Name
-			gotName := value.Get("name").String()
-			if gotName != instanceName {
-				t.Errorf("MRC Cluster '%s' has invalid name: got %s, want %s", instanceName, gotName, instanceName)
-			}
-
-			// 2. Check if the cluster is in the ACTIVE state
-			gotStatus := value.Get("state").String()
-			if gotStatus != "ACTIVE" {
-				t.Logf("MRC Cluster '%s' not yet ACTIVE (current state: %s). Retrying...", instanceName, gotStatus)
-				instanceReady = false
-				return false // Stop iterating if an instance is not ready
-			}
-
-			// 3. Verify Network ID using gcloud command
-			expectedNetworkID := value.Get("network").String()
-			cmd := shell.Command{
-				Command: "gcloud",
-				Args:    []string{"redis", "clusters", "describe", instanceName, "--project=" + projectID, "--region=" + region, "--format=json", "--verbosity=none", "--quiet"},
-			}
-			output, err := shell.RunCommandAndGetOutputE(t, cmd)
-			if err != nil {
-				t.Errorf("Error running gcloud command: %s", err)
-				instanceReady = false
-				return false
-			}
-
-			actualNetwork := gjson.Get(output, "pscConnections.0.network").String()
-
-			if actualNetwork != expectedNetworkID {
-				t.Errorf("MRC Cluster '%s' has invalid network ID: got %s, want %s", instanceName, actualNetwork, expectedNetworkID)
-				instanceReady = false
-				return false
-			}
-
-			// 4. Verify Shard Count
-			gotShardCount := value.Get("shard_count").Int()
-			if gotShardCount != 3 {
-				t.Errorf("MRC Cluster '%s' has invalid shard count: got %d, want 3", instanceName, gotShardCount)
-			}
-
-			// 5. Verify Replica Count
-			gotReplicaCount := value.Get("replica_count").Int()
-			if gotReplicaCount != 1 {
-				t.Errorf("MRC Cluster '%s' has invalid replica count: got %d, want 1", instanceName, gotReplicaCount)
-			}
-			return true // Continue iterating to the next instance
-		})
-
-		if instanceReady {
-			break
-		} else {
-			time.Sleep(retryInterval)
+		// 3. Verify Shard Count
+		gotShardCount := value.Get("shard_count").Int()
+		if gotShardCount != 3 {
+			t.Errorf("MRC Cluster '%s' has invalid shard count: got %d, want 3", instanceName, gotShardCount)
 		}
+
+		// 4. Verify Replica Count
+		gotReplicaCount := value.Get("replica_count").Int()
+		if gotReplicaCount != 1 {
+			t.Errorf("MRC Cluster '%s' has invalid replica count: got %d, want 1", instanceName, gotReplicaCount)
+		}
+		return true // Continue iterating to the next instance
+	})
+
+	// 5. Verify the discovery endpoint answers from the VPC.
+	if discoveryAddress == "" {
+		t.Errorf("MRC Cluster '%s' has no discovery endpoint", instanceName)
+		return
+	}
+	verifyReachable(t, network, discoveryAddress)
+}
+
+/*
+verifyReachable creates a client VM in the subnet of the VPC and checks that the
+discovery endpoint of the cluster answers PING.
+*/
+func verifyReachable(t *testing.T, network *fixture.Handle, address string) {
+	client := gcloud.New(projectID)
+	client.Labels = naming.Labels(t)
+	vm, err := probe.NewVM(t, client, probe.VMOptions{
+		Zone:    env.Zones.Default,
+		Network: networkName,
+		Subnet:  fmt.Sprintf("%s-subnet", networkName),
+	}, network)
+	if err != nil {
+		t.Fatalf("===Error %s Encountered while creating the client VM", err)
+	}
+	results, err := vm.Eventually(t, wait.DefaultOptions, true, probe.Redis(address, 6379))
+	if err != nil {
+		t.Errorf("MRC Cluster '%s' is not reachable on %s:6379 from the VPC: %v", instanceName, address, err)
+		return
 	}
+	t.Logf("MRC Cluster '%s' is reachable: %s", instanceName, results[0])
 }
 
 /*
@@ -194,7 +234,7 @@ func createVPC(t *testing.T, projectID string, networkName string) {
 			"--project=" + projectID,
 			"--network=" + networkName,
 			"--region=" + region,
-			"--range=10.0.0.0/24",
+			"--range=" + ipam.Subnet(t, 24),
 		},
 	}
 	_, err = shell.RunCommandAndGetOutputE(t, cmd)
@@ -224,67 +264,60 @@ func createVPC(t *testing.T, projectID string, networkName string) {
 
 /*
 deleteVPC deletes the VPC, subnet, and service connection policy after the test.
+Each delete is retried while the cluster teardown still holds the resource.
 */
 func deleteVPC(t *testing.T, projectID string, networkName string) {
-	text := "compute"
-	time.Sleep(60 * time.Second)
+	client := gcloud.New(projectID)
 
 	// Delete Service Connection Policy
 	policyName := fmt.Sprintf("%s-policy", networkName)
-	cmd := shell.Command{
-		Command: "gcloud",
-		Args: []string{
-			"network-connectivity", "service-connection-policies", "delete", policyName,
-			"--region=" + region,
-			"--project=" + projectID,
-			"--quiet", // Suppress prompts for confirmation
-		},
-	}
-	_, err := shell.RunCommandAndGetOutputE(t, cmd)
+	_, err := wait.ForE(t, "service connection policy "+policyName+" to be deleted", wait.DefaultOptions, wait.Deletes(func() error {
+		return client.ServiceConnectionPolicies.Delete(t, region, policyName)
+	}))
 	if err != nil {
-		t.Errorf("===Error %s Encountered while executing %s", err, text)
+		t.Errorf("===Error %s Encountered while deleting service connection policy %s", err, policyName)
 	}
 
 	// Delete Subnet
 	subnetName := fmt.Sprintf("%s-subnet", networkName)
-	cmd = shell.Command{
-		Command: "gcloud",
-		Args: []string{
-			text, "networks", "subnets", "delete", subnetName,
-			"--project=" + projectID,
-			"--region=" + region,
-			"--quiet",
-		},
-	}
-	_, err = shell.RunCommandAndGetOutputE(t, cmd)
+	_, err = wait.ForE(t, "subnetwork "+subnetName+" to be deleted", wait.DefaultOptions, wait.Deletes(func() error {
+		return client.Subnets.Delete(t, region, subnetName)
+	}))
 	if err != nil {
-		t.Errorf("===Error %s Encountered while executing %s", err, text)
+		t.Errorf("===Error %s Encountered while deleting subnetwork %s", err, subnetName)
 	}
 
-	// 3. Delete VPC
-	time.Sleep(120 * time.Second) // Wait for firewall deletion to complete
-	cmd = shell.Command{
-		Command: "gcloud",
-		Args:    []string{text, "networks", "delete", networkName, "--project=" + projectID, "--quiet"},
-	}
-	_, err = shell.RunCommandAndGetOutputE(t, cmd)
+	// Delete VPC
+	_, err = wait.ForE(t, "network "+networkName+" to be deleted", wait.DefaultOptions, wait.Deletes(func() error {
+		return client.Networks.Delete(t, networkName)
+	}))
 	if err != nil {
-		t.Errorf("===Error %s Encountered while executing %s", err, text)
+		t.Errorf("===Error %s Encountered while deleting network %s", err, networkName)
 	}
 }
 
+/*
+setNames is a helper function which derives the names of the cluster and of its
+network from the run and the test.
+*/
+func setNames(t *testing.T) {
+	instanceName = naming.Name(t, naming.Compute, "mrc")
+	networkName = fmt.Sprintf("vpc-%s-test", instanceName)
+	networkID = fmt.Sprintf("projects/%s/global/networks/%s", projectID, networkName)
+}
+
 /*
 createConfigYAML is a helper function which creates the configigration YAML file
 for an MRC instance.
 */
 func createConfigYAML(t *testing.T) {
 	t.Log("========= YAML File =========")
-	instance1 := MRCStruct{
-		InstanceName:              instanceName,
+	instance1 := schema.MRC{
+		RedisClusterName:          instanceName,
 		ProjectID:                 projectID,
 		NetworkID:                 networkID,
 		Region:                    region,
-		DeletionProtectionEnabled: deletionProtectionEnabled,
+		DeletionProtectionEnabled: schema.Bool(deletionProtectionEnabled),
 	}
 
 	yamlData, err := yaml.Marshal(&instance1)
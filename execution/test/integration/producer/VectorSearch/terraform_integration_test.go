@@ -15,44 +15,55 @@ package integrationtest
 
 import (
 	"fmt"
+	"github.com/GoogleCloudPlatform/cloudnetworking-config-solutions/common_utils/fixture"
+	"github.com/GoogleCloudPlatform/cloudnetworking-config-solutions/common_utils/gcloud"
+	"github.com/GoogleCloudPlatform/cloudnetworking-config-solutions/common_utils/ipam"
+	"github.com/GoogleCloudPlatform/cloudnetworking-config-solutions/common_utils/naming"
+	"github.com/GoogleCloudPlatform/cloudnetworking-config-solutions/common_utils/preflight"
+	"github.com/GoogleCloudPlatform/cloudnetworking-config-solutions/common_utils/profile"
+	"github.com/GoogleCloudPlatform/cloudnetworking-config-solutions/common_utils/retryable"
+	"github.com/GoogleCloudPlatform/cloudnetworking-config-solutions/common_utils/schema"
+	"github.com/GoogleCloudPlatform/cloudnetworking-config-solutions/common_utils/wait"
 	"github.com/gruntwork-io/terratest/modules/shell"
 	"github.com/gruntwork-io/terratest/modules/terraform"
 	"github.com/tidwall/gjson"
 	"gopkg.in/yaml.v2"
 	"io"
-	"math/rand"
 	"os"
+	"strings"
 	"testing"
-	"time"
 )
 // Test configuration (adjust as needed)
 var (
-	projectID                 = os.Getenv("TF_VAR_project_id")
-	region                    = "us-central1"
+	env, envErr               = profile.Current()
+	projectID                 = env.Projects.Endpoint
+	region                    = env.Regions.Default
 	terraformDirectoryPath    = "../../../../04-producer/VectorSearch"
 	configFolderPath          = "../../test/integration/producer/VectorSearch/config"
 	indexUpdateMethod         = "BATCH_UPDATE"
-	indexDisplayName          = fmt.Sprintf("vectorsearch%d", rand.Int())
-	rangeName                 = fmt.Sprintf("psa-%s", indexDisplayName)
-	indexEndpointDisplayName  = fmt.Sprintf("indexendpoint-%s", indexDisplayName)
-	deployedIndexID           = fmt.Sprintf("deployedindexid_%s", indexDisplayName)
-	networkName               = fmt.Sprintf("vpc-%s-test", indexDisplayName)
 	dimension                 = 2
 	approximateNeighborsCount = 150
 )
 
-type VectorSearchStruct struct {
-	ProjectID                 string `yaml:"project_id"`
-	Region                    string `yaml:"region"`
-	IndexDisplayName          string `yaml:"index_display_name"`
-	IndexDescription          string `yaml:"index_description"`
-	Dimension                 int    `yaml:"dimension"`
-	ApproximateNeighborsCount int    `yaml:"approximate_neighbors_count"`
-	IndexUpdateMethod         string `yaml:"index_update_method"`
-	IndexEndpointDisplayname  string `yaml:"index_endpoint_display_name"`
-	IndexEndpointNetwork      string `yaml:"index_endpoint_network"`
-	BruteForceConfig          string `yaml:"brute_force_config"`
-	DeployedIndexId           string `yaml:"deployed_index_id"`
+// The names below are derived from the run and the test by setNames.
+var (
+	indexDisplayName         string
+	rangeName                string
+	indexEndpointDisplayName string
+	deployedIndexID          string
+	networkName              string
+)
+
+// TestMain checks the APIs, roles and quota the suite needs before the network
+// and the index endpoint are created.
+func TestMain(m *testing.M) {
+	os.Exit(preflight.Run(m, preflight.Requirements{
+		Profile: envErr,
+		Project: projectID,
+		APIs:    []string{"aiplatform.googleapis.com", "compute.googleapis.com", "servicenetworking.googleapis.com"},
+		Roles:   []string{"roles/aiplatform.admin", "roles/compute.networkAdmin", "roles/servicenetworking.networksAdmin"},
+		Quotas:  []preflight.Quota{{Metric: "NETWORKS"}},
+	}))
 }
 
 /*
@@ -60,6 +71,8 @@ TestCreateVectorSearch creates a vector search index, index endpoint and deploys
 performs verification on successfull creation of the vector search resources.
 */
 func TestCreateVectorSearch(t *testing.T) {
+	profile.Require(t, "projects.endpoint")
+	setNames(t)
 	// Initialize a Vector Search config YAML file to be tested.
 	createConfigYAML(t)
 
@@ -92,7 +105,7 @@ func TestCreateVectorSearch(t *testing.T) {
 			"config_folder_path": configFolderPath,
 		}
 	)
-	terraformOptions := terraform.WithDefaultRetryableErrors(t, &terraform.Options{
+	terraformOptions := retryable.With(t, &terraform.Options{
 		// Set the path to the Terraform code that will be tested.
 		Vars:                 tfVars,
 		TerraformDir:         terraformDirectoryPath,
@@ -102,22 +115,17 @@ func TestCreateVectorSearch(t *testing.T) {
 		SetVarsAfterVarFiles: true,
 	})
 	// Create VPC outside of the terraform module.
-	err := createVPC(t, projectID, networkName)
+	network, err := createVPC(t, projectID, networkName)
 	if err != nil {
 		t.Error(err)
 	}
-	// Create PSA in the VPC.
-	createPSA(t, projectID, networkName, rangeName)
-	// Delete VPC created outside of the terraform module.
-	defer deleteVPC(t, projectID, networkName)
-	// Remove PSA from the VPC.
-	defer deletePSA(t, projectID, networkName, rangeName)
+	// Create PSA in the VPC. The PSA and the VPC are removed at the end of the
+	// test, after "terraform destroy".
+	psa := createPSA(t, projectID, networkName, rangeName, network)
 	// Clean up resources with "terraform destroy" at the end of the test.
-	defer terraform.Destroy(t, terraformOptions)
+	fixture.Terraform(t, terraformOptions, psa)
 	// Run "terraform init" and "terraform apply". Fail the test if there are any errors.
 	terraform.InitAndApply(t, terraformOptions)
-	// Wait for 60 seconds to let resource acheive stable state.
-	time.Sleep(60 * time.Second)
 	// Run `terraform output` to get the values of output variables and check they have the expected values.
 	vectorSearchOutputValue := terraform.OutputJson(t, terraformOptions, "vector_search_instance_details")
 	t.Log(" ========= Terraform resource creation completed ========= ")
@@ -129,6 +137,12 @@ func TestCreateVectorSearch(t *testing.T) {
 	indexEndpointNamePath := fmt.Sprintf("%s.index_endpoint_name", indexDisplayName)
 	indexID := gjson.Get(result.String(), indexNamePath).String()
 	indexEndpointID := gjson.Get(result.String(), indexEndpointNamePath).String()
+	// Wait for the index endpoint to be readable before verifying.
+	client := gcloud.New(projectID)
+	wait.For(t, "index endpoint "+indexEndpointID+" to exist", wait.DefaultOptions, wait.Exists(func() error {
+		_, err := client.Run(t, "ai", "index-endpoints", "describe", indexEndpointID, "--region="+region)
+		return err
+	}))
 
 	t.Log(" ========= Verify Vector Search Index ID name ========= ")
 	indexIDPath := fmt.Sprintf("%s.index_id", indexDisplayName)
@@ -146,96 +160,56 @@ func TestCreateVectorSearch(t *testing.T) {
 	}
 }
 
-/*
-deleteVPC is a helper function which deletes the VPC after
-completion of the test.
-*/
-func deleteVPC(t *testing.T, projectID string, networkName string) {
-	time.Sleep(60 * time.Second)
-	text := "compute"
-	time.Sleep(60 * time.Second)
-	cmd := shell.Command{
-		Command: "gcloud",
-		Args:    []string{text, "networks", "delete", networkName, "--project=" + projectID, "--quiet"},
-	}
-	_, err := shell.RunCommandAndGetOutputE(t, cmd)
-	if err != nil {
-		t.Errorf("===Error %s Encountered while executing %s", err, text)
-	}
-}
-
-/*
-deletePSA is a helper function which deletes the PSA range after the
-execution of the test.
-*/
-func deletePSA(t *testing.T, projectID string, networkName string, rangeName string) {
-	// Delete PSA IP range
-	time.Sleep(60 * time.Second)
-	text := "compute"
-	cmd := shell.Command{
-		Command: "gcloud",
-		Args:    []string{text, "addresses", "delete", rangeName, "--project=" + projectID, "--global", "--verbosity=info", "--format=json", "--quiet"},
-	}
-	_, err := shell.RunCommandAndGetOutputE(t, cmd)
-	if err != nil {
-		t.Logf("===Error %s Encountered while executing %s", err, text)
-	}
-	time.Sleep(60 * time.Second)
-	// Delete PSA range
-	text = "services"
-	cmd = shell.Command{
-		Command: "gcloud",
-		Args:    []string{text, "vpc-peerings", "delete", "--service=servicenetworking.googleapis.com", "--project=" + projectID, "--network=" + networkName, "--verbosity=info", "--format=json", "--quiet"},
-	}
-	_, err = shell.RunCommandAndGetOutputE(t, cmd)
-	if err != nil {
-		t.Logf("===Error %s Encountered while executing %s", err, text)
-	}
-}
-
 /*
 createVPC is a helper function which creates the VPC before the
-execution of the test.
+execution of the test. The VPC is deleted when the test ends.
 */
-func createVPC(t *testing.T, projectID string, networkName string) error {
-	text := "compute"
-	cmd := shell.Command{
-		Command: "gcloud",
-		Args:    []string{text, "networks", "create", networkName, "--project=" + projectID, "--format=json", "--bgp-routing-mode=global", "--subnet-mode=custom", "--verbosity=info","--quiet"},
-	}
-	_, err := shell.RunCommandAndGetOutputE(t, cmd)
+func createVPC(t *testing.T, projectID string, networkName string) (*fixture.Handle, error) {
+	network, err := fixture.Network(t, gcloud.New(projectID), networkName, gcloud.NetworkOptions{BGPRoutingMode: "global"})
 	if err != nil {
-		t.Errorf("===Error %s Encountered while executing %s", err, text)
+		t.Errorf("===Error %s Encountered while creating network %s", err, networkName)
 	}
-	return err
+	return network, err
 }
 
 /*
 createPSA is a helper function which creates the PSA range before the
-execution of the test.
+execution of the test, with a /20 leased from the ipam package. The connection
+and the range are removed when the test ends, before the VPC.
 */
-func createPSA(t *testing.T, projectID string, networkName string, rangeName string) {
+func createPSA(t *testing.T, projectID string, networkName string, rangeName string, network *fixture.Handle) *fixture.Handle {
+	client := gcloud.New(projectID)
 	// Create an IP range
-	text := "compute"
-	cmd := shell.Command{
-		Command: "gcloud",
-		Args:    []string{text, "addresses", "create", rangeName, "--purpose=VPC_PEERING", "--addresses=10.0.64.0", "--prefix-length=20", "--project=" + projectID, "--network=" + networkName, "--global", "--verbosity=info", "--format=json","--quiet"},
-	}
-	_, err := shell.RunCommandAndGetOutputE(t, cmd)
+	psaRange := ipam.PSARange(t, 20)
+	address, err := fixture.Address(t, client, rangeName, gcloud.AddressOptions{
+		Purpose:      "VPC_PEERING",
+		Address:      ipam.Host(psaRange, 0),
+		PrefixLength: 20,
+		Network:      networkName,
+	}, network)
 	if err != nil {
-		t.Errorf("===Error %s Encountered while executing %s", err, text)
+		t.Errorf("===Error %s Encountered while creating address %s", err, rangeName)
 	}
 	// Create PSA range
-	text = "services"
-	cmd = shell.Command{
-		Command: "gcloud",
-		Args:    []string{text, "vpc-peerings", "connect", "--service=servicenetworking.googleapis.com", "--ranges=" + rangeName, "--project=" + projectID, "--network=" + networkName, "--verbosity=info", "--format=json","--quiet"},
-	}
-	_, err = shell.RunCommandAndGetOutputE(t, cmd)
+	peering, err := fixture.VPCPeering(t, client, networkName, []string{rangeName}, address)
 	if err != nil {
-		t.Errorf("===Error %s Encountered while executing %s", err, text)
+		t.Errorf("===Error %s Encountered while connecting vpc peering of %s", err, networkName)
 	}
-	time.Sleep(60 * time.Second)
+	wait.For(t, "vpc peering of "+networkName+" to be connected", wait.DefaultOptions, wait.VPCPeeringConnected(t, client, networkName))
+	return peering
+}
+
+/*
+setNames is a helper function which derives the names of the index, of its
+endpoint and of their network from the run and the test. Deployed index IDs
+admit no hyphens, so they are replaced by underscores.
+*/
+func setNames(t *testing.T) {
+	indexDisplayName = naming.Name(t, naming.Compute, "vectorsearch")
+	rangeName = fmt.Sprintf("psa-%s", indexDisplayName)
+	indexEndpointDisplayName = fmt.Sprintf("indexendpoint-%s", indexDisplayName)
+	deployedIndexID = fmt.Sprintf("deployedindexid_%s", strings.ReplaceAll(indexDisplayName, "-", "_"))
+	networkName = fmt.Sprintf("vpc-%s-test", indexDisplayName)
 }
 
 /*
@@ -256,17 +230,18 @@ func createConfigYAML(t *testing.T) {
 	t.Log("========= YAML File =========")
 	indexEndpointNetwork := fmt.Sprintf("projects/%s/global/networks/%s", projectNumber, networkName)
 	t.Logf("Index Endpoint Network : %s", indexEndpointNetwork)
-	instance1 := VectorSearchStruct{
+	instance1 := schema.VectorSearch{
 		ProjectID:                 projectID,
 		Region:                    region,
 		IndexDisplayName:          indexDisplayName,
-		ApproximateNeighborsCount: approximateNeighborsCount,
-		Dimension:                 dimension,
+		ApproximateNeighborsCount: schema.Int(approximateNeighborsCount),
+		Dimension:                 schema.Int(dimension),
 		IndexUpdateMethod:         indexUpdateMethod,
-		IndexEndpointDisplayname:  indexEndpointDisplayName,
+		IndexEndpointDisplayName:  indexEndpointDisplayName,
 		IndexEndpointNetwork:      indexEndpointNetwork,
-		BruteForceConfig:          "",
-		DeployedIndexId:           deployedIndexID,
+		DeployedIndexID:           deployedIndexID,
+		IndexLabels:               naming.Labels(t),
+		IndexEndpointLabels:       naming.Labels(t),
 	}
 	yamlData, err := yaml.Marshal(&instance1)
 	if err != nil {
@@ -19,13 +19,18 @@ import (
 	"os"
 	"path/filepath"
 	"testing"
-	"time"
 
+	"github.com/GoogleCloudPlatform/cloudnetworking-config-solutions/common_utils/gcloud"
+	"github.com/GoogleCloudPlatform/cloudnetworking-config-solutions/common_utils/ipam"
+	"github.com/GoogleCloudPlatform/cloudnetworking-config-solutions/common_utils/naming"
+	"github.com/GoogleCloudPlatform/cloudnetworking-config-solutions/common_utils/preflight"
+	"github.com/GoogleCloudPlatform/cloudnetworking-config-solutions/common_utils/profile"
+	"github.com/GoogleCloudPlatform/cloudnetworking-config-solutions/common_utils/retryable"
+	"github.com/GoogleCloudPlatform/cloudnetworking-config-solutions/common_utils/schema"
+	"github.com/GoogleCloudPlatform/cloudnetworking-config-solutions/common_utils/wait"
 	"github.com/gruntwork-io/terratest/modules/shell"
 	"github.com/gruntwork-io/terratest/modules/terraform"
 	"github.com/tidwall/gjson"
-	"golang.org/x/exp/rand"
-	"gopkg.in/yaml.v2"
 )
 
 var (
@@ -36,26 +41,20 @@ var (
 
 	// Path to the main Terraform directory for the VertexAI module.
 	configFolderPath = filepath.Join(projectRoot, "Vertex-AI-Online-Endpoints/config")
-	projectID        = os.Getenv("TF_VAR_project_id")
-	region           = "us-central1"
-	psaRangeName     = "psa-range-cncs-test"
+	env, envErr      = profile.Current()
+	projectID        = env.Projects.Endpoint
+	region           = env.Regions.Default
+	// The name below is derived from the run and the test by
+	// TestCreateEndpointWithVPC.
+	psaRangeName string
 )
 
-type EndpointConfig struct {
-	Name        string `yaml:"name"`
-	Project     string `yaml:"project"`
-	DisplayName string `yaml:"display_name"`
-	Description string `yaml:"description"`
-	Location    string `yaml:"location"`
-	Region      string `yaml:"region"`
-	Network     string `yaml:"network"`
-}
-
 // TestCreateEndpointWithVPC creates a VPC and then creates an Vertex AI Online Endpoint with the new VPC
 func TestCreateEndpointWithVPC(t *testing.T) {
+	profile.Require(t, "projects.endpoint")
 
-	timestamp := time.Now().Format("20060102150405")
-	VPCName := fmt.Sprintf("vpc-%s-%d", timestamp, rand.Intn(100000))
+	VPCName := naming.Name(t, naming.Compute, "vpc")
+	psaRangeName = naming.Name(t, naming.Compute, "psa-range-cncs-test")
 
 	createVPC(t, projectID, VPCName)
 	defer deleteVPC(t, projectID, VPCName)
@@ -68,7 +67,7 @@ func TestCreateEndpointWithVPC(t *testing.T) {
 		}
 	)
 
-	terraformOptions := terraform.WithDefaultRetryableErrors(t, &terraform.Options{
+	terraformOptions := retryable.With(t, &terraform.Options{
 		Vars:                 tfVars,
 		TerraformDir:         terraformDirectoryPath,
 		Reconfigure:          true,
@@ -94,103 +93,74 @@ func TestCreateEndpointWithVPC(t *testing.T) {
 func createEndpointConfigYAML(t *testing.T, vpcName string, fileName string) {
 	t.Log("========= YAML File =========")
 
-	// Generate a unique endpoint name with a timestamp
-	endpointName := fmt.Sprintf("vertexai-name-%s-%08d", time.Now().Format("20060102150405"), rand.Intn(100000000))
+	// The endpoint is named after the run and the test.
+	endpointName := naming.Name(t, naming.Compute, "vertexai-name")
 
-	endpointConfig := EndpointConfig{
+	endpointConfig := schema.VertexEndpoint{
 		Name:        endpointName,
 		Project:     projectID,
-		DisplayName: fmt.Sprintf("vertexai-displayname-%s-%08d", time.Now().Format("20060102150405"), rand.Intn(100000000)),
+		DisplayName: naming.Name(t, naming.Compute, "vertexai-displayname"),
 		Description: "test-description",
 		Location:    region,
 		Region:      region,
 		Network:     fmt.Sprintf("projects/%s/global/networks/%s", getProjectNumber(t, projectID), vpcName),
+		Labels:      naming.Labels(t),
 	}
 
-	yamlData, err := yaml.Marshal(&endpointConfig)
-	if err != nil {
-		t.Errorf("Error while marshalling %v", err)
-	}
-
-	filePath := fmt.Sprintf("%s/%s", configFolderPath, fileName)
-	t.Logf("Created YAML config at %s with content:\n%s", filePath, string(yamlData))
-
-	err = os.WriteFile(filePath, []byte(yamlData), 0666)
-	if err != nil {
-		t.Errorf("Unable to write data into the file %v", err)
+	filePath := filepath.Join(configFolderPath, fileName)
+	if err := schema.Write(filePath, endpointConfig); err != nil {
+		t.Errorf("Unable to write the config %s: %v", filePath, err)
 	}
+	t.Logf("Created YAML config at %s", filePath)
 }
 
-// readEndpointConfigYAML reads the YAML file and returns the EndpointConfig struct
-func readEndpointConfigYAML(fileName string) (*EndpointConfig, error) {
-	filePath := fmt.Sprintf("%s/%s", configFolderPath, fileName)
-	yamlData, err := os.ReadFile(filePath)
-	if err != nil {
-		return nil, fmt.Errorf("unable to read YAML file: %v", err)
-	}
-
-	var config EndpointConfig
-	err = yaml.Unmarshal(yamlData, &config)
-	if err != nil {
-		return nil, fmt.Errorf("unable to unmarshal YAML data: %v", err)
+// readEndpointConfigYAML reads the YAML file back, failing on any key that
+// schema.VertexEndpoint does not declare.
+func readEndpointConfigYAML(fileName string) (*schema.VertexEndpoint, error) {
+	var config schema.VertexEndpoint
+	if err := schema.Load(filepath.Join(configFolderPath, fileName), &config); err != nil {
+		return nil, err
 	}
-
 	return &config, nil
 }
 
 // validateEndpoints validates the endpoints created by the Terraform module
 func validateEndpoints(t *testing.T, terraformOptions *terraform.Options, expectedNetwork string) {
-	maxRetries := 10
-	retryInterval := 10 * time.Second
-	endpointsReady := false
+	endpointOutputValue := terraform.OutputJson(t, terraformOptions, "endpoint_configurations")
+	if !gjson.Valid(endpointOutputValue) {
+		t.Errorf("Error parsing output, invalid json: %s", endpointOutputValue)
+		return
+	}
 
-	for i := 0; i < maxRetries; i++ {
-		endpointOutputValue := terraform.OutputJson(t, terraformOptions, "endpoint_configurations")
+	result := gjson.Parse(endpointOutputValue)
+	result.ForEach(func(key, value gjson.Result) bool {
+		expectedDisplayName := key.String()
 
-		if !gjson.Valid(endpointOutputValue) {
-			t.Errorf("Error parsing output, invalid json: %s", endpointOutputValue)
-			continue
+		// Verify Endpoint Display Name
+		gotDisplayName := value.Get("display_name").String()
+		if gotDisplayName != expectedDisplayName {
+			t.Errorf("Endpoint '%s' has invalid display_name: got %s, want %s", expectedDisplayName, gotDisplayName, expectedDisplayName)
+		} else {
+			t.Logf("Endpoint has valid display_name")
 		}
 
-		result := gjson.Parse(endpointOutputValue)
-		endpointsReady = true
-
-		result.ForEach(func(key, value gjson.Result) bool {
-			expectedDisplayName := key.String()
-
-			// Verify Endpoint Display Name
-			gotDisplayName := value.Get("display_name").String()
-			if gotDisplayName != expectedDisplayName {
-				t.Errorf("Endpoint '%s' has invalid display_name: got %s, want %s", expectedDisplayName, gotDisplayName, expectedDisplayName)
-			} else {
-				t.Logf("Endpoint has valid display_name")
-			}
-
-			// Verify Endpoint Network (using the expectedNetwork argument)
-			gotNetwork := value.Get("network").String()
-			if gotNetwork != expectedNetwork {
-				t.Errorf("Endpoint '%s' has invalid network: got %s, want %s", expectedDisplayName, gotNetwork, expectedNetwork)
-			} else {
-				t.Logf("Endpoint has valid network")
-			}
-
-			return true
-		})
-
-		if endpointsReady {
-			break
+		// Verify Endpoint Network (using the expectedNetwork argument)
+		gotNetwork := value.Get("network").String()
+		if gotNetwork != expectedNetwork {
+			t.Errorf("Endpoint '%s' has invalid network: got %s, want %s", expectedDisplayName, gotNetwork, expectedNetwork)
 		} else {
-			t.Errorf("Endpoints not ready after %d retries", maxRetries)
-			time.Sleep(retryInterval)
+			t.Logf("Endpoint has valid network")
 		}
-	}
+
+		return true
+	})
 }
 
 // getProjectNumber gets the Project Number for the Endpoint configuration
 func getProjectNumber(t *testing.T, projectID string) string {
 	cmd := shell.Command{
 		Command: "gcloud",
-		Args:    []string{"projects", "describe", projectID, "--format=value(projectNumber)","--quiet"},
+		Args:    []string{"projects", "describe", projectID, "--format=value(projectNumber)", "--quiet"},
 	}
 	output, err := shell.RunCommandAndGetOutputE(t, cmd)
 	if err != nil {
@@ -206,7 +176,7 @@ func createVPC(t *testing.T, projectID string, networkName string) {
 	// Create VPC
 	cmd := shell.Command{
 		Command: "gcloud",
-		Args:    []string{text, "networks", "create", networkName, "--project=" + projectID, "--format=json", "--bgp-routing-mode=global", "--subnet-mode=custom", "--verbosity=none","--quiet"},
+		Args:    []string{text, "networks", "create", networkName, "--project=" + projectID, "--format=json", "--bgp-routing-mode=global", "--subnet-mode=custom", "--verbosity=none", "--quiet"},
 	}
 	_, err := shell.RunCommandAndGetOutputE(t, cmd)
 	if err != nil {
@@ -214,7 +184,7 @@ func createVPC(t *testing.T, projectID string, networkName string) {
 	}
 
 	// Wait for VPC creation to propagate
-	time.Sleep(60 * time.Second)
+	wait.For(t, "network "+networkName+" to exist", wait.DefaultOptions, wait.NetworkExists(t, gcloud.New(projectID), networkName))
 
 	// Create Subnet
 	subnetName := fmt.Sprintf("%s-subnet", networkName)
@@ -225,7 +195,7 @@ func createVPC(t *testing.T, projectID string, networkName string) {
 			"--project=" + projectID,
 			"--network=" + networkName,
 			"--region=" + region,
-			"--range=10.0.0.0/24",
+			"--range=" + ipam.Subnet(t, 24),
 			"--quiet",
 		},
 	}
@@ -280,33 +250,22 @@ func enablePrivateServiceAccess(t *testing.T, projectID, networkName, psaRangeNa
 
 // deletePSARange deletes the PSA range after testing
 func deletePSARange(t *testing.T, projectID string, psaRangeName string) {
-	cmd := shell.Command{
-		Command: "gcloud",
-		Args: []string{
-			"compute", "addresses", "delete", psaRangeName,
-			"--project=" + projectID,
-			"--global",
-			"--quiet",
-		},
-	}
-	_, err := shell.RunCommandAndGetOutputE(t, cmd)
+	client := gcloud.New(projectID)
+	_, err := wait.ForE(t, "address "+psaRangeName+" to be deleted", wait.DefaultOptions, wait.Deletes(func() error {
+		return client.Addresses.Delete(t, "", psaRangeName)
+	}))
 	if err != nil {
 		t.Errorf("Error deleting internal IP range: %v", err)
 	}
 }
 
-// deletePSAConnection deletes the PSA connection after testing
+// deletePSAConnection deletes the PSA connection after testing, retrying
+// while the endpoints still hold it.
 func deletePSAConnection(t *testing.T, projectID string, networkName string) {
-	cmd := shell.Command{
-		Command: "gcloud",
-		Args: []string{
-			"services", "vpc-peerings", "delete", "--service=servicenetworking.googleapis.com",
-			"--network=" + networkName,
-			"--project=" + projectID,
-			"--quiet",
-		},
-	}
-	_, err := shell.RunCommandAndGetOutputE(t, cmd)
+	client := gcloud.New(projectID)
+	_, err := wait.ForE(t, "vpc peering of "+networkName+" to be deleted", wait.DefaultOptions, wait.Deletes(func() error {
+		return client.VPCPeerings.Delete(t, networkName)
+	}))
 	if err != nil {
 		t.Errorf("Error deleting PSA Connection: %v", err)
 	}
@@ -314,37 +273,26 @@ func deletePSAConnection(t *testing.T, projectID string, networkName string) {
 
 // deleteVPC deletes the VPC and subnets after testing
 func deleteVPC(t *testing.T, projectID string, networkName string) {
-	text := "compute"
-	time.Sleep(60 * time.Second)
+	client := gcloud.New(projectID)
 
-	deletePSARange(t, projectID, psaRangeName)
 	deletePSAConnection(t, projectID, networkName)
+	deletePSARange(t, projectID, psaRangeName)
 
 	// Delete Subnet
 	subnetName := fmt.Sprintf("%s-subnet", networkName)
-	cmd := shell.Command{
-		Command: "gcloud",
-		Args: []string{
-			text, "networks", "subnets", "delete", subnetName,
-			"--project=" + projectID,
-			"--region=" + region,
-			"--quiet",
-		},
-	}
-	_, err := shell.RunCommandAndGetOutputE(t, cmd)
+	_, err := wait.ForE(t, "subnetwork "+subnetName+" to be deleted", wait.DefaultOptions, wait.Deletes(func() error {
+		return client.Subnets.Delete(t, region, subnetName)
+	}))
 	if err != nil {
-		t.Errorf("===Error %s Encountered while executing %s", err, text)
+		t.Errorf("===Error %s Encountered while deleting subnetwork %s", err, subnetName)
 	}
 
 	// Delete VPC
-	time.Sleep(120 * time.Second)
-	cmd = shell.Command{
-		Command: "gcloud",
-		Args:    []string{text, "networks", "delete", networkName, "--project=" + projectID, "--quiet"},
-	}
-	_, err = shell.RunCommandAndGetOutputE(t, cmd)
+	_, err = wait.ForE(t, "network "+networkName+" to be deleted", wait.DefaultOptions, wait.Deletes(func() error {
+		return client.Networks.Delete(t, networkName)
+	}))
 	if err != nil {
-		t.Errorf("===Error %s Encountered while executing %s", err, text)
+		t.Errorf("===Error %s Encountered while deleting network %s", err, networkName)
 	}
 }
 
@@ -356,7 +304,12 @@ func TestMain(m *testing.M) {
 		os.Exit(1) // Exit after tests complete
 	}
 
-	os.Exit(m.Run())
+	os.Exit(preflight.Run(m, preflight.Requirements{
+		Profile: envErr,
+		Project: projectID,
+		APIs:    []string{"aiplatform.googleapis.com", "compute.googleapis.com", "servicenetworking.googleapis.com"},
+		Roles:   []string{"roles/aiplatform.admin", "roles/compute.networkAdmin", "roles/servicenetworking.networksAdmin"},
+	}))
 }
 
 // cleanupYAMLFiles cleans up unused old YAML files.
@@ -16,25 +16,40 @@ package integrationtest
 
 import (
 	"fmt"
-	"math/rand"
 	"os"
 	"testing"
-	"time"
 
-	"github.com/gruntwork-io/terratest/modules/shell"
+	"github.com/GoogleCloudPlatform/cloudnetworking-config-solutions/common_utils/fakeapi"
+	"github.com/GoogleCloudPlatform/cloudnetworking-config-solutions/common_utils/gcloud"
+	"github.com/GoogleCloudPlatform/cloudnetworking-config-solutions/common_utils/naming"
+	"github.com/GoogleCloudPlatform/cloudnetworking-config-solutions/common_utils/preflight"
+	"github.com/GoogleCloudPlatform/cloudnetworking-config-solutions/common_utils/profile"
+	"github.com/GoogleCloudPlatform/cloudnetworking-config-solutions/common_utils/retryable"
+	"github.com/GoogleCloudPlatform/cloudnetworking-config-solutions/common_utils/wait"
 	"github.com/gruntwork-io/terratest/modules/terraform"
 	"github.com/tidwall/gjson"
 )
 
 var (
 	terraformDirectoryPath = "../../../../03-security/AlloyDB"
-	projectID              = os.Getenv("TF_VAR_project_id")
-	uniqueID               = rand.Int() //included as a suffix to the VPC and subnet names.
-	networkName            = fmt.Sprintf("test-vpc-security-%d", uniqueID)
+	env, envErr            = profile.Current()
+	projectID              = env.Projects.Endpoint
 	firewallName           = "test-allow-egress-alloydb"
 	firewallDirection      = "EGRESS"
 )
 
+// TestMain checks the APIs, roles and quota the suite needs before the
+// firewall rules are created.
+func TestMain(m *testing.M) {
+	os.Exit(preflight.Run(m, preflight.Requirements{
+		Profile: envErr,
+		Project: projectID,
+		APIs:    []string{"compute.googleapis.com"},
+		Roles:   []string{"roles/compute.securityAdmin", "roles/compute.networkAdmin"},
+		Quotas:  []preflight.Quota{{Metric: "NETWORKS"}, {Metric: "FIREWALLS"}},
+	}))
+}
+
 /*
 This test creates all the resources including the vpc network, subnetwork along with a PSA range.
 
@@ -43,6 +58,9 @@ It then validates if
 2. Firewall Rule with correct direction is created
 */
 func TestCreateAlloyDBFirewallRule(t *testing.T) {
+	profile.Require(t, "projects.endpoint")
+	fakeapi.Use(t)
+	networkName := naming.Name(t, naming.Compute, "test-vpc-security")
 	var (
 		tfVars = map[string]any{
 			"project_id": projectID,
@@ -60,7 +78,7 @@ func TestCreateAlloyDBFirewallRule(t *testing.T) {
 			},
 		}
 	)
-	terraformOptions := terraform.WithDefaultRetryableErrors(t, &terraform.Options{
+	terraformOptions := retryable.With(t, &terraform.Options{
 		// Set the path to the Terraform code that will be tested.
 		Vars:                 tfVars,
 		TerraformDir:         terraformDirectoryPath,
@@ -81,8 +99,8 @@ func TestCreateAlloyDBFirewallRule(t *testing.T) {
 	// Run "terraform init" and "terraform apply". Fail the test if there are any errors.
 	terraform.InitAndApply(t, terraformOptions)
 
-	// Wait for 60 seconds to let resource acheive stable state.
-	time.Sleep(60 * time.Second)
+	// Wait for the firewall rule to be readable before verifying.
+	wait.For(t, "firewall rule "+firewallName+" to exist", wait.DefaultOptions, wait.FirewallExists(t, gcloud.New(projectID), firewallName))
 
 	// Run `terraform output` to get the values of output variables and check they have the expected values.
 	want := firewallName
@@ -112,35 +130,27 @@ func TestCreateAlloyDBFirewallRule(t *testing.T) {
 
 /*
 deleteVPC is a helper function which deletes the VPC after
-completion of the test.
+completion of the test, retrying while terraform destroy releases it.
 */
 func deleteVPC(t *testing.T, projectID string, networkName string) {
-	text := "compute"
-	time.Sleep(60 * time.Second)
-	cmd := shell.Command{
-		Command: "gcloud",
-		Args:    []string{text, "networks", "delete", networkName, "--project=" + projectID, "--quiet"},
-	}
-	_, err := shell.RunCommandAndGetOutputE(t, cmd)
+	client := gcloud.New(projectID)
+	_, err := wait.ForE(t, "network "+networkName+" to be deleted", wait.DefaultOptions, wait.Deletes(func() error {
+		return client.Networks.Delete(t, networkName)
+	}))
 	if err != nil {
-		t.Errorf("===Error %s Encountered while executing %s", err, text)
+		t.Errorf("===Error %s Encountered while deleting network %s", err, networkName)
 	}
 }
 
 /*
- createVPC is a helper function which creates the VPC before the
- execution of the test.
+createVPC is a helper function which creates the VPC before the
+execution of the test.
 */
-
 func createVPC(t *testing.T, projectID string, networkName string) {
-	text := "compute"
-	cmd := shell.Command{
-		Command: "gcloud",
-		Args:    []string{text, "networks", "create", networkName, "--project=" + projectID, "--format=json", "--bgp-routing-mode=global", "--subnet-mode=custom", "--verbosity=none"},
-	}
-	_, err := shell.RunCommandAndGetOutputE(t, cmd)
-	if err != nil {
-		t.Errorf("===Error %s Encountered while executing %s", err, text)
+	client := gcloud.New(projectID)
+	if _, err := client.Networks.Create(t, networkName, gcloud.NetworkOptions{BGPRoutingMode: "global"}); err != nil {
+		t.Errorf("===Error %s Encountered while creating network %s", err, networkName)
+		return
 	}
-	time.Sleep(60 * time.Second)
+	wait.For(t, "network "+networkName+" to exist", wait.DefaultOptions, wait.NetworkExists(t, client, networkName))
 }
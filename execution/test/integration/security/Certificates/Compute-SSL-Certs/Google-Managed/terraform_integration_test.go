@@ -16,12 +16,15 @@ package integrationtest
 
 import (
 	"fmt"
-	"math/rand"
 	"os"
 	"strings"
 	"testing"
 	"time"
 
+	"github.com/GoogleCloudPlatform/cloudnetworking-config-solutions/common_utils/naming"
+	"github.com/GoogleCloudPlatform/cloudnetworking-config-solutions/common_utils/preflight"
+	"github.com/GoogleCloudPlatform/cloudnetworking-config-solutions/common_utils/profile"
+	"github.com/GoogleCloudPlatform/cloudnetworking-config-solutions/common_utils/retryable"
 	"github.com/gruntwork-io/terratest/modules/retry"
 	"github.com/gruntwork-io/terratest/modules/shell"
 	"github.com/gruntwork-io/terratest/modules/terraform"
@@ -32,11 +35,24 @@ import (
 var (
 	// Path to the Terraform configuration that uses the SSL certificate module
 	terraformSslModulePath    = "../../../../../../03-security/Certificates/Compute-SSL-Certs/Google-Managed/"
-	projectID                 = os.Getenv("TF_VAR_project_id")
+	env, envErr               = profile.Current()
+	projectID                 = env.Projects.Endpoint
 	sslCertificateTypeManaged = "MANAGED"
 	sslCertificatePathPrefix  = "/global/sslCertificates/"
 )
 
+// TestMain checks the APIs, roles and quota the suite needs before the
+// certificate is created.
+func TestMain(m *testing.M) {
+	os.Exit(preflight.Run(m, preflight.Requirements{
+		Profile: envErr,
+		Project: projectID,
+		APIs:    []string{"compute.googleapis.com"},
+		Roles:   []string{"roles/compute.loadBalancerAdmin"},
+		Quotas:  []preflight.Quota{{Metric: "SSL_CERTIFICATES"}},
+	}))
+}
+
 // CertificateDetails struct to hold relevant information from gcloud describe output
 type CertificateDetails struct {
 	Name              string   `json:"name"`
@@ -48,16 +64,11 @@ type CertificateDetails struct {
 }
 
 func TestCreateGoogleManagedSslCertificate(t *testing.T) {
+	profile.Require(t, "projects.endpoint")
 	t.Parallel() // Mark test as parallelizable
 
-	// Ensure projectID is set
-	if projectID == "" {
-		t.Fatal("TF_VAR_project_id environment variable must be set.")
-	}
-
-	uniqueID := rand.Int() // Use uniqueID to ensure unique resource names
-	sslCertificateName := fmt.Sprintf("test-managed-cert-%d", uniqueID)
-	domainName := fmt.Sprintf("terratest-managed-cert-%d.example.com", uniqueID)
+	sslCertificateName := naming.Name(t, naming.Compute, "test-managed-cert")
+	domainName := fmt.Sprintf("%s.example.com", sslCertificateName)
 
 	// Variables for the SSL Certificate Terraform module
 	tfSslVars := map[string]interface{}{
@@ -71,7 +82,7 @@ func TestCreateGoogleManagedSslCertificate(t *testing.T) {
 		},
 	}
 
-	terraformSslOptions := terraform.WithDefaultRetryableErrors(t, &terraform.Options{
+	terraformSslOptions := retryable.With(t, &terraform.Options{
 		TerraformDir:         terraformSslModulePath,
 		Vars:                 tfSslVars,
 		Reconfigure:          true,
@@ -16,30 +16,41 @@ package integrationtest
 
 import (
 	"fmt"
+	"github.com/GoogleCloudPlatform/cloudnetworking-config-solutions/common_utils/gcloud"
+	"github.com/GoogleCloudPlatform/cloudnetworking-config-solutions/common_utils/naming"
+	"github.com/GoogleCloudPlatform/cloudnetworking-config-solutions/common_utils/preflight"
+	"github.com/GoogleCloudPlatform/cloudnetworking-config-solutions/common_utils/profile"
+	"github.com/GoogleCloudPlatform/cloudnetworking-config-solutions/common_utils/retryable"
+	"github.com/GoogleCloudPlatform/cloudnetworking-config-solutions/common_utils/wait"
 	"github.com/gruntwork-io/terratest/modules/shell"
 	"github.com/gruntwork-io/terratest/modules/terraform"
 	"github.com/tidwall/gjson"
 	"gopkg.in/yaml.v2"
-	"math/rand"
 	"os"
 	"testing"
-	"time"
 )
 
 var (
-	projectID                     = os.Getenv("TF_VAR_project_id")
-	region                        = "us-central1"
-	global                        = "global"
-	terraformDirectoryPath        = "../../../../../03-security/Firewall/FirewallPolicy"
-	configFolderPath              = "../../../test/integration/security/Firewall/FirewallPolicy/config"
-	uniqueIdentifier              = fmt.Sprint(rand.Int())
-	networkName                   = fmt.Sprintf("vpc-%s-test", uniqueIdentifier)
-	regionalFirewallPolicy        = fmt.Sprintf("regionalfirewallpolicy-%s", "test")
-	globalFirewallPolicy          = fmt.Sprintf("globalfirewallpolicy-%s", "test")
-	regionalFirewallPolicyVPCName = fmt.Sprintf("regionalfirewallpolicyvpc-%s", "test")
-	globalFirewallPolicyVPCName   = fmt.Sprintf("globalfirewallpolicyvpc-%s", "test")
+	env, envErr            = profile.Current()
+	projectID              = env.Projects.Endpoint
+	region                 = env.Regions.Default
+	global                 = "global"
+	terraformDirectoryPath = "../../../../../03-security/Firewall/FirewallPolicy"
+	configFolderPath       = "../../../test/integration/security/Firewall/FirewallPolicy/config"
 )
 
+// TestMain checks the APIs, roles and quota the suite needs before the
+// firewall policies are created.
+func TestMain(m *testing.M) {
+	os.Exit(preflight.Run(m, preflight.Requirements{
+		Profile: envErr,
+		Project: projectID,
+		APIs:    []string{"compute.googleapis.com"},
+		Roles:   []string{"roles/compute.securityAdmin", "roles/compute.networkAdmin"},
+		Quotas:  []preflight.Quota{{Metric: "NETWORKS", Need: 2}},
+	}))
+}
+
 type FirewallPolicyStruct struct {
 	Name        string            `yaml:"name"`
 	ParentID    string            `yaml:"parent_id"`
@@ -56,6 +67,11 @@ This test creates all the pre-requsite resources including the vpc network
 It then validates if Regional and Global network firewall policies are created and validates the same.
 */
 func TestCreateFirewallPolicy(t *testing.T) {
+	profile.Require(t, "projects.endpoint")
+	regionalFirewallPolicy := naming.Name(t, naming.Compute, "regionalfirewallpolicy-test")
+	globalFirewallPolicy := naming.Name(t, naming.Compute, "globalfirewallpolicy-test")
+	regionalFirewallPolicyVPCName := naming.Name(t, naming.Compute, "regionalfirewallpolicyvpc-test")
+	globalFirewallPolicyVPCName := naming.Name(t, naming.Compute, "globalfirewallpolicyvpc-test")
 	// Initialize Network Firewall Policy config YAML files
 	createConfigYAMLs(t, region, projectID, regionalFirewallPolicyVPCName, regionalFirewallPolicy)
 	createConfigYAMLs(t, global, projectID, globalFirewallPolicyVPCName, globalFirewallPolicy)
@@ -66,7 +82,7 @@ func TestCreateFirewallPolicy(t *testing.T) {
 		}
 	)
 
-	terraformOptions := terraform.WithDefaultRetryableErrors(t, &terraform.Options{
+	terraformOptions := retryable.With(t, &terraform.Options{
 		// Set the path to the Terraform code that will be tested.
 		Vars:                 tfVars,
 		TerraformDir:         terraformDirectoryPath,
@@ -94,10 +110,16 @@ func TestCreateFirewallPolicy(t *testing.T) {
 
 	// Run "terraform init" and "terraform apply". Fail the test if there are any errors.
 	terraform.InitAndApply(t, terraformOptions)
-	t.Log("Waiting for 60 seconds to let resource achieve stable state")
-	// Wait for 60 seconds to let resource acheive stable state.
-	time.Sleep(60 * time.Second)
-	t.Log("Waiting for 60 seconds to let resource achieve stable state")
+	// Wait for both firewall policies to be readable before verifying.
+	client := gcloud.New(projectID)
+	wait.For(t, "firewall policy "+regionalFirewallPolicy+" to exist", wait.DefaultOptions, wait.Exists(func() error {
+		_, err := client.Run(t, "compute", "network-firewall-policies", "describe", regionalFirewallPolicy, "--region="+region)
+		return err
+	}))
+	wait.For(t, "firewall policy "+globalFirewallPolicy+" to exist", wait.DefaultOptions, wait.Exists(func() error {
+		_, err := client.Run(t, "compute", "network-firewall-policies", "describe", globalFirewallPolicy, "--global")
+		return err
+	}))
 	// Run `terraform output` to get the values of output variables
 	firewallPolicyOutputValue := terraform.OutputJson(t, terraformOptions, "id")
 	if !gjson.Valid(firewallPolicyOutputValue) {
@@ -127,16 +149,12 @@ func TestCreateFirewallPolicy(t *testing.T) {
 // completion of the test.
 // */
 func deleteVPC(t *testing.T, projectID string, networkName string) {
-	text := "compute"
-	t.Log("Waiting for 60 seconds to let resource achieve stable state")
-	time.Sleep(60 * time.Second)
-	cmd := shell.Command{
-		Command: "gcloud",
-		Args:    []string{text, "networks", "delete", networkName, "--project=" + projectID, "--quiet"},
-	}
-	_, err := shell.RunCommandAndGetOutputE(t, cmd)
+	client := gcloud.New(projectID)
+	_, err := wait.ForE(t, "network "+networkName+" to be deleted", wait.DefaultOptions, wait.Deletes(func() error {
+		return client.Networks.Delete(t, networkName)
+	}))
 	if err != nil {
-		t.Fatalf("===Error %s Encountered while executing %s", err, text)
+		t.Fatalf("===Error %s Encountered while deleting network %s", err, networkName)
 	}
 }
 
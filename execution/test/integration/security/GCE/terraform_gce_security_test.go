@@ -15,13 +15,17 @@
 package integrationtest
 
 import (
-	"fmt"
-	"math/rand"
 	"os"
 	"testing"
-	"time"
 
-	"github.com/gruntwork-io/terratest/modules/shell"
+	"github.com/GoogleCloudPlatform/cloudnetworking-config-solutions/common_utils/fakeapi"
+	"github.com/GoogleCloudPlatform/cloudnetworking-config-solutions/common_utils/fixture"
+	"github.com/GoogleCloudPlatform/cloudnetworking-config-solutions/common_utils/gcloud"
+	"github.com/GoogleCloudPlatform/cloudnetworking-config-solutions/common_utils/naming"
+	"github.com/GoogleCloudPlatform/cloudnetworking-config-solutions/common_utils/preflight"
+	"github.com/GoogleCloudPlatform/cloudnetworking-config-solutions/common_utils/profile"
+	"github.com/GoogleCloudPlatform/cloudnetworking-config-solutions/common_utils/retryable"
+	"github.com/GoogleCloudPlatform/cloudnetworking-config-solutions/common_utils/wait"
 	"github.com/gruntwork-io/terratest/modules/terraform"
 	"github.com/stretchr/testify/assert"
 	"github.com/tidwall/gjson"
@@ -29,13 +33,27 @@ import (
 
 var (
 	terraformDirectoryPath = "../../../../03-security/GCE" // Update with your GCE directory path
-	projectID              = os.Getenv("TF_VAR_project_id")
-	uniqueID               = rand.Int()
-	network                = fmt.Sprintf("test-vpc-security-%d", uniqueID)
+	env, envErr            = profile.Current()
+	projectID              = env.Projects.Endpoint
 	firewallRuleName       = "allow-ssh-custom-ranges-gce"
 )
 
+// TestMain checks the APIs, roles and quota the suite needs before the
+// firewall rules are created.
+func TestMain(m *testing.M) {
+	os.Exit(preflight.Run(m, preflight.Requirements{
+		Profile: envErr,
+		Project: projectID,
+		APIs:    []string{"compute.googleapis.com"},
+		Roles:   []string{"roles/compute.securityAdmin", "roles/compute.networkAdmin"},
+		Quotas:  []preflight.Quota{{Metric: "NETWORKS"}, {Metric: "FIREWALLS"}},
+	}))
+}
+
 func TestGCEFirewallRuleProperties(t *testing.T) {
+	profile.Require(t, "projects.endpoint")
+	fakeapi.Use(t)
+	network := naming.Name(t, naming.Compute, "test-vpc-security")
 	var (
 		tfVars = map[string]any{
 			"project_id": projectID,
@@ -54,7 +72,7 @@ func TestGCEFirewallRuleProperties(t *testing.T) {
 		}
 	)
 
-	terraformOptions := terraform.WithDefaultRetryableErrors(t, &terraform.Options{
+	terraformOptions := retryable.With(t, &terraform.Options{
 		TerraformDir: terraformDirectoryPath,
 		Vars:         tfVars,
 		Reconfigure:  true,
@@ -62,15 +80,14 @@ func TestGCEFirewallRuleProperties(t *testing.T) {
 		NoColor:      true,
 	})
 
-	// Create VPC
-	createVPC(t, projectID, network)
-
-	// Defer VPC deletion
-	defer deleteVPC(t, projectID, network)
+	// Create VPC, deleted when the test ends after "terraform destroy"
+	vpc := createVPC(t, projectID, network)
+	fixture.Terraform(t, terraformOptions, vpc)
 
 	// Terraform init and apply
 	terraform.InitAndApply(t, terraformOptions)
-	time.Sleep(60 * time.Second) // Wait for resource creation
+	// Wait for the firewall rule to be readable before verifying.
+	wait.For(t, "firewall rule "+firewallRuleName+" to exist", wait.DefaultOptions, wait.FirewallExists(t, gcloud.New(projectID), firewallRuleName))
 
 	// Get Firewall rule from output
 	firewallRulesOutput := terraform.OutputJson(t, terraformOptions, "rules")
@@ -150,34 +167,18 @@ func TestGCEFirewallRuleProperties(t *testing.T) {
 			assert.Equal(t, wantPorts, extractedPorts, "Allow rule ports mismatch")
 		})
 	})
-
-	// Clean up resources with "terraform destroy"
-	terraform.Destroy(t, terraformOptions)
-}
-
-// Helper Functions
-func deleteVPC(t *testing.T, projectID string, network string) {
-	text := "compute"
-	time.Sleep(60 * time.Second)
-	cmd := shell.Command{
-		Command: "gcloud",
-		Args:    []string{text, "networks", "delete", network, "--project=" + projectID, "--quiet"},
-	}
-	_, err := shell.RunCommandAndGetOutputE(t, cmd)
-	if err != nil {
-		t.Errorf("===Error %s Encountered while executing %s", err, text)
-	}
 }
 
-func createVPC(t *testing.T, projectID string, network string) {
-	text := "compute"
-	cmd := shell.Command{
-		Command: "gcloud",
-		Args:    []string{text, "networks", "create", network, "--project=" + projectID, "--format=json", "--bgp-routing-mode=global", "--subnet-mode=custom", "--verbosity=none"},
-	}
-	_, err := shell.RunCommandAndGetOutputE(t, cmd)
+/*
+createVPC is a helper function which creates the VPC before the
+execution of the test as a fixture, deleted when the test ends.
+*/
+func createVPC(t *testing.T, projectID string, network string) *fixture.Handle {
+	client := gcloud.New(projectID)
+	vpc, err := fixture.Network(t, client, network, gcloud.NetworkOptions{BGPRoutingMode: "global"})
 	if err != nil {
-		t.Errorf("===Error %s Encountered while executing %s", err, text)
+		t.Fatalf("===Error %s Encountered while creating network %s", err, network)
 	}
-	time.Sleep(60 * time.Second)
+	wait.For(t, "network "+network+" to exist", wait.DefaultOptions, wait.NetworkExists(t, client, network))
+	return vpc
 }
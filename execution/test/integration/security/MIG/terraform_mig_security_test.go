@@ -15,13 +15,16 @@
 package integrationtest
 
 import (
-	"fmt"
-	"math/rand"
 	"os"
 	"testing"
-	"time"
 
-	"github.com/gruntwork-io/terratest/modules/shell"
+	"github.com/GoogleCloudPlatform/cloudnetworking-config-solutions/common_utils/fakeapi"
+	"github.com/GoogleCloudPlatform/cloudnetworking-config-solutions/common_utils/gcloud"
+	"github.com/GoogleCloudPlatform/cloudnetworking-config-solutions/common_utils/naming"
+	"github.com/GoogleCloudPlatform/cloudnetworking-config-solutions/common_utils/preflight"
+	"github.com/GoogleCloudPlatform/cloudnetworking-config-solutions/common_utils/profile"
+	"github.com/GoogleCloudPlatform/cloudnetworking-config-solutions/common_utils/retryable"
+	"github.com/GoogleCloudPlatform/cloudnetworking-config-solutions/common_utils/wait"
 	"github.com/gruntwork-io/terratest/modules/terraform"
 	"github.com/stretchr/testify/assert"
 	"github.com/tidwall/gjson"
@@ -29,13 +32,27 @@ import (
 
 var (
 	terraformDirectoryPath = "../../../../03-security/MIG"
-	projectID              = os.Getenv("TF_VAR_project_id")
-	uniqueID               = rand.Int()
-	network                = fmt.Sprintf("test-vpc-security-%d", uniqueID)
-	firewallRuleName       = fmt.Sprintf("mig-fw-allow-health-check-%d", uniqueID)
+	env, envErr            = profile.Current()
+	projectID              = env.Projects.Endpoint
 )
 
+// TestMain checks the APIs, roles and quota the suite needs before the
+// firewall rules are created.
+func TestMain(m *testing.M) {
+	os.Exit(preflight.Run(m, preflight.Requirements{
+		Profile: envErr,
+		Project: projectID,
+		APIs:    []string{"compute.googleapis.com"},
+		Roles:   []string{"roles/compute.securityAdmin", "roles/compute.networkAdmin"},
+		Quotas:  []preflight.Quota{{Metric: "NETWORKS"}, {Metric: "FIREWALLS"}},
+	}))
+}
+
 func TestMIGFirewallRuleProperties(t *testing.T) {
+	profile.Require(t, "projects.endpoint")
+	fakeapi.Use(t)
+	network := naming.Name(t, naming.Compute, "test-vpc-security")
+	firewallRuleName := naming.Name(t, naming.Compute, "mig-fw-allow-health-check")
 	var (
 		tfVars = map[string]any{
 			"project_id": projectID,
@@ -66,7 +83,7 @@ func TestMIGFirewallRuleProperties(t *testing.T) {
 		}
 	)
 
-	terraformOptions := terraform.WithDefaultRetryableErrors(t, &terraform.Options{
+	terraformOptions := retryable.With(t, &terraform.Options{
 		TerraformDir: terraformDirectoryPath,
 		Vars:         tfVars,
 		Reconfigure:  true,
@@ -82,7 +99,8 @@ func TestMIGFirewallRuleProperties(t *testing.T) {
 
 	// Terraform init and apply
 	terraform.InitAndApply(t, terraformOptions)
-	time.Sleep(60 * time.Second) // Wait for resource creation
+	// Wait for the firewall rule to be readable before verifying.
+	wait.For(t, "firewall rule "+firewallRuleName+" to exist", wait.DefaultOptions, wait.FirewallExists(t, gcloud.New(projectID), firewallRuleName))
 
 	// Get Firewall rule from output
 	firewallRulesOutput := terraform.OutputJson(t, terraformOptions, "rules")
@@ -166,35 +184,29 @@ func TestMIGFirewallRuleProperties(t *testing.T) {
 	terraform.Destroy(t, terraformOptions)
 }
 
-// Helper Functions
+/*
+deleteVPC is a helper function which deletes the VPC after
+completion of the test, retrying while terraform destroy releases it.
+*/
 func deleteVPC(t *testing.T, projectID string, network string) {
-	text := "compute"
-	time.Sleep(60 * time.Second)
-	cmd := shell.Command{
-		Command: "gcloud",
-		Args:    []string{text, "networks", "delete", network, "--project=" + projectID, "--quiet"},
-	}
-	_, err := shell.RunCommandAndGetOutputE(t, cmd)
+	client := gcloud.New(projectID)
+	_, err := wait.ForE(t, "network "+network+" to be deleted", wait.DefaultOptions, wait.Deletes(func() error {
+		return client.Networks.Delete(t, network)
+	}))
 	if err != nil {
-		t.Errorf("===Error %s Encountered while executing %s", err, text)
+		t.Errorf("===Error %s Encountered while deleting network %s", err, network)
 	}
 }
 
+/*
+createVPC is a helper function which creates the VPC before the
+execution of the test.
+*/
 func createVPC(t *testing.T, projectID string, network string) {
-	text := "compute"
-	cmd := shell.Command{
-		Command: "gcloud",
-		Args: []string{text, "networks", "create", network,
-			"--project=" + projectID,
-			"--format=json",
-			"--bgp-routing-mode=global",
-			"--subnet-mode=custom",
-			"--verbosity=none"},
-	}
-
-	_, err := shell.RunCommandAndGetOutputE(t, cmd)
-	if err != nil {
-		t.Errorf("===Error %s Encountered while executing %s", err, text)
+	client := gcloud.New(projectID)
+	if _, err := client.Networks.Create(t, network, gcloud.NetworkOptions{BGPRoutingMode: "global"}); err != nil {
+		t.Errorf("===Error %s Encountered while creating network %s", err, network)
+		return
 	}
-	time.Sleep(60 * time.Second)
+	wait.For(t, "network "+network+" to exist", wait.DefaultOptions, wait.NetworkExists(t, client, network))
 }
@@ -16,27 +16,45 @@ package integrationtest
 
 import (
 	"fmt"
-	"math/rand"
 	"os"
 	"slices"
 	"testing"
-	"time"
 
-	"github.com/gruntwork-io/terratest/modules/shell"
+	"github.com/GoogleCloudPlatform/cloudnetworking-config-solutions/common_utils/fakeapi"
+	"github.com/GoogleCloudPlatform/cloudnetworking-config-solutions/common_utils/gcloud"
+	"github.com/GoogleCloudPlatform/cloudnetworking-config-solutions/common_utils/naming"
+	"github.com/GoogleCloudPlatform/cloudnetworking-config-solutions/common_utils/preflight"
+	"github.com/GoogleCloudPlatform/cloudnetworking-config-solutions/common_utils/profile"
+	"github.com/GoogleCloudPlatform/cloudnetworking-config-solutions/common_utils/retryable"
+	"github.com/GoogleCloudPlatform/cloudnetworking-config-solutions/common_utils/wait"
 	"github.com/gruntwork-io/terratest/modules/terraform"
 	"github.com/tidwall/gjson"
 )
 
 var (
 	terraformDirectoryPath = "../../../../03-security/MRC" // Update with your actual path
-	projectID              = os.Getenv("TF_VAR_project_id")
-	uniqueID               = rand.Int()
-	networkName            = fmt.Sprintf("test-vpc-security-%d", uniqueID)
+	env, envErr            = profile.Current()
+	projectID              = env.Projects.Endpoint
 	firewallName           = "test-allow-egress-mrc"
 	firewallDirection      = "EGRESS"
 )
 
+// TestMain checks the APIs, roles and quota the suite needs before the
+// firewall rules are created.
+func TestMain(m *testing.M) {
+	os.Exit(preflight.Run(m, preflight.Requirements{
+		Profile: envErr,
+		Project: projectID,
+		APIs:    []string{"compute.googleapis.com"},
+		Roles:   []string{"roles/compute.securityAdmin", "roles/compute.networkAdmin"},
+		Quotas:  []preflight.Quota{{Metric: "NETWORKS"}, {Metric: "FIREWALLS"}},
+	}))
+}
+
 func TestCreateMemorystoreRedisFirewallRule(t *testing.T) {
+	profile.Require(t, "projects.endpoint")
+	fakeapi.Use(t)
+	networkName := naming.Name(t, naming.Compute, "test-vpc-security")
 	var (
 		tfVars = map[string]any{
 			"project_id": projectID,
@@ -55,7 +73,7 @@ func TestCreateMemorystoreRedisFirewallRule(t *testing.T) {
 		}
 	)
 
-	terraformOptions := terraform.WithDefaultRetryableErrors(t, &terraform.Options{
+	terraformOptions := retryable.With(t, &terraform.Options{
 		Vars:                 tfVars,
 		TerraformDir:         terraformDirectoryPath,
 		Reconfigure:          true,
@@ -73,7 +91,8 @@ func TestCreateMemorystoreRedisFirewallRule(t *testing.T) {
 
 	// Initialize and Apply
 	terraform.InitAndApply(t, terraformOptions)
-	time.Sleep(60 * time.Second)
+	// Wait for the firewall rule to be readable before verifying.
+	wait.For(t, "firewall rule "+firewallName+" to exist", wait.DefaultOptions, wait.FirewallExists(t, gcloud.New(projectID), firewallName))
 
 	// Get Output and Validate
 	want := firewallName
@@ -141,29 +160,29 @@ func TestCreateMemorystoreRedisFirewallRule(t *testing.T) {
 	})
 }
 
-// Helper Functions
+/*
+deleteVPC is a helper function which deletes the VPC after
+completion of the test, retrying while terraform destroy releases it.
+*/
 func deleteVPC(t *testing.T, projectID string, networkName string) {
-	text := "compute"
-	time.Sleep(60 * time.Second)
-	cmd := shell.Command{
-		Command: "gcloud",
-		Args:    []string{text, "networks", "delete", networkName, "--project=" + projectID, "--quiet"},
-	}
-	_, err := shell.RunCommandAndGetOutputE(t, cmd)
+	client := gcloud.New(projectID)
+	_, err := wait.ForE(t, "network "+networkName+" to be deleted", wait.DefaultOptions, wait.Deletes(func() error {
+		return client.Networks.Delete(t, networkName)
+	}))
 	if err != nil {
-		t.Errorf("===Error %s Encountered while executing %s", err, text)
+		t.Errorf("===Error %s Encountered while deleting network %s", err, networkName)
 	}
 }
 
+/*
+createVPC is a helper function which creates the VPC before the
+execution of the test.
+*/
 func createVPC(t *testing.T, projectID string, networkName string) {
-	text := "compute"
-	cmd := shell.Command{
-		Command: "gcloud",
-		Args:    []string{text, "networks", "create", networkName, "--project=" + projectID, "--format=json", "--bgp-routing-mode=global", "--subnet-mode=custom", "--verbosity=none"},
-	}
-	_, err := shell.RunCommandAndGetOutputE(t, cmd)
-	if err != nil {
-		t.Errorf("===Error %s Encountered while executing %s", err, text)
+	client := gcloud.New(projectID)
+	if _, err := client.Networks.Create(t, networkName, gcloud.NetworkOptions{BGPRoutingMode: "global"}); err != nil {
+		t.Errorf("===Error %s Encountered while creating network %s", err, networkName)
+		return
 	}
-	time.Sleep(60 * time.Second)
+	wait.For(t, "network "+networkName+" to exist", wait.DefaultOptions, wait.NetworkExists(t, client, networkName))
 }
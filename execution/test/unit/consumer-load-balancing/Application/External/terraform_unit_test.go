@@ -22,6 +22,7 @@ import (
 	"strings"
 	"testing"
 
+	"github.com/GoogleCloudPlatform/cloudnetworking-config-solutions/common_utils/plan"
 	"github.com/gruntwork-io/terratest/modules/terraform" // Terraform testing library
 	"github.com/stretchr/testify/assert"
 	"gopkg.in/yaml.v2"
@@ -41,6 +42,21 @@ var (
 	}
 )
 
+// stage is initialized and planned once in TestMain and shared by the tests
+// that plan with the default variables.
+var stage = plan.NewStage(&terraform.Options{
+	TerraformDir: terraformDirectoryPath,
+	Vars:         tfVars,
+	Reconfigure:  true,
+	Lock:         true,
+	PlanFilePath: "./plan",
+	NoColor:      true,
+})
+
+func TestMain(m *testing.M) {
+	os.Exit(stage.Run(m))
+}
+
 /*
 TestInitAndPlanRunWithTfVars tests Terraform initialization and planning with specified
 variables, expecting an exit code of 2 to indicate changes are planned. If the exit
@@ -48,17 +64,8 @@ code differs, it logs an error.
 */
 
 func TestInitAndPlanRunWithTfVars(t *testing.T) {
-	terraformOptions := terraform.WithDefaultRetryableErrors(t, &terraform.Options{
-		TerraformDir: terraformDirectoryPath,
-		Vars:         tfVars,
-		Reconfigure:  true,
-		Lock:         true,
-		PlanFilePath: "./plan",
-		NoColor:      true,
-	})
-
-	// Run 'terraform init' and 'terraform plan', get the exit code.
-	planExitCode := terraform.InitAndPlanWithExitCode(t, terraformOptions)
+	// Get the exit code of the plan shared by the package.
+	planExitCode := stage.ExitCode(t)
 	want := 2 // Expect changes to be applied
 	got := planExitCode
 
@@ -75,18 +82,8 @@ count matches the expected value of 7. An error is logged if the count differs.
 */
 
 func TestResourcesCount(t *testing.T) {
-	terraformOptions := terraform.WithDefaultRetryableErrors(t, &terraform.Options{
-		TerraformDir: terraformDirectoryPath,
-		Vars:         tfVars,
-		Reconfigure:  true,
-		Lock:         true,
-		PlanFilePath: "./plan",
-		NoColor:      true,
-	})
-
-	// Initialize and create a plan, then parse the resource count.
-	planStruct := terraform.InitAndPlan(t, terraformOptions)
-	resourceCount := terraform.GetResourceCount(t, planStruct)
+	// Count the resources of the plan shared by the package.
+	resourceCount := plan.ResourceCount(stage.Plan(t))
 
 	if got, want := resourceCount.Add, 6; got != want {
 		t.Errorf("Test Resource Count Add = %v, want = %v", got, want)
@@ -133,21 +130,8 @@ func TestTerraformModuleLBResourceAddressListMatch(t *testing.T) {
 		}
 	}
 
-	// Initialize Terraform and generate a plan.
-	terraformOptions := terraform.WithDefaultRetryableErrors(t, &terraform.Options{
-		TerraformDir: terraformDirectoryPath,
-		Vars:         tfVars,
-		Reconfigure:  true,
-		Lock:         true,
-		PlanFilePath: "./plan",
-		NoColor:      true,
-	})
-
-	planStruct := terraform.InitAndPlanAndShow(t, terraformOptions)
-	content, err := terraform.ParsePlanJSON(planStruct)
-	if err != nil {
-		t.Fatal(err.Error())
-	}
+	// Read the plan shared by the package.
+	content := stage.Plan(t)
 
 	actualModuleAddresses := make(map[string]struct{}) // Use a map to ensure uniqueness
 	for _, element := range content.ResourceChangesMap {
@@ -182,3 +166,18 @@ func TestTerraformModuleLBResourceAddressListMatch(t *testing.T) {
 		}
 	}
 }
+
+/*
+TestPlannedAttributes checks the backend service and forwarding rule of load-balancer.
+*/
+func TestPlannedAttributes(t *testing.T) {
+	p := plan.For(t, stage.Plan(t))
+	p.Resource(`module.lb_http["load-balancer"].google_compute_backend_service.default["default"]`).
+		Attr("name").Equals("load-balancer-backend-default").
+		Attr("protocol").Equals("HTTP").
+		Attr("port_name").Equals("http").
+		Attr("timeout_sec").Equals(10).
+		Attr("backend.0.group").Equals("instance-group-name")
+	p.Resource(`module.lb_http["load-balancer"].google_compute_global_forwarding_rule.http[0]`).
+		Attr("name").Equals("load-balancer")
+}
@@ -23,6 +23,7 @@ import (
 	"strings"
 	"testing"
 
+	"github.com/GoogleCloudPlatform/cloudnetworking-config-solutions/common_utils/plan"
 	"github.com/gruntwork-io/terratest/modules/terraform"
 	"github.com/stretchr/testify/assert"
 	"gopkg.in/yaml.v2"
@@ -43,6 +44,21 @@ var tfVarsNLB = map[string]interface{}{
 	"config_folder_path": configFolderPathNLB,
 }
 
+// stage is initialized and planned once in TestMain and shared by the tests
+// that plan with the default variables.
+var stage = plan.NewStage(&terraform.Options{
+	TerraformDir: terraformDirectoryPathNLB,
+	Vars:         tfVarsNLB,
+	Reconfigure:  true,
+	Lock:         true,
+	PlanFilePath: "./plan-nlb",
+	NoColor:      true,
+})
+
+func TestMain(m *testing.M) {
+	os.Exit(stage.Run(m))
+}
+
 /*
 TestInitAndPlanRunWithTfVarsNLB tests Terraform initialization and planning
 for the Network Load Balancer module with specified variables.
@@ -50,17 +66,8 @@ It expects an exit code of 2, indicating that changes are planned.
 If the exit code differs, it logs an error.
 */
 func TestInitAndPlanRunWithTfVarsNLB(t *testing.T) {
-	terraformOptions := terraform.WithDefaultRetryableErrors(t, &terraform.Options{
-		TerraformDir: terraformDirectoryPathNLB,
-		Vars:         tfVarsNLB,
-		Reconfigure:  true,
-		Lock:         true,
-		PlanFilePath: "./plan-nlb", // Use a distinct plan file name
-		NoColor:      true,
-	})
-
-	// Run 'terraform init' and 'terraform plan', get the exit code.
-	planExitCode := terraform.InitAndPlanWithExitCode(t, terraformOptions)
+	// Get the exit code of the plan shared by the package.
+	planExitCode := stage.ExitCode(t)
 	want := 2 // Expect changes to be applied (exit code 2 means plan has changes)
 	got := planExitCode
 
@@ -83,18 +90,8 @@ If you have one YAML file defining one NLB, and it creates these 3 main resource
 the count would be 3.
 */
 func TestResourcesCountNLB(t *testing.T) {
-	terraformOptions := terraform.WithDefaultRetryableErrors(t, &terraform.Options{
-		TerraformDir: terraformDirectoryPathNLB,
-		Vars:         tfVarsNLB,
-		Reconfigure:  true,
-		Lock:         true,
-		PlanFilePath: "./plan-nlb", // Use a distinct plan file name
-		NoColor:      true,
-	})
-
-	// Initialize and create a plan, then parse the resource count.
-	planStruct := terraform.InitAndPlan(t, terraformOptions)
-	resourceCount := terraform.GetResourceCount(t, planStruct)
+	// Count the resources of the plan shared by the package.
+	resourceCount := plan.ResourceCount(stage.Plan(t))
 
 	// --- Determine the expected resource count ---
 	// Count the number of NLB configurations in your test YAML files.
@@ -122,7 +119,7 @@ func TestResourcesCountNLB(t *testing.T) {
 
 	if got, want := resourceCount.Add, expectedResourceAddCount; got != want {
 		// For debugging, show the plan output
-		planJSON := terraform.Show(t, terraformOptions)
+		planJSON := stage.JSON(t)
 		t.Logf("Plan output: %s", planJSON) // Log the plan for inspection
 		t.Errorf("TestResourcesCountNLB: Resource Count Add = %v, want = %v (based on %d NLB configs)", got, want, numberOfNLBs)
 	}
@@ -187,21 +184,9 @@ func TestTerraformModuleNLBResourceAddressListMatch(t *testing.T) {
 			"If this is unexpected, check your configFolderPathNLB and test setup.", configFolderPathNLB)
 	}
 
-	// Initialize Terraform and generate a plan.
-	terraformOptions := terraform.WithDefaultRetryableErrors(t, &terraform.Options{
-		TerraformDir: terraformDirectoryPathNLB,
-		Vars:         tfVarsNLB,
-		Reconfigure:  true,
-		Lock:         true,
-		PlanFilePath: "./plan-nlb-addressmatch", // Use a distinct plan file name
-		NoColor:      true,
-	})
-
-	planJSON := terraform.InitAndPlanAndShow(t, terraformOptions) // Shows JSON output of plan
-	content, err := terraform.ParsePlanJSON(planJSON)             // planStruct was planJSON string, content needs to be parsed from it
-	if err != nil {
-		t.Fatalf("Failed to parse plan JSON: %v", err)
-	}
+	// Read the plan shared by the package.
+	planJSON := stage.JSON(t)
+	content := stage.Plan(t)
 
 	actualModuleAddresses := make(map[string]struct{}) // Use a map for actual addresses as well
 	for _, resourceChange := range content.ResourceChangesMap {
@@ -241,3 +226,17 @@ func TestTerraformModuleNLBResourceAddressListMatch(t *testing.T) {
 		t.Errorf("TestTerraformModuleNLBResourceAddressListMatch: Mismatch in module instance addresses.\nExpected: %v\nActual:   %v", expectedSlice, actualSlice)
 	}
 }
+
+/*
+TestPlannedAttributes checks the protocol, scheme and instance groups of the zonal and regional backend services.
+*/
+func TestPlannedAttributes(t *testing.T) {
+	p := plan.For(t, stage.Plan(t))
+	p.Resource(`module.nlb_passthrough_ext["nlb-*"].google_compute_region_backend_service.*`).
+		Attr("protocol").Equals("TCP").
+		Attr("load_balancing_scheme").Equals("EXTERNAL")
+	p.Resource(`module.nlb_passthrough_ext["nlb-zonal"].google_compute_region_backend_service.*`).
+		Attr("backend.0.group").Equals("projects/your-project-id/zones/us-central1-a/instanceGroups/my-zmig-zone-a")
+	p.Resource(`module.nlb_passthrough_ext["nlb-regional"].google_compute_region_backend_service.*`).
+		Attr("backend.0.group").Equals("projects/your-project-id/regions/us-central1/instanceGroups/my-rmig-default-region")
+}
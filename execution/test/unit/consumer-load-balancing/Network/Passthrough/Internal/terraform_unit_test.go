@@ -21,6 +21,7 @@ import (
 	"path/filepath"
 	"testing"
 
+	"github.com/GoogleCloudPlatform/cloudnetworking-config-solutions/common_utils/plan"
 	"github.com/gruntwork-io/terratest/modules/terraform"
 	"github.com/stretchr/testify/assert"
 )
@@ -87,3 +88,45 @@ region: us-central1
 		t.Errorf("Expected plan to fail with exit code %d due to invalid config, but got %d", wantCode, exitCode)
 	}
 }
+
+// TestPlannedHealthCheckAttributes plans the health check of a load balancer,
+// which is the only resource of the module that reads no network or subnetwork,
+// and checks the attributes it takes from the YAML config and the defaults.
+func TestPlannedHealthCheckAttributes(t *testing.T) {
+	t.Parallel()
+
+	tempDir := t.TempDir()
+	config := []byte(`
+name: plan-lb
+project: plan-project
+region: us-central1
+network: plan-network
+subnetwork: plan-subnetwork
+health_check:
+  check_interval_sec: 10
+  request_path: /healthz
+`)
+	err := os.WriteFile(filepath.Join(tempDir, "plan-lb.yaml"), config, 0644)
+	assert.NoError(t, err)
+
+	terraformOptions := terraform.WithDefaultRetryableErrors(t, &terraform.Options{
+		TerraformDir: terraformDirectoryPath,
+		Vars: map[string]interface{}{
+			"config_folder_path": tempDir,
+		},
+		Targets:      []string{`module.internal_passthrough_nlb["plan-lb"].google_compute_health_check.http`},
+		Reconfigure:  true,
+		Lock:         true,
+		PlanFilePath: filepath.Join(tempDir, "plan"),
+		NoColor:      true,
+	})
+
+	p := plan.For(t, terraform.InitAndPlanAndShowWithStruct(t, terraformOptions))
+	p.Resource(`module.internal_passthrough_nlb["plan-lb"].google_compute_health_check.http[0]`).
+		Count(1).
+		Attr("project").Equals("plan-project").
+		Attr("check_interval_sec").Equals(10).
+		Attr("timeout_sec").Equals(5).
+		Attr("http_health_check.0.port").Equals(80).
+		Attr("http_health_check.0.request_path").Equals("/healthz")
+}
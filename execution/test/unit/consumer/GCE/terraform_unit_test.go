@@ -26,6 +26,7 @@ import (
 	"strings"
 	"testing"
 
+	"github.com/GoogleCloudPlatform/cloudnetworking-config-solutions/common_utils/plan"
 	"github.com/gruntwork-io/terratest/modules/terraform" // Terraform testing library
 	"github.com/stretchr/testify/assert"
 	"gopkg.in/yaml.v2"
@@ -45,18 +46,24 @@ var (
 	}
 )
 
-func TestInitAndPlanRunWithTfVars(t *testing.T) {
-	terraformOptions := terraform.WithDefaultRetryableErrors(t, &terraform.Options{
-		TerraformDir: terraformDirectoryPath,
-		Vars:         tfVars,
-		Reconfigure:  true,
-		Lock:         true,
-		PlanFilePath: "./plan",
-		NoColor:      true,
-	})
+// stage is initialized and planned once in TestMain and shared by the tests
+// that plan with the default variables.
+var stage = plan.NewStage(&terraform.Options{
+	TerraformDir: terraformDirectoryPath,
+	Vars:         tfVars,
+	Reconfigure:  true,
+	Lock:         true,
+	PlanFilePath: "./plan",
+	NoColor:      true,
+})
+
+func TestMain(m *testing.M) {
+	os.Exit(stage.Run(m))
+}
 
-	// Run 'terraform init' and 'terraform plan', get the exit code.
-	planExitCode := terraform.InitAndPlanWithExitCode(t, terraformOptions)
+func TestInitAndPlanRunWithTfVars(t *testing.T) {
+	// Get the exit code of the plan shared by the package.
+	planExitCode := stage.ExitCode(t)
 	want := 2 // Expect changes to be applied
 	got := planExitCode
 
@@ -68,18 +75,8 @@ func TestInitAndPlanRunWithTfVars(t *testing.T) {
 
 // TestResourcesCount verifies the number of resources to be added by the Terraform plan.
 func TestResourcesCount(t *testing.T) {
-	terraformOptions := terraform.WithDefaultRetryableErrors(t, &terraform.Options{
-		TerraformDir: terraformDirectoryPath,
-		Vars:         tfVars,
-		Reconfigure:  true,
-		Lock:         true,
-		PlanFilePath: "./plan",
-		NoColor:      true,
-	})
-
-	// Initialize and create a plan, then parse the resource count.
-	planStruct := terraform.InitAndPlan(t, terraformOptions)
-	resourceCount := terraform.GetResourceCount(t, planStruct)
+	// Count the resources of the plan shared by the package.
+	resourceCount := plan.ResourceCount(stage.Plan(t))
 
 	if got, want := resourceCount.Add, 3; got != want { // Expect 3 resources to be added (instance1, instance2, instance3)
 		t.Errorf("Test Resource Count Add = %v, want = %v", got, want)
@@ -87,15 +84,6 @@ func TestResourcesCount(t *testing.T) {
 }
 
 func TestTerraformModuleVMResourceAddressListMatch(t *testing.T) {
-	terraformOptions := terraform.WithDefaultRetryableErrors(t, &terraform.Options{
-		TerraformDir: terraformDirectoryPath,
-		Vars:         tfVars,
-		Reconfigure:  true,
-		Lock:         true,
-		PlanFilePath: "./plan",
-		NoColor:      true,
-	})
-
 	localInstanceMap := make(map[string]map[string]string) // Initialize the map
 	err := filepath.Walk(configFolderPath, func(path string, info os.FileInfo, err error) error {
 		if err != nil {
@@ -134,11 +122,7 @@ func TestTerraformModuleVMResourceAddressListMatch(t *testing.T) {
 		expectedModuleAddresses = append(expectedModuleAddresses, fmt.Sprintf("module.vm[\"%s\"]", name))
 	}
 
-	planStruct := terraform.InitAndPlanAndShow(t, terraformOptions)
-	content, err := terraform.ParsePlanJSON(planStruct)
-	if err != nil {
-		t.Errorf("Error parsing plan JSON: %s", err) // Fail fast if parsing errors occur
-	}
+	content := stage.Plan(t)
 
 	actualModuleAddresses := make([]string, 0)
 	for _, element := range content.ResourceChangesMap {
@@ -150,3 +134,16 @@ func TestTerraformModuleVMResourceAddressListMatch(t *testing.T) {
 
 	assert.ElementsMatch(t, expectedModuleAddresses, actualModuleAddresses)
 }
+
+/*
+TestPlannedAttributes checks the zone and network interface planned for instance1.
+*/
+func TestPlannedAttributes(t *testing.T) {
+	p := plan.For(t, stage.Plan(t))
+	p.Resource(`module.vm["instance1"].google_compute_instance.*`).
+		Count(1).
+		Attr("zone").Equals("us-central1-a").
+		Attr("project").Equals("your-project-id").
+		Attr("network_interface.0.network").Equals("projects/your-project-id/global/networks/default").
+		Attr("network_interface.0.subnetwork").Equals("projects/your-project-id/regions/us-central1/subnetworks/default")
+}
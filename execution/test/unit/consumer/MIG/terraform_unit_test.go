@@ -24,6 +24,7 @@ import (
 	"strings"
 	"testing"
 
+	"github.com/GoogleCloudPlatform/cloudnetworking-config-solutions/common_utils/plan"
 	"github.com/gruntwork-io/terratest/modules/terraform" // Terraform testing library
 	"github.com/stretchr/testify/assert"
 	"gopkg.in/yaml.v2"
@@ -43,18 +44,24 @@ var (
 	}
 )
 
+// stage is initialized and planned once in TestMain and shared by the tests
+// that plan with the default variables.
+var stage = plan.NewStage(&terraform.Options{
+	TerraformDir: terraformDirectoryPath,
+	Vars:         tfVars,
+	Reconfigure:  true,
+	Lock:         true,
+	PlanFilePath: "./plan",
+	NoColor:      true,
+})
+
+func TestMain(m *testing.M) {
+	os.Exit(stage.Run(m))
+}
+
 func TestInitAndPlanRunWithTfVars(t *testing.T) {
-	terraformOptions := terraform.WithDefaultRetryableErrors(t, &terraform.Options{
-		TerraformDir: terraformDirectoryPath,
-		Vars:         tfVars,
-		Reconfigure:  true,
-		Lock:         true,
-		PlanFilePath: "./plan",
-		NoColor:      true,
-	})
-
-	// Run 'terraform init' and 'terraform plan', get the exit code.
-	planExitCode := terraform.InitAndPlanWithExitCode(t, terraformOptions)
+	// Get the exit code of the plan shared by the package.
+	planExitCode := stage.ExitCode(t)
 	want := 2 // Expect changes to be applied
 	got := planExitCode
 
@@ -66,18 +73,8 @@ func TestInitAndPlanRunWithTfVars(t *testing.T) {
 
 // TestResourcesCount verifies the number of resources to be added by the Terraform plan.
 func TestResourcesCount(t *testing.T) {
-	terraformOptions := terraform.WithDefaultRetryableErrors(t, &terraform.Options{
-		TerraformDir: terraformDirectoryPath,
-		Vars:         tfVars,
-		Reconfigure:  true,
-		Lock:         true,
-		PlanFilePath: "./plan",
-		NoColor:      true,
-	})
-
-	// Initialize and create a plan, then parse the resource count.
-	planStruct := terraform.InitAndPlan(t, terraformOptions)
-	resourceCount := terraform.GetResourceCount(t, planStruct)
+	// Count the resources of the plan shared by the package.
+	resourceCount := plan.ResourceCount(stage.Plan(t))
 
 	if got, want := resourceCount.Add, 3; got != want {
 		t.Errorf("Test Resource Count Add = %v, want = %v", got, want)
@@ -116,21 +113,8 @@ func TestTerraformModuleMIGResourceAddressListMatch(t *testing.T) {
 		}
 	}
 
-	// Initialize Terraform and generate a plan.
-	terraformOptions := terraform.WithDefaultRetryableErrors(t, &terraform.Options{
-		TerraformDir: terraformDirectoryPath,
-		Vars:         tfVars,
-		Reconfigure:  true,
-		Lock:         true,
-		PlanFilePath: "./plan",
-		NoColor:      true,
-	})
-
-	planStruct := terraform.InitAndPlanAndShow(t, terraformOptions)
-	content, err := terraform.ParsePlanJSON(planStruct)
-	if err != nil {
-		t.Fatal(err.Error())
-	}
+	// Read the plan shared by the package.
+	content := stage.Plan(t)
 
 	actualModuleAddresses := make(map[string]struct{}) // Use a map to ensure uniqueness
 	for _, element := range content.ResourceChangesMap {
@@ -166,3 +150,16 @@ func TestTerraformModuleMIGResourceAddressListMatch(t *testing.T) {
 		}
 	}
 }
+
+/*
+TestPlannedAttributes checks the group manager and instance template planned for minimal-mig.
+*/
+func TestPlannedAttributes(t *testing.T) {
+	p := plan.For(t, stage.Plan(t))
+	p.Resource(`module.mig["minimal-mig"].google_compute_*instance_group_manager.*`).
+		Count(1).
+		Attr("name").Equals("minimal-mig").
+		Attr("project").Equals("project-id")
+	p.Resource(`module.mig-template["minimal-mig"].google_compute_instance_template.*`).
+		Attr("network_interface.0.network").Equals("projects/project-id/global/networks/default")
+}
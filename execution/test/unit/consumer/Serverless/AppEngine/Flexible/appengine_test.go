@@ -18,9 +18,11 @@ package unittest
 
 import (
 	"io/ioutil"
+	"os"
 	"path/filepath"
 	"testing"
 
+	"github.com/GoogleCloudPlatform/cloudnetworking-config-solutions/common_utils/plan"
 	"github.com/gruntwork-io/terratest/modules/terraform"
 	"github.com/stretchr/testify/assert"
 	"gopkg.in/yaml.v2"
@@ -32,21 +34,27 @@ var (
 	configFolderPath       = filepath.Join(projectRoot, "test/unit/consumer/Serverless/AppEngine/Flexible/config")
 )
 
+// stage is initialized and planned once in TestMain and shared by the tests
+// that plan with the default variables.
+var stage = plan.NewStage(&terraform.Options{
+	TerraformDir: terraformDirectoryPath,
+	Vars: map[string]interface{}{
+		"config_folder_path": configFolderPath,
+	},
+	Reconfigure:  true,
+	Lock:         true,
+	PlanFilePath: "./plan",
+	NoColor:      true,
+})
+
+func TestMain(m *testing.M) {
+	os.Exit(stage.Run(m))
+}
+
 // Create the resources using the terrafrom consumer code
 func TestInitAndPlan(t *testing.T) {
-	// Construct the terraform options.
-	terraformOptions := terraform.WithDefaultRetryableErrors(t, &terraform.Options{
-		TerraformDir: terraformDirectoryPath,
-		Vars: map[string]interface{}{
-			"config_folder_path": configFolderPath, // Use the YAML configs
-		},
-		Reconfigure:  true, // Important for switching between test cases
-		Lock:         true,
-		PlanFilePath: "./plan",
-		NoColor:      true,
-	})
-	// Run 'terraform init' and 'terraform plan', get the exit code.
-	planExitCode := terraform.InitAndPlanWithExitCode(t, terraformOptions)
+	// Get the exit code of the plan shared by the package.
+	planExitCode := stage.ExitCode(t)
 	want := 2 // Expect no  changes to be applied
 	got := planExitCode
 
@@ -59,20 +67,8 @@ func TestInitAndPlan(t *testing.T) {
 
 // Count the number of resources created expected vs actual
 func TestResourcesCount(t *testing.T) {
-	// Construct the terraform options.
-	terraformOptions := terraform.WithDefaultRetryableErrors(t, &terraform.Options{
-		TerraformDir: terraformDirectoryPath,
-		Vars: map[string]interface{}{
-			"config_folder_path": configFolderPath, // Use the YAML configs
-		},
-		Reconfigure:  true, // Important for switching between test cases
-		Lock:         true,
-		PlanFilePath: "./plan",
-		NoColor:      true,
-	})
-	// Initialize and create a plan, then parse the resource count.
-	planStruct := terraform.InitAndPlan(t, terraformOptions)
-	resourceCount := terraform.GetResourceCount(t, planStruct)
+	// Count the resources of the plan shared by the package.
+	resourceCount := plan.ResourceCount(stage.Plan(t))
 	// Initialize expectedAddCount, expectedChangeCount, and expectedDestroyCount to zero
 	expectedAddCount := 8
 
@@ -109,17 +105,6 @@ func TestResourcesCount(t *testing.T) {
 
 // Match Expected resources to Created resources to ensure correct resource creation
 func TestTerraformModuleResourceAddressListMatch(t *testing.T) {
-	terraformOptions := terraform.WithDefaultRetryableErrors(t, &terraform.Options{
-		TerraformDir: terraformDirectoryPath,
-		Vars: map[string]interface{}{
-			"config_folder_path": configFolderPath,
-		},
-		Reconfigure:  true,
-		Lock:         true,
-		PlanFilePath: "./plan",
-		NoColor:      true,
-	})
-
 	// Define the *expected* resources within the flexible module.  This is crucial.
 	// We're testing the *flexible* module, so we list resources *it* creates.
 	expectedResources := []string{
@@ -133,11 +118,7 @@ func TestTerraformModuleResourceAddressListMatch(t *testing.T) {
 		"module.flexible_app_engine_instance[\"project-id1_test-service1\"].google_app_engine_service_split_traffic.split_traffic[\"test-service1\"]",
 	}
 
-	planJSON := terraform.InitAndPlanAndShow(t, terraformOptions)
-	plan, err := terraform.ParsePlanJSON(planJSON)
-	if err != nil {
-		t.Fatalf("Failed to parse plan JSON: %v", err)
-	}
+	plan := stage.Plan(t)
 
 	// Get the actual resources from the plan.
 	actualResources := make([]string, 0)
@@ -168,3 +149,21 @@ func TestInitAndPlanFailure(t *testing.T) {
 	exitCode := terraform.InitAndPlanWithExitCode(t, terraformOptions)
 	assert.Equal(t, 1, exitCode, "Expected Terraform to fail with exit code 1")
 }
+
+/*
+TestPlannedAttributes checks the version and firewall rule planned for test-service1.
+*/
+func TestPlannedAttributes(t *testing.T) {
+	p := plan.For(t, stage.Plan(t))
+	instance := `module.flexible_app_engine_instance["project-id1_test-service1"]`
+	p.Resource(instance + `.google_app_engine_flexible_app_version.flexible["test-service1"]`).
+		Attr("runtime").Equals("python").
+		Attr("version_id").Equals("v1").
+		Attr("instance_class").Equals("F4_1G").
+		Attr("network.0.name").Equals("appeng-int-vpc").
+		Attr("network.0.subnetwork").Equals("appeng-int-sub")
+	p.Resource(instance + `.google_app_engine_firewall_rule.firewall["0"]`).
+		Attr("source_range").Equals("10.0.0.0/8").
+		Attr("action").Equals("ALLOW").
+		Attr("priority").Equals(100)
+}
@@ -24,6 +24,7 @@ import (
 	"strings"
 	"testing"
 
+	"github.com/GoogleCloudPlatform/cloudnetworking-config-solutions/common_utils/plan"
 	"github.com/gruntwork-io/terratest/modules/terraform"
 	"github.com/stretchr/testify/assert"
 	"gopkg.in/yaml.v2"
@@ -35,20 +36,26 @@ var (
 	configFolderPath       = filepath.Join(projectRoot, "test/unit/consumer/Serverless/AppEngine/Standard/config")
 )
 
+// stage is initialized and planned once in TestMain and shared by the tests
+// that plan with the default variables.
+var stage = plan.NewStage(&terraform.Options{
+	TerraformDir: terraformDirectoryPath,
+	Vars: map[string]interface{}{
+		"config_folder_path": configFolderPath,
+	},
+	Reconfigure:  true,
+	Lock:         true,
+	PlanFilePath: "./plan",
+	NoColor:      true,
+})
+
+func TestMain(m *testing.M) {
+	os.Exit(stage.Run(m))
+}
+
 func TestInitAndPlan(t *testing.T) {
-	// Construct the terraform options.
-	terraformOptions := terraform.WithDefaultRetryableErrors(t, &terraform.Options{
-		TerraformDir: terraformDirectoryPath,
-		Vars: map[string]interface{}{
-			"config_folder_path": configFolderPath, // Use the YAML configs
-		},
-		Reconfigure:  true, // Important for switching between test cases
-		Lock:         true,
-		PlanFilePath: "./plan",
-		NoColor:      true,
-	})
-	// Run 'terraform init' and 'terraform plan', get the exit code.
-	planExitCode := terraform.InitAndPlanWithExitCode(t, terraformOptions)
+	// Get the exit code of the plan shared by the package.
+	planExitCode := stage.ExitCode(t)
 	want := 2 // Expect no  changes to be applied
 	got := planExitCode
 
@@ -59,20 +66,8 @@ func TestInitAndPlan(t *testing.T) {
 
 }
 func TestResourcesCount(t *testing.T) {
-	// Construct the terraform options.
-	terraformOptions := terraform.WithDefaultRetryableErrors(t, &terraform.Options{
-		TerraformDir: terraformDirectoryPath,
-		Vars: map[string]interface{}{
-			"config_folder_path": configFolderPath, // Use the YAML configs
-		},
-		Reconfigure:  true, // Important for switching between test cases
-		Lock:         true,
-		PlanFilePath: "./plan",
-		NoColor:      true,
-	})
-	// Initialize and create a plan, then parse the resource count.
-	planStruct := terraform.InitAndPlan(t, terraformOptions)
-	resourceCount := terraform.GetResourceCount(t, planStruct)
+	// Count the resources of the plan shared by the package.
+	resourceCount := plan.ResourceCount(stage.Plan(t))
 	// Initialize expectedAddCount, expectedChangeCount, and expectedDestroyCount to zero
 	expectedAddCount := 6
 
@@ -108,17 +103,6 @@ func TestResourcesCount(t *testing.T) {
 }
 
 func TestTerraformModuleResourceAddressListMatch(t *testing.T) {
-	terraformOptions := terraform.WithDefaultRetryableErrors(t, &terraform.Options{
-		TerraformDir: terraformDirectoryPath,
-		Vars: map[string]interface{}{
-			"config_folder_path": configFolderPath, // Use the YAML configs
-		},
-		Reconfigure:  true,
-		Lock:         true,
-		PlanFilePath: "./plan",
-		NoColor:      true,
-	})
-
 	localServiceMap := make(map[string]map[string]interface{}) // Initialize the map
 	err := filepath.Walk(configFolderPath, func(path string, info os.FileInfo, err error) error {
 		if err != nil {
@@ -155,11 +139,7 @@ func TestTerraformModuleResourceAddressListMatch(t *testing.T) {
 	expectedModuleAddresses := []string{} // Start with an empty slice
 	expectedModuleAddresses = append(expectedModuleAddresses, "module.appengine_standard_instance[\"instance1\"]", "module.appengine_standard_instance[\"instance2\"]")
 
-	planStruct := terraform.InitAndPlanAndShow(t, terraformOptions)
-	content, err := terraform.ParsePlanJSON(planStruct)
-	if err != nil {
-		t.Errorf("Error parsing plan JSON: %s", err) // Fail fast if parsing errors occur
-	}
+	content := stage.Plan(t)
 
 	actualModuleAddresses := make([]string, 0)
 	for _, element := range content.ResourceChangesMap {
@@ -191,3 +171,15 @@ func TestInitAndPlanFailure(t *testing.T) {
 	exitCode := terraform.InitAndPlanWithExitCode(t, terraformOptions)
 	assert.Equal(t, 1, exitCode, "Expected Terraform to fail with exit code 1")
 }
+
+/*
+TestPlannedAttributes checks the version planned for instance1.
+*/
+func TestPlannedAttributes(t *testing.T) {
+	p := plan.For(t, stage.Plan(t))
+	p.Resource(`module.appengine_standard_instance["instance1"].google_app_engine_standard_app_version.*`).
+		Attr("service").Equals("service-one").
+		Attr("version_id").Equals("v1").
+		Attr("runtime").Equals("python39").
+		Attr("instance_class").Equals("F2")
+}
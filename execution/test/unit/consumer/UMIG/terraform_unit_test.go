@@ -22,6 +22,7 @@ import (
 	"strings"
 	"testing"
 
+	"github.com/GoogleCloudPlatform/cloudnetworking-config-solutions/common_utils/plan"
 	"github.com/gruntwork-io/terratest/modules/terraform"
 	"github.com/stretchr/testify/assert"
 	"gopkg.in/yaml.v2"
@@ -39,17 +40,23 @@ var (
 	}
 )
 
-func TestInitAndPlanRunWithTfVars(t *testing.T) {
-	terraformOptions := terraform.WithDefaultRetryableErrors(t, &terraform.Options{
-		TerraformDir: terraformDirectoryPath,
-		Vars:         tfVars,
-		Reconfigure:  true,
-		Lock:         true,
-		PlanFilePath: "./plan",
-		NoColor:      true,
-	})
+// stage is initialized and planned once in TestMain and shared by the tests
+// that plan with the default variables.
+var stage = plan.NewStage(&terraform.Options{
+	TerraformDir: terraformDirectoryPath,
+	Vars:         tfVars,
+	Reconfigure:  true,
+	Lock:         true,
+	PlanFilePath: "./plan",
+	NoColor:      true,
+})
+
+func TestMain(m *testing.M) {
+	os.Exit(stage.Run(m))
+}
 
-	planExitCode := terraform.InitAndPlanWithExitCode(t, terraformOptions)
+func TestInitAndPlanRunWithTfVars(t *testing.T) {
+	planExitCode := stage.ExitCode(t)
 	want := 2 // Expect changes to be applied
 	got := planExitCode
 
@@ -59,17 +66,7 @@ func TestInitAndPlanRunWithTfVars(t *testing.T) {
 }
 
 func TestResourcesCount(t *testing.T) {
-	terraformOptions := terraform.WithDefaultRetryableErrors(t, &terraform.Options{
-		TerraformDir: terraformDirectoryPath,
-		Vars:         tfVars,
-		Reconfigure:  true,
-		Lock:         true,
-		PlanFilePath: "./plan",
-		NoColor:      true,
-	})
-
-	planStruct := terraform.InitAndPlan(t, terraformOptions)
-	resourceCount := terraform.GetResourceCount(t, planStruct)
+	resourceCount := plan.ResourceCount(stage.Plan(t))
 
 	if got, want := resourceCount.Add, 1; got != want {
 		t.Errorf("Test Resource Count Add = %v, want = %v", got, want)
@@ -108,20 +105,7 @@ func TestTerraformModuleUMIGResourceAddressListMatch(t *testing.T) {
 		}
 	}
 
-	terraformOptions := terraform.WithDefaultRetryableErrors(t, &terraform.Options{
-		TerraformDir: terraformDirectoryPath,
-		Vars:         tfVars,
-		Reconfigure:  true,
-		Lock:         true,
-		PlanFilePath: "./plan",
-		NoColor:      true,
-	})
-
-	planStruct := terraform.InitAndPlanAndShow(t, terraformOptions)
-	content, err := terraform.ParsePlanJSON(planStruct)
-	if err != nil {
-		t.Fatal(err.Error())
-	}
+	content := stage.Plan(t)
 
 	actualModuleAddresses := make(map[string]struct{})
 	for _, element := range content.ResourceChangesMap {
@@ -172,3 +156,17 @@ func TestInitAndPlanFailure(t *testing.T) {
 	exitCode := terraform.InitAndPlanWithExitCode(t, terraformOptions)
 	assert.Equal(t, 1, exitCode, "Expected Terraform to fail with exit code 1")
 }
+
+/*
+TestPlannedAttributes checks the members, network and named ports of the unmanaged group of the config folder.
+*/
+func TestPlannedAttributes(t *testing.T) {
+	p := plan.For(t, stage.Plan(t))
+	p.Resource(`module.umig["umig-0"].google_compute_instance_group.unmanaged`).
+		Attr("name").Equals("your_umig_name").
+		Attr("zone").Equals("your_zone").
+		Attr("network").Equals("https://www.googleapis.com/compute/v1/projects/your_project_id/global/networks/your_network_name").
+		Attr("instances").Contains("projects/your_project_id/zones/your_zone/instances/instance_name_1").
+		Attr("named_port").Contains(map[string]any{"name": "http", "port": 80}).
+		Attr("named_port").Contains(map[string]any{"name": "https", "port": 443})
+}
@@ -23,6 +23,7 @@ import (
 	"strings"
 	"testing"
 
+	"github.com/GoogleCloudPlatform/cloudnetworking-config-solutions/common_utils/plan"
 	"github.com/gruntwork-io/terratest/modules/terraform"
 	"github.com/stretchr/testify/assert"
 	"gopkg.in/yaml.v2"
@@ -41,20 +42,26 @@ var (
 	}
 )
 
+// stage is initialized and planned once in TestMain and shared by the tests
+// that plan with the default variables.
+var stage = plan.NewStage(&terraform.Options{
+	TerraformDir: terraformDirectoryPath,
+	Vars:         tfVars,
+	Reconfigure:  true,
+	Lock:         true,
+	PlanFilePath: "./plan",
+	NoColor:      true,
+})
+
+func TestMain(m *testing.M) {
+	os.Exit(stage.Run(m))
+}
+
 // TestInitAndPlanRunWithTfVars verifies that 'terraform init' and 'terraform plan'
 // execute successfully with the provided tfVars and checks the expected exit code.
 func TestInitAndPlanRunWithTfVars(t *testing.T) {
-	terraformOptions := terraform.WithDefaultRetryableErrors(t, &terraform.Options{
-		TerraformDir: terraformDirectoryPath,
-		Vars:         tfVars,
-		Reconfigure:  true,
-		Lock:         true,
-		PlanFilePath: "./plan",
-		NoColor:      true,
-	})
-
-	// Run 'terraform init' and 'terraform plan', get the exit code.
-	planExitCode := terraform.InitAndPlanWithExitCode(t, terraformOptions)
+	// Get the exit code of the plan shared by the package.
+	planExitCode := stage.ExitCode(t)
 	want := 2 // Expect changes to be applied
 	got := planExitCode
 
@@ -66,18 +73,8 @@ func TestInitAndPlanRunWithTfVars(t *testing.T) {
 
 // TestResourcesCount verifies the number of resources to be added by the Terraform plan.
 func TestResourcesCount(t *testing.T) {
-	terraformOptions := terraform.WithDefaultRetryableErrors(t, &terraform.Options{
-		TerraformDir: terraformDirectoryPath,
-		Vars:         tfVars,
-		Reconfigure:  true,
-		Lock:         true,
-		PlanFilePath: "./plan",
-		NoColor:      true,
-	})
-
-	// Initialize and create a plan, then parse the resource count.
-	planStruct := terraform.InitAndPlan(t, terraformOptions)
-	resourceCount := terraform.GetResourceCount(t, planStruct)
+	// Count the resources of the plan shared by the package.
+	resourceCount := plan.ResourceCount(stage.Plan(t))
 
 	if got, want := resourceCount.Add, 1; got != want {
 		t.Errorf("Test Resource Count Add = %v, want = %v", got, want)
@@ -119,21 +116,8 @@ func TestTerraformModuleWorkbenchResourceAddressListMatch(t *testing.T) {
 	// Log the expected module addresses for debugging
 	t.Logf("Expected module addresses: %+v", expectedModuleAddresses)
 
-	// Initialize Terraform and generate a plan.
-	terraformOptions := terraform.WithDefaultRetryableErrors(t, &terraform.Options{
-		TerraformDir: terraformDirectoryPath,
-		Vars:         tfVars,
-		Reconfigure:  true,
-		Lock:         true,
-		PlanFilePath: "./plan",
-		NoColor:      true,
-	})
-
-	planStruct := terraform.InitAndPlanAndShow(t, terraformOptions)
-	content, err := terraform.ParsePlanJSON(planStruct)
-	if err != nil {
-		t.Fatal(err.Error())
-	}
+	// Read the plan shared by the package.
+	content := stage.Plan(t)
 
 	actualModuleAddresses := make(map[string]struct{})
 	for _, element := range content.ResourceChangesMap {
@@ -167,3 +151,14 @@ func TestTerraformModuleWorkbenchResourceAddressListMatch(t *testing.T) {
 		}
 	}
 }
+
+/*
+TestPlannedAttributes checks the location and machine of workbench-instance-1, including that it gets no public IP.
+*/
+func TestPlannedAttributes(t *testing.T) {
+	p := plan.For(t, stage.Plan(t))
+	p.Resource(`module.workbench_instance["workbench-instance-1"].google_workbench_instance.*`).
+		Attr("location").Equals("us-central1-a").
+		Attr("gce_setup.0.machine_type").Equals("n1-standard-1").
+		Attr("gce_setup.0.disable_public_ip").Equals(true)
+}
@@ -24,6 +24,7 @@ import (
 	"strings"
 	"testing"
 
+	"github.com/GoogleCloudPlatform/cloudnetworking-config-solutions/common_utils/plan"
 	"github.com/gruntwork-io/terratest/modules/terraform"
 	"github.com/stretchr/testify/assert"
 )
@@ -42,32 +43,29 @@ var (
 	}
 )
 
+// stage is initialized and planned once in TestMain and shared by the tests
+// that plan with the default variables.
+var stage = plan.NewStage(&terraform.Options{
+	TerraformDir: terraformDirectoryPathFE,
+	Vars:         tfVarsFE,
+	Reconfigure:  true,
+	PlanFilePath: "./plan_fe",
+	NoColor:      true,
+})
+
+func TestMain(m *testing.M) {
+	os.Exit(stage.Run(m))
+}
+
 // TestFirewallEndpointPlanExitCode verifies that the plan exits with a code of 2, indicating changes are planned.
 func TestFirewallEndpointPlanExitCode(t *testing.T) {
-	terraformOptions := terraform.WithDefaultRetryableErrors(t, &terraform.Options{
-		TerraformDir: terraformDirectoryPathFE,
-		Vars:         tfVarsFE,
-		Reconfigure:  true,
-		PlanFilePath: "./plan_fe",
-		NoColor:      true,
-	})
-
-	planExitCode := terraform.InitAndPlanWithExitCode(t, terraformOptions)
+	planExitCode := stage.ExitCode(t)
 	assert.Equal(t, 2, planExitCode, "Test Plan Exit Code: Expected changes to be applied")
 }
 
 // TestFirewallEndpointResourcesCount verifies the number of resources to be added by the plan.
 func TestFirewallEndpointResourcesCount(t *testing.T) {
-	terraformOptions := terraform.WithDefaultRetryableErrors(t, &terraform.Options{
-		TerraformDir: terraformDirectoryPathFE,
-		Vars:         tfVarsFE,
-		Reconfigure:  true,
-		PlanFilePath: "./plan_fe",
-		NoColor:      true,
-	})
-
-	planStruct := terraform.InitAndPlan(t, terraformOptions)
-	resourceCount := terraform.GetResourceCount(t, planStruct)
+	resourceCount := plan.ResourceCount(stage.Plan(t))
 
 	// ASSUMPTION: Your test config folder contains YAML files that together create 3 resources.
 	// Adjust this number based on your actual test files.
@@ -78,13 +76,6 @@ func TestFirewallEndpointResourcesCount(t *testing.T) {
 // TestFirewallEndpointModuleAddressListMatch verifies that a module instance is planned for each YAML config file.
 func TestFirewallEndpointModuleAddressListMatch(t *testing.T) {
 	t.Parallel()
-	terraformOptions := terraform.WithDefaultRetryableErrors(t, &terraform.Options{
-		TerraformDir: terraformDirectoryPathFE,
-		Vars:         tfVarsFE,
-		Reconfigure:  true,
-		PlanFilePath: "./plan_fe",
-		NoColor:      true,
-	})
 
 	// Get the expected module keys directly from the filenames.
 	expectedModuleKeys := []string{}
@@ -109,10 +100,8 @@ func TestFirewallEndpointModuleAddressListMatch(t *testing.T) {
 		expectedModuleAddresses = append(expectedModuleAddresses, fmt.Sprintf("module.firewall_endpoints[\"%s\"]", key))
 	}
 
-	// Parse the plan and find the actual module addresses being created.
-	planStruct := terraform.InitAndPlanAndShow(t, terraformOptions)
-	content, err := terraform.ParsePlanJSON(planStruct)
-	assert.NoError(t, err, "Error parsing plan JSON")
+	// Find the actual module addresses being created in the shared plan.
+	content := stage.Plan(t)
 
 	actualModuleAddresses := make([]string, 0)
 	for _, element := range content.ResourceChangesMap {
@@ -124,3 +113,23 @@ func TestFirewallEndpointModuleAddressListMatch(t *testing.T) {
 
 	assert.ElementsMatch(t, expectedModuleAddresses, actualModuleAddresses, "The planned module addresses do not match the expected addresses from YAML files.")
 }
+
+// TestFirewallEndpointPlannedAttributes checks the zone, network and endpoint of the associations planned for the config folder.
+func TestFirewallEndpointPlannedAttributes(t *testing.T) {
+	p := plan.For(t, stage.Plan(t))
+	p.Resource(`module.firewall_endpoints["instance1"].google_network_security_firewall_endpoint_association.firewall_endpoint_association[0]`).
+		Count(1).
+		Attr("location").Equals("us-central1-c").
+		Attr("network").Equals("projects/my-production-project/global/networks/test-vpc-2").
+		Attr("firewall_endpoint").Equals("organizations/YOUR_ORGANIZATION_ID/locations/us-central1-c/firewallEndpoints/some-pre-existing-endpoint")
+	p.Resource(`module.firewall_endpoints["instance2"].google_network_security_firewall_endpoint_association.firewall_endpoint_association[0]`).
+		Count(1).
+		Attr("location").Equals("us-east1-b").
+		Attr("parent").Equals("projects/YOUR_VPC_PROJECT_ID").
+		Attr("network").Equals("projects/my-production-project/global/networks/test-vpc-1")
+	p.Resource(`module.firewall_endpoints["instance2"].google_network_security_firewall_endpoint.firewall_endpoint[0]`).
+		Count(1).
+		Attr("parent").Equals("organizations/YOUR_ORGANIZATION_ID").
+		Attr("location").Equals("us-east1-b").
+		Attr("billing_project_id").Equals("YOUR_BILLING_PROJECT_ID")
+}
@@ -14,12 +14,12 @@
 package unittest
 
 import (
-	"sort"
+	"os"
 	"testing"
 
-	"github.com/google/go-cmp/cmp"
+	"github.com/GoogleCloudPlatform/cloudnetworking-config-solutions/common_utils/plan"
+	"github.com/GoogleCloudPlatform/cloudnetworking-config-solutions/common_utils/plan/golden"
 	"github.com/gruntwork-io/terratest/modules/terraform"
-	"golang.org/x/exp/slices"
 )
 
 const (
@@ -98,24 +98,28 @@ var secondVaBandwidth = "BPS_1G"
 var secondVaBgpRange = "169.254.61.8/29"
 var secondVlanTag = 601
 
+// stage is initialized and planned once in TestMain and shared by the tests
+// that plan with the default variables.
+var stage = plan.NewStage(&terraform.Options{
+	TerraformDir: terraformDirectoryPath,
+	Vars:         tfVars,
+	Reconfigure:  true,
+	Lock:         true,
+	PlanFilePath: "./plan",
+	NoColor:      true,
+})
+
+func TestMain(m *testing.M) {
+	os.Exit(stage.Run(m))
+}
+
 func TestInitAndPlanRunWithTfVars(t *testing.T) {
 	/*
 	   0 = Succeeded with empty diff (no changes)
 	   1 = Error
 	   2 = Succeeded with non-empty diff (changes present)
 	*/
-	// Construct the terraform options with default retryable errors to handle the most common
-	// retryable errors in terraform testing.
-	terraformOptions := terraform.WithDefaultRetryableErrors(t, &terraform.Options{
-		// Set the path to the Terraform code that will be tested.
-		TerraformDir: terraformDirectoryPath,
-		Vars:         tfVars,
-		Reconfigure:  true,
-		Lock:         true,
-		PlanFilePath: "./plan",
-		NoColor:      true,
-	})
-	planExitCode := terraform.InitAndPlanWithExitCode(t, terraformOptions)
+	planExitCode := stage.ExitCode(t)
 	want := 2
 	got := planExitCode
 	if got != want {
@@ -147,141 +151,38 @@ func TestInitAndPlanRunWithoutTfVarsExpectFailureScenario(t *testing.T) {
 	}
 }
 
-func TestResourcesCount(t *testing.T) {
-	terraformOptions := terraform.WithDefaultRetryableErrors(t, &terraform.Options{
-		TerraformDir: terraformDirectoryPath,
-		Vars:         tfVars,
-		Reconfigure:  true,
-		Lock:         true,
-		PlanFilePath: "./plan",
-		NoColor:      true,
-	})
-	planStruct := terraform.InitAndPlan(t, terraformOptions)
-	resourceCount := terraform.GetResourceCount(t, planStruct)
-	want := 29
-	got := resourceCount.Add
-	if got != want {
-		t.Errorf("Test Resource Count Add = %v, want = %v", got, want)
-	}
-	if got, want := resourceCount.Change, 0; got != want {
-		t.Errorf("Test Resource Count Change = %v, want = %v", got, want)
-	}
-	if got, want := resourceCount.Destroy, 0; got != want {
-		t.Errorf("Test Resource Count Destroy = %v, want = %v", got, want)
-	}
+/*
+TestPlanMatchesGolden compares the resources planned by the networking stage,
+their actions and the attributes of the network and its subnets against
+testdata/networking.golden. Run go test -update to regenerate the file after a
+change to the stage.
+*/
+func TestPlanMatchesGolden(t *testing.T) {
+	planStruct := stage.Plan(t)
+	golden.Match(t, "networking", plan.New(planStruct, plan.Attributes{
+		"google_compute_network":    {"name"},
+		"google_compute_subnetwork": {"name", "ip_cidr_range", "region"},
+	}))
 }
 
-func TestTerraformModuleResourceAddressListMatch(t *testing.T) {
-	// Construct the terraform options with default retryable errors to handle the most common
-	// retryable errors in terraform testing.
-	expectedModuleAddresses := []string{"module.vpc_network", "module.vlan_attachment_a[0]", "module.vlan_attachment_b[0]", "module.havpn[0]", "module.nat[0]"}
-	terraformOptions := terraform.WithDefaultRetryableErrors(t, &terraform.Options{
-		// Set the path to the Terraform code that will be tested.
-		TerraformDir: terraformDirectoryPath,
-		Vars:         tfVars,
-		Reconfigure:  true,
-		Lock:         true,
-		PlanFilePath: "./plan",
-		NoColor:      true,
-	})
-	planStruct := terraform.InitAndPlanAndShow(t, terraformOptions)
-	content, err := terraform.ParsePlanJSON(planStruct)
-	if err != nil {
-		t.Errorf("Error parsing Terraform plan: %v", err) // Detailed error message
-		return                                            // Exit early on parsing error
-	}
-	actualModuleAddresses := make([]string, 0)
-	for _, element := range content.ResourceChangesMap {
-		if element.ModuleAddress != "" && !slices.Contains(actualModuleAddresses, element.ModuleAddress) {
-			actualModuleAddresses = append(actualModuleAddresses, element.ModuleAddress)
-		}
-	}
-	want := expectedModuleAddresses
-	got := actualModuleAddresses
-
-	sort.Strings(want)
-	sort.Strings(got)
-	// Compare the actual and expected module addresses
-	if !cmp.Equal(got, want) {
-		t.Errorf("Test Element Mismatch = %v, want = %v", got, want)
-	}
-
-	expectedResourceAddresses := []string{
-		"google_compute_route.default[0]",
-		"google_network_connectivity_service_connection_policy.policy[0]",
-		"module.havpn[0].google_compute_ha_vpn_gateway.ha_gateway[0]",
-		"module.havpn[0].google_compute_router.router[0]",
-		"module.havpn[0].google_compute_router_interface.router_interface[\"remote-0\"]",
-		"module.havpn[0].google_compute_router_interface.router_interface[\"remote-1\"]",
-		"module.havpn[0].google_compute_router_peer.bgp_peer[\"remote-0\"]",
-		"module.havpn[0].google_compute_router_peer.bgp_peer[\"remote-1\"]",
-		"module.havpn[0].google_compute_vpn_tunnel.tunnels[\"remote-0\"]",
-		"module.havpn[0].google_compute_vpn_tunnel.tunnels[\"remote-1\"]",
-		"module.havpn[0].random_id.secret",
-		"module.nat[0].google_compute_router.router[0]",
-		"module.nat[0].google_compute_router_nat.nat",
-		"module.vlan_attachment_a[0].google_compute_interconnect_attachment.default",
-		"module.vlan_attachment_a[0].google_compute_router_interface.default[0]",
-		"module.vlan_attachment_a[0].google_compute_router_peer.default[0]",
-		"module.vlan_attachment_b[0].google_compute_interconnect_attachment.default",
-		"module.vlan_attachment_b[0].google_compute_router_interface.default[0]",
-		"module.vlan_attachment_b[0].google_compute_router_peer.default[0]",
-		"module.vpc_network.google_compute_network.network[0]",
-		"module.vpc_network.google_compute_route.gateway[\"private-googleapis\"]",
-		"module.vpc_network.google_compute_route.gateway[\"restricted-googleapis\"]",
-		"module.vpc_network.google_compute_subnetwork.subnetwork[\"us-central1/unit-test-subnet-1\"]",
-		"module.vpc_network.google_compute_subnetwork.subnetwork[\"us-central1/unit-test-subnet-2\"]",
-		"google_compute_router.interconnect-router[0]",
-		"module.vpc_network.time_sleep.wait_60_seconds",
-		"module.vpc_network.google_service_networking_connection.psa_connection[\"servicenetworking.googleapis.com\"]",
-		"module.vpc_network.google_compute_global_address.psa_ranges[\"servicenetworking-googleapis-com-psarange\"]",
-		"module.vpc_network.google_compute_network_peering_routes_config.psa_routes[\"servicenetworking.googleapis.com\"]",
-	}
-
-	terraformOptions = terraform.WithDefaultRetryableErrors(t, &terraform.Options{
-		TerraformDir: terraformDirectoryPath,
-		Vars:         tfVars,
-		Reconfigure:  true,
-		Lock:         true,
-		PlanFilePath: "./plan",
-		NoColor:      true,
-	})
-
-	planStruct = terraform.InitAndPlanAndShow(t, terraformOptions)
-	content, err = terraform.ParsePlanJSON(planStruct)
-	if err != nil {
-		t.Errorf("Error parsing Terraform plan: %v", err) // Detailed error
-		return                                            // Exit early on parsing error
-	}
-
-	actualResourceAddresses := make([]string, 0)
-	resourcePolicyFound := false
-
-	for _, element := range content.ResourceChangesMap {
-		if element.Address != "" { // Check the Address field directly
-			if !slices.Contains(actualResourceAddresses, element.Address) {
-				actualResourceAddresses = append(actualResourceAddresses, element.Address)
-			}
-
-			// Check for your resource based on its complete address
-			if element.Address == "google_network_connectivity_service_connection_policy.policy[0]" {
-				resourcePolicyFound = true
-				// Optionally add further checks on element.Change here (actions, values, etc.)
-			}
-		}
-	}
-
-	if !resourcePolicyFound {
-		t.Errorf("Resource 'google_network_connectivity_service_connection_policy.policy[0]' not found in the plan.")
-	}
-
-	want = expectedResourceAddresses
-	got = actualResourceAddresses
-
-	sort.Strings(want)
-	sort.Strings(got)
-
-	if !cmp.Equal(got, want) {
-		t.Errorf("Test Element Mismatch = %v, want = %v", got, want)
-	}
+/*
+TestPlannedAttributes checks the network and region of the subnets, the PSA
+range and the region of the NAT router and of the HA VPN gateway.
+*/
+func TestPlannedAttributes(t *testing.T) {
+	p := plan.For(t, stage.Plan(t))
+	p.Resource(`module.vpc_network.google_compute_subnetwork.subnetwork["us-central1/*"]`).
+		Count(2).
+		Attr("project").Equals(projectID).
+		Attr("network").Equals(networkName).
+		Attr("region").Equals(region)
+	p.Resource(`module.vpc_network.google_compute_global_address.psa_ranges[*]`).
+		Count(1).
+		Attr("purpose").Equals("VPC_PEERING").
+		Attr("address").Equals("10.0.64.0").
+		Attr("prefix_length").Equals(20)
+	p.Resource("module.nat[0].google_compute_router.router[0]").
+		Attr("region").Equals(region)
+	p.Resource("module.havpn[0].google_compute_ha_vpn_gateway.ha_gateway[0]").
+		Attr("region").Equals(region)
 }
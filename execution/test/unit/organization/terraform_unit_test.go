@@ -16,8 +16,10 @@ package unittest
 import (
 	compare "cmp"
 	"fmt"
+	"os"
 	"testing"
 
+	"github.com/GoogleCloudPlatform/cloudnetworking-config-solutions/common_utils/plan"
 	"github.com/google/go-cmp/cmp"
 	"github.com/google/go-cmp/cmp/cmpopts"
 	"github.com/gruntwork-io/terratest/modules/terraform"
@@ -50,24 +52,28 @@ var (
 	}
 )
 
+// stage is initialized and planned once in TestMain and shared by the tests
+// that plan with the default variables.
+var stage = plan.NewStage(&terraform.Options{
+	TerraformDir: terraformDirectoryPath,
+	Vars:         tfVars,
+	Reconfigure:  true,
+	Lock:         true,
+	PlanFilePath: "./plan",
+	NoColor:      true,
+})
+
+func TestMain(m *testing.M) {
+	os.Exit(stage.Run(m))
+}
+
 func TestInitAndPlanRunWithTfVars(t *testing.T) {
 	/*
 	   0 = Succeeded with empty diff (no changes)
 	   1 = Error
 	   2 = Succeeded with non-empty diff (changes present)
 	*/
-	// Construct the terraform options with default retryable errors to handle the most common
-	// retryable errors in terraform testing.
-	terraformOptions := terraform.WithDefaultRetryableErrors(t, &terraform.Options{
-		// Set the path to the Terraform code that will be tested.
-		TerraformDir: terraformDirectoryPath,
-		Vars:         tfVars,
-		Reconfigure:  true,
-		Lock:         true,
-		PlanFilePath: "./plan",
-		NoColor:      true,
-	})
-	planExitCode := terraform.InitAndPlanWithExitCode(t, terraformOptions)
+	planExitCode := stage.ExitCode(t)
 	want := 2
 	got := planExitCode
 	if got != want {
@@ -100,19 +106,7 @@ func TestInitAndPlanRunWithoutTfVarsExpectFailureScenario(t *testing.T) {
 }
 
 func TestResourcesCount(t *testing.T) {
-	// Construct the terraform options with default retryable errors to handle the most common
-	// retryable errors in terraform testing.
-	terraformOptions := terraform.WithDefaultRetryableErrors(t, &terraform.Options{
-		// Set the path to the Terraform code that will be tested.
-		TerraformDir: terraformDirectoryPath,
-		Vars:         tfVars,
-		Reconfigure:  true,
-		Lock:         true,
-		PlanFilePath: "./plan",
-		NoColor:      true,
-	})
-	planStruct := terraform.InitAndPlan(t, terraformOptions)
-	resourceCount := terraform.GetResourceCount(t, planStruct)
+	resourceCount := plan.ResourceCount(stage.Plan(t))
 	if got, want := resourceCount.Add, 9; got != want {
 		t.Errorf("Test Resource Count Add = %v, want = %v", got, want)
 	}
@@ -125,23 +119,8 @@ func TestResourcesCount(t *testing.T) {
 }
 
 func TestTerraformModuleResourceAddressListMatch(t *testing.T) {
-	// Construct the terraform options with default retryable errors to handle the most common
-	// retryable errors in terraform testing.
 	expectedModulesAddress := []string{fmt.Sprintf("module.activate_project_apis[\"%s\"]", projectID)}
-	terraformOptions := terraform.WithDefaultRetryableErrors(t, &terraform.Options{
-		// Set the path to the Terraform code that will be tested.
-		TerraformDir: terraformDirectoryPath,
-		Vars:         tfVars,
-		Reconfigure:  true,
-		Lock:         true,
-		PlanFilePath: "./plan",
-		NoColor:      true,
-	})
-	planStruct := terraform.InitAndPlanAndShow(t, terraformOptions)
-	content, err := terraform.ParsePlanJSON(planStruct)
-	if err != nil {
-		t.Error(err.Error())
-	}
+	content := stage.Plan(t)
 	actualModuleAddress := make([]string, 0)
 	for _, element := range content.ResourceChangesMap {
 		if element.ModuleAddress != "" && !slices.Contains(actualModuleAddress, element.ModuleAddress) {
@@ -154,3 +133,18 @@ func TestTerraformModuleResourceAddressListMatch(t *testing.T) {
 		t.Errorf("Test Element Mismatch = %v, want = %v", got, want)
 	}
 }
+
+/*
+TestPlannedAttributes checks the project and the destroy behaviour of the services enabled for the project.
+*/
+func TestPlannedAttributes(t *testing.T) {
+	p := plan.For(t, stage.Plan(t))
+	p.Resource(fmt.Sprintf(`module.activate_project_apis[%q].google_project_service.project_services[*]`, projectID)).
+		Count(9).
+		Attr("project").Equals(projectID).
+		Attr("disable_on_destroy").Equals(false).
+		Attr("disable_dependent_services").Equals(false)
+	p.Resource(fmt.Sprintf(`module.activate_project_apis[%q].google_project_service.project_services["servicenetworking.googleapis.com"]`, projectID)).
+		Count(1).
+		Attr("service").Equals("servicenetworking.googleapis.com")
+}
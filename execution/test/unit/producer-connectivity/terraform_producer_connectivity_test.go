@@ -17,6 +17,7 @@ package unittest
 import (
 	"testing"
 
+	"github.com/GoogleCloudPlatform/cloudnetworking-config-solutions/common_utils/plan"
 	"github.com/gruntwork-io/terratest/modules/terraform"
 )
 
@@ -122,3 +123,45 @@ func TestPlanFailsWithoutInputVariables(t *testing.T) {
 		t.Errorf("TestPlanFailsWithoutVars: Expected plan to fail due to missing variables, but got exit code: %v", got)
 	}
 }
+
+/*
+TestPlannedAttributes plans an endpoint for a service attachment, which reads no
+producer instance, and checks the region, network, target and address of its
+forwarding rule.
+*/
+func TestPlannedAttributes(t *testing.T) {
+	const target = "projects/xxx-tp/regions/us-central1/serviceAttachments/gkedpm-xxx"
+	terraformOptions := terraform.WithDefaultRetryableErrors(t, &terraform.Options{
+		TerraformDir: terraformDirectoryPath,
+		Vars: map[string]any{
+			"psc_endpoints": []any{
+				map[string]any{
+					"endpoint_project_id":          "endpoint-project-id",
+					"producer_instance_project_id": "producer-instance-project-id",
+					"subnetwork_name":              "subnetwork",
+					"network_name":                 "network",
+					"ip_address_literal":           "10.128.0.6",
+					"region":                       "us-central1",
+					"target":                       target,
+				},
+			},
+		},
+		Reconfigure:  true,
+		Lock:         true,
+		PlanFilePath: planFilePath,
+		NoColor:      true,
+	})
+	p := plan.For(t, terraform.InitAndPlanAndShowWithStruct(t, terraformOptions))
+	p.Resource(`module.psc_forwarding_rule.google_compute_forwarding_rule.psc_forwarding_rule["0"]`).
+		Count(1).
+		Attr("project").Equals("endpoint-project-id").
+		Attr("region").Equals("us-central1").
+		Attr("network").Equals("network").
+		Attr("subnetwork").Equals("subnetwork").
+		Attr("target").Equals(target)
+	p.Resource(`module.psc_forwarding_rule.google_compute_address.psc_address["0"]`).
+		Count(1).
+		Attr("region").Equals("us-central1").
+		Attr("address").Equals("10.128.0.6").
+		Attr("address_type").Equals("INTERNAL")
+}
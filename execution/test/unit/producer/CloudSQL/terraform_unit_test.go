@@ -14,11 +14,11 @@
 package unittest
 
 import (
-	compare "cmp"
+	"github.com/GoogleCloudPlatform/cloudnetworking-config-solutions/common_utils/plan"
+	"github.com/GoogleCloudPlatform/cloudnetworking-config-solutions/common_utils/plan/golden"
 	"github.com/google/go-cmp/cmp"
-	"github.com/google/go-cmp/cmp/cmpopts"
 	"github.com/gruntwork-io/terratest/modules/terraform"
-	"golang.org/x/exp/slices"
+	"os"
 	"testing"
 )
 
@@ -40,6 +40,21 @@ var (
 	}
 )
 
+// stage is initialized and planned once in TestMain and shared by the tests
+// that plan with the default variables.
+var stage = plan.NewStage(&terraform.Options{
+	TerraformDir: terraformDirectoryPath,
+	Vars:         tfVars,
+	Reconfigure:  true,
+	Lock:         true,
+	PlanFilePath: "./plan",
+	NoColor:      true,
+})
+
+func TestMain(m *testing.M) {
+	os.Exit(stage.Run(m))
+}
+
 /*
 	TestInitAndPlanRunWithTfVars performs sanity check to ensure the terraform init
 
@@ -51,18 +66,7 @@ func TestInitAndPlanRunWithTfVars(t *testing.T) {
 	 1 = Error
 	 2 = Succeeded with non-empty diff (changes present)
 	*/
-	// Construct the terraform options with default retryable errors to handle the most common
-	// retryable errors in terraform testing.
-	terraformOptions := terraform.WithDefaultRetryableErrors(t, &terraform.Options{
-		// Set the path to the Terraform code that will be tested.
-		TerraformDir: terraformDirectoryPath,
-		Vars:         tfVars,
-		Reconfigure:  true,
-		Lock:         true,
-		PlanFilePath: "./plan",
-		NoColor:      true,
-	})
-	planExitCode := terraform.InitAndPlanWithExitCode(t, terraformOptions)
+	planExitCode := stage.ExitCode(t)
 	want := 2
 	got := planExitCode
 	if got != want {
@@ -101,67 +105,29 @@ func TestInitAndPlanRunWithInvalidTfVarsExpectFailureScenario(t *testing.T) {
 }
 
 /*
-	TestResourcesCount performs validation to verify number of  resources created, deleted and
-
-updated.
+TestPlanMatchesGolden compares the resources planned for the instances of the
+config folder, their actions and their main attributes against
+testdata/cloudsql.golden. Run go test -update to regenerate the file after a
+change to the stage or the config folder.
 */
-func TestResourcesCount(t *testing.T) {
-	// Construct the terraform options with default retryable errors to handle the most common
-	// retryable errors in terraform testing.
-	terraformOptions := terraform.WithDefaultRetryableErrors(t, &terraform.Options{
-		// Set the path to the Terraform code that will be tested.
-		TerraformDir: terraformDirectoryPath,
-		Vars:         tfVars,
-		Reconfigure:  true,
-		Lock:         true,
-		PlanFilePath: "./plan",
-		NoColor:      true,
-	})
-	planStruct := terraform.InitAndPlan(t, terraformOptions)
-	resourceCount := terraform.GetResourceCount(t, planStruct)
-	if got, want := resourceCount.Add, 3; got != want {
-		t.Errorf("Test Resource Count Add = %v, want = %v", got, want)
-	}
-	if got, want := resourceCount.Change, 0; got != want {
-		t.Errorf("Test Resource Count Change = %v, want = %v", got, want)
-	}
-	if got, want := resourceCount.Destroy, 0; got != want {
-		t.Errorf("Test Resource Count Destroy = %v, want = %v", got, want)
-	}
+func TestPlanMatchesGolden(t *testing.T) {
+	planStruct := stage.Plan(t)
+	golden.Match(t, "cloudsql", plan.New(planStruct, plan.Attributes{
+		"google_sql_database_instance": {"name", "database_version", "region"},
+	}))
 }
 
 /*
-	TestTerraformModuleResourceAddressListMatch compares and verifies the list of resources, modules
-
-created by the terraform solution.
+TestPlannedAttributes checks the instance planned for dummy1, including that it gets no public IP.
 */
-func TestTerraformModuleResourceAddressListMatch(t *testing.T) {
-	// Construct the terraform options with default retryable errors to handle the most common
-	// retryable errors in terraform testing.
-	expectedModulesAddress := []string{"module.cloudsql[\"dummy1\"]", "module.cloudsql[\"dummy2\"]", "module.cloudsql[\"dummy3\"]"}
-	terraformOptions := terraform.WithDefaultRetryableErrors(t, &terraform.Options{
-		// Set the path to the Terraform code that will be tested.
-		TerraformDir: terraformDirectoryPath,
-		Vars:         tfVars,
-		Reconfigure:  true,
-		Lock:         true,
-		PlanFilePath: "./plan",
-		NoColor:      true,
-	})
-	planStruct := terraform.InitAndPlanAndShow(t, terraformOptions)
-	content, err := terraform.ParsePlanJSON(planStruct)
-	if err != nil {
-		t.Error(err.Error())
-	}
-	actualModuleAddress := make([]string, 0)
-	for _, element := range content.ResourceChangesMap {
-		if element.ModuleAddress != "" && !slices.Contains(actualModuleAddress, element.ModuleAddress) {
-			actualModuleAddress = append(actualModuleAddress, element.ModuleAddress)
-		}
-	}
-	want := expectedModulesAddress
-	got := actualModuleAddress
-	if !cmp.Equal(got, want, cmpopts.SortSlices(compare.Less[string])) {
-		t.Errorf("Test Element Mismatch = %v, want = %v", got, want)
-	}
+func TestPlannedAttributes(t *testing.T) {
+	p := plan.For(t, stage.Plan(t))
+	p.Resource(`module.cloudsql["dummy1"].google_sql_database_instance.*`).
+		Count(1).
+		Attr("database_version").Equals("MYSQL_8_0").
+		Attr("project").Equals("project-dummy-id").
+		Attr("settings.0.tier").Equals("db-g1-small").
+		Attr("settings.0.availability_type").Equals("ZONAL").
+		Attr("settings.0.ip_configuration.0.ipv4_enabled").Equals(false).
+		Attr("settings.0.ip_configuration.0.private_network").Equals("projects/dummy-hostproject-id/global/networks/dummy-vpc-network")
 }
@@ -15,11 +15,16 @@
 package unittest
 
 import (
+	"os"
 	"path/filepath"
 	"strings"
 	"testing"
 
+	"github.com/GoogleCloudPlatform/cloudnetworking-config-solutions/common_utils/plan"
+	"github.com/GoogleCloudPlatform/cloudnetworking-config-solutions/common_utils/profile"
+	"github.com/GoogleCloudPlatform/cloudnetworking-config-solutions/common_utils/schema"
 	"github.com/gruntwork-io/terratest/modules/terraform" // Terraform testing library
+	"gopkg.in/yaml.v2"
 )
 
 var (
@@ -67,3 +72,50 @@ func TestTerraformInit(t *testing.T) {
 		t.Errorf("Terraform initialization failed. Output = %v", initOutput)
 	}
 }
+
+// TestPlannedAttributes plans a cluster in the endpoint project of the test
+// profile, as the private cluster module reads the zones and the engine
+// versions of a real project, and checks the planned cluster. It is skipped
+// when no profile is set.
+func TestPlannedAttributes(t *testing.T) {
+	env := profile.Require(t, "projects.endpoint")
+	projectID, region := env.Projects.Endpoint, env.Regions.Default
+
+	configFolderPath := t.TempDir()
+	yamlData, err := yaml.Marshal(&schema.GKE{
+		Name:               "gke-plan",
+		ProjectID:          projectID,
+		Region:             region,
+		Network:            "gke-plan-vpc",
+		Subnetwork:         "gke-plan-subnet",
+		IPRangePods:        "pods",
+		IPRangeServices:    "services",
+		DeletionProtection: schema.Bool(false),
+	})
+	if err != nil {
+		t.Fatalf("Error while marshalling YAML: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(configFolderPath, "gke-plan.yaml"), yamlData, 0666); err != nil {
+		t.Fatalf("Unable to write data into the file: %v", err)
+	}
+
+	terraformOptions := terraform.WithDefaultRetryableErrors(t, &terraform.Options{
+		TerraformDir: terraformDirectoryPath,
+		Vars: map[string]any{
+			"config_folder_path": configFolderPath,
+		},
+		PlanFilePath: filepath.Join(configFolderPath, "plan"),
+		NoColor:      true,
+	})
+	p := plan.For(t, terraform.InitAndPlanAndShowWithStruct(t, terraformOptions))
+	p.Resource(`module.gke["gke-plan"].google_container_cluster.primary`).
+		Count(1).
+		Attr("name").Equals("gke-plan").
+		Attr("project").Equals(projectID).
+		Attr("location").Equals(region).
+		Attr("network").Equals("projects/" + projectID + "/global/networks/gke-plan-vpc").
+		Attr("subnetwork").Equals("projects/" + projectID + "/regions/" + region + "/subnetworks/gke-plan-subnet").
+		Attr("ip_allocation_policy.0.cluster_secondary_range_name").Equals("pods").
+		Attr("ip_allocation_policy.0.services_secondary_range_name").Equals("services").
+		Attr("deletion_protection").Equals(false)
+}
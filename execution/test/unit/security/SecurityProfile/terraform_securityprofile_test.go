@@ -24,6 +24,7 @@ import (
 	"strings"
 	"testing"
 
+	"github.com/GoogleCloudPlatform/cloudnetworking-config-solutions/common_utils/plan"
 	"github.com/gruntwork-io/terratest/modules/terraform"
 	"github.com/stretchr/testify/assert"
 )
@@ -39,46 +40,35 @@ var (
 	}
 )
 
+// stage is initialized and planned once in TestMain and shared by the tests
+// that plan with the default variables.
+var stage = plan.NewStage(&terraform.Options{
+	TerraformDir: terraformDirectoryPathSP,
+	Vars:         tfVarsSP,
+	Reconfigure:  true,
+	PlanFilePath: "./plan_sp",
+	NoColor:      true,
+})
+
+func TestMain(m *testing.M) {
+	os.Exit(stage.Run(m))
+}
+
 // TestSecurityProfilePlanExitCode verifies that the plan exits with a code of 2, indicating changes are planned.
 func TestSecurityProfilePlanExitCode(t *testing.T) {
-	terraformOptions := terraform.WithDefaultRetryableErrors(t, &terraform.Options{
-		TerraformDir: terraformDirectoryPathSP,
-		Vars:         tfVarsSP,
-		Reconfigure:  true,
-		PlanFilePath: "./plan_sp",
-		NoColor:      true,
-	})
-
-	planExitCode := terraform.InitAndPlanWithExitCode(t, terraformOptions)
+	planExitCode := stage.ExitCode(t)
 	assert.Equal(t, 2, planExitCode, "Test Plan Exit Code: Expected changes to be applied")
 }
 
 // TestSecurityProfileResourcesCount verifies the number of resources to be added by the plan.
 func TestSecurityProfileResourcesCount(t *testing.T) {
-	terraformOptions := terraform.WithDefaultRetryableErrors(t, &terraform.Options{
-		TerraformDir: terraformDirectoryPathSP,
-		Vars:         tfVarsSP,
-		Reconfigure:  true,
-		PlanFilePath: "./plan_sp",
-		NoColor:      true,
-	})
-
-	planStruct := terraform.InitAndPlan(t, terraformOptions)
-	resourceCount := terraform.GetResourceCount(t, planStruct)
+	resourceCount := plan.ResourceCount(stage.Plan(t))
 	expectedResourceCount := 3
 	assert.Equal(t, expectedResourceCount, resourceCount.Add, "Test Resource Count Add: Unexpected number of resources to be created")
 }
 
 // TestSecurityProfileModuleAddressListMatch verifies that a module instance is planned for each YAML config file.
 func TestSecurityProfileModuleAddressListMatch(t *testing.T) {
-	terraformOptions := terraform.WithDefaultRetryableErrors(t, &terraform.Options{
-		TerraformDir: terraformDirectoryPathSP,
-		Vars:         tfVarsSP,
-		Reconfigure:  true,
-		PlanFilePath: "./plan_sp",
-		NoColor:      true,
-	})
-
 	expectedModuleKeys := []string{}
 	files, err := os.ReadDir(configFolderPathSP)
 	assert.NoError(t, err, "Error reading config directory")
@@ -98,9 +88,7 @@ func TestSecurityProfileModuleAddressListMatch(t *testing.T) {
 	for _, key := range expectedModuleKeys {
 		expectedModuleAddresses = append(expectedModuleAddresses, fmt.Sprintf("module.security_profiles[\"%s\"]", key))
 	}
-	planStruct := terraform.InitAndPlanAndShow(t, terraformOptions)
-	content, err := terraform.ParsePlanJSON(planStruct)
-	assert.NoError(t, err, "Error parsing plan JSON")
+	content := stage.Plan(t)
 
 	actualModuleAddresses := make([]string, 0)
 	for _, element := range content.ResourceChangesMap {
@@ -112,3 +100,22 @@ func TestSecurityProfileModuleAddressListMatch(t *testing.T) {
 
 	assert.ElementsMatch(t, expectedModuleAddresses, actualModuleAddresses, "The planned module addresses do not match the expected addresses from YAML files.")
 }
+
+// TestSecurityProfilePlannedAttributes checks the parent, location and type of the profiles and of the group planned for the config folder.
+func TestSecurityProfilePlannedAttributes(t *testing.T) {
+	p := plan.For(t, stage.Plan(t))
+	p.Resource(`module.security_profiles[*].google_network_security_security_profile.security_profile[0]`).
+		Count(2).
+		Attr("parent").Equals("organizations/YOUR_ORGANIZATION_ID").
+		Attr("location").Equals("global")
+	p.Resource(`module.security_profiles["instance1"].google_network_security_security_profile.security_profile[0]`).
+		Attr("type").Equals("THREAT_PREVENTION").
+		Attr("threat_prevention_profile.0.severity_overrides.0.severity").Equals("CRITICAL")
+	p.Resource(`module.security_profiles["instance2"].google_network_security_security_profile.security_profile[0]`).
+		Attr("type").Equals("CUSTOM_MIRRORING").
+		Attr("custom_mirroring_profile.0.mirroring_endpoint_group").Equals("projects/your-project/locations/global/mirroringEndpointGroups/test-group")
+	p.Resource(`module.security_profiles["instance1"].google_network_security_security_profile_group.security_profile_group[0]`).
+		Count(1).
+		Attr("name").Equals("test-threat-profile-group").
+		Attr("location").Equals("global")
+}